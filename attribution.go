@@ -0,0 +1,40 @@
+package booklit
+
+import "net/url"
+
+// Attribution credits the source and license of a section or of some
+// third-party asset included in it.
+type Attribution struct {
+	Subject Content
+	Credit  Content
+
+	// SPDX license identifier, e.g. CC-BY-4.0
+	License string
+
+	Section *Section
+}
+
+// LicenseURL returns the URL of the license on spdx.org.
+func (attr Attribution) LicenseURL() string {
+	if attr.License == "" {
+		return ""
+	}
+
+	return "https://spdx.org/licenses/" + url.PathEscape(attr.License) + ".html"
+}
+
+type Attributions struct {
+	Section *Section
+}
+
+func (con Attributions) IsFlow() bool {
+	return false
+}
+
+func (con Attributions) String() string {
+	return ""
+}
+
+func (con Attributions) Visit(visitor Visitor) error {
+	return visitor.VisitAttributions(con)
+}
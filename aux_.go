@@ -38,7 +38,9 @@ func (strip *stripAuxVisitor) VisitPreformatted(con Preformatted) error {
 
 func (strip *stripAuxVisitor) VisitReference(con *Reference) error {
 	ref := *con
-	ref.Content = StripAux(ref.Content)
+	if ref.Content != nil {
+		ref.Content = StripAux(ref.Content)
+	}
 	strip.Result = &ref
 	return nil
 }
@@ -96,7 +98,11 @@ func (strip *stripAuxVisitor) VisitLink(con Link) error {
 }
 
 func (strip *stripAuxVisitor) VisitTable(con Table) error {
-	newTable := Table{}
+	newTable := Table{
+		HeaderRows: con.HeaderRows,
+		Alignments: con.Alignments,
+	}
+
 	for _, row := range con.Rows {
 		newTable.Rows = append(newTable.Rows, stripAuxSeq(row))
 	}
@@ -118,6 +124,28 @@ func (strip *stripAuxVisitor) VisitDefinitions(con Definitions) error {
 	return nil
 }
 
+func (strip *stripAuxVisitor) VisitFigure(con *Figure) error {
+	strip.Result = con
+	return nil
+}
+
+// footnotes are omitted wherever auxiliary content is, e.g. from titles in
+// tables of contents, so that they are only marked where they appear
+func (strip *stripAuxVisitor) VisitFootnote(con *Footnote) error {
+	strip.Result = Empty
+	return nil
+}
+
+func (strip *stripAuxVisitor) VisitListOfFigures(con ListOfFigures) error {
+	strip.Result = con
+	return nil
+}
+
+func (strip *stripAuxVisitor) VisitAttributions(con Attributions) error {
+	strip.Result = con
+	return nil
+}
+
 func stripAuxSeq(seq []Content) []Content {
 	stripped := []Content{}
 
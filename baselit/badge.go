@@ -0,0 +1,71 @@
+package baselit
+
+import (
+	"fmt"
+	"html"
+
+	"github.com/vito/booklit"
+)
+
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"lightgrey":   "#9f9f9f",
+}
+
+// approximate width of a character in 11px Verdana
+const badgeCharWidth = 7
+
+const badgePadding = 10
+
+func (plugin Plugin) Badge(label string, value string, color ...string) booklit.Content {
+	fill := badgeColors["lightgrey"]
+	if len(color) > 0 && color[0] != "" {
+		fill = color[0]
+		if named, found := badgeColors[color[0]]; found {
+			fill = named
+		}
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StyleBadge,
+		Content: booklit.String(label + ": " + value),
+		Partials: booklit.Partials{
+			"Label": booklit.String(label),
+			"Value": booklit.String(value),
+			"Color": booklit.String(fill),
+			"SVG":   booklit.String(badgeSVG(label, value, fill)),
+		},
+	}
+}
+
+func badgeSVG(label string, value string, fill string) string {
+	labelWidth := len([]rune(label))*badgeCharWidth + badgePadding
+	valueWidth := len([]rune(value))*badgeCharWidth + badgePadding
+	width := labelWidth + valueWidth
+
+	label = html.EscapeString(label)
+	value = html.EscapeString(value)
+	fill = html.EscapeString(fill)
+
+	return fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" class="badge" width="%d" height="20" role="img" aria-label="%s: %s">`+
+			`<rect width="%d" height="20" fill="#555"/>`+
+			`<rect x="%d" width="%d" height="20" fill="%s"/>`+
+			`<g fill="#fff" font-family="Verdana,Geneva,sans-serif" font-size="11" text-anchor="middle">`+
+			`<text x="%d" y="14">%s</text>`+
+			`<text x="%d" y="14">%s</text>`+
+			`</g>`+
+			`</svg>`,
+		width, label, value,
+		labelWidth,
+		labelWidth, valueWidth, fill,
+		labelWidth/2, label,
+		labelWidth+valueWidth/2, value,
+	)
+}
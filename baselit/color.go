@@ -0,0 +1,71 @@
+package baselit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+func (plugin Plugin) Color(hex string) (booklit.Content, error) {
+	r, g, b, err := parseHexColor(hex)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := fmt.Sprintf("#%02x%02x%02x", r, g, b)
+
+	return booklit.Styled{
+		Style:   booklit.StyleSwatch,
+		Content: booklit.String(normalized),
+		Partials: booklit.Partials{
+			"Hex": booklit.String(normalized),
+			"RGB": booklit.String(fmt.Sprintf("rgb(%d, %d, %d)", r, g, b)),
+		},
+	}, nil
+}
+
+func (plugin Plugin) Palette(colors ...booklit.Content) (booklit.Content, error) {
+	swatches := booklit.Sequence{}
+	for _, color := range colors {
+		if styled, ok := color.(booklit.Styled); ok && styled.Style == booklit.StyleSwatch {
+			swatches = append(swatches, styled)
+			continue
+		}
+
+		swatch, err := plugin.Color(color.String())
+		if err != nil {
+			return nil, err
+		}
+
+		swatches = append(swatches, swatch)
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StylePalette,
+		Block:   true,
+		Content: swatches,
+	}, nil
+}
+
+func parseHexColor(hex string) (uint8, uint8, uint8, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(hex), "#")
+
+	if len(digits) == 3 {
+		digits = strings.Repeat(digits[0:1], 2) +
+			strings.Repeat(digits[1:2], 2) +
+			strings.Repeat(digits[2:3], 2)
+	}
+
+	if len(digits) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid hex color: %s", hex)
+	}
+
+	rgb, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hex color: %s", hex)
+	}
+
+	return uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), nil
+}
@@ -0,0 +1,257 @@
+package baselit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vito/booklit"
+)
+
+const defaultDateLayout = "January 2, 2006"
+
+var dateInputLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+}
+
+func (plugin Plugin) Date(date string, layout ...string) (booklit.Content, error) {
+	t, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.localDate(t, layout...), nil
+}
+
+func (plugin Plugin) Today(layout ...string) booklit.Content {
+	return plugin.localDate(plugin.buildTime(), layout...)
+}
+
+func (plugin Plugin) TimeAgo(date string) (booklit.Content, error) {
+	t, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StyleDate,
+		Content: booklit.String(timeAgo(plugin.buildTime().Sub(t))),
+		Partials: booklit.Partials{
+			"Datetime": booklit.String(t.Format(time.RFC3339)),
+		},
+	}, nil
+}
+
+func parseDate(date string) (time.Time, error) {
+	for _, layout := range dateInputLayouts {
+		t, err := time.Parse(layout, date)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date (expected YYYY-MM-DD or RFC3339): %s", date)
+}
+
+// buildTime returns the time the section's book is built as of, or the
+// current time if it wasn't given one.
+func (plugin Plugin) buildTime() time.Time {
+	if t := plugin.section.Top().BuildTime; !t.IsZero() {
+		return t
+	}
+
+	return time.Now()
+}
+
+// localDate formats the date in the section's locale, with the names of
+// months and weekdays in its language if they're known and its default
+// layout unless one is given.
+func (plugin Plugin) localDate(t time.Time, layout ...string) booklit.Content {
+	names := lookupDateNames(plugin.section.InheritedLocale())
+
+	format := defaultDateLayout
+	if names != nil {
+		format = names.Layout
+	}
+
+	if len(layout) > 0 && layout[0] != "" {
+		format = layout[0]
+	}
+
+	return dateContent(t, format, names)
+}
+
+func dateContent(t time.Time, format string, names *dateNames) booklit.Content {
+	return booklit.Styled{
+		Style:   booklit.StyleDate,
+		Content: booklit.String(formatDate(t, format, names)),
+		Partials: booklit.Partials{
+			"Datetime": booklit.String(t.Format(time.RFC3339)),
+		},
+	}
+}
+
+// dateNames are the names of months and weekdays in a language, in the order
+// of time.Month and time.Weekday, along with how dates are usually written in
+// it.
+type dateNames struct {
+	Layout string
+
+	Months      [12]string
+	ShortMonths [12]string
+
+	Days      [7]string
+	ShortDays [7]string
+}
+
+// names of months and weekdays by language; English is Go's own
+var localeDateNames = map[string]*dateNames{
+	"de": {
+		Layout:      "2. January 2006",
+		Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+	},
+
+	"es": {
+		Layout:      "2 de January de 2006",
+		Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		Days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+
+	"fr": {
+		Layout:      "2 January 2006",
+		Months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	},
+
+	"it": {
+		Layout:      "2 January 2006",
+		Months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		ShortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		Days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		ShortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+	},
+
+	"nl": {
+		Layout:      "2 January 2006",
+		Months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		ShortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		Days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		ShortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	},
+
+	"pt": {
+		Layout:      "2 de January de 2006",
+		Months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		ShortMonths: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		Days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		ShortDays:   [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+	},
+}
+
+// lookupDateNames returns the names for the locale's language, trying the
+// locale itself first, e.g. pt-BR before pt, or nil if it's English or
+// unknown.
+func lookupDateNames(locale string) *dateNames {
+	if locale == "" {
+		return nil
+	}
+
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+
+	if names, found := localeDateNames[locale]; found {
+		return names
+	}
+
+	return localeDateNames[strings.SplitN(locale, "-", 2)[0]]
+}
+
+// formatDate formats the time like time.Format, replacing the names of
+// months and weekdays with the given ones if any.
+func formatDate(t time.Time, layout string, names *dateNames) string {
+	if names == nil {
+		return t.Format(layout)
+	}
+
+	formatted := new(strings.Builder)
+
+	// checked in the same order as time.Format, so that e.g. "January" is
+	// the month rather than "Jan" followed by "uary"
+	elements := []struct {
+		Layout string
+		Name   string
+	}{
+		{"January", names.Months[t.Month()-1]},
+		{"Jan", names.ShortMonths[t.Month()-1]},
+		{"Monday", names.Days[t.Weekday()]},
+		{"Mon", names.ShortDays[t.Weekday()]},
+	}
+
+	start := 0
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, element := range elements {
+			if strings.HasPrefix(layout[i:], element.Layout) {
+				formatted.WriteString(t.Format(layout[start:i]))
+				formatted.WriteString(element.Name)
+
+				i += len(element.Layout)
+				start = i
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			i++
+		}
+	}
+
+	formatted.WriteString(t.Format(layout[start:]))
+
+	return formatted.String()
+}
+
+func timeAgo(d time.Duration) string {
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+
+	units := []struct {
+		Size time.Duration
+		Name string
+	}{
+		{365 * 24 * time.Hour, "year"},
+		{30 * 24 * time.Hour, "month"},
+		{7 * 24 * time.Hour, "week"},
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+
+	for _, unit := range units {
+		count := int64(d / unit.Size)
+		if count == 0 {
+			continue
+		}
+
+		name := unit.Name
+		if count != 1 {
+			name += "s"
+		}
+
+		return fmt.Sprintf("%d %s %s", count, name, suffix)
+	}
+
+	return "just now"
+}
@@ -0,0 +1,130 @@
+package baselit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// Mermaid renders a diagram from its Mermaid source.
+func (plugin Plugin) Mermaid(source booklit.Content) (booklit.Content, error) {
+	return plugin.diagram("mermaid", source)
+}
+
+// Dot renders a diagram from its Graphviz source.
+func (plugin Plugin) Dot(source booklit.Content) (booklit.Content, error) {
+	return plugin.diagram("dot", source)
+}
+
+// diagram renders the source to SVG with the command configured for the
+// language, if any. Otherwise it's left to the renderer, e.g. for rendering
+// in the browser, or as a code block.
+func (plugin Plugin) diagram(language string, source booklit.Content) (booklit.Content, error) {
+	diagram := booklit.Styled{
+		Style: booklit.StyleDiagram,
+		Block: true,
+		Content: booklit.Styled{
+			Style:   booklit.StyleVerbatim,
+			Block:   true,
+			Content: source,
+		},
+		Partials: booklit.Partials{
+			"Language": booklit.String(language),
+		},
+	}
+
+	command := plugin.section.Top().DiagramCommands[language]
+	if len(command) == 0 {
+		return diagram, nil
+	}
+
+	svg, err := renderWithCommand(plugin.section.Context(), command, "diagram."+language, "diagram.svg", source.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s diagram command failed: %w", language, err)
+	}
+
+	sanitized, err := booklit.SanitizeHTML(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	diagram.Partials["SVG"] = booklit.String(sanitized)
+
+	return diagram, nil
+}
+
+// renderWithCommand runs the command to render the source, e.g. a diagram to
+// SVG. The source is given on stdin and the output read from stdout, unless
+// the command has "{input}" or "{output}" placeholders, which are replaced
+// with the paths of files with the given names to read the source from or
+// write the output to instead. The command is killed if the context is done
+// first.
+func renderWithCommand(ctx context.Context, command []string, inputName string, outputName string, source string) (string, error) {
+	dir, err := ioutil.TempDir("", "booklit-render")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, inputName)
+	outputPath := filepath.Join(dir, outputName)
+
+	var readsInput, writesOutput bool
+
+	args := make([]string, len(command))
+	for i, arg := range command {
+		if strings.Contains(arg, "{input}") {
+			readsInput = true
+			arg = strings.Replace(arg, "{input}", inputPath, -1)
+		}
+
+		if strings.Contains(arg, "{output}") {
+			writesOutput = true
+			arg = strings.Replace(arg, "{output}", outputPath, -1)
+		}
+
+		args[i] = arg
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+
+	if readsInput {
+		err := ioutil.WriteFile(inputPath, []byte(source), 0644)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		cmd.Stdin = strings.NewReader(source)
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return "", ctx.Err()
+	} else if err != nil {
+		return "", fmt.Errorf("%w\n%s", err, stderr.String())
+	}
+
+	if !writesOutput {
+		return stdout.String(), nil
+	}
+
+	output, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), nil
+}
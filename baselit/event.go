@@ -0,0 +1,57 @@
+package baselit
+
+import (
+	"time"
+
+	"github.com/vito/booklit"
+)
+
+// Event announces something happening on the given date, e.g. a release or
+// a meetup. Events are listed by the "UpcomingEvents" partial of each page
+// and saved to the book's calendar by --save-calendar.
+func (plugin Plugin) Event(date string, title booklit.Content, description booklit.Content, tag ...string) (booklit.Content, error) {
+	t, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = time.Parse(dateInputLayouts[0], date)
+	allDay := err == nil
+
+	event := &booklit.Event{
+		Date:   t,
+		AllDay: allDay,
+
+		Title:       title,
+		Description: description,
+
+		Section:  plugin.section,
+		Location: plugin.section.InvokeLocation,
+	}
+
+	if len(tag) > 0 {
+		event.TagName = tag[0]
+	} else {
+		event.TagName = plugin.section.InheritedSlugifier().Slug(
+			t.Format(dateInputLayouts[0]) + " " + booklit.StripAux(title).String(),
+		)
+	}
+
+	plugin.section.Events = append(plugin.section.Events, event)
+
+	return booklit.Styled{
+		Style:   booklit.StyleEvent,
+		Block:   true,
+		Content: description,
+		Partials: booklit.Partials{
+			"Title": title,
+			"Date":  dateContent(t, event.DateLayout(), nil),
+			"Target": booklit.Target{
+				TagName:  event.TagName,
+				Location: event.Location,
+				Title:    title,
+				Content:  description,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,95 @@
+package baselit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vito/booklit"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var byteUnits = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+
+func (plugin Plugin) Num(number string) (booklit.Content, error) {
+	printer := plugin.printer()
+
+	if i, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64); err == nil {
+		return booklit.String(printer.Sprintf("%d", i)), nil
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number: %s", number)
+	}
+
+	return booklit.String(printer.Sprint(f)), nil
+}
+
+func (plugin Plugin) Bytes(size string) (booklit.Content, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(size), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid byte size: %s", size)
+	}
+
+	unit := 0
+	for math.Abs(n) >= 1000 && unit < len(byteUnits)-1 {
+		n /= 1000
+		unit++
+	}
+
+	printer := plugin.printer()
+
+	if unit == 0 {
+		return booklit.String(printer.Sprintf("%d %s", int64(n), byteUnits[unit])), nil
+	}
+
+	return booklit.String(printer.Sprintf("%.1f %s", n, byteUnits[unit])), nil
+}
+
+func (plugin Plugin) Duration(duration string) (booklit.Content, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(duration))
+	if err != nil {
+		return nil, err
+	}
+
+	units := []struct {
+		Size time.Duration
+		Name string
+	}{
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+		{time.Second, "second"},
+		{time.Millisecond, "millisecond"},
+	}
+
+	parts := []string{}
+	for _, unit := range units {
+		count := d / unit.Size
+		if count == 0 {
+			continue
+		}
+
+		d -= count * unit.Size
+
+		name := unit.Name
+		if count != 1 {
+			name += "s"
+		}
+
+		parts = append(parts, plugin.printer().Sprintf("%d %s", int64(count), name))
+	}
+
+	if len(parts) == 0 {
+		return booklit.String("0 seconds"), nil
+	}
+
+	return booklit.String(strings.Join(parts, " ")), nil
+}
+
+func (plugin Plugin) printer() *message.Printer {
+	return message.NewPrinter(language.Make(plugin.section.InheritedLocale()))
+}
@@ -0,0 +1,43 @@
+package baselit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/vito/booklit"
+)
+
+// ManSection sets the manual section of the section's man page, e.g. 1 for
+// commands or 5 for file formats. It is inherited by its children.
+func (plugin Plugin) ManSection(section string) error {
+	section = strings.TrimSpace(section)
+
+	if section == "" || !unicode.IsDigit(rune(section[0])) {
+		return fmt.Errorf("invalid man section: %s", section)
+	}
+
+	for _, r := range section {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return fmt.Errorf("invalid man section: %s", section)
+		}
+	}
+
+	plugin.section.SetPartial("ManSection", booklit.String(section))
+
+	return nil
+}
+
+// ManName sets the name of the section's man page, e.g. the command it
+// documents, in place of its tag.
+func (plugin Plugin) ManName(name string) error {
+	name = strings.TrimSpace(name)
+
+	if name == "" || strings.ContainsAny(name, " \t\n/") {
+		return fmt.Errorf("invalid man name: %s", name)
+	}
+
+	plugin.section.SetPartial("ManName", booklit.String(name))
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package baselit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/mathml"
+)
+
+// Math renders the TeX math inline with the text around it.
+func (plugin Plugin) Math(source string) (booklit.Content, error) {
+	return plugin.math(source, false)
+}
+
+// DisplayMath renders the TeX math as a block, e.g. for an equation.
+func (plugin Plugin) DisplayMath(source string) (booklit.Content, error) {
+	return plugin.math(source, true)
+}
+
+// math renders the source to HTML with the command configured for it, if
+// any, or to MathML otherwise. The source is kept as the content, for
+// renderers which render math themselves, e.g. LaTeX.
+func (plugin Plugin) math(source string, display bool) (booklit.Content, error) {
+	source = strings.TrimSpace(source)
+
+	top := plugin.section.Top()
+
+	command := top.MathCommand
+	if display && len(top.DisplayMathCommand) > 0 {
+		command = top.DisplayMathCommand
+	}
+
+	var rendered string
+	if len(command) == 0 {
+		markup, err := mathml.Convert(source, display)
+		if err != nil {
+			return nil, fmt.Errorf("invalid math: %w", err)
+		}
+
+		rendered = markup
+	} else {
+		output, err := renderWithCommand(plugin.section.Context(), command, "math.tex", "math.html", source)
+		if err != nil {
+			return nil, fmt.Errorf("math command failed: %w", err)
+		}
+
+		sanitized, err := booklit.SanitizeHTML(output)
+		if err != nil {
+			return nil, err
+		}
+
+		rendered = sanitized
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StyleMath,
+		Block:   display,
+		Content: booklit.String(source),
+		Partials: booklit.Partials{
+			"HTML": booklit.String(rendered),
+		},
+	}, nil
+}
@@ -0,0 +1,37 @@
+package baselit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// Playground renders the code as an editable code area which can be run by
+// the execution backend at the given URL, or the book's default backend, e.g.
+// for interactive tutorials. Engines which can't run code render it as a code
+// block.
+func (plugin Plugin) Playground(language string, code booklit.Content, url ...string) (booklit.Content, error) {
+	backend := plugin.section.Top().PlaygroundURL
+	if len(url) > 0 {
+		backend = strings.TrimSpace(url[0])
+	}
+
+	if backend == "" {
+		return nil, fmt.Errorf("no execution backend for playground (expected --html-playground-url or a URL)")
+	}
+
+	return booklit.Styled{
+		Style: booklit.StylePlayground,
+		Block: true,
+		Content: booklit.Styled{
+			Style:   booklit.StyleVerbatim,
+			Block:   true,
+			Content: code,
+		},
+		Partials: booklit.Partials{
+			"Language": booklit.String(strings.TrimSpace(language)),
+			"URL":      booklit.String(backend),
+		},
+	}, nil
+}
@@ -1,15 +1,23 @@
 package baselit
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/vito/booklit"
 	"github.com/vito/booklit/ast"
+	"github.com/vito/booklit/emoji"
+	"github.com/vito/booklit/issues"
 )
 
 func init() {
 	booklit.RegisterPlugin("base", NewPlugin)
+	booklit.DeclareSafePlugin("base")
 }
 
 func NewPlugin(section *booklit.Section) booklit.Plugin {
@@ -23,12 +31,19 @@ type Plugin struct {
 }
 
 func (plugin Plugin) UsePlugin(name string) error {
-	pluginFactory, found := booklit.LookupPlugin(name)
-	if !found {
-		return fmt.Errorf("unknown plugin '%s'", name)
+	resolve := booklit.ResolvePlugin
+	if plugin.section.Top().Safe != nil {
+		resolve = booklit.ResolveSafePlugin
 	}
 
-	plugin.section.UsePlugin(pluginFactory)
+	pluginFactories, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	for _, pf := range pluginFactories {
+		plugin.section.UsePlugin(pf)
+	}
 
 	return nil
 }
@@ -37,17 +52,49 @@ func (plugin Plugin) Styled(name string) {
 	plugin.section.Style = name
 }
 
+func (plugin Plugin) StyleSection(classes string, data ...string) error {
+	for _, attr := range data {
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 || !dataAttributeRegexp.MatchString(kv[0]) {
+			return fmt.Errorf("invalid data attribute (expected name=value): %s", attr)
+		}
+
+		if plugin.section.DataAttributes == nil {
+			plugin.section.DataAttributes = map[string]string{}
+		}
+
+		plugin.section.DataAttributes[kv[0]] = kv[1]
+	}
+
+	plugin.section.Classes = append(plugin.section.Classes, strings.Fields(classes)...)
+
+	return nil
+}
+
+var dataAttributeRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
 func (plugin Plugin) Title(title booklit.Content, tags ...string) {
 	plugin.section.SetTitle(title, plugin.section.InvokeLocation, tags...)
 }
 
+func (plugin Plugin) AliasTag(name string, names ...string) {
+	plugin.section.AddAlias(name, plugin.section.InvokeLocation)
+
+	for _, name := range names {
+		plugin.section.AddAlias(name, plugin.section.InvokeLocation)
+	}
+}
+
 func (plugin Plugin) Aux(content booklit.Content) booklit.Content {
 	return booklit.Aux{
 		Content: content,
 	}
 }
 
-func (plugin Plugin) Section(node ast.Node) error {
+// Section evaluates a child section. It takes the context so that the build
+// waits on it rather than giving up on it, as the evaluation stops by itself
+// once the context is done.
+func (plugin Plugin) Section(_ context.Context, node ast.Node) error {
 	section, err := plugin.section.Processor.EvaluateNode(plugin.section, node, plugin.section.PluginFactories)
 	if err != nil {
 		return err
@@ -60,7 +107,9 @@ func (plugin Plugin) Section(node ast.Node) error {
 	return nil
 }
 
-func (plugin Plugin) IncludeSection(path string) error {
+// IncludeSection evaluates a child section from a file. Like Section, it takes
+// the context so that it's waited on.
+func (plugin Plugin) IncludeSection(_ context.Context, path string) error {
 	sectionPath := filepath.Join(filepath.Dir(plugin.section.FilePath()), path)
 
 	section, err := plugin.section.Processor.EvaluateFile(plugin.section, sectionPath, []booklit.PluginFactory{NewPlugin})
@@ -73,6 +122,22 @@ func (plugin Plugin) IncludeSection(path string) error {
 	return nil
 }
 
+// IfFlag evaluates the node if the flag is enabled, or else the otherwise
+// node, if given. Like Section, it takes the context so that it's waited on.
+func (plugin Plugin) IfFlag(_ context.Context, flag string, node ast.Node, otherwise ...ast.Node) (booklit.Content, error) {
+	if !plugin.section.FlagEnabled(strings.TrimSpace(flag)) {
+		if len(otherwise) == 0 {
+			return nil, nil
+		}
+
+		node = otherwise[0]
+	}
+
+	// only the chosen branch is evaluated, so that sections and tags within
+	// the other never exist
+	return plugin.section.Processor.EvaluateContent(plugin.section, node)
+}
+
 func (plugin Plugin) SinglePage() {
 	plugin.section.PreventSplitSections = true
 }
@@ -85,10 +150,136 @@ func (plugin Plugin) SplitSections() {
 	}
 }
 
+func (plugin Plugin) Permalinks(strategy string) error {
+	permalinks, err := booklit.ParsePermalinks(strings.TrimSpace(strategy))
+	if err != nil {
+		return err
+	}
+
+	plugin.section.Permalinks = permalinks
+
+	return nil
+}
+
+func (plugin Plugin) Published(date string) error {
+	t, err := parseDate(strings.TrimSpace(date))
+	if err != nil {
+		return err
+	}
+
+	plugin.section.Published = t
+
+	return nil
+}
+
+func (plugin Plugin) Requires(tag string, tags ...string) {
+	plugin.section.Requires = append(plugin.section.Requires, tag)
+	plugin.section.Requires = append(plugin.section.Requires, tags...)
+
+	prerequisites := booklit.List{}
+	for _, tag := range plugin.section.Requires {
+		prerequisites.Items = append(prerequisites.Items, &booklit.Reference{
+			TagName: tag,
+
+			Location: plugin.section.InvokeLocation,
+		})
+	}
+
+	plugin.section.SetPartial("Prerequisites", prerequisites)
+}
+
+func (plugin Plugin) LearningPath(tags ...string) {
+	plugin.section.LearningPath = append([]string{}, tags...)
+}
+
+func (plugin Plugin) SplitSectionsOver(words string) error {
+	limit, err := strconv.Atoi(strings.TrimSpace(words))
+	if err != nil {
+		return fmt.Errorf("invalid word count: %s", words)
+	}
+
+	plugin.section.SplitSectionsOverWords = limit
+
+	return nil
+}
+
+func (plugin Plugin) ShuffleSections(seed ...string) error {
+	plugin.section.ShuffleChildren = true
+
+	if len(seed) > 0 {
+		n, err := strconv.ParseInt(strings.TrimSpace(seed[0]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed: %s", seed[0])
+		}
+
+		plugin.section.ShuffleSeed = n
+	}
+
+	return nil
+}
+
+func (plugin Plugin) FrontMatter(kind string) error {
+	for _, k := range booklit.FrontMatterKinds {
+		if string(k) == kind {
+			plugin.section.FrontMatter = k
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown front matter kind '%s'", kind)
+}
+
+func (plugin Plugin) Direction(dir string) error {
+	direction, err := parseDirection(dir)
+	if err != nil {
+		return err
+	}
+
+	plugin.section.Direction = direction
+
+	return nil
+}
+
+func parseDirection(dir string) (booklit.Direction, error) {
+	for _, d := range booklit.Directions {
+		if string(d) == dir {
+			return d, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid direction: %s", dir)
+}
+
+func (plugin Plugin) ErrorPage(status string) error {
+	code, err := strconv.Atoi(strings.TrimSpace(status))
+	if err != nil || code < 400 || code > 599 {
+		return fmt.Errorf("invalid status code: %s", status)
+	}
+
+	plugin.section.ErrorPage = code
+
+	return nil
+}
+
 func (plugin Plugin) OmitChildrenFromTableOfContents() {
 	plugin.section.OmitChildrenFromTableOfContents = true
 }
 
+func (plugin Plugin) HideFromToc() {
+	plugin.section.HideFromTableOfContents = true
+}
+
+func (plugin Plugin) TocDepth(depth string) error {
+	levels, err := strconv.Atoi(strings.TrimSpace(depth))
+	if err != nil || levels < 1 {
+		return fmt.Errorf("invalid depth: %s", depth)
+	}
+
+	plugin.section.TableOfContentsDepth = levels
+
+	return nil
+}
+
 func (plugin Plugin) TableOfContents() booklit.Content {
 	return booklit.TableOfContents{
 		Section: plugin.section,
@@ -165,10 +356,66 @@ func (plugin Plugin) Aside(content booklit.Content) booklit.Content {
 	}
 }
 
+func (plugin Plugin) Isolate(content booklit.Content, dir ...string) (booklit.Content, error) {
+	isolate := booklit.Styled{
+		Content: content,
+		Style:   booklit.StyleIsolate,
+	}
+
+	if len(dir) > 0 {
+		direction, err := parseDirection(dir[0])
+		if err != nil {
+			return nil, err
+		}
+
+		isolate.Partials = booklit.Partials{
+			"Direction": booklit.String(direction),
+		}
+	}
+
+	return isolate, nil
+}
+
+func (plugin Plugin) Ruby(base booklit.Content, reading booklit.Content) booklit.Content {
+	return booklit.Styled{
+		Content: base,
+		Style:   booklit.StyleRuby,
+		Partials: booklit.Partials{
+			"Reading": reading,
+		},
+	}
+}
+
+func (plugin Plugin) Epigraph(quote booklit.Content, attribution ...booklit.Content) booklit.Content {
+	epigraph := booklit.Styled{
+		Style:   booklit.StyleEpigraph,
+		Block:   true,
+		Content: quote,
+	}
+
+	if len(attribution) > 0 {
+		epigraph.Partials = booklit.Partials{
+			"Attribution": attribution[0],
+		}
+	}
+
+	return epigraph
+}
+
+func (plugin Plugin) Pullquote(content booklit.Content) booklit.Content {
+	return booklit.Styled{
+		Style:   booklit.StylePullQuote,
+		Block:   true,
+		Content: content,
+	}
+}
+
 func (plugin Plugin) Link(content booklit.Content, target string) booklit.Content {
 	return booklit.Link{
 		Content: content,
-		Target:  target,
+		Target:  plugin.section.RewriteURL(target),
+
+		Location: plugin.section.InvokeLocation,
 	}
 }
 
@@ -205,6 +452,61 @@ func (plugin Plugin) Target(tag string, titleAndContent ...booklit.Content) book
 	return ref
 }
 
+func (plugin Plugin) Figure(content booklit.Content, caption booklit.Content, tag ...string) booklit.Content {
+	fig := &booklit.Figure{
+		Content:  content,
+		Caption:  caption,
+		Location: plugin.section.InvokeLocation,
+	}
+
+	if len(tag) > 0 {
+		fig.TagName = tag[0]
+	}
+
+	return fig
+}
+
+func (plugin Plugin) ListOfFigures() booklit.Content {
+	return booklit.ListOfFigures{
+		Section: plugin.section,
+	}
+}
+
+func (plugin Plugin) Footnote(content booklit.Content) booklit.Content {
+	return &booklit.Footnote{
+		Content:  content,
+		Location: plugin.section.InvokeLocation,
+	}
+}
+
+func (plugin Plugin) CollectEndnotes() {
+	plugin.section.CollectEndnotes = true
+}
+
+func (plugin Plugin) License(spdxID string) {
+	plugin.section.License = spdxID
+}
+
+func (plugin Plugin) Credit(subject booklit.Content, credit booklit.Content, spdxID ...string) {
+	attr := booklit.Attribution{
+		Subject: subject,
+		Credit:  credit,
+		Section: plugin.section,
+	}
+
+	if len(spdxID) > 0 {
+		attr.License = spdxID[0]
+	}
+
+	plugin.section.Attributions = append(plugin.section.Attributions, attr)
+}
+
+func (plugin Plugin) Attributions() booklit.Content {
+	return booklit.Attributions{
+		Section: plugin.section,
+	}
+}
+
 func (plugin Plugin) List(items ...booklit.Content) booklit.Content {
 	return booklit.List{
 		Items: items,
@@ -218,7 +520,7 @@ func (plugin Plugin) OrderedList(items ...booklit.Content) booklit.Content {
 	}
 }
 
-func (plugin Plugin) Image(path string, description ...string) booklit.Content {
+func (plugin Plugin) Image(path string, description ...string) (booklit.Content, error) {
 	img := booklit.Image{
 		Path: path,
 	}
@@ -227,30 +529,100 @@ func (plugin Plugin) Image(path string, description ...string) booklit.Content {
 		img.Description = description[0]
 	}
 
-	return img
+	if processor := plugin.section.Top().ImageProcessor; processor != nil {
+		var err error
+		img, err = processor.ProcessImage(plugin.section, img)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	img.Path = plugin.section.RewriteURL(img.Path)
+
+	for i, variant := range img.Variants {
+		img.Variants[i].Path = plugin.section.RewriteURL(variant.Path)
+	}
+
+	return img, nil
 }
 
-func (plugin Plugin) SetPartial(name string, content booklit.Content) {
-	plugin.section.SetPartial(name, content)
+func (plugin Plugin) Emoji(name string) (booklit.Content, error) {
+	return emoji.Lookup(plugin.section, name)
 }
 
-func (plugin Plugin) Table(rows ...booklit.Content) (booklit.Content, error) {
-	table := booklit.Table{}
+func (plugin Plugin) Issue(ref string) (booklit.Content, error) {
+	return issues.Content(plugin.section, ref)
+}
 
-	for _, row := range rows {
-		list, ok := row.(booklit.List)
-		if !ok {
-			return nil, fmt.Errorf("table row is not a list: %s", row)
+func (plugin Plugin) EmojiShortcodes() {
+	plugin.section.EmojiShortcodes = true
+}
+
+func (plugin Plugin) HardWraps() {
+	plugin.section.HardWraps = true
+}
+
+func (plugin Plugin) AttachUnits() {
+	plugin.section.AttachUnits = true
+}
+
+// Nbsp renders a space which lines never break at.
+func (plugin Plugin) Nbsp() booklit.Content {
+	return booklit.String(booklit.NonBreakingSpace)
+}
+
+// Thinspace renders a narrow space, e.g. between initials.
+func (plugin Plugin) Thinspace() booklit.Content {
+	return booklit.String(booklit.ThinSpace)
+}
+
+// Wbr renders nothing, but allows lines to break at it, e.g. within a long
+// path.
+func (plugin Plugin) Wbr() booklit.Content {
+	return booklit.String(booklit.WordBreak)
+}
+
+// Verse renders the content with the line breaks within each of its
+// paragraphs preserved, e.g. for poetry or lyrics.
+func (plugin Plugin) Verse(content booklit.Content) booklit.Content {
+	return booklit.Styled{
+		Style:   booklit.StyleVerse,
+		Block:   true,
+		Content: breakLines(content),
+	}
+}
+
+func breakLines(content booklit.Content) booklit.Content {
+	switch con := content.(type) {
+	case booklit.Paragraph:
+		return con.Lines()
+	case booklit.Sequence:
+		broken := booklit.Sequence{}
+		for _, c := range con {
+			broken = append(broken, breakLines(c))
 		}
 
-		table.Rows = append(table.Rows, list.Items)
+		return broken
+	default:
+		return content
+	}
+}
+
+func (plugin Plugin) EmojiImages(pattern string) {
+	plugin.section.EmojiImages = pattern
+}
+
+func (plugin Plugin) Translate(key string) (booklit.Content, error) {
+	str, err := plugin.section.Translate(key)
+	if err != nil {
+		return nil, err
 	}
 
-	return table, nil
+	return booklit.String(str), nil
 }
 
-func (plugin Plugin) TableRow(cols ...booklit.Content) booklit.Content {
-	return plugin.List(cols...)
+func (plugin Plugin) SetPartial(name string, content booklit.Content) {
+	plugin.section.SetPartial(name, content)
 }
 
 func (plugin Plugin) Definitions(items ...booklit.Content) (booklit.Content, error) {
@@ -274,6 +646,27 @@ func (plugin Plugin) Definitions(items ...booklit.Content) (booklit.Content, err
 	return defs, nil
 }
 
+// Glossary renders a definition list like \definitions, sorted alphabetically
+// by subject according to the section's locale.
+func (plugin Plugin) Glossary(items ...booklit.Content) (booklit.Content, error) {
+	content, err := plugin.Definitions(items...)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := content.(booklit.Definitions)
+
+	collator := plugin.section.Collator()
+	sort.SliceStable(defs, func(i, j int) bool {
+		return collator.CompareString(
+			booklit.StripAux(defs[i].Subject).String(),
+			booklit.StripAux(defs[j].Subject).String(),
+		) < 0
+	})
+
+	return defs, nil
+}
+
 func (plugin Plugin) Definition(subject booklit.Content, definition booklit.Content) booklit.Content {
 	return plugin.List(subject, definition)
 }
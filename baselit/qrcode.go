@@ -0,0 +1,50 @@
+package baselit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vito/booklit"
+	"rsc.io/qr"
+)
+
+// number of blank modules surrounding the code, as required by the spec
+const qrQuietZone = 4
+
+func (plugin Plugin) Qrcode(content string) (booklit.Content, error) {
+	code, err := qr.Encode(content, qr.M)
+	if err != nil {
+		return nil, err
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StyleQRCode,
+		Content: booklit.String(content),
+		Partials: booklit.Partials{
+			"SVG": booklit.String(qrcodeSVG(code)),
+		},
+	}, nil
+}
+
+func qrcodeSVG(code *qr.Code) string {
+	size := code.Size + qrQuietZone*2
+
+	path := new(strings.Builder)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(path, "M%d %dh1v1h-1z", x+qrQuietZone, y+qrQuietZone)
+			}
+		}
+	}
+
+	return fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" class="qrcode" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+			`<rect width="%d" height="%d" fill="#fff"/>`+
+			`<path fill="#000" d="%s"/>`+
+			`</svg>`,
+		size, size,
+		size, size,
+		path.String(),
+	)
+}
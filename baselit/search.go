@@ -0,0 +1,25 @@
+package baselit
+
+import "github.com/vito/booklit"
+
+// searchIndexFile is the search index saved by --save-search-index, relative
+// to the destination.
+const searchIndexFile = "search_index.json"
+
+// SearchBox renders a search widget which searches the titles and text of
+// every section, using the search index saved alongside the pages.
+func (plugin Plugin) SearchBox(placeholder ...string) booklit.Content {
+	prompt := "Search"
+	if len(placeholder) > 0 {
+		prompt = placeholder[0]
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StyleSearchBox,
+		Block:   true,
+		Content: booklit.String(prompt),
+		Partials: booklit.Partials{
+			"Index": booklit.String(searchIndexFile),
+		},
+	}
+}
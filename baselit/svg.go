@@ -0,0 +1,44 @@
+package baselit
+
+import (
+	"path/filepath"
+
+	"github.com/vito/booklit"
+)
+
+func (plugin Plugin) Svg(path string, fallback ...string) (booklit.Content, error) {
+	svgPath := filepath.Join(filepath.Dir(plugin.section.FilePath()), path)
+
+	err := plugin.section.CheckFile(svgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := plugin.section.ReadFile(svgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin.section.AddDependency(svgPath)
+
+	sanitized, err := booklit.SanitizeHTML(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	img := booklit.Image{
+		Path: path,
+	}
+
+	if len(fallback) > 0 {
+		img.Path = fallback[0]
+	}
+
+	return booklit.Styled{
+		Style:   booklit.StyleSVG,
+		Content: img,
+		Partials: booklit.Partials{
+			"SVG": booklit.String(sanitized),
+		},
+	}, nil
+}
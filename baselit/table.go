@@ -0,0 +1,179 @@
+package baselit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// Table renders a table of the given rows, each from \table-row, \row, or
+// \header-row. Rows may also be given together as one argument, one after
+// another, along with \align-columns to align their columns.
+func (plugin Plugin) Table(rows ...booklit.Content) (booklit.Content, error) {
+	table := booklit.Table{}
+
+	var add func(booklit.Content) error
+	add = func(content booklit.Content) error {
+		switch con := content.(type) {
+		case tableRow:
+			if con.header && len(table.Rows) > table.HeaderRows {
+				return fmt.Errorf("header row follows body rows: %s", con)
+			}
+
+			if con.header {
+				table.HeaderRows++
+			}
+
+			table.Rows = append(table.Rows, tableCells(con.Items))
+		case booklit.List:
+			table.Rows = append(table.Rows, tableCells(con.Items))
+		case columnAlignments:
+			table.Alignments = con.alignments
+		case booklit.Sequence:
+			for _, c := range con {
+				err := add(c)
+				if err != nil {
+					return err
+				}
+			}
+		case booklit.Paragraph:
+			for _, c := range con {
+				err := add(c)
+				if err != nil {
+					return err
+				}
+			}
+		case booklit.String:
+			if strings.TrimSpace(con.String()) != "" {
+				return fmt.Errorf("table row is not a list: %s", con)
+			}
+		default:
+			return fmt.Errorf("table row is not a list: %s", content)
+		}
+
+		return nil
+	}
+
+	for _, row := range rows {
+		err := add(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+func (plugin Plugin) TableRow(cols ...booklit.Content) booklit.Content {
+	return plugin.List(cols...)
+}
+
+// Row is a shorthand for \table-row.
+func (plugin Plugin) Row(cols ...booklit.Content) booklit.Content {
+	return plugin.TableRow(cols...)
+}
+
+// HeaderRow is a row of a table which heads its columns, e.g. naming them.
+// Header rows must come before the rest.
+func (plugin Plugin) HeaderRow(cols ...booklit.Content) booklit.Content {
+	return tableRow{
+		List:   booklit.List{Items: cols},
+		header: true,
+	}
+}
+
+// Cell is a cell of a table row, for giving a row its cells together as one
+// argument, e.g. \row{\cell{a} \cell{b}}.
+func (plugin Plugin) Cell(content booklit.Content) booklit.Content {
+	return tableCell{content}
+}
+
+// AlignColumns aligns the cells of each column of the table it is given to,
+// in order: left, center, right, or default.
+func (plugin Plugin) AlignColumns(alignments ...string) (booklit.Content, error) {
+	aligned := columnAlignments{Content: booklit.Empty}
+
+	for _, alignment := range alignments {
+		switch align := booklit.Alignment(strings.TrimSpace(alignment)); align {
+		case booklit.AlignLeft, booklit.AlignCenter, booklit.AlignRight:
+			aligned.alignments = append(aligned.alignments, align)
+		case "default":
+			aligned.alignments = append(aligned.alignments, booklit.AlignDefault)
+		default:
+			return nil, fmt.Errorf("invalid alignment: %s", alignment)
+		}
+	}
+
+	return aligned, nil
+}
+
+// tableRow is a row given by \header-row. Anywhere other than a table, it
+// renders as a list of its cells.
+type tableRow struct {
+	booklit.List
+
+	header bool
+}
+
+// tableCell is a cell given by \cell. Anywhere other than a table row, it
+// renders as its content.
+type tableCell struct {
+	booklit.Content
+}
+
+// columnAlignments is given by \align-columns. Anywhere other than a table,
+// it renders as nothing.
+type columnAlignments struct {
+	booklit.Content
+
+	alignments []booklit.Alignment
+}
+
+// tableCells returns the cells of a row. If the row was given its cells
+// together via \cell, they are split up, ignoring the whitespace between
+// them.
+func tableCells(items []booklit.Content) []booklit.Content {
+	cells := []booklit.Content{}
+	for _, item := range items {
+		if split, ok := splitCells(item); ok {
+			cells = append(cells, split...)
+		} else {
+			cells = append(cells, item)
+		}
+	}
+
+	return cells
+}
+
+// splitCells returns the cells the content is made up of, if it is made up
+// of nothing but cells and whitespace.
+func splitCells(content booklit.Content) ([]booklit.Content, bool) {
+	var contents []booklit.Content
+	switch con := content.(type) {
+	case tableCell:
+		return []booklit.Content{con.Content}, true
+	case booklit.Sequence:
+		contents = con
+	case booklit.Paragraph:
+		contents = con
+	default:
+		return nil, false
+	}
+
+	cells := []booklit.Content{}
+	for _, c := range contents {
+		if str, ok := c.(booklit.String); ok && strings.TrimSpace(str.String()) == "" {
+			continue
+		}
+
+		split, ok := splitCells(c)
+		if !ok {
+			return nil, false
+		}
+
+		cells = append(cells, split...)
+	}
+
+	return cells, len(cells) > 0
+}
@@ -0,0 +1,69 @@
+package booklitcmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLog logs each request served by the handler, along with its status,
+// size, and duration.
+type AccessLog struct {
+	Handler http.Handler
+
+	// Whether to log each request.
+	Log bool
+
+	// If set, each request is recorded.
+	Metrics *Metrics
+}
+
+func (log AccessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	recorder := &statusRecorder{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+
+	log.Handler.ServeHTTP(recorder, r)
+
+	duration := time.Since(start)
+
+	if log.Metrics != nil {
+		log.Metrics.ObserveRequest(recorder.status, duration)
+	}
+
+	if !log.Log {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"method":     r.Method,
+		"path":       r.URL.Path,
+		"status":     recorder.status,
+		"bytes":      recorder.bytes,
+		"duration":   duration.Seconds(),
+		"remote":     r.RemoteAddr,
+		"user_agent": r.UserAgent(),
+	}).Info("request")
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
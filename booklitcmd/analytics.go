@@ -0,0 +1,396 @@
+package booklitcmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// analyticsPath is the path of the endpoint which reports what readers
+// didn't find, and which search queries with no results are recorded via.
+const analyticsPath = "/_booklit/analytics"
+
+// defaults for Analytics
+const (
+	defaultAnalyticsEntries = 1000
+	defaultAnalyticsLogSize = 10 << 20
+)
+
+// longest search query recorded, in characters
+const maxAnalyticsQuery = 200
+
+// Analytics records what readers looked for and didn't find when serving:
+// search queries with no results and paths which weren't found, so that
+// maintainers can write new content or add redirects for them.
+type Analytics struct {
+	// Token which must be given as a bearer token to view the report; if
+	// empty, anyone can view it.
+	Token string
+
+	// If set, each event is appended to the file as a line of JSON, and
+	// loaded from it again upon restarting. Once it reaches MaxLogSize it's
+	// moved aside to the same path with a .1 suffix, replacing the last one.
+	Log        string
+	MaxLogSize int64
+
+	// Number of distinct queries and paths which are kept, each forgetting
+	// those seen least recently first. Defaults to 1000.
+	MaxEntries int
+
+	searches map[string]*analyticsEntry
+	notFound map[string]*analyticsEntry
+	loaded   bool
+
+	logFile *os.File
+	logSize int64
+
+	lock sync.Mutex
+}
+
+// analyticsEvent is a single search query or path recorded, as written to
+// the log.
+type analyticsEvent struct {
+	Time     time.Time `json:"time"`
+	Query    string    `json:"query,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Referrer string    `json:"referrer,omitempty"`
+}
+
+type analyticsEntry struct {
+	Query string `json:"query,omitempty"`
+	Path  string `json:"path,omitempty"`
+
+	Count int       `json:"count"`
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+
+	// page which last linked to the path, if known
+	Referrer string `json:"referrer,omitempty"`
+}
+
+type analyticsReport struct {
+	Searches []*analyticsEntry `json:"searches"`
+	NotFound []*analyticsEntry `json:"not_found"`
+}
+
+// RecordSearch records a search query which found nothing.
+func (analytics *Analytics) RecordSearch(query string) {
+	analytics.record(analyticsEvent{
+		Time:  time.Now().UTC(),
+		Query: query,
+	})
+}
+
+// RecordNotFound records a request for a path which wasn't found.
+func (analytics *Analytics) RecordNotFound(r *http.Request) {
+	analytics.record(analyticsEvent{
+		Time:     time.Now().UTC(),
+		Path:     r.URL.Path,
+		Referrer: r.Referer(),
+	})
+}
+
+func (analytics *Analytics) record(event analyticsEvent) {
+	analytics.lock.Lock()
+	defer analytics.lock.Unlock()
+
+	analytics.load()
+	analytics.observe(event)
+
+	err := analytics.write(event)
+	if err != nil {
+		logrus.Errorf("failed to write analytics log: %s", err)
+	}
+}
+
+// load replays the log, if any, upon first use.
+func (analytics *Analytics) load() {
+	if analytics.loaded {
+		return
+	}
+
+	analytics.loaded = true
+	analytics.searches = map[string]*analyticsEntry{}
+	analytics.notFound = map[string]*analyticsEntry{}
+
+	if analytics.Log == "" {
+		return
+	}
+
+	for _, path := range []string{analytics.Log + ".1", analytics.Log} {
+		file, err := os.Open(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logrus.Warnf("failed to load analytics log: %s", err)
+			}
+
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var event analyticsEvent
+			if json.Unmarshal(scanner.Bytes(), &event) == nil {
+				analytics.observe(event)
+			}
+		}
+
+		_ = file.Close()
+	}
+}
+
+func (analytics *Analytics) observe(event analyticsEvent) {
+	entries, key := analytics.notFound, event.Path
+	if event.Query != "" {
+		entries, key = analytics.searches, strings.ToLower(event.Query)
+	}
+
+	entry, found := entries[key]
+	if !found {
+		max := analytics.MaxEntries
+		if max == 0 {
+			max = defaultAnalyticsEntries
+		}
+
+		if len(entries) >= max {
+			forgetOldest(entries)
+		}
+
+		entry = &analyticsEntry{
+			Query: event.Query,
+			Path:  event.Path,
+			First: event.Time,
+		}
+
+		entries[key] = entry
+	}
+
+	entry.Count++
+	entry.Last = event.Time
+
+	if event.Referrer != "" {
+		entry.Referrer = event.Referrer
+	}
+}
+
+func forgetOldest(entries map[string]*analyticsEntry) {
+	var oldest string
+	for key, entry := range entries {
+		if oldest == "" || entry.Last.Before(entries[oldest].Last) {
+			oldest = key
+		}
+	}
+
+	delete(entries, oldest)
+}
+
+// write appends the event to the log, rotating it first if it's full.
+func (analytics *Analytics) write(event analyticsEvent) error {
+	if analytics.Log == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	max := analytics.MaxLogSize
+	if max == 0 {
+		max = defaultAnalyticsLogSize
+	}
+
+	if analytics.logFile != nil && analytics.logSize+int64(len(line)) > max {
+		_ = analytics.logFile.Close()
+		analytics.logFile = nil
+
+		err := os.Rename(analytics.Log, analytics.Log+".1")
+		if err != nil {
+			return err
+		}
+	}
+
+	if analytics.logFile == nil {
+		file, err := os.OpenFile(analytics.Log, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+
+		analytics.logFile = file
+		analytics.logSize = info.Size()
+	}
+
+	n, err := analytics.logFile.Write(line)
+	analytics.logSize += int64(n)
+	return err
+}
+
+// report returns the queries and paths recorded, most frequent first.
+func (analytics *Analytics) report() analyticsReport {
+	analytics.lock.Lock()
+	defer analytics.lock.Unlock()
+
+	analytics.load()
+
+	return analyticsReport{
+		Searches: sortedEntries(analytics.searches),
+		NotFound: sortedEntries(analytics.notFound),
+	}
+}
+
+func sortedEntries(entries map[string]*analyticsEntry) []*analyticsEntry {
+	sorted := []*analyticsEntry{}
+	for _, entry := range entries {
+		copied := *entry
+		sorted = append(sorted, &copied)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+
+		return sorted[i].Last.After(sorted[j].Last)
+	})
+
+	return sorted
+}
+
+// serveAnalytics serves the report for GET requests, and records a search
+// query which found nothing for POST requests, as sent by the pages served.
+func (server *Server) serveAnalytics(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if !server.Analytics.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(server.Analytics.report())
+
+	case http.MethodPost:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var search struct {
+			Query string `json:"query"`
+		}
+
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&search)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		query := strings.Join(strings.Fields(search.Query), " ")
+		if query == "" || utf8.RuneCountInString(query) > maxAnalyticsQuery {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		server.Analytics.RecordSearch(query)
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (analytics *Analytics) authorized(r *http.Request) bool {
+	if analytics.Token == "" {
+		return true
+	}
+
+	bearer := r.Header.Get("Authorization")
+	if !strings.HasPrefix(bearer, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimPrefix(bearer, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(analytics.Token)) == 1
+}
+
+// injectAnalytics adds a script to the page which records search queries
+// which find nothing once the reader stops typing.
+func (server *Server) injectAnalytics(page []byte) []byte {
+	script := new(bytes.Buffer)
+	err := analyticsScript.Execute(script, map[string]string{
+		"Endpoint": strings.TrimSuffix(server.Processor.BasePath, "/") + analyticsPath,
+	})
+	if err != nil {
+		logrus.Errorf("failed to render analytics: %s", err)
+		return page
+	}
+
+	return injectBeforeBodyEnd(page, script.Bytes())
+}
+
+var analyticsScript = template.Must(template.New("analytics").Parse(`
+<script data-endpoint="{{.Endpoint}}">
+  (function() {
+    var endpoint = document.currentScript.dataset.endpoint;
+    var reported = {};
+    var pending = null;
+    var timer = null;
+
+    function report() {
+      clearTimeout(timer);
+
+      var query = pending;
+      pending = null;
+
+      if (query === null || reported[query]) {
+        return;
+      }
+
+      reported[query] = true;
+
+      var body = new Blob([JSON.stringify({ query: query })], { type: "application/json" });
+      navigator.sendBeacon(endpoint, body);
+    }
+
+    // dispatched by the search box as the reader types
+    document.addEventListener("booklit:search", function(event) {
+      var query = event.detail.query.trim();
+
+      clearTimeout(timer);
+      pending = null;
+
+      if (event.detail.results === 0 && query.length > 1) {
+        pending = query;
+        timer = setTimeout(report, 2000);
+      }
+    });
+
+    document.addEventListener("visibilitychange", function() {
+      if (document.visibilityState === "hidden") {
+        report();
+      }
+    });
+  })();
+</script>
+`))
@@ -0,0 +1,46 @@
+package booklitcmd
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render"
+)
+
+// checkAssets warns about each file in --asset-dir which is never referred
+// to by the rendered pages, or deletes them with --prune-assets.
+func (cmd *Command) checkAssets(writer render.Writer, section *booklit.Section) error {
+	unused, err := writer.UnusedAssets(section, cmd.AssetDirs...)
+	if err != nil {
+		return err
+	}
+
+	if cmd.PruneAssets {
+		for _, file := range unused {
+			logrus.WithField("path", file).Info("pruning unused asset")
+
+			err := os.Remove(file)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	warnings := &booklit.Warnings{Strict: cmd.Strict}
+	for _, file := range unused {
+		err := warnings.Record(booklit.UnusedAssetWarning{Path: file})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(warnings.Warnings) > 0 {
+		cmd.diagnostics.RecordWarnings(warnings.Warnings)
+		printCollectedWarnings(warnings)
+	}
+
+	return nil
+}
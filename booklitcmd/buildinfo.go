@@ -0,0 +1,439 @@
+package booklitcmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+const (
+	buildInfoFile     = "build-info.json"
+	sourceArchiveFile = "sources.tar.gz"
+)
+
+// BuildInfo records what a book was built from, so that published output
+// can be traced back to its inputs.
+type BuildInfo struct {
+	Version   string    `json:"version"`
+	GoVersion string    `json:"go_version"`
+	BuiltAt   time.Time `json:"built_at"`
+
+	Git *GitInfo `json:"git,omitempty"`
+
+	Plugins []PluginInfo `json:"plugins"`
+
+	// every file the book was built from, including the config files, which
+	// are left out of the source archive as they may hold secrets, e.g.
+	// --rebuild-token
+	Sources []SourceFile `json:"sources"`
+
+	Archive *SourceFile `json:"archive,omitempty"`
+}
+
+type GitInfo struct {
+	Commit string `json:"commit"`
+
+	// whether there were uncommitted changes at the time of the build
+	Dirty bool `json:"dirty"`
+}
+
+type PluginInfo struct {
+	Package string `json:"package"`
+	Module  string `json:"module,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type SourceFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// writeBuildInfo writes build-info.json and, with --save-source-archive, an
+// archive of every source file but the config files into the destination.
+func (cmd *Command) writeBuildInfo(section *booklit.Section, out string) error {
+	files, err := cmd.sourceFiles(section)
+	if err != nil {
+		return err
+	}
+
+	var archive *SourceFile
+	if cmd.SaveSourceArchive {
+		archive, err = writeSourceArchive(files, filepath.Join(out, sourceArchiveFile), buildTime())
+		if err != nil {
+			return err
+		}
+	}
+
+	if !cmd.SaveBuildInfo {
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path": buildInfoFile,
+	}).Infoln("writing build info")
+
+	info := BuildInfo{
+		Version:   booklit.Version,
+		GoVersion: runtime.Version(),
+		BuiltAt:   buildTime(),
+
+		Git: gitInfo(filepath.Dir(section.FilePath())),
+
+		Plugins: cmd.pluginInfo(),
+		Sources: []SourceFile{},
+
+		Archive: archive,
+	}
+
+	sources := append(cmd.configFiles(), files...)
+	sort.Strings(sources)
+
+	for _, file := range sources {
+		sum, err := sha256File(file)
+		if err != nil {
+			return err
+		}
+
+		info.Sources = append(info.Sources, SourceFile{
+			Path:   archivePath(file),
+			SHA256: sum,
+		})
+	}
+
+	payload, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(out, buildInfoFile), append(payload, '\n'), 0644)
+}
+
+// sourceFiles returns every file the book was built from but the config
+// files: the .lit files and their dependencies, templates, stylesheets, and
+// the source of any plugins outside of the module cache.
+func (cmd *Command) sourceFiles(section *booklit.Section) ([]string, error) {
+	seen := map[string]bool{}
+	files := []string{}
+
+	add := func(file string) {
+		if file == "" || seen[file] {
+			return
+		}
+
+		seen[file] = true
+		files = append(files, file)
+	}
+
+	for _, file := range litFiles(section) {
+		add(file)
+	}
+
+	templateDirs := []string{
+		cmd.HTMLEngine.Templates,
+		cmd.DocBookEngine.Templates,
+		cmd.EPUBEngine.Templates,
+		cmd.LaTeXEngine.Templates,
+		cmd.MarkdownEngine.Templates,
+		cmd.ManpageEngine.Templates,
+		cmd.PDFEngine.Templates,
+		cmd.TexinfoEngine.Templates,
+		cmd.TextEngine.Templates,
+	}
+
+	for _, dir := range templateDirs {
+		if dir == "" {
+			continue
+		}
+
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() {
+				add(path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, stylesheet := range cmd.EPUBEngine.Stylesheets {
+		add(stylesheet)
+	}
+
+	if cmd.LaTeXEngine.Preamble != "" {
+		add(cmd.LaTeXEngine.Preamble)
+	}
+
+	if len(cmd.Plugins) > 0 {
+		packages, err := listPackages(cmd.Plugins)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pkg := range packages {
+			if pkg.Version != "" {
+				// identified by its version in the build info instead
+				continue
+			}
+
+			for _, file := range pkg.GoFiles {
+				add(filepath.Join(pkg.Dir, file))
+			}
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// configFiles returns the config file and its overlay for --env, if they
+// exist.
+func (cmd *Command) configFiles() []string {
+	config := cmd.Config
+	if config == "" {
+		config = defaultConfig
+	}
+
+	configs := []string{config}
+	if cmd.Env != "" {
+		ext := filepath.Ext(config)
+		configs = append(configs, strings.TrimSuffix(config, ext)+"."+string(cmd.Env)+ext)
+	}
+
+	files := []string{}
+	for _, config := range configs {
+		if _, err := os.Stat(config); err == nil {
+			files = append(files, config)
+		}
+	}
+
+	return files
+}
+
+// litFiles returns the .lit files which the section and its children were
+// loaded from, along with their dependencies.
+func litFiles(section *booklit.Section) []string {
+	seen := map[string]bool{}
+	files := []string{}
+
+	var walk func(*booklit.Section)
+	walk = func(section *booklit.Section) {
+		for _, file := range append([]string{section.Path}, section.Dependencies...) {
+			if file != "" && !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+
+		for _, child := range section.Children {
+			walk(child)
+		}
+	}
+
+	walk(section)
+
+	return files
+}
+
+// pluginInfo returns the module and version providing each plugin, as
+// compiled into the running binary.
+func (cmd *Command) pluginInfo() []PluginInfo {
+	plugins := []PluginInfo{}
+
+	build, ok := debug.ReadBuildInfo()
+
+	for _, pkg := range cmd.Plugins {
+		plugin := PluginInfo{
+			Package: pkg,
+		}
+
+		if ok {
+			modules := append([]*debug.Module{&build.Main}, build.Deps...)
+
+			for _, mod := range modules {
+				if mod.Path == "" || (pkg != mod.Path && !strings.HasPrefix(pkg, mod.Path+"/")) {
+					continue
+				}
+
+				// prefer the most specific module, e.g. a nested one
+				if len(mod.Path) < len(plugin.Module) {
+					continue
+				}
+
+				plugin.Module = mod.Path
+				plugin.Version = mod.Version
+
+				if mod.Replace != nil {
+					plugin.Version = mod.Replace.Version
+				}
+			}
+		}
+
+		plugins = append(plugins, plugin)
+	}
+
+	return plugins
+}
+
+// gitInfo returns the commit checked out in the directory, or nil if it is
+// not in a git repository.
+func gitInfo(dir string) *GitInfo {
+	revParse := exec.Command("git", "rev-parse", "HEAD")
+	revParse.Dir = dir
+
+	commit, err := revParse.Output()
+	if err != nil {
+		logrus.WithError(err).Debug("not recording git commit")
+		return nil
+	}
+
+	info := &GitInfo{
+		Commit: strings.TrimSpace(string(commit)),
+	}
+
+	status := exec.Command("git", "status", "--porcelain")
+	status.Dir = dir
+
+	changes, err := status.Output()
+	if err == nil {
+		info.Dirty = len(strings.TrimSpace(string(changes))) > 0
+	}
+
+	return info
+}
+
+// buildTime returns the current time, or the time given by
+// $SOURCE_DATE_EPOCH for reproducible builds.
+func buildTime() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+
+		logrus.WithField("SOURCE_DATE_EPOCH", epoch).Warn("ignoring invalid source date")
+	}
+
+	return time.Now().UTC()
+}
+
+// writeSourceArchive writes a gzipped tarball of the files, returning its
+// path and hash.
+func writeSourceArchive(files []string, path string, modTime time.Time) (*SourceFile, error) {
+	logrus.WithFields(logrus.Fields{
+		"path": path,
+	}).Infoln("writing source archive")
+
+	archive, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer archive.Close()
+
+	hash := sha256.New()
+
+	gz := gzip.NewWriter(io.MultiWriter(archive, hash))
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tw.WriteHeader(&tar.Header{
+			Name:    archivePath(file),
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: modTime,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tw.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SourceFile{
+		Path:   sourceArchiveFile,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// archivePath returns the path to record a file under: relative to the
+// working directory if it is within it, and otherwise absolute without the
+// leading slash.
+func archivePath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		rel, err := filepath.Rel(wd, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	return strings.TrimPrefix(filepath.ToSlash(abs), "/")
+}
+
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("hashing %s failed: %w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
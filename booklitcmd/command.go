@@ -1,126 +1,1164 @@
 package booklitcmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vito/booklit"
 	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/confluence"
+	"github.com/vito/booklit/images"
+	"github.com/vito/booklit/issues"
 	"github.com/vito/booklit/load"
 	"github.com/vito/booklit/render"
+	"github.com/vito/booklit/stages"
 )
 
 type Command struct {
 	Version func() `short:"v" long:"version" description:"Print the version of Boooklit and exit."`
 
-	In  string `long:"in"  short:"i" required:"true" description:"Input .lit file to load."`
+	Config string      `long:"config" description:"Config file providing defaults for flags. Defaults to booklit.yml, if present."`
+	Env    Environment `long:"env"    env:"BOOKLIT_ENV" description:"Environment whose config overlay to apply, e.g. 'production' for booklit.production.yml."`
+
+	In  string `long:"in"  short:"i" description:"Input .lit file to load."`
 	Out string `long:"out" short:"o" description:"Directory into which sections will be rendered."`
 
-	SectionTag  string `long:"section-tag"  description:"Section tag to render."`
-	SectionPath string `long:"section-path" description:"Section path to load and render with --in as its parent."`
+	Renderer string `long:"renderer" choice:"html" choice:"confluence" choice:"docx" choice:"docbook" choice:"epub" choice:"latex" choice:"markdown" choice:"manpage" choice:"pdf" choice:"texinfo" choice:"text" description:"Rendering engine to render with. Defaults to html, or the engine selected by its --X-render flag, which may not conflict with it. The text engine requires --text-file-extension."`
+
+	Books []string `long:"book" description:"Book to build in a workspace, as name=path. Each book is rendered into a sub-directory of --out, and may reference tags from the others."`
+
+	Languages    []string `long:"language"     description:"Translation of the book to build, as locale=path, e.g. ja=ja/index.lit. Each language is rendered into a sub-directory of --out named by its locale. Files missing from a language's directory are built from the first language's instead. Can be specified multiple times."`
+	Translations string   `long:"translations" description:"Directory of translation catalogs used by \\translate, one YAML file of keys and strings per locale, e.g. ja.yml. Strings missing from a locale fall back to the first --language, or --locale."`
+
+	SectionTag  Tag    `long:"section-tag"  description:"Section tag to render."`
+	SectionPath string `long:"section-path" description:"Section path to load and render with --in as its parent."`
+
+	SaveSearchIndex bool `long:"save-search-index" description:"Save a search index JSON file in the destination."`
+	SaveLLMsBundle  bool `long:"save-llms-txt"     description:"Save an llms.txt index and a Markdown file for each chapter in the destination."`
+
+	SaveManifest     bool   `long:"save-manifest"     description:"Save a manifest of each tag's URL in the destination, and redirect tags that moved since the last saved manifest."`
+	PreviousManifest string `long:"previous-manifest" description:"Manifest from a previous build, used for redirecting tags that have since moved."`
+
+	SaveHeaders bool `long:"save-headers" description:"Save a _headers file in the destination, as understood by Netlify and Cloudflare Pages, which caches fingerprinted assets forever and revalidates pages on every request."`
+
+	SaveBuildInfo     bool `long:"save-build-info"     description:"Save a build-info.json file in the destination recording the git commit, build time, and versions of Booklit and each plugin, along with a hash of every source file."`
+	SaveSourceArchive bool `long:"save-source-archive" description:"Save a sources.tar.gz archive of every source file in the destination, including templates and local plugins, but not the config files."`
+
+	FreezeManifest string `long:"freeze-manifest" description:"Manifest of the checksums of the source of each frozen section, e.g. booklit.freeze.json. Builds fail if the source of any section in it has changed."`
+	Freeze         []Tag  `long:"freeze"          description:"Freeze the section with the given tag, e.g. the docs for a released version, recording the checksums of its source in --freeze-manifest. Can be specified multiple times."`
+
+	SaveServiceWorker bool `long:"save-service-worker" description:"Save a service worker in the destination which precaches every page and asset, registered by each page, so the site works offline and loads instantly on repeat visits."`
+
+	SaveCalendar bool `long:"save-calendar" description:"Save an events.ics iCalendar feed of every event in the destination, linked to by each page, so readers can subscribe to them."`
+
+	URLStyle string `long:"url-style" choice:"files" choice:"directories" choice:"extensionless" description:"How pages are named and linked to: tag.html, tag/index.html linked as /tag/, or tag.html linked as tag. Defaults to files."`
+
+	Terminology string `long:"terminology" description:"YAML file of terms which must be cased as given, preferred spellings, and banned terms, which the prose of every section is checked against, reporting each violation as a warning."`
+
+	Flags []string `long:"flag" description:"Flag to enable for conditional content, e.g. enterprise, including the content of \\if-flag for it. Can be specified multiple times."`
+
+	Permalinks []Permalinks `long:"permalinks" description:"Permalinks of the pages beneath the section with the given tag, as tag=strategy, where strategy is flat, nested, date, or a pattern such as {parent}/{year}/{tag}. Pages nested in directories are linked to absolutely. Can be specified multiple times."`
+
+	BasePath string `long:"base-path" description:"Path the site is hosted under, e.g. /docs/. Links to pages and assets are made absolute to it."`
+
+	LinkRewrites []LinkRewrite `long:"rewrite-link" description:"Rule for rewriting the URLs of links, images, and assets, as pattern=replacement, where pattern is a regular expression and replacement may refer to its groups, e.g. $1. Can be specified multiple times."`
+
+	AssetDirs   []string `long:"asset-dir"    description:"Directory of images, stylesheets, and other files used by the book, e.g. ./assets. Files in it which are never referred to by any page or other file are reported as warnings. Can be specified multiple times."`
+	PruneAssets bool     `long:"prune-assets" description:"Delete the files in --asset-dir which are never referred to, rather than warning about them."`
+
+	PostRender []PostRenderHook `long:"post-render" description:"Command to run on each rendered page with the given extension, as ext=command, e.g. 'html=tidy -q -e {file}', failing the build if it exits nonzero. The page's path replaces {file}, or is appended if it's absent. Use * as the extension to run it on every page. Can be specified multiple times."`
+	PostBuild  []CommandLine    `long:"post-build"  unquote:"false" description:"Command to run once the book has been built, e.g. 'vale {out}', failing the build if it exits nonzero. The destination replaces {out}, or is appended if it's absent. Can be specified multiple times."`
+
+	ErrorPageBase string `long:"error-page-base" description:"Path which relative links in error pages, e.g. 404.html, are resolved against. Defaults to --base-path, or /."`
+
+	Jobs int  `long:"jobs" short:"j" description:"Number of files to parse and pages to render at once. Defaults to the number of CPUs."`
+	Race bool `long:"race"           description:"Build Booklit and any plugins with the race detector, reporting data races, e.g. in plugins' content rendered concurrently by --jobs. Requires the Go toolchain."`
+
+	Incremental bool   `long:"incremental" description:"Skip parsing files and rendering pages which are unchanged since the last incremental build, using a cache saved in --cache-dir."`
+	CacheDir    string `long:"cache-dir"   description:"Directory to save the cache for --incremental in. Defaults to .booklit-cache in --out."`
+
+	ServerPort int `long:"serve" short:"s" description:"Start an HTTP server on the given port."`
+
+	RebuildToken   string `long:"rebuild-token"    description:"Enable a POST /rebuild endpoint when serving, authenticated by the given token."`
+	RebuildGitPull bool   `long:"rebuild-git-pull" description:"Run 'git pull' in the input's directory before rebuilding."`
+
+	Edit bool `long:"edit" description:"Include a panel in each page served for editing its .lit source in the browser, saving it to disk and rebuilding. Anyone who can reach the server can edit the files, so only use this for previewing locally."`
+
+	Plugins []string `long:"plugin" short:"p" description:"Package to import, providing a plugin."`
+
+	ExternalPlugins []ExternalPlugin `long:"external-plugin" description:"Plugin implemented by an executable speaking JSON-RPC over stdio, as name=command, e.g. shout=./plugins/shout.py. Can be specified multiple times."`
+
+	Debug bool `long:"debug" short:"d" description:"Log at debug level."`
+
+	Verbose bool `long:"verbose" description:"Log each section as it's parsed, resolved, and rendered, along with how long each stage took."`
+
+	DebugEval    bool `long:"debug-eval"    description:"Log each function invocation along with its arguments, location, and the type of content it returned."`
+	DebugSection Tag  `long:"debug-section" description:"Print the resolved content tree of the section with the given tag to stderr."`
+
+	LogFormat string `long:"log-format" choice:"text" choice:"json" description:"Format to log in. Defaults to text."`
+
+	AccessLog bool `long:"access-log" description:"Log each request when serving."`
+
+	SlowRender   time.Duration `long:"slow-render"   description:"Warn when rendering a page takes longer than the given duration when serving, e.g. 500ms."`
+	BuildTimeout time.Duration `long:"build-timeout" description:"Cancel loading a page which takes longer than the given duration when serving, e.g. 30s, responding with 503 Service Unavailable."`
+
+	Profile bool `long:"profile" description:"Print a report of the time spent in each function after building."`
+
+	Metrics     bool   `long:"metrics"      description:"Expose Prometheus metrics at /metrics when serving."`
+	MetricsFile string `long:"metrics-file" description:"Write Prometheus metrics for the build to the given file."`
+
+	Analytics           bool   `long:"analytics"              description:"Record search queries which find nothing and paths which aren't found when serving, reporting them as JSON at /_booklit/analytics."`
+	AnalyticsToken      string `long:"analytics-token"        description:"Bearer token required to view the report at /_booklit/analytics. Without one, anyone can view it."`
+	AnalyticsLog        string `long:"analytics-log"          description:"File to append each search query and path recorded by --analytics to as a line of JSON, loaded again upon restarting."`
+	AnalyticsLogMaxSize int64  `long:"analytics-log-max-size" description:"Size in bytes at which --analytics-log is moved aside with a .1 suffix and started afresh. Defaults to 10MiB."`
+
+	AllowBrokenReferences bool `long:"allow-broken-references" description:"Replace broken references with a bogus tag."`
+	IgnoreMissingPlugins  bool `long:"ignore-missing-plugins"  description:"Render placeholders for unknown plugins and functions instead of failing."`
+	SanitizeHTML          bool `long:"sanitize-html"           description:"Strip scripts, event handlers, and other unsafe markup from raw HTML generated by plugins, e.g. for building contributed content."`
+	Strict                bool `long:"strict"                  description:"Treat warnings, e.g. for empty sections or deprecated functions, as errors."`
+	HardWraps             bool `long:"hard-wraps"              description:"Preserve line breaks within paragraphs, e.g. for poetry, rather than joining each paragraph's lines."`
+	AttachUnits           bool `long:"attach-units"            description:"Keep units attached to the numbers before them in prose, e.g. '10 km', with non-breaking spaces."`
+
+	Safe            bool  `long:"safe"              description:"Limit the build for processing untrusted input: only plugins declared safe may be used, files outside of the input's directory may not be read, raw HTML is sanitized, and flags which run commands or access the network are rejected. Issues are linked to without being fetched."`
+	MaxIncludeDepth int   `long:"max-include-depth" description:"Maximum depth of sections included via \\include-section with --safe. Defaults to 16."`
+	MaxFileSize     int64 `long:"max-file-size"     description:"Maximum size in bytes of each file read with --safe. Defaults to 1048576."`
+
+	ErrorFormat string `long:"error-format" choice:"text" choice:"json" choice:"sarif" description:"Format to print errors in. Defaults to text. With json, errors are printed to stderr as structured JSON. With sarif, a SARIF 2.1.0 log of the errors and warnings is printed to stdout, even if the build succeeds."`
+
+	// deprecated in favor of --error-format
+	Errors func(string) `long:"errors" choice:"text" choice:"json" choice:"sarif" hidden:"true" description:"Deprecated alias for --error-format."`
+
+	MaxErrors int `long:"max-errors" description:"Keep going after errors, stopping after the given number of them, and print them grouped by file along with a summary by type and file."`
+
+	ErrorReport string `long:"error-report" description:"If the build fails, write a page describing every error to the given HTML file, e.g. for viewing in a browser or publishing from CI."`
+
+	Reports []string `long:"report" value-name:"FORMAT=PATH" description:"Write a report of every error and warning from the build to a file once it's done, e.g. sarif=booklit.sarif for annotating pull requests via code scanning. Only sarif is supported. Can be given multiple times."`
+
+	Locale string `long:"locale" description:"Locale to use when formatting numbers and sorting alphabetically, e.g. en-US."`
+
+	Slugs struct {
+		Transliterate bool   `long:"transliterate" description:"Transliterate letters in titles to ASCII, e.g. 'é' to 'e', rather than dropping them."`
+		Case          string `long:"case" choice:"lower" choice:"preserve" description:"Case of tags generated from titles. Defaults to lower."`
+	} `group:"Tags Generated From Titles" namespace:"slug"`
+
+	HTMLEngine struct {
+		Templates string `long:"templates" description:"Directory containing .tmpl files to load."`
+
+		PDFCommand CommandLine `long:"pdf-command" unquote:"false" description:"Command for converting each page to PDF, with {input} and {output} placeholders."`
+
+		PlaygroundURL string `long:"playground-url" description:"URL of the execution backend which the code of each \\playground is run by, receiving a POST of its language and code as JSON and responding with its output."`
+	} `group:"HTML Rendering Engine" namespace:"html"`
+
+	Diagrams struct {
+		MermaidCommand CommandLine `long:"mermaid-command" unquote:"false" description:"Command for rendering each \\mermaid diagram to SVG at build time, e.g. 'mmdc -i {input} -o {output}'. Without one, HTML renders them in the browser."`
+		DotCommand     CommandLine `long:"dot-command"     unquote:"false" description:"Command for rendering each \\dot diagram to SVG at build time, e.g. 'dot -Tsvg'. Without one, HTML renders them in the browser."`
+	} `group:"Diagrams" namespace:"diagram"`
+
+	Math struct {
+		Command        CommandLine `long:"command"         unquote:"false" description:"Command for rendering each \\math expression to HTML at build time, e.g. 'katex'. Without one, math is converted to MathML."`
+		DisplayCommand CommandLine `long:"display-command" unquote:"false" description:"Command for rendering each \\display-math expression to HTML at build time, e.g. 'katex --display-mode'. Defaults to --math-command."`
+	} `group:"Math" namespace:"math"`
+
+	Images struct {
+		Widths      []int       `long:"width"        description:"Width to resize each PNG and JPEG \\image in --out to, for those which are wider, so that browsers can download the smallest one suited to the screen. Can be specified multiple times."`
+		Sizes       string      `long:"sizes"        description:"Sizes which images are displayed at, as in the HTML sizes attribute, e.g. '(max-width: 48em) 100vw, 48em'. Defaults to the width of the screen."`
+		Quality     int         `long:"quality"      description:"Quality which resized JPEGs are encoded at, from 1 to 100. Defaults to 85."`
+		WebPCommand CommandLine `long:"webp-command" unquote:"false" description:"Command for converting each image and its resized versions to WebP, which browsers that support it prefer, e.g. 'cwebp -quiet {input} -o {output}'."`
+	} `group:"Images" namespace:"image"`
+
+	Confluence struct {
+		Render bool `long:"render" description:"Render pages in Confluence storage format."`
+
+		URL      string `long:"url"       description:"Publish pages to the Confluence instance at the given URL, e.g. https://example.atlassian.net/wiki."`
+		Space    string `long:"space"     description:"Key of the space to publish pages to."`
+		ParentID string `long:"parent-id" description:"ID of the page to publish the root section under."`
+		User     string `long:"user"      description:"User to authenticate as."`
+		Token    string `long:"token"     env:"CONFLUENCE_TOKEN" description:"API token to authenticate with."`
+	} `group:"Confluence" namespace:"confluence"`
+
+	Embeddings struct {
+		URL     string      `long:"url"     description:"OpenAI-compatible embeddings endpoint, e.g. https://api.openai.com/v1/embeddings."`
+		Model   string      `long:"model"   description:"Model to request embeddings from."`
+		Token   string      `long:"token"   env:"EMBEDDINGS_TOKEN" description:"Bearer token to authenticate with."`
+		Command CommandLine `long:"command" unquote:"false" description:"Command which reads a JSON array of texts on stdin and prints a JSON array of embeddings."`
+	} `group:"Search Embeddings" namespace:"embeddings"`
+
+	Issues struct {
+		GitHubToken string        `long:"github-token" env:"GITHUB_TOKEN" description:"Token for fetching the title and state of each GitHub \\issue. Without one, issues are linked to as-is."`
+		GitHubURL   string        `long:"github-url"   description:"URL of a GitHub Enterprise instance to link to and fetch issues from. Defaults to https://github.com."`
+		GitLabToken string        `long:"gitlab-token" env:"GITLAB_TOKEN" description:"Token for fetching the title and state of each GitLab \\issue. Without one, issues are linked to as-is."`
+		GitLabURL   string        `long:"gitlab-url"   description:"URL of a self-hosted GitLab instance to link to and fetch issues from. Defaults to https://gitlab.com."`
+		Cache       string        `long:"cache"        description:"JSON file to save fetched issues to, so that later builds don't fetch them again, e.g. .booklit-issues.json."`
+		MaxAge      time.Duration `long:"max-age"      description:"How long cached issues are used before being fetched again, e.g. 24h. Defaults to forever."`
+	} `group:"Issue Tracker" namespace:"issues"`
+
+	Contributions struct {
+		Repo      string `long:"repo"       description:"GitHub repository which edits made with --edit may be proposed to as a pull request, e.g. vito/booklit. The input must be within a checkout of it."`
+		Base      string `long:"base"       description:"Branch which proposed edits are to be merged into. Defaults to the repository's default branch."`
+		Token     string `long:"token"      env:"GITHUB_TOKEN" description:"Token for pushing branches and opening pull requests with."`
+		GitHubURL string `long:"github-url" description:"URL of a GitHub Enterprise instance to propose edits to. Defaults to https://github.com."`
+	} `group:"Contributions" namespace:"contribute"`
+
+	DocxEngine struct {
+		Render bool `long:"render" description:"Render the book as a single Word document."`
+	} `group:"DOCX Rendering Engine" namespace:"docx"`
+
+	DocBookEngine struct {
+		Render    bool   `long:"render"    description:"Render the book as a single DocBook 5 document."`
+		Templates string `long:"templates" description:"Directory containing .tmpl files to load."`
+	} `group:"DocBook Rendering Engine" namespace:"docbook"`
+
+	EPUBEngine struct {
+		Render      bool     `long:"render"     description:"Render the book as a single EPUB 3 book."`
+		Templates   string   `long:"templates"  description:"Directory containing .tmpl files to load."`
+		Stylesheets []string `long:"stylesheet" description:"Stylesheet to embed in the book. Can be specified multiple times."`
+	} `group:"EPUB Rendering Engine" namespace:"epub"`
+
+	LaTeXEngine struct {
+		Render        bool   `long:"render"         description:"Render the book as a single LaTeX document."`
+		Templates     string `long:"templates"      description:"Directory containing .tmpl files to load."`
+		DocumentClass string `long:"document-class" description:"Document class of the book, e.g. article. Defaults to book."`
+		ClassOptions  string `long:"class-options"  description:"Options for the document class, e.g. a4paper,11pt."`
+		Preamble      string `long:"preamble"       description:"File containing LaTeX to include in the preamble, e.g. \\usepackage commands."`
+	} `group:"LaTeX Rendering Engine" namespace:"latex"`
+
+	MarkdownEngine struct {
+		Render    bool   `long:"render"    description:"Render pages as GitHub Flavored Markdown, e.g. for publishing to a GitHub wiki."`
+		Templates string `long:"templates" description:"Directory containing .tmpl files to load."`
+	} `group:"Markdown Rendering Engine" namespace:"markdown"`
+
+	ManpageEngine struct {
+		Render    bool   `long:"render"    description:"Render pages as man pages, named after their \\man-name and \\man-section."`
+		Templates string `long:"templates" description:"Directory containing .tmpl files to load."`
+	} `group:"Man Page Rendering Engine" namespace:"manpage"`
+
+	PDFEngine struct {
+		Render    bool        `long:"render"    description:"Render the book as a single PDF document."`
+		Templates string      `long:"templates" description:"Directory containing .tmpl files to load."`
+		Command   CommandLine `long:"command"   unquote:"false" description:"Command for converting the rendered HTML to PDF, with {input} and {output} placeholders. Defaults to weasyprint."`
+	} `group:"PDF Rendering Engine" namespace:"pdf"`
+
+	TexinfoEngine struct {
+		Render    bool   `long:"render"    description:"Render the book as a single Texinfo manual."`
+		Templates string `long:"templates" description:"Directory containing .tmpl files to load."`
+	} `group:"Texinfo Rendering Engine" namespace:"texinfo"`
+
+	TextEngine struct {
+		FileExtension string `long:"file-extension" description:"File extension to use for generated files."`
+		Templates     string `long:"templates"      description:"Directory containing .tmpl files to load."`
+	} `group:"Text Rendering Engine" namespace:"text"`
+
+	AST      ASTCommand      `command:"ast"      description:"Print the parsed syntax tree of a .lit file."`
+	Resolve  ResolveCommand  `command:"resolve"  description:"Print the fully resolved content tree of a section."`
+	Syntax   SyntaxCommand   `command:"syntax"   description:"Print a syntax definition for highlighting .lit files in an editor."`
+	Fragment FragmentCommand `command:"fragment" description:"Print the content of a single tag as a fragment, for embedding in another site."`
+	Lint     LintCommand     `command:"lint"     description:"Check a book for broken references and links without rendering it."`
+
+	ExportJSON ExportJSONCommand `command:"export-json" description:"Print the resolved content of a book in the JSON interchange format."`
+	ImportJSON ImportJSONCommand `command:"import-json" description:"Render a book from a document in the JSON interchange format."`
+
+	Completion CompletionCommand `command:"completion" description:"Print a script for completing flags and tags in bash, zsh, or fish."`
+
+	// terminology loaded from --terminology
+	terminology *booklit.Terminology
+
+	// catalogs loaded from --translations
+	translations booklit.Translations
+
+	// checksums loaded from --freeze-manifest
+	frozen FreezeManifest
+
+	// done once the build is interrupted
+	ctx context.Context
+
+	// tracker for \issue, shared by every build so that issues are only
+	// fetched once when serving
+	issueTracker *issues.Tracker
+
+	// pages rendered by the last build, loaded for --incremental
+	buildCache *render.BuildCache
+
+	// chapters of documents rendered by the last build, for --incremental
+	chapterCache *render.ChapterCache
+
+	// progress of builds, logged with each stage's timing
+	logProgress *logProgress
+
+	// errors and warnings from the build, for --report and --error-format sarif
+	diagnostics booklit.Diagnostics
+}
+
+func (cmd *Command) Execute(args []string) error {
+	cmd.configureLogging()
+
+	if cmd.Safe {
+		err := cmd.checkSafe()
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.shouldReexec() {
+		return cmd.reexec()
+	}
+
+	stopWatching := cmd.watchInterrupts()
+	defer stopWatching()
+
+	if cmd.In == "" && len(cmd.Books) == 0 && len(cmd.Languages) == 0 {
+		return fmt.Errorf("either --in, --book, or --language must be specified")
+	}
+
+	if len(cmd.Books) > 0 && len(cmd.Languages) > 0 {
+		return fmt.Errorf("--language is not supported with --book")
+	}
+
+	stop, err := cmd.startExternalPlugins()
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	if cmd.BasePath != "" {
+		if !strings.HasPrefix(cmd.BasePath, "/") {
+			return fmt.Errorf("invalid base path (must start with /): %s", cmd.BasePath)
+		}
+
+		if !strings.HasSuffix(cmd.BasePath, "/") {
+			cmd.BasePath += "/"
+		}
+	}
+
+	if cmd.Terminology != "" {
+		cmd.terminology, err = loadTerminology(cmd.Terminology)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.Translations != "" {
+		cmd.translations, err = loadTranslations(cmd.Translations)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(cmd.Freeze) > 0 && cmd.FreezeManifest == "" {
+		return fmt.Errorf("--freeze requires --freeze-manifest")
+	}
+
+	if cmd.FreezeManifest != "" {
+		cmd.frozen, err = loadFreezeManifest(cmd.FreezeManifest)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.ServerPort != 0 {
+		if len(cmd.Freeze) > 0 {
+			return fmt.Errorf("--freeze is not supported with --serve")
+		}
+
+		if len(cmd.Books) > 0 {
+			return fmt.Errorf("--book is not supported with --serve")
+		}
+
+		if len(cmd.Languages) > 0 {
+			return fmt.Errorf("--language is not supported with --serve")
+		}
+
+		if len(cmd.Reports) > 0 {
+			return fmt.Errorf("--report is not supported with --serve")
+		}
+
+		if cmd.Contributions.Repo != "" {
+			if !cmd.Edit {
+				return fmt.Errorf("--contribute-repo requires --edit")
+			}
+
+			if cmd.Contributions.Token == "" {
+				return fmt.Errorf("--contribute-repo requires --contribute-token")
+			}
+		}
+
+		return cmd.Serve()
+	} else {
+		return cmd.Build()
+	}
+}
+
+func (cmd *Command) configureLogging() {
+	if cmd.Debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	if cmd.LogFormat == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+}
+
+// progress returns the progress which builds are logged with, shared by
+// the processor and the writer so that each stage is tracked once.
+func (cmd *Command) progress() booklit.Progress {
+	if cmd.logProgress == nil {
+		cmd.logProgress = &logProgress{
+			verbose: cmd.Verbose,
+		}
+	}
+
+	return cmd.logProgress
+}
+
+// shouldReexec determines whether plugins are configured which have not yet
+// been compiled in by reexecing, or whether to reexec with the race detector.
+func (cmd *Command) shouldReexec() bool {
+	isReexec := os.Getenv("BOOKLIT_REEXEC") != ""
+	if !isReexec && len(cmd.Plugins) > 0 {
+		logrus.Debug("plugins configured; reexecing")
+		return true
+	}
+
+	if !isReexec && cmd.Race {
+		logrus.Debug("race detector requested; reexecing")
+		return true
+	}
+
+	return false
+}
+
+// processor constructs a processor configured by the command's flags.
+func (cmd *Command) processor() *load.Processor {
+	processor := &load.Processor{
+		AllowBrokenReferences: cmd.AllowBrokenReferences,
+		IgnoreMissingPlugins:  cmd.IgnoreMissingPlugins,
+		Strict:                cmd.Strict,
+		HardWraps:             cmd.HardWraps,
+		AttachUnits:           cmd.AttachUnits,
+		DebugEval:             cmd.DebugEval,
+		MaxErrors:             cmd.MaxErrors,
+		Locale:                cmd.Locale,
+		URLStyle:              booklit.URLStyle(cmd.URLStyle),
+		BasePath:              cmd.BasePath,
+		Flags:                 cmd.Flags,
+		PlaygroundURL:         cmd.HTMLEngine.PlaygroundURL,
+		DiagramCommands:       cmd.diagramCommands(),
+		MathCommand:           cmd.Math.Command,
+		DisplayMathCommand:    cmd.Math.DisplayCommand,
+		Terminology:           cmd.terminology,
+		Translations:          cmd.translations,
+		DefaultLocale:         cmd.defaultLocale(),
+		IssueTracker:          cmd.issues(),
+		ImageProcessor:        cmd.imageProcessor(),
+		Jobs:                  cmd.jobs(),
+		Progress:              cmd.progress(),
+	}
+
+	if cmd.Safe {
+		processor.Safe = cmd.safeMode()
+	}
+
+	for _, permalinks := range cmd.Permalinks {
+		if processor.Permalinks == nil {
+			processor.Permalinks = map[string]booklit.Permalinks{}
+		}
+
+		processor.Permalinks[permalinks.Tag] = permalinks.Strategy
+	}
+
+	for _, rewrite := range cmd.LinkRewrites {
+		processor.LinkRewrites = append(processor.LinkRewrites, booklit.LinkRewrite(rewrite))
+	}
+
+	if cmd.Slugs.Transliterate || cmd.Slugs.Case != "" {
+		processor.Slugifier = &booklit.Slugifier{
+			Transliterate: cmd.Slugs.Transliterate,
+			Case:          booklit.SlugCase(cmd.Slugs.Case),
+		}
+	}
+
+	return processor
+}
+
+// jobs returns the number of files to parse and pages to render at once.
+func (cmd *Command) jobs() int {
+	if cmd.Jobs > 0 {
+		return cmd.Jobs
+	}
+
+	return runtime.NumCPU()
+}
+
+// diagramCommands returns the commands configured by the --diagram-* flags,
+// by the language of the diagrams they render.
+func (cmd *Command) diagramCommands() map[string][]string {
+	commands := map[string][]string{}
+
+	if len(cmd.Diagrams.MermaidCommand) != 0 {
+		commands["mermaid"] = cmd.Diagrams.MermaidCommand
+	}
+
+	if len(cmd.Diagrams.DotCommand) != 0 {
+		commands["dot"] = cmd.Diagrams.DotCommand
+	}
+
+	return commands
+}
+
+// imageProcessor returns the processor configured by the --image-* flags, or
+// nil if images aren't to be processed.
+func (cmd *Command) imageProcessor() booklit.ImageProcessor {
+	if cmd.Out == "" || (len(cmd.Images.Widths) == 0 && len(cmd.Images.WebPCommand) == 0) {
+		return nil
+	}
+
+	return &images.Processor{
+		Dir:         cmd.Out,
+		Widths:      cmd.Images.Widths,
+		Sizes:       cmd.Images.Sizes,
+		Quality:     cmd.Images.Quality,
+		WebPCommand: cmd.Images.WebPCommand,
+	}
+}
+
+// issues returns the tracker configured by the --issues-* flags, constructing
+// it upon the first build.
+//
+// With --safe, issues are linked to without being fetched, as the tokens
+// default to $GITHUB_TOKEN and $GITLAB_TOKEN, which are often set in CI
+// regardless.
+func (cmd *Command) issues() *issues.Tracker {
+	if cmd.issueTracker == nil {
+		githubToken, gitlabToken := cmd.Issues.GitHubToken, cmd.Issues.GitLabToken
+		if cmd.Safe {
+			githubToken, gitlabToken = "", ""
+		}
+
+		cmd.issueTracker = &issues.Tracker{
+			GitHub: issues.Host{
+				URL:   cmd.Issues.GitHubURL,
+				Token: githubToken,
+			},
+			GitLab: issues.Host{
+				URL:   cmd.Issues.GitLabURL,
+				Token: gitlabToken,
+			},
+			Cache:  cmd.Issues.Cache,
+			MaxAge: cmd.Issues.MaxAge,
+			Client: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		}
+	}
+
+	return cmd.issueTracker
+}
+
+func (cmd *Command) Serve() error {
+	processor := cmd.processor()
+
+	engine := render.NewHTMLRenderingEngine()
+	engine.SanitizeHTML = cmd.SanitizeHTML
+	processor.Engine = engine
+
+	server := &Server{
+		In:        cmd.In,
+		Processor: processor,
+
+		Templates:  cmd.HTMLEngine.Templates,
+		Engine:     engine,
+		FileServer: http.FileServer(http.Dir(cmd.Out)),
+
+		SlowRender:   cmd.SlowRender,
+		BuildTimeout: cmd.BuildTimeout,
+	}
+
+	if cmd.RebuildToken != "" {
+		server.Rebuild = &RebuildWebhook{
+			Token:   cmd.RebuildToken,
+			GitPull: cmd.RebuildGitPull,
+		}
+
+		if cmd.Out != "" {
+			server.Rebuild.Build = func() error {
+				_, err := cmd.build(processor)
+				return err
+			}
+		}
+	}
+
+	if cmd.Edit {
+		server.Editor = &Editor{}
+
+		if cmd.Contributions.Repo != "" {
+			server.Editor.Contributions = &Contributions{
+				Repo:  cmd.Contributions.Repo,
+				Base:  cmd.Contributions.Base,
+				Token: cmd.Contributions.Token,
+				URL:   cmd.Contributions.GitHubURL,
+			}
+		}
+
+		if cmd.Out != "" {
+			server.Editor.Build = func() error {
+				_, err := cmd.build(processor)
+				return err
+			}
+		}
+	}
+
+	if cmd.Analytics {
+		server.Analytics = &Analytics{
+			Token:      cmd.AnalyticsToken,
+			Log:        cmd.AnalyticsLog,
+			MaxLogSize: cmd.AnalyticsLogMaxSize,
+		}
+	}
+
+	if cmd.Metrics {
+		server.Metrics = &Metrics{
+			Processor: processor,
+		}
+
+		http.Handle("/metrics", server.Metrics)
+	}
+
+	var handler http.Handler = server
+	if cmd.BasePath != "" {
+		handler = stripBasePath(cmd.BasePath, handler)
+	}
+
+	if cmd.AccessLog || server.Metrics != nil {
+		http.Handle("/", AccessLog{
+			Handler: handler,
+			Log:     cmd.AccessLog,
+			Metrics: server.Metrics,
+		})
+	} else {
+		http.Handle("/", handler)
+	}
+
+	ctx := cmd.context()
+
+	// requests are cancelled once interrupted, so that their builds stop
+	httpServer := &http.Server{
+		Addr: fmt.Sprintf(":%d", cmd.ServerPort),
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		logrus.Info("shutting down")
+
+		_ = httpServer.Shutdown(context.Background())
+	}()
+
+	logrus.WithField("port", cmd.ServerPort).Info("listening")
+
+	err := httpServer.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// watchInterrupts gives the build a context which is done once it's
+// interrupted, returning a function which stops watching for interrupts.
+func (cmd *Command) watchInterrupts() context.CancelFunc {
+	// the first interrupt cancels the build, and the second kills it, e.g. if
+	// a plugin doesn't stop
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+
+	cmd.ctx = ctx
+
+	return cancel
+}
+
+// context returns the context of the build, which is done once it's
+// interrupted.
+func (cmd *Command) context() context.Context {
+	if cmd.ctx == nil {
+		return context.Background()
+	}
+
+	return cmd.ctx
+}
+
+const manifestFile = "manifest.json"
+
+const headersFile = "_headers"
+
+const calendarFile = "events.ics"
+
+const (
+	serviceWorkerFile    = "sw.js"
+	precacheManifestFile = "precache-manifest.json"
+)
+
+var basePluginFactories = []booklit.PluginFactory{
+	baselit.NewPlugin,
+}
+
+func (cmd *Command) Build() error {
+	processor := cmd.processor()
+
+	if cmd.Profile {
+		processor.Profile = &stages.Profile{}
+
+		defer func(start time.Time) {
+			processor.Profile.WriteReport(os.Stderr, time.Since(start))
+		}(time.Now())
+	}
+
+	if cmd.MetricsFile != "" {
+		metrics := &Metrics{
+			Processor: processor,
+		}
+
+		start := time.Now()
+		sections, err := cmd.build(processor)
+		metrics.ObserveBuild(sections, time.Since(start), err)
+
+		metricsErr := cmd.writeMetrics(metrics)
+		if err != nil {
+			return err
+		}
+
+		return metricsErr
+	}
+
+	_, err := cmd.build(processor)
+	return err
+}
+
+func (cmd *Command) writeMetrics(metrics *Metrics) error {
+	file, err := os.Create(cmd.MetricsFile)
+	if err != nil {
+		return err
+	}
+
+	err = metrics.Write(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (cmd *Command) build(processor *load.Processor) ([]*booklit.Section, error) {
+	if cmd.Incremental {
+		return cmd.buildIncrementally(processor)
+	}
+
+	return cmd.buildSections(processor)
+}
+
+func (cmd *Command) buildSections(processor *load.Processor) ([]*booklit.Section, error) {
+	engine, err := cmd.engine()
+	if err != nil {
+		return nil, err
+	}
+
+	if info, ok := engine.(booklit.RenderingEngine); ok {
+		processor.Engine = info
+	}
+
+	if len(cmd.Books) > 0 {
+		return cmd.buildBooks(processor, engine)
+	}
+
+	if len(cmd.Languages) > 0 {
+		return cmd.buildLanguages(processor, engine)
+	}
+
+	section, err := processor.LoadFileContext(cmd.context(), cmd.In, basePluginFactories)
+	if err != nil {
+		return nil, cmd.failedLoad(processor, err)
+	}
+
+	cmd.reportWarnings(processor)
+
+	err = cmd.checkFrozen([]*booklit.Section{section})
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.DebugSection != "" {
+		err = cmd.dumpSection(section)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.Confluence.URL != "" {
+		if cmd.Out != "" {
+			err = cmd.write(processor, engine, section, cmd.Out)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		publisher := confluence.Publisher{
+			URL:      cmd.Confluence.URL,
+			Space:    cmd.Confluence.Space,
+			ParentID: cmd.Confluence.ParentID,
+			User:     cmd.Confluence.User,
+			Token:    cmd.Confluence.Token,
+			Engine:   engine,
+		}
+
+		return []*booklit.Section{section}, publisher.Publish(section)
+	}
+
+	return []*booklit.Section{section}, cmd.write(processor, engine, section, cmd.Out)
+}
+
+func (cmd *Command) dumpSection(section *booklit.Section) error {
+	tags := section.FindTag(string(cmd.DebugSection))
+	if len(tags) == 0 {
+		return fmt.Errorf("unknown tag: %s", cmd.DebugSection)
+	}
+
+	return booklit.Dump(os.Stderr, tags[0].Section)
+}
+
+// renderer returns the name of the rendering engine selected by --renderer or
+// the --X-render flags, defaulting to html. Only one may be selected.
+func (cmd *Command) renderer() (string, error) {
+	selectors := []struct {
+		renderer string
+		flag     string
+		set      bool
+	}{
+		{"confluence", "--confluence-render", cmd.Confluence.Render},
+		{"confluence", "--confluence-url", cmd.Confluence.URL != ""},
+		{"docx", "--docx-render", cmd.DocxEngine.Render},
+		{"docbook", "--docbook-render", cmd.DocBookEngine.Render},
+		{"epub", "--epub-render", cmd.EPUBEngine.Render},
+		{"latex", "--latex-render", cmd.LaTeXEngine.Render},
+		{"markdown", "--markdown-render", cmd.MarkdownEngine.Render},
+		{"manpage", "--manpage-render", cmd.ManpageEngine.Render},
+		{"pdf", "--pdf-render", cmd.PDFEngine.Render},
+		{"texinfo", "--texinfo-render", cmd.TexinfoEngine.Render},
+		{"text", "--text-file-extension", cmd.TextEngine.FileExtension != ""},
+	}
+
+	renderer := "html"
+	selectedBy := ""
+	if cmd.Renderer != "" {
+		renderer = cmd.Renderer
+		selectedBy = "--renderer " + cmd.Renderer
+	}
+
+	for _, selector := range selectors {
+		if !selector.set {
+			continue
+		}
+
+		if selectedBy != "" && selector.renderer != renderer {
+			return "", fmt.Errorf("only one renderer may be selected, but %s and %s select different ones", selectedBy, selector.flag)
+		}
+
+		renderer = selector.renderer
+		selectedBy = selector.flag
+	}
+
+	if renderer == "text" && cmd.TextEngine.FileExtension == "" {
+		return "", fmt.Errorf("--renderer text requires --text-file-extension")
+	}
+
+	return renderer, nil
+}
+
+func (cmd *Command) engine() (render.RenderingEngine, error) {
+	renderer, err := cmd.renderer()
+	if err != nil {
+		return nil, err
+	}
+
+	switch renderer {
+	case "confluence":
+		return render.NewConfluenceRenderingEngine(), nil
+
+	case "docx":
+		return render.NewDocxRenderingEngine(), nil
 
-	SaveSearchIndex bool `long:"save-search-index" description:"Save a search index JSON file in the destination."`
+	case "docbook":
+		docbookEngine := render.NewDocBookRenderingEngine()
+		docbookEngine.SanitizeHTML = cmd.SanitizeHTML
 
-	ServerPort int `long:"serve" short:"s" description:"Start an HTTP server on the given port."`
+		if cmd.DocBookEngine.Templates != "" {
+			err := docbookEngine.LoadTemplates(cmd.DocBookEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	Plugins []string `long:"plugin" short:"p" description:"Package to import, providing a plugin."`
+		return docbookEngine, nil
 
-	Debug bool `long:"debug" short:"d" description:"Log at debug level."`
+	case "epub":
+		epubEngine := render.NewEPUBRenderingEngine()
+		epubEngine.SanitizeHTML = cmd.SanitizeHTML
+		epubEngine.Stylesheets = cmd.EPUBEngine.Stylesheets
 
-	AllowBrokenReferences bool `long:"allow-broken-references" description:"Replace broken references with a bogus tag."`
+		if cmd.EPUBEngine.Templates != "" {
+			err := epubEngine.LoadTemplates(cmd.EPUBEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	HTMLEngine struct {
-		Templates string `long:"templates" description:"Directory containing .tmpl files to load."`
-	} `group:"HTML Rendering Engine" namespace:"html"`
+		return epubEngine, nil
 
-	TextEngine struct {
-		FileExtension string `long:"file-extension" description:"File extension to use for generated files."`
-		Templates     string `long:"templates"      description:"Directory containing .tmpl files to load."`
-	} `group:"Text Rendering Engine" namespace:"text"`
-}
+	case "latex":
+		latexEngine := render.NewLaTeXRenderingEngine()
+		latexEngine.DocumentClass = cmd.LaTeXEngine.DocumentClass
+		latexEngine.ClassOptions = cmd.LaTeXEngine.ClassOptions
 
-func (cmd *Command) Execute(args []string) error {
-	if cmd.Debug {
-		logrus.SetLevel(logrus.DebugLevel)
-	}
+		if cmd.LaTeXEngine.Preamble != "" {
+			preamble, err := ioutil.ReadFile(cmd.LaTeXEngine.Preamble)
+			if err != nil {
+				return nil, err
+			}
 
-	isReexec := os.Getenv("BOOKLIT_REEXEC") != ""
-	if !isReexec && len(cmd.Plugins) > 0 {
-		logrus.Debug("plugins configured; reexecing")
-		return cmd.reexec()
-	}
+			latexEngine.Preamble = string(preamble)
+		}
 
-	if cmd.ServerPort != 0 {
-		return cmd.Serve()
-	} else {
-		return cmd.Build()
-	}
-}
+		if cmd.LaTeXEngine.Templates != "" {
+			err := latexEngine.LoadTemplates(cmd.LaTeXEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-func (cmd *Command) Serve() error {
-	http.Handle("/", &Server{
-		In: cmd.In,
-		Processor: &load.Processor{
-			AllowBrokenReferences: cmd.AllowBrokenReferences,
-		},
+		return latexEngine, nil
 
-		Templates:  cmd.HTMLEngine.Templates,
-		Engine:     render.NewHTMLRenderingEngine(),
-		FileServer: http.FileServer(http.Dir(cmd.Out)),
-	})
+	case "markdown":
+		markdownEngine := render.NewMarkdownRenderingEngine()
 
-	logrus.WithField("port", cmd.ServerPort).Info("listening")
+		if cmd.MarkdownEngine.Templates != "" {
+			err := markdownEngine.LoadTemplates(cmd.MarkdownEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	return http.ListenAndServe(fmt.Sprintf(":%d", cmd.ServerPort), nil)
-}
+		return markdownEngine, nil
 
-var basePluginFactories = []booklit.PluginFactory{
-	baselit.NewPlugin,
-}
+	case "manpage":
+		manpageEngine := render.NewManpageRenderingEngine()
 
-func (cmd *Command) Build() error {
-	processor := &load.Processor{
-		AllowBrokenReferences: cmd.AllowBrokenReferences,
-	}
+		if cmd.ManpageEngine.Templates != "" {
+			err := manpageEngine.LoadTemplates(cmd.ManpageEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return manpageEngine, nil
+
+	case "pdf":
+		pdfEngine := render.NewPDFRenderingEngine()
+		pdfEngine.SanitizeHTML = cmd.SanitizeHTML
+
+		if len(cmd.PDFEngine.Command) != 0 {
+			pdfEngine.Converter.Command = cmd.PDFEngine.Command
+		}
 
-	var engine render.RenderingEngine
-	if cmd.TextEngine.FileExtension != "" {
+		if cmd.PDFEngine.Templates != "" {
+			err := pdfEngine.LoadTemplates(cmd.PDFEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return pdfEngine, nil
+
+	case "texinfo":
+		texinfoEngine := render.NewTexinfoRenderingEngine()
+
+		if cmd.TexinfoEngine.Templates != "" {
+			err := texinfoEngine.LoadTemplates(cmd.TexinfoEngine.Templates)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return texinfoEngine, nil
+
+	case "text":
 		textEngine := render.NewTextRenderingEngine(cmd.TextEngine.FileExtension)
 
 		if cmd.TextEngine.Templates != "" {
 			err := textEngine.LoadTemplates(cmd.TextEngine.Templates)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
-		engine = textEngine
-	} else {
-		htmlEngine := render.NewHTMLRenderingEngine()
+		return textEngine, nil
+	}
 
-		if cmd.HTMLEngine.Templates != "" {
-			err := htmlEngine.LoadTemplates(cmd.HTMLEngine.Templates)
-			if err != nil {
-				return err
-			}
+	htmlEngine := render.NewHTMLRenderingEngine()
+	htmlEngine.SanitizeHTML = cmd.SanitizeHTML
+
+	if cmd.HTMLEngine.Templates != "" {
+		err := htmlEngine.LoadTemplates(cmd.HTMLEngine.Templates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return htmlEngine, nil
+}
+
+func (cmd *Command) embedder() render.Embedder {
+	if len(cmd.Embeddings.Command) != 0 {
+		return render.CommandEmbedder{
+			Command: cmd.Embeddings.Command,
+		}
+	}
+
+	if cmd.Embeddings.URL != "" {
+		return render.HTTPEmbedder{
+			URL:   cmd.Embeddings.URL,
+			Model: cmd.Embeddings.Model,
+			Token: cmd.Embeddings.Token,
+		}
+	}
+
+	return nil
+}
+
+func (cmd *Command) buildBooks(processor *load.Processor, engine render.RenderingEngine) ([]*booklit.Section, error) {
+	if cmd.Out == "" {
+		return nil, fmt.Errorf("--out must be specified when building multiple books")
+	}
+
+	names := []string{}
+	paths := []string{}
+	for _, book := range cmd.Books {
+		segs := strings.SplitN(book, "=", 2)
+		if len(segs) != 2 {
+			return nil, fmt.Errorf("invalid book (expected name=path): %s", book)
 		}
 
-		engine = htmlEngine
+		names = append(names, segs[0])
+		paths = append(paths, segs[1])
 	}
 
-	section, err := processor.LoadFile(cmd.In, basePluginFactories)
+	books, err := processor.LoadBooksContext(cmd.context(), paths, basePluginFactories)
 	if err != nil {
-		return err
+		return nil, cmd.failedLoad(processor, err)
+	}
+
+	cmd.reportWarnings(processor)
+
+	err = cmd.checkFrozen(books)
+	if err != nil {
+		return nil, err
+	}
+
+	return books, cmd.writeBooks(processor, engine, books, names)
+}
+
+func (cmd *Command) buildLanguages(processor *load.Processor, engine render.RenderingEngine) ([]*booklit.Section, error) {
+	if cmd.Out == "" {
+		return nil, fmt.Errorf("--out must be specified when building multiple languages")
+	}
+
+	names := []string{}
+	langs := []load.Language{}
+	for _, language := range cmd.Languages {
+		segs := strings.SplitN(language, "=", 2)
+		if len(segs) != 2 {
+			return nil, fmt.Errorf("invalid language (expected locale=path): %s", language)
+		}
+
+		names = append(names, segs[0])
+		langs = append(langs, load.Language{
+			Locale: segs[0],
+			Path:   segs[1],
+		})
+	}
+
+	books, err := processor.LoadLanguagesContext(cmd.context(), langs, basePluginFactories)
+	if err != nil {
+		return nil, cmd.failedLoad(processor, err)
+	}
+
+	cmd.reportWarnings(processor)
+
+	err = cmd.checkFrozen(books)
+	if err != nil {
+		return nil, err
+	}
+
+	return books, cmd.writeBooks(processor, engine, books, names)
+}
+
+// writeBooks renders each book into the sub-directory of --out with its
+// name, linking to the others' pages accordingly.
+func (cmd *Command) writeBooks(processor *load.Processor, engine render.RenderingEngine, books []*booklit.Section, names []string) error {
+	for i, book := range books {
+		if book.BasePath != "" {
+			book.URLPrefix = book.BasePath + names[i] + "/"
+		} else if book.URLStyle == booklit.URLStyleDirectories || book.NestedPermalinks {
+			// pages are in sub-directories, so link absolutely
+			book.URLPrefix = "/" + names[i] + "/"
+		} else {
+			book.URLPrefix = "../" + names[i] + "/"
+		}
+	}
+
+	for i, book := range books {
+		err := cmd.write(processor, engine, book, filepath.Join(cmd.Out, names[i]))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defaultLocale is the locale translations fall back to: the first
+// --language's, or --locale.
+func (cmd *Command) defaultLocale() string {
+	if len(cmd.Languages) > 0 {
+		return strings.SplitN(cmd.Languages[0], "=", 2)[0]
 	}
 
+	return cmd.Locale
+}
+
+func (cmd *Command) write(processor *load.Processor, engine render.RenderingEngine, section *booklit.Section, out string) error {
+	var err error
+
 	sectionToRender := section
 	if cmd.SectionTag != "" {
-		tags := section.FindTag(cmd.SectionTag)
+		tags := section.FindTag(string(cmd.SectionTag))
 		if len(tags) == 0 {
 			return fmt.Errorf("unknown tag: %s", cmd.SectionTag)
 		}
@@ -133,18 +1171,71 @@ func (cmd *Command) Build() error {
 		}
 	}
 
-	if cmd.Out == "" {
+	if out == "" {
 		return engine.RenderSection(os.Stdout, sectionToRender)
 	}
 
-	err = os.MkdirAll(cmd.Out, 0755)
+	err = os.MkdirAll(out, 0755)
 	if err != nil {
 		return err
 	}
 
+	// relative paths to assets are resolved against the destination
+	switch documentEngine := engine.(type) {
+	case *render.PDFRenderingEngine:
+		documentEngine.Directory = out
+		documentEngine.Chapters = cmd.chapterCache
+	case *render.EPUBRenderingEngine:
+		documentEngine.Directory = out
+		documentEngine.Chapters = cmd.chapterCache
+	case *render.LaTeXRenderingEngine:
+		documentEngine.Chapters = cmd.chapterCache
+	}
+
 	writer := render.Writer{
 		Engine:      engine,
-		Destination: cmd.Out,
+		Destination: out,
+
+		ErrorPageBase: cmd.ErrorPageBase,
+
+		Jobs:  cmd.jobs(),
+		Cache: cmd.buildCache,
+
+		Hooks: cmd.hooks(),
+
+		Progress: cmd.progress(),
+
+		// the same time \today and \time-ago were evaluated as of
+		Now: section.Top().BuildTime,
+	}
+
+	_, isDocument := engine.(render.DocumentRenderingEngine)
+
+	if cmd.SaveServiceWorker && !isDocument {
+		writer.ServiceWorker = serviceWorkerFile
+	}
+
+	if cmd.SaveCalendar && !isDocument {
+		writer.Calendar = calendarFile
+	}
+
+	var previousManifest render.Manifest
+	if cmd.PreviousManifest != "" {
+		previousManifest, err = render.LoadManifest(cmd.PreviousManifest)
+		if err != nil {
+			return err
+		}
+	} else if cmd.SaveManifest {
+		previousManifest, err = render.LoadManifest(filepath.Join(out, manifestFile))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if len(cmd.HTMLEngine.PDFCommand) != 0 {
+		writer.PDF = &render.PDFConverter{
+			Command: cmd.HTMLEngine.PDFCommand,
+		}
 	}
 
 	err = writer.WriteSection(sectionToRender)
@@ -152,16 +1243,108 @@ func (cmd *Command) Build() error {
 		return err
 	}
 
+	if !isDocument {
+		err = writer.WriteErrorPages(section)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(cmd.AssetDirs) > 0 && !isDocument {
+		err = cmd.checkAssets(writer, section)
+		if err != nil {
+			return err
+		}
+	}
+
 	if cmd.SaveSearchIndex {
 		err = writer.WriteSearchIndex(section, "search_index.json")
 		if err != nil {
 			return err
 		}
+
+		if embedder := cmd.embedder(); embedder != nil {
+			err = writer.WriteSearchEmbeddings(section, "search_embeddings.json", embedder)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if cmd.SaveLLMsBundle {
+		err = writer.WriteLLMsBundle(section)
+		if err != nil {
+			return err
+		}
+	}
+
+	// sections' aliases are redirected even without a previous manifest
+	if previousManifest != nil || !isDocument {
+		err = writer.WriteRedirects(section, previousManifest)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.SaveManifest {
+		err = writer.WriteManifest(section, manifestFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.SaveHeaders {
+		err = writer.WriteHeaders(section, headersFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.SaveBuildInfo || cmd.SaveSourceArchive {
+		err = cmd.writeBuildInfo(section, out)
+		if err != nil {
+			return err
+		}
+	}
+
+	if writer.ServiceWorker != "" {
+		err = writer.WriteServiceWorker(section, serviceWorkerFile, precacheManifestFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if writer.Calendar != "" {
+		err = writer.WriteCalendar(section, calendarFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, command := range cmd.PostBuild {
+		logrus.WithFields(logrus.Fields{
+			"command": command,
+		}).Info("running post-build hook")
+
+		err = render.RunHook(cmd.context(), command, "{out}", out, writer.Pages(section))
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// hooks returns the commands to run on each rendered page.
+func (cmd *Command) hooks() []render.Hook {
+	hooks := []render.Hook{}
+	for _, hook := range cmd.PostRender {
+		hooks = append(hooks, render.Hook(hook))
+	}
+
+	return hooks
+}
+
 func (cmd *Command) reexec() error {
 	tmpdir, err := ioutil.TempDir("", "booklit-reexec")
 	if err != nil {
@@ -189,7 +1372,12 @@ func (cmd *Command) reexec() error {
 		return err
 	}
 
-	build := exec.Command("go", "install", src)
+	install := []string{"install"}
+	if cmd.Race {
+		install = append(install, "-race")
+	}
+
+	build := exec.Command("go", append(install, src)...)
 	build.Env = append(os.Environ(), "GOBIN="+tmpdir)
 	build.Stdout = os.Stdout
 	build.Stderr = os.Stderr
@@ -220,3 +1408,62 @@ func (cmd *Command) reexec() error {
 
 	return nil
 }
+
+// LinkRewrite is a rule for rewriting URLs, given as pattern=replacement.
+// Permalinks configures the permalinks of the pages beneath the section with
+// the given tag.
+type Permalinks struct {
+	Tag      string
+	Strategy booklit.Permalinks
+}
+
+func (permalinks *Permalinks) UnmarshalFlag(value string) error {
+	segs := strings.SplitN(value, "=", 2)
+	if len(segs) != 2 {
+		return fmt.Errorf("invalid permalinks (expected tag=strategy): %s", value)
+	}
+
+	strategy, err := booklit.ParsePermalinks(segs[1])
+	if err != nil {
+		return err
+	}
+
+	permalinks.Tag = segs[0]
+	permalinks.Strategy = strategy
+
+	return nil
+}
+
+// PostRenderHook is a command to run on each rendered page with an
+// extension, given as ext=command.
+type PostRenderHook render.Hook
+
+func (hook *PostRenderHook) UnmarshalFlag(value string) error {
+	segs := strings.SplitN(value, "=", 2)
+	if len(segs) != 2 || segs[0] == "" || strings.TrimSpace(segs[1]) == "" {
+		return fmt.Errorf("invalid post-render hook (expected ext=command): %s", value)
+	}
+
+	hook.Extension = strings.TrimPrefix(segs[0], ".")
+	command, err := splitCommand(segs[1])
+	if err != nil {
+		return err
+	}
+
+	hook.Command = command
+
+	return nil
+}
+
+type LinkRewrite booklit.LinkRewrite
+
+func (rewrite *LinkRewrite) UnmarshalFlag(value string) error {
+	parsed, err := booklit.ParseLinkRewrite(value)
+	if err != nil {
+		return err
+	}
+
+	*rewrite = LinkRewrite(parsed)
+
+	return nil
+}
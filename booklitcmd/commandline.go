@@ -0,0 +1,107 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommandLine is a command given as a single flag, e.g. 'mmdc -i {input} -o
+// {output}', split into its arguments the way a shell would, so that quoted
+// arguments and paths with spaces stay together.
+type CommandLine []string
+
+func (command *CommandLine) UnmarshalFlag(value string) error {
+	args, err := splitCommand(value)
+	if err != nil {
+		return err
+	}
+
+	*command = args
+
+	return nil
+}
+
+// splitCommand splits a command into its arguments by whitespace, as a shell
+// would: single quotes preserve everything between them, double quotes
+// preserve everything but backslash escapes of \, ", $, and `, and a backslash
+// outside of quotes escapes the character after it. Variables and globs are
+// not expanded.
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+
+	arg := new(strings.Builder)
+	inArg := false
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("trailing backslash in command: %s", command)
+			}
+
+			i++
+			inArg = true
+
+			// a backslash-newline continues the line
+			if runes[i] != '\n' {
+				arg.WriteRune(runes[i])
+			}
+
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated quote in command: %s", command)
+			}
+
+			arg.WriteString(string(runes[i+1 : end]))
+			inArg = true
+			i = end
+
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`", runes[i+1]) {
+					i++
+				}
+
+				arg.WriteRune(runes[i])
+			}
+
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated quote in command: %s", command)
+			}
+
+			inArg = true
+
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,197 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/vito/booklit/render"
+)
+
+// CompletionCommand prints a script which completes booklit's flags,
+// subcommands, config environments, and section tags in the given shell.
+//
+// Completions are computed by booklit itself, using go-flags' completion
+// mode, so the script never needs to be regenerated as flags change.
+type CompletionCommand struct {
+	Args struct {
+		Shell Shell `positional-arg-name:"shell" description:"Shell to print the completion script for: bash, zsh, or fish."`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (cmd *CompletionCommand) Execute(args []string) error {
+	script, found := completionScripts[cmd.Args.Shell]
+	if !found {
+		return fmt.Errorf("unknown shell: %s", cmd.Args.Shell)
+	}
+
+	_, err := fmt.Fprint(os.Stdout, script)
+	return err
+}
+
+// Shell is the name of a shell for which a completion script can be printed.
+type Shell string
+
+func (Shell) Complete(match string) []flags.Completion {
+	shells := []string{}
+	for shell := range completionScripts {
+		shells = append(shells, string(shell))
+	}
+
+	sort.Strings(shells)
+
+	return completeMatching(shells, match)
+}
+
+// Environment is the name of a config overlay, completed from the overlays
+// alongside the config file, e.g. 'production' for booklit.production.yml.
+type Environment string
+
+func (Environment) Complete(match string) []flags.Completion {
+	config := completionFlag("config", "")
+	if config == "" {
+		config = defaultConfig
+	}
+
+	ext := filepath.Ext(config)
+	prefix := strings.TrimSuffix(config, ext) + "."
+
+	overlays, _ := filepath.Glob(prefix + "*" + ext)
+
+	envs := []string{}
+	for _, overlay := range overlays {
+		envs = append(envs, strings.TrimSuffix(strings.TrimPrefix(overlay, prefix), ext))
+	}
+
+	return completeMatching(envs, match)
+}
+
+// Tag is the name of a section's tag, completed from the manifest saved in
+// --out by --save-manifest.
+type Tag string
+
+func (Tag) Complete(match string) []flags.Completion {
+	out := completionFlag("out", "o")
+	if out == "" {
+		configArgs, err := configArgs(completionFlag("config", ""), completionFlag("env", ""))
+		if err != nil {
+			return nil
+		}
+
+		out = flagValue(configArgs, "out", "o")
+	}
+
+	if out == "" {
+		return nil
+	}
+
+	manifest, err := render.LoadManifest(filepath.Join(out, manifestFile))
+	if err != nil {
+		return nil
+	}
+
+	tags := []string{}
+	for tag := range manifest {
+		tags = append(tags, tag)
+	}
+
+	sort.Strings(tags)
+
+	return completeMatching(tags, match)
+}
+
+func completeMatching(items []string, match string) []flags.Completion {
+	completions := []flags.Completion{}
+	for _, item := range items {
+		if strings.HasPrefix(item, match) {
+			completions = append(completions, flags.Completion{Item: item})
+		}
+	}
+
+	return completions
+}
+
+// completionFlag returns the value of a flag given on the command line being
+// completed, since completion happens before flags are parsed.
+func completionFlag(long string, short string) string {
+	return flagValue(os.Args[1:], long, short)
+}
+
+// flagValue returns the last value given for a flag in the arguments, or ""
+// if it was not given.
+func flagValue(args []string, long string, short string) string {
+	names := []string{"--" + long}
+	if short != "" {
+		names = append(names, "-"+short)
+	}
+
+	var value string
+	for i, arg := range args {
+		for _, name := range names {
+			switch {
+			case arg == name && i+1 < len(args):
+				value = args[i+1]
+			case strings.HasPrefix(arg, name+"="):
+				value = strings.TrimPrefix(arg, name+"=")
+			}
+		}
+	}
+
+	return value
+}
+
+var completionScripts = map[Shell]string{
+	"bash": `# bash completion for booklit
+#
+# add to ~/.bashrc:
+#
+#   source <(booklit completion bash)
+
+_booklit() {
+  local args=("${COMP_WORDS[@]:1:$COMP_CWORD}")
+  local IFS=$'\n'
+  COMPREPLY=($(GO_FLAGS_COMPLETION=1 "${COMP_WORDS[0]}" "${args[@]}"))
+  return 0
+}
+
+complete -o default -F _booklit booklit
+`,
+
+	"zsh": `# zsh completion for booklit
+#
+# add to ~/.zshrc:
+#
+#   source <(booklit completion zsh)
+
+_booklit() {
+  local -a completions
+  completions=("${(@f)$(GO_FLAGS_COMPLETION=1 "${words[1]}" "${(@)words[2,CURRENT]}")}")
+
+  if [[ -n "${completions[*]}" ]]; then
+    compadd -a completions
+  else
+    _files
+  fi
+}
+
+compdef _booklit booklit
+`,
+
+	"fish": `# fish completion for booklit
+#
+# save to ~/.config/fish/completions/booklit.fish:
+#
+#   booklit completion fish > ~/.config/fish/completions/booklit.fish
+
+function __booklit_complete
+  set -l args (commandline -opc)
+  set -e args[1]
+  env GO_FLAGS_COMPLETION=1 booklit $args (commandline -ct)
+end
+
+complete -c booklit -a '(__booklit_complete)'
+`,
+}
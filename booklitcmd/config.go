@@ -0,0 +1,121 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultConfig = "booklit.yml"
+
+// configArgs loads the config file and its overlay for the given
+// environment, e.g. booklit.production.yml, converting them to flags.
+//
+// Each key corresponds to a flag's long name, e.g. 'out' for --out. Nested
+// keys are joined with '-', so 'html: {templates: ./html}' becomes
+// --html-templates.
+func configArgs(path string, env string) ([]string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfig
+	}
+
+	config := map[string]interface{}{}
+
+	err := loadConfig(path, config)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			if env == "" {
+				return nil, nil
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	if env != "" {
+		ext := filepath.Ext(path)
+		overlay := strings.TrimSuffix(path, ext) + "." + env + ext
+
+		err := loadConfig(overlay, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keys := []string{}
+	for key := range config {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	args := []string{}
+	for _, key := range keys {
+		switch val := config[key].(type) {
+		case bool:
+			if val {
+				args = append(args, "--"+key)
+			}
+		case []interface{}:
+			for _, v := range val {
+				args = append(args, fmt.Sprintf("--%s=%v", key, v))
+			}
+		case nil:
+		default:
+			args = append(args, fmt.Sprintf("--%s=%v", key, val))
+		}
+	}
+
+	return args, nil
+}
+
+// loadConfig merges the config file into the given flattened config, with
+// values from the file taking precedence.
+func loadConfig(path string, config map[string]interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	err = yaml.Unmarshal(content, &values)
+	if err != nil {
+		return fmt.Errorf("invalid config %s: %s", path, err)
+	}
+
+	return flattenConfig("", values, config)
+}
+
+func flattenConfig(prefix string, values map[string]interface{}, config map[string]interface{}) error {
+	for key, val := range values {
+		flag := prefix + key
+
+		switch v := val.(type) {
+		case map[interface{}]interface{}:
+			nested := map[string]interface{}{}
+			for k, nv := range v {
+				str, ok := k.(string)
+				if !ok {
+					return fmt.Errorf("invalid config key under %s: %v", flag, k)
+				}
+
+				nested[str] = nv
+			}
+
+			err := flattenConfig(flag+"-", nested, config)
+			if err != nil {
+				return err
+			}
+		default:
+			config[flag] = val
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,518 @@
+package booklitcmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// contributePath is the path of the endpoint which the editing panel proposes
+// the edits made with it through.
+const contributePath = "/_booklit/contribute"
+
+const defaultGitHubURL = "https://github.com"
+
+// Contributions configures proposing the edits made with the editing panel as
+// a GitHub pull request. The edits are committed on top of the input's
+// checkout and pushed to a new branch, leaving the checkout itself
+// unchanged.
+type Contributions struct {
+	// Repository to open pull requests against, e.g. vito/booklit.
+	Repo string
+
+	// Branch to merge pull requests into; defaults to the repository's
+	// default branch.
+	Base string
+
+	// Token to push branches and open pull requests with.
+	Token string
+
+	// URL of GitHub, or of a GitHub Enterprise instance; defaults to
+	// https://github.com.
+	URL string
+
+	Client *http.Client
+}
+
+type contribution struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type pullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+}
+
+// changedSection is a section with lines which were changed by an edit.
+type changedSection struct {
+	Title string
+	Path  string
+}
+
+// serveContribute commits the files edited since the last contribution to a
+// new branch and opens a pull request for it, listing the sections changed.
+// The edited files are then restored, so that the next contribution starts
+// afresh.
+//
+// Like saving, it requires a JSON request so that pages elsewhere can't
+// propose edits.
+func (server *Server) serveContribute(w http.ResponseWriter, r *http.Request) {
+	log := logrus.WithFields(logrus.Fields{
+		"request": r.URL.Path,
+	})
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeEditResponse(w, http.StatusUnsupportedMediaType, editError{"expected JSON"})
+		return
+	}
+
+	var req contribution
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeEditResponse(w, http.StatusBadRequest, editError{fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	server.buildLock.Lock()
+	defer server.buildLock.Unlock()
+
+	top, err := git(filepath.Dir(server.In), nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		log.Errorf("failed to find repository: %s", err)
+		writeEditResponse(w, http.StatusInternalServerError, editError{err.Error()})
+		return
+	}
+
+	files := []string{}
+	changedLines := map[string][]int{}
+	for _, path := range server.Editor.editedFiles() {
+		lines, err := changedLinesOf(top, path)
+		if err != nil {
+			log.Errorf("failed to diff %s: %s", path, err)
+			writeEditResponse(w, http.StatusInternalServerError, editError{err.Error()})
+			return
+		}
+
+		if len(lines) == 0 {
+			// edited back to how it was
+			continue
+		}
+
+		files = append(files, path)
+		changedLines[path] = lines
+	}
+
+	if len(files) == 0 {
+		writeEditResponse(w, http.StatusBadRequest, editError{"no changes to propose"})
+		return
+	}
+
+	var sections []changedSection
+	root, err := server.loadRoot(r.Context())
+	if err != nil {
+		// the pull request can still be opened, e.g. to fix the error
+		log.Warnf("failed to load changed sections: %s", err)
+	} else {
+		sections = changedSections(root, top, changedLines)
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = "Update " + strings.Join(relativePaths(top, files), ", ")
+	}
+
+	contributions := server.Editor.Contributions
+
+	log = log.WithField("repo", contributions.Repo)
+
+	log.Info("committing")
+
+	commit, err := commitFiles(top, files, title)
+	if err != nil {
+		log.Errorf("failed to commit: %s", err)
+		writeEditResponse(w, http.StatusInternalServerError, editError{err.Error()})
+		return
+	}
+
+	base := contributions.Base
+	if base == "" {
+		base, err = contributions.defaultBranch()
+		if err != nil {
+			log.Errorf("failed to find default branch: %s", err)
+			writeEditResponse(w, http.StatusBadGateway, editError{err.Error()})
+			return
+		}
+	}
+
+	branch := "booklit/edit-" + commit[:7]
+
+	log.WithField("branch", branch).Info("pushing")
+
+	err = contributions.push(top, commit, branch)
+	if err != nil {
+		log.Errorf("failed to push: %s", err)
+		writeEditResponse(w, http.StatusBadGateway, editError{err.Error()})
+		return
+	}
+
+	pr, err := contributions.openPullRequest(title, pullRequestBody(req.Description, sections, relativePaths(top, files)), branch, base)
+	if err != nil {
+		log.Errorf("failed to open pull request: %s", err)
+		writeEditResponse(w, http.StatusBadGateway, editError{err.Error()})
+		return
+	}
+
+	log.WithField("url", pr.URL).Info("opened pull request")
+
+	_, err = git(top, nil, append([]string{"checkout", "HEAD", "--"}, files...)...)
+	if err != nil {
+		// the pull request was opened regardless
+		log.Errorf("failed to restore edited files: %s", err)
+	}
+
+	server.Editor.edited = nil
+
+	if server.Editor.Build != nil {
+		log.Info("rebuilding")
+
+		err := server.Editor.Build()
+		if err != nil {
+			log.Errorf("failed to rebuild: %s", err)
+		}
+	}
+
+	writeEditResponse(w, http.StatusOK, pr)
+}
+
+// commitFiles commits the files as they are on disk on top of HEAD, using an
+// index of its own so that anything already staged isn't included. It
+// returns the commit, which no branch refers to yet.
+func commitFiles(top string, files []string, message string) (string, error) {
+	index, err := ioutil.TempFile("", "booklit-index")
+	if err != nil {
+		return "", err
+	}
+
+	_ = index.Close()
+	defer os.Remove(index.Name())
+
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+
+	_, err = git(top, env, "read-tree", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = git(top, env, append([]string{"add", "--"}, files...)...)
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := git(top, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	return git(top, env, "commit-tree", tree, "-p", "HEAD", "-m", message)
+}
+
+// push pushes the commit to a new branch of the repository, authenticating
+// with the token. The token is passed through the environment rather than
+// the URL or arguments, so that it isn't visible to other processes.
+func (contributions *Contributions) push(top string, commit string, branch string) error {
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + contributions.Token))
+
+	env := []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraheader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + auth,
+	}
+
+	remote := contributions.webURL() + "/" + contributions.Repo + ".git"
+
+	_, err := git(top, env, "push", remote, commit+":refs/heads/"+branch)
+	return err
+}
+
+func (contributions *Contributions) defaultBranch() (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	err := contributions.api("GET", "/repos/"+contributions.Repo, nil, &repo)
+	if err != nil {
+		return "", err
+	}
+
+	return repo.DefaultBranch, nil
+}
+
+func (contributions *Contributions) openPullRequest(title string, body string, head string, base string) (pullRequest, error) {
+	var pr pullRequest
+	err := contributions.api("POST", "/repos/"+contributions.Repo+"/pulls", map[string]string{
+		"title": title,
+		"body":  body,
+		"head":  head,
+		"base":  base,
+	}, &pr)
+	return pr, err
+}
+
+func (contributions *Contributions) api(method string, path string, payload interface{}, dest interface{}) error {
+	body := new(bytes.Buffer)
+	if payload != nil {
+		err := json.NewEncoder(body).Encode(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, contributions.apiURL()+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+contributions.Token)
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := contributions.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var failure struct {
+			Message string `json:"message"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&failure)
+		if failure.Message != "" {
+			return fmt.Errorf("unexpected response: %s: %s", res.Status, failure.Message)
+		}
+
+		return fmt.Errorf("unexpected response: %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(dest)
+}
+
+func (contributions *Contributions) webURL() string {
+	if contributions.URL == "" {
+		return defaultGitHubURL
+	}
+
+	return strings.TrimRight(contributions.URL, "/")
+}
+
+func (contributions *Contributions) apiURL() string {
+	web := contributions.webURL()
+	if web == defaultGitHubURL {
+		return "https://api.github.com"
+	}
+
+	// GitHub Enterprise
+	return web + "/api/v3"
+}
+
+var hunkRegexp = regexp.MustCompile(`(?m)^@@ -[0-9]+(?:,[0-9]+)? \+([0-9]+)(?:,([0-9]+))? @@`)
+
+// changedLinesOf returns the lines of the file which differ from HEAD. Lines
+// which were removed are attributed to the line before them.
+func changedLinesOf(top string, path string) ([]int, error) {
+	diff, err := git(top, nil, "diff", "--unified=0", "HEAD", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []int{}
+	for _, match := range hunkRegexp.FindAllStringSubmatch(diff, -1) {
+		start, _ := strconv.Atoi(match[1])
+
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+
+		if count == 0 {
+			if start == 0 {
+				start = 1
+			}
+
+			lines = append(lines, start)
+			continue
+		}
+
+		for line := start; line < start+count; line++ {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
+
+// changedSections returns the sections which the changed lines of each file
+// are within, in the order they appear in the book. A line is within the
+// innermost section which starts before it in the same file.
+func changedSections(root *booklit.Section, top string, changedLines map[string][]int) []changedSection {
+	type located struct {
+		section *booklit.Section
+		order   int
+	}
+
+	byFile := map[string][]located{}
+
+	order := 0
+
+	var walk func(*booklit.Section)
+	walk = func(section *booklit.Section) {
+		path, err := filepath.Abs(section.FilePath())
+		if err == nil {
+			byFile[path] = append(byFile[path], located{section, order})
+			order++
+		}
+
+		for _, child := range section.Children {
+			walk(child)
+		}
+	}
+
+	walk(root)
+
+	changed := map[*booklit.Section]int{}
+	for path, lines := range changedLines {
+		candidates := byFile[path]
+
+		for _, line := range lines {
+			var within *located
+			for i, candidate := range candidates {
+				if candidate.section.Location.Line > line {
+					continue
+				}
+
+				if within == nil || candidate.section.Location.Line >= within.section.Location.Line {
+					within = &candidates[i]
+				}
+			}
+
+			if within != nil {
+				changed[within.section] = within.order
+			}
+		}
+	}
+
+	sections := make([]*booklit.Section, 0, len(changed))
+	for section := range changed {
+		sections = append(sections, section)
+	}
+
+	sort.Slice(sections, func(i, j int) bool {
+		return changed[sections[i]] < changed[sections[j]]
+	})
+
+	result := []changedSection{}
+	for _, section := range sections {
+		path, _ := filepath.Abs(section.FilePath())
+
+		result = append(result, changedSection{
+			Title: section.Title.String(),
+			Path:  relativePaths(top, []string{path})[0],
+		})
+	}
+
+	return result
+}
+
+// pullRequestBody describes the pull request, listing the sections changed
+// or, if the book failed to load, the files.
+func pullRequestBody(description string, sections []changedSection, files []string) string {
+	body := new(strings.Builder)
+
+	if description = strings.TrimSpace(description); description != "" {
+		fmt.Fprintf(body, "%s\n\n", description)
+	}
+
+	if len(sections) > 0 {
+		fmt.Fprintln(body, "Changed sections:")
+		fmt.Fprintln(body)
+
+		for _, section := range sections {
+			fmt.Fprintf(body, "- %s (`%s`)\n", section.Title, section.Path)
+		}
+	} else {
+		fmt.Fprintln(body, "Changed files:")
+		fmt.Fprintln(body)
+
+		for _, file := range files {
+			fmt.Fprintf(body, "- `%s`\n", file)
+		}
+	}
+
+	fmt.Fprintln(body)
+	fmt.Fprint(body, "Proposed with the editing panel of `booklit --serve`.")
+
+	return body.String()
+}
+
+func relativePaths(top string, paths []string) []string {
+	rels := []string{}
+	for _, path := range paths {
+		rel, err := filepath.Rel(top, path)
+		if err != nil {
+			rel = path
+		}
+
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+
+	return rels
+}
+
+// git runs git in the given directory, returning its trimmed output.
+func git(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w\n%s", args[0], err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,509 @@
+package booklitcmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// editPath is the path of the endpoint which the editing panel loads and
+// saves the source of pages with.
+const editPath = "/_booklit/edit"
+
+// Editor configures the panel for editing the source of each page served.
+type Editor struct {
+	// Called after the source is saved to rebuild the output; may be nil.
+	Build func() error
+
+	// If set, the edits may be proposed as a pull request, via
+	// /_booklit/contribute.
+	Contributions *Contributions
+
+	// absolute paths of the files saved since the edits were last proposed
+	edited map[string]bool
+}
+
+func (editor *Editor) recordEdit(path string) {
+	if editor.edited == nil {
+		editor.edited = map[string]bool{}
+	}
+
+	editor.edited[path] = true
+}
+
+func (editor *Editor) editedFiles() []string {
+	files := []string{}
+	for path := range editor.edited {
+		files = append(files, path)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+// editedSource is the source of the file which a page is loaded from, as
+// loaded and saved by the editing panel.
+type editedSource struct {
+	// path of the file, relative to the input's directory
+	Path string `json:"path,omitempty"`
+
+	Source string `json:"source"`
+
+	// digest of the source which was loaded, so that changes made to the
+	// file in the meantime aren't overwritten
+	Revision string `json:"revision"`
+}
+
+type editError struct {
+	Error string `json:"error"`
+}
+
+// serveEdit serves the source of the page given by the 'page' query param
+// for GET requests, and saves it for POST requests.
+//
+// Saving requires a JSON request, which browsers don't allow other sites to
+// send without permission, so that pages elsewhere can't edit the files.
+func (server *Server) serveEdit(w http.ResponseWriter, r *http.Request) {
+	log := logrus.WithFields(logrus.Fields{
+		"request": r.URL.Path,
+		"page":    r.URL.Query().Get("page"),
+	})
+
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	server.buildLock.Lock()
+	defer server.buildLock.Unlock()
+
+	path, rel, status, err := server.editedFile(r.Context(), r.URL.Query().Get("page"))
+	if err != nil {
+		log.Errorf("failed to find source: %s", err)
+		writeEditResponse(w, status, editError{err.Error()})
+		return
+	}
+
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Errorf("failed to read source: %s", err)
+		writeEditResponse(w, http.StatusInternalServerError, editError{err.Error()})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeEditResponse(w, http.StatusOK, editedSource{
+			Path:     rel,
+			Source:   string(current),
+			Revision: sourceRevision(current),
+		})
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeEditResponse(w, http.StatusUnsupportedMediaType, editError{"expected JSON"})
+		return
+	}
+
+	var edit editedSource
+	err = json.NewDecoder(r.Body).Decode(&edit)
+	if err != nil {
+		writeEditResponse(w, http.StatusBadRequest, editError{fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	if edit.Revision != sourceRevision(current) {
+		log.Warn("conflicting edit")
+		writeEditResponse(w, http.StatusConflict, editError{rel + " has changed since it was loaded"})
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		writeEditResponse(w, http.StatusInternalServerError, editError{err.Error()})
+		return
+	}
+
+	log.WithField("path", rel).Info("saving")
+
+	err = ioutil.WriteFile(path, []byte(edit.Source), info.Mode())
+	if err != nil {
+		log.Errorf("failed to save source: %s", err)
+		writeEditResponse(w, http.StatusInternalServerError, editError{err.Error()})
+		return
+	}
+
+	server.Editor.recordEdit(path)
+
+	if server.Editor.Build != nil {
+		log.Info("rebuilding")
+
+		err := server.Editor.Build()
+		if err != nil {
+			// the page shows the error itself once reloaded
+			log.Errorf("failed to rebuild: %s", err)
+		}
+	}
+
+	writeEditResponse(w, http.StatusOK, editedSource{
+		Path:     rel,
+		Source:   edit.Source,
+		Revision: sourceRevision([]byte(edit.Source)),
+	})
+}
+
+// editedFile returns the path of the file which the page at the given path
+// is loaded from, along with its path relative to the input's directory. If
+// the book fails to load, the file the error occurred in is edited instead,
+// so that it can be fixed.
+//
+// Only .lit files within the input's directory may be edited.
+func (server *Server) editedFile(ctx context.Context, page string) (string, string, int, error) {
+	var path string
+
+	section, found, err := server.loadRequestedSection(ctx, page)
+	if err != nil {
+		path = booklit.ErrorFilePath(err)
+		if path == "" {
+			return "", "", http.StatusInternalServerError, err
+		}
+	} else if !found {
+		return "", "", http.StatusNotFound, fmt.Errorf("unknown page: %s", page)
+	} else {
+		path = section.FilePath()
+	}
+
+	if path == "" {
+		return "", "", http.StatusNotFound, fmt.Errorf("page has no source file: %s", page)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", http.StatusInternalServerError, err
+	}
+
+	root, err := filepath.Abs(filepath.Dir(server.In))
+	if err != nil {
+		return "", "", http.StatusInternalServerError, err
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.Ext(abs) != ".lit" {
+		return "", "", http.StatusForbidden, fmt.Errorf("source may not be edited: %s", path)
+	}
+
+	return abs, filepath.ToSlash(rel), http.StatusOK, nil
+}
+
+func writeEditResponse(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func sourceRevision(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+// injectEditor adds the editing panel to the page.
+func (server *Server) injectEditor(page []byte, path string) []byte {
+	base := strings.TrimSuffix(server.Processor.BasePath, "/")
+
+	data := map[string]string{
+		"Endpoint": base + editPath,
+		"Page":     path,
+	}
+
+	if server.Editor.Contributions != nil {
+		data["ContributeEndpoint"] = base + contributePath
+	}
+
+	panel := new(bytes.Buffer)
+	err := editorPanel.Execute(panel, data)
+	if err != nil {
+		logrus.Errorf("failed to render editor: %s", err)
+		return page
+	}
+
+	return injectBeforeBodyEnd(page, panel.Bytes())
+}
+
+// injectBeforeBodyEnd inserts the markup just before the end of the page's
+// body, or appends it if it has none.
+func injectBeforeBodyEnd(page []byte, markup []byte) []byte {
+	end := bytes.LastIndex(page, []byte("</body>"))
+	if end == -1 {
+		return append(page, markup...)
+	}
+
+	injected := append([]byte{}, page[:end]...)
+	injected = append(injected, markup...)
+	return append(injected, page[end:]...)
+}
+
+var editorPanel = template.Must(template.New("editor").Parse(`
+<div id="booklit-editor" data-endpoint="{{.Endpoint}}" data-page="{{.Page}}" hidden>
+  <div class="booklit-editor-header">
+    <span class="booklit-editor-path"></span>
+    <span class="booklit-editor-status" role="status"></span>
+    <button type="button" class="booklit-editor-save">Save</button>
+    <button type="button" class="booklit-editor-close">Close</button>
+  </div>
+  <textarea class="booklit-editor-source" spellcheck="false" aria-label="Source"></textarea>
+  {{- if .ContributeEndpoint}}
+  <div class="booklit-editor-contribute" data-endpoint="{{.ContributeEndpoint}}">
+    <input type="text" class="booklit-editor-title" placeholder="Summary of your changes" aria-label="Summary">
+    <input type="text" class="booklit-editor-description" placeholder="Why they're needed (optional)" aria-label="Description">
+    <button type="button" class="booklit-editor-propose">Propose changes</button>
+  </div>
+  {{- end}}
+</div>
+<button type="button" id="booklit-editor-open">Edit</button>
+<style>
+  #booklit-editor {
+    position: fixed;
+    top: 0;
+    right: 0;
+    bottom: 0;
+    z-index: 10000;
+    display: flex;
+    flex-direction: column;
+    width: min(48em, 100%);
+    background: #fff;
+    color: #222;
+    box-shadow: 0 0 1em rgba(0, 0, 0, 0.3);
+    font: 14px/1.4 sans-serif;
+  }
+
+  #booklit-editor[hidden] {
+    display: none;
+  }
+
+  .booklit-editor-header {
+    display: flex;
+    align-items: center;
+    gap: 0.5em;
+    padding: 0.5em;
+    border-bottom: 1px solid #ddd;
+  }
+
+  .booklit-editor-path {
+    font-family: monospace;
+  }
+
+  .booklit-editor-status {
+    flex: 1;
+    color: #666;
+  }
+
+  .booklit-editor-status.error {
+    color: #c00;
+  }
+
+  .booklit-editor-source {
+    flex: 1;
+    margin: 0;
+    padding: 0.75em;
+    border: 0;
+    resize: none;
+    font: 13px/1.5 monospace;
+    tab-size: 2;
+  }
+
+  .booklit-editor-contribute {
+    display: flex;
+    gap: 0.5em;
+    padding: 0.5em;
+    border-top: 1px solid #ddd;
+  }
+
+  .booklit-editor-contribute input {
+    flex: 1;
+    min-width: 0;
+    font: inherit;
+  }
+
+  #booklit-editor-open {
+    position: fixed;
+    right: 1em;
+    bottom: 1em;
+    z-index: 9999;
+    padding: 0.5em 1em;
+    font: 14px sans-serif;
+    cursor: pointer;
+  }
+</style>
+<script>
+  (function() {
+    var panel = document.getElementById("booklit-editor");
+    var open = document.getElementById("booklit-editor-open");
+    var source = panel.querySelector(".booklit-editor-source");
+    var path = panel.querySelector(".booklit-editor-path");
+    var status = panel.querySelector(".booklit-editor-status");
+    var save = panel.querySelector(".booklit-editor-save");
+    var close = panel.querySelector(".booklit-editor-close");
+
+    var url = panel.dataset.endpoint + "?page=" + encodeURIComponent(panel.dataset.page);
+    var revision = null;
+    var dirty = false;
+
+    function report(message, failed) {
+      status.textContent = message;
+      status.classList.toggle("error", !!failed);
+    }
+
+    function respond(response) {
+      return response.json().then(function(body) {
+        if (!response.ok) {
+          throw new Error(body.error || response.statusText);
+        }
+
+        return body;
+      });
+    }
+
+    function load() {
+      report("Loading...");
+
+      return fetch(url, { cache: "no-store" }).then(respond).then(function(body) {
+        source.value = body.source;
+        path.textContent = body.path;
+        revision = body.revision;
+        dirty = false;
+        report("");
+      }).catch(function(err) {
+        report(err.message, true);
+      });
+    }
+
+    function store() {
+      if (revision === null) {
+        return;
+      }
+
+      report("Saving...");
+      save.disabled = true;
+
+      fetch(url, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ source: source.value, revision: revision })
+      }).then(respond).then(function(body) {
+        revision = body.revision;
+        dirty = false;
+        sessionStorage.setItem("booklit-editor-open", "true");
+        location.reload();
+      }).catch(function(err) {
+        report(err.message, true);
+      }).then(function() {
+        save.disabled = false;
+      });
+    }
+
+    function show() {
+      panel.hidden = false;
+      open.hidden = true;
+      load().then(function() {
+        source.focus();
+      });
+    }
+
+    open.addEventListener("click", show);
+
+    close.addEventListener("click", function() {
+      if (dirty && !confirm("Discard your changes?")) {
+        return;
+      }
+
+      panel.hidden = true;
+      open.hidden = false;
+    });
+
+    save.addEventListener("click", store);
+
+    var contribute = panel.querySelector(".booklit-editor-contribute");
+    if (contribute) {
+      var title = contribute.querySelector(".booklit-editor-title");
+      var description = contribute.querySelector(".booklit-editor-description");
+      var propose = contribute.querySelector(".booklit-editor-propose");
+
+      propose.addEventListener("click", function() {
+        if (dirty) {
+          report("Save your changes before proposing them.", true);
+          return;
+        }
+
+        report("Proposing...");
+        propose.disabled = true;
+
+        fetch(contribute.dataset.endpoint, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ title: title.value, description: description.value })
+        }).then(respond).then(function(body) {
+          title.value = "";
+          description.value = "";
+
+          // the edits are now on the pull request's branch, and the files
+          // are back to how they were
+          return load().then(function() {
+            var link = document.createElement("a");
+            link.href = body.html_url;
+            link.target = "_blank";
+            link.textContent = "pull request #" + body.number;
+
+            report("Proposed as ");
+            status.appendChild(link);
+          });
+        }).catch(function(err) {
+          report(err.message, true);
+        }).then(function() {
+          propose.disabled = false;
+        });
+      });
+    }
+
+    source.addEventListener("input", function() {
+      dirty = true;
+    });
+
+    source.addEventListener("keydown", function(event) {
+      if ((event.ctrlKey || event.metaKey) && event.key === "s") {
+        event.preventDefault();
+        store();
+      }
+    });
+
+    window.addEventListener("beforeunload", function(event) {
+      if (dirty) {
+        event.preventDefault();
+        event.returnValue = "";
+      }
+    });
+
+    // stay open after saving, which reloads the page to show the changes
+    if (sessionStorage.getItem("booklit-editor-open")) {
+      sessionStorage.removeItem("booklit-editor-open");
+      show();
+    }
+  })();
+</script>
+`))
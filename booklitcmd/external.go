@@ -0,0 +1,63 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/external"
+)
+
+// ExternalPlugin is a plugin implemented by an executable, given as
+// name=command.
+type ExternalPlugin struct {
+	Name    string
+	Command []string
+}
+
+func (plugin *ExternalPlugin) UnmarshalFlag(value string) error {
+	segs := strings.SplitN(value, "=", 2)
+	if len(segs) != 2 || segs[0] == "" || strings.TrimSpace(segs[1]) == "" {
+		return fmt.Errorf("invalid external plugin (must be name=command): %s", value)
+	}
+
+	plugin.Name = segs[0]
+	command, err := splitCommand(segs[1])
+	if err != nil {
+		return err
+	}
+
+	plugin.Command = command
+
+	return nil
+}
+
+// startExternalPlugins starts the process for each external plugin and
+// registers it, returning a function which stops them all.
+func (cmd *Command) startExternalPlugins() (func(), error) {
+	processes := []*external.Process{}
+
+	stop := func() {
+		for _, process := range processes {
+			err := process.Close()
+			if err != nil {
+				logrus.WithError(err).Warn("external plugin failed")
+			}
+		}
+	}
+
+	for _, plugin := range cmd.ExternalPlugins {
+		process, err := external.Start(plugin.Name, plugin.Command)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+
+		processes = append(processes, process)
+
+		booklit.RegisterPlugin(plugin.Name, process.NewPlugin)
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,81 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render"
+)
+
+// FragmentCommand renders the content of a single tag from --in on its own,
+// without the page around it, for embedding in another site. Links and
+// assets are made absolute to the URL the book is hosted at.
+type FragmentCommand struct {
+	Command *Command `no-flag:"true"`
+
+	Tag     Tag    `long:"tag"      required:"true" description:"Tag of the section, target, or figure to render."`
+	BaseURL string `long:"base-url" required:"true" description:"URL the book is hosted at, e.g. https://example.com/docs/, which links and assets are made absolute to."`
+}
+
+func (cmd *FragmentCommand) Execute(args []string) error {
+	cmd.Command.configureLogging()
+
+	if cmd.Command.shouldReexec() {
+		return cmd.Command.reexec()
+	}
+
+	stopWatching := cmd.Command.watchInterrupts()
+	defer stopWatching()
+
+	if cmd.Command.In == "" {
+		return fmt.Errorf("--in must be specified")
+	}
+
+	rewrites, err := booklit.AbsoluteLinkRewrites(cmd.BaseURL)
+	if err != nil {
+		return err
+	}
+
+	stop, err := cmd.Command.startExternalPlugins()
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	engine, err := cmd.Command.engine()
+	if err != nil {
+		return err
+	}
+
+	fragmentEngine, ok := engine.(render.FragmentRenderingEngine)
+	if !ok {
+		return fmt.Errorf("engine does not support rendering fragments: %T", engine)
+	}
+
+	processor := cmd.Command.processor()
+	processor.BasePath = cmd.BaseURL
+	processor.LinkRewrites = append(processor.LinkRewrites, rewrites...)
+
+	if !strings.HasSuffix(processor.BasePath, "/") {
+		processor.BasePath += "/"
+	}
+
+	if info, ok := engine.(booklit.RenderingEngine); ok {
+		processor.Engine = info
+	}
+
+	section, err := processor.LoadFileContext(cmd.Command.context(), cmd.Command.In, basePluginFactories)
+	if err != nil {
+		return err
+	}
+
+	tags := section.FindTag(string(cmd.Tag))
+	if len(tags) == 0 {
+		return fmt.Errorf("unknown tag: %s", cmd.Tag)
+	}
+
+	return fragmentEngine.RenderFragment(os.Stdout, tags[0])
+}
@@ -0,0 +1,246 @@
+package booklitcmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// FreezeManifest records the checksums of the source of the frozen sections,
+// by the sections' tags and the paths of the files they were loaded from,
+// relative to the manifest. Builds fail if any of them change, so that e.g.
+// the docs for a released version aren't edited by accident.
+type FreezeManifest map[string]map[string]string
+
+// loadFreezeManifest reads the manifest, which is empty if it doesn't exist
+// yet.
+func loadFreezeManifest(path string) (FreezeManifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FreezeManifest{}, nil
+		}
+
+		return nil, err
+	}
+
+	manifest := FreezeManifest{}
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid freeze manifest %s: %s", path, err)
+	}
+
+	return manifest, nil
+}
+
+// checkFrozen returns an error if the source files of any frozen section have
+// changed since it was frozen, and then freezes the sections given by
+// --freeze, saving the manifest.
+func (cmd *Command) checkFrozen(books []*booklit.Section) error {
+	if cmd.FreezeManifest == "" {
+		return nil
+	}
+
+	freezing := map[string]bool{}
+	for _, tag := range cmd.Freeze {
+		freezing[string(tag)] = true
+	}
+
+	tags := []string{}
+	for tag := range cmd.frozen {
+		if !freezing[tag] {
+			tags = append(tags, tag)
+		}
+	}
+
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		sums, found, err := cmd.frozenChecksums(books, tag)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return fmt.Errorf("frozen section '%s' no longer exists", tag)
+		}
+
+		changed := changedFiles(cmd.frozen[tag], sums)
+		if len(changed) > 0 {
+			return fmt.Errorf("frozen section '%s' has changed: %s", tag, strings.Join(changed, ", "))
+		}
+	}
+
+	if len(cmd.Freeze) == 0 {
+		return nil
+	}
+
+	for _, tag := range cmd.Freeze {
+		sums, found, err := cmd.frozenChecksums(books, string(tag))
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return fmt.Errorf("unknown tag: %s", tag)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"tag":   tag,
+			"files": len(sums),
+		}).Info("freezing section")
+
+		cmd.frozen[string(tag)] = sums
+	}
+
+	payload, err := json.MarshalIndent(cmd.frozen, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(cmd.FreezeManifest, append(payload, '\n'), 0644)
+}
+
+// frozenChecksums returns the checksums of the source of the section with
+// the given tag, in every book which has it, e.g. each of its translations,
+// by the files it was loaded from.
+//
+// The section and those of its children with files of their own are
+// checksummed by their syntax trees rather than their files, so that editing
+// the sections around it in the same file doesn't change it. Files they
+// depend on are checksummed as a whole.
+func (cmd *Command) frozenChecksums(books []*booklit.Section, tag string) (map[string]string, bool, error) {
+	dir, err := filepath.Abs(filepath.Dir(cmd.FreezeManifest))
+	if err != nil {
+		return nil, false, err
+	}
+
+	sums := map[string]string{}
+
+	record := func(file string, sum string) error {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return err
+		}
+
+		sums[filepath.ToSlash(rel)] = sum
+
+		return nil
+	}
+
+	var walk func(*booklit.Section, bool) error
+	walk = func(section *booklit.Section, frozen bool) error {
+		if frozen || section.Path != "" {
+			sum, err := sectionChecksum(section)
+			if err != nil {
+				return err
+			}
+
+			err = record(section.FilePath(), sum)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, dep := range section.Dependencies {
+			sum, err := sha256File(dep)
+			if err != nil {
+				return err
+			}
+
+			err = record(dep, sum)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, child := range section.Children {
+			err := walk(child, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	found := false
+	for _, book := range books {
+		tags := book.FindTag(tag)
+		if len(tags) == 0 {
+			continue
+		}
+
+		found = true
+
+		err := walk(tags[0].Section, true)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return sums, found, nil
+}
+
+// sectionChecksum returns the checksum of the syntax tree the section was
+// evaluated from, without the locations within its file, or of its file if
+// it wasn't evaluated.
+func sectionChecksum(section *booklit.Section) (string, error) {
+	if section.Node == nil {
+		return sha256File(section.FilePath())
+	}
+
+	tree := &astTree{
+		OmitLocations: true,
+	}
+
+	err := section.Node.Visit(tree)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(tree.Result)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// changedFiles returns the files which were added, removed, or modified,
+// sorted.
+func changedFiles(frozen map[string]string, current map[string]string) []string {
+	changed := []string{}
+
+	for file, sum := range frozen {
+		if current[file] != sum {
+			changed = append(changed, file)
+		}
+	}
+
+	for file := range current {
+		if _, found := frozen[file]; !found {
+			changed = append(changed, file)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
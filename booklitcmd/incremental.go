@@ -0,0 +1,318 @@
+package booklitcmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+// defaultCacheDir is the directory within --out which the cache for
+// --incremental is saved in, unless --cache-dir is given.
+const defaultCacheDir = ".booklit-cache"
+
+const (
+	parseCacheFile  = "parsed.gob"
+	buildCacheFile  = "pages.json"
+	chapterCacheDir = "chapters"
+)
+
+func (cmd *Command) cacheDir() (string, error) {
+	if cmd.CacheDir != "" {
+		return cmd.CacheDir, nil
+	}
+
+	if cmd.Out == "" {
+		return "", fmt.Errorf("--out or --cache-dir must be specified with --incremental")
+	}
+
+	return filepath.Join(cmd.Out, defaultCacheDir), nil
+}
+
+// buildIncrementally builds using the cache saved by the last build, and
+// saves it again for the next one if the build succeeds.
+func (cmd *Command) buildIncrementally(processor *load.Processor) ([]*booklit.Section, error) {
+	dir, err := cmd.cacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	err = readParseCache(processor, filepath.Join(dir, parseCacheFile))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cmd.cacheKey()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.buildCache, err = render.LoadBuildCache(filepath.Join(dir, buildCacheFile), key)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.chapterCache = render.NewChapterCache(filepath.Join(dir, chapterCacheDir), key)
+
+	sections, err := cmd.buildSections(processor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeParseCache(processor, filepath.Join(dir, parseCacheFile))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.buildCache.Save(filepath.Join(dir, buildCacheFile))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.chapterCache.Prune()
+	if err != nil {
+		return nil, err
+	}
+
+	return sections, nil
+}
+
+func readParseCache(processor *load.Processor, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	return processor.ReadParseCache(file)
+}
+
+func writeParseCache(processor *load.Processor, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = processor.WriteParseCache(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// cacheKey computes a digest of everything which may affect every page: the
+// flags and config, the templates and stylesheets, and the booklit
+// executable itself, which has any plugins compiled in.
+func (cmd *Command) cacheKey() (string, error) {
+	h := sha256.New()
+
+	for _, arg := range os.Args[1:] {
+		fmt.Fprintf(h, "arg=%s\n", arg)
+	}
+
+	configArgs, err := configArgs(cmd.Config, string(cmd.Env))
+	if err != nil {
+		return "", err
+	}
+
+	for _, arg := range configArgs {
+		fmt.Fprintf(h, "config=%s\n", arg)
+	}
+
+	templateDirs := []string{
+		cmd.HTMLEngine.Templates,
+		cmd.DocBookEngine.Templates,
+		cmd.EPUBEngine.Templates,
+		cmd.LaTeXEngine.Templates,
+		cmd.MarkdownEngine.Templates,
+		cmd.ManpageEngine.Templates,
+		cmd.PDFEngine.Templates,
+		cmd.TexinfoEngine.Templates,
+		cmd.TextEngine.Templates,
+	}
+
+	for _, dir := range templateDirs {
+		if dir == "" {
+			continue
+		}
+
+		err := hashDir(h, dir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, stylesheet := range cmd.EPUBEngine.Stylesheets {
+		err := hashFile(h, stylesheet)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cmd.LaTeXEngine.Preamble != "" {
+		err := hashFile(h, cmd.LaTeXEngine.Preamble)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(cmd.Plugins) > 0 || cmd.Race {
+		// the reexec binary is built anew each time, so hash its source instead
+		err = hashPackages(h, append([]string{"github.com/vito/booklit/booklitcmd"}, cmd.Plugins...))
+		if err != nil {
+			return "", err
+		}
+	} else {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", err
+		}
+
+		err = hashFile(h, executable)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashPackages hashes the source of the packages and everything they import,
+// as would be compiled in by reexecing. Packages from the module cache are
+// identified by their version alone, since they never change.
+func hashPackages(h hash.Hash, packages []string) error {
+	listed, err := listPackages(packages)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range listed {
+		if pkg.Version != "" {
+			fmt.Fprintf(h, "package=%s@%s\n", pkg.Dir, pkg.Version)
+			continue
+		}
+
+		for _, file := range pkg.GoFiles {
+			err := hashFile(h, filepath.Join(pkg.Dir, file))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// goPackage is a non-standard package listed by 'go list'.
+type goPackage struct {
+	Dir string
+
+	// version of the package's module, empty if it is not from the module
+	// cache, e.g. the main module
+	Version string
+
+	GoFiles []string
+}
+
+// listPackages lists the packages and every non-standard package they
+// import.
+func listPackages(packages []string) ([]goPackage, error) {
+	list := exec.Command("go", append([]string{
+		"list", "-deps",
+		"-f", "{{if not .Standard}}{{.Dir}}\t{{with .Module}}{{.Version}}{{end}}\t{{join .GoFiles \"\\t\"}}{{end}}",
+	}, packages...)...)
+	list.Stderr = os.Stderr
+
+	output, err := list.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing plugin packages failed: %w", err)
+	}
+
+	listed := []goPackage{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+
+		pkg := goPackage{
+			Dir:     fields[0],
+			Version: fields[1],
+		}
+
+		for _, file := range fields[2:] {
+			if file != "" {
+				pkg.GoFiles = append(pkg.GoFiles, file)
+			}
+		}
+
+		listed = append(listed, pkg)
+	}
+
+	return listed, nil
+}
+
+func hashDir(h hash.Hash, dir string) error {
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(files)
+
+	for _, file := range files {
+		err := hashFile(h, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hashFile(h hash.Hash, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	fmt.Fprintf(h, "file=%s\n", path)
+
+	_, err = io.Copy(h, file)
+	return err
+}
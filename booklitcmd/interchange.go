@@ -0,0 +1,134 @@
+package booklitcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/interchange"
+)
+
+// ExportJSONCommand prints the book loaded from --in in the JSON interchange
+// format, after every function has been evaluated and every reference
+// resolved.
+type ExportJSONCommand struct {
+	Command *Command `no-flag:"true"`
+
+	Compact bool `long:"compact" description:"Print the document on a single line rather than indented."`
+}
+
+func (cmd *ExportJSONCommand) Execute(args []string) error {
+	cmd.Command.configureLogging()
+
+	if cmd.Command.shouldReexec() {
+		return cmd.Command.reexec()
+	}
+
+	stopWatching := cmd.Command.watchInterrupts()
+	defer stopWatching()
+
+	if cmd.Command.In == "" {
+		return fmt.Errorf("--in must be specified")
+	}
+
+	stop, err := cmd.Command.startExternalPlugins()
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	engine, err := cmd.Command.engine()
+	if err != nil {
+		return err
+	}
+
+	processor := cmd.Command.processor()
+
+	// engine-aware plugins produce the same content as they would for a build
+	if info, ok := engine.(booklit.RenderingEngine); ok {
+		processor.Engine = info
+	}
+
+	section, err := processor.LoadFileContext(cmd.Command.context(), cmd.Command.In, basePluginFactories)
+	if err != nil {
+		return cmd.Command.failedLoad(processor, err)
+	}
+
+	cmd.Command.reportWarnings(processor)
+
+	doc, err := interchange.Export(section)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if !cmd.Compact {
+		enc.SetIndent("", "  ")
+	}
+
+	return enc.Encode(doc)
+}
+
+// ImportJSONCommand renders a book from a document in the JSON interchange
+// format, e.g. one exported by ExportJSONCommand and then edited, or
+// generated by another system, in place of --in.
+type ImportJSONCommand struct {
+	Command *Command `no-flag:"true"`
+
+	Args struct {
+		File string `positional-arg-name:"file" description:"JSON document to import, or - to read it from stdin."`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (cmd *ImportJSONCommand) Execute(args []string) error {
+	cmd.Command.configureLogging()
+
+	stopWatching := cmd.Command.watchInterrupts()
+	defer stopWatching()
+
+	var in io.Reader = os.Stdin
+	if cmd.Args.File != "-" {
+		file, err := os.Open(cmd.Args.File)
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		in = file
+	}
+
+	var doc interchange.Document
+	err := json.NewDecoder(in).Decode(&doc)
+	if err != nil {
+		return fmt.Errorf("invalid document %s: %s", cmd.Args.File, err)
+	}
+
+	book, err := interchange.Import(&doc)
+	if err != nil {
+		return err
+	}
+
+	engine, err := cmd.Command.engine()
+	if err != nil {
+		return err
+	}
+
+	processor := cmd.Command.processor()
+
+	if info, ok := engine.(booklit.RenderingEngine); ok {
+		processor.Engine = info
+	}
+
+	section, err := processor.LoadSectionContext(cmd.Command.context(), book)
+	if err != nil {
+		return cmd.Command.failedLoad(processor, err)
+	}
+
+	cmd.Command.reportWarnings(processor)
+
+	return cmd.Command.write(processor, engine, section, cmd.Command.Out)
+}
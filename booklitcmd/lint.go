@@ -0,0 +1,91 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit/render"
+)
+
+// LintCommand checks the book loaded from --in for broken references and
+// links without rendering it. Problems are reported as errors, like those
+// from a build.
+type LintCommand struct {
+	Command *Command `no-flag:"true"`
+
+	External    bool          `long:"external"    description:"Also check http and https links by requesting them."`
+	Timeout     time.Duration `long:"timeout"     default:"10s" description:"How long to wait for each external link to respond."`
+	Concurrency int           `long:"concurrency" default:"8"   description:"Number of external links to request at a time."`
+}
+
+func (cmd *LintCommand) Execute(args []string) error {
+	cmd.Command.configureLogging()
+
+	if cmd.Command.Safe {
+		if cmd.External {
+			return fmt.Errorf("--external is not supported with --safe")
+		}
+
+		err := cmd.Command.checkSafe()
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.Command.shouldReexec() {
+		return cmd.Command.reexec()
+	}
+
+	stopWatching := cmd.Command.watchInterrupts()
+	defer stopWatching()
+
+	if cmd.Command.In == "" {
+		return fmt.Errorf("--in must be specified")
+	}
+
+	stop, err := cmd.Command.startExternalPlugins()
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	engine, err := cmd.Command.engine()
+	if err != nil {
+		return err
+	}
+
+	processor := cmd.Command.processor()
+
+	// unknown tags are reported by loading the book
+	section, err := processor.LoadFileContext(cmd.Command.context(), cmd.Command.In, basePluginFactories)
+	if err != nil {
+		return cmd.Command.failedLoad(processor, err)
+	}
+
+	cmd.Command.reportWarnings(processor)
+
+	dirs := cmd.Command.AssetDirs
+	if cmd.Command.Out != "" {
+		dirs = append([]string{cmd.Command.Out}, dirs...)
+	}
+
+	checker := render.LinkChecker{
+		FileExtension: engine.FileExtension(),
+		Directories:   dirs,
+		External:      cmd.External,
+		Client:        &http.Client{Timeout: cmd.Timeout},
+		Jobs:          cmd.Concurrency,
+	}
+
+	err = checker.CheckLinks(section)
+	if err != nil {
+		return err
+	}
+
+	logrus.Info("no broken links")
+
+	return nil
+}
@@ -9,16 +9,133 @@ import (
 )
 
 func Main() {
+	cmd, run, args := parseArgs(os.Args[1:])
+
+	// parse again with the config's flags first, so that flags given on the
+	// command line take precedence
+	extraArgs, err := configArgs(cmd.Config, string(cmd.Env))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if len(extraArgs) > 0 {
+		cmd, run, args = parseArgs(append(extraArgs, os.Args[1:]...))
+	}
+
+	reports, err := parseReports(cmd.Reports)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = run.Execute(args)
+
+	// the diagnostics are collected by the reexeced binary, if there is one
+	if !cmd.shouldReexec() {
+		cmd.diagnostics.RecordError(err)
+
+		reportErr := cmd.writeReports(reports)
+		if reportErr != nil {
+			fmt.Fprintln(os.Stderr, reportErr)
+			os.Exit(1)
+		}
+	}
+
+	// the report is written by the reexeced binary, if there is one
+	if err != nil && cmd.ErrorReport != "" && !cmd.shouldReexec() {
+		reportErr := writeErrorReport(cmd.ErrorReport, err)
+		if reportErr != nil {
+			fmt.Fprintln(os.Stderr, reportErr)
+		}
+	}
+
+	// the log is written by the reexeced binary, if there is one
+	if cmd.ErrorFormat == "sarif" && !cmd.shouldReexec() {
+		sarifErr := cmd.diagnostics.WriteSARIF(os.Stdout)
+		if sarifErr != nil {
+			fmt.Fprintln(os.Stderr, sarifErr)
+			os.Exit(1)
+		}
+
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if err != nil && cmd.ErrorFormat == "json" {
+		jsonErr := booklit.WriteJSON(os.Stderr, err)
+		if jsonErr != nil {
+			fmt.Fprintln(os.Stderr, jsonErr)
+		}
+
+		os.Exit(1)
+	}
+
+	if err != nil {
+		if prettyErr, ok := err.(booklit.PrettyError); ok {
+			prettyErr.PrettyPrint(os.Stderr)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+// writeErrorReport writes an HTML page describing the error to the given
+// path.
+func writeErrorReport(path string, err error) error {
+	file, createErr := os.Create(path)
+	if createErr != nil {
+		return createErr
+	}
+
+	writeErr := booklit.WriteErrorPage(file, err)
+	if writeErr != nil {
+		file.Close()
+		return writeErr
+	}
+
+	return file.Close()
+}
+
+// parseArgs parses the command line, returning the command along with the
+// subcommand to run, if one was given, or else the command itself.
+func parseArgs(argv []string) (*Command, flags.Commander, []string) {
 	cmd := &Command{}
 	cmd.Version = func() {
 		fmt.Println(booklit.Version)
 		os.Exit(0)
 	}
 
+	cmd.Errors = func(format string) {
+		cmd.ErrorFormat = format
+	}
+
+	cmd.Resolve.Command = cmd
+	cmd.Syntax.Command = cmd
+	cmd.Fragment.Command = cmd
+	cmd.Lint.Command = cmd
+	cmd.ExportJSON.Command = cmd
+	cmd.ImportJSON.Command = cmd
+
+	var run flags.Commander = cmd
+
 	parser := flags.NewParser(cmd, flags.Default)
 	parser.NamespaceDelimiter = "-"
+	parser.SubcommandsOptional = true
+	parser.CommandHandler = func(command flags.Commander, args []string) error {
+		if command != nil {
+			run = command
+		}
+
+		return nil
+	}
 
-	args, err := parser.Parse()
+	args, err := parser.ParseArgs(argv)
 	if err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
 			fmt.Println(err)
@@ -28,14 +145,5 @@ func Main() {
 		}
 	}
 
-	err = cmd.Execute(args)
-	if err != nil {
-		if prettyErr, ok := err.(booklit.PrettyError); ok {
-			prettyErr.PrettyPrint(os.Stderr)
-		} else {
-			fmt.Fprintln(os.Stderr, err)
-		}
-
-		os.Exit(1)
-	}
+	return cmd, run, args
 }
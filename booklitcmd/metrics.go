@@ -0,0 +1,137 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/load"
+)
+
+// Metrics tracks build health and exposes it in the Prometheus text format.
+type Metrics struct {
+	Processor *load.Processor
+
+	builds        int
+	buildErrors   int
+	buildDuration time.Duration
+	lastDuration  time.Duration
+	sections      int
+
+	requests        map[int]int
+	requestDuration time.Duration
+
+	lock sync.Mutex
+}
+
+// ObserveBuild records the outcome of a single build of the given sections,
+// which may be empty if the build failed.
+func (metrics *Metrics) ObserveBuild(sections []*booklit.Section, duration time.Duration, err error) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.builds++
+	metrics.buildDuration += duration
+	metrics.lastDuration = duration
+
+	if err != nil {
+		metrics.buildErrors++
+	}
+
+	if len(sections) > 0 {
+		metrics.sections = 0
+		for _, section := range sections {
+			metrics.sections += countSections(section)
+		}
+	}
+}
+
+// ObserveRequest records a request served with the given status.
+func (metrics *Metrics) ObserveRequest(status int, duration time.Duration) {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	if metrics.requests == nil {
+		metrics.requests = map[int]int{}
+	}
+
+	metrics.requests[status]++
+	metrics.requestDuration += duration
+}
+
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	err := metrics.Write(w)
+	if err != nil {
+		logrus.Errorf("failed to write metrics: %s", err)
+	}
+}
+
+func (metrics *Metrics) Write(w io.Writer) error {
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	var hits, misses int
+	if metrics.Processor != nil {
+		hits, misses = metrics.Processor.CacheStats()
+	}
+
+	for _, metric := range []struct {
+		name  string
+		kind  string
+		help  string
+		value interface{}
+	}{
+		{"booklit_builds_total", "counter", "Number of builds.", metrics.builds},
+		{"booklit_build_errors_total", "counter", "Number of builds which failed.", metrics.buildErrors},
+		{"booklit_build_duration_seconds_total", "counter", "Total time spent building.", metrics.buildDuration.Seconds()},
+		{"booklit_last_build_duration_seconds", "gauge", "Time spent on the most recent build.", metrics.lastDuration.Seconds()},
+		{"booklit_sections", "gauge", "Number of sections in the most recent successful build.", metrics.sections},
+		{"booklit_parse_cache_hits_total", "counter", "Number of files whose parsed content was reused.", hits},
+		{"booklit_parse_cache_misses_total", "counter", "Number of files which had to be parsed.", misses},
+		{"booklit_request_duration_seconds_total", "counter", "Total time spent serving requests.", metrics.requestDuration.Seconds()},
+	} {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(metrics.requests) > 0 {
+		_, err := fmt.Fprintf(w, "# HELP booklit_requests_total Number of requests served, by status code.\n# TYPE booklit_requests_total counter\n")
+		if err != nil {
+			return err
+		}
+
+		statuses := []int{}
+		for status := range metrics.requests {
+			statuses = append(statuses, status)
+		}
+
+		sort.Ints(statuses)
+
+		for _, status := range statuses {
+			_, err := fmt.Fprintf(w, "booklit_requests_total{code=\"%d\"} %d\n", status, metrics.requests[status])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func countSections(section *booklit.Section) int {
+	count := 1
+	for _, child := range section.Children {
+		count += countSections(child)
+	}
+
+	return count
+}
@@ -0,0 +1,147 @@
+package booklitcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/load"
+)
+
+// logProgress logs how long each stage of a build took, at debug level unless
+// --verbose is given, and with --verbose, each section as it goes through
+// them.
+type logProgress struct {
+	verbose bool
+
+	stages  map[booklit.Stage]*stageProgress
+	stagesL sync.Mutex
+}
+
+// message logged for each section with --verbose, by stage
+var advanced = map[booklit.Stage]string{
+	booklit.StageParse:   "parsed",
+	booklit.StageResolve: "resolved",
+	booklit.StageRender:  "rendered",
+}
+
+type stageProgress struct {
+	total int
+	done  int
+}
+
+func (progress *logProgress) StartStage(stage booklit.Stage, total int) {
+	progress.stagesL.Lock()
+	defer progress.stagesL.Unlock()
+
+	if progress.stages == nil {
+		progress.stages = map[booklit.Stage]*stageProgress{}
+	}
+
+	progress.stages[stage] = &stageProgress{total: total}
+
+	logrus.WithFields(logrus.Fields{
+		"stage": stage,
+		"total": total,
+	}).Debug("starting stage")
+}
+
+func (progress *logProgress) Advance(stage booklit.Stage, section *booklit.Section) {
+	progress.stagesL.Lock()
+	state := progress.state(stage)
+	state.done++
+	done, total := state.done, state.total
+	progress.stagesL.Unlock()
+
+	if !progress.verbose {
+		return
+	}
+
+	fields := logrus.Fields{
+		"stage":   stage,
+		"section": section.PrimaryTag.Name,
+		"path":    section.FilePath(),
+		"done":    done,
+	}
+
+	// the number of files to parse isn't known up front
+	if total > 0 {
+		fields["total"] = total
+	}
+
+	logrus.WithFields(fields).Info(advanced[stage])
+}
+
+func (progress *logProgress) FinishStage(stage booklit.Stage, elapsed time.Duration) {
+	progress.stagesL.Lock()
+	done := progress.state(stage).done
+	delete(progress.stages, stage)
+	progress.stagesL.Unlock()
+
+	entry := logrus.WithFields(logrus.Fields{
+		"stage":    stage,
+		"sections": done,
+		"duration": elapsed.Seconds(),
+	})
+
+	if progress.verbose {
+		entry.Info("finished stage")
+	} else {
+		entry.Debug("finished stage")
+	}
+}
+
+// state returns the progress of the stage, tracking it from now on if it
+// wasn't started.
+func (progress *logProgress) state(stage booklit.Stage) *stageProgress {
+	if progress.stages == nil {
+		progress.stages = map[booklit.Stage]*stageProgress{}
+	}
+
+	state, found := progress.stages[stage]
+	if !found {
+		state = &stageProgress{}
+		progress.stages[stage] = state
+	}
+
+	return state
+}
+
+// printWarnings prints the warnings reported while loading to stderr, or
+// logs each of them with --log-format json so that the output stays
+// parseable.
+func printWarnings(processor *load.Processor) {
+	warnings := processor.Warnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	collected := &booklit.Warnings{Warnings: warnings}
+	printCollectedWarnings(collected)
+}
+
+func printCollectedWarnings(warnings *booklit.Warnings) {
+	if _, isJSON := logrus.StandardLogger().Formatter.(*logrus.JSONFormatter); !isJSON {
+		warnings.PrettyPrint(os.Stderr)
+		return
+	}
+
+	for _, warning := range warnings.Warnings {
+		log := logrus.NewEntry(logrus.StandardLogger())
+
+		// include the warning's type and location, as with --error-format json
+		if pretty, ok := warning.(interface{ PrettyJSON(io.Writer) error }); ok {
+			buf := new(bytes.Buffer)
+			if pretty.PrettyJSON(buf) == nil {
+				log = log.WithField("warning", json.RawMessage(buf.Bytes()))
+			}
+		}
+
+		log.Warn(warning.Error())
+	}
+}
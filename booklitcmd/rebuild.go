@@ -0,0 +1,106 @@
+package booklitcmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RebuildWebhook configures the server's /rebuild endpoint.
+type RebuildWebhook struct {
+	// Token which must be given as a bearer token, or used as the secret for a
+	// GitHub-style X-Hub-Signature-256 header.
+	Token string
+
+	// Run 'git pull' in the input's directory before rebuilding.
+	GitPull bool
+
+	// Called to rebuild the output; may be nil when only pulling.
+	Build func() error
+}
+
+func (server *Server) serveRebuild(w http.ResponseWriter, r *http.Request) {
+	log := logrus.WithFields(logrus.Fields{
+		"request": r.URL.Path,
+	})
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("failed to read request: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !server.Rebuild.authorized(r, body) {
+		log.Warn("unauthorized rebuild")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	server.buildLock.Lock()
+	defer server.buildLock.Unlock()
+
+	if server.Rebuild.GitPull {
+		log.Info("pulling")
+
+		pull := exec.Command("git", "pull", "--ff-only")
+		pull.Dir = filepath.Dir(server.In)
+
+		output, err := pull.CombinedOutput()
+		if err != nil {
+			log.Errorf("failed to pull: %s\n%s", err, output)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to pull: %s\n%s", err, output)
+			return
+		}
+	}
+
+	if server.Rebuild.Build != nil {
+		log.Info("rebuilding")
+
+		err := server.Rebuild.Build()
+		if err != nil {
+			log.Errorf("failed to rebuild: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to rebuild: %s\n", err)
+			return
+		}
+	}
+
+	fmt.Fprintln(w, "rebuilt")
+}
+
+func (webhook *RebuildWebhook) authorized(r *http.Request, body []byte) bool {
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		token := strings.TrimPrefix(bearer, "Bearer ")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(webhook.Token)) == 1
+	}
+
+	if sig := r.Header.Get("X-Hub-Signature-256"); strings.HasPrefix(sig, "sha256=") {
+		given, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, []byte(webhook.Token))
+		mac.Write(body)
+
+		return hmac.Equal(given, mac.Sum(nil))
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/load"
+)
+
+// report is a file to write the errors and warnings from a build to, given
+// by --report.
+type report struct {
+	Format string
+	Path   string
+}
+
+// report formats, by name
+var reportFormats = map[string]func(*booklit.Diagnostics, *os.File) error{
+	"sarif": func(diagnostics *booklit.Diagnostics, file *os.File) error {
+		return diagnostics.WriteSARIF(file)
+	},
+}
+
+func parseReports(specs []string) ([]report, error) {
+	reports := []report{}
+	for _, spec := range specs {
+		segs := strings.SplitN(spec, "=", 2)
+		if len(segs) != 2 || segs[1] == "" {
+			return nil, fmt.Errorf("invalid report (expected format=path): %s", spec)
+		}
+
+		if _, found := reportFormats[segs[0]]; !found {
+			return nil, fmt.Errorf("unknown report format: %s", segs[0])
+		}
+
+		reports = append(reports, report{
+			Format: segs[0],
+			Path:   segs[1],
+		})
+	}
+
+	return reports, nil
+}
+
+// writeReports writes the errors and warnings collected from the build to
+// each report.
+func (cmd *Command) writeReports(reports []report) error {
+	for _, report := range reports {
+		file, createErr := os.Create(report.Path)
+		if createErr != nil {
+			return createErr
+		}
+
+		writeErr := reportFormats[report.Format](&cmd.diagnostics, file)
+		if writeErr != nil {
+			_ = file.Close()
+			return fmt.Errorf("write %s report: %w", report.Format, writeErr)
+		}
+
+		closeErr := file.Close()
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+
+	return nil
+}
+
+// reportWarnings prints the warnings reported while loading, recording them
+// for --report and --error-format sarif.
+func (cmd *Command) reportWarnings(processor *load.Processor) {
+	cmd.diagnostics.RecordWarnings(processor.Warnings())
+	printWarnings(processor)
+}
+
+// failedLoad records the warnings reported before the load failed for
+// --report, returning the error. They aren't printed, so that the error
+// stands out.
+func (cmd *Command) failedLoad(processor *load.Processor, err error) error {
+	cmd.diagnostics.RecordWarnings(processor.Warnings())
+	return err
+}
@@ -0,0 +1,73 @@
+package booklitcmd
+
+import (
+	"fmt"
+
+	"github.com/vito/booklit"
+)
+
+// default limits for --safe
+const (
+	defaultMaxIncludeDepth = 16
+	defaultMaxFileSize     = 1 << 20
+)
+
+// checkSafe returns an error if any flags are configured which would run
+// commands or access the network, which --safe does not allow. Raw HTML is
+// sanitized regardless of --sanitize-html.
+func (cmd *Command) checkSafe() error {
+	unsafe := []struct {
+		flag string
+		set  bool
+	}{
+		{"--plugin", len(cmd.Plugins) > 0},
+		{"--external-plugin", len(cmd.ExternalPlugins) > 0},
+		{"--race", cmd.Race},
+		{"--rebuild-git-pull", cmd.RebuildGitPull},
+		{"--edit", cmd.Edit},
+		{"--contribute-repo", cmd.Contributions.Repo != ""},
+		{"--save-build-info", cmd.SaveBuildInfo},
+		{"--post-render", len(cmd.PostRender) > 0},
+		{"--post-build", len(cmd.PostBuild) > 0},
+		{"--html-pdf-command", len(cmd.HTMLEngine.PDFCommand) != 0},
+		{"--diagram-mermaid-command", len(cmd.Diagrams.MermaidCommand) != 0},
+		{"--diagram-dot-command", len(cmd.Diagrams.DotCommand) != 0},
+		{"--math-command", len(cmd.Math.Command) != 0},
+		{"--math-display-command", len(cmd.Math.DisplayCommand) != 0},
+		{"--image-width", len(cmd.Images.Widths) > 0},
+		{"--image-webp-command", len(cmd.Images.WebPCommand) != 0},
+		{"--pdf-render", cmd.PDFEngine.Render},
+		{"--renderer pdf", cmd.Renderer == "pdf"},
+		{"--confluence-url", cmd.Confluence.URL != ""},
+		{"--embeddings-url", cmd.Embeddings.URL != ""},
+		{"--embeddings-command", len(cmd.Embeddings.Command) != 0},
+	}
+
+	for _, option := range unsafe {
+		if option.set {
+			return fmt.Errorf("%s is not supported with --safe", option.flag)
+		}
+	}
+
+	cmd.SanitizeHTML = true
+
+	return nil
+}
+
+// safeMode returns the limits configured for --safe.
+func (cmd *Command) safeMode() *booklit.SafeMode {
+	safe := &booklit.SafeMode{
+		MaxIncludeDepth: cmd.MaxIncludeDepth,
+		MaxFileSize:     cmd.MaxFileSize,
+	}
+
+	if safe.MaxIncludeDepth == 0 {
+		safe.MaxIncludeDepth = defaultMaxIncludeDepth
+	}
+
+	if safe.MaxFileSize == 0 {
+		safe.MaxFileSize = defaultMaxFileSize
+	}
+
+	return safe
+}
@@ -1,9 +1,14 @@
 package booklitcmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vito/booklit"
@@ -20,6 +25,30 @@ type Server struct {
 
 	FileServer http.Handler
 
+	// If set, each build is recorded.
+	Metrics *Metrics
+
+	// If set, POST /rebuild pulls and rebuilds the content.
+	Rebuild *RebuildWebhook
+
+	// If set, each page includes a panel for editing its source, which is
+	// loaded and saved via /_booklit/edit, and optionally proposed as a pull
+	// request via /_booklit/contribute.
+	Editor *Editor
+
+	// If set, search queries which find nothing and paths which aren't found
+	// are recorded, and reported via /_booklit/analytics.
+	Analytics *Analytics
+
+	// If non-zero, pages which take longer than this to load and render are
+	// logged as a warning.
+	SlowRender time.Duration
+
+	// If non-zero, loading a page is cancelled once it takes longer than
+	// this, e.g. because a plugin hung, responding with 503 Service
+	// Unavailable. Loading is always cancelled if the request is.
+	BuildTimeout time.Duration
+
 	buildLock sync.Mutex
 }
 
@@ -30,19 +59,29 @@ func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	log.Debugln("serving")
 
-	section, found, err := server.loadRequestedSection(r.URL.Path)
-	if err != nil {
-		log.Errorf("failed to load section: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		booklit.ErrorPage(err, w)
+	if server.Rebuild != nil && r.URL.Path == "/rebuild" {
+		server.serveRebuild(w, r)
 		return
 	}
 
-	if !found {
-		server.FileServer.ServeHTTP(w, r)
+	if server.Editor != nil && r.URL.Path == editPath {
+		server.serveEdit(w, r)
+		return
+	}
+
+	if server.Analytics != nil && r.URL.Path == analyticsPath {
+		server.serveAnalytics(w, r)
+		return
+	}
+
+	if server.Editor != nil && server.Editor.Contributions != nil && r.URL.Path == contributePath {
+		server.serveContribute(w, r)
 		return
 	}
 
+	start := time.Now()
+
+	// templates are loaded first so that plugins can see which are available
 	server.buildLock.Lock()
 	defer server.buildLock.Unlock()
 
@@ -56,49 +95,240 @@ func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	ctx := r.Context()
+	if server.BuildTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, server.BuildTimeout)
+		defer cancel()
+	}
+
+	section, found, err := server.loadRequestedSection(ctx, r.URL.Path)
+	if err != nil {
+		server.observeBuild(nil, start, err)
+		log.Errorf("failed to load section: %s", err)
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusServiceUnavailable
+		}
+
+		if server.Editor != nil {
+			// allow editing the source to fix the error
+			buf := new(bytes.Buffer)
+			renderErr := booklit.WriteErrorPage(buf, err)
+			if renderErr != nil {
+				fmt.Fprintf(buf, "failed to render error page: %s", renderErr)
+			}
+
+			w.WriteHeader(status)
+			_, _ = w.Write(server.injectEditor(buf.Bytes(), r.URL.Path))
+			return
+		}
+
+		w.WriteHeader(status)
+		booklit.ErrorPage(err, w)
+		return
+	}
+
+	if !found {
+		server.FileServer.ServeHTTP(&errorPageWriter{
+			ResponseWriter: w,
+			server:         server,
+			request:        r,
+		}, r)
+		return
+	}
+
 	log = log.WithFields(logrus.Fields{
 		"section": section.Path,
 	})
 
 	log.Info("rendering")
 
-	err = server.Engine.RenderSection(w, section)
+	buf := new(bytes.Buffer)
+	err = server.Engine.RenderSection(buf, section)
+	server.observeBuild(section, start, err)
+
+	if elapsed := time.Since(start); server.SlowRender != 0 && elapsed > server.SlowRender {
+		log.WithFields(logrus.Fields{
+			"duration": elapsed.Seconds(),
+		}).Warn("slow render")
+	}
 	if err != nil {
 		log.Errorf("failed to render: %s", err)
+
+		if server.serveErrorPage(w, section.Top(), http.StatusInternalServerError) {
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 		booklit.ErrorPage(err, w)
 		return
 	}
 
-	return
+	page := buf.Bytes()
+
+	if server.Analytics != nil {
+		page = server.injectAnalytics(page)
+	}
+
+	if server.Editor != nil {
+		page = server.injectEditor(page, r.URL.Path)
+	}
+
+	_, _ = w.Write(page)
 }
 
-func (server *Server) loadRequestedSection(path string) (*booklit.Section, bool, error) {
-	ext := server.Engine.FileExtension()
+// stripBasePath removes the base path from requests which include it, so
+// that the site can be served whether or not a reverse proxy in front of it
+// removes the base path itself.
+func stripBasePath(basePath string, handler http.Handler) http.Handler {
+	prefix := strings.TrimSuffix(basePath, "/")
+	stripped := http.StripPrefix(prefix, handler)
 
-	if path == "/" {
-		path = "/index." + ext
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, basePath) {
+			stripped.ServeHTTP(w, r)
+		} else {
+			handler.ServeHTTP(w, r)
+		}
+	})
+}
+
+// serveErrorPage renders the book's error page for the status, if it has
+// one, returning whether it was served.
+func (server *Server) serveErrorPage(w http.ResponseWriter, root *booklit.Section, status int) bool {
+	page := root.FindErrorPage(status)
+	if page == nil {
+		return false
 	}
 
-	if !strings.HasSuffix(path, "."+ext) {
-		return nil, false, nil
+	buf := new(bytes.Buffer)
+	err := render.RenderErrorPage(server.Engine, buf, page, "")
+	if err != nil {
+		logrus.Errorf("failed to render error page: %s", err)
+		return false
 	}
 
-	tagName := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "."+ext)
+	html := buf.Bytes()
 
-	logrus.WithFields(logrus.Fields{
-		"section": server.In,
-	}).Info("loading root section")
+	// e.g. for searching from a 404 page
+	if server.Analytics != nil {
+		html = server.injectAnalytics(html)
+	}
+
+	w.Header().Del("X-Content-Type-Options")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(html)
+
+	return true
+}
+
+// errorPageWriter replaces the file server's 404 response with the book's
+// 404 page, if it has one.
+type errorPageWriter struct {
+	http.ResponseWriter
+
+	server      *Server
+	request     *http.Request
+	intercepted bool
+}
+
+func (w *errorPageWriter) WriteHeader(status int) {
+	if status == http.StatusNotFound && w.server.Analytics != nil {
+		w.server.Analytics.RecordNotFound(w.request)
+	}
+
+	if status == http.StatusNotFound {
+		root, err := w.server.loadRoot(w.request.Context())
+		if err == nil && w.server.serveErrorPage(w.ResponseWriter, root, status) {
+			w.intercepted = true
+			return
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorPageWriter) Write(p []byte) (int, error) {
+	if w.intercepted {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
 
-	rootSection, err := server.Processor.LoadFile(server.In, basePluginFactories)
+func (server *Server) observeBuild(section *booklit.Section, start time.Time, err error) {
+	if server.Metrics != nil {
+		var sections []*booklit.Section
+		if section != nil {
+			sections = append(sections, section)
+		}
+
+		server.Metrics.ObserveBuild(sections, time.Since(start), err)
+	}
+}
+
+func (server *Server) loadRequestedSection(ctx context.Context, path string) (*booklit.Section, bool, error) {
+	link, ok := server.requestedPermalink(path)
+	if !ok {
+		return nil, false, nil
+	}
+
+	rootSection, err := server.loadRoot(ctx)
 	if err != nil {
 		return nil, false, err
 	}
 
-	tags := rootSection.FindTag(tagName)
+	if page := rootSection.FindPermalink(link); page != nil {
+		return page, true, nil
+	}
+
+	tags := rootSection.FindTag(link)
 	if len(tags) == 0 {
 		return nil, false, nil
 	}
 
 	return tags[0].Section, true, nil
 }
+
+// requestedPermalink returns the permalink of the page at the given path,
+// which may be in any URL style, e.g. /tag.html or /tag/. Extensionless paths
+// are only considered when using booklit.URLStyleExtensionless, so that other
+// files can still be served.
+func (server *Server) requestedPermalink(path string) (string, bool) {
+	ext := "." + server.Engine.FileExtension()
+
+	path = strings.TrimPrefix(path, "/")
+
+	switch {
+	case path == "":
+		return "index", true
+	case strings.HasSuffix(path, "/index"+ext):
+		return strings.TrimSuffix(path, "/index"+ext), true
+	case strings.HasSuffix(path, "/"):
+		return strings.TrimSuffix(path, "/"), true
+	case strings.HasSuffix(path, ext):
+		return strings.TrimSuffix(path, ext), true
+	case server.Processor.URLStyle == booklit.URLStyleExtensionless && !strings.Contains(path, "."):
+		return path, true
+	default:
+		return "", false
+	}
+}
+
+func (server *Server) loadRoot(ctx context.Context) (*booklit.Section, error) {
+	logrus.WithFields(logrus.Fields{
+		"section": server.In,
+	}).Info("loading root section")
+
+	section, err := server.Processor.LoadFileContext(ctx, server.In, basePluginFactories)
+	if err != nil {
+		return nil, err
+	}
+
+	printWarnings(server.Processor)
+
+	return section, nil
+}
@@ -0,0 +1,186 @@
+package booklitcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// SyntaxCommand prints a syntax definition for highlighting .lit files in an
+// editor, which recognizes the functions of every plugin given by --plugin
+// along with the base plugin.
+type SyntaxCommand struct {
+	Command *Command `no-flag:"true"`
+
+	Target string `long:"target" short:"t" required:"true" choice:"vscode" choice:"vim" choice:"sublime" description:"Editor to print the syntax definition for."`
+}
+
+func (cmd *SyntaxCommand) Execute(args []string) error {
+	cmd.Command.configureLogging()
+
+	if cmd.Command.shouldReexec() {
+		return cmd.Command.reexec()
+	}
+
+	stop, err := cmd.Command.startExternalPlugins()
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	functions := pluginFunctions()
+
+	switch cmd.Target {
+	case "vscode":
+		return writeVSCodeSyntax(os.Stdout, functions)
+	case "vim":
+		return writeVimSyntax(os.Stdout, functions)
+	case "sublime":
+		return writeSublimeSyntax(os.Stdout, functions)
+	default:
+		return fmt.Errorf("unknown target: %s", cmd.Target)
+	}
+}
+
+// pluginFunctions returns the names of the functions provided by every
+// registered plugin, sorted and without duplicates.
+func pluginFunctions() []string {
+	seen := map[string]bool{}
+	functions := []string{}
+
+	for _, name := range booklit.PluginNames() {
+		factory, _ := booklit.LookupPlugin(name)
+
+		for _, fn := range booklit.PluginFunctions(factory(&booklit.Section{})) {
+			if seen[fn] {
+				continue
+			}
+
+			seen[fn] = true
+			functions = append(functions, fn)
+		}
+	}
+
+	sort.Strings(functions)
+
+	return functions
+}
+
+func writeVSCodeSyntax(out io.Writer, functions []string) error {
+	grammar := map[string]interface{}{
+		"$schema":   "https://raw.githubusercontent.com/martinring/tmlanguage/master/tmlanguage.json",
+		"name":      "Booklit",
+		"scopeName": "text.booklit",
+		"fileTypes": []string{"lit"},
+		"patterns": []interface{}{
+			map[string]string{"include": "#comment"},
+			map[string]string{"include": "#verbatim"},
+			map[string]string{"include": "#function"},
+			map[string]string{
+				"match": `[{}]`,
+				"name":  "punctuation.section.braces.booklit",
+			},
+		},
+		"repository": map[string]interface{}{
+			"comment": map[string]interface{}{
+				"begin": `\{-`,
+				"end":   `-\}`,
+				"name":  "comment.block.booklit",
+				"patterns": []interface{}{
+					map[string]string{"include": "#comment"},
+				},
+			},
+			"verbatim": map[string]interface{}{
+				"begin": `\{\{\{`,
+				"end":   `\}\}\}`,
+				"name":  "markup.raw.block.booklit",
+			},
+			"function": map[string]interface{}{
+				"patterns": []interface{}{
+					map[string]string{
+						"match": `\\(?:` + strings.Join(functions, "|") + `)(?![a-z-])`,
+						"name":  "support.function.booklit",
+					},
+					map[string]string{
+						"match": `\\[a-z-]+`,
+						"name":  "variable.function.booklit",
+					},
+				},
+			},
+		},
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(grammar)
+}
+
+func writeVimSyntax(out io.Writer, functions []string) error {
+	_, err := fmt.Fprintf(out, `" Vim syntax file
+" Language: Booklit
+" Generated by: booklit syntax --target vim
+
+if exists("b:current_syntax")
+  finish
+endif
+
+syn match booklitBrace "[{}]"
+syn match booklitFunction "\\[a-z-]\+"
+syn match booklitKnownFunction "\\\%%(%s\)[a-z-]\@!"
+syn region booklitVerbatim start="{{{" end="}}}"
+syn region booklitComment start="{-" end="-}" contains=booklitComment
+
+hi def link booklitBrace Delimiter
+hi def link booklitFunction Identifier
+hi def link booklitKnownFunction Function
+hi def link booklitVerbatim String
+hi def link booklitComment Comment
+
+let b:current_syntax = "booklit"
+`, strings.Join(functions, `\|`))
+
+	return err
+}
+
+func writeSublimeSyntax(out io.Writer, functions []string) error {
+	_, err := fmt.Fprintf(out, `%%YAML 1.2
+---
+# Generated by: booklit syntax --target sublime
+name: Booklit
+file_extensions: [lit]
+scope: text.booklit
+
+contexts:
+  main:
+    - include: comment
+    - match: '\{\{\{'
+      push: verbatim
+    - match: '\\(?:%s)(?![a-z-])'
+      scope: support.function.booklit
+    - match: '\\[a-z-]+'
+      scope: variable.function.booklit
+    - match: '[{}]'
+      scope: punctuation.section.braces.booklit
+
+  comment:
+    - match: '\{-'
+      push:
+        - meta_scope: comment.block.booklit
+        - match: '-\}'
+          pop: true
+        - include: comment
+
+  verbatim:
+    - meta_scope: markup.raw.block.booklit
+    - match: '\}\}\}'
+      pop: true
+`, strings.Join(functions, "|"))
+
+	return err
+}
@@ -0,0 +1,25 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/vito/booklit"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// loadTerminology loads the YAML terminology file given by --terminology.
+func loadTerminology(path string) (*booklit.Terminology, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var terminology booklit.Terminology
+	err = yaml.UnmarshalStrict(content, &terminology)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminology %s: %s", path, err)
+	}
+
+	return &terminology, nil
+}
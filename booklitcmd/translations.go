@@ -0,0 +1,45 @@
+package booklitcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/vito/booklit"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// loadTranslations loads the catalog for each locale from the directory
+// given by --translations, e.g. ja.yml for ja.
+func loadTranslations(dir string) (booklit.Translations, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	translations := booklit.Translations{}
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var catalog map[string]string
+		err = yaml.UnmarshalStrict(content, &catalog)
+		if err != nil {
+			return nil, fmt.Errorf("invalid translations %s: %s", path, err)
+		}
+
+		translations[strings.TrimSuffix(file.Name(), ext)] = catalog
+	}
+
+	return translations, nil
+}
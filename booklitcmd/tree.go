@@ -0,0 +1,534 @@
+package booklitcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+	"github.com/vito/booklit/load"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ASTCommand prints the syntax tree of a .lit file as it was parsed, before
+// any functions are evaluated.
+type ASTCommand struct {
+	Format string `long:"format" short:"f" choice:"json" choice:"yaml" description:"Format to print the tree in. Defaults to json."`
+
+	Args struct {
+		File string `positional-arg-name:"file" description:".lit file to parse."`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (cmd *ASTCommand) Execute(args []string) error {
+	node, err := load.ParseFile(cmd.Args.File)
+	if err != nil {
+		return err
+	}
+
+	tree := &astTree{}
+
+	err = node.Visit(tree)
+	if err != nil {
+		return err
+	}
+
+	return printTree(os.Stdout, cmd.Format, tree.Result)
+}
+
+// ResolveCommand prints the content tree of a section loaded from --in, after
+// every function has been evaluated and every reference resolved.
+type ResolveCommand struct {
+	Command *Command `no-flag:"true"`
+
+	Section Tag    `long:"section" description:"Tag of the section to print. Defaults to the section loaded from --in."`
+	Format  string `long:"format" short:"f" choice:"json" choice:"yaml" description:"Format to print the tree in. Defaults to json."`
+}
+
+func (cmd *ResolveCommand) Execute(args []string) error {
+	cmd.Command.configureLogging()
+
+	if cmd.Command.shouldReexec() {
+		return cmd.Command.reexec()
+	}
+
+	stopWatching := cmd.Command.watchInterrupts()
+	defer stopWatching()
+
+	if cmd.Command.In == "" {
+		return fmt.Errorf("--in must be specified")
+	}
+
+	stop, err := cmd.Command.startExternalPlugins()
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	section, err := cmd.Command.processor().LoadFileContext(cmd.Command.context(), cmd.Command.In, basePluginFactories)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Section != "" {
+		tags := section.FindTag(string(cmd.Section))
+		if len(tags) == 0 {
+			return fmt.Errorf("unknown tag: %s", cmd.Section)
+		}
+
+		section = tags[0].Section
+	}
+
+	tree := &contentTree{}
+
+	err = section.Visit(tree)
+	if err != nil {
+		return err
+	}
+
+	return printTree(os.Stdout, cmd.Format, tree.Result)
+}
+
+func printTree(out io.Writer, format string, tree interface{}) error {
+	if format == "yaml" {
+		return yaml.NewEncoder(out).Encode(tree)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tree)
+}
+
+// node is a single node of a printed tree, keyed by field name and
+// identified by its "type".
+type node map[string]interface{}
+
+// astTree converts a syntax tree into nodes.
+type astTree struct {
+	Result interface{}
+
+	// leave out the locations of invocations, e.g. so that the tree is the
+	// same wherever it is in a file
+	OmitLocations bool
+}
+
+func (tree *astTree) convert(n ast.Node) (interface{}, error) {
+	sub := &astTree{
+		OmitLocations: tree.OmitLocations,
+	}
+
+	err := n.Visit(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub.Result, nil
+}
+
+func (tree *astTree) convertSequences(seqs []ast.Sequence) ([]interface{}, error) {
+	nodes := []interface{}{}
+	for _, seq := range seqs {
+		n, err := tree.convert(seq)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
+
+func (tree *astTree) VisitString(str ast.String) error {
+	tree.Result = node{"type": "string", "value": string(str)}
+	return nil
+}
+
+func (tree *astTree) VisitInvoke(invoke ast.Invoke) error {
+	args := []interface{}{}
+	for _, arg := range invoke.Arguments {
+		n, err := tree.convert(arg)
+		if err != nil {
+			return err
+		}
+
+		args = append(args, n)
+	}
+
+	result := node{
+		"type":      "invoke",
+		"function":  invoke.Function,
+		"arguments": args,
+	}
+
+	if !tree.OmitLocations {
+		result["location"] = node{
+			"line":   invoke.Location.Line,
+			"column": invoke.Location.Col,
+		}
+	}
+
+	tree.Result = result
+
+	return nil
+}
+
+func (tree *astTree) VisitSequence(seq ast.Sequence) error {
+	nodes := []interface{}{}
+	for _, n := range seq {
+		converted, err := tree.convert(n)
+		if err != nil {
+			return err
+		}
+
+		nodes = append(nodes, converted)
+	}
+
+	tree.Result = node{"type": "sequence", "nodes": nodes}
+
+	return nil
+}
+
+func (tree *astTree) VisitParagraph(para ast.Paragraph) error {
+	lines, err := tree.convertSequences(para)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "paragraph", "lines": lines}
+
+	return nil
+}
+
+func (tree *astTree) VisitPreformatted(pre ast.Preformatted) error {
+	lines, err := tree.convertSequences(pre)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "preformatted", "lines": lines}
+
+	return nil
+}
+
+// contentTree converts evaluated content into nodes.
+type contentTree struct {
+	Result interface{}
+}
+
+func (tree *contentTree) convert(content booklit.Content) (interface{}, error) {
+	if content == nil {
+		return nil, nil
+	}
+
+	sub := &contentTree{}
+
+	err := content.Visit(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub.Result, nil
+}
+
+func (tree *contentTree) convertAll(contents []booklit.Content) ([]interface{}, error) {
+	nodes := []interface{}{}
+	for _, content := range contents {
+		n, err := tree.convert(content)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
+
+func (tree *contentTree) VisitString(con booklit.String) error {
+	tree.Result = node{"type": "string", "value": string(con)}
+	return nil
+}
+
+func (tree *contentTree) VisitSequence(con booklit.Sequence) error {
+	contents, err := tree.convertAll(con)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "sequence", "contents": contents}
+
+	return nil
+}
+
+func (tree *contentTree) VisitReference(con *booklit.Reference) error {
+	n := node{"type": "reference", "tag": con.TagName}
+
+	if con.Content != nil {
+		content, err := tree.convert(con.Content)
+		if err != nil {
+			return err
+		}
+
+		n["content"] = content
+	}
+
+	tree.Result = n
+
+	return nil
+}
+
+func (tree *contentTree) VisitLink(con booklit.Link) error {
+	content, err := tree.convert(con.Content)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "link", "target": con.Target, "content": content}
+
+	return nil
+}
+
+func (tree *contentTree) VisitSection(con *booklit.Section) error {
+	title, err := tree.convert(con.Title)
+	if err != nil {
+		return err
+	}
+
+	body, err := tree.convert(con.Body)
+	if err != nil {
+		return err
+	}
+
+	tags := []string{}
+	for _, tag := range con.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	children := []interface{}{}
+	for _, child := range con.Children {
+		n, err := tree.convert(child)
+		if err != nil {
+			return err
+		}
+
+		children = append(children, n)
+	}
+
+	tree.Result = node{
+		"type":     "section",
+		"tags":     tags,
+		"number":   con.Number(),
+		"title":    title,
+		"body":     body,
+		"children": children,
+	}
+
+	return nil
+}
+
+func (tree *contentTree) VisitParagraph(con booklit.Paragraph) error {
+	lines, err := tree.convertAll(con)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "paragraph", "lines": lines}
+
+	return nil
+}
+
+func (tree *contentTree) VisitTableOfContents(con booklit.TableOfContents) error {
+	tree.Result = node{"type": "table-of-contents"}
+	return nil
+}
+
+func (tree *contentTree) VisitPreformatted(con booklit.Preformatted) error {
+	lines, err := tree.convertAll(con)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "preformatted", "lines": lines}
+
+	return nil
+}
+
+func (tree *contentTree) VisitStyled(con booklit.Styled) error {
+	content, err := tree.convert(con.Content)
+	if err != nil {
+		return err
+	}
+
+	n := node{
+		"type":    "styled",
+		"style":   string(con.Style),
+		"block":   con.Block,
+		"content": content,
+	}
+
+	if len(con.Partials) > 0 {
+		names := []string{}
+		for name := range con.Partials {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		partials := node{}
+		for _, name := range names {
+			partial, err := tree.convert(con.Partials[name])
+			if err != nil {
+				return err
+			}
+
+			partials[name] = partial
+		}
+
+		n["partials"] = partials
+	}
+
+	tree.Result = n
+
+	return nil
+}
+
+func (tree *contentTree) VisitTarget(con booklit.Target) error {
+	title, err := tree.convert(con.Title)
+	if err != nil {
+		return err
+	}
+
+	content, err := tree.convert(con.Content)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{
+		"type":    "target",
+		"tag":     con.TagName,
+		"title":   title,
+		"content": content,
+	}
+
+	return nil
+}
+
+func (tree *contentTree) VisitImage(con booklit.Image) error {
+	tree.Result = node{
+		"type":        "image",
+		"path":        con.Path,
+		"description": con.Description,
+	}
+
+	return nil
+}
+
+func (tree *contentTree) VisitList(con booklit.List) error {
+	items, err := tree.convertAll(con.Items)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{"type": "list", "ordered": con.Ordered, "items": items}
+
+	return nil
+}
+
+func (tree *contentTree) VisitTable(con booklit.Table) error {
+	rows := []interface{}{}
+	for _, row := range con.Rows {
+		cells, err := tree.convertAll(row)
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, cells)
+	}
+
+	table := node{"type": "table", "rows": rows}
+
+	if con.HeaderRows > 0 {
+		table["header_rows"] = con.HeaderRows
+	}
+
+	if len(con.Alignments) > 0 {
+		table["alignments"] = con.Alignments
+	}
+
+	tree.Result = table
+
+	return nil
+}
+
+func (tree *contentTree) VisitDefinitions(con booklit.Definitions) error {
+	defs := []interface{}{}
+	for _, def := range con {
+		subject, err := tree.convert(def.Subject)
+		if err != nil {
+			return err
+		}
+
+		definition, err := tree.convert(def.Definition)
+		if err != nil {
+			return err
+		}
+
+		defs = append(defs, node{"subject": subject, "definition": definition})
+	}
+
+	tree.Result = node{"type": "definitions", "definitions": defs}
+
+	return nil
+}
+
+func (tree *contentTree) VisitFigure(con *booklit.Figure) error {
+	content, err := tree.convert(con.Content)
+	if err != nil {
+		return err
+	}
+
+	caption, err := tree.convert(con.Caption)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{
+		"type":    "figure",
+		"tag":     con.TagName,
+		"number":  con.Number(),
+		"content": content,
+		"caption": caption,
+	}
+
+	return nil
+}
+
+func (tree *contentTree) VisitFootnote(con *booklit.Footnote) error {
+	content, err := tree.convert(con.Content)
+	if err != nil {
+		return err
+	}
+
+	tree.Result = node{
+		"type":    "footnote",
+		"number":  con.Number(),
+		"content": content,
+	}
+
+	return nil
+}
+
+func (tree *contentTree) VisitListOfFigures(con booklit.ListOfFigures) error {
+	tree.Result = node{"type": "list-of-figures"}
+	return nil
+}
+
+func (tree *contentTree) VisitAttributions(con booklit.Attributions) error {
+	tree.Result = node{"type": "attributions"}
+	return nil
+}
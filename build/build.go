@@ -0,0 +1,202 @@
+// Package build builds books from Go programs, the way the booklit command
+// does, without shelling out to it.
+package build
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+// Config configures a build.
+type Config struct {
+	// Path of the top-level .lit file to build.
+	In string
+
+	// File system which In and the files it refers to are read from, e.g. an
+	// embed.FS. Defaults to the operating system's.
+	FS fs.FS
+
+	// Directory to which pages are written. If empty, the top-level section
+	// is rendered to Output instead.
+	Out string
+
+	// Where pages are written instead of Out, e.g. a *render.MemoryOutput.
+	Destination render.Output
+
+	// Writer to which the top-level section is rendered when neither Out nor
+	// Destination is set. Defaults to os.Stdout.
+	Output io.Writer
+
+	// Engine which pages are rendered with. Defaults to
+	// render.NewHTMLRenderingEngine().
+	Engine render.RenderingEngine
+
+	// Processor which the book is loaded with, e.g. to set its base path or
+	// make it strict. Defaults to an empty load.Processor.
+	Processor *load.Processor
+
+	// Plugins available to the book in addition to baselit, which is always
+	// available.
+	Plugins []booklit.PluginFactory
+
+	// Number of pages to render at once, if the engine supports it. Defaults
+	// to 1.
+	Jobs int
+
+	// If set, a search index is written to search_index.json in Out.
+	SaveSearchIndex bool
+
+	// Commands run on each page written to Out, e.g. validators. Requires
+	// Out rather than Destination.
+	Hooks []render.Hook
+
+	// If set, it's notified as the book's files are parsed, its sections
+	// resolved, and its pages rendered, e.g. to report the build's progress.
+	// Pages rendered to Output aren't reported.
+	Progress booklit.Progress
+}
+
+// Result is the outcome of a successful build.
+type Result struct {
+	// The book that was built.
+	Book *booklit.Section
+
+	// Warnings reported while loading the book.
+	Warnings []booklit.Warning
+
+	// URL of each tag in the book, relative to where it was written. Empty
+	// when it was rendered to Output.
+	Manifest render.Manifest
+}
+
+// Build loads the book and renders it.
+//
+// Errors from loading the book are returned as the same types the booklit
+// command reports, e.g. booklit.ParseError or *booklit.BuildErrors, which can
+// be inspected with errors.As or written with booklit.WriteJSON.
+//
+// The build stops once the context is done, returning its error, possibly
+// wrapped with where it stopped, e.g. in a booklit.FailedFunctionError for a
+// plugin which didn't return in time. Plugins are given the context; see
+// booklit.Section.Context.
+func Build(ctx context.Context, config Config) (Result, error) {
+	if config.In == "" {
+		return Result{}, errors.New("no input file given")
+	}
+
+	engine := config.Engine
+	if engine == nil {
+		engine = render.NewHTMLRenderingEngine()
+	}
+
+	processor := config.Processor
+	if processor == nil {
+		processor = &load.Processor{}
+	}
+
+	if info, ok := engine.(booklit.RenderingEngine); ok {
+		processor.Engine = info
+	}
+
+	if config.FS != nil {
+		processor.FS = config.FS
+	}
+
+	if config.Progress != nil {
+		processor.Progress = config.Progress
+	}
+
+	factories := append([]booklit.PluginFactory{baselit.NewPlugin}, config.Plugins...)
+
+	book, err := processor.LoadFileContext(ctx, config.In, factories)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Book:     book,
+		Warnings: processor.Warnings(),
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return Result{}, err
+	}
+
+	if config.Out == "" && config.Destination == nil {
+		output := config.Output
+		if output == nil {
+			output = os.Stdout
+		}
+
+		return result, engine.RenderSection(output, book)
+	}
+
+	result.Manifest, err = write(config, engine, book)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+func write(config Config, engine render.RenderingEngine, book *booklit.Section) (render.Manifest, error) {
+	if config.Destination == nil {
+		err := os.MkdirAll(config.Out, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// relative paths to assets are resolved against the destination
+	switch documentEngine := engine.(type) {
+	case *render.PDFRenderingEngine:
+		documentEngine.Directory = config.Out
+	case *render.EPUBRenderingEngine:
+		documentEngine.Directory = config.Out
+	}
+
+	writer := render.Writer{
+		Engine:      engine,
+		Destination: config.Out,
+		Output:      config.Destination,
+		Jobs:        config.Jobs,
+		Hooks:       config.Hooks,
+		Progress:    config.Progress,
+	}
+
+	err := writer.WriteSection(book)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, isDocument := engine.(render.DocumentRenderingEngine); !isDocument {
+		err = writer.WriteErrorPages(book)
+		if err != nil {
+			return nil, err
+		}
+
+		// sections' aliases are redirected to them
+		err = writer.WriteRedirects(book, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.SaveSearchIndex {
+		err = writer.WriteSearchIndex(book, "search_index.json")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return writer.Manifest(book), nil
+}
@@ -0,0 +1,166 @@
+package changelog
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Commit is a single commit message parsed according to the Conventional
+// Commits format, i.e. "type(scope)!: subject".
+type Commit struct {
+	Hash string
+
+	Type     string
+	Scope    string
+	Subject  string
+	Breaking bool
+}
+
+// Group is a set of commits sharing the same type.
+type Group struct {
+	Type    string
+	Title   string
+	Commits []Commit
+}
+
+// Types configures the order and titles of the groups that are emitted.
+// Commits with any other type are omitted.
+var Types = []Group{
+	{Type: "feat", Title: "Features"},
+	{Type: "fix", Title: "Bug Fixes"},
+	{Type: "perf", Title: "Performance Improvements"},
+	{Type: "revert", Title: "Reverts"},
+	{Type: "docs", Title: "Documentation"},
+}
+
+var headerRegexp = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: (.+)$`)
+
+// Parse parses the given commit message. It returns false if the message does
+// not follow the Conventional Commits format.
+func Parse(hash string, message string) (Commit, bool) {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+
+	match := headerRegexp.FindStringSubmatch(lines[0])
+	if match == nil {
+		return Commit{}, false
+	}
+
+	commit := Commit{
+		Hash:     hash,
+		Type:     strings.ToLower(match[1]),
+		Scope:    match[2],
+		Breaking: match[3] != "",
+		Subject:  match[4],
+	}
+
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			commit.Breaking = true
+		}
+	}
+
+	return commit, true
+}
+
+// Log collects the commits reachable from `to` but not from `from` in the git
+// repository at dir. If from is empty, all commits reachable from `to` are
+// collected.
+func Log(dir string, from string, to string) ([]Commit, error) {
+	rev := to
+	if from != "" {
+		rev = from + ".." + to
+	}
+
+	cmd := exec.Command("git", "log", "--format=%H%x1f%B%x1e", rev)
+	cmd.Dir = dir
+
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s failed: %w: %s", rev, err, strings.TrimSpace(stderr.String()))
+	}
+
+	commits := []Commit{}
+	for _, entry := range strings.Split(string(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(entry), "\x1f", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		commit, ok := Parse(fields[0], fields[1])
+		if !ok {
+			continue
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// Filter selects commits by scope. A scope prefixed with "!" is excluded;
+// any other scope is included. If no scopes are included, all scopes which
+// are not excluded are selected.
+func Filter(commits []Commit, scopes ...string) []Commit {
+	include := map[string]bool{}
+	exclude := map[string]bool{}
+	for _, scope := range scopes {
+		if strings.HasPrefix(scope, "!") {
+			exclude[strings.TrimPrefix(scope, "!")] = true
+		} else {
+			include[scope] = true
+		}
+	}
+
+	filtered := []Commit{}
+	for _, commit := range commits {
+		if exclude[commit.Scope] {
+			continue
+		}
+
+		if len(include) > 0 && !include[commit.Scope] {
+			continue
+		}
+
+		filtered = append(filtered, commit)
+	}
+
+	return filtered
+}
+
+// GroupByType groups the commits by their type, following the order of
+// Types. Breaking changes are additionally collected into a leading group.
+func GroupByType(commits []Commit) []Group {
+	groups := []Group{}
+
+	breaking := Group{Type: "breaking", Title: "Breaking Changes"}
+	for _, commit := range commits {
+		if commit.Breaking {
+			breaking.Commits = append(breaking.Commits, commit)
+		}
+	}
+
+	if len(breaking.Commits) > 0 {
+		groups = append(groups, breaking)
+	}
+
+	for _, t := range Types {
+		group := Group{Type: t.Type, Title: t.Title}
+		for _, commit := range commits {
+			if commit.Type == t.Type {
+				group.Commits = append(group.Commits, commit)
+			}
+		}
+
+		if len(group.Commits) > 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,85 @@
+package changelog
+
+import (
+	"path/filepath"
+
+	"github.com/vito/booklit"
+)
+
+func NewPlugin(section *booklit.Section) booklit.Plugin {
+	return Plugin{
+		section: section,
+	}
+}
+
+type Plugin struct {
+	section *booklit.Section
+}
+
+// Changelog generates a sub-section for each group of commits between the
+// `from` and `to` revisions of the git repository containing the section.
+func (plugin Plugin) Changelog(from string, to string, scopes ...string) error {
+	dir := filepath.Dir(plugin.section.FilePath())
+
+	commits, err := Log(dir, from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range GroupByType(Filter(commits, scopes...)) {
+		items := []booklit.Content{}
+		for _, commit := range group.Commits {
+			items = append(items, commitContent(commit))
+		}
+
+		section := &booklit.Section{
+			Parent: plugin.section,
+
+			Body: booklit.List{
+				Items: items,
+			},
+
+			Location: plugin.section.InvokeLocation,
+
+			Processor:       plugin.section.Processor,
+			PluginFactories: plugin.section.PluginFactories,
+		}
+
+		section.SetTitle(
+			booklit.String(group.Title),
+			plugin.section.InvokeLocation,
+			to+"-"+group.Type,
+		)
+
+		plugin.section.Children = append(plugin.section.Children, section)
+	}
+
+	return nil
+}
+
+func commitContent(commit Commit) booklit.Content {
+	content := booklit.Sequence{}
+
+	if commit.Scope != "" {
+		content = append(content, booklit.Styled{
+			Style:   booklit.StyleBold,
+			Content: booklit.String(commit.Scope + ":"),
+		}, booklit.String(" "))
+	}
+
+	hash := commit.Hash
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+
+	content = append(content,
+		booklit.String(commit.Subject+" ("),
+		booklit.Styled{
+			Style:   booklit.StyleVerbatim,
+			Content: booklit.String(hash),
+		},
+		booklit.String(")"),
+	)
+
+	return content
+}
@@ -0,0 +1,10 @@
+package plugin
+
+import (
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/changelog"
+)
+
+func init() {
+	booklit.RegisterPlugin("changelog", changelog.NewPlugin)
+}
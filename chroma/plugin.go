@@ -45,9 +45,9 @@ func (plugin Plugin) Syntax(language string, code booklit.Content, styleName ...
 
 	var style booklit.Style
 	if code.IsFlow() {
-		style = "inline-code"
+		style = booklit.StyleInlineCode
 	} else {
-		style = "code-block"
+		style = booklit.StyleCodeBlock
 	}
 
 	return booklit.Styled{
@@ -7,4 +7,5 @@ import (
 
 func init() {
 	booklit.RegisterPlugin("chroma", chroma.NewPlugin)
+	booklit.DeclareSafePlugin("chroma")
 }
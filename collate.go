@@ -0,0 +1,22 @@
+package booklit
+
+import (
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Collator returns a collator for sorting text alphabetically according to
+// the section's locale, e.g. for indexes and glossaries.
+//
+// Without a locale, the Unicode root collation is used, which still sorts
+// letters case-insensitively and with accented letters alongside their base
+// letters, rather than in byte order.
+func (con *Section) Collator() *collate.Collator {
+	return collate.New(language.Make(con.InheritedLocale()))
+}
+
+// SortStrings sorts the strings alphabetically according to the section's
+// locale.
+func (con *Section) SortStrings(strs []string) {
+	con.Collator().SortStrings(strs)
+}
@@ -0,0 +1,204 @@
+// Package confluence publishes rendered sections as pages in a Confluence
+// space via its REST API.
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render"
+)
+
+type Publisher struct {
+	// Base URL of the Confluence instance, e.g. https://example.atlassian.net/wiki.
+	URL string
+
+	// Key of the space to publish to.
+	Space string
+
+	// ID of the page to publish the root section under, if any.
+	ParentID string
+
+	// Credentials for basic auth; the token is typically an API token.
+	User  string
+	Token string
+
+	// Engine used to render each page, e.g. from
+	// render.NewConfluenceRenderingEngine.
+	Engine render.RenderingEngine
+
+	Client *http.Client
+}
+
+type content struct {
+	ID        string     `json:"id,omitempty"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Space     *space     `json:"space,omitempty"`
+	Ancestors []ancestor `json:"ancestors,omitempty"`
+	Body      *body      `json:"body,omitempty"`
+	Version   *version   `json:"version,omitempty"`
+}
+
+type searchResults struct {
+	Results []content `json:"results"`
+}
+
+type space struct {
+	Key string `json:"key"`
+}
+
+type ancestor struct {
+	ID string `json:"id"`
+}
+
+type body struct {
+	Storage storage `json:"storage"`
+}
+
+type storage struct {
+	Value          string `json:"value"`
+	Representation string `json:"representation"`
+}
+
+type version struct {
+	Number int `json:"number"`
+}
+
+// Publish creates or updates a page for each page-level section, preserving
+// their hierarchy. Pages are matched by title, so titles must be unique
+// within the space.
+func (publisher Publisher) Publish(section *booklit.Section) error {
+	return publisher.publish(section, publisher.ParentID)
+}
+
+func (publisher Publisher) publish(section *booklit.Section, parentID string) error {
+	if section.Parent != nil && !section.Parent.SplitSections {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	err := publisher.Engine.RenderSection(buf, section)
+	if err != nil {
+		return err
+	}
+
+	title := section.Title.String()
+
+	logrus.WithFields(logrus.Fields{
+		"section": section.Path,
+		"title":   title,
+	}).Info("publishing to confluence")
+
+	id, err := publisher.upsert(title, parentID, buf.String())
+	if err != nil {
+		return fmt.Errorf("publish '%s': %s", title, err)
+	}
+
+	for _, child := range section.Children {
+		err := publisher.publish(child, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (publisher Publisher) upsert(title string, parentID string, value string) (string, error) {
+	page := content{
+		Type:  "page",
+		Title: title,
+		Space: &space{Key: publisher.Space},
+		Body: &body{
+			Storage: storage{
+				Value:          value,
+				Representation: "storage",
+			},
+		},
+	}
+
+	if parentID != "" {
+		page.Ancestors = []ancestor{{ID: parentID}}
+	}
+
+	query := url.Values{}
+	query.Set("spaceKey", publisher.Space)
+	query.Set("title", title)
+	query.Set("expand", "version")
+
+	var existing searchResults
+	err := publisher.request("GET", "/rest/api/content?"+query.Encode(), nil, &existing)
+	if err != nil {
+		return "", err
+	}
+
+	var result content
+	if len(existing.Results) > 0 {
+		current := existing.Results[0]
+
+		page.ID = current.ID
+		page.Version = &version{Number: 1}
+		if current.Version != nil {
+			page.Version.Number = current.Version.Number + 1
+		}
+
+		err = publisher.request("PUT", "/rest/api/content/"+current.ID, page, &result)
+	} else {
+		err = publisher.request("POST", "/rest/api/content", page, &result)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return result.ID, nil
+}
+
+func (publisher Publisher) request(method string, path string, payload interface{}, dest interface{}) error {
+	reqBody := new(bytes.Buffer)
+	if payload != nil {
+		err := json.NewEncoder(reqBody).Encode(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(publisher.URL, "/")+path, reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if publisher.User != "" || publisher.Token != "" {
+		req.SetBasicAuth(publisher.User, publisher.Token)
+	}
+
+	client := publisher.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, path, res.Status, msg)
+	}
+
+	return json.NewDecoder(res.Body).Decode(dest)
+}
@@ -23,4 +23,8 @@ type Visitor interface {
 	VisitList(List) error
 	VisitTable(Table) error
 	VisitDefinitions(Definitions) error
+	VisitFigure(*Figure) error
+	VisitFootnote(*Footnote) error
+	VisitListOfFigures(ListOfFigures) error
+	VisitAttributions(Attributions) error
 }
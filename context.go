@@ -0,0 +1,24 @@
+package booklit
+
+import "context"
+
+// Context returns the context the section's book is being loaded in, which
+// is done once the build is cancelled, e.g. by Ctrl-C or a request timing
+// out. Plugins which run commands or make requests should respect it, e.g.
+// by passing it to exec.CommandContext.
+//
+// Returns context.Background() if the book wasn't given one.
+func (con *Section) Context() context.Context {
+	ctx := con.Top().ctx
+	if ctx == nil {
+		return context.Background()
+	}
+
+	return ctx
+}
+
+// SetContext sets the context the section's book is being loaded in. Only
+// consulted on the top-level section.
+func (con *Section) SetContext(ctx context.Context) {
+	con.ctx = ctx
+}
@@ -0,0 +1,46 @@
+package booklit
+
+import (
+	"errors"
+	"sync"
+)
+
+// Diagnostics collects the errors and warnings from a build, e.g. for
+// writing a report of all of them once it's done.
+type Diagnostics struct {
+	Errors   []error
+	Warnings []Warning
+
+	lock sync.Mutex
+}
+
+// RecordError adds the error to the diagnostics. Multiple errors collected
+// by BuildErrors are each added on their own. A nil error is ignored.
+func (diagnostics *Diagnostics) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	all := []error{err}
+
+	var errs *BuildErrors
+	if errors.As(err, &errs) {
+		all = errs.Errors
+	}
+
+	diagnostics.lock.Lock()
+	defer diagnostics.lock.Unlock()
+
+	for _, e := range all {
+		diagnostics.Errors = append(diagnostics.Errors, innermostError(e))
+	}
+}
+
+// RecordWarnings adds the warnings to the diagnostics, e.g. those reported
+// by a processor while loading a book.
+func (diagnostics *Diagnostics) RecordWarnings(warnings []Warning) {
+	diagnostics.lock.Lock()
+	defer diagnostics.lock.Unlock()
+
+	diagnostics.Warnings = append(diagnostics.Warnings, warnings...)
+}
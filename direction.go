@@ -0,0 +1,59 @@
+package booklit
+
+import "golang.org/x/text/language"
+
+// Direction is the direction in which a section's text is written.
+type Direction string
+
+const (
+	DirectionLTR Direction = "ltr"
+	DirectionRTL Direction = "rtl"
+)
+
+var Directions = []Direction{
+	DirectionLTR,
+	DirectionRTL,
+}
+
+// scripts written right-to-left, by ISO 15924 code
+var rtlScripts = map[string]bool{
+	"Adlm": true,
+	"Arab": true,
+	"Hebr": true,
+	"Mand": true,
+	"Nkoo": true,
+	"Rohg": true,
+	"Samr": true,
+	"Syrc": true,
+	"Thaa": true,
+}
+
+// InheritedDirection returns the section's direction, inherited from its
+// parent if not set. If no section sets it, it is derived from the locale,
+// e.g. right-to-left for ar or he. Without a locale, it is empty.
+func (con *Section) InheritedDirection() Direction {
+	if con.Direction != "" {
+		return con.Direction
+	}
+
+	if con.Parent != nil {
+		return con.Parent.InheritedDirection()
+	}
+
+	return LocaleDirection(con.Locale)
+}
+
+// LocaleDirection returns the direction of the locale's script, or "" if
+// the locale is empty.
+func LocaleDirection(locale string) Direction {
+	if locale == "" {
+		return ""
+	}
+
+	script, _ := language.Make(locale).Script()
+	if rtlScripts[script.String()] {
+		return DirectionRTL
+	}
+
+	return DirectionLTR
+}
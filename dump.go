@@ -0,0 +1,322 @@
+package booklit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Dump writes the content tree as indented text, one node per line, for
+// debugging plugins.
+//
+// Sub-sections are included along with their content.
+func Dump(w io.Writer, content Content) error {
+	return content.Visit(&dumpVisitor{w: w})
+}
+
+type dumpVisitor struct {
+	w     io.Writer
+	depth int
+}
+
+func (dump *dumpVisitor) line(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(dump.w, strings.Repeat("  ", dump.depth)+format+"\n", args...)
+	return err
+}
+
+// child dumps nested content one level deeper, optionally labeling it, e.g.
+// with the name of a partial.
+func (dump *dumpVisitor) child(label string, content Content) error {
+	dump.depth++
+	defer func() { dump.depth-- }()
+
+	if label != "" {
+		err := dump.line("%s:", label)
+		if err != nil {
+			return err
+		}
+
+		dump.depth++
+		defer func() { dump.depth-- }()
+	}
+
+	if content == nil {
+		return dump.line("(nil)")
+	}
+
+	return content.Visit(dump)
+}
+
+func (dump *dumpVisitor) children(label string, contents []Content) error {
+	for _, content := range contents {
+		err := dump.child(label, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitString(con String) error {
+	return dump.line("String %q", string(con))
+}
+
+func (dump *dumpVisitor) VisitSequence(con Sequence) error {
+	err := dump.line("Sequence")
+	if err != nil {
+		return err
+	}
+
+	return dump.children("", con)
+}
+
+func (dump *dumpVisitor) VisitReference(con *Reference) error {
+	err := dump.line("Reference %q", con.TagName)
+	if err != nil {
+		return err
+	}
+
+	if con.Content == nil {
+		return nil
+	}
+
+	return dump.child("", con.Content)
+}
+
+func (dump *dumpVisitor) VisitLink(con Link) error {
+	err := dump.line("Link %q", con.Target)
+	if err != nil {
+		return err
+	}
+
+	return dump.child("", con.Content)
+}
+
+func (dump *dumpVisitor) VisitSection(con *Section) error {
+	err := dump.line("Section %q", con.PrimaryTag.Name)
+	if err != nil {
+		return err
+	}
+
+	err = dump.child("title", con.Title)
+	if err != nil {
+		return err
+	}
+
+	err = dump.child("body", con.Body)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range con.Children {
+		err := dump.child("", child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitParagraph(con Paragraph) error {
+	err := dump.line("Paragraph")
+	if err != nil {
+		return err
+	}
+
+	return dump.children("", con)
+}
+
+func (dump *dumpVisitor) VisitTableOfContents(con TableOfContents) error {
+	return dump.line("TableOfContents")
+}
+
+func (dump *dumpVisitor) VisitPreformatted(con Preformatted) error {
+	err := dump.line("Preformatted")
+	if err != nil {
+		return err
+	}
+
+	return dump.children("", con)
+}
+
+func (dump *dumpVisitor) VisitStyled(con Styled) error {
+	block := ""
+	if con.Block {
+		block = " (block)"
+	}
+
+	err := dump.line("Styled %q%s", con.Style, block)
+	if err != nil {
+		return err
+	}
+
+	err = dump.child("", con.Content)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range con.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := dump.child("partial "+name, con.Partials[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitTarget(con Target) error {
+	err := dump.line("Target %q", con.TagName)
+	if err != nil {
+		return err
+	}
+
+	if con.Title != nil {
+		err := dump.child("title", con.Title)
+		if err != nil {
+			return err
+		}
+	}
+
+	if con.Content != nil {
+		err := dump.child("content", con.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitImage(con Image) error {
+	return dump.line("Image %q %q", con.Path, con.Description)
+}
+
+func (dump *dumpVisitor) VisitList(con List) error {
+	kind := "unordered"
+	if con.Ordered {
+		kind = "ordered"
+	}
+
+	err := dump.line("List (%s)", kind)
+	if err != nil {
+		return err
+	}
+
+	return dump.children("item", con.Items)
+}
+
+func (dump *dumpVisitor) VisitTable(con Table) error {
+	var details []string
+	if con.HeaderRows > 0 {
+		details = append(details, fmt.Sprintf("header rows: %d", con.HeaderRows))
+	}
+
+	if len(con.Alignments) > 0 {
+		alignments := []string{}
+		for i := range con.Alignments {
+			alignment := string(con.Alignment(i))
+			if alignment == "" {
+				alignment = "default"
+			}
+
+			alignments = append(alignments, alignment)
+		}
+
+		details = append(details, "alignments: "+strings.Join(alignments, " "))
+	}
+
+	var err error
+	if len(details) > 0 {
+		err = dump.line("Table (%s)", strings.Join(details, "; "))
+	} else {
+		err = dump.line("Table")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	dump.depth++
+	defer func() { dump.depth-- }()
+
+	for i, row := range con.Rows {
+		err := dump.line("row %d:", i+1)
+		if err != nil {
+			return err
+		}
+
+		err = dump.children("", row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitDefinitions(con Definitions) error {
+	err := dump.line("Definitions")
+	if err != nil {
+		return err
+	}
+
+	for _, def := range con {
+		err := dump.child("subject", def.Subject)
+		if err != nil {
+			return err
+		}
+
+		err = dump.child("definition", def.Definition)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitFigure(con *Figure) error {
+	err := dump.line("Figure %q", con.TagName)
+	if err != nil {
+		return err
+	}
+
+	err = dump.child("content", con.Content)
+	if err != nil {
+		return err
+	}
+
+	if con.Caption != nil {
+		return dump.child("caption", con.Caption)
+	}
+
+	return nil
+}
+
+func (dump *dumpVisitor) VisitFootnote(con *Footnote) error {
+	err := dump.line("Footnote %d", con.Number())
+	if err != nil {
+		return err
+	}
+
+	return dump.child("content", con.Content)
+}
+
+func (dump *dumpVisitor) VisitListOfFigures(con ListOfFigures) error {
+	return dump.line("ListOfFigures")
+}
+
+func (dump *dumpVisitor) VisitAttributions(con Attributions) error {
+	return dump.line("Attributions")
+}
@@ -0,0 +1,149 @@
+package emoji
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// Shortcodes maps GitHub-style shortcode names to their emoji.
+var Shortcodes = map[string]string{
+	"+1":                 "\U0001F44D",
+	"-1":                 "\U0001F44E",
+	"100":                "\U0001F4AF",
+	"airplane":           "✈️",
+	"alarm_clock":        "⏰",
+	"bangbang":           "‼️",
+	"beers":              "\U0001F37B",
+	"bell":               "\U0001F514",
+	"book":               "\U0001F4D6",
+	"books":              "\U0001F4DA",
+	"boom":               "\U0001F4A5",
+	"bug":                "\U0001F41B",
+	"bulb":               "\U0001F4A1",
+	"calendar":           "\U0001F4C6",
+	"clap":               "\U0001F44F",
+	"construction":       "\U0001F6A7",
+	"cry":                "\U0001F622",
+	"eyes":               "\U0001F440",
+	"fire":               "\U0001F525",
+	"gear":               "⚙️",
+	"gift":               "\U0001F381",
+	"grin":               "\U0001F601",
+	"hammer":             "\U0001F528",
+	"heart":              "❤️",
+	"heavy_check_mark":   "✔️",
+	"hourglass":          "⌛",
+	"information_source": "ℹ️",
+	"joy":                "\U0001F602",
+	"key":                "\U0001F511",
+	"laughing":           "\U0001F606",
+	"link":               "\U0001F517",
+	"lock":               "\U0001F512",
+	"mag":                "\U0001F50D",
+	"memo":               "\U0001F4DD",
+	"no_entry":           "⛔",
+	"ok_hand":            "\U0001F44C",
+	"package":            "\U0001F4E6",
+	"pencil":             "\U0001F4DD",
+	"point_right":        "\U0001F449",
+	"pushpin":            "\U0001F4CC",
+	"question":           "❓",
+	"rainbow":            "\U0001F308",
+	"recycle":            "♻️",
+	"rocket":             "\U0001F680",
+	"rotating_light":     "\U0001F6A8",
+	"see_no_evil":        "\U0001F648",
+	"smile":              "\U0001F604",
+	"smiley":             "\U0001F603",
+	"sparkles":           "✨",
+	"star":               "⭐",
+	"tada":               "\U0001F389",
+	"thinking":           "\U0001F914",
+	"thumbsdown":         "\U0001F44E",
+	"thumbsup":           "\U0001F44D",
+	"trophy":             "\U0001F3C6",
+	"warning":            "⚠️",
+	"wave":               "\U0001F44B",
+	"white_check_mark":   "✅",
+	"wink":               "\U0001F609",
+	"wrench":             "\U0001F527",
+	"x":                  "❌",
+	"zap":                "⚡",
+}
+
+var shortcodeRegexp = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+// Content returns the styled content for the emoji with the given shortcode
+// name. If the section configures an image path, it is passed along as the
+// "Image" partial for use by renderers which cannot rely on font support.
+func Content(section *booklit.Section, name string) (booklit.Content, bool) {
+	char, found := Shortcodes[name]
+	if !found {
+		return nil, false
+	}
+
+	partials := booklit.Partials{
+		"Name": booklit.String(name),
+	}
+
+	if pattern := section.InheritedEmojiImages(); pattern != "" {
+		partials["Image"] = booklit.String(strings.Replace(pattern, "%s", name, -1))
+	}
+
+	return booklit.Styled{
+		Style:    booklit.StyleEmoji,
+		Content:  booklit.String(char),
+		Partials: partials,
+	}, true
+}
+
+// Expand replaces any known :shortcode: occurrences in the string with
+// emoji content. Unknown shortcodes are left as-is.
+func Expand(section *booklit.Section, str string) booklit.Content {
+	matches := shortcodeRegexp.FindAllStringSubmatchIndex(str, -1)
+	if len(matches) == 0 {
+		return booklit.String(str)
+	}
+
+	var result booklit.Content
+
+	last := 0
+	for _, match := range matches {
+		content, found := Content(section, str[match[2]:match[3]])
+		if !found {
+			continue
+		}
+
+		if match[0] > last {
+			result = booklit.Append(result, booklit.String(str[last:match[0]]))
+		}
+
+		result = booklit.Append(result, content)
+
+		last = match[1]
+	}
+
+	if last < len(str) {
+		result = booklit.Append(result, booklit.String(str[last:]))
+	}
+
+	if result == nil {
+		return booklit.String(str)
+	}
+
+	return result
+}
+
+// Lookup returns the emoji content for the shortcode, or an error if it is
+// unknown.
+func Lookup(section *booklit.Section, name string) (booklit.Content, error) {
+	content, found := Content(section, strings.Trim(name, ":"))
+	if !found {
+		return nil, fmt.Errorf("unknown emoji: %s", name)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,16 @@
+package booklit
+
+// RenderingEngine describes the engine that sections will be rendered with,
+// allowing plugins to choose an output strategy, e.g. SVG for HTML and ASCII
+// art for text.
+type RenderingEngine interface {
+	// Name of the engine, e.g. "html" or "text".
+	EngineName() string
+
+	// Extension of the files rendered by the engine, e.g. "html".
+	FileExtension() string
+
+	// Whether a template with the given name (without an extension) is
+	// available, e.g. for a style.
+	HasTemplate(name string) bool
+}
@@ -3,13 +3,17 @@ package booklit
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"net/http"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/segmentio/textio"
 	"github.com/vito/booklit/ast"
@@ -64,12 +68,18 @@ func init() {
 }
 
 func ErrorPage(err error, w http.ResponseWriter) {
-	renderErr := errorTmpl.Lookup("page.tmpl").Execute(w, err)
+	renderErr := WriteErrorPage(w, err)
 	if renderErr != nil {
 		fmt.Fprintf(w, "failed to render error page: %s", renderErr)
 	}
 }
 
+// WriteErrorPage writes a standalone HTML page describing the error, with
+// every error collected by BuildErrors grouped by file.
+func WriteErrorPage(out io.Writer, err error) error {
+	return errorTmpl.Lookup("page.tmpl").Execute(out, err)
+}
+
 type PrettyError interface {
 	PrettyPrint(io.Writer)
 	PrettyHTML(io.Writer) error
@@ -94,6 +104,10 @@ func (err ParseError) PrettyHTML(out io.Writer) error {
 	return errorTmpl.Lookup("parse-error.tmpl").Execute(out, err)
 }
 
+func (err ParseError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
 type UnknownTagError struct {
 	TagName string
 
@@ -128,6 +142,10 @@ func (err UnknownTagError) PrettyHTML(out io.Writer) error {
 	return errorTmpl.Lookup("unknown-tag.tmpl").Execute(out, err)
 }
 
+func (err UnknownTagError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
 type AmbiguousReferenceError struct {
 	TagName          string
 	DefinedLocations []ErrorLocation
@@ -161,6 +179,76 @@ func (err AmbiguousReferenceError) PrettyHTML(out io.Writer) error {
 	return errorTmpl.Lookup("ambiguous-reference.tmpl").Execute(out, err)
 }
 
+func (err AmbiguousReferenceError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
+// PageCollisionError is returned when multiple sections would be rendered
+// to the same file, e.g. because their titles result in the same tag.
+type PageCollisionError struct {
+	FileName string
+
+	// where each section's tag was defined
+	DefinedLocations []ErrorLocation
+}
+
+func (err PageCollisionError) Error() string {
+	return fmt.Sprintf(
+		"multiple sections would be rendered to '%s'",
+		err.FileName,
+	)
+}
+
+func (err PageCollisionError) PrettyPrint(out io.Writer) {
+	fmt.Fprintf(out, "%s:\n\n", err)
+
+	fmt.Fprintf(out, "The sections were given the same tag in the following locations:\n\n")
+
+	for _, loc := range err.DefinedLocations {
+		fmt.Fprintf(out, "- %s:\n", loc.FilePath)
+		loc.AnnotateLocation(textio.NewPrefixWriter(out, "  "))
+	}
+
+	fmt.Fprintf(out, "Give one of them a different tag so they don't overwrite each other!\n")
+}
+
+func (err PageCollisionError) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("page-collision.tmpl").Execute(out, err)
+}
+
+func (err PageCollisionError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
+// BrokenLinkError is returned when checking links for a link which goes
+// nowhere, e.g. to a page or anchor which doesn't exist or a URL which
+// responds with an error.
+type BrokenLinkError struct {
+	Target string
+
+	// why the link is broken, e.g. "404 Not Found"
+	Reason string
+
+	ErrorLocation
+}
+
+func (err BrokenLinkError) Error() string {
+	return fmt.Sprintf("broken link '%s': %s", err.Target, err.Reason)
+}
+
+func (err BrokenLinkError) PrettyPrint(out io.Writer) {
+	fmt.Fprintf(out, err.Annotate("%s\n\n", err))
+	err.AnnotateLocation(out)
+}
+
+func (err BrokenLinkError) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("broken-link.tmpl").Execute(out, err)
+}
+
+func (err BrokenLinkError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
 type UndefinedFunctionError struct {
 	Function string
 
@@ -183,6 +271,31 @@ func (err UndefinedFunctionError) PrettyHTML(out io.Writer) error {
 	return errorTmpl.Lookup("undefined-function.tmpl").Execute(out, err)
 }
 
+func (err UndefinedFunctionError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
+// UnknownPluginError is returned when using a plugin which has not been
+// registered, either directly or as a dependency of another plugin.
+type UnknownPluginError struct {
+	Plugin string
+
+	// plugin which depends on the unknown plugin, if any
+	RequiredBy string
+}
+
+func (err UnknownPluginError) Error() string {
+	if err.RequiredBy != "" {
+		return fmt.Sprintf("plugin '%s' requires unknown plugin '%s'", err.RequiredBy, err.Plugin)
+	}
+
+	return fmt.Sprintf("unknown plugin '%s'", err.Plugin)
+}
+
+func (err UnknownPluginError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
 type FailedFunctionError struct {
 	Function string
 	Err      error
@@ -213,10 +326,581 @@ func (err FailedFunctionError) PrettyHTML(out io.Writer) error {
 	return errorTmpl.Lookup("function-error.tmpl").Execute(out, err)
 }
 
+func (err FailedFunctionError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
+func (err FailedFunctionError) Unwrap() error {
+	return err.Err
+}
+
+// TemplateError is returned when a template fails to render a section's
+// page, e.g. one from a custom theme. Templates render content with other
+// templates, so the templates which were rendering at the time are traced
+// in Frames, outermost first.
+type TemplateError struct {
+	// tag of the section whose page was being rendered, if known
+	Section string
+
+	Frames []TemplateFrame
+	Err    error
+
+	// where the section was titled
+	ErrorLocation
+}
+
+// TemplateFrame is a template which was rendering when the error occurred.
+type TemplateFrame struct {
+	// file name of the template, e.g. page.tmpl
+	Template string
+
+	// position within the template, if known
+	Line   int
+	Column int
+
+	// action at the position, e.g. render
+	Action string
+
+	// name of the partial the template was rendering, if any
+	Partial string
+}
+
+func (frame TemplateFrame) String() string {
+	str := frame.Template
+
+	if frame.Line != 0 {
+		str += fmt.Sprintf(":%d:%d", frame.Line, frame.Column)
+	}
+
+	if frame.Action != "" {
+		str += fmt.Sprintf(" at {{%s}}", frame.Action)
+	}
+
+	if frame.Partial != "" {
+		str += fmt.Sprintf(" (rendering partial %q)", frame.Partial)
+	}
+
+	return str
+}
+
+func (err TemplateError) Error() string {
+	var failed string
+	if len(err.Frames) > 0 {
+		failed = " in " + err.Frames[len(err.Frames)-1].String()
+	}
+
+	if err.Section != "" {
+		return fmt.Sprintf("rendering section '%s' failed%s: %s", err.Section, failed, err.Err)
+	}
+
+	return fmt.Sprintf("rendering failed%s: %s", failed, err.Err)
+}
+
+func (err TemplateError) PrettyPrint(out io.Writer) {
+	if err.FilePath != "" {
+		fmt.Fprintf(out, err.Annotate("%s\n\n", err))
+		err.AnnotateLocation(out)
+	} else {
+		fmt.Fprintf(out, "%s\n\n", err)
+	}
+
+	fmt.Fprintf(out, "Templates rendering at the time, outermost first:\n\n")
+
+	for _, frame := range err.Frames {
+		fmt.Fprintf(out, "- %s\n", frame)
+	}
+}
+
+func (err TemplateError) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("template-error.tmpl").Execute(out, err)
+}
+
+func (err TemplateError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
+func (err TemplateError) Unwrap() error {
+	return err.Err
+}
+
+// FailedHookError is returned when a command run on the rendered output fails,
+// e.g. a validator such as tidy. It's located at the section whose page the
+// command was run on, or whose page the command's output mentioned, if known.
+type FailedHookError struct {
+	Command string
+
+	// what the command printed, e.g. the problems it found
+	Output string
+
+	Err error
+
+	// where the section was titled
+	ErrorLocation
+}
+
+func (err FailedHookError) Error() string {
+	return fmt.Sprintf("command '%s' failed: %s", err.Command, err.Err)
+}
+
+func (err FailedHookError) PrettyPrint(out io.Writer) {
+	if err.FilePath != "" {
+		fmt.Fprintf(out, err.Annotate("%s\n\n", err))
+		err.AnnotateLocation(out)
+	} else {
+		fmt.Fprintf(out, "%s\n\n", err)
+	}
+
+	if err.Output != "" {
+		fmt.Fprintln(textio.NewPrefixWriter(out, "  "), strings.TrimRight(err.Output, "\n"))
+	}
+}
+
+func (err FailedHookError) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("hook-error.tmpl").Execute(out, err)
+}
+
+func (err FailedHookError) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(err))
+}
+
+func (err FailedHookError) Unwrap() error {
+	return err.Err
+}
+
+// BuildErrors collects the errors encountered while loading a book, rather
+// than stopping at the first one, so that they can all be reported at once.
+//
+// Once Max errors have been recorded the build is stopped, with the
+// BuildErrors itself returned as the error.
+type BuildErrors struct {
+	// Number of errors after which to stop the build. If zero, every error
+	// is collected.
+	Max int
+
+	Errors []error
+
+	errsL sync.Mutex
+}
+
+// ErrorCount is the number of errors in a group, e.g. of a type or in a
+// file.
+type ErrorCount struct {
+	Name  string
+	Count int
+}
+
+// Record adds the error to the collection. If the error limit has been
+// reached, the BuildErrors is returned so that the build is stopped;
+// otherwise nil is returned so that the build may continue.
+//
+// Errors which already contain the BuildErrors, i.e. because the limit was
+// reached while evaluating a nested section, are not recorded again.
+func (errs *BuildErrors) Record(err error) error {
+	var recorded *BuildErrors
+	if errors.As(err, &recorded) {
+		return recorded
+	}
+
+	errs.errsL.Lock()
+	defer errs.errsL.Unlock()
+
+	errs.Errors = append(errs.Errors, err)
+
+	if errs.Max > 0 && len(errs.Errors) >= errs.Max {
+		return errs
+	}
+
+	return nil
+}
+
+// Err returns the BuildErrors if any errors were recorded, or nil.
+func (errs *BuildErrors) Err() error {
+	errs.errsL.Lock()
+	defer errs.errsL.Unlock()
+
+	if len(errs.Errors) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// Stopped returns true if the build was stopped because the error limit was
+// reached.
+func (errs *BuildErrors) Stopped() bool {
+	return errs.Max > 0 && len(errs.Errors) >= errs.Max
+}
+
+func (errs *BuildErrors) Error() string {
+	msgs := []string{}
+	for _, err := range errs.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d errors:\n- %s", len(errs.Errors), strings.Join(msgs, "\n- "))
+}
+
+// ByType returns the number of errors of each type, most frequent first.
+func (errs *BuildErrors) ByType() []ErrorCount {
+	return countErrors(errs.Errors, errorType)
+}
+
+// ByFile returns the number of errors in each file, most frequent first.
+func (errs *BuildErrors) ByFile() []ErrorCount {
+	return countErrors(errs.Errors, groupFile)
+}
+
+// FileErrors is the errors found in a single file.
+type FileErrors struct {
+	FilePath string
+	Errors   []error
+}
+
+// GroupByFile returns the errors grouped by the file they were found in, in
+// the order that each file was first encountered, with each file's errors
+// ordered by line.
+//
+// Errors returned by functions are grouped under the file of the error they
+// returned, if it has a location of its own, e.g. a parse error in an
+// included section.
+func (errs *BuildErrors) GroupByFile() []FileErrors {
+	groups := []FileErrors{}
+	indices := map[string]int{}
+
+	for _, err := range errs.Errors {
+		file := groupFile(err)
+
+		i, found := indices[file]
+		if !found {
+			i = len(groups)
+			indices[file] = i
+			groups = append(groups, FileErrors{FilePath: file})
+		}
+
+		groups[i].Errors = append(groups[i].Errors, err)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.Errors, func(i, j int) bool {
+			return errorLine(group.Errors[i]) < errorLine(group.Errors[j])
+		})
+	}
+
+	return groups
+}
+
+func (errs *BuildErrors) PrettyPrint(out io.Writer) {
+	for _, group := range errs.GroupByFile() {
+		fmt.Fprintf(out, "%s (%d errors):\n\n", group.FilePath, len(group.Errors))
+
+		indented := textio.NewPrefixWriter(out, "  ")
+
+		for _, err := range group.Errors {
+			if prettyErr, ok := err.(PrettyError); ok {
+				prettyErr.PrettyPrint(indented)
+			} else {
+				fmt.Fprintln(indented, err)
+			}
+
+			fmt.Fprintln(indented)
+		}
+
+		indented.Flush()
+	}
+
+	if errs.Stopped() {
+		fmt.Fprintf(out, "Stopped after %d errors.\n\n", len(errs.Errors))
+	} else {
+		fmt.Fprintf(out, "Found %d errors.\n\n", len(errs.Errors))
+	}
+
+	fmt.Fprintf(out, "By type:\n\n")
+	for _, count := range errs.ByType() {
+		fmt.Fprintf(out, "% 6d  %s\n", count.Count, count.Name)
+	}
+
+	fmt.Fprintf(out, "\nBy file:\n\n")
+	for _, count := range errs.ByFile() {
+		fmt.Fprintf(out, "% 6d  %s\n", count.Count, count.Name)
+	}
+}
+
+func (errs *BuildErrors) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("build-errors.tmpl").Execute(out, errs)
+}
+
+// PrettyJSON prints an object with each error, as printed by the error's
+// own PrettyJSON, and whether the build was stopped because the error
+// limit was reached.
+func (errs *BuildErrors) PrettyJSON(out io.Writer) error {
+	list := jsonErrorList{
+		Errors:  []jsonError{},
+		Stopped: errs.Stopped(),
+	}
+
+	for _, err := range errs.Errors {
+		list.Errors = append(list.Errors, jsonErrorOf(err))
+	}
+
+	return writeJSON(out, list)
+}
+
+func countErrors(errs []error, group func(error) string) []ErrorCount {
+	counts := map[string]int{}
+	for _, err := range errs {
+		counts[group(err)]++
+	}
+
+	groups := []ErrorCount{}
+	for name, count := range counts {
+		groups = append(groups, ErrorCount{
+			Name:  name,
+			Count: count,
+		})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count == groups[j].Count {
+			return groups[i].Name < groups[j].Name
+		}
+
+		return groups[i].Count > groups[j].Count
+	})
+
+	return groups
+}
+
+func errorType(err error) string {
+	switch err.(type) {
+	case ParseError:
+		return "parse error"
+	case UnknownTagError:
+		return "unknown tag"
+	case AmbiguousReferenceError:
+		return "ambiguous reference"
+	case PageCollisionError:
+		return "page collision"
+	case BrokenLinkError:
+		return "broken link"
+	case UndefinedFunctionError:
+		return "undefined function"
+	case UnknownPluginError:
+		return "unknown plugin"
+	case FailedFunctionError:
+		return "failed function"
+	case TemplateError:
+		return "template error"
+	case FailedHookError:
+		return "failed hook"
+	case DeprecatedFunctionWarning:
+		return "deprecated function"
+	case EmptySectionWarning:
+		return "empty section"
+	case BrokenReferenceWarning:
+		return "broken reference"
+	case UnusedAssetWarning:
+		return "unused asset"
+	case TerminologyWarning:
+		return "terminology"
+	default:
+		return "other"
+	}
+}
+
+// ErrorFilePath returns the path of the file which the innermost error
+// occurred in, or the first of them for errors collected by BuildErrors, or
+// an empty string if it is unknown.
+func ErrorFilePath(err error) string {
+	var errs *BuildErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs.Errors {
+			if path := ErrorFilePath(e); path != "" {
+				return path
+			}
+		}
+
+		return ""
+	}
+
+	loc, _ := errorLocation(innermostError(err))
+	return loc.FilePath
+}
+
+func errorFile(err error) string {
+	if loc, ok := errorLocation(err); ok {
+		return loc.FilePath
+	}
+
+	return "(unknown)"
+}
+
+// groupFile returns the file which an error is grouped under; see
+// GroupByFile.
+func groupFile(err error) string {
+	return errorFile(innermostError(err))
+}
+
+// errorLine returns the line of the innermost error's location, or 0 if it
+// is unknown.
+func errorLine(err error) int {
+	loc, _ := errorLocation(innermostError(err))
+	return loc.NodeLocation.Line
+}
+
+// errorLocation returns the location of errors which embed ErrorLocation,
+// if known.
+func errorLocation(err error) (ErrorLocation, bool) {
+	located, ok := err.(interface{ location() ErrorLocation })
+	if !ok || located.location().FilePath == "" {
+		return ErrorLocation{}, false
+	}
+
+	return located.location(), true
+}
+
+// WriteJSON writes an object with each error as structured JSON, e.g. for
+// consuming errors in CI or an editor. Multiple errors collected by
+// BuildErrors are each listed, as with BuildErrors.PrettyJSON.
+func WriteJSON(out io.Writer, err error) error {
+	if err == nil {
+		return writeJSON(out, jsonErrorList{Errors: []jsonError{}})
+	}
+
+	var errs *BuildErrors
+	if errors.As(err, &errs) {
+		return errs.PrettyJSON(out)
+	}
+
+	return writeJSON(out, jsonErrorList{
+		Errors: []jsonError{jsonErrorOf(err)},
+	})
+}
+
+type jsonErrorList struct {
+	Errors  []jsonError `json:"errors"`
+	Stopped bool        `json:"stopped"`
+}
+
+type jsonError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+
+	jsonLocation
+
+	// function which was undefined, deprecated, or returned the error
+	Function string `json:"function,omitempty"`
+
+	// error returned by the function, or the broken reference's error
+	Cause *jsonError `json:"cause,omitempty"`
+
+	SimilarTags      []string       `json:"similar_tags,omitempty"`
+	DefinedLocations []jsonLocation `json:"defined_locations,omitempty"`
+
+	// templates which were rendering when a template failed
+	Frames []jsonTemplateFrame `json:"frames,omitempty"`
+
+	// what a hook's command printed
+	Output string `json:"output,omitempty"`
+}
+
+type jsonTemplateFrame struct {
+	Template string `json:"template"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Partial  string `json:"partial,omitempty"`
+}
+
+type jsonLocation struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	Length int    `json:"length,omitempty"`
+}
+
+func jsonErrorOf(err error) jsonError {
+	obj := jsonError{
+		Type:    strings.Replace(errorType(err), " ", "-", -1),
+		Message: err.Error(),
+	}
+
+	if loc, ok := errorLocation(err); ok {
+		obj.jsonLocation = jsonLocationOf(loc)
+	}
+
+	switch typed := err.(type) {
+	case UnknownTagError:
+		seen := map[string]bool{}
+		for _, tag := range typed.SimilarTags {
+			// a section may be tagged with the same name more than once
+			if seen[tag.Name] {
+				continue
+			}
+
+			seen[tag.Name] = true
+			obj.SimilarTags = append(obj.SimilarTags, tag.Name)
+		}
+	case AmbiguousReferenceError:
+		for _, loc := range typed.DefinedLocations {
+			obj.DefinedLocations = append(obj.DefinedLocations, jsonLocationOf(loc))
+		}
+	case PageCollisionError:
+		for _, loc := range typed.DefinedLocations {
+			obj.DefinedLocations = append(obj.DefinedLocations, jsonLocationOf(loc))
+		}
+	case UndefinedFunctionError:
+		obj.Function = typed.Function
+	case DeprecatedFunctionWarning:
+		obj.Function = typed.Function
+	case BrokenReferenceWarning:
+		cause := jsonErrorOf(typed.Err)
+		obj.Cause = &cause
+	case FailedFunctionError:
+		obj.Function = typed.Function
+
+		cause := jsonErrorOf(typed.Err)
+		obj.Cause = &cause
+	case TemplateError:
+		for _, frame := range typed.Frames {
+			obj.Frames = append(obj.Frames, jsonTemplateFrame(frame))
+		}
+
+		cause := jsonErrorOf(typed.Err)
+		obj.Cause = &cause
+	case FailedHookError:
+		obj.Output = typed.Output
+	}
+
+	return obj
+}
+
+func jsonLocationOf(loc ErrorLocation) jsonLocation {
+	return jsonLocation{
+		File:   loc.FilePath,
+		Line:   loc.NodeLocation.Line,
+		Column: loc.NodeLocation.Col,
+		Length: loc.Length,
+	}
+}
+
+func writeJSON(out io.Writer, obj interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(obj)
+}
+
 type ErrorLocation struct {
 	FilePath     string
 	NodeLocation ast.Location
 	Length       int
+
+	// file system which the file is read from to annotate the location;
+	// see Section.FS
+	FS fs.FS
+}
+
+func (loc ErrorLocation) location() ErrorLocation {
+	return loc
 }
 
 func (loc ErrorLocation) Annotate(msg string, args ...interface{}) string {
@@ -292,7 +976,7 @@ func (loc ErrorLocation) AnnotatedHTML(out io.Writer) error {
 }
 
 func (loc ErrorLocation) lineInQuestion() (string, error) {
-	file, err := os.Open(loc.FilePath)
+	file, err := OpenFile(loc.FS, loc.FilePath)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,92 @@
+package booklit
+
+import (
+	"sort"
+	"time"
+
+	"github.com/vito/booklit/ast"
+)
+
+// Event is something happening on a given date, e.g. a release or a meetup,
+// announced by a section.
+type Event struct {
+	Date time.Time
+
+	// set if the event was given a date without a time of day
+	AllDay bool
+
+	Title       Content
+	Description Content
+
+	// tag of the event's target, for linking to it
+	TagName string
+
+	// section announcing the event
+	Section *Section
+
+	// original location of the event
+	Location ast.Location
+}
+
+// Tag returns a tag pointing to the event, suitable for generating URLs.
+func (event *Event) Tag() Tag {
+	return Tag{
+		Name:     event.TagName,
+		Title:    event.Title,
+		Section:  event.Section,
+		Location: event.Location,
+		Anchor:   event.TagName,
+		Content:  event.Description,
+	}
+}
+
+// DateLayout returns the layout for displaying the event's date, including
+// its time of day unless it is all-day.
+func (event *Event) DateLayout() string {
+	if event.AllDay {
+		return "January 2, 2006"
+	}
+
+	return "January 2, 2006 at 3:04 PM MST"
+}
+
+// AllEvents returns the events announced by the section and its children,
+// recursively, ordered by date.
+func (con *Section) AllEvents() []*Event {
+	events := con.allEvents()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
+	return events
+}
+
+func (con *Section) allEvents() []*Event {
+	events := []*Event{}
+	events = append(events, con.Events...)
+
+	for _, child := range con.Children {
+		events = append(events, child.allEvents()...)
+	}
+
+	return events
+}
+
+// UpcomingEvents returns the events from AllEvents which have yet to happen
+// as of the given time. All-day events are upcoming until the day is over.
+func (con *Section) UpcomingEvents(now time.Time) []*Event {
+	upcoming := []*Event{}
+	for _, event := range con.AllEvents() {
+		end := event.Date
+		if event.AllDay {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		if end.After(now) {
+			upcoming = append(upcoming, event)
+		}
+	}
+
+	return upcoming
+}
@@ -0,0 +1,26 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/interchange"
+)
+
+// decodeContent decodes content returned by a process, in the same form as
+// exported by 'booklit export-json'. A string may be given in place of a
+// "string" node, and an array in place of a "sequence".
+func decodeContent(payload json.RawMessage) (booklit.Content, error) {
+	if len(payload) == 0 || string(payload) == "null" {
+		return nil, nil
+	}
+
+	var node interchange.Node
+	err := json.Unmarshal(payload, &node)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content: %s", err)
+	}
+
+	return interchange.Decode(&node)
+}
@@ -0,0 +1,87 @@
+package external
+
+import (
+	"encoding/json"
+
+	"github.com/vito/booklit"
+)
+
+// Plugin provides the functions of a process to a section.
+type Plugin struct {
+	section *booklit.Section
+	process *Process
+}
+
+var _ booklit.DynamicPlugin = Plugin{}
+
+func (plugin Plugin) Functions() []string {
+	return plugin.process.Functions()
+}
+
+func (plugin Plugin) Invoke(function string, args ...booklit.Content) (booklit.Content, error) {
+	params := invokeParams{
+		Function:  function,
+		Arguments: []string{},
+		Section: sectionInfo{
+			Path: plugin.section.FilePath(),
+			Tag:  plugin.section.PrimaryTag.Name,
+		},
+	}
+
+	if plugin.section.Title != nil {
+		params.Section.Title = plugin.section.Title.String()
+	}
+
+	for _, arg := range args {
+		text := ""
+		if arg != nil {
+			text = arg.String()
+		}
+
+		params.Arguments = append(params.Arguments, text)
+	}
+
+	var result invokeResult
+	err := plugin.process.call(plugin.section.Context(), "invoke", params, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, partial := range result.Partials {
+		content, err := decodeContent(partial)
+		if err != nil {
+			return nil, err
+		}
+
+		plugin.section.SetPartial(name, content)
+	}
+
+	for _, dep := range result.Dependencies {
+		plugin.section.AddDependency(dep)
+	}
+
+	return decodeContent(result.Content)
+}
+
+type invokeParams struct {
+	Function  string      `json:"function"`
+	Arguments []string    `json:"arguments"`
+	Section   sectionInfo `json:"section"`
+}
+
+type sectionInfo struct {
+	Path  string `json:"path"`
+	Tag   string `json:"tag"`
+	Title string `json:"title"`
+}
+
+type invokeResult struct {
+	// content to insert in place of the invocation, if any
+	Content json.RawMessage `json:"content"`
+
+	// partials to set on the section, e.g. for its template
+	Partials map[string]json.RawMessage `json:"partials"`
+
+	// files the content was generated from; see booklit.Section.AddDependency
+	Dependencies []string `json:"dependencies"`
+}
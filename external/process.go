@@ -0,0 +1,361 @@
+// Package external runs plugins implemented as separate executables, which
+// speak JSON-RPC 2.0 over their stdin and stdout, so that plugins may be
+// written in any language and used without rebuilding Booklit.
+//
+// Each message is a single line of JSON. Once started, the process is sent
+// an "initialize" request and must respond with the functions it provides:
+//
+//	--> {"jsonrpc":"2.0","id":1,"method":"initialize","params":{"booklit":"0.10.0"}}
+//	<-- {"jsonrpc":"2.0","id":1,"result":{"functions":["shout"]}}
+//
+// Each time one of its functions is invoked, it is sent an "invoke" request
+// with the text of each argument, and responds with the content to insert in
+// its place, in the JSON interchange format exported by 'booklit export-json':
+//
+//	--> {"jsonrpc":"2.0","id":2,"method":"invoke","params":{"function":"shout","section":{...},"arguments":["hello"]}}
+//	<-- {"jsonrpc":"2.0","id":2,"result":{"content":{"type":"string","value":"HELLO!"}}}
+//
+// Errors are returned as JSON-RPC errors, and fail the build. The process is
+// sent one request at a time, and should exit once its stdin is closed. If
+// the section's context is done while waiting on a response, the process is
+// killed, and started again for the next request.
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// Process is a running plugin executable, shared by every section which uses
+// the plugin.
+type Process struct {
+	Name    string
+	Command []string
+
+	functions []string
+
+	// held for each request, so that one request is sent at a time
+	lock   sync.Mutex
+	lastID int
+
+	// guards the running command, which is replaced upon the next request if
+	// it is killed
+	runningL sync.Mutex
+	running  *running
+	closed   bool
+
+	// set while waiting on a response, which a closed process won't send
+	waiting bool
+}
+
+// running is a started instance of the command.
+type running struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// Start runs the command and asks it which functions it provides.
+func Start(name string, command []string) (*Process, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no command given for plugin '%s'", name)
+	}
+
+	process := &Process{
+		Name:    name,
+		Command: command,
+	}
+
+	functions, err := process.start(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	process.functions = functions
+
+	return process, nil
+}
+
+// start runs the command and initializes it, replacing the one which was
+// killed, if any, and returns the functions it provides.
+func (process *Process) start(ctx context.Context) ([]string, error) {
+	cmd := exec.Command(process.Command[0], process.Command[1:]...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"plugin":  process.Name,
+		"command": process.Command,
+	}).Debug("starting external plugin")
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("starting plugin '%s' failed: %w", process.Name, err)
+	}
+
+	run := &running{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+	}
+
+	var initialized initializeResult
+	err = process.request(ctx, run, "initialize", initializeParams{Booklit: booklit.Version}, &initialized)
+	if err != nil {
+		_ = stdin.Close()
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+
+	process.runningL.Lock()
+	closed := process.closed
+	if !closed {
+		process.running = run
+	}
+	process.runningL.Unlock()
+
+	if closed {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("plugin '%s' is closed", process.Name)
+	}
+
+	return initialized.Functions, nil
+}
+
+// Functions returns the names of the functions the process provides.
+func (process *Process) Functions() []string {
+	return process.functions
+}
+
+// NewPlugin constructs a plugin for the section which invokes the process's
+// functions. It may be registered as the plugin's factory.
+func (process *Process) NewPlugin(section *booklit.Section) booklit.Plugin {
+	return Plugin{
+		section: section,
+		process: process,
+	}
+}
+
+// Close closes the process's stdin and waits for it to exit. If a request is
+// waiting on a response, e.g. from a function which hangs, the process is
+// killed instead, and the request fails.
+func (process *Process) Close() error {
+	process.runningL.Lock()
+	run, waiting := process.running, process.waiting
+	process.running = nil
+	process.closed = true
+	process.runningL.Unlock()
+
+	if run == nil {
+		return nil
+	}
+
+	_ = run.stdin.Close()
+
+	if waiting {
+		_ = run.cmd.Process.Kill()
+		_ = run.cmd.Wait()
+		return nil
+	}
+
+	err := run.cmd.Wait()
+	if err != nil {
+		return fmt.Errorf("plugin '%s' failed: %w", process.Name, err)
+	}
+
+	return nil
+}
+
+// discard stops the command once a request to it is cancelled, as it can't
+// be known whether it will ever respond. The next request starts it again.
+func (process *Process) discard(run *running) {
+	process.runningL.Lock()
+	current := process.running == run
+	if current {
+		process.running = nil
+	}
+	process.runningL.Unlock()
+
+	// closed or restarted already, by whoever removed it
+	if !current {
+		return
+	}
+
+	_ = run.stdin.Close()
+	_ = run.cmd.Process.Kill()
+	_ = run.cmd.Wait()
+}
+
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type response struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+// ResponseError is an error returned by a plugin's process.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err ResponseError) Error() string {
+	return err.Message
+}
+
+// call sends a request to the command, starting it again if the last one
+// was killed. If the context is done before the command responds, it is
+// killed and the context's error is returned.
+func (process *Process) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.runningL.Lock()
+	run, closed := process.running, process.closed
+	process.runningL.Unlock()
+
+	if closed {
+		return fmt.Errorf("plugin '%s' is closed", process.Name)
+	}
+
+	if run == nil {
+		logrus.WithField("plugin", process.Name).Debug("restarting external plugin")
+
+		_, err := process.start(ctx)
+		if err != nil {
+			return err
+		}
+
+		process.runningL.Lock()
+		run = process.running
+		process.runningL.Unlock()
+	}
+
+	process.runningL.Lock()
+	process.waiting = true
+	process.runningL.Unlock()
+
+	err := process.request(ctx, run, method, params, result)
+
+	process.runningL.Lock()
+	process.waiting = false
+	process.runningL.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		process.discard(run)
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// request sends a request to the running command and reads its response,
+// killing the command if the context is done first so that the read stops.
+// If the command is killed, the context's error is returned.
+func (process *Process) request(ctx context.Context, run *running, method string, params interface{}, result interface{}) (err error) {
+	process.lastID++
+
+	payload, err := json.Marshal(request{
+		JSONRPC: "2.0",
+		ID:      process.lastID,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	responded := make(chan struct{})
+	killed := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = run.cmd.Process.Kill()
+			killed <- true
+		case <-responded:
+			killed <- false
+		}
+	}()
+
+	defer func() {
+		close(responded)
+
+		if <-killed {
+			err = ctx.Err()
+		}
+	}()
+
+	_, err = run.stdin.Write(append(payload, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing to plugin '%s' failed: %w", process.Name, err)
+	}
+
+	line, err := run.stdout.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("plugin '%s' exited before responding to %s", process.Name, method)
+		}
+
+		return fmt.Errorf("reading from plugin '%s' failed: %w", process.Name, err)
+	}
+
+	var res response
+	err = json.Unmarshal(line, &res)
+	if err != nil {
+		return fmt.Errorf("invalid response from plugin '%s': %s", process.Name, err)
+	}
+
+	if res.ID != process.lastID {
+		return fmt.Errorf("invalid response from plugin '%s': expected id %d, got %d", process.Name, process.lastID, res.ID)
+	}
+
+	if res.Error != nil {
+		return *res.Error
+	}
+
+	if result == nil || len(res.Result) == 0 {
+		return nil
+	}
+
+	err = json.Unmarshal(res.Result, result)
+	if err != nil {
+		return fmt.Errorf("invalid result from plugin '%s': %s", process.Name, err)
+	}
+
+	return nil
+}
+
+type initializeParams struct {
+	Booklit string `json:"booklit"`
+}
+
+type initializeResult struct {
+	Functions []string `json:"functions"`
+}
@@ -0,0 +1,90 @@
+package booklit
+
+import (
+	"fmt"
+
+	"github.com/vito/booklit/ast"
+)
+
+type Figure struct {
+	Content Content
+	Caption Content
+
+	// optional tag for referencing the figure
+	TagName string
+
+	// section containing the figure, set upon collection
+	Section *Section
+
+	// original location of the figure
+	Location ast.Location
+
+	// position among all figures in the book, set upon collection
+	number int
+}
+
+func (con *Figure) IsFlow() bool {
+	return false
+}
+
+func (con *Figure) String() string {
+	return fmt.Sprintf("%s\n\nFigure %d: %s\n\n", con.Content, con.Number(), con.Caption)
+}
+
+func (con *Figure) Visit(visitor Visitor) error {
+	return visitor.VisitFigure(con)
+}
+
+// Number returns the figure's position among all figures in the book,
+// starting from 1, or 0 if it hasn't been collected.
+func (con *Figure) Number() int {
+	return con.number
+}
+
+// SetNumber sets the figure's position among all figures in the book, as
+// they're collected in the order they appear.
+func (con *Figure) SetNumber(number int) {
+	con.number = number
+}
+
+// Title returns the display used when referencing the figure.
+func (con *Figure) Title() Content {
+	return String(fmt.Sprintf("Figure %d", con.Number()))
+}
+
+// Anchor returns the anchor identifying the figure in rendered output.
+func (con *Figure) Anchor() string {
+	if con.TagName != "" {
+		return con.TagName
+	}
+
+	return fmt.Sprintf("figure-%d", con.Number())
+}
+
+// Tag returns a tag pointing to the figure, suitable for generating URLs.
+func (con *Figure) Tag() Tag {
+	return Tag{
+		Name:     con.Anchor(),
+		Title:    con.Title(),
+		Section:  con.Section,
+		Location: con.Location,
+		Anchor:   con.Anchor(),
+		Content:  con.Caption,
+	}
+}
+
+type ListOfFigures struct {
+	Section *Section
+}
+
+func (con ListOfFigures) IsFlow() bool {
+	return false
+}
+
+func (con ListOfFigures) String() string {
+	return ""
+}
+
+func (con ListOfFigures) Visit(visitor Visitor) error {
+	return visitor.VisitListOfFigures(con)
+}
@@ -0,0 +1,107 @@
+package booklit
+
+import (
+	"fmt"
+
+	"github.com/vito/booklit/ast"
+)
+
+// Footnote is a note on the content preceding it, rendered as a numbered mark
+// which refers to the note's content, e.g. in a list of endnotes collected at
+// the end of the page or section.
+type Footnote struct {
+	Content Content
+
+	// section containing the footnote, set upon collection
+	Section *Section
+
+	// original location of the footnote
+	Location ast.Location
+}
+
+func (con *Footnote) IsFlow() bool {
+	return true
+}
+
+func (con *Footnote) String() string {
+	return ""
+}
+
+func (con *Footnote) Visit(visitor Visitor) error {
+	return visitor.VisitFootnote(con)
+}
+
+// Collector returns the section whose endnotes include the footnote: the
+// nearest section which collects endnotes, or the page the footnote is on.
+func (con *Footnote) Collector() *Section {
+	for section := con.Section; section != nil; section = section.Parent {
+		if section.CollectsEndnotes() {
+			return section
+		}
+	}
+
+	return nil
+}
+
+// Number returns the footnote's position among the endnotes of its
+// collector, starting from 1.
+func (con *Footnote) Number() int {
+	collector := con.Collector()
+	if collector == nil {
+		return 0
+	}
+
+	for i, note := range collector.Endnotes() {
+		if note == con {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// Anchor returns the anchor identifying the footnote's content in rendered
+// output.
+func (con *Footnote) Anchor() string {
+	return fmt.Sprintf("%s-footnote-%d", con.collectorName(), con.Number())
+}
+
+// ReferenceAnchor returns the anchor identifying the footnote's mark in
+// rendered output, for linking back to it from the footnote's content.
+func (con *Footnote) ReferenceAnchor() string {
+	return fmt.Sprintf("%s-footnote-ref-%d", con.collectorName(), con.Number())
+}
+
+// Tag returns a tag pointing to the footnote's content, suitable for
+// generating URLs.
+func (con *Footnote) Tag() Tag {
+	return Tag{
+		Name:     con.Anchor(),
+		Title:    String(fmt.Sprintf("%d", con.Number())),
+		Section:  con.Collector(),
+		Location: con.Location,
+		Anchor:   con.Anchor(),
+		Content:  con.Content,
+	}
+}
+
+// ReferenceTag returns a tag pointing to the footnote's mark, suitable for
+// generating URLs which link back to it.
+func (con *Footnote) ReferenceTag() Tag {
+	return Tag{
+		Name:     con.ReferenceAnchor(),
+		Title:    String(fmt.Sprintf("%d", con.Number())),
+		Section:  con.Section,
+		Location: con.Location,
+		Anchor:   con.ReferenceAnchor(),
+	}
+}
+
+func (con *Footnote) collectorName() string {
+	collector := con.Collector()
+	if collector == nil {
+		return ""
+	}
+
+	return collector.PrimaryTag.Name
+}
@@ -1,10 +1,46 @@
 package booklit
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Image struct {
 	Path        string
 	Description string
+
+	// intrinsic size of the image in pixels, if known, so that space can be
+	// reserved for it before it loads
+	Width  int
+	Height int
+
+	// versions of the image for browsers to choose between, e.g. resized for
+	// smaller screens, along with the sizes it's displayed at, as in the HTML
+	// sizes attribute, e.g. (max-width: 48em) 100vw, 48em
+	Variants []ImageVariant
+	Sizes    string
+}
+
+// ImageVariant is a version of an image, e.g. resized or in another format.
+type ImageVariant struct {
+	Path  string
+	Width int
+
+	// MIME type of the variant, e.g. image/webp, if it differs from the
+	// image's own
+	Type string
+}
+
+// ImageProcessor generates variants of the images referred to by \image at
+// build time, e.g. resized for smaller screens.
+type ImageProcessor interface {
+	// ProcessImage returns the image with its size and variants, given it
+	// with the path it was referred to by in the section. Images which can't
+	// be processed, e.g. remote ones, are returned as-is.
+	//
+	// Processors which read files must respect the section's safe mode, if
+	// any; see Section.Top().Safe.
+	ProcessImage(*Section, Image) (Image, error)
 }
 
 func (con Image) IsFlow() bool {
@@ -18,3 +54,33 @@ func (con Image) String() string {
 func (con Image) Visit(visitor Visitor) error {
 	return visitor.VisitImage(con)
 }
+
+// SrcSet returns the variants of the given MIME type as an HTML srcset
+// attribute, e.g. a-480w.png 480w, a.png 1200w. An empty type returns those
+// in the image's own format.
+func (con Image) SrcSet(mimeType string) string {
+	candidates := []string{}
+	for _, variant := range con.Variants {
+		if variant.Type == mimeType {
+			candidates = append(candidates, fmt.Sprintf("%s %dw", variant.Path, variant.Width))
+		}
+	}
+
+	return strings.Join(candidates, ", ")
+}
+
+// AlternateTypes returns the MIME types of the variants which are in other
+// formats than the image's own, e.g. image/webp, in the order they're
+// preferred.
+func (con Image) AlternateTypes() []string {
+	types := []string{}
+	seen := map[string]bool{}
+	for _, variant := range con.Variants {
+		if variant.Type != "" && !seen[variant.Type] {
+			seen[variant.Type] = true
+			types = append(types, variant.Type)
+		}
+	}
+
+	return types
+}
@@ -0,0 +1,297 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// DefaultQuality is the quality which resized JPEGs are encoded at.
+const DefaultQuality = 85
+
+// Processor resizes the PNG and JPEG images referred to by \image to each of
+// the given widths, and optionally converts them to WebP, so that browsers can
+// download the smallest one suited to the screen.
+//
+// Variants are written next to the image with the digest of its content in
+// their name, e.g. images/shot-480w.1a2b3c4d5e6f.png, and are reused by later
+// builds until the image changes.
+type Processor struct {
+	// directory which the paths of images are relative to, e.g. the output
+	// directory
+	Dir string
+
+	// widths to resize images to, for those which are wider
+	Widths []int
+
+	// sizes which images are displayed at, as in the HTML sizes attribute,
+	// e.g. (max-width: 48em) 100vw, 48em
+	Sizes string
+
+	// quality which resized JPEGs are encoded at; defaults to DefaultQuality
+	Quality int
+
+	// command which converts an image to WebP, e.g. cwebp {input} -o
+	// {output}; reads from stdin and writes to stdout unless it has {input}
+	// or {output} placeholders
+	WebPCommand []string
+
+	lock sync.Mutex
+}
+
+// ProcessImage returns the image with its size and variants. Images which
+// aren't local PNGs or JPEGs, which don't exist, or whose paths lead outside
+// of Dir are returned as-is.
+//
+// If the section's book is built in safe mode, images are only read if they
+// are within Dir once symlinks are followed, and no larger than the limit.
+func (processor *Processor) ProcessImage(section *booklit.Section, img booklit.Image) (booklit.Image, error) {
+	ref, err := url.Parse(img.Path)
+	if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Path == "" || ref.RawQuery != "" || ref.Fragment != "" {
+		return img, nil
+	}
+
+	var format string
+	switch strings.ToLower(path.Ext(ref.Path)) {
+	case ".png":
+		format = "png"
+	case ".jpg", ".jpeg":
+		format = "jpeg"
+	default:
+		return img, nil
+	}
+
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(ref.Path, "/")))
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// not served from the output, so there's nothing to resize
+		return img, nil
+	}
+
+	file := filepath.Join(processor.Dir, rel)
+
+	if safe := section.Top().Safe; safe != nil {
+		err := safe.CheckFileIn(processor.Dir, file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return img, nil
+			}
+
+			return booklit.Image{}, err
+		}
+	}
+
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return img, nil
+		}
+
+		return booklit.Image{}, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(source))
+	if err != nil {
+		return booklit.Image{}, fmt.Errorf("invalid image %s: %w", img.Path, err)
+	}
+
+	img.Width = config.Width
+	img.Height = config.Height
+	img.Sizes = processor.Sizes
+
+	sum := sha256.Sum256(source)
+	digest := hex.EncodeToString(sum[:])[:12]
+
+	widths := []int{}
+	for _, width := range processor.Widths {
+		if width > 0 && width < config.Width {
+			widths = append(widths, width)
+		}
+	}
+
+	sort.Ints(widths)
+
+	// the image itself is the widest candidate
+	widths = append(widths, config.Width)
+
+	processor.lock.Lock()
+	defer processor.lock.Unlock()
+
+	var decoded image.Image
+
+	variants := []booklit.ImageVariant{}
+	webps := []booklit.ImageVariant{}
+	for _, width := range widths {
+		resized := file
+		variant := booklit.ImageVariant{
+			Path:  img.Path,
+			Width: width,
+		}
+
+		if width != config.Width {
+			resized = variantPath(file, width, digest, filepath.Ext(file))
+			variant.Path = variantPath(img.Path, width, digest, path.Ext(ref.Path))
+
+			if !exists(resized) {
+				if decoded == nil {
+					decoded, _, err = image.Decode(bytes.NewReader(source))
+					if err != nil {
+						return booklit.Image{}, fmt.Errorf("invalid image %s: %w", img.Path, err)
+					}
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"image": img.Path,
+					"width": width,
+				}).Info("resizing image")
+
+				err := processor.encode(resized, format, resize(decoded, width))
+				if err != nil {
+					return booklit.Image{}, err
+				}
+			}
+		}
+
+		variants = append(variants, variant)
+
+		if len(processor.WebPCommand) == 0 {
+			continue
+		}
+
+		webp := variantPath(file, width, digest, ".webp")
+		if !exists(webp) {
+			logrus.WithFields(logrus.Fields{
+				"image": img.Path,
+				"width": width,
+			}).Info("converting image to webp")
+
+			err := convert(section.Context(), processor.WebPCommand, resized, webp)
+			if err != nil {
+				return booklit.Image{}, fmt.Errorf("webp command failed: %w", err)
+			}
+		}
+
+		webps = append(webps, booklit.ImageVariant{
+			Path:  variantPath(img.Path, width, digest, ".webp"),
+			Width: width,
+			Type:  "image/webp",
+		})
+	}
+
+	// WebP is preferred, being smaller
+	img.Variants = append(webps, variants...)
+
+	return img, nil
+}
+
+// encode writes the image to the file in the given format, via a temporary
+// file so that a partially written variant is never reused.
+func (processor *Processor) encode(file string, format string, img image.Image) error {
+	buf := new(bytes.Buffer)
+
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(buf, img)
+	case "jpeg":
+		quality := processor.Quality
+		if quality == 0 {
+			quality = DefaultQuality
+		}
+
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeAtomically(file, buf.Bytes())
+}
+
+// variantPath returns the path of the variant of the image at the given path
+// with the given width and extension, e.g. shot-480w.1a2b3c4d5e6f.webp.
+func variantPath(p string, width int, digest string, ext string) string {
+	base := strings.TrimSuffix(p, path.Ext(p))
+	return fmt.Sprintf("%s-%dw.%s%s", base, width, digest, ext)
+}
+
+// convert runs the command to convert the input file, writing the output
+// file. The command is killed if the context is done first.
+func convert(ctx context.Context, command []string, input string, output string) error {
+	var readsInput, writesOutput bool
+
+	args := make([]string, len(command))
+	for i, arg := range command {
+		if strings.Contains(arg, "{input}") {
+			readsInput = true
+			arg = strings.Replace(arg, "{input}", input, -1)
+		}
+
+		if strings.Contains(arg, "{output}") {
+			writesOutput = true
+			arg = strings.Replace(arg, "{output}", output+".tmp", -1)
+		}
+
+		args[i] = arg
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+
+	if !readsInput {
+		source, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+
+		defer source.Close()
+
+		cmd.Stdin = source
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		_ = os.Remove(output + ".tmp")
+		return fmt.Errorf("%w\n%s", err, stderr.String())
+	}
+
+	if !writesOutput {
+		return writeAtomically(output, stdout.Bytes())
+	}
+
+	return os.Rename(output+".tmp", output)
+}
+
+func writeAtomically(file string, content []byte) error {
+	err := ioutil.WriteFile(file+".tmp", content, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file+".tmp", file)
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
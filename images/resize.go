@@ -0,0 +1,75 @@
+package images
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// resize scales the image down to the given width, keeping its aspect ratio.
+// Each pixel is the average of the area of the image it covers, which keeps
+// fine detail like text legible, unlike sampling.
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+
+	height := int(math.Round(float64(bounds.Dy()) * float64(width) / float64(bounds.Dx())))
+	if height < 1 {
+		height = 1
+	}
+
+	// average premultiplied colors, so that transparent pixels don't darken
+	// the edges of opaque ones
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	pix := make([]float64, len(rgba.Pix))
+	for i, v := range rgba.Pix {
+		pix[i] = float64(v)
+	}
+
+	// scaling transposes the pixels, so scaling twice scales both axes and
+	// leaves them the right way around
+	pix = scaleRows(pix, bounds.Dx(), bounds.Dy(), width)
+	pix = scaleRows(pix, bounds.Dy(), width, height)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, v := range pix {
+		dst.Pix[i] = uint8(math.Round(math.Max(0, math.Min(255, v))))
+	}
+
+	return dst
+}
+
+// scaleRows scales each of the rows of RGBA pixels from the given length to
+// the new one, returning them transposed, i.e. as newLength rows of the given
+// number of pixels.
+func scaleRows(pix []float64, length int, rows int, newLength int) []float64 {
+	scaled := make([]float64, newLength*rows*4)
+
+	scale := float64(length) / float64(newLength)
+
+	for row := 0; row < rows; row++ {
+		for i := 0; i < newLength; i++ {
+			start := float64(i) * scale
+			end := start + scale
+
+			var sum [4]float64
+			for j := int(start); j < length && float64(j) < end; j++ {
+				// the portion of the pixel within the scaled one
+				coverage := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+
+				offset := (row*length + j) * 4
+				for c := 0; c < 4; c++ {
+					sum[c] += pix[offset+c] * coverage
+				}
+			}
+
+			offset := (i*rows + row) * 4
+			for c := 0; c < 4; c++ {
+				scaled[offset+c] = sum[c] / scale
+			}
+		}
+	}
+
+	return scaled
+}
@@ -0,0 +1,442 @@
+package interchange
+
+import (
+	"sort"
+
+	"github.com/vito/booklit"
+)
+
+// Export converts a loaded book into a Document.
+func Export(book *booklit.Section) (*Document, error) {
+	node, err := Encode(book)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		Version: Version,
+		Book:    node,
+	}, nil
+}
+
+// Encode converts content into a node. Sections are encoded along with their
+// sub-sections.
+func Encode(content booklit.Content) (*Node, error) {
+	if content == nil {
+		return nil, nil
+	}
+
+	if aux, ok := content.(booklit.Aux); ok {
+		inner, err := Encode(aux.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Node{Type: "aux", Content: inner}, nil
+	}
+
+	encoder := &encoder{}
+
+	err := content.Visit(encoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.Result, nil
+}
+
+func encodeAll(contents []booklit.Content) ([]*Node, error) {
+	nodes := []*Node{}
+	for _, content := range contents {
+		node, err := Encode(content)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+func encodePartials(partials booklit.Partials) (map[string]*Node, error) {
+	if len(partials) == 0 {
+		return nil, nil
+	}
+
+	names := []string{}
+	for name := range partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	nodes := map[string]*Node{}
+	for _, name := range names {
+		node, err := Encode(partials[name])
+		if err != nil {
+			return nil, err
+		}
+
+		nodes[name] = node
+	}
+
+	return nodes, nil
+}
+
+// sectionTag returns the tag identifying the section, if any.
+func sectionTag(section *booklit.Section) string {
+	if section == nil {
+		return ""
+	}
+
+	return section.PrimaryTag.Name
+}
+
+// encoder converts content into nodes.
+type encoder struct {
+	Result *Node
+}
+
+var _ booklit.Visitor = &encoder{}
+
+func (encoder *encoder) VisitString(con booklit.String) error {
+	encoder.Result = &Node{Type: "string", Value: string(con)}
+	return nil
+}
+
+func (encoder *encoder) VisitSequence(con booklit.Sequence) error {
+	contents, err := encodeAll(con)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "sequence", Contents: contents}
+
+	return nil
+}
+
+func (encoder *encoder) VisitParagraph(con booklit.Paragraph) error {
+	lines, err := encodeAll(con)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "paragraph", Lines: lines}
+
+	return nil
+}
+
+func (encoder *encoder) VisitPreformatted(con booklit.Preformatted) error {
+	lines, err := encodeAll(con)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "preformatted", Lines: lines}
+
+	return nil
+}
+
+func (encoder *encoder) VisitReference(con *booklit.Reference) error {
+	content, err := Encode(con.Content)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "reference", Tag: con.TagName, Content: content}
+
+	return nil
+}
+
+func (encoder *encoder) VisitLink(con booklit.Link) error {
+	content, err := Encode(con.Content)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "link", Target: con.Target, Content: content}
+
+	return nil
+}
+
+func (encoder *encoder) VisitSection(con *booklit.Section) error {
+	title, err := Encode(con.Title)
+	if err != nil {
+		return err
+	}
+
+	body, err := Encode(con.Body)
+	if err != nil {
+		return err
+	}
+
+	partials, err := encodePartials(con.Partials)
+	if err != nil {
+		return err
+	}
+
+	node := &Node{
+		Type: "section",
+
+		Path:     con.Path,
+		Title:    title,
+		Body:     body,
+		Style:    con.Style,
+		Partials: partials,
+		Aliases:  con.Aliases,
+
+		SplitSections:                   con.SplitSections,
+		PreventSplitSections:            con.PreventSplitSections,
+		ResetDepth:                      con.ResetDepth,
+		OmitChildrenFromTableOfContents: con.OmitChildrenFromTableOfContents,
+		HideFromTableOfContents:         con.HideFromTableOfContents,
+		TableOfContentsDepth:            con.TableOfContentsDepth,
+		CollectEndnotes:                 con.CollectEndnotes,
+
+		FrontMatter:    string(con.FrontMatter),
+		Locale:         con.Locale,
+		Direction:      string(con.Direction),
+		Classes:        con.Classes,
+		DataAttributes: con.DataAttributes,
+		Permalinks:     string(con.Permalinks),
+		ErrorPage:      con.ErrorPage,
+		Requires:       con.Requires,
+		License:        con.License,
+	}
+
+	if !con.Published.IsZero() {
+		published := con.Published
+		node.Published = &published
+	}
+
+	for _, tag := range con.Tags {
+		title, err := Encode(tag.Title)
+		if err != nil {
+			return err
+		}
+
+		content, err := Encode(tag.Content)
+		if err != nil {
+			return err
+		}
+
+		node.Tags = append(node.Tags, Tag{
+			Name:    tag.Name,
+			Title:   title,
+			Anchor:  tag.Anchor,
+			Content: content,
+		})
+	}
+
+	for _, attr := range con.Attributions {
+		subject, err := Encode(attr.Subject)
+		if err != nil {
+			return err
+		}
+
+		credit, err := Encode(attr.Credit)
+		if err != nil {
+			return err
+		}
+
+		node.Attributions = append(node.Attributions, Attribution{
+			Subject: subject,
+			Credit:  credit,
+			License: attr.License,
+		})
+	}
+
+	for _, event := range con.Events {
+		title, err := Encode(event.Title)
+		if err != nil {
+			return err
+		}
+
+		description, err := Encode(event.Description)
+		if err != nil {
+			return err
+		}
+
+		node.Events = append(node.Events, Event{
+			Date:        event.Date,
+			AllDay:      event.AllDay,
+			Title:       title,
+			Description: description,
+			Tag:         event.TagName,
+		})
+	}
+
+	for _, child := range con.Children {
+		n, err := Encode(child)
+		if err != nil {
+			return err
+		}
+
+		node.Children = append(node.Children, n)
+	}
+
+	encoder.Result = node
+
+	return nil
+}
+
+func (encoder *encoder) VisitTableOfContents(con booklit.TableOfContents) error {
+	encoder.Result = &Node{Type: "table-of-contents", Tag: sectionTag(con.Section)}
+	return nil
+}
+
+func (encoder *encoder) VisitStyled(con booklit.Styled) error {
+	content, err := Encode(con.Content)
+	if err != nil {
+		return err
+	}
+
+	partials, err := encodePartials(con.Partials)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{
+		Type:     "styled",
+		Style:    string(con.Style),
+		Block:    con.Block,
+		Content:  content,
+		Partials: partials,
+	}
+
+	return nil
+}
+
+func (encoder *encoder) VisitTarget(con booklit.Target) error {
+	title, err := Encode(con.Title)
+	if err != nil {
+		return err
+	}
+
+	content, err := Encode(con.Content)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{
+		Type:    "target",
+		Tag:     con.TagName,
+		Title:   title,
+		Content: content,
+	}
+
+	return nil
+}
+
+func (encoder *encoder) VisitImage(con booklit.Image) error {
+	encoder.Result = &Node{
+		Type:        "image",
+		Path:        con.Path,
+		Description: con.Description,
+	}
+
+	return nil
+}
+
+func (encoder *encoder) VisitList(con booklit.List) error {
+	items, err := encodeAll(con.Items)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "list", Items: items, Ordered: con.Ordered}
+
+	return nil
+}
+
+func (encoder *encoder) VisitTable(con booklit.Table) error {
+	node := &Node{
+		Type:       "table",
+		HeaderRows: con.HeaderRows,
+	}
+
+	for _, row := range con.Rows {
+		cells, err := encodeAll(row)
+		if err != nil {
+			return err
+		}
+
+		node.Rows = append(node.Rows, cells)
+	}
+
+	for _, alignment := range con.Alignments {
+		node.Alignments = append(node.Alignments, string(alignment))
+	}
+
+	encoder.Result = node
+
+	return nil
+}
+
+func (encoder *encoder) VisitDefinitions(con booklit.Definitions) error {
+	node := &Node{Type: "definitions"}
+
+	for _, def := range con {
+		subject, err := Encode(def.Subject)
+		if err != nil {
+			return err
+		}
+
+		definition, err := Encode(def.Definition)
+		if err != nil {
+			return err
+		}
+
+		node.Definitions = append(node.Definitions, Definition{
+			Subject:    subject,
+			Definition: definition,
+		})
+	}
+
+	encoder.Result = node
+
+	return nil
+}
+
+func (encoder *encoder) VisitFigure(con *booklit.Figure) error {
+	content, err := Encode(con.Content)
+	if err != nil {
+		return err
+	}
+
+	caption, err := Encode(con.Caption)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{
+		Type:    "figure",
+		Tag:     con.TagName,
+		Content: content,
+		Caption: caption,
+	}
+
+	return nil
+}
+
+func (encoder *encoder) VisitFootnote(con *booklit.Footnote) error {
+	content, err := Encode(con.Content)
+	if err != nil {
+		return err
+	}
+
+	encoder.Result = &Node{Type: "footnote", Content: content}
+
+	return nil
+}
+
+func (encoder *encoder) VisitListOfFigures(con booklit.ListOfFigures) error {
+	encoder.Result = &Node{Type: "list-of-figures", Tag: sectionTag(con.Section)}
+	return nil
+}
+
+func (encoder *encoder) VisitAttributions(con booklit.Attributions) error {
+	encoder.Result = &Node{Type: "attributions", Tag: sectionTag(con.Section)}
+	return nil
+}
@@ -0,0 +1,539 @@
+package interchange
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+)
+
+// Import converts a Document into a book. Its references are left to be
+// resolved by loading it, e.g. with (*load.Processor).LoadSection.
+//
+// Sections which aren't given any tags are tagged by their title, and the
+// tables of contents, lists of figures, and attributions which aren't given
+// a tag are for the section they're in.
+func Import(doc *Document) (*booklit.Section, error) {
+	if doc.Version != Version {
+		return nil, fmt.Errorf("unsupported interchange version: %d", doc.Version)
+	}
+
+	if doc.Book == nil || doc.Book.Type != "section" {
+		return nil, fmt.Errorf("invalid book: expected a section")
+	}
+
+	sections := []importedSection{}
+
+	book, err := importSection(doc.Book, nil, &sections)
+	if err != nil {
+		return nil, err
+	}
+
+	// now that every section is tagged, decode the content which may refer
+	// to them
+	for _, imported := range sections {
+		err := imported.decodeContent(book)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// number the figures in the order they appear, as if collected
+	for i, figure := range book.AllFigures() {
+		figure.SetNumber(i + 1)
+	}
+
+	return book, nil
+}
+
+// Decode converts a node into content. Sections may only be imported along
+// with a book, by Import.
+func Decode(node *Node) (booklit.Content, error) {
+	return (&nodeDecoder{}).decode(node)
+}
+
+// importedSection is a section whose body and partials have yet to be
+// decoded.
+type importedSection struct {
+	node    *Node
+	section *booklit.Section
+}
+
+// importSection constructs the section and its sub-sections, along with
+// their titles and tags.
+func importSection(node *Node, parent *booklit.Section, sections *[]importedSection) (*booklit.Section, error) {
+	section := &booklit.Section{
+		Parent: parent,
+
+		Path: node.Path,
+
+		Title: booklit.Empty,
+		Body:  booklit.Empty,
+
+		Style: node.Style,
+
+		SplitSections:                   node.SplitSections,
+		PreventSplitSections:            node.PreventSplitSections,
+		ResetDepth:                      node.ResetDepth,
+		OmitChildrenFromTableOfContents: node.OmitChildrenFromTableOfContents,
+		HideFromTableOfContents:         node.HideFromTableOfContents,
+		TableOfContentsDepth:            node.TableOfContentsDepth,
+		CollectEndnotes:                 node.CollectEndnotes,
+
+		FrontMatter:    booklit.FrontMatter(node.FrontMatter),
+		Locale:         node.Locale,
+		Direction:      booklit.Direction(node.Direction),
+		Classes:        node.Classes,
+		DataAttributes: node.DataAttributes,
+		Permalinks:     booklit.Permalinks(node.Permalinks),
+		ErrorPage:      node.ErrorPage,
+		Requires:       node.Requires,
+		License:        node.License,
+	}
+
+	if node.Published != nil {
+		section.Published = *node.Published
+	}
+
+	sectionDecoder := &nodeDecoder{section: section}
+
+	title, err := sectionDecoder.decodeRequired(node.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	section.Title = title
+
+	tagDecoder := &nodeDecoder{}
+
+	for _, tag := range node.Tags {
+		if tag.Name == "" {
+			return nil, fmt.Errorf("invalid tag: expected a name")
+		}
+
+		tagTitle, err := tagDecoder.decode(tag.Title)
+		if err != nil {
+			return nil, err
+		}
+
+		if tagTitle == nil {
+			tagTitle = title
+		}
+
+		content, err := tagDecoder.decode(tag.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		section.SetTagAnchored(tag.Name, tagTitle, ast.Location{}, content, tag.Anchor)
+	}
+
+	tags := section.Tags
+
+	primary := -1
+	for i, tag := range tags {
+		if tag.Anchor == "" {
+			primary = i
+			break
+		}
+	}
+
+	if primary == -1 {
+		section.SetTitle(title, ast.Location{})
+		section.Tags = append(section.Tags, tags...)
+	} else {
+		section.PrimaryTag = tags[primary]
+	}
+
+	// the tags for the aliases are already among the tags, so just note them
+	section.Aliases = node.Aliases
+
+	*sections = append(*sections, importedSection{
+		node:    node,
+		section: section,
+	})
+
+	for _, childNode := range node.Children {
+		if childNode == nil || childNode.Type != "section" {
+			return nil, fmt.Errorf("invalid sub-section: expected a section")
+		}
+
+		child, err := importSection(childNode, section, sections)
+		if err != nil {
+			return nil, err
+		}
+
+		section.Children = append(section.Children, child)
+	}
+
+	return section, nil
+}
+
+// decodeContent decodes the section's body, partials, attributions, and
+// events, collecting its figures and footnotes.
+func (imported importedSection) decodeContent(book *booklit.Section) error {
+	node := imported.node
+	section := imported.section
+
+	decoder := &nodeDecoder{
+		section: section,
+		book:    book,
+	}
+
+	body, err := decoder.decodeRequired(node.Body)
+	if err != nil {
+		return err
+	}
+
+	section.Body = body
+
+	names := []string{}
+	for name := range node.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		partial, err := decoder.decodeRequired(node.Partials[name])
+		if err != nil {
+			return err
+		}
+
+		section.SetPartial(name, partial)
+	}
+
+	for _, attr := range node.Attributions {
+		subject, err := decoder.decode(attr.Subject)
+		if err != nil {
+			return err
+		}
+
+		credit, err := decoder.decode(attr.Credit)
+		if err != nil {
+			return err
+		}
+
+		section.Attributions = append(section.Attributions, booklit.Attribution{
+			Subject: subject,
+			Credit:  credit,
+			License: attr.License,
+			Section: section,
+		})
+	}
+
+	for _, event := range node.Events {
+		title, err := decoder.decodeRequired(event.Title)
+		if err != nil {
+			return err
+		}
+
+		description, err := decoder.decode(event.Description)
+		if err != nil {
+			return err
+		}
+
+		section.Events = append(section.Events, &booklit.Event{
+			Date:        event.Date,
+			AllDay:      event.AllDay,
+			Title:       title,
+			Description: description,
+			TagName:     event.Tag,
+			Section:     section,
+		})
+	}
+
+	return nil
+}
+
+// nodeDecoder converts nodes into content.
+type nodeDecoder struct {
+	// section the content is in, which figures and footnotes are collected
+	// into, if known
+	section *booklit.Section
+
+	// book which tables of contents and such may refer to the sections of,
+	// if known
+	book *booklit.Section
+}
+
+// decodeRequired decodes the node, returning empty content in place of a
+// missing node.
+func (decoder *nodeDecoder) decodeRequired(node *Node) (booklit.Content, error) {
+	content, err := decoder.decode(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if content == nil {
+		return booklit.Empty, nil
+	}
+
+	return content, nil
+}
+
+func (decoder *nodeDecoder) decodeAll(nodes []*Node) ([]booklit.Content, error) {
+	contents := []booklit.Content{}
+	for _, node := range nodes {
+		content, err := decoder.decode(node)
+		if err != nil {
+			return nil, err
+		}
+
+		if content == nil {
+			continue
+		}
+
+		contents = append(contents, content)
+	}
+
+	return contents, nil
+}
+
+// sectionFor returns the section tagged with the given tag, or the section
+// being decoded if no tag is given.
+func (decoder *nodeDecoder) sectionFor(tag string) (*booklit.Section, error) {
+	if tag == "" {
+		if decoder.section == nil {
+			return nil, fmt.Errorf("unknown section: expected a tag")
+		}
+
+		return decoder.section, nil
+	}
+
+	if decoder.book != nil {
+		tags := decoder.book.FindTag(tag)
+		if len(tags) > 0 {
+			return tags[0].Section, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown section: %s", tag)
+}
+
+func (decoder *nodeDecoder) decode(node *Node) (booklit.Content, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	switch node.Type {
+	case "string":
+		return booklit.String(node.Value), nil
+	case "sequence":
+		contents, err := decoder.decodeAll(node.Contents)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Sequence(contents), nil
+	case "aux":
+		content, err := decoder.decodeRequired(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Aux{Content: content}, nil
+	case "paragraph":
+		lines, err := decoder.decodeAll(node.Lines)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Paragraph(lines), nil
+	case "preformatted":
+		lines, err := decoder.decodeAll(node.Lines)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Preformatted(lines), nil
+	case "styled":
+		content, err := decoder.decodeRequired(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		styled := booklit.Styled{
+			Style:   booklit.Style(node.Style),
+			Block:   node.Block,
+			Content: content,
+		}
+
+		for name, partialNode := range node.Partials {
+			partial, err := decoder.decode(partialNode)
+			if err != nil {
+				return nil, err
+			}
+
+			if styled.Partials == nil {
+				styled.Partials = booklit.Partials{}
+			}
+
+			styled.Partials[name] = partial
+		}
+
+		return styled, nil
+	case "link":
+		content, err := decoder.decodeRequired(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Link{
+			Content: content,
+			Target:  node.Target,
+		}, nil
+	case "reference":
+		content, err := decoder.decode(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		return &booklit.Reference{
+			TagName: node.Tag,
+			Content: content,
+		}, nil
+	case "target":
+		// like the content of tags, not collected; it's typically also given
+		// alongside the target
+		uncollected := &nodeDecoder{book: decoder.book}
+
+		title, err := uncollected.decode(node.Title)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := uncollected.decode(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Target{
+			TagName: node.Tag,
+			Title:   title,
+			Content: content,
+		}, nil
+	case "image":
+		return booklit.Image{
+			Path:        node.Path,
+			Description: node.Description,
+		}, nil
+	case "list":
+		items, err := decoder.decodeAll(node.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.List{
+			Items:   items,
+			Ordered: node.Ordered,
+		}, nil
+	case "table":
+		table := booklit.Table{
+			HeaderRows: node.HeaderRows,
+		}
+
+		for _, row := range node.Rows {
+			cells, err := decoder.decodeAll(row)
+			if err != nil {
+				return nil, err
+			}
+
+			table.Rows = append(table.Rows, cells)
+		}
+
+		for _, alignment := range node.Alignments {
+			switch align := booklit.Alignment(alignment); align {
+			case booklit.AlignDefault, booklit.AlignLeft, booklit.AlignCenter, booklit.AlignRight:
+				table.Alignments = append(table.Alignments, align)
+			default:
+				return nil, fmt.Errorf("invalid alignment: %s", alignment)
+			}
+		}
+
+		return table, nil
+	case "definitions":
+		defs := booklit.Definitions{}
+		for _, def := range node.Definitions {
+			subject, err := decoder.decodeRequired(def.Subject)
+			if err != nil {
+				return nil, err
+			}
+
+			definition, err := decoder.decodeRequired(def.Definition)
+			if err != nil {
+				return nil, err
+			}
+
+			defs = append(defs, booklit.Definition{
+				Subject:    subject,
+				Definition: definition,
+			})
+		}
+
+		return defs, nil
+	case "figure":
+		content, err := decoder.decodeRequired(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		caption, err := decoder.decodeRequired(node.Caption)
+		if err != nil {
+			return nil, err
+		}
+
+		figure := &booklit.Figure{
+			Content: content,
+			Caption: caption,
+			TagName: node.Tag,
+		}
+
+		if decoder.section != nil {
+			figure.Section = decoder.section
+			decoder.section.Figures = append(decoder.section.Figures, figure)
+		}
+
+		return figure, nil
+	case "footnote":
+		content, err := decoder.decodeRequired(node.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		footnote := &booklit.Footnote{
+			Content: content,
+		}
+
+		if decoder.section != nil {
+			footnote.Section = decoder.section
+			decoder.section.Footnotes = append(decoder.section.Footnotes, footnote)
+		}
+
+		return footnote, nil
+	case "table-of-contents":
+		section, err := decoder.sectionFor(node.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.TableOfContents{Section: section}, nil
+	case "list-of-figures":
+		section, err := decoder.sectionFor(node.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.ListOfFigures{Section: section}, nil
+	case "attributions":
+		section, err := decoder.sectionFor(node.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return booklit.Attributions{Section: section}, nil
+	default:
+		return nil, fmt.Errorf("invalid content type: %q", node.Type)
+	}
+}
@@ -0,0 +1,212 @@
+// Package interchange converts books to and from a stable JSON format, e.g.
+// for exchanging content with a CMS or generating it from another system.
+//
+// A book is exported as a Document, whose Book is the root section. Every
+// node of content is an object identified by its "type", with the fields
+// described on Node. In input, a string may be given in place of a "string"
+// node, an array in place of a "sequence", and a name in place of a tag.
+//
+// Content is exported after every function has been evaluated and every
+// reference resolved, so importing a book only resolves its references
+// again; no plugins are involved.
+package interchange
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Version is the version of the format, which is only incremented for
+// changes that existing documents or readers would be broken by. New fields
+// and node types may be added without incrementing it.
+const Version = 1
+
+// Document is a book in the interchange format.
+type Document struct {
+	// version of the format the document was written in
+	Version int `json:"version"`
+
+	// root section of the book
+	Book *Node `json:"book"`
+}
+
+// Node is a section or a node of content. Which fields are set depends on the
+// Type:
+//
+//   - string: Value
+//   - sequence: Contents
+//   - aux: Content, which is left out of tags generated from titles and of
+//     references to them
+//   - paragraph, preformatted: Lines
+//   - styled: Style, Block, Content, and Partials
+//   - link: Target and Content
+//   - reference: Tag, and Content to display in place of the tag's title
+//   - target: Tag, Title, and Content
+//   - image: Path and Description
+//   - list: Items and Ordered
+//   - table: Rows, HeaderRows, and Alignments
+//   - definitions: Definitions
+//   - figure: Content, Caption, and Tag
+//   - footnote: Content
+//   - table-of-contents, list-of-figures, attributions: Tag of the section
+//     they're for
+//   - section: Title, Body, Tags, Aliases, Partials, Children, and the fields after
+//     them
+type Node struct {
+	Type string `json:"type"`
+
+	Value       string `json:"value,omitempty"`
+	Style       string `json:"style,omitempty"`
+	Block       bool   `json:"block,omitempty"`
+	Target      string `json:"target,omitempty"`
+	Tag         string `json:"tag,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Description string `json:"description,omitempty"`
+	Ordered     bool   `json:"ordered,omitempty"`
+
+	Content  *Node   `json:"content,omitempty"`
+	Title    *Node   `json:"title,omitempty"`
+	Caption  *Node   `json:"caption,omitempty"`
+	Contents []*Node `json:"contents,omitempty"`
+	Lines    []*Node `json:"lines,omitempty"`
+	Items    []*Node `json:"items,omitempty"`
+
+	Rows       [][]*Node `json:"rows,omitempty"`
+	HeaderRows int       `json:"header_rows,omitempty"`
+	Alignments []string  `json:"alignments,omitempty"`
+
+	Definitions []Definition `json:"definitions,omitempty"`
+
+	Partials map[string]*Node `json:"partials,omitempty"`
+
+	// tags of the section, the first of which is its primary tag, followed
+	// by any tags for targets and figures within it
+	Tags []Tag `json:"tags,omitempty"`
+
+	// former tags of the section, which are also among its tags; each of
+	// them names a page which is redirected to the section's own
+	Aliases []string `json:"aliases,omitempty"`
+
+	Body     *Node   `json:"body,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+
+	SplitSections                   bool `json:"split_sections,omitempty"`
+	PreventSplitSections            bool `json:"prevent_split_sections,omitempty"`
+	ResetDepth                      bool `json:"reset_depth,omitempty"`
+	OmitChildrenFromTableOfContents bool `json:"omit_children_from_table_of_contents,omitempty"`
+	HideFromTableOfContents         bool `json:"hide_from_table_of_contents,omitempty"`
+	TableOfContentsDepth            int  `json:"table_of_contents_depth,omitempty"`
+	CollectEndnotes                 bool `json:"collect_endnotes,omitempty"`
+
+	FrontMatter    string            `json:"front_matter,omitempty"`
+	Locale         string            `json:"locale,omitempty"`
+	Direction      string            `json:"direction,omitempty"`
+	Classes        []string          `json:"classes,omitempty"`
+	DataAttributes map[string]string `json:"data_attributes,omitempty"`
+	Permalinks     string            `json:"permalinks,omitempty"`
+	ErrorPage      int               `json:"error_page,omitempty"`
+	Published      *time.Time        `json:"published,omitempty"`
+	Requires       []string          `json:"requires,omitempty"`
+
+	License      string        `json:"license,omitempty"`
+	Attributions []Attribution `json:"attributions,omitempty"`
+
+	Events []Event `json:"events,omitempty"`
+}
+
+// Tag is a tag of a section, which references may refer to.
+type Tag struct {
+	Name  string `json:"name"`
+	Title *Node  `json:"title,omitempty"`
+
+	// anchor of the tag within the section's page, if it isn't for the
+	// section itself, along with the content it refers to
+	Anchor  string `json:"anchor,omitempty"`
+	Content *Node  `json:"content,omitempty"`
+}
+
+// Definition is a term and its definition in a "definitions" node.
+type Definition struct {
+	Subject    *Node `json:"subject"`
+	Definition *Node `json:"definition"`
+}
+
+// Attribution credits the source of something in a section.
+type Attribution struct {
+	Subject *Node  `json:"subject,omitempty"`
+	Credit  *Node  `json:"credit,omitempty"`
+	License string `json:"license,omitempty"`
+}
+
+// Event is an event announced by a section.
+type Event struct {
+	Date   time.Time `json:"date"`
+	AllDay bool      `json:"all_day,omitempty"`
+
+	Title       *Node  `json:"title,omitempty"`
+	Description *Node  `json:"description,omitempty"`
+	Tag         string `json:"tag,omitempty"`
+}
+
+// UnmarshalJSON decodes a node, which may also be given as a string or an
+// array of nodes.
+func (node *Node) UnmarshalJSON(payload []byte) error {
+	if len(payload) > 0 {
+		switch payload[0] {
+		case '"':
+			var str string
+			err := json.Unmarshal(payload, &str)
+			if err != nil {
+				return err
+			}
+
+			*node = Node{Type: "string", Value: str}
+
+			return nil
+		case '[':
+			var contents []*Node
+			err := json.Unmarshal(payload, &contents)
+			if err != nil {
+				return err
+			}
+
+			*node = Node{Type: "sequence", Contents: contents}
+
+			return nil
+		}
+	}
+
+	// decode into a type without this method to avoid recursing
+	type plainNode Node
+
+	var plain plainNode
+	err := json.Unmarshal(payload, &plain)
+	if err != nil {
+		return err
+	}
+
+	*node = Node(plain)
+
+	return nil
+}
+
+// UnmarshalJSON decodes a tag, which may also be given as just its name.
+func (tag *Tag) UnmarshalJSON(payload []byte) error {
+	if len(payload) > 0 && payload[0] == '"' {
+		*tag = Tag{}
+		return json.Unmarshal(payload, &tag.Name)
+	}
+
+	// decode into a type without this method to avoid recursing
+	type plainTag Tag
+
+	var plain plainTag
+	err := json.Unmarshal(payload, &plain)
+	if err != nil {
+		return err
+	}
+
+	*tag = Tag(plain)
+
+	return nil
+}
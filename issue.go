@@ -0,0 +1,23 @@
+package booklit
+
+import "context"
+
+// IssueTracker looks up issues and pull requests referred to by \issue, e.g.
+// vito/booklit#123.
+type IssueTracker interface {
+	// Issue looks up the issue, stopping once the context is done.
+	Issue(ctx context.Context, ref string) (Issue, error)
+}
+
+// Issue is an issue or pull request in a tracker, e.g. on GitHub.
+type Issue struct {
+	// reference to the issue, e.g. vito/booklit#123
+	Ref string
+
+	URL string
+
+	// title and state, e.g. open, closed, or merged; empty if they couldn't
+	// be fetched, e.g. when building offline
+	Title string
+	State string
+}
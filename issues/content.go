@@ -0,0 +1,58 @@
+package issues
+
+import "github.com/vito/booklit"
+
+// stateColors are the colors of each state of an issue, as shown by GitHub.
+var stateColors = map[string]string{
+	"open":   "#1a7f37",
+	"closed": "#cf222e",
+	"merged": "#8250df",
+}
+
+// Content returns a link to the issue, styled by its state if it's known.
+// Books without an IssueTracker link to issues on github.com and gitlab.com
+// without fetching them.
+func Content(section *booklit.Section, ref string) (booklit.Content, error) {
+	var tracker booklit.IssueTracker = &Tracker{}
+	if top := section.Top(); top.IssueTracker != nil {
+		tracker = top.IssueTracker
+	}
+
+	issue, err := tracker.Issue(section.Context(), ref)
+	if err != nil {
+		return nil, err
+	}
+
+	label := issue.Ref
+	if issue.Title != "" {
+		label += ": " + issue.Title
+	}
+
+	partials := booklit.Partials{
+		"Ref": booklit.String(issue.Ref),
+		"URL": booklit.String(section.RewriteURL(issue.URL)),
+	}
+
+	if issue.Title != "" {
+		partials["Title"] = booklit.String(issue.Title)
+	}
+
+	if issue.State != "" {
+		color, found := stateColors[issue.State]
+		if !found {
+			color = "#6e7781"
+		}
+
+		partials["State"] = booklit.String(issue.State)
+		partials["Color"] = booklit.String(color)
+	}
+
+	return booklit.Styled{
+		Style: booklit.StyleIssue,
+		Content: booklit.Link{
+			Content: booklit.String(label),
+			Target:  section.RewriteURL(issue.URL),
+		},
+		Partials: partials,
+	}, nil
+}
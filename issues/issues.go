@@ -0,0 +1,329 @@
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// Host is an instance of GitHub or GitLab, e.g. https://github.com or a
+// self-hosted one.
+type Host struct {
+	// URL of the site, e.g. https://gitlab.example.com
+	URL string
+
+	// Token to authenticate with; if empty, issues are linked to without
+	// being fetched.
+	Token string
+}
+
+// Tracker looks up issues and pull requests on GitHub and GitLab. A
+// reference such as vito/booklit#123 is on GitHub unless prefixed with
+// gitlab:, e.g. gitlab:group/project#45 for an issue or
+// gitlab:group/project!67 for a merge request.
+//
+// Issues are only fetched from hosts with a token. They're cached in a file,
+// if given, and refetched once they're older than MaxAge, falling back to the
+// cached issue if fetching fails.
+type Tracker struct {
+	GitHub Host
+	GitLab Host
+
+	// JSON file which fetched issues are saved to, e.g. .booklit-issues.json
+	Cache string
+
+	// how long cached issues are used for before being refetched; if zero,
+	// they're used until the cache is removed
+	MaxAge time.Duration
+
+	// client to fetch issues with; defaults to one which gives up after
+	// defaultTimeout
+	Client *http.Client
+
+	cache  map[string]cachedIssue
+	loaded bool
+	lock   sync.Mutex
+}
+
+type cachedIssue struct {
+	Title   string    `json:"title"`
+	State   string    `json:"state"`
+	URL     string    `json:"url"`
+	Fetched time.Time `json:"fetched"`
+}
+
+var refRegexp = regexp.MustCompile(`^(?:(github|gitlab):)?([\w.-]+(?:/[\w.-]+)+)([#!])([0-9]+)$`)
+
+const (
+	defaultGitHubURL = "https://github.com"
+	defaultGitLabURL = "https://gitlab.com"
+)
+
+// defaultTimeout is how long to wait for an issue to be fetched without a
+// Client, so that an unresponsive host can't hang the build.
+const defaultTimeout = 10 * time.Second
+
+type ref struct {
+	host   string
+	repo   string
+	merge  bool
+	number string
+}
+
+// Issue looks up the issue, fetching it if its host has a token and it
+// isn't cached. Failing to fetch an issue is logged rather than returned, as
+// it can still be linked to, unless it's because the context is done.
+func (tracker *Tracker) Issue(ctx context.Context, reference string) (booklit.Issue, error) {
+	match := refRegexp.FindStringSubmatch(strings.TrimSpace(reference))
+	if match == nil {
+		return booklit.Issue{}, fmt.Errorf("invalid issue (expected org/repo#123 or gitlab:group/project#123): %s", reference)
+	}
+
+	r := ref{
+		host:   match[1],
+		repo:   match[2],
+		merge:  match[3] == "!",
+		number: match[4],
+	}
+
+	if r.host == "" {
+		r.host = "github"
+	}
+
+	if r.host == "github" && r.merge {
+		return booklit.Issue{}, fmt.Errorf("invalid issue (GitHub pull requests are referred to with #): %s", reference)
+	}
+
+	issue := booklit.Issue{
+		Ref: r.repo + match[3] + r.number,
+		URL: tracker.webURL(r),
+	}
+
+	host := tracker.host(r)
+	if host.Token == "" {
+		return issue, nil
+	}
+
+	key := r.host + ":" + issue.Ref
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.load()
+
+	cached, found := tracker.cache[key]
+	if found && (tracker.MaxAge == 0 || time.Since(cached.Fetched) < tracker.MaxAge) {
+		return cached.issue(issue.Ref), nil
+	}
+
+	fetched, err := tracker.fetch(ctx, r, host)
+	if err != nil {
+		if ctx.Err() != nil {
+			return booklit.Issue{}, ctx.Err()
+		}
+
+		logrus.WithError(err).WithField("issue", reference).Warn("failed to fetch issue")
+
+		if found {
+			return cached.issue(issue.Ref), nil
+		}
+
+		return issue, nil
+	}
+
+	fetched.Fetched = time.Now()
+
+	tracker.cache[key] = fetched
+	tracker.save()
+
+	return fetched.issue(issue.Ref), nil
+}
+
+func (cached cachedIssue) issue(ref string) booklit.Issue {
+	return booklit.Issue{
+		Ref:   ref,
+		URL:   cached.URL,
+		Title: cached.Title,
+		State: cached.State,
+	}
+}
+
+func (tracker *Tracker) host(r ref) Host {
+	host := tracker.GitHub
+	if r.host == "gitlab" {
+		host = tracker.GitLab
+	}
+
+	if host.URL == "" {
+		if r.host == "gitlab" {
+			host.URL = defaultGitLabURL
+		} else {
+			host.URL = defaultGitHubURL
+		}
+	}
+
+	host.URL = strings.TrimRight(host.URL, "/")
+
+	return host
+}
+
+func (tracker *Tracker) webURL(r ref) string {
+	host := tracker.host(r)
+
+	switch {
+	case r.host == "gitlab" && r.merge:
+		return host.URL + "/" + r.repo + "/-/merge_requests/" + r.number
+	case r.host == "gitlab":
+		return host.URL + "/" + r.repo + "/-/issues/" + r.number
+	default:
+		// redirects to the pull request if it is one
+		return host.URL + "/" + r.repo + "/issues/" + r.number
+	}
+}
+
+func (tracker *Tracker) fetch(ctx context.Context, r ref, host Host) (cachedIssue, error) {
+	var apiURL string
+	switch {
+	case r.host == "gitlab":
+		kind := "issues"
+		if r.merge {
+			kind = "merge_requests"
+		}
+
+		apiURL = host.URL + "/api/v4/projects/" + url.PathEscape(r.repo) + "/" + kind + "/" + r.number
+	case host.URL == defaultGitHubURL:
+		apiURL = "https://api.github.com/repos/" + r.repo + "/issues/" + r.number
+	default:
+		// GitHub Enterprise
+		apiURL = host.URL + "/api/v3/repos/" + r.repo + "/issues/" + r.number
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return cachedIssue{}, err
+	}
+
+	if r.host == "gitlab" {
+		req.Header.Set("PRIVATE-TOKEN", host.Token)
+	} else {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+host.Token)
+	}
+
+	client := tracker.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return cachedIssue{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return cachedIssue{}, fmt.Errorf("unexpected response: %s", res.Status)
+	}
+
+	var body struct {
+		Title string `json:"title"`
+		State string `json:"state"`
+
+		// GitHub
+		HTMLURL     string `json:"html_url"`
+		PullRequest *struct {
+			MergedAt *time.Time `json:"merged_at"`
+		} `json:"pull_request"`
+
+		// GitLab
+		WebURL string `json:"web_url"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return cachedIssue{}, err
+	}
+
+	issue := cachedIssue{
+		Title: body.Title,
+		State: body.State,
+		URL:   body.HTMLURL,
+	}
+
+	if r.host == "gitlab" {
+		issue.URL = body.WebURL
+
+		if issue.State == "opened" {
+			issue.State = "open"
+		}
+	}
+
+	if body.PullRequest != nil && body.PullRequest.MergedAt != nil {
+		issue.State = "merged"
+	}
+
+	if issue.URL == "" {
+		issue.URL = tracker.webURL(r)
+	}
+
+	return issue, nil
+}
+
+// load reads the cache file, if any, upon the first lookup.
+func (tracker *Tracker) load() {
+	if tracker.loaded {
+		return
+	}
+
+	tracker.loaded = true
+	tracker.cache = map[string]cachedIssue{}
+
+	if tracker.Cache == "" {
+		return
+	}
+
+	content, err := ioutil.ReadFile(tracker.Cache)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.WithError(err).Warn("failed to read issue cache")
+		}
+
+		return
+	}
+
+	err = json.Unmarshal(content, &tracker.cache)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to parse issue cache")
+		tracker.cache = map[string]cachedIssue{}
+	}
+}
+
+// save writes the cache file, if any, after each fetch.
+func (tracker *Tracker) save() {
+	if tracker.Cache == "" {
+		return
+	}
+
+	content, err := json.MarshalIndent(tracker.cache, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Warn("failed to encode issue cache")
+		return
+	}
+
+	err = ioutil.WriteFile(tracker.Cache, content, 0644)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to write issue cache")
+	}
+}
@@ -1,9 +1,14 @@
 package booklit
 
+import "github.com/vito/booklit/ast"
+
 type Link struct {
 	Content
 
 	Target string
+
+	// original location of the link, if known
+	Location ast.Location
 }
 
 func (con Link) Visit(visitor Visitor) error {
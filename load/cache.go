@@ -0,0 +1,88 @@
+package load
+
+import (
+	"encoding/gob"
+	"io"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+)
+
+func init() {
+	gob.Register(ast.String(""))
+	gob.Register(ast.Invoke{})
+	gob.Register(ast.Sequence{})
+	gob.Register(ast.Paragraph{})
+	gob.Register(ast.Preformatted{})
+}
+
+// parseCache is the format of the cache written by WriteParseCache. Caches
+// written by a different version of Booklit are ignored, since the syntax
+// tree may have changed.
+type parseCache struct {
+	Version string
+	Files   map[string]parsedFile
+}
+
+type parsedFile struct {
+	Node    ast.Node
+	ModTime time.Time
+}
+
+// WriteParseCache writes every file parsed by the processor along with its
+// modification time, so that a later process can skip parsing them again
+// with ReadParseCache.
+func (processor *Processor) WriteParseCache(out io.Writer) error {
+	processor.parsedL.Lock()
+	defer processor.parsedL.Unlock()
+
+	cache := parseCache{
+		Version: booklit.Version,
+		Files:   map[string]parsedFile{},
+	}
+
+	for path, parsed := range processor.parsed {
+		cache.Files[path] = parsedFile{
+			Node:    parsed.Node,
+			ModTime: parsed.ModTime,
+		}
+	}
+
+	return gob.NewEncoder(out).Encode(cache)
+}
+
+// ReadParseCache reads files parsed by a previous process, as written by
+// WriteParseCache. They are reused by later loads for as long as they have
+// not been modified since.
+func (processor *Processor) ReadParseCache(in io.Reader) error {
+	var cache parseCache
+	err := gob.NewDecoder(in).Decode(&cache)
+	if err != nil {
+		return err
+	}
+
+	if cache.Version != booklit.Version {
+		return nil
+	}
+
+	processor.parsedL.Lock()
+	defer processor.parsedL.Unlock()
+
+	if processor.parsed == nil {
+		processor.parsed = map[string]parsedNode{}
+	}
+
+	for path, file := range cache.Files {
+		if _, found := processor.parsed[path]; found {
+			continue
+		}
+
+		processor.parsed[path] = parsedNode{
+			Node:    file.Node,
+			ModTime: file.ModTime,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package load
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+)
+
+// prefetch is a file being parsed ahead of time, in the background.
+type prefetch struct {
+	done chan struct{}
+
+	node    ast.Node
+	modTime time.Time
+	err     error
+}
+
+// prefetchIncludes parses every file included by \include-section with a
+// literal path in the given file's syntax tree in the background, along
+// with any files they include in turn, so that they're ready by the time
+// they're evaluated. Evaluation itself still happens in order, so the
+// result is the same as parsing each file as it's included.
+//
+// Files which may not be included in safe mode are not prefetched, and are
+// left to fail once they're evaluated.
+func (processor *Processor) prefetchIncludes(section *booklit.Section, node ast.Node) {
+	if processor.Jobs <= 1 {
+		return
+	}
+
+	dir := filepath.Dir(section.Path)
+
+	for _, include := range literalIncludes(node) {
+		path := filepath.Join(dir, include)
+
+		err := processor.checkSafe(section, path)
+		if err != nil {
+			continue
+		}
+
+		processor.startPrefetch(section, path)
+	}
+}
+
+// startPrefetch parses the file included by the parent in the background.
+func (processor *Processor) startPrefetch(parent *booklit.Section, path string) {
+	processor.parsedL.Lock()
+	defer processor.parsedL.Unlock()
+
+	if processor.prefetches == nil {
+		processor.prefetches = map[string]*prefetch{}
+	}
+
+	if _, found := processor.prefetches[path]; found {
+		return
+	}
+
+	if processor.prefetchSlots == nil {
+		processor.prefetchSlots = make(chan struct{}, processor.Jobs)
+	}
+
+	fetch := &prefetch{
+		done: make(chan struct{}),
+	}
+
+	processor.prefetches[path] = fetch
+
+	slots := processor.prefetchSlots
+
+	go func() {
+		defer close(fetch.done)
+
+		slots <- struct{}{}
+		defer func() { <-slots }()
+
+		fetch.node, fetch.modTime, fetch.err = processor.prefetchFile(path)
+		if fetch.err == nil {
+			// stands in for the section until it's evaluated, so that the
+			// files it includes are checked at the right depth
+			included := &booklit.Section{
+				Parent: parent,
+				Path:   path,
+			}
+
+			processor.prefetchIncludes(included, fetch.node)
+		}
+	}()
+}
+
+func (processor *Processor) prefetchFile(path string) (ast.Node, time.Time, error) {
+	info, err := booklit.StatFile(processor.FS, path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	modTime := info.ModTime()
+
+	processor.parsedL.Lock()
+	parsed, found := processor.parsed[path]
+	processor.parsedL.Unlock()
+
+	if found && !modTime.After(parsed.ModTime) {
+		return parsed.Node, parsed.ModTime, nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path": path,
+	}).Debug("prefetching section")
+
+	processor.parsedL.Lock()
+	processor.cacheMisses++
+	processor.parsedL.Unlock()
+
+	node, err := ParseFS(processor.FS, path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return node, modTime, nil
+}
+
+// prefetched waits for the file to be parsed if it is being prefetched,
+// returning its syntax tree if it was not modified since.
+func (processor *Processor) prefetched(path string, modTime time.Time) (ast.Node, bool, error) {
+	processor.parsedL.Lock()
+	fetch, found := processor.prefetches[path]
+	processor.parsedL.Unlock()
+
+	if !found {
+		return nil, false, nil
+	}
+
+	<-fetch.done
+
+	if fetch.err != nil {
+		return nil, false, fetch.err
+	}
+
+	if !fetch.modTime.Equal(modTime) {
+		return nil, false, nil
+	}
+
+	return fetch.node, true, nil
+}
+
+// literalIncludes returns the paths given to every \include-section in the
+// syntax tree which are literal strings, i.e. not computed by other
+// functions.
+func literalIncludes(node ast.Node) []string {
+	includes := []string{}
+
+	var walk func(ast.Node)
+	walk = func(node ast.Node) {
+		switch typed := node.(type) {
+		case ast.Invoke:
+			if typed.Function == "include-section" && len(typed.Arguments) == 1 {
+				if path, ok := literalString(typed.Arguments[0]); ok {
+					includes = append(includes, path)
+				}
+			}
+
+			for _, arg := range typed.Arguments {
+				walk(arg)
+			}
+		case ast.Sequence:
+			for _, sub := range typed {
+				walk(sub)
+			}
+		case ast.Paragraph:
+			for _, line := range typed {
+				walk(line)
+			}
+		case ast.Preformatted:
+			for _, line := range typed {
+				walk(line)
+			}
+		}
+	}
+
+	walk(node)
+
+	return includes
+}
+
+func literalString(node ast.Node) (string, bool) {
+	switch typed := node.(type) {
+	case ast.String:
+		return string(typed), true
+	case ast.Sequence:
+		str := ""
+		for _, sub := range typed {
+			s, ok := literalString(sub)
+			if !ok {
+				return "", false
+			}
+
+			str += s
+		}
+
+		return str, true
+	case ast.Paragraph:
+		if len(typed) != 1 {
+			return "", false
+		}
+
+		return literalString(typed[0])
+	default:
+		return "", false
+	}
+}
@@ -1,7 +1,13 @@
 package load
 
 import (
+	"context"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,8 +21,140 @@ import (
 type Processor struct {
 	AllowBrokenReferences bool
 
+	// Locale used for formatting by root sections, e.g. en-US.
+	Locale string
+
+	// Slugifier used by root sections for generating default tags.
+	Slugifier *booklit.Slugifier
+
+	// How pages are named and linked to. Defaults to booklit.URLStyleFiles.
+	URLStyle booklit.URLStyle
+
+	// Permalinks of the pages beneath the sections with the given tags.
+	Permalinks map[string]booklit.Permalinks
+
+	// Path the site is hosted under, e.g. /docs/, which links to pages and
+	// assets are made absolute to.
+	BasePath string
+
+	// Rules for rewriting the URLs of links, images, and assets in root
+	// sections' books, applied in order.
+	LinkRewrites []booklit.LinkRewrite
+
+	// Engine that root sections will be rendered with, so that plugins may
+	// tailor their output.
+	Engine booklit.RenderingEngine
+
+	// If set, the time spent in each function invocation is recorded.
+	Profile *stages.Profile
+
+	// If set, undefined functions and unknown plugins are rendered as
+	// placeholders with a warning, e.g. for previewing prose without every
+	// plugin available.
+	IgnoreMissingPlugins bool
+
+	// If set, each function invocation is logged, for debugging plugins.
+	DebugEval bool
+
+	// If non-zero, evaluation and resolution carry on past errors until this
+	// many have been encountered, at which point they are all returned at
+	// once as *booklit.BuildErrors.
+	MaxErrors int
+
+	// If set, warnings are returned as errors, stopping the build.
+	Strict bool
+
+	// If set, root sections' books are limited by it, e.g. for building
+	// untrusted input; see booklit.SafeMode.
+	Safe *booklit.SafeMode
+
+	// Flags enabled for root sections' books, which conditional content is
+	// included by, e.g. enterprise.
+	Flags []string
+
+	// URL of the execution backend which playgrounds in root sections' books
+	// run their code with.
+	PlaygroundURL string
+
+	// Commands which diagrams in root sections' books are rendered to SVG
+	// with, by the language of the diagram.
+	DiagramCommands map[string][]string
+
+	// Commands which math in root sections' books is rendered to HTML with,
+	// rather than being converted to MathML.
+	MathCommand        []string
+	DisplayMathCommand []string
+
+	// Catalogs of translated strings for root sections' books, and the
+	// locale they fall back to.
+	Translations  booklit.Translations
+	DefaultLocale string
+
+	// Looks up the issues referred to by root sections' books.
+	IssueTracker booklit.IssueTracker
+
+	// Generates variants of the images referred to by root sections' books,
+	// e.g. resized for smaller screens.
+	ImageProcessor booklit.ImageProcessor
+
+	// File system which files are loaded from, e.g. an embed.FS, with paths
+	// relative to its root. Defaults to the operating system's, with paths
+	// relative to the working directory.
+	FS fs.FS
+
+	// If set, the prose of root sections' books is checked against it, with
+	// each term which breaks it reported as a warning.
+	Terminology *booklit.Terminology
+
+	// If set, the line breaks within the paragraphs of root sections' books
+	// are preserved rather than their lines being joined.
+	HardWraps bool
+
+	// If set, units in the prose of root sections' books are kept attached
+	// to the numbers before them with non-breaking spaces.
+	AttachUnits bool
+
+	// Number of files to parse at once. If greater than 1, files included
+	// by \include-section are parsed in the background ahead of their
+	// evaluation, which still happens in order.
+	Jobs int
+
+	// If set, it's notified as each file is parsed and each section is
+	// resolved.
+	Progress booklit.Progress
+
+	// Time root sections' books are built as of, e.g. for \today. Defaults
+	// to $SOURCE_DATE_EPOCH if it's set, for reproducible builds, or else
+	// the time each load starts.
+	BuildTime time.Time
+
+	// languages being loaded by LoadLanguages
+	languages []Language
+
+	// context of the current load, which root sections' books are given
+	ctx context.Context
+
+	// time the current load's books are built as of
+	buildTime time.Time
+
+	// errors collected during the current load, if MaxErrors is set; see
+	// loadErrors
+	errors  *booklit.BuildErrors
+	errorsL sync.Mutex
+
+	// warnings collected during the current or last load
+	warnings *booklit.Warnings
+
 	parsed  map[string]parsedNode
 	parsedL sync.Mutex
+
+	// files being parsed ahead of time during the current load, if Jobs is
+	// greater than 1
+	prefetches    map[string]*prefetch
+	prefetchSlots chan struct{}
+
+	cacheHits   int
+	cacheMisses int
 }
 
 type parsedNode struct {
@@ -25,20 +163,121 @@ type parsedNode struct {
 }
 
 func (processor *Processor) LoadFile(path string, pluginFactories []booklit.PluginFactory) (*booklit.Section, error) {
-	return processor.LoadFileIn(nil, path, pluginFactories)
+	return processor.LoadFileContext(context.Background(), path, pluginFactories)
 }
 
+// LoadFileContext is like LoadFile, stopping once the context is done and
+// returning its error, possibly wrapped with where it stopped. The context is
+// given to the book's plugins; see booklit.Section.Context.
+func (processor *Processor) LoadFileContext(ctx context.Context, path string, pluginFactories []booklit.PluginFactory) (*booklit.Section, error) {
+	return processor.loadFileIn(ctx, nil, path, pluginFactories)
+}
+
+// LoadFileIn loads the file as a child of the parent, in the parent's
+// context.
 func (processor *Processor) LoadFileIn(parent *booklit.Section, path string, pluginFactories []booklit.PluginFactory) (*booklit.Section, error) {
+	ctx := context.Background()
+	if parent != nil {
+		ctx = parent.Context()
+	}
+
+	return processor.loadFileIn(ctx, parent, path, pluginFactories)
+}
+
+func (processor *Processor) loadFileIn(ctx context.Context, parent *booklit.Section, path string, pluginFactories []booklit.PluginFactory) (*booklit.Section, error) {
+	processor.startLoad(ctx)
+
+	started := processor.startStage(booklit.StageParse, 0)
+
 	section, err := processor.EvaluateFile(parent, path, pluginFactories)
+	if err != nil {
+		return nil, processor.loadError(err)
+	}
+
+	processor.finishStage(booklit.StageParse, started)
+
+	section, err = processor.runStages(section)
+	if err != nil {
+		return nil, processor.loadError(err)
+	}
+
+	err = processor.finishLoad()
 	if err != nil {
 		return nil, err
 	}
 
-	return processor.runStages(section)
+	return section, nil
+}
+
+// LoadSection loads a book which was constructed rather than evaluated, e.g.
+// one imported from JSON, configuring it like a book loaded from a file and
+// resolving its references. Its figures and footnotes must already be
+// collected.
+func (processor *Processor) LoadSection(book *booklit.Section) (*booklit.Section, error) {
+	return processor.LoadSectionContext(context.Background(), book)
+}
+
+// LoadSectionContext is like LoadSection, stopping once the context is done.
+func (processor *Processor) LoadSectionContext(ctx context.Context, book *booklit.Section) (*booklit.Section, error) {
+	processor.startLoad(ctx)
+
+	processor.configureBook(book)
+	setProcessor(book, processor)
+
+	err := processor.resolve(book, nil)
+	if err != nil {
+		return nil, processor.loadError(err)
+	}
+
+	err = processor.lint(book)
+	if err != nil {
+		return nil, processor.loadError(err)
+	}
+
+	err = processor.finishLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+func setProcessor(section *booklit.Section, processor booklit.SectionProcessor) {
+	section.Processor = processor
+
+	for _, child := range section.Children {
+		setProcessor(child, processor)
+	}
 }
 
 func (processor *Processor) EvaluateFile(parent *booklit.Section, path string, pluginFactories []booklit.PluginFactory) (*booklit.Section, error) {
-	info, err := os.Stat(path)
+	err := checkContext(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	var locale string
+	if parent == nil {
+		if lang, found := processor.language(path); found {
+			locale = lang.Locale
+		}
+	}
+
+	info, err := booklit.StatFile(processor.FS, path)
+	if err != nil && os.IsNotExist(err) && parent != nil {
+		// sections which have yet to be translated fall back to the default
+		// language's
+		if fallback, lang, found := processor.untranslated(path); found {
+			path, locale = fallback, lang.Locale
+			info, err = booklit.StatFile(processor.FS, path)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = processor.checkSafe(parent, path)
 	if err != nil {
 		return nil, err
 	}
@@ -60,37 +299,31 @@ func (processor *Processor) EvaluateFile(parent *booklit.Section, path string, p
 	if found && !modTime.After(parsed.ModTime) {
 		log.Debug("already parsed section")
 		node = parsed.Node
-	} else {
-		log.Debug("parsing section")
 
-		file, err := os.Open(path)
+		processor.parsedL.Lock()
+		processor.cacheHits++
+		processor.parsedL.Unlock()
+	} else {
+		var prefetched bool
+		node, prefetched, err = processor.prefetched(path, modTime)
 		if err != nil {
 			return nil, err
 		}
 
-		result, err := ast.ParseReader(path, file)
-		if err != nil {
-			err, loc, ok := ast.UnpackError(err)
-			if !ok {
-				return nil, err
-			}
+		if prefetched {
+			log.Debug("already prefetched section")
+		} else {
+			log.Debug("parsing section")
 
-			return nil, booklit.ParseError{
-				Err: err,
-				ErrorLocation: booklit.ErrorLocation{
-					FilePath:     path,
-					NodeLocation: loc,
-					Length:       1,
-				},
-			}
-		}
+			processor.parsedL.Lock()
+			processor.cacheMisses++
+			processor.parsedL.Unlock()
 
-		err = file.Close()
-		if err != nil {
-			return nil, err
+			node, err = ParseFS(processor.FS, path)
+			if err != nil {
+				return nil, err
+			}
 		}
-
-		node = result.(ast.Node)
 	}
 
 	section := &booklit.Section{
@@ -102,8 +335,16 @@ func (processor *Processor) EvaluateFile(parent *booklit.Section, path string, p
 		Body:  booklit.Empty,
 
 		Processor: processor,
+
+		Locale: locale,
+	}
+
+	if parent == nil {
+		processor.configureBook(section)
 	}
 
+	processor.prefetchIncludes(section, node)
+
 	err = processor.evaluateSection(section, node, pluginFactories)
 	if err != nil {
 		return nil, err
@@ -116,10 +357,107 @@ func (processor *Processor) EvaluateFile(parent *booklit.Section, path string, p
 	}
 	processor.parsedL.Unlock()
 
+	if processor.Progress != nil {
+		processor.Progress.Advance(booklit.StageParse, section)
+	}
+
 	return section, nil
 }
 
+// configureBook sets the fields of a top-level section which are configured
+// by the processor, and only consulted on the top-level section.
+func (processor *Processor) configureBook(section *booklit.Section) {
+	if section.Locale == "" {
+		section.Locale = processor.Locale
+	}
+
+	section.Slugifier = processor.Slugifier
+	section.URLStyle = processor.URLStyle
+	section.PermalinksByTag = processor.Permalinks
+	section.BasePath = processor.BasePath
+	section.LinkRewrites = processor.LinkRewrites
+	section.Warnings = processor.warnings
+	section.Safe = processor.Safe
+	section.Flags = processor.Flags
+	section.PlaygroundURL = processor.PlaygroundURL
+	section.DiagramCommands = processor.DiagramCommands
+	section.MathCommand = processor.MathCommand
+	section.DisplayMathCommand = processor.DisplayMathCommand
+	section.HardWraps = processor.HardWraps
+	section.AttachUnits = processor.AttachUnits
+	section.Translations = processor.Translations
+	section.DefaultLocale = processor.DefaultLocale
+	section.IssueTracker = processor.IssueTracker
+	section.ImageProcessor = processor.ImageProcessor
+	section.FS = processor.FS
+	section.Engine = processor.Engine
+	section.BuildTime = processor.buildTime
+	section.SetContext(processor.ctx)
+}
+
+// checkSafe returns an error if the file may not be evaluated as a child of
+// the parent in safe mode, either because it may not be read or because it
+// would be included too deeply.
+func (processor *Processor) checkSafe(parent *booklit.Section, path string) error {
+	if processor.Safe == nil {
+		return nil
+	}
+
+	if parent == nil {
+		if processor.FS != nil {
+			return processor.Safe.CheckFSFile(processor.FS, path, path)
+		}
+
+		return processor.Safe.CheckFile(path, path)
+	}
+
+	err := parent.CheckFile(path)
+	if err != nil {
+		return err
+	}
+
+	if processor.Safe.MaxIncludeDepth == 0 {
+		return nil
+	}
+
+	depth := 0
+	for sec := parent; sec.Parent != nil; sec = sec.Parent {
+		if sec.Path != "" {
+			depth++
+		}
+	}
+
+	if depth >= processor.Safe.MaxIncludeDepth {
+		return fmt.Errorf("include depth exceeds %d in safe mode: %s", processor.Safe.MaxIncludeDepth, path)
+	}
+
+	return nil
+}
+
+// CacheStats returns the number of times a file's parsed content was reused
+// from a previous load, and the number of times it had to be parsed.
+func (processor *Processor) CacheStats() (int, int) {
+	processor.parsedL.Lock()
+	defer processor.parsedL.Unlock()
+
+	return processor.cacheHits, processor.cacheMisses
+}
+
+// Warnings returns the warnings reported during the last load.
+func (processor *Processor) Warnings() []booklit.Warning {
+	if processor.warnings == nil {
+		return nil
+	}
+
+	return processor.warnings.Warnings
+}
+
 func (processor *Processor) EvaluateNode(parent *booklit.Section, node ast.Node, pluginFactories []booklit.PluginFactory) (*booklit.Section, error) {
+	err := checkContext(parent)
+	if err != nil {
+		return nil, err
+	}
+
 	section := &booklit.Section{
 		Parent: parent,
 
@@ -129,7 +467,11 @@ func (processor *Processor) EvaluateNode(parent *booklit.Section, node ast.Node,
 		Processor: processor,
 	}
 
-	err := processor.evaluateSection(section, node, pluginFactories)
+	if parent == nil {
+		processor.configureBook(section)
+	}
+
+	err = processor.evaluateSection(section, node, pluginFactories)
 	if err != nil {
 		return nil, err
 	}
@@ -137,13 +479,43 @@ func (processor *Processor) EvaluateNode(parent *booklit.Section, node ast.Node,
 	return section, nil
 }
 
+func (processor *Processor) EvaluateContent(section *booklit.Section, node ast.Node) (booklit.Content, error) {
+	err := checkContext(section)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := &stages.Evaluate{
+		Section: section,
+		Profile: processor.Profile,
+
+		IgnoreMissingPlugins: processor.IgnoreMissingPlugins,
+		Debug:                processor.DebugEval,
+		Errors:               processor.loadErrors(),
+	}
+
+	err = node.Visit(evaluator)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluator.Result, nil
+}
+
 func (processor *Processor) evaluateSection(section *booklit.Section, node ast.Node, pluginFactories []booklit.PluginFactory) error {
+	section.Node = node
+
 	for _, pf := range pluginFactories {
 		section.UsePlugin(pf)
 	}
 
 	evaluator := &stages.Evaluate{
 		Section: section,
+		Profile: processor.Profile,
+
+		IgnoreMissingPlugins: processor.IgnoreMissingPlugins,
+		Debug:                processor.DebugEval,
+		Errors:               processor.loadErrors(),
 	}
 
 	err := node.Visit(evaluator)
@@ -155,29 +527,463 @@ func (processor *Processor) evaluateSection(section *booklit.Section, node ast.N
 		section.Body = evaluator.Result
 	}
 
+	// sections with a style may be rendered from their partials alone
+	if section.Body == booklit.Empty && len(section.Children) == 0 && section.Style == "" {
+		err := processor.warn(section, booklit.EmptySectionWarning{
+			TagName:       section.PrimaryTag.Name,
+			ErrorLocation: titleLocation(section),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// checkContext returns the error of the section's context once it's done,
+// so that nothing more is evaluated for a load which has stopped, e.g. by a
+// plugin method which the build gave up on.
+func checkContext(section *booklit.Section) error {
+	if section == nil {
+		return nil
+	}
+
+	return section.Context().Err()
+}
+
+// warn reports a warning for the section's book, recording it as an error
+// if the build is strict and errors are being collected.
+func (processor *Processor) warn(section *booklit.Section, warning booklit.Warning) error {
+	err := section.Warn(warning)
+	if errs := processor.loadErrors(); err != nil && errs != nil {
+		return errs.Record(err)
+	}
+
+	return err
+}
+
+// titleLocation returns the location of the section's \title, if it has
+// one.
+func titleLocation(section *booklit.Section) booklit.ErrorLocation {
+	loc := booklit.ErrorLocation{
+		FilePath: section.FilePath(),
+		FS:       section.Top().FS,
+	}
+
+	if section.PrimaryTag.Location.Line != 0 {
+		loc.NodeLocation = section.PrimaryTag.Location
+		loc.Length = len("\\title")
+	}
+
+	return loc
+}
+
+// LoadBooks loads each file as its own book, allowing each book to reference
+// tags from the others.
+func (processor *Processor) LoadBooks(paths []string, pluginFactories []booklit.PluginFactory) ([]*booklit.Section, error) {
+	return processor.LoadBooksContext(context.Background(), paths, pluginFactories)
+}
+
+// LoadBooksContext is like LoadBooks, stopping once the context is done.
+func (processor *Processor) LoadBooksContext(ctx context.Context, paths []string, pluginFactories []booklit.PluginFactory) ([]*booklit.Section, error) {
+	processor.startLoad(ctx)
+
+	started := processor.startStage(booklit.StageParse, 0)
+
+	books := []*booklit.Section{}
+	for _, path := range paths {
+		book, err := processor.EvaluateFile(nil, path, pluginFactories)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+
+		books = append(books, book)
+	}
+
+	processor.finishStage(booklit.StageParse, started)
+
+	for _, book := range books {
+		err := processor.collect(book)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+	}
+
+	for i, book := range books {
+		others := []*booklit.Section{}
+		others = append(others, books[:i]...)
+		others = append(others, books[i+1:]...)
+
+		err := processor.resolve(book, others)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+
+		err = processor.lint(book)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+	}
+
+	err := processor.finishLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// Language is a translation of a book, loaded from its own directory.
+type Language struct {
+	// e.g. ja
+	Locale string
+
+	// path to the book's root file, e.g. ja/index.lit
+	Path string
+}
+
+// LoadLanguages loads each language's translation of a book. Files which
+// have yet to be translated are loaded from the first language's directory
+// instead, in its locale.
+//
+// Each translation references only its own tags, as they're typically the
+// same in every language.
+func (processor *Processor) LoadLanguages(langs []Language, pluginFactories []booklit.PluginFactory) ([]*booklit.Section, error) {
+	return processor.LoadLanguagesContext(context.Background(), langs, pluginFactories)
+}
+
+// LoadLanguagesContext is like LoadLanguages, stopping once the context is
+// done.
+func (processor *Processor) LoadLanguagesContext(ctx context.Context, langs []Language, pluginFactories []booklit.PluginFactory) ([]*booklit.Section, error) {
+	processor.startLoad(ctx)
+
+	processor.languages = langs
+	defer func() { processor.languages = nil }()
+
+	books := []*booklit.Section{}
+	for _, lang := range langs {
+		started := processor.startStage(booklit.StageParse, 0)
+
+		book, err := processor.EvaluateFile(nil, lang.Path, pluginFactories)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+
+		processor.finishStage(booklit.StageParse, started)
+
+		err = processor.collect(book)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+
+		err = processor.resolve(book, nil)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+
+		err = processor.lint(book)
+		if err != nil {
+			return nil, processor.loadError(err)
+		}
+
+		books = append(books, book)
+	}
+
+	err := processor.finishLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// language returns the language being loaded whose directory contains the
+// path.
+func (processor *Processor) language(path string) (Language, bool) {
+	for _, lang := range processor.languages {
+		rel, err := filepath.Rel(filepath.Dir(lang.Path), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return lang, true
+		}
+	}
+
+	return Language{}, false
+}
+
+// untranslated returns the path to the default language's version of a file
+// missing from another language's directory, if it has one.
+func (processor *Processor) untranslated(path string) (string, Language, bool) {
+	if len(processor.languages) == 0 {
+		return "", Language{}, false
+	}
+
+	lang, found := processor.language(path)
+	if !found {
+		return "", Language{}, false
+	}
+
+	def := processor.languages[0]
+	if lang == def {
+		return "", Language{}, false
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(lang.Path), path)
+	if err != nil {
+		return "", Language{}, false
+	}
+
+	fallback := filepath.Join(filepath.Dir(def.Path), rel)
+
+	_, err = booklit.StatFile(processor.FS, fallback)
+	if err != nil {
+		return "", Language{}, false
+	}
+
+	return fallback, def, true
+}
+
+// startLoad begins collecting warnings for a new load in the context, along
+// with errors if MaxErrors is set.
+func (processor *Processor) startLoad(ctx context.Context) {
+	processor.ctx = ctx
+
+	processor.buildTime = processor.BuildTime
+	if processor.buildTime.IsZero() {
+		processor.buildTime = sourceDate()
+	}
+
+	processor.parsedL.Lock()
+	processor.prefetches = nil
+	processor.parsedL.Unlock()
+
+	processor.warnings = &booklit.Warnings{
+		Strict: processor.Strict,
+	}
+
+	var errs *booklit.BuildErrors
+	if processor.MaxErrors > 0 {
+		errs = &booklit.BuildErrors{
+			Max: processor.MaxErrors,
+		}
+	}
+
+	processor.swapErrors(errs)
+}
+
+// loadErrors returns the errors being collected during the current load, if
+// any. Unlike the rest of the load's state, they're consulted as sections are
+// evaluated, which a plugin method given up on during an earlier load may
+// still be doing, so they're guarded by errorsL.
+func (processor *Processor) loadErrors() *booklit.BuildErrors {
+	processor.errorsL.Lock()
+	defer processor.errorsL.Unlock()
+
+	return processor.errors
+}
+
+// swapErrors sets the errors to collect for the current load, returning the
+// ones being collected until then.
+func (processor *Processor) swapErrors(errs *booklit.BuildErrors) *booklit.BuildErrors {
+	processor.errorsL.Lock()
+	defer processor.errorsL.Unlock()
+
+	prev := processor.errors
+	processor.errors = errs
+
+	return prev
+}
+
+// sourceDate returns the time given by $SOURCE_DATE_EPOCH, or the current
+// time if it isn't set.
+func sourceDate() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err == nil {
+			return time.Unix(secs, 0).UTC()
+		}
+	}
+
+	return time.Now()
+}
+
+// loadError returns the error which stopped the load, along with any errors
+// collected before it, unless the load was cancelled.
+func (processor *Processor) loadError(err error) error {
+	errs := processor.swapErrors(nil)
+
+	if processor.ctx != nil && processor.ctx.Err() != nil {
+		return err
+	}
+
+	if errs == nil {
+		return err
+	}
+
+	_ = errs.Record(err)
+
+	return errs
+}
+
+// finishLoad stops collecting errors, returning the errors collected during
+// the load, if any.
+func (processor *Processor) finishLoad() error {
+	errs := processor.swapErrors(nil)
+	if errs == nil {
+		return nil
+	}
+
+	return errs.Err()
+}
+
 func (processor *Processor) runStages(section *booklit.Section) (*booklit.Section, error) {
-	collector := &stages.Collect{
-		Section: section,
+	err := processor.collect(section)
+	if err != nil {
+		return nil, err
+	}
+
+	err = processor.resolve(section, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	err := section.Visit(collector)
+	err = processor.lint(section)
 	if err != nil {
 		return nil, err
 	}
 
+	return section, nil
+}
+
+func (processor *Processor) collect(section *booklit.Section) error {
+	err := section.Context().Err()
+	if err != nil {
+		return err
+	}
+
+	collector := &stages.Collect{
+		Section: section,
+	}
+
+	return section.Visit(collector)
+}
+
+func (processor *Processor) resolve(section *booklit.Section, books []*booklit.Section) error {
+	started := processor.startStage(booklit.StageResolve, countSections(section))
+
 	resolver := &stages.Resolve{
 		AllowBrokenReferences: processor.AllowBrokenReferences,
 
+		Books: books,
+
+		Errors: processor.loadErrors(),
+
+		Progress: processor.Progress,
+
 		Section: section,
 	}
 
-	err = section.Visit(resolver)
+	err := section.Visit(resolver)
+	if err != nil {
+		return err
+	}
+
+	processor.finishStage(booklit.StageResolve, started)
+
+	return nil
+}
+
+// startStage notifies Progress that the stage is starting, if it's set,
+// returning when it started.
+func (processor *Processor) startStage(stage booklit.Stage, total int) time.Time {
+	if processor.Progress != nil {
+		processor.Progress.StartStage(stage, total)
+	}
+
+	return time.Now()
+}
+
+// finishStage notifies Progress that the stage which started at the given
+// time is done, if it's set.
+func (processor *Processor) finishStage(stage booklit.Stage, started time.Time) {
+	if processor.Progress != nil {
+		processor.Progress.FinishStage(stage, time.Since(started))
+	}
+}
+
+func countSections(section *booklit.Section) int {
+	count := 1
+	for _, child := range section.Children {
+		count += countSections(child)
+	}
+
+	return count
+}
+
+// lint reports a warning for each term in the section's prose which breaks
+// the terminology, if any.
+func (processor *Processor) lint(section *booklit.Section) error {
+	if processor.Terminology == nil {
+		return nil
+	}
+
+	err := section.Context().Err()
+	if err != nil {
+		return err
+	}
+
+	linter := &stages.Lint{
+		Terminology: processor.Terminology,
+	}
+
+	err = section.Visit(linter)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range linter.Warnings {
+		err := processor.warn(section, warning)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseFile parses the .lit file at the given path, returning a
+// booklit.ParseError if it is invalid.
+func ParseFile(path string) (ast.Node, error) {
+	return ParseFS(nil, path)
+}
+
+// ParseFS is like ParseFile, reading the file from the file system, or from
+// the operating system's if it's nil.
+func ParseFS(fsys fs.FS, path string) (ast.Node, error) {
+	file, err := booklit.OpenFile(fsys, path)
 	if err != nil {
 		return nil, err
 	}
 
-	return section, nil
+	defer file.Close()
+
+	result, err := ast.ParseReader(path, file)
+	if err != nil {
+		err, loc, ok := ast.UnpackError(err)
+		if !ok {
+			return nil, err
+		}
+
+		return nil, booklit.ParseError{
+			Err: err,
+			ErrorLocation: booklit.ErrorLocation{
+				FilePath:     path,
+				NodeLocation: loc,
+				Length:       1,
+				FS:           fsys,
+			},
+		}
+	}
+
+	return result.(ast.Node), nil
 }
@@ -0,0 +1,719 @@
+// Package mathml converts TeX math to MathML, so that it can be rendered by
+// browsers without any scripts.
+//
+// Only the commonly used subset of TeX's math is supported: letters,
+// numbers, and operators, superscripts and subscripts, fractions, roots,
+// Greek letters and other symbols, functions like \sin, accents, fonts like
+// \mathbb, delimiters sized with \left and \right, and matrix environments
+// like pmatrix, cases, and aligned.
+package mathml
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Convert converts the TeX math to a MathML <math> element, rendered as a
+// block if display is set. The source is kept in an annotation, e.g. for
+// copying.
+func Convert(source string, display bool) (string, error) {
+	p := &parser{
+		src:     []rune(source),
+		display: display,
+	}
+
+	row, err := p.parseRow(func(tok string) bool { return false })
+	if err != nil {
+		return "", err
+	}
+
+	if tok := p.next(); tok != "" {
+		return "", fmt.Errorf("unexpected %s", tok)
+	}
+
+	// the namespace is needed for XHTML, e.g. in EPUB
+	attrs := ` xmlns="http://www.w3.org/1998/Math/MathML"`
+	if display {
+		attrs += ` display="block"`
+	}
+
+	return `<math` + attrs + `><semantics>` + mrow(row, true) +
+		`<annotation encoding="application/x-tex">` + html.EscapeString(strings.TrimSpace(source)) + `</annotation>` +
+		`</semantics></math>`, nil
+}
+
+type parser struct {
+	src []rune
+	pos int
+
+	// whether the math is displayed as a block rather than inline with text
+	display bool
+
+	// variant which letters are styled in, e.g. by \mathbb
+	variant string
+}
+
+// next returns the next token, skipping whitespace: a command including its
+// backslash, a number, or any other single character. An empty string is
+// returned at the end of the source.
+func (p *parser) next() string {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+
+	if p.pos >= len(p.src) {
+		return ""
+	}
+
+	start := p.pos
+	r := p.src[p.pos]
+	p.pos++
+
+	switch {
+	case r == '\\':
+		if p.pos >= len(p.src) {
+			return `\`
+		}
+
+		if !isLetter(p.src[p.pos]) {
+			p.pos++
+			break
+		}
+
+		for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+			p.pos++
+		}
+	case isDigit(r):
+		for p.pos < len(p.src) {
+			if isDigit(p.src[p.pos]) {
+				p.pos++
+			} else if p.src[p.pos] == '.' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1]) {
+				p.pos += 2
+			} else {
+				break
+			}
+		}
+	}
+
+	return string(p.src[start:p.pos])
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() string {
+	pos := p.pos
+	tok := p.next()
+	p.pos = pos
+	return tok
+}
+
+// parseRow parses elements until the end of the source or the token for
+// which stop returns true, which is left to be consumed.
+func (p *parser) parseRow(stop func(string) bool) ([]string, error) {
+	row := []string{}
+	for {
+		tok := p.peek()
+		if tok == "" || stop(tok) {
+			return row, nil
+		}
+
+		el, err := p.parseScripted()
+		if err != nil {
+			return nil, err
+		}
+
+		if el != "" {
+			row = append(row, el)
+		}
+	}
+}
+
+// parseScripted parses an element along with its superscripts and
+// subscripts.
+func (p *parser) parseScripted() (string, error) {
+	base := "<mrow></mrow>"
+	limits := false
+
+	switch p.peek() {
+	case "^", "_", "'":
+		// scripts without a base, e.g. {}^2
+	default:
+		var err error
+		base, limits, err = p.parseAtom()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var sub, sup []string
+	primes := ""
+
+	for {
+		switch p.peek() {
+		case `\limits`:
+			p.next()
+			limits = true
+			continue
+		case `\nolimits`:
+			p.next()
+			limits = false
+			continue
+		case "'":
+			p.next()
+			primes += "′"
+			continue
+		case "^":
+			p.next()
+
+			if sup != nil {
+				return "", fmt.Errorf("double superscript")
+			}
+
+			arg, err := p.parseArg("^")
+			if err != nil {
+				return "", err
+			}
+
+			sup = []string{arg}
+			continue
+		case "_":
+			p.next()
+
+			if sub != nil {
+				return "", fmt.Errorf("double subscript")
+			}
+
+			arg, err := p.parseArg("_")
+			if err != nil {
+				return "", err
+			}
+
+			sub = []string{arg}
+			continue
+		}
+
+		break
+	}
+
+	if primes != "" {
+		sup = append([]string{"<mo>" + primes + "</mo>"}, sup...)
+	}
+
+	switch {
+	case sub != nil && sup != nil:
+		if limits {
+			return "<munderover>" + base + mrow(sub, false) + mrow(sup, false) + "</munderover>", nil
+		}
+
+		return "<msubsup>" + base + mrow(sub, false) + mrow(sup, false) + "</msubsup>", nil
+	case sub != nil:
+		if limits {
+			return "<munder>" + base + mrow(sub, false) + "</munder>", nil
+		}
+
+		return "<msub>" + base + mrow(sub, false) + "</msub>", nil
+	case sup != nil:
+		if limits {
+			return "<mover>" + base + mrow(sup, false) + "</mover>", nil
+		}
+
+		return "<msup>" + base + mrow(sup, false) + "</msup>", nil
+	default:
+		return base, nil
+	}
+}
+
+// parseArg parses the argument of a command or script, which is either a
+// group or a single element.
+func (p *parser) parseArg(command string) (string, error) {
+	switch p.peek() {
+	case "", "}", "&", `\\`, "^", "_":
+		return "", fmt.Errorf("missing argument for %s", command)
+	}
+
+	el, _, err := p.parseAtom()
+	return el, err
+}
+
+// parseGroup parses the elements up to the closing brace, the opening brace
+// having already been consumed.
+func (p *parser) parseGroup() (string, error) {
+	row, err := p.parseRow(func(tok string) bool { return tok == "}" })
+	if err != nil {
+		return "", err
+	}
+
+	if p.next() != "}" {
+		return "", fmt.Errorf("missing }")
+	}
+
+	return mrow(row, false), nil
+}
+
+// parseRaw returns the source of the group which is next, for commands
+// whose argument is text rather than math.
+func (p *parser) parseRaw(command string) (string, error) {
+	if p.next() != "{" {
+		return "", fmt.Errorf("missing argument for %s", command)
+	}
+
+	start := p.pos
+	depth := 1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+
+		if depth == 0 {
+			raw := string(p.src[start:p.pos])
+			p.pos++
+			return raw, nil
+		}
+	}
+
+	return "", fmt.Errorf("missing }")
+}
+
+// parseAtom parses a single element, returning whether its scripts are
+// placed above and below it rather than beside it.
+func (p *parser) parseAtom() (string, bool, error) {
+	tok := p.next()
+
+	switch {
+	case tok == "{":
+		group, err := p.parseGroup()
+		return group, false, err
+	case tok == "}":
+		return "", false, fmt.Errorf("unexpected }")
+	case tok == "&", tok == `\\`:
+		return "", false, fmt.Errorf("unexpected %s outside of an environment", tok)
+	case strings.HasPrefix(tok, `\`):
+		return p.parseCommand(tok[1:])
+	case isDigit([]rune(tok)[0]):
+		return p.number(tok), false, nil
+	case unicode.IsLetter([]rune(tok)[0]):
+		return p.identifier([]rune(tok)[0]), false, nil
+	case tok == "~":
+		return `<mtext>&#xa0;</mtext>`, false, nil
+	case tok == "-":
+		return "<mo>−</mo>", false, nil
+	case tok == "*":
+		return "<mo>∗</mo>", false, nil
+	default:
+		return mo(tok), false, nil
+	}
+}
+
+func (p *parser) parseCommand(name string) (string, bool, error) {
+	if sym, found := identifiers[name]; found {
+		return "<mi>" + sym + "</mi>", false, nil
+	}
+
+	if sym, found := uprightIdentifiers[name]; found {
+		return `<mi mathvariant="normal">` + sym + "</mi>", false, nil
+	}
+
+	if sym, found := operators[name]; found {
+		return mo(sym), false, nil
+	}
+
+	if sym, found := largeOperators[name]; found {
+		return "<mo>" + sym + "</mo>", p.display, nil
+	}
+
+	if sym, found := integrals[name]; found {
+		return "<mo>" + sym + "</mo>", false, nil
+	}
+
+	if limits, found := functions[name]; found {
+		return "<mi>" + name + "</mi>", limits && p.display, nil
+	}
+
+	if width, found := spaces[name]; found {
+		return `<mspace width="` + width + `"></mspace>`, false, nil
+	}
+
+	if mark, found := accents[name]; found {
+		arg, err := p.parseArg(`\` + name)
+		if err != nil {
+			return "", false, err
+		}
+
+		return `<mover accent="true">` + arg + mo(mark) + "</mover>", false, nil
+	}
+
+	if mark, found := underAccents[name]; found {
+		arg, err := p.parseArg(`\` + name)
+		if err != nil {
+			return "", false, err
+		}
+
+		return `<munder accentunder="true">` + arg + mo(mark) + "</munder>", false, nil
+	}
+
+	if size, found := delimiterSizes[name]; found {
+		delim, err := p.parseDelimiter(`\` + name)
+		if err != nil {
+			return "", false, err
+		}
+
+		return `<mo minsize="` + size + `" maxsize="` + size + `">` + delim + "</mo>", false, nil
+	}
+
+	if variant, found := fonts[name]; found {
+		outer := p.variant
+		p.variant = variant
+		defer func() { p.variant = outer }()
+
+		arg, err := p.parseArg(`\` + name)
+		return arg, false, err
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		num, err := p.parseArg(`\` + name)
+		if err != nil {
+			return "", false, err
+		}
+
+		den, err := p.parseArg(`\` + name)
+		if err != nil {
+			return "", false, err
+		}
+
+		return "<mfrac>" + num + den + "</mfrac>", false, nil
+
+	case "binom":
+		n, err := p.parseArg(`\binom`)
+		if err != nil {
+			return "", false, err
+		}
+
+		k, err := p.parseArg(`\binom`)
+		if err != nil {
+			return "", false, err
+		}
+
+		return `<mrow><mo fence="true" form="prefix">(</mo><mfrac linethickness="0">` + n + k + `</mfrac><mo fence="true" form="postfix">)</mo></mrow>`, false, nil
+
+	case "sqrt":
+		var index string
+		if p.peek() == "[" {
+			p.next()
+
+			row, err := p.parseRow(func(tok string) bool { return tok == "]" })
+			if err != nil {
+				return "", false, err
+			}
+
+			if p.next() != "]" {
+				return "", false, fmt.Errorf("missing ]")
+			}
+
+			index = mrow(row, false)
+		}
+
+		radicand, err := p.parseArg(`\sqrt`)
+		if err != nil {
+			return "", false, err
+		}
+
+		if index != "" {
+			return "<mroot>" + radicand + index + "</mroot>", false, nil
+		}
+
+		return "<msqrt>" + radicand + "</msqrt>", false, nil
+
+	case "text", "textrm", "mbox":
+		text, err := p.parseRaw(`\` + name)
+		if err != nil {
+			return "", false, err
+		}
+
+		return "<mtext>" + html.EscapeString(unescapeText(text)) + "</mtext>", false, nil
+
+	case "operatorname":
+		text, err := p.parseRaw(`\operatorname`)
+		if err != nil {
+			return "", false, err
+		}
+
+		text = strings.TrimSpace(unescapeText(text))
+		if len([]rune(text)) == 1 {
+			return `<mi mathvariant="normal">` + html.EscapeString(text) + "</mi>", false, nil
+		}
+
+		return "<mi>" + html.EscapeString(text) + "</mi>", false, nil
+
+	case "left":
+		return p.parseFenced()
+
+	case "right":
+		return "", false, fmt.Errorf(`unexpected \right`)
+
+	case "middle":
+		delim, err := p.parseDelimiter(`\middle`)
+		if err != nil {
+			return "", false, err
+		}
+
+		return `<mo fence="true" form="infix">` + delim + "</mo>", false, nil
+
+	case "begin":
+		return p.parseEnvironment()
+
+	case "end":
+		return "", false, fmt.Errorf(`unexpected \end`)
+	}
+
+	return "", false, fmt.Errorf(`unknown command: \%s`, name)
+}
+
+// parseDelimiter parses the delimiter following \left, \right, and the like,
+// returning its escaped symbol, or an empty string for '.'.
+func (p *parser) parseDelimiter(command string) (string, error) {
+	tok := p.next()
+
+	switch {
+	case tok == ".":
+		return "", nil
+	case strings.HasPrefix(tok, `\`):
+		if sym, found := operators[tok[1:]]; found {
+			return html.EscapeString(sym), nil
+		}
+	case tok != "" && strings.ContainsAny(tok, "()[]|/<>"):
+		switch tok {
+		case "<":
+			return "⟨", nil
+		case ">":
+			return "⟩", nil
+		default:
+			return html.EscapeString(tok), nil
+		}
+	}
+
+	return "", fmt.Errorf("missing delimiter for %s", command)
+}
+
+// parseFenced parses the content between \left and \right, the \left having
+// already been consumed.
+func (p *parser) parseFenced() (string, bool, error) {
+	open, err := p.parseDelimiter(`\left`)
+	if err != nil {
+		return "", false, err
+	}
+
+	row, err := p.parseRow(func(tok string) bool { return tok == `\right` })
+	if err != nil {
+		return "", false, err
+	}
+
+	if p.next() != `\right` {
+		return "", false, fmt.Errorf(`missing \right`)
+	}
+
+	closing, err := p.parseDelimiter(`\right`)
+	if err != nil {
+		return "", false, err
+	}
+
+	return fence(open, closing, mrow(row, false)), false, nil
+}
+
+// parseEnvironment parses the rows and columns of an environment, the
+// \begin having already been consumed.
+func (p *parser) parseEnvironment() (string, bool, error) {
+	name, err := p.parseRaw(`\begin`)
+	if err != nil {
+		return "", false, err
+	}
+
+	var open, closing, align string
+	switch name {
+	case "matrix", "smallmatrix":
+	case "pmatrix":
+		open, closing = "(", ")"
+	case "bmatrix":
+		open, closing = "[", "]"
+	case "Bmatrix":
+		open, closing = "{", "}"
+	case "vmatrix":
+		open, closing = "|", "|"
+	case "Vmatrix":
+		open, closing = "‖", "‖"
+	case "cases":
+		open = "{"
+		align = "left left"
+	case "aligned", "align", "align*", "split":
+		align = "right left"
+	case "gathered", "gather", "gather*":
+	case "array":
+		spec, err := p.parseRaw(`\begin{array}`)
+		if err != nil {
+			return "", false, err
+		}
+
+		columns := []string{}
+		for _, r := range spec {
+			switch r {
+			case 'l':
+				columns = append(columns, "left")
+			case 'c':
+				columns = append(columns, "center")
+			case 'r':
+				columns = append(columns, "right")
+			}
+		}
+
+		align = strings.Join(columns, " ")
+	default:
+		return "", false, fmt.Errorf("unknown environment: %s", name)
+	}
+
+	stop := func(tok string) bool {
+		return tok == "&" || tok == `\\` || tok == `\end`
+	}
+
+	rows := []string{}
+	cells := []string{}
+	for {
+		cell, err := p.parseRow(stop)
+		if err != nil {
+			return "", false, err
+		}
+
+		cells = append(cells, "<mtd>"+mrow(cell, false)+"</mtd>")
+
+		tok := p.next()
+		if tok == "&" {
+			continue
+		}
+
+		if tok == `\\` || len(cell) > 0 || len(cells) > 1 {
+			rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+		}
+
+		cells = []string{}
+
+		if tok == `\\` {
+			continue
+		}
+
+		if tok != `\end` {
+			return "", false, fmt.Errorf(`missing \end{%s}`, name)
+		}
+
+		end, err := p.parseRaw(`\end`)
+		if err != nil {
+			return "", false, err
+		}
+
+		if end != name {
+			return "", false, fmt.Errorf(`mismatched \end{%s} for \begin{%s}`, end, name)
+		}
+
+		break
+	}
+
+	attrs := ""
+	if align != "" {
+		attrs = ` columnalign="` + align + `"`
+	}
+
+	table := "<mtable" + attrs + ">" + strings.Join(rows, "") + "</mtable>"
+
+	if open == "" && closing == "" {
+		return table, false, nil
+	}
+
+	return fence(html.EscapeString(open), html.EscapeString(closing), table), false, nil
+}
+
+// identifier renders a letter, styled in the current variant.
+func (p *parser) identifier(r rune) string {
+	switch p.variant {
+	case "":
+		return "<mi>" + html.EscapeString(string(r)) + "</mi>"
+	case "normal":
+		return `<mi mathvariant="normal">` + html.EscapeString(string(r)) + "</mi>"
+	default:
+		return "<mi>" + string(styledRune(p.variant, r)) + "</mi>"
+	}
+}
+
+// number renders a number, styled in the current variant.
+func (p *parser) number(num string) string {
+	styled := []rune{}
+	for _, r := range num {
+		styled = append(styled, styledRune(p.variant, r))
+	}
+
+	return "<mn>" + string(styled) + "</mn>"
+}
+
+// fence wraps the content in stretchy delimiters, either of which may be
+// empty.
+func fence(open, closing, content string) string {
+	out := "<mrow>"
+	if open != "" {
+		out += `<mo fence="true" form="prefix">` + open + "</mo>"
+	}
+
+	out += content
+
+	if closing != "" {
+		out += `<mo fence="true" form="postfix">` + closing + "</mo>"
+	}
+
+	return out + "</mrow>"
+}
+
+// mrow groups the elements into a single element. A single element is left
+// as-is, unless always is set.
+func mrow(els []string, always bool) string {
+	if len(els) == 1 && !always {
+		return els[0]
+	}
+
+	if len(els) == 1 && strings.HasPrefix(els[0], "<mrow>") {
+		return els[0]
+	}
+
+	return "<mrow>" + strings.Join(els, "") + "</mrow>"
+}
+
+func mo(sym string) string {
+	return "<mo>" + html.EscapeString(sym) + "</mo>"
+}
+
+// unescapeText removes the backslashes from escaped characters within the
+// argument of a text command, e.g. \{.
+func unescapeText(text string) string {
+	out := []rune{}
+	escaped := false
+	for _, r := range text {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+
+		escaped = false
+		out = append(out, r)
+	}
+
+	return string(out)
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,393 @@
+package mathml
+
+// identifiers maps commands to the symbols they render as identifiers.
+var identifiers = map[string]string{
+	"alpha":      "α",
+	"beta":       "β",
+	"gamma":      "γ",
+	"delta":      "δ",
+	"epsilon":    "ϵ",
+	"varepsilon": "ε",
+	"zeta":       "ζ",
+	"eta":        "η",
+	"theta":      "θ",
+	"vartheta":   "ϑ",
+	"iota":       "ι",
+	"kappa":      "κ",
+	"lambda":     "λ",
+	"mu":         "μ",
+	"nu":         "ν",
+	"xi":         "ξ",
+	"omicron":    "ο",
+	"pi":         "π",
+	"varpi":      "ϖ",
+	"rho":        "ρ",
+	"varrho":     "ϱ",
+	"sigma":      "σ",
+	"varsigma":   "ς",
+	"tau":        "τ",
+	"upsilon":    "υ",
+	"phi":        "ϕ",
+	"varphi":     "φ",
+	"chi":        "χ",
+	"psi":        "ψ",
+	"omega":      "ω",
+
+	"infty":      "∞",
+	"partial":    "∂",
+	"nabla":      "∇",
+	"emptyset":   "∅",
+	"varnothing": "∅",
+	"hbar":       "ℏ",
+	"ell":        "ℓ",
+	"aleph":      "ℵ",
+	"Re":         "ℜ",
+	"Im":         "ℑ",
+	"wp":         "℘",
+}
+
+// uprightIdentifiers maps commands to the symbols they render as identifiers
+// which are upright rather than italic, like upper-case Greek letters.
+var uprightIdentifiers = map[string]string{
+	"Gamma":   "Γ",
+	"Delta":   "Δ",
+	"Theta":   "Θ",
+	"Lambda":  "Λ",
+	"Xi":      "Ξ",
+	"Pi":      "Π",
+	"Sigma":   "Σ",
+	"Upsilon": "Υ",
+	"Phi":     "Φ",
+	"Psi":     "Ψ",
+	"Omega":   "Ω",
+}
+
+// operators maps commands to the symbols they render as operators.
+var operators = map[string]string{
+	"times":    "×",
+	"cdot":     "⋅",
+	"pm":       "±",
+	"mp":       "∓",
+	"div":      "÷",
+	"ast":      "∗",
+	"star":     "⋆",
+	"circ":     "∘",
+	"bullet":   "∙",
+	"oplus":    "⊕",
+	"ominus":   "⊖",
+	"otimes":   "⊗",
+	"setminus": "∖",
+
+	"leq":    "≤",
+	"le":     "≤",
+	"geq":    "≥",
+	"ge":     "≥",
+	"neq":    "≠",
+	"ne":     "≠",
+	"ll":     "≪",
+	"gg":     "≫",
+	"approx": "≈",
+	"equiv":  "≡",
+	"sim":    "∼",
+	"simeq":  "≃",
+	"cong":   "≅",
+	"propto": "∝",
+	"prec":   "≺",
+	"succ":   "≻",
+
+	"in":        "∈",
+	"notin":     "∉",
+	"ni":        "∋",
+	"subset":    "⊂",
+	"subseteq":  "⊆",
+	"supset":    "⊃",
+	"supseteq":  "⊇",
+	"cup":       "∪",
+	"cap":       "∩",
+	"forall":    "∀",
+	"exists":    "∃",
+	"nexists":   "∄",
+	"neg":       "¬",
+	"lnot":      "¬",
+	"land":      "∧",
+	"wedge":     "∧",
+	"lor":       "∨",
+	"vee":       "∨",
+	"perp":      "⊥",
+	"parallel":  "∥",
+	"mid":       "∣",
+	"angle":     "∠",
+	"top":       "⊤",
+	"bot":       "⊥",
+	"vdash":     "⊢",
+	"models":    "⊨",
+	"therefore": "∴",
+	"because":   "∵",
+
+	"to":                "→",
+	"rightarrow":        "→",
+	"leftarrow":         "←",
+	"gets":              "←",
+	"leftrightarrow":    "↔",
+	"Rightarrow":        "⇒",
+	"Leftarrow":         "⇐",
+	"Leftrightarrow":    "⇔",
+	"implies":           "⟹",
+	"impliedby":         "⟸",
+	"iff":               "⟺",
+	"mapsto":            "↦",
+	"uparrow":           "↑",
+	"downarrow":         "↓",
+	"longrightarrow":    "⟶",
+	"longleftarrow":     "⟵",
+	"hookrightarrow":    "↪",
+	"rightleftharpoons": "⇌",
+
+	"ldots": "…",
+	"dots":  "…",
+	"cdots": "⋯",
+	"vdots": "⋮",
+	"ddots": "⋱",
+	"prime": "′",
+
+	"langle":    "⟨",
+	"rangle":    "⟩",
+	"lfloor":    "⌊",
+	"rfloor":    "⌋",
+	"lceil":     "⌈",
+	"rceil":     "⌉",
+	"vert":      "|",
+	"Vert":      "‖",
+	"lvert":     "|",
+	"rvert":     "|",
+	"lVert":     "‖",
+	"rVert":     "‖",
+	"backslash": "\\",
+	"{":         "{",
+	"}":         "}",
+	"|":         "‖",
+	"lbrace":    "{",
+	"rbrace":    "}",
+	"lbrack":    "[",
+	"rbrack":    "]",
+
+	"#": "#",
+	"$": "$",
+	"%": "%",
+	"&": "&",
+	"_": "_",
+}
+
+// largeOperators maps commands to the symbols of operators whose limits are
+// placed above and below them in display math.
+var largeOperators = map[string]string{
+	"sum":       "∑",
+	"prod":      "∏",
+	"coprod":    "∐",
+	"bigcup":    "⋃",
+	"bigcap":    "⋂",
+	"bigvee":    "⋁",
+	"bigwedge":  "⋀",
+	"bigoplus":  "⨁",
+	"bigotimes": "⨂",
+	"bigsqcup":  "⨆",
+}
+
+// integrals maps commands to the symbols of operators whose limits are
+// placed beside them, even in display math.
+var integrals = map[string]string{
+	"int":   "∫",
+	"iint":  "∬",
+	"iiint": "∭",
+	"oint":  "∮",
+}
+
+// functions are the commands which render as their name, e.g. \sin. Those set
+// to true have their limits placed below them in display math, like \lim.
+var functions = map[string]bool{
+	"sin":    false,
+	"cos":    false,
+	"tan":    false,
+	"cot":    false,
+	"sec":    false,
+	"csc":    false,
+	"arcsin": false,
+	"arccos": false,
+	"arctan": false,
+	"sinh":   false,
+	"cosh":   false,
+	"tanh":   false,
+	"coth":   false,
+	"log":    false,
+	"lg":     false,
+	"ln":     false,
+	"exp":    false,
+	"arg":    false,
+	"deg":    false,
+	"dim":    false,
+	"hom":    false,
+	"ker":    false,
+
+	"lim":    true,
+	"liminf": true,
+	"limsup": true,
+	"max":    true,
+	"min":    true,
+	"sup":    true,
+	"inf":    true,
+	"det":    true,
+	"gcd":    true,
+	"Pr":     true,
+}
+
+// spaces maps commands to the widths of the spaces they render.
+var spaces = map[string]string{
+	",":         "0.167em",
+	"thinspace": "0.167em",
+	":":         "0.222em",
+	">":         "0.222em",
+	";":         "0.278em",
+	"!":         "-0.167em",
+	" ":         "0.333em",
+	"quad":      "1em",
+	"qquad":     "2em",
+}
+
+// accents maps commands to the marks they place above their argument.
+var accents = map[string]string{
+	"hat":            "^",
+	"widehat":        "^",
+	"check":          "ˇ",
+	"tilde":          "~",
+	"widetilde":      "~",
+	"acute":          "´",
+	"grave":          "`",
+	"dot":            "˙",
+	"ddot":           "¨",
+	"breve":          "˘",
+	"bar":            "¯",
+	"vec":            "→",
+	"overline":       "‾",
+	"overrightarrow": "→",
+	"overleftarrow":  "←",
+	"overbrace":      "⏞",
+}
+
+// underAccents maps commands to the marks they place below their argument.
+var underAccents = map[string]string{
+	"underline":  "_",
+	"underbrace": "⏟",
+}
+
+// delimiterSizes maps the commands which enlarge delimiters to their sizes.
+var delimiterSizes = map[string]string{
+	"big":   "1.2em",
+	"bigl":  "1.2em",
+	"bigr":  "1.2em",
+	"bigm":  "1.2em",
+	"Big":   "1.8em",
+	"Bigl":  "1.8em",
+	"Bigr":  "1.8em",
+	"Bigm":  "1.8em",
+	"bigg":  "2.4em",
+	"biggl": "2.4em",
+	"biggr": "2.4em",
+	"biggm": "2.4em",
+	"Bigg":  "3em",
+	"Biggl": "3em",
+	"Biggr": "3em",
+	"Biggm": "3em",
+}
+
+// fonts maps the commands which style letters to their variants.
+var fonts = map[string]string{
+	"mathrm":     "normal",
+	"mathit":     "italic",
+	"mathbf":     "bold",
+	"boldsymbol": "bold",
+	"mathbb":     "double-struck",
+	"mathcal":    "script",
+	"mathscr":    "script",
+	"mathfrak":   "fraktur",
+	"mathsf":     "sans-serif",
+	"mathtt":     "monospace",
+}
+
+// alphanumerics describes where each variant's letters and digits are among
+// the mathematical alphanumeric symbols, along with the letters which are
+// elsewhere, having been encoded before them.
+var alphanumerics = map[string]struct {
+	upper, lower, digits rune
+	exceptions           map[rune]rune
+}{
+	"bold": {
+		upper:  0x1D400,
+		lower:  0x1D41A,
+		digits: 0x1D7CE,
+	},
+	"italic": {
+		upper: 0x1D434,
+		lower: 0x1D44E,
+		exceptions: map[rune]rune{
+			'h': 'ℎ',
+		},
+	},
+	"script": {
+		upper: 0x1D49C,
+		lower: 0x1D4B6,
+		exceptions: map[rune]rune{
+			'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ',
+			'M': 'ℳ', 'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ',
+		},
+	},
+	"fraktur": {
+		upper: 0x1D504,
+		lower: 0x1D51E,
+		exceptions: map[rune]rune{
+			'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ',
+		},
+	},
+	"double-struck": {
+		upper:  0x1D538,
+		lower:  0x1D552,
+		digits: 0x1D7D8,
+		exceptions: map[rune]rune{
+			'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ',
+			'Z': 'ℤ',
+		},
+	},
+	"sans-serif": {
+		upper:  0x1D5A0,
+		lower:  0x1D5BA,
+		digits: 0x1D7E2,
+	},
+	"monospace": {
+		upper:  0x1D670,
+		lower:  0x1D68A,
+		digits: 0x1D7F6,
+	},
+}
+
+// styledRune returns the letter or digit in the given variant, or the rune
+// itself if there isn't one.
+func styledRune(variant string, r rune) rune {
+	alpha, found := alphanumerics[variant]
+	if !found {
+		return r
+	}
+
+	if styled, found := alpha.exceptions[r]; found {
+		return styled
+	}
+
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return alpha.upper + (r - 'A')
+	case r >= 'a' && r <= 'z':
+		return alpha.lower + (r - 'a')
+	case r >= '0' && r <= '9' && alpha.digits != 0:
+		return alpha.digits + (r - '0')
+	default:
+		return r
+	}
+}
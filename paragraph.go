@@ -20,6 +20,16 @@ func (con Paragraph) String() string {
 	return str
 }
 
+// Lines returns the paragraph styled so that each of its lines is rendered on
+// a line of its own, rather than being joined, e.g. for poetry or addresses.
+func (con Paragraph) Lines() Styled {
+	return Styled{
+		Style:   StyleLines,
+		Block:   true,
+		Content: con,
+	}
+}
+
 func (con Paragraph) IsFlow() bool {
 	return false
 }
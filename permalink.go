@@ -0,0 +1,170 @@
+package booklit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Permalinks determines the path of each page beneath a section, relative to
+// the root of the book, from which the page's file name and URL are derived,
+// e.g. blog/2024/05/01/hello for blog/2024/05/01/hello.html.
+//
+// It is either the name of a strategy, e.g. PermalinksDate, or a pattern of
+// placeholders: {tag} for the page's tag, {parent} for the path of its parent
+// page, or {year}, {month}, and {day} for the date it was published.
+type Permalinks string
+
+const (
+	// pages are named by their tag alone; the default
+	PermalinksFlat Permalinks = "flat"
+
+	// pages are nested beneath the pages of their ancestors, like
+	// {parent}/{tag}
+	PermalinksNested Permalinks = "nested"
+
+	// pages are nested beneath their parent by the date they were published,
+	// like {parent}/{year}/{month}/{day}/{tag}
+	PermalinksDate Permalinks = "date"
+)
+
+var permalinkPatterns = map[Permalinks]string{
+	PermalinksFlat:   "{tag}",
+	PermalinksNested: "{parent}/{tag}",
+	PermalinksDate:   "{parent}/{year}/{month}/{day}/{tag}",
+}
+
+var permalinkPlaceholderRegexp = regexp.MustCompile(`\{[^}]*\}`)
+
+// ParsePermalinks parses the name of a strategy or a pattern, returning an
+// error if the name is unknown or the pattern refers to an unknown
+// placeholder.
+func ParsePermalinks(strategy string) (Permalinks, error) {
+	if _, found := permalinkPatterns[Permalinks(strategy)]; found {
+		return Permalinks(strategy), nil
+	}
+
+	placeholders := permalinkPlaceholderRegexp.FindAllString(strategy, -1)
+	if len(placeholders) == 0 {
+		return "", fmt.Errorf("invalid permalinks (expected flat, nested, date, or a pattern of placeholders such as {tag}): %s", strategy)
+	}
+
+	for _, placeholder := range placeholders {
+		switch placeholder {
+		case "{tag}", "{parent}", "{year}", "{month}", "{day}":
+		default:
+			return "", fmt.Errorf("invalid permalink placeholder: %s", placeholder)
+		}
+	}
+
+	return Permalinks(strategy), nil
+}
+
+// Pattern returns the pattern the permalinks are generated from.
+func (permalinks Permalinks) Pattern() string {
+	if pattern, found := permalinkPatterns[permalinks]; found {
+		return pattern
+	}
+
+	if permalinks == "" {
+		return permalinkPatterns[PermalinksFlat]
+	}
+
+	return string(permalinks)
+}
+
+// Dated reports whether the permalinks refer to the date each page was
+// published.
+func (permalinks Permalinks) Dated() bool {
+	pattern := permalinks.Pattern()
+
+	return strings.Contains(pattern, "{year}") ||
+		strings.Contains(pattern, "{month}") ||
+		strings.Contains(pattern, "{day}")
+}
+
+// InheritedPermalinks returns the permalinks of the section's page: those
+// configured by the nearest ancestor, either by setting Permalinks or by its
+// tag in the top-level section's PermalinksByTag, or PermalinksFlat if none
+// do.
+func (con *Section) InheritedPermalinks() Permalinks {
+	top := con.Top()
+
+	for ancestor := con.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor.Permalinks != "" {
+			return ancestor.Permalinks
+		}
+
+		if permalinks, found := top.PermalinksByTag[ancestor.PrimaryTag.Name]; found {
+			return permalinks
+		}
+	}
+
+	return PermalinksFlat
+}
+
+// Permalink returns the path of the section's page, if it's rendered on a
+// page of its own, relative to the root of the book and without a file
+// extension, e.g. blog/2024/05/01/hello.
+func (con *Section) Permalink() string {
+	return con.PermalinkAs(con.PrimaryTag.Name)
+}
+
+// PermalinkAs returns the path the section's page would have if its primary
+// tag were the given tag, e.g. one of its aliases.
+func (con *Section) PermalinkAs(tag string) string {
+	if con.Parent == nil {
+		return tag
+	}
+
+	var parent string
+	if con.Parent.Parent != nil {
+		// pages nest beneath their ancestors' pages, but not the book's own,
+		// which is at the root
+		parent = con.Parent.Permalink()
+	}
+
+	published := con.Published
+
+	link := permalinkPlaceholderRegexp.ReplaceAllStringFunc(con.InheritedPermalinks().Pattern(), func(placeholder string) string {
+		switch placeholder {
+		case "{tag}":
+			return tag
+		case "{parent}":
+			return parent
+		case "{year}":
+			return fmt.Sprintf("%04d", published.Year())
+		case "{month}":
+			return fmt.Sprintf("%02d", published.Month())
+		case "{day}":
+			return fmt.Sprintf("%02d", published.Day())
+		default:
+			return placeholder
+		}
+	})
+
+	segments := []string{}
+	for _, segment := range strings.Split(link, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// FindPermalink returns the page in the section, or beneath it, with the
+// given permalink, or nil if there is none.
+func (con *Section) FindPermalink(link string) *Section {
+	if (con.Parent == nil || con.Parent.SplitSections) && con.Permalink() == link {
+		return con
+	}
+
+	for _, child := range con.Children {
+		if page := child.FindPermalink(link); page != nil {
+			return page
+		}
+	}
+
+	return nil
+}
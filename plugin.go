@@ -1,18 +1,374 @@
 package booklit
 
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/vito/booklit/ast"
+)
+
+// Plugin provides functions to sections which use it, as methods which are
+// dynamically invoked.
+//
+// Each section which uses a plugin is given its own instance, constructed by
+// the plugin's factory, and an instance's methods are only ever invoked by
+// one goroutine at a time, so a plugin need not synchronize access to its own
+// fields. State shared between instances, e.g. package variables or values
+// captured by the factory, must be synchronized by the plugin.
+//
+// The exception is a method which doesn't take the section's context and is
+// still running once its deadline passes, i.e. because the build timed out.
+// Rather than waiting for it, the build gives up on the method and leaves it
+// to return in the background. None of the instance's other methods are
+// invoked after that, but the method may still be running as the next build
+// begins, so anything it shares with other instances must be synchronized all
+// the same. Methods which take the context are always waited on, and so must
+// return once it's done.
+//
+// Content returned by a plugin must not be modified afterwards, as pages may
+// be rendered concurrently (see render.Writer.Jobs). Likewise, plugins should
+// only modify sections while they are being evaluated; the Section methods
+// for doing so, e.g. SetPartial and AddDependency, are safe to call
+// concurrently.
 type Plugin interface {
 	// methods are dynamically invoked
 }
 
+// DynamicPlugin is a plugin whose functions are not known until it is
+// constructed, e.g. one implemented by another process. Its functions are
+// invoked with their evaluated arguments, as if they were variadic methods
+// taking booklit.Content.
+type DynamicPlugin interface {
+	Plugin
+
+	// Functions returns the names of the functions the plugin provides, as
+	// they are invoked from a document, e.g. "split-sections".
+	Functions() []string
+
+	// Invoke calls the named function, returning its content, if any.
+	Invoke(function string, args ...Content) (Content, error)
+}
+
+// PluginFactory constructs a plugin for the given section. Factories may be
+// called from any goroutine.
 type PluginFactory func(*Section) Plugin
 
+// PluginRequirements declares what a plugin needs in order to be used.
+type PluginRequirements struct {
+	// Names of plugins to use before this one.
+	Plugins []string
+
+	// Minimum version of Booklit, e.g. "0.10.0".
+	Version string
+
+	// Version of the plugin interface the plugin implements, e.g. "2.0" for
+	// plugins registered via the plugin/v2 package. Plugins which don't
+	// declare one implement version 1: Plugin's dynamically invoked methods.
+	API string
+}
+
+// PluginAPIVersion is the newest version of the plugin interface supported,
+// i.e. the version implemented by the plugin/v2 package. Plugins implementing
+// version 1 or any 2.x no newer than it may be used; version 1 is
+// deprecated.
+const PluginAPIVersion = "2.0"
+
 var plugins = map[string]PluginFactory{}
 
+var pluginRequirements = map[string]PluginRequirements{}
+
+var safePlugins = map[string]bool{}
+
+// guards plugins, pluginRequirements, and safePlugins
+var pluginsL sync.RWMutex
+
 func RegisterPlugin(name string, factory PluginFactory) {
+	pluginsL.Lock()
 	plugins[name] = factory
+	pluginsL.Unlock()
+}
+
+// RequirePlugin declares requirements for the named plugin, checked whenever
+// it is resolved via ResolvePlugin.
+func RequirePlugin(name string, requirements PluginRequirements) {
+	pluginsL.Lock()
+	pluginRequirements[name] = requirements
+	pluginsL.Unlock()
+}
+
+// DeclareSafePlugin declares that the named plugin neither runs commands nor
+// accesses the network, and checks any files it reads via Section.CheckFile,
+// so that it may be used in safe mode; see SafeMode.
+func DeclareSafePlugin(name string) {
+	pluginsL.Lock()
+	safePlugins[name] = true
+	pluginsL.Unlock()
 }
 
 func LookupPlugin(name string) (PluginFactory, bool) {
+	pluginsL.RLock()
 	plugin, found := plugins[name]
+	pluginsL.RUnlock()
 	return plugin, found
 }
+
+// PluginNames returns the names of every registered plugin, sorted.
+func PluginNames() []string {
+	pluginsL.RLock()
+	names := []string{}
+	for name := range plugins {
+		names = append(names, name)
+	}
+	pluginsL.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+// PluginFunctions returns the names of the functions provided by a plugin,
+// sorted, i.e. its methods which may be invoked from a document, with
+// \split-sections calling SplitSections.
+func PluginFunctions(plugin Plugin) []string {
+	names := []string{}
+
+	dynamic, isDynamic := plugin.(DynamicPlugin)
+	if isDynamic {
+		names = append(names, dynamic.Functions()...)
+	}
+
+	value := reflect.ValueOf(plugin)
+	for i := 0; i < value.NumMethod(); i++ {
+		method := value.Type().Method(i)
+		if !isInvokable(value.Method(i).Type()) {
+			continue
+		}
+
+		// provides the dynamic functions rather than being one
+		if isDynamic && method.Name == "Invoke" {
+			continue
+		}
+
+		name := functionName(method.Name)
+		if name == "" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+var (
+	stringReflectType  = reflect.TypeOf("")
+	contentReflectType = reflect.TypeOf((*Content)(nil)).Elem()
+	nodeReflectType    = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	errorReflectType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextReflectType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// isInvokable returns true if a method's arguments and return values are
+// supported by evaluation.
+func isInvokable(method reflect.Type) bool {
+	for i := 0; i < method.NumIn(); i++ {
+		arg := method.In(i)
+		if method.IsVariadic() && i == method.NumIn()-1 {
+			arg = arg.Elem()
+		}
+
+		// given the book's context rather than an argument
+		if i == 0 && method.In(0) == contextReflectType {
+			continue
+		}
+
+		if arg != stringReflectType && arg != contentReflectType && arg != nodeReflectType {
+			return false
+		}
+	}
+
+	switch method.NumOut() {
+	case 0:
+		return true
+	case 1:
+		return method.Out(0) == contentReflectType || method.Out(0) == errorReflectType
+	case 2:
+		return method.Out(0) == contentReflectType && method.Out(1) == errorReflectType
+	default:
+		return false
+	}
+}
+
+// functionName converts a method name to the name of the function which
+// invokes it, or returns "" if it cannot be invoked.
+func functionName(method string) string {
+	name := new(strings.Builder)
+	for i, r := range method {
+		if !unicode.IsLetter(r) || r > unicode.MaxASCII {
+			return ""
+		}
+
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				name.WriteRune('-')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		name.WriteRune(r)
+	}
+
+	return name.String()
+}
+
+// ResolvePlugin returns the factories for the named plugin and everything it
+// requires, in the order they should be used: dependencies first.
+func ResolvePlugin(name string) ([]PluginFactory, error) {
+	resolver := &pluginResolver{
+		visited: map[string]bool{},
+	}
+
+	err := resolver.resolve(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.factories, nil
+}
+
+// ResolveSafePlugin is like ResolvePlugin, but returns an error if the
+// plugin or anything it requires has not been declared safe via
+// DeclareSafePlugin.
+func ResolveSafePlugin(name string) ([]PluginFactory, error) {
+	resolver := &pluginResolver{
+		visited: map[string]bool{},
+		safe:    true,
+	}
+
+	err := resolver.resolve(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.factories, nil
+}
+
+type pluginResolver struct {
+	visited   map[string]bool
+	factories []PluginFactory
+
+	// only resolve plugins declared safe
+	safe bool
+}
+
+func (resolver *pluginResolver) resolve(name string, path []string) error {
+	for _, p := range path {
+		if p == name {
+			return fmt.Errorf("plugin dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+	}
+
+	if resolver.visited[name] {
+		return nil
+	}
+
+	factory, found := LookupPlugin(name)
+	if !found {
+		err := UnknownPluginError{Plugin: name}
+		if len(path) > 0 {
+			err.RequiredBy = path[len(path)-1]
+		}
+
+		return err
+	}
+
+	pluginsL.RLock()
+	requirements := pluginRequirements[name]
+	safe := safePlugins[name]
+	pluginsL.RUnlock()
+
+	if resolver.safe && !safe {
+		return fmt.Errorf("plugin '%s' is not allowed in safe mode", name)
+	}
+
+	if requirements.Version != "" && !versionSatisfies(Version, requirements.Version) {
+		return fmt.Errorf("plugin '%s' requires booklit %s or newer (running %s)", name, requirements.Version, Version)
+	}
+
+	if requirements.API != "" && !pluginAPISupported(requirements.API) {
+		return fmt.Errorf("plugin '%s' implements plugin API %s, which booklit %s does not support (supports 1 through %s)", name, requirements.API, Version, PluginAPIVersion)
+	}
+
+	for _, dep := range requirements.Plugins {
+		err := resolver.resolve(dep, append(path, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	resolver.visited[name] = true
+	resolver.factories = append(resolver.factories, factory)
+
+	return nil
+}
+
+// versionSatisfies reports whether the version is at least the minimum.
+// Builds without a version satisfy any minimum; prereleases, e.g.
+// 0.10.0-rc.1, are compared by their numbers alone.
+func versionSatisfies(version string, minimum string) bool {
+	if version == devVersion {
+		return true
+	}
+
+	have := parseVersion(version)
+	want := parseVersion(minimum)
+
+	for i := range want {
+		if have[i] != want[i] {
+			return have[i] > want[i]
+		}
+	}
+
+	return true
+}
+
+// pluginAPISupported reports whether plugins implementing the version of the
+// plugin interface can be used: version 1, or a 2.x no newer than
+// PluginAPIVersion, as minor versions only add to the interface.
+func pluginAPISupported(api string) bool {
+	have := parseVersion(PluginAPIVersion)
+	want := parseVersion(api)
+
+	switch want[0] {
+	case 1:
+		return true
+	case have[0]:
+		return want[1] <= have[1]
+	default:
+		return false
+	}
+}
+
+func parseVersion(version string) [3]int {
+	version = strings.TrimPrefix(version, "v")
+
+	if i := strings.IndexAny(version, "-+"); i != -1 {
+		version = version[:i]
+	}
+
+	var parsed [3]int
+	for i, seg := range strings.SplitN(version, ".", 3) {
+		parsed[i], _ = strconv.Atoi(seg)
+	}
+
+	return parsed
+}
@@ -0,0 +1,311 @@
+// Package plugin is version 2 of Booklit's plugin interface, which new
+// plugins should be written against.
+//
+// In version 1, a plugin is any value whose exported methods are invoked by
+// name, converting arguments and return values by reflection, so mistakes in
+// a method's signature are only found once it's invoked. In version 2, a
+// plugin lists its functions explicitly, each with the same signature, so that
+// both sides of the interface are checked by the compiler:
+//
+//	func init() {
+//		plugin.Register(plugin.Manifest{Name: "shout"}, NewPlugin)
+//	}
+//
+//	func NewPlugin(section *booklit.Section) plugin.Plugin {
+//		return Shout{}
+//	}
+//
+//	type Shout struct{}
+//
+//	func (Shout) Functions() map[string]plugin.Function {
+//		return map[string]plugin.Function{
+//			"shout": func(ctx context.Context, call plugin.Call) (booklit.Content, error) {
+//				return booklit.String(strings.ToUpper(call.Args[0].String())), nil
+//			},
+//		}
+//	}
+//
+// The version of the interface a plugin needs is declared in its Manifest and
+// checked when the plugin is used, so a plugin built for a newer Booklit fails
+// with an error naming the version rather than misbehaving.
+//
+// Version 2 only grows: minor versions, e.g. 2.1, add fields and functions to
+// this package without changing or removing anything, so plugins built
+// against 2.0 keep working with every 2.x release.
+//
+// Version 1 is deprecated, but remains supported throughout Booklit 0.x. It
+// won't be removed before 1.0, and its removal will be announced in the
+// release notes of at least two minor releases beforehand. In the meantime,
+// V1 wraps version 1 plugins so that they can be registered with a Manifest
+// before being rewritten.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+)
+
+// APIVersion is the version of the plugin interface implemented by this
+// package.
+const APIVersion = booklit.PluginAPIVersion
+
+// Plugin provides functions to the sections which use it.
+//
+// As in version 1, each section which uses a plugin is given its own
+// instance, whose functions are only ever invoked by one goroutine at a time,
+// except that a function which is still running once the build times out is
+// left to return in the background; see booklit.Plugin.
+type Plugin interface {
+	// Functions returns the plugin's functions by the name they're invoked
+	// with, e.g. "split-sections".
+	Functions() map[string]Function
+}
+
+// Factory constructs a plugin for the given section. Factories may be called
+// from any goroutine.
+type Factory func(*booklit.Section) Plugin
+
+// Function is invoked with its evaluated arguments, returning the content to
+// insert in its place, if any.
+//
+// The context is done once the build is cancelled; functions which run
+// commands or make requests should pass it along, e.g. to
+// exec.CommandContext.
+type Function func(ctx context.Context, call Call) (booklit.Content, error)
+
+// Call is an invocation of a function.
+type Call struct {
+	// Section the function was invoked in.
+	Section *booklit.Section
+
+	// Name the function was invoked with.
+	Function string
+
+	// Evaluated arguments, in order.
+	Args []booklit.Content
+}
+
+// Manifest describes a plugin, which is checked whenever it's used.
+type Manifest struct {
+	// Name which the plugin is used by, e.g. via \use-plugin.
+	Name string
+
+	// Version of the plugin interface the plugin needs, e.g. "2.1" if it
+	// relies on something added in 2.1. Defaults to "2.0".
+	API string
+
+	// Minimum version of Booklit, e.g. "0.10.0".
+	Booklit string
+
+	// Names of plugins to use before this one.
+	Requires []string
+
+	// Set if the plugin may be used in safe mode, declaring it via
+	// booklit.DeclareSafePlugin.
+	Safe bool
+}
+
+// Register registers the plugin under the manifest's name, declaring its
+// requirements.
+func Register(manifest Manifest, factory Factory) {
+	api := manifest.API
+	if api == "" {
+		api = "2.0"
+	}
+
+	booklit.RegisterPlugin(manifest.Name, Adapt(factory))
+
+	booklit.RequirePlugin(manifest.Name, booklit.PluginRequirements{
+		Plugins: manifest.Requires,
+		Version: manifest.Booklit,
+		API:     api,
+	})
+
+	if manifest.Safe {
+		booklit.DeclareSafePlugin(manifest.Name)
+	}
+}
+
+// Adapt converts the factory to a booklit.PluginFactory, e.g. for
+// constructing the plugin for a load.Processor without registering it.
+func Adapt(factory Factory) booklit.PluginFactory {
+	return func(section *booklit.Section) booklit.Plugin {
+		plugin := factory(section)
+
+		// invoked as it always has been, e.g. with unevaluated arguments
+		if v1, ok := plugin.(v1Plugin); ok {
+			return v1.plugin
+		}
+
+		return adapter{
+			section:   section,
+			functions: plugin.Functions(),
+		}
+	}
+}
+
+// adapter invokes a version 2 plugin's functions as a booklit.DynamicPlugin.
+type adapter struct {
+	section   *booklit.Section
+	functions map[string]Function
+}
+
+func (adapter adapter) Functions() []string {
+	names := []string{}
+	for name := range adapter.functions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (adapter adapter) Invoke(function string, args ...booklit.Content) (booklit.Content, error) {
+	fn, found := adapter.functions[function]
+	if !found {
+		return nil, fmt.Errorf("unknown function: %s", function)
+	}
+
+	return fn(adapter.section.Context(), Call{
+		Section:  adapter.section,
+		Function: function,
+		Args:     args,
+	})
+}
+
+// V1 wraps the factory of a plugin implementing version 1 of the interface,
+// so that it can be registered with a Manifest.
+//
+// The plugin's methods are invoked from documents as they always have been.
+// Its Functions are those of its methods which take only a context, strings,
+// and booklit.Content, e.g. for invoking them from Go.
+func V1(factory booklit.PluginFactory) Factory {
+	return func(section *booklit.Section) Plugin {
+		return v1Plugin{
+			plugin: factory(section),
+		}
+	}
+}
+
+type v1Plugin struct {
+	plugin booklit.Plugin
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	contentType = reflect.TypeOf((*booklit.Content)(nil)).Elem()
+	stringType  = reflect.TypeOf("")
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+func (plugin v1Plugin) Functions() map[string]Function {
+	functions := map[string]Function{}
+
+	dynamic, isDynamic := plugin.plugin.(booklit.DynamicPlugin)
+	if isDynamic {
+		for _, name := range dynamic.Functions() {
+			name := name
+			functions[name] = func(ctx context.Context, call Call) (booklit.Content, error) {
+				return dynamic.Invoke(name, call.Args...)
+			}
+		}
+	}
+
+	value := reflect.ValueOf(plugin.plugin)
+	for _, name := range booklit.PluginFunctions(plugin.plugin) {
+		if _, found := functions[name]; found {
+			continue
+		}
+
+		method := value.MethodByName(ast.Invoke{Function: name}.Method())
+		if !method.IsValid() || !takesEvaluated(method.Type()) {
+			continue
+		}
+
+		functions[name] = func(ctx context.Context, call Call) (booklit.Content, error) {
+			return invokeV1(ctx, method, call)
+		}
+	}
+
+	return functions
+}
+
+// takesEvaluated returns true if the method's arguments can be given
+// evaluated content.
+func takesEvaluated(method reflect.Type) bool {
+	for i := 0; i < method.NumIn(); i++ {
+		arg := method.In(i)
+		if method.IsVariadic() && i == method.NumIn()-1 {
+			arg = arg.Elem()
+		}
+
+		if i == 0 && arg == contextType {
+			continue
+		}
+
+		if arg != stringType && arg != contentType {
+			return false
+		}
+	}
+
+	return true
+}
+
+// invokeV1 invokes a version 1 plugin's method, converting the arguments and
+// return values as evaluation does.
+func invokeV1(ctx context.Context, method reflect.Value, call Call) (booklit.Content, error) {
+	methodType := method.Type()
+
+	argv := []reflect.Value{}
+	if methodType.NumIn() > 0 && methodType.In(0) == contextType {
+		argv = append(argv, reflect.ValueOf(ctx))
+	}
+
+	offset := len(argv)
+
+	argc := methodType.NumIn() - offset
+	if methodType.IsVariadic() {
+		argc--
+
+		if len(call.Args) < argc {
+			return nil, fmt.Errorf("argument count mismatch for %s: given %d, need at least %d", call.Function, len(call.Args), argc)
+		}
+	} else if len(call.Args) != argc {
+		return nil, fmt.Errorf("argument count mismatch for %s: given %d, need %d", call.Function, len(call.Args), argc)
+	}
+
+	args := reflect.ValueOf(call.Args)
+	for i, arg := range call.Args {
+		var argType reflect.Type
+		if i < argc {
+			argType = methodType.In(offset + i)
+		} else {
+			argType = methodType.In(offset + argc).Elem()
+		}
+
+		if argType == stringType {
+			argv = append(argv, reflect.ValueOf(arg.String()))
+		} else {
+			argv = append(argv, args.Index(i))
+		}
+	}
+
+	var content booklit.Content
+	var err error
+	for i, result := range method.Call(argv) {
+		switch methodType.Out(i) {
+		case contentType:
+			content, _ = result.Interface().(booklit.Content)
+		case errorType:
+			err, _ = result.Interface().(error)
+		}
+	}
+
+	return content, err
+}
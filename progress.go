@@ -0,0 +1,36 @@
+package booklit
+
+import "time"
+
+// Stage is a stage of a build, which Progress is notified of.
+type Stage string
+
+const (
+	// Each file is parsed and evaluated into a section.
+	StageParse Stage = "parse"
+
+	// References in each section are resolved.
+	StageResolve Stage = "resolve"
+
+	// Each page is rendered.
+	StageRender Stage = "render"
+)
+
+// Progress is notified as a build progresses, e.g. to show a progress bar or
+// log how long each stage took.
+//
+// A stage may run more than once in a build, e.g. once per book, and its
+// sections may be reported from several goroutines at once, so
+// implementations must be safe for concurrent use.
+type Progress interface {
+	// StartStage is called as the stage starts, with the number of sections
+	// it will go through, or 0 if it isn't known up front, e.g. for parsing.
+	StartStage(stage Stage, total int)
+
+	// Advance is called once the stage is done with each section.
+	Advance(stage Stage, section *Section)
+
+	// FinishStage is called once the stage is done, with how long it took.
+	// It isn't called if the stage fails.
+	FinishStage(stage Stage, elapsed time.Duration)
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// UnusedAssets returns the files in each of the directories, e.g. of images
+// and stylesheets, which are never referred to by the section's pages, by
+// any of the other files, e.g. a font referred to by a stylesheet, or by any
+// plugin which read them; see booklit.Section.AddDependency. The pages must
+// have been written first.
+//
+// Files in a directory within the destination are referred to by their path
+// relative to the destination, e.g. css/booklit.css. Files in any other
+// directory are referred to by their path relative to it, as if they were
+// copied into the destination. Hidden files are skipped.
+func (writer Writer) UnusedAssets(section *booklit.Section, dirs ...string) ([]string, error) {
+	type asset struct {
+		file string
+		refs []string
+		text []byte
+	}
+
+	assets := []asset{}
+	for _, dir := range dirs {
+		base := dir
+		if rel, err := filepath.Rel(writer.Destination, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			base = writer.Destination
+		}
+
+		err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if file != dir && strings.HasPrefix(info.Name(), ".") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(base, file)
+			if err != nil {
+				return err
+			}
+
+			ref := filepath.ToSlash(rel)
+
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			found := asset{
+				file: file,
+				refs: []string{ref, (&url.URL{Path: ref}).EscapedPath()},
+			}
+
+			// only search text files, e.g. stylesheets, for references
+			if strings.HasPrefix(http.DetectContentType(content), "text/") {
+				found.text = content
+			}
+
+			assets = append(assets, found)
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pages := [][]byte{}
+	read := map[string]bool{}
+
+	var walk func(*booklit.Section) error
+	walk = func(sub *booklit.Section) error {
+		if writesPage(sub) {
+			content, err := ioutil.ReadFile(filepath.Join(writer.Destination, writer.pagePath(sub)))
+			if err != nil {
+				return err
+			}
+
+			pages = append(pages, content)
+		}
+
+		for _, dep := range sub.Dependencies {
+			abs, err := filepath.Abs(dep)
+			if err != nil {
+				return err
+			}
+
+			read[abs] = true
+		}
+
+		for _, child := range sub.Children {
+			err := walk(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := walk(section)
+	if err != nil {
+		return nil, err
+	}
+
+	unused := []string{}
+	for _, asset := range assets {
+		abs, err := filepath.Abs(asset.file)
+		if err != nil {
+			return nil, err
+		}
+
+		if read[abs] {
+			continue
+		}
+
+		used := referenced(pages, asset.refs)
+
+		for _, other := range assets {
+			if used || other.file == asset.file || other.text == nil {
+				continue
+			}
+
+			refs := asset.refs
+
+			// e.g. a stylesheet referring to an image beside it
+			if rel, err := filepath.Rel(filepath.Dir(other.file), asset.file); err == nil {
+				refs = append([]string{filepath.ToSlash(rel)}, refs...)
+			}
+
+			used = referenced([][]byte{other.text}, refs)
+		}
+
+		if !used {
+			unused = append(unused, asset.file)
+		}
+	}
+
+	sort.Strings(unused)
+
+	return unused, nil
+}
+
+func referenced(sources [][]byte, refs []string) bool {
+	for _, source := range sources {
+		for _, ref := range refs {
+			if bytes.Contains(source, []byte(ref)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,275 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vito/booklit"
+)
+
+// BuildCache records a digest of everything each page was rendered from, so
+// that pages whose inputs have not changed can be skipped when building
+// again.
+//
+// A page's digest covers the files of every section rendered on it along
+// with their dependencies (see booklit.Section.AddDependency), and the
+// titles, tags, numbers, and URLs of every section in the book, since pages
+// typically link to and navigate between the others.
+type BuildCache struct {
+	// Digest of everything which affects every page, e.g. templates and
+	// flags. Pages recorded under a different key are always re-rendered.
+	Key string `json:"key"`
+
+	// Digest of each page, by path.
+	Pages map[string]string `json:"pages"`
+
+	pagesL sync.Mutex
+
+	files fileHashes
+}
+
+// fileHashes remembers the digest of each file hashed, along with when it
+// was modified, so that unchanged files need not be read again.
+type fileHashes struct {
+	hashes  map[string]fileHash
+	hashesL sync.Mutex
+}
+
+type fileHash struct {
+	ModTime time.Time
+	Size    int64
+	Sum     string
+}
+
+// NewBuildCache constructs an empty cache with the given key.
+func NewBuildCache(key string) *BuildCache {
+	return &BuildCache{
+		Key:   key,
+		Pages: map[string]string{},
+	}
+}
+
+// LoadBuildCache reads a cache previously written by Save. If the file does
+// not exist or was saved with a different key, an empty cache is returned.
+func LoadBuildCache(path string, key string) (*BuildCache, error) {
+	cache := NewBuildCache(key)
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var saved BuildCache
+	err = json.NewDecoder(file).Decode(&saved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid build cache: %s", err)
+	}
+
+	if saved.Key == key && saved.Pages != nil {
+		cache.Pages = saved.Pages
+	}
+
+	return cache, nil
+}
+
+// Save writes the cache to the given path, creating its directory if
+// needed.
+func (cache *BuildCache) Save(path string) error {
+	cache.pagesL.Lock()
+	defer cache.pagesL.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(file).Encode(cache)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// fresh returns true if the page was last rendered from the same inputs
+// and is still present.
+func (cache *BuildCache) fresh(path string, digest string) bool {
+	cache.pagesL.Lock()
+	recorded, found := cache.Pages[path]
+	cache.pagesL.Unlock()
+
+	if !found || recorded != digest {
+		return false
+	}
+
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (cache *BuildCache) record(path string, digest string) {
+	cache.pagesL.Lock()
+	cache.Pages[path] = digest
+	cache.pagesL.Unlock()
+}
+
+// pageDigest computes the digest of everything the page for the section is
+// rendered from.
+func (cache *BuildCache) pageDigest(writer Writer, section *booklit.Section) (string, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "key=%s\n", cache.Key)
+	fmt.Fprintf(h, "version=%s\n", booklit.Version)
+	fmt.Fprintf(h, "engine=%T\n", writer.Engine)
+	fmt.Fprintf(h, "structure=%s\n", writer.structure)
+	fmt.Fprintf(h, "section=%s\n", section.PrimaryTag.Name)
+
+	// pages list the upcoming events, which change over time
+	for _, event := range section.Top().UpcomingEvents(writer.now()) {
+		fmt.Fprintf(h, "upcoming=%s\n", event.TagName)
+	}
+
+	seen := map[string]bool{}
+	for _, sub := range pageSections(writer, section) {
+		files := append([]string{sub.FilePath()}, sub.Dependencies...)
+
+		for _, file := range files {
+			if file == "" || seen[file] {
+				continue
+			}
+
+			seen[file] = true
+
+			sum, err := cache.files.hash(file)
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(h, "file=%s:%s\n", file, sum)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hash returns a digest of the file's content, reusing the last one computed
+// if the file has not been modified since.
+func (cache *fileHashes) hash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	cache.hashesL.Lock()
+	if cache.hashes == nil {
+		cache.hashes = map[string]fileHash{}
+	}
+	hashed, found := cache.hashes[path]
+	cache.hashesL.Unlock()
+
+	if found && hashed.ModTime.Equal(info.ModTime()) && hashed.Size == info.Size() {
+		return hashed.Sum, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	cache.hashesL.Lock()
+	cache.hashes[path] = fileHash{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Sum:     sum,
+	}
+	cache.hashesL.Unlock()
+
+	return sum, nil
+}
+
+// pageSections returns the section along with every section rendered on its
+// page, i.e. those children which do not have pages of their own.
+func pageSections(writer Writer, section *booklit.Section) []*booklit.Section {
+	_, document := writer.Engine.(DocumentRenderingEngine)
+
+	sections := []*booklit.Section{section}
+	for _, child := range section.Children {
+		if !document && writesPage(child) {
+			continue
+		}
+
+		sections = append(sections, pageSections(writer, child)...)
+	}
+
+	return sections
+}
+
+// structureDigest computes a digest of the titles, tags, numbers, and URLs
+// of every section in the book, which any page may render, e.g. in its
+// navigation.
+func structureDigest(engine RenderingEngine, top *booklit.Section) string {
+	h := sha256.New()
+	writeStructure(h, engine, top)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeStructure(h hash.Hash, engine RenderingEngine, section *booklit.Section) {
+	fmt.Fprintf(h, "section=%s:%s:%s:%t:%t:%t:%d\n",
+		section.PrimaryTag.Name,
+		section.Number(),
+		section.Style,
+		section.SplitSections,
+		section.OmitChildrenFromTableOfContents,
+		section.HideFromTableOfContents,
+		section.TableOfContentsDepth,
+	)
+
+	// footnotes are numbered across every section collected together, so
+	// adding one renumbers those following it on other pages or chapters
+	fmt.Fprintf(h, "footnotes=%d:%t\n", len(section.Footnotes), section.CollectEndnotes)
+
+	for _, tag := range section.Tags {
+		fmt.Fprintf(h, "tag=%s:%s:%q:%s\n", tag.Name, tag.Anchor, tag.Title.String(), engine.URL(tag))
+	}
+
+	fmt.Fprintf(h, "title=%q\n", section.Title.String())
+
+	for _, event := range section.Events {
+		fmt.Fprintf(h, "event=%s:%s:%t:%q\n", event.TagName, event.Date.Format(time.RFC3339), event.AllDay, event.Title.String())
+	}
+
+	for _, child := range section.Children {
+		writeStructure(h, engine, child)
+	}
+
+	fmt.Fprintln(h, "end")
+}
@@ -0,0 +1,130 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// eventList lists the events by date, linking to each of them.
+func eventList(events []*booklit.Event) booklit.Content {
+	list := booklit.List{}
+
+	for _, event := range events {
+		tag := event.Tag()
+
+		list.Items = append(list.Items, booklit.Sequence{
+			booklit.Styled{
+				Style:   booklit.StyleDate,
+				Content: booklit.String(event.Date.Format(event.DateLayout())),
+				Partials: booklit.Partials{
+					"Datetime": booklit.String(event.Date.Format(time.RFC3339)),
+				},
+			},
+			booklit.String(": "),
+			&booklit.Reference{
+				TagName: tag.Name,
+				Tag:     &tag,
+			},
+		})
+	}
+
+	return list
+}
+
+// WriteCalendar writes every event in the book to path as an iCalendar
+// feed, so that readers can subscribe to them.
+//
+// Events are linked to by their URL if the book is hosted under an absolute
+// URL, e.g. with a BasePath of https://example.com/docs/.
+func (writer Writer) WriteCalendar(section *booklit.Section, path string) error {
+	logrus.WithFields(logrus.Fields{
+		"path": path,
+	}).Infoln("writing calendar")
+
+	top := section.Top()
+
+	buf := new(bytes.Buffer)
+
+	writeCalendarLine(buf, "BEGIN:VCALENDAR")
+	writeCalendarLine(buf, "VERSION:2.0")
+	writeCalendarLine(buf, "PRODID:-//Booklit//Booklit "+booklit.Version+"//EN")
+	writeCalendarLine(buf, "CALSCALE:GREGORIAN")
+	writeCalendarLine(buf, "X-WR-CALNAME:"+calendarText(top.Title.String()))
+
+	stamp := writer.now().UTC().Format(calendarTimeLayout)
+
+	for _, event := range top.AllEvents() {
+		writeCalendarLine(buf, "BEGIN:VEVENT")
+		writeCalendarLine(buf, "UID:"+event.TagName+"@"+top.PrimaryTag.Name)
+		writeCalendarLine(buf, "DTSTAMP:"+stamp)
+
+		if event.AllDay {
+			writeCalendarLine(buf, "DTSTART;VALUE=DATE:"+event.Date.Format(calendarDateLayout))
+			writeCalendarLine(buf, "DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format(calendarDateLayout))
+		} else {
+			writeCalendarLine(buf, "DTSTART:"+event.Date.UTC().Format(calendarTimeLayout))
+		}
+
+		writeCalendarLine(buf, "SUMMARY:"+calendarText(event.Title.String()))
+
+		if event.Description != nil {
+			writeCalendarLine(buf, "DESCRIPTION:"+calendarText(event.Description.String()))
+		}
+
+		if url := writer.Engine.URL(event.Tag()); strings.Contains(url, "://") {
+			writeCalendarLine(buf, "URL:"+url)
+		}
+
+		writeCalendarLine(buf, "END:VEVENT")
+	}
+
+	writeCalendarLine(buf, "END:VCALENDAR")
+
+	return writer.writeFile(path, buf.Bytes())
+}
+
+const (
+	calendarDateLayout = "20060102"
+	calendarTimeLayout = "20060102T150405Z"
+)
+
+// calendarLineLength is the maximum length of a line in octets, beyond which
+// lines are folded onto the next.
+const calendarLineLength = 75
+
+// writeCalendarLine writes a content line, folding it onto continuation
+// lines beginning with a space if it is too long.
+func writeCalendarLine(buf *bytes.Buffer, line string) {
+	for len(line) > calendarLineLength {
+		// don't split a multi-byte character
+		cut := calendarLineLength
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		fmt.Fprintf(buf, "%s\r\n", line[:cut])
+
+		// continuation lines begin with a space, which counts towards their
+		// length
+		line = " " + line[cut:]
+	}
+
+	fmt.Fprintf(buf, "%s\r\n", line)
+}
+
+// calendarText escapes text for a property value.
+func calendarText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(strings.TrimSpace(text))
+}
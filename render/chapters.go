@@ -0,0 +1,207 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// ChapterCache saves what each chapter of a document was rendered to, by a
+// digest of everything it was rendered from, so that engines which render
+// the whole book at once, e.g. EPUB and LaTeX, need only render the chapters
+// which changed since the last build and reassemble the rest.
+//
+// As with BuildCache, a chapter's digest covers the files of every section
+// in it along with their dependencies, and the titles, tags, numbers, and
+// URLs of every section in the book.
+type ChapterCache struct {
+	// Directory the rendered chapters are saved in, one file per digest.
+	Directory string
+
+	// Digest of everything which affects every chapter, e.g. templates and
+	// flags. Chapters rendered under a different key are rendered again.
+	Key string
+
+	// digests of the chapters fetched or stored since the cache was
+	// constructed, which Prune keeps
+	used  map[string]bool
+	usedL sync.Mutex
+
+	files fileHashes
+}
+
+// chapterArtifact is what a chapter was rendered to, along with anything
+// else its engine recorded while rendering it.
+type chapterArtifact struct {
+	Content []byte `json:"content"`
+
+	// relative image paths referenced by the chapter, to embed in an EPUB
+	Images []string `json:"images,omitempty"`
+}
+
+// NewChapterCache constructs a cache which saves chapters in the given
+// directory, under the given key.
+func NewChapterCache(dir string, key string) *ChapterCache {
+	return &ChapterCache{
+		Directory: dir,
+		Key:       key,
+
+		used: map[string]bool{},
+	}
+}
+
+// Prune removes the chapters which were neither fetched nor stored since the
+// cache was constructed, e.g. those which have since changed. If none were,
+// e.g. because the document was unchanged and not rendered at all, nothing
+// is removed.
+func (cache *ChapterCache) Prune() error {
+	cache.usedL.Lock()
+	used := len(cache.used)
+	cache.usedL.Unlock()
+
+	if used == 0 {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(cache.Directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	cache.usedL.Lock()
+	defer cache.usedL.Unlock()
+
+	for _, file := range files {
+		digest := strings.TrimSuffix(file.Name(), ".json")
+		if cache.used[digest] {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(cache.Directory, file.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// digest computes the digest of everything the chapter made up of the given
+// sections is rendered from, given the digest of the book's structure.
+func (cache *ChapterCache) digest(engine RenderingEngine, structure string, chapter *booklit.Section, sections []*booklit.Section) (string, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "key=%s\n", cache.Key)
+	fmt.Fprintf(h, "version=%s\n", booklit.Version)
+	fmt.Fprintf(h, "engine=%T\n", engine)
+	fmt.Fprintf(h, "structure=%s\n", structure)
+	fmt.Fprintf(h, "chapter=%s\n", chapter.PrimaryTag.Name)
+
+	seen := map[string]bool{}
+	for _, sub := range sections {
+		files := append([]string{sub.FilePath()}, sub.Dependencies...)
+
+		for _, file := range files {
+			if file == "" || seen[file] {
+				continue
+			}
+
+			seen[file] = true
+
+			sum, err := cache.files.hash(file)
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(h, "file=%s:%s\n", file, sum)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fetch returns what the chapter with the digest was last rendered to, if
+// it was saved.
+func (cache *ChapterCache) fetch(digest string) (chapterArtifact, bool, error) {
+	var artifact chapterArtifact
+
+	content, err := ioutil.ReadFile(cache.path(digest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return artifact, false, nil
+		}
+
+		return artifact, false, err
+	}
+
+	err = json.Unmarshal(content, &artifact)
+	if err != nil {
+		return artifact, false, fmt.Errorf("invalid chapter cache: %s", err)
+	}
+
+	cache.use(digest)
+
+	logrus.WithFields(logrus.Fields{
+		"digest": digest,
+	}).Debug("reusing rendered chapter")
+
+	return artifact, true, nil
+}
+
+// store saves what the chapter with the digest was rendered to.
+func (cache *ChapterCache) store(digest string, artifact chapterArtifact) error {
+	err := os.MkdirAll(cache.Directory, 0755)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(artifact)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(cache.path(digest), content, 0644)
+	if err != nil {
+		return err
+	}
+
+	cache.use(digest)
+
+	return nil
+}
+
+func (cache *ChapterCache) use(digest string) {
+	cache.usedL.Lock()
+	if cache.used == nil {
+		cache.used = map[string]bool{}
+	}
+	cache.used[digest] = true
+	cache.usedL.Unlock()
+}
+
+func (cache *ChapterCache) path(digest string) string {
+	return filepath.Join(cache.Directory, digest+".json")
+}
+
+// chapterSections returns the chapter along with every section within it.
+func chapterSections(chapter *booklit.Section) []*booklit.Section {
+	sections := []*booklit.Section{chapter}
+	for _, child := range chapter.Children {
+		sections = append(sections, chapterSections(child)...)
+	}
+
+	return sections
+}
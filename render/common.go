@@ -1,13 +1,26 @@
 package render
 
-import "github.com/vito/booklit"
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// tmplSource is a template loaded from a file by an engine.
+type tmplSource struct {
+	Name    string
+	Content string
+}
 
 type WalkContext struct {
 	Current *booklit.Section
 	Section *booklit.Section
 }
 
-func sectionPageOwner(section *booklit.Section) *booklit.Section {
+// PageOwner returns the section whose page the given section is rendered on.
+func PageOwner(section *booklit.Section) *booklit.Section {
 	if section.Parent == nil {
 		return section
 	}
@@ -16,25 +29,134 @@ func sectionPageOwner(section *booklit.Section) *booklit.Section {
 		return section
 	}
 
-	return sectionPageOwner(section.Parent)
+	return PageOwner(section.Parent)
 }
 
-func sectionURL(ext string, section *booklit.Section, anchor string) string {
-	owner := sectionPageOwner(section)
+// SectionURL returns the URL of the section, or of the anchor within it, for
+// an engine whose pages have the given file extension.
+func SectionURL(ext string, section *booklit.Section, anchor string) string {
+	owner := PageOwner(section)
 
 	if owner != section {
 		if anchor == "" {
 			anchor = section.PrimaryTag.Name
 		}
 
-		return sectionURL(ext, owner, anchor)
+		return SectionURL(ext, owner, anchor)
+	}
+
+	return pageURL(ext, section.Top(), section.Permalink(), anchor)
+}
+
+// LinkURL returns the URL of the tag as linked to from the page of the given
+// section. Tags in another book are linked to through that book's URLPrefix,
+// if it has one; otherwise this is the same as SectionURL.
+func LinkURL(ext string, from *booklit.Section, tag booklit.Tag) string {
+	url := SectionURL(ext, tag.Section, tag.Anchor)
+
+	top := tag.Section.Top()
+	if from == nil || from.Top() == top || top.URLPrefix == "" {
+		return url
 	}
 
-	filename := section.PrimaryTag.Name + "." + ext
+	return top.URLPrefix + strings.TrimPrefix(url, basePath(top))
+}
+
+// pageURL returns the URL of the page in the book with the given permalink,
+// or of the anchor within it.
+func pageURL(ext string, top *booklit.Section, name string, anchor string) string {
+	prefix := basePath(top)
+
+	var url string
+	switch top.URLStyle {
+	case booklit.URLStyleDirectories:
+		if name == indexTag {
+			url = prefix
+		} else {
+			url = prefix + name + "/"
+		}
+	case booklit.URLStyleExtensionless:
+		url = prefix + name
+	default:
+		url = prefix + name + "." + ext
+	}
 
 	if anchor != "" {
-		filename += "#" + anchor
+		url += "#" + anchor
+	}
+
+	return url
+}
+
+// indexTag is the tag of the page served at the root of the site when using
+// booklit.URLStyleDirectories.
+const indexTag = "index"
+
+// PagePath returns the path of the file to which the section's page is
+// written, relative to the destination, for an engine whose pages have the
+// given file extension.
+func PagePath(ext string, section *booklit.Section) string {
+	name := section.Permalink()
+
+	if section.Top().URLStyle == booklit.URLStyleDirectories && name != indexTag {
+		return path.Join(name, "index."+ext)
 	}
 
-	return filename
+	return name + "." + ext
+}
+
+// FragmentContent returns the content rendered for the tag as a fragment:
+// the whole section for a section's tag, as it would appear within its page,
+// or the content of a target or figure otherwise.
+func FragmentContent(tag booklit.Tag) (booklit.Content, error) {
+	if tag.Anchor == "" {
+		return tag.Section, nil
+	}
+
+	if tag.Content == nil {
+		return nil, fmt.Errorf("tag '%s' has no content to render", tag.Name)
+	}
+
+	return tag.Content, nil
+}
+
+// AssetURL returns the URL to use for a file in the destination, e.g. a
+// stylesheet, from the section's page, with the book's link rewrites applied.
+func AssetURL(section *booklit.Section, path string) string {
+	return section.RewriteURL(basePath(section.Top()) + strings.TrimPrefix(path, "/"))
+}
+
+// basePath returns the path which pages and assets are linked to relative
+// to, which is empty for relative links.
+func basePath(top *booklit.Section) string {
+	if top.BasePath != "" {
+		return top.BasePath
+	}
+
+	if top.URLStyle == booklit.URLStyleDirectories || top.NestedPermalinks {
+		// pages are in sub-directories, so links must be absolute
+		return "/"
+	}
+
+	return ""
+}
+
+// urlPagePath returns the path of the file which the page URL, without an
+// anchor, refers to for the top-level section; the inverse of SectionURL.
+func urlPagePath(top *booklit.Section, ext string, url string) string {
+	url = strings.TrimPrefix(url, basePath(top))
+
+	switch top.URLStyle {
+	case booklit.URLStyleDirectories:
+		url = strings.Trim(url, "/")
+		if url == "" {
+			return "index." + ext
+		}
+
+		return path.Join(url, "index."+ext)
+	case booklit.URLStyleExtensionless:
+		return url + "." + ext
+	default:
+		return url
+	}
 }
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"html"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/confluence"
+)
+
+// NewConfluenceRenderingEngine constructs an engine which renders pages in
+// Confluence's storage format. It is based on the HTML engine, overriding
+// the templates for links and anchors so that they refer to Confluence pages
+// by title.
+func NewConfluenceRenderingEngine() *HTMLRenderingEngine {
+	base := template.Must(initHTMLTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"anchor":    confluenceAnchor,
+		"pageLink":  confluencePageLink,
+		"codeBlock": confluenceCodeBlock,
+	})
+
+	for _, asset := range confluence.AssetNames() {
+		info, err := confluence.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(confluence.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine := &HTMLRenderingEngine{
+		name:          "confluence",
+		fileExtension: "xml",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return engine
+}
+
+// ConfluencePageTitle returns the title of the Confluence page the tag is
+// rendered on.
+func ConfluencePageTitle(tag booklit.Tag) string {
+	return PageOwner(tag.Section).Title.String()
+}
+
+func confluenceAnchor(name string) template.HTML {
+	return template.HTML(`<ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">` + html.EscapeString(name) + `</ac:parameter></ac:structured-macro>`)
+}
+
+func confluencePageLink(tag booklit.Tag, body template.HTML) template.HTML {
+	link := "<ac:link"
+
+	url := SectionURL("xml", tag.Section, tag.Anchor)
+	if i := strings.Index(url, "#"); i != -1 {
+		link += ` ac:anchor="` + html.EscapeString(url[i+1:]) + `"`
+	}
+
+	link += `><ri:page ri:content-title="` + html.EscapeString(ConfluencePageTitle(tag)) + `" />`
+	link += `<ac:link-body>` + string(body) + `</ac:link-body></ac:link>`
+
+	return template.HTML(link)
+}
+
+func confluenceCodeBlock(content booklit.Content) template.HTML {
+	code := strings.Replace(content.String(), "]]>", "]]]]><![CDATA[>", -1)
+	return template.HTML(`<ac:structured-macro ac:name="code"><ac:plain-text-body><![CDATA[` + code + `]]></ac:plain-text-body></ac:structured-macro>`)
+}
@@ -0,0 +1,203 @@
+package render
+
+import (
+	"reflect"
+
+	"github.com/vito/booklit"
+)
+
+// RenderContext is the context in which content is rendered: the content it
+// is within, and the content alongside it.
+//
+// It is given as . to context templates, which are named after the template
+// they're used in place of with a -context suffix, e.g.
+// paragraph-context.tmpl or verbatim-context.tmpl. A context template may
+// render the content as usual with e.g. {{template "paragraph.tmpl"
+// .Content}}, but must not render .Content itself with render, as it would
+// just be rendered by the context template again.
+type RenderContext struct {
+	// content being rendered
+	Content booklit.Content
+
+	// context of the content whose template is rendering the content, or nil
+	// for the page or fragment being rendered
+	Parent *RenderContext
+
+	// content alongside the content within its parent, e.g. the paragraphs of
+	// a section's body, and its position among them, or -1 if it isn't one of
+	// them
+	Siblings []booklit.Content
+	Index    int
+
+	// position among the children from which to look for the next child to
+	// be rendered, as they're typically rendered in order
+	cursor int
+}
+
+func newRenderContext(content booklit.Content) *RenderContext {
+	return &RenderContext{
+		Content: content,
+		Index:   -1,
+	}
+}
+
+// child returns the context for content rendered by the content's template.
+func (context *RenderContext) child(content booklit.Content) *RenderContext {
+	child := newRenderContext(content)
+	child.Parent = context
+	child.Siblings = childContents(context.Content)
+
+	for i := context.cursor; i < len(child.Siblings); i++ {
+		if sameContent(child.Siblings[i], content) {
+			child.Index = i
+			context.cursor = i + 1
+			return child
+		}
+	}
+
+	for i := 0; i < context.cursor && i < len(child.Siblings); i++ {
+		if sameContent(child.Siblings[i], content) {
+			child.Index = i
+			return child
+		}
+	}
+
+	return child
+}
+
+// First returns whether the content is the first of its siblings.
+func (context *RenderContext) First() bool {
+	return context.Index == 0
+}
+
+// Last returns whether the content is the last of its siblings.
+func (context *RenderContext) Last() bool {
+	return context.Index != -1 && context.Index == len(context.Siblings)-1
+}
+
+// Previous returns the sibling before the content, if any.
+func (context *RenderContext) Previous() booklit.Content {
+	if context.Index <= 0 {
+		return nil
+	}
+
+	return context.Siblings[context.Index-1]
+}
+
+// Next returns the sibling after the content, if any.
+func (context *RenderContext) Next() booklit.Content {
+	if context.Index == -1 || context.Index >= len(context.Siblings)-1 {
+		return nil
+	}
+
+	return context.Siblings[context.Index+1]
+}
+
+// Section returns the section the content is rendered within.
+func (context *RenderContext) Section() *booklit.Section {
+	for ctx := context; ctx != nil; ctx = ctx.Parent {
+		if section, ok := ctx.Content.(*booklit.Section); ok {
+			return section
+		}
+	}
+
+	return nil
+}
+
+// Opening returns whether the content opens the body of its section, e.g. its
+// first paragraph, or the first line within it.
+func (context *RenderContext) Opening() bool {
+	for ctx := context; ctx.Parent != nil; ctx = ctx.Parent {
+		if !ctx.First() {
+			return false
+		}
+
+		if _, ok := ctx.Parent.Content.(*booklit.Section); ok {
+			// the body is the first child of a section
+			return true
+		}
+	}
+
+	return false
+}
+
+// Continued returns whether the sibling before the content is rendered the
+// same way, i.e. whether the content is part of the previous sibling's Run.
+func (context *RenderContext) Continued() bool {
+	previous := context.Previous()
+	return previous != nil && sameKind(previous, context.Content)
+}
+
+// Run returns the content followed by the siblings immediately after it
+// which are rendered the same way, e.g. consecutive code blocks, so that they
+// may be rendered together.
+func (context *RenderContext) Run() []booklit.Content {
+	if context.Index == -1 {
+		return []booklit.Content{context.Content}
+	}
+
+	end := context.Index + 1
+	for end < len(context.Siblings) && sameKind(context.Siblings[end], context.Content) {
+		end++
+	}
+
+	return context.Siblings[context.Index:end]
+}
+
+// childContents returns the content which the content's template renders
+// in order, as far as is known.
+func childContents(content booklit.Content) []booklit.Content {
+	switch con := content.(type) {
+	case booklit.Sequence:
+		return con
+	case booklit.Paragraph:
+		return con
+	case booklit.Preformatted:
+		return con
+	case booklit.List:
+		return con.Items
+	case booklit.Styled:
+		return []booklit.Content{con.Content}
+	case booklit.Aux:
+		return []booklit.Content{con.Content}
+	case *booklit.Section:
+		children := []booklit.Content{con.Body}
+		for _, child := range con.Children {
+			children = append(children, child)
+		}
+
+		return children
+	default:
+		return nil
+	}
+}
+
+// sameContent returns whether the two are the same content. Pointers are
+// compared by identity, so as not to compare entire sections.
+func sameContent(a, b booklit.Content) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+
+	if reflect.ValueOf(a).Kind() == reflect.Ptr {
+		return a == b
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// sameKind returns whether the two are rendered with the same template.
+func sameKind(a, b booklit.Content) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+
+	switch con := a.(type) {
+	case booklit.Styled:
+		return con.Style == b.(booklit.Styled).Style
+	case *booklit.Section:
+		return con.Style == b.(*booklit.Section).Style
+	default:
+		return true
+	}
+}
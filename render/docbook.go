@@ -0,0 +1,99 @@
+package render
+
+import (
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/docbook"
+)
+
+// DocBookRenderingEngine renders a section and all of its children as a
+// single DocBook 5 book.
+type DocBookRenderingEngine struct {
+	*HTMLRenderingEngine
+}
+
+// NewDocBookRenderingEngine constructs an engine which renders a DocBook 5
+// book. It is based on the HTML engine, overriding its templates to generate
+// DocBook elements; top-level sections become chapters, asides become notes,
+// and references link to the xml:id of their target.
+func NewDocBookRenderingEngine() *DocBookRenderingEngine {
+	base := template.Must(initHTMLTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"xmlID":     docbookID,
+		"linkend":   docbookLinkend,
+		"isChapter": docbookIsChapter,
+		"xmlDeclaration": func() template.HTML {
+			return template.HTML(`<?xml version="1.0" encoding="UTF-8"?>`)
+		},
+		"hasBody": func(section *booklit.Section) bool {
+			return strings.TrimSpace(section.Body.String()) != ""
+		},
+	})
+
+	for _, asset := range docbook.AssetNames() {
+		info, err := docbook.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(docbook.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine := &HTMLRenderingEngine{
+		name:          "docbook",
+		fileExtension: "xml",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return &DocBookRenderingEngine{engine}
+}
+
+func (engine *DocBookRenderingEngine) RendersDocument() {}
+
+func (engine *DocBookRenderingEngine) URL(tag booklit.Tag) string {
+	return "#" + docbookLinkend(tag)
+}
+
+func docbookLinkend(tag booklit.Tag) string {
+	if tag.Anchor != "" {
+		return docbookID(tag.Anchor)
+	}
+
+	return docbookID(tag.Section.PrimaryTag.Name)
+}
+
+func docbookIsChapter(section *booklit.Section) bool {
+	return section.Depth() == 1
+}
+
+// docbookID converts a tag name to a valid xml:id, which may not contain
+// spaces or most punctuation and must not start with a digit.
+func docbookID(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+
+		return '-'
+	}, name)
+
+	first, _ := utf8.DecodeRuneInString(id)
+	if !unicode.IsLetter(first) && first != '_' {
+		id = "_" + id
+	}
+
+	return id
+}
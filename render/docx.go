@@ -0,0 +1,743 @@
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// DocumentRenderingEngine is implemented by engines which render an entire
+// section tree as a single document, regardless of split sections.
+type DocumentRenderingEngine interface {
+	RenderingEngine
+
+	RendersDocument()
+}
+
+// DocxRenderingEngine renders a section and all of its children as a single
+// Word document.
+type DocxRenderingEngine struct {
+	body *bytes.Buffer
+
+	inParagraph  bool
+	paragraphPPr string
+
+	runProps []string
+
+	bookmarks int
+	links     []string
+}
+
+func NewDocxRenderingEngine() *DocxRenderingEngine {
+	return &DocxRenderingEngine{}
+}
+
+func (engine *DocxRenderingEngine) RendersDocument() {}
+
+func (engine *DocxRenderingEngine) EngineName() string {
+	return "docx"
+}
+
+func (engine *DocxRenderingEngine) FileExtension() string {
+	return "docx"
+}
+
+func (engine *DocxRenderingEngine) HasTemplate(string) bool {
+	return false
+}
+
+func (engine *DocxRenderingEngine) URL(tag booklit.Tag) string {
+	return "#" + docxBookmark(docxAnchor(tag))
+}
+
+func (engine *DocxRenderingEngine) RenderSection(out io.Writer, con *booklit.Section) error {
+	engine.body = new(bytes.Buffer)
+	engine.inParagraph = false
+	engine.runProps = nil
+	engine.bookmarks = 0
+	engine.links = nil
+
+	err := con.Visit(engine)
+	if err != nil {
+		return err
+	}
+
+	engine.closeParagraph()
+
+	archive := zip.NewWriter(out)
+
+	rels := docxDocumentRels
+	for i, target := range engine.links {
+		rels += fmt.Sprintf(`<Relationship Id="rLink%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`, i, docxEscape(target))
+	}
+	rels += `</Relationships>`
+
+	for _, part := range []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/_rels/document.xml.rels", rels},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", docxDocumentHeader + engine.body.String() + docxDocumentFooter},
+	} {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, part.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func (engine *DocxRenderingEngine) VisitString(con booklit.String) error {
+	engine.run(string(con))
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitSequence(con booklit.Sequence) error {
+	for _, c := range con {
+		err := c.Visit(engine)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitReference(con *booklit.Reference) error {
+	engine.openParagraph()
+
+	fmt.Fprintf(engine.body, `<w:hyperlink w:anchor="%s">`, docxBookmark(docxAnchor(*con.Tag)))
+
+	err := engine.withRunProps(`<w:rStyle w:val="Hyperlink"/>`, func() error {
+		return booklit.StripAux(con.Display()).Visit(engine)
+	})
+	if err != nil {
+		return err
+	}
+
+	engine.body.WriteString(`</w:hyperlink>`)
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitLink(con booklit.Link) error {
+	engine.openParagraph()
+
+	fmt.Fprintf(engine.body, `<w:hyperlink r:id="rLink%d">`, len(engine.links))
+	engine.links = append(engine.links, con.Target)
+
+	err := engine.withRunProps(`<w:rStyle w:val="Hyperlink"/>`, func() error {
+		return con.Content.Visit(engine)
+	})
+	if err != nil {
+		return err
+	}
+
+	engine.body.WriteString(`</w:hyperlink>`)
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitSection(con *booklit.Section) error {
+	depth := con.Depth() + 1
+	if depth > 6 {
+		depth = 6
+	}
+
+	err := engine.paragraph(fmt.Sprintf(`<w:pStyle w:val="Heading%d"/>`, depth), func() error {
+		engine.bookmark(con.PrimaryTag.Name)
+
+		if con.Number() != "" {
+			engine.run(con.Number() + " ")
+		}
+
+		return con.Title.Visit(engine)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = con.Body.Visit(engine)
+	if err != nil {
+		return err
+	}
+
+	engine.closeParagraph()
+
+	for _, child := range con.Children {
+		err := child.Visit(engine)
+		if err != nil {
+			return err
+		}
+	}
+
+	return engine.endnotes(con.Endnotes())
+}
+
+func (engine *DocxRenderingEngine) VisitParagraph(con booklit.Paragraph) error {
+	return engine.paragraph("", func() error {
+		for i, line := range con {
+			if i > 0 {
+				engine.run(" ")
+			}
+
+			err := line.Visit(engine)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (engine *DocxRenderingEngine) VisitTableOfContents(booklit.TableOfContents) error {
+	engine.closeParagraph()
+
+	engine.body.WriteString(`<w:p>` +
+		`<w:r><w:fldChar w:fldCharType="begin"/></w:r>` +
+		`<w:r><w:instrText xml:space="preserve"> TOC \o "1-3" \h \z \u </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+		`<w:r><w:t>Update this field to show the table of contents.</w:t></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="end"/></w:r>` +
+		`</w:p>`)
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitPreformatted(con booklit.Preformatted) error {
+	return engine.paragraph(`<w:pStyle w:val="Code"/>`, func() error {
+		for i, line := range con {
+			if i > 0 {
+				engine.lineBreak()
+			}
+
+			err := line.Visit(engine)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (engine *DocxRenderingEngine) VisitStyled(con booklit.Styled) error {
+	switch con.Style {
+	case booklit.StyleBold:
+		return engine.withRunProps(`<w:b/>`, func() error { return con.Content.Visit(engine) })
+	case booklit.StyleItalic:
+		return engine.withRunProps(`<w:i/>`, func() error { return con.Content.Visit(engine) })
+	case booklit.StyleStrike:
+		return engine.withRunProps(`<w:strike/>`, func() error { return con.Content.Visit(engine) })
+	case booklit.StyleSuperscript:
+		return engine.withRunProps(`<w:vertAlign w:val="superscript"/>`, func() error { return con.Content.Visit(engine) })
+	case booklit.StyleSubscript:
+		return engine.withRunProps(`<w:vertAlign w:val="subscript"/>`, func() error { return con.Content.Visit(engine) })
+	case booklit.StyleVerbatim:
+		if con.Content.IsFlow() {
+			return engine.withRunProps(`<w:rStyle w:val="CodeChar"/>`, func() error { return con.Content.Visit(engine) })
+		}
+
+		return engine.paragraph(`<w:pStyle w:val="Code"/>`, func() error {
+			return engine.preformatted(con.Content.String())
+		})
+	case booklit.StyleInset, booklit.StyleAside, booklit.StylePullQuote, booklit.StyleEpigraph:
+		return engine.styledBlocks("Quote", con.Content)
+	case booklit.StylePlayground:
+		// documents can't run code, so only the code block is rendered
+		return con.Content.Visit(engine)
+	case booklit.StyleRuby:
+		err := con.Content.Visit(engine)
+		if err != nil {
+			return err
+		}
+
+		engine.run("(")
+
+		err = con.Partial("Reading").Visit(engine)
+		if err != nil {
+			return err
+		}
+
+		engine.run(")")
+
+		return nil
+	}
+
+	// custom styles have no template to arrange their partials, so they're
+	// rendered in order of name, ahead of the content
+	names := []string{}
+	for name := range con.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := con.Partials[name].Visit(engine)
+		if err != nil {
+			return err
+		}
+	}
+
+	return con.Content.Visit(engine)
+}
+
+func (engine *DocxRenderingEngine) VisitTarget(con booklit.Target) error {
+	engine.openParagraph()
+	engine.bookmark(con.TagName)
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitImage(con booklit.Image) error {
+	desc := con.Description
+	if desc == "" {
+		desc = con.Path
+	}
+
+	return engine.withRunProps(`<w:i/>`, func() error {
+		engine.run("[image: " + desc + "]")
+		return nil
+	})
+}
+
+func (engine *DocxRenderingEngine) VisitList(con booklit.List) error {
+	for i, item := range con.Items {
+		prefix := "• "
+		if con.Ordered {
+			prefix = fmt.Sprintf("%d. ", i+1)
+		}
+
+		err := engine.paragraph(`<w:pStyle w:val="ListParagraph"/>`, func() error {
+			engine.run(prefix)
+			return engine.inline(item)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitTable(con booklit.Table) error {
+	engine.closeParagraph()
+
+	engine.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="0" w:type="auto"/></w:tblPr>`)
+
+	for i, row := range con.Rows {
+		header := i < con.HeaderRows
+
+		if header {
+			// repeated at the top of each page the table spans
+			engine.body.WriteString(`<w:tr><w:trPr><w:tblHeader/></w:trPr>`)
+		} else {
+			engine.body.WriteString(`<w:tr>`)
+		}
+
+		for j, cell := range row {
+			engine.body.WriteString(`<w:tc>`)
+
+			var pPr string
+			switch con.Alignment(j) {
+			case booklit.AlignLeft:
+				pPr = `<w:jc w:val="left"/>`
+			case booklit.AlignCenter:
+				pPr = `<w:jc w:val="center"/>`
+			case booklit.AlignRight:
+				pPr = `<w:jc w:val="right"/>`
+			}
+
+			err := engine.paragraph(pPr, func() error {
+				if header {
+					return engine.withRunProps(`<w:b/>`, func() error {
+						return engine.inline(cell)
+					})
+				}
+
+				return engine.inline(cell)
+			})
+			if err != nil {
+				return err
+			}
+
+			engine.body.WriteString(`</w:tc>`)
+		}
+
+		engine.body.WriteString(`</w:tr>`)
+	}
+
+	engine.body.WriteString(`</w:tbl>`)
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitDefinitions(con booklit.Definitions) error {
+	for _, def := range con {
+		err := engine.paragraph("", func() error {
+			return engine.withRunProps(`<w:b/>`, func() error {
+				return engine.inline(def.Subject)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		err = engine.styledBlocks("ListParagraph", def.Definition)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitFigure(con *booklit.Figure) error {
+	err := con.Content.Visit(engine)
+	if err != nil {
+		return err
+	}
+
+	return engine.paragraph(`<w:pStyle w:val="Caption"/>`, func() error {
+		engine.bookmark(con.Anchor())
+		engine.run(con.Title().String() + ": ")
+		return con.Caption.Visit(engine)
+	})
+}
+
+func (engine *DocxRenderingEngine) VisitFootnote(con *booklit.Footnote) error {
+	engine.bookmark(con.ReferenceAnchor())
+
+	fmt.Fprintf(engine.body, `<w:hyperlink w:anchor="%s">`, docxBookmark(con.Anchor()))
+
+	err := engine.withRunProps(`<w:vertAlign w:val="superscript"/>`, func() error {
+		engine.run(strconv.Itoa(con.Number()))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	engine.body.WriteString(`</w:hyperlink>`)
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitListOfFigures(con booklit.ListOfFigures) error {
+	for _, fig := range con.Section.AllFigures() {
+		err := engine.paragraph("", func() error {
+			fmt.Fprintf(engine.body, `<w:hyperlink w:anchor="%s">`, docxBookmark(fig.Anchor()))
+			engine.withRunProps(`<w:rStyle w:val="Hyperlink"/>`, func() error {
+				engine.run(fig.Title().String())
+				return nil
+			})
+			engine.body.WriteString(`</w:hyperlink>`)
+
+			engine.run(": ")
+			return booklit.StripAux(fig.Caption).Visit(engine)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (engine *DocxRenderingEngine) VisitAttributions(con booklit.Attributions) error {
+	for _, attr := range con.Section.AllAttributions() {
+		err := engine.paragraph(`<w:pStyle w:val="ListParagraph"/>`, func() error {
+			err := booklit.StripAux(attr.Subject).Visit(engine)
+			if err != nil {
+				return err
+			}
+
+			if attr.Credit != nil {
+				engine.run(": ")
+
+				err := attr.Credit.Visit(engine)
+				if err != nil {
+					return err
+				}
+			}
+
+			if attr.License != "" {
+				engine.run(" (" + attr.License + ")")
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endnotes renders the footnotes collected at the end of a section, each
+// linking back to where it was marked.
+func (engine *DocxRenderingEngine) endnotes(notes []*booklit.Footnote) error {
+	for _, note := range notes {
+		err := engine.paragraph(`<w:pStyle w:val="EndnoteText"/>`, func() error {
+			engine.bookmark(note.Anchor())
+
+			fmt.Fprintf(engine.body, `<w:hyperlink w:anchor="%s">`, docxBookmark(note.ReferenceAnchor()))
+			engine.run(strconv.Itoa(note.Number()) + ".")
+			engine.body.WriteString(`</w:hyperlink>`)
+
+			engine.run(" ")
+
+			return engine.inline(note.Content)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// paragraph renders content within a paragraph with the given properties,
+// closing any paragraph already open.
+func (engine *DocxRenderingEngine) paragraph(pPr string, render func() error) error {
+	engine.closeParagraph()
+
+	engine.paragraphPPr = pPr
+	engine.openParagraph()
+
+	err := render()
+	if err != nil {
+		return err
+	}
+
+	engine.closeParagraph()
+
+	return nil
+}
+
+// styledBlocks renders block content, applying the paragraph style to each
+// paragraph within it.
+func (engine *DocxRenderingEngine) styledBlocks(style string, content booklit.Content) error {
+	pPr := `<w:pStyle w:val="` + style + `"/>`
+
+	if content.IsFlow() {
+		return engine.paragraph(pPr, func() error {
+			return content.Visit(engine)
+		})
+	}
+
+	sub := &DocxRenderingEngine{
+		body:      new(bytes.Buffer),
+		bookmarks: engine.bookmarks,
+		links:     engine.links,
+	}
+
+	err := content.Visit(sub)
+	if err != nil {
+		return err
+	}
+
+	sub.closeParagraph()
+
+	engine.closeParagraph()
+	engine.body.WriteString(strings.Replace(sub.body.String(), "<w:p><w:pPr></w:pPr>", "<w:p><w:pPr>"+pPr+"</w:pPr>", -1))
+	engine.bookmarks = sub.bookmarks
+	engine.links = sub.links
+
+	return nil
+}
+
+// inline renders content within the current paragraph, flattening any
+// paragraphs within it.
+func (engine *DocxRenderingEngine) inline(content booklit.Content) error {
+	switch con := content.(type) {
+	case booklit.Paragraph:
+		for i, line := range con {
+			if i > 0 {
+				engine.run(" ")
+			}
+
+			err := engine.inline(line)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case booklit.Sequence:
+		for i, c := range con {
+			if _, ok := c.(booklit.Paragraph); ok && i > 0 {
+				engine.lineBreak()
+			}
+
+			err := engine.inline(c)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return content.Visit(engine)
+	}
+}
+
+func (engine *DocxRenderingEngine) withRunProps(rPr string, render func() error) error {
+	engine.runProps = append(engine.runProps, rPr)
+	defer func() {
+		engine.runProps = engine.runProps[:len(engine.runProps)-1]
+	}()
+
+	return render()
+}
+
+func (engine *DocxRenderingEngine) openParagraph() {
+	if engine.inParagraph {
+		return
+	}
+
+	engine.body.WriteString(`<w:p><w:pPr>` + engine.paragraphPPr + `</w:pPr>`)
+	engine.inParagraph = true
+}
+
+func (engine *DocxRenderingEngine) closeParagraph() {
+	if !engine.inParagraph {
+		return
+	}
+
+	engine.body.WriteString(`</w:p>`)
+	engine.inParagraph = false
+	engine.paragraphPPr = ""
+}
+
+func (engine *DocxRenderingEngine) run(text string) {
+	if text == "" {
+		return
+	}
+
+	engine.openParagraph()
+
+	engine.body.WriteString(`<w:r><w:rPr>` + strings.Join(engine.runProps, "") + `</w:rPr>`)
+
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			engine.body.WriteString(`<w:br/>`)
+		}
+
+		engine.body.WriteString(`<w:t xml:space="preserve">` + docxEscape(line) + `</w:t>`)
+	}
+
+	engine.body.WriteString(`</w:r>`)
+}
+
+func (engine *DocxRenderingEngine) preformatted(text string) error {
+	engine.run(strings.TrimRight(text, "\n"))
+	return nil
+}
+
+func (engine *DocxRenderingEngine) lineBreak() {
+	engine.openParagraph()
+	engine.body.WriteString(`<w:r><w:br/></w:r>`)
+}
+
+func (engine *DocxRenderingEngine) bookmark(name string) {
+	if name == "" {
+		return
+	}
+
+	engine.openParagraph()
+
+	id := engine.bookmarks
+	engine.bookmarks++
+
+	fmt.Fprintf(engine.body, `<w:bookmarkStart w:id="%d" w:name="%s"/><w:bookmarkEnd w:id="%d"/>`, id, docxBookmark(name), id)
+}
+
+func docxAnchor(tag booklit.Tag) string {
+	if tag.Anchor != "" {
+		return tag.Anchor
+	}
+
+	return tag.Section.PrimaryTag.Name
+}
+
+var docxBookmarkInvalid = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// docxBookmark converts a tag name to a valid bookmark name, which may only
+// contain letters, digits, and underscores and must be at most 40 characters.
+func docxBookmark(name string) string {
+	bookmark := "b_" + docxBookmarkInvalid.ReplaceAllString(name, "_")
+	if len(bookmark) > 40 {
+		bookmark = bookmark[:40]
+	}
+
+	return bookmark
+}
+
+func docxEscape(str string) string {
+	buf := new(bytes.Buffer)
+	_ = xml.EscapeText(buf, []byte(str))
+	return buf.String()
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+</Types>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rDocument" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`
+
+const docxDocumentHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<w:body>`
+
+const docxDocumentFooter = `<w:sectPr/></w:body>
+</w:document>`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:pPr><w:spacing w:after="160"/></w:pPr><w:rPr><w:sz w:val="22"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="360"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="40"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/><w:i/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:i/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/><w:pPr><w:shd w:val="clear" w:fill="F2F2F2"/><w:spacing w:after="160" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="character" w:styleId="CodeChar"><w:name w:val="Code Char"/><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Caption"><w:name w:val="caption"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:rPr><w:i/><w:sz w:val="18"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="EndnoteText"><w:name w:val="endnote text"/><w:basedOn w:val="Normal"/><w:rPr><w:sz w:val="20"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:pPr><w:ind w:left="720" w:right="720"/></w:pPr><w:rPr><w:i/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:ind w:left="720"/></w:pPr></w:style>
+<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>
+<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:left w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:bottom w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:right w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideH w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="auto"/></w:tblBorders></w:tblPr></w:style>
+</w:styles>`
@@ -0,0 +1,196 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// Embedder computes a vector embedding for each of the given texts, in
+// order, giving up once the context is done.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// SearchEmbeddings maps each tag name in the search index to the embedding
+// of its document.
+type SearchEmbeddings map[string][]float64
+
+// embeddingsBatchSize is the maximum number of texts embedded at once.
+const embeddingsBatchSize = 64
+
+// WriteSearchEmbeddings computes an embedding for each document in the
+// search index and writes them as JSON to the given path, keyed by the same
+// tag names as the search index.
+func (writer Writer) WriteSearchEmbeddings(section *booklit.Section, path string, embedder Embedder) error {
+	logrus.WithFields(logrus.Fields{
+		"path": path,
+	}).Infoln("writing search embeddings")
+
+	index := SearchIndex{}
+	writer.loadTags(index, section)
+
+	tags := []string{}
+	for tag := range index {
+		tags = append(tags, tag)
+	}
+
+	sort.Strings(tags)
+
+	embeddings := SearchEmbeddings{}
+	for start := 0; start < len(tags); start += embeddingsBatchSize {
+		end := start + embeddingsBatchSize
+		if end > len(tags) {
+			end = len(tags)
+		}
+
+		texts := []string{}
+		for _, tag := range tags[start:end] {
+			doc := index[tag]
+			texts = append(texts, doc.Title+"\n\n"+doc.Text)
+		}
+
+		vectors, err := embedder.Embed(section.Context(), texts)
+		if err != nil {
+			return fmt.Errorf("embed: %s", err)
+		}
+
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embed: expected %d embeddings, got %d", len(texts), len(vectors))
+		}
+
+		for i, tag := range tags[start:end] {
+			embeddings[tag] = vectors[i]
+		}
+	}
+
+	payload, err := json.Marshal(embeddings)
+	if err != nil {
+		return err
+	}
+
+	return writer.writeFile(path, append(payload, '\n'))
+}
+
+// HTTPEmbedder computes embeddings with an OpenAI-compatible embeddings
+// endpoint, e.g. https://api.openai.com/v1/embeddings or a local Ollama
+// server.
+type HTTPEmbedder struct {
+	URL   string
+	Model string
+
+	// Bearer token to authenticate with, if any.
+	Token string
+
+	Client *http.Client
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (embedder HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	payload, err := json.Marshal(embeddingsRequest{
+		Model: embedder.Model,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", embedder.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if embedder.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+embedder.Token)
+	}
+
+	client := embedder.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return nil, fmt.Errorf("%s: %s: %s", embedder.URL, res.Status, msg)
+	}
+
+	var result embeddingsResponse
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, datum := range result.Data {
+		if datum.Index < 0 || datum.Index >= len(vectors) {
+			return nil, fmt.Errorf("invalid embedding index: %d", datum.Index)
+		}
+
+		vectors[datum.Index] = datum.Embedding
+	}
+
+	return vectors, nil
+}
+
+// CommandEmbedder computes embeddings by running a command, e.g. a script
+// wrapping a local model. The command is given a JSON array of texts on
+// stdin and must print a JSON array of embeddings to stdout.
+type CommandEmbedder struct {
+	Command []string
+}
+
+func (embedder CommandEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := new(bytes.Buffer)
+
+	cmd := exec.CommandContext(ctx, embedder.Command[0], embedder.Command[1:]...)
+	cmd.Stdin = bytes.NewBuffer(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors [][]float64
+	err = json.Unmarshal(stdout.Bytes(), &vectors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embeddings output: %s", err)
+	}
+
+	return vectors, nil
+}
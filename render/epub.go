@@ -0,0 +1,448 @@
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"mime"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/epub"
+)
+
+// EPUBRenderingEngine renders a section and all of its children as a single
+// EPUB 3 book, e.g. for reading on e-readers.
+type EPUBRenderingEngine struct {
+	*HTMLRenderingEngine
+
+	// Directory which relative image paths are resolved against when
+	// embedding them in the book.
+	Directory string
+
+	// Paths to stylesheets to embed in the book and link from every
+	// document.
+	Stylesheets []string
+
+	// Time the book was last modified, recorded in its metadata. Defaults
+	// to the time it is rendered.
+	Modified time.Time
+
+	// If set, chapters which are unchanged since they were saved in the
+	// cache are not rendered again.
+	Chapters *ChapterCache
+
+	// section being rendered, whose children each become a document
+	root *booklit.Section
+
+	// relative image paths referenced while rendering
+	images []string
+}
+
+// NewEPUBRenderingEngine constructs an engine which renders an EPUB 3 book.
+// It is based on the HTML engine, overriding its templates to generate
+// XHTML; the root section and each of its children become a document in the
+// book's spine, in order, and the table of contents becomes its navigation
+// document.
+func NewEPUBRenderingEngine() *EPUBRenderingEngine {
+	engine := &EPUBRenderingEngine{}
+
+	base := template.Must(initHTMLTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"url": engine.URL,
+
+		"isRoot": func(section *booklit.Section) bool {
+			return section == engine.root
+		},
+
+		"image": func(path string) string {
+			return engine.addImage(path)
+		},
+
+		"stylesheets": engine.stylesheetPaths,
+
+		"language": epubLanguage,
+
+		"headerDepth": func(con *booklit.Section) int {
+			depth := con.Depth() + 1
+			if engine.root != nil {
+				depth -= engine.root.Depth()
+			}
+
+			if depth > 6 {
+				depth = 6
+			}
+
+			return depth
+		},
+
+		"xmlDeclaration": func() template.HTML {
+			return template.HTML(`<?xml version="1.0" encoding="UTF-8"?>`)
+		},
+	})
+
+	for _, asset := range epub.AssetNames() {
+		info, err := epub.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(epub.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine.HTMLRenderingEngine = &HTMLRenderingEngine{
+		name:          "epub",
+		fileExtension: "epub",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return engine
+}
+
+func (engine *EPUBRenderingEngine) RendersDocument() {}
+
+// URL returns the link to the tag within the document of the chapter which
+// contains it.
+func (engine *EPUBRenderingEngine) URL(tag booklit.Tag) string {
+	chapter := engine.chapterOf(tag.Section)
+
+	url := epubDocument(chapter)
+	if tag.Anchor != "" {
+		url += "#" + tag.Anchor
+	} else if tag.Section != chapter {
+		url += "#" + tag.Section.PrimaryTag.Name
+	}
+
+	return url
+}
+
+func (engine *EPUBRenderingEngine) RenderSection(out io.Writer, con *booklit.Section) error {
+	engine.root = con
+	engine.images = nil
+
+	modified := engine.Modified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+
+	chapters := append([]*booklit.Section{con}, con.Children...)
+
+	var structure string
+	if engine.Chapters != nil {
+		structure = structureDigest(engine, con.Top())
+	}
+
+	documents := map[string][]byte{}
+	for _, chapter := range chapters {
+		document, err := engine.renderChapter(structure, chapter)
+		if err != nil {
+			return err
+		}
+
+		documents[epubDocument(chapter)] = document
+	}
+
+	nav := new(bytes.Buffer)
+
+	engine.data = con
+	err := engine.setTmpl("nav")
+	if err != nil {
+		return err
+	}
+
+	err = engine.render(nav)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(out)
+
+	// the mimetype must come first, uncompressed, so the container can be
+	// identified by its leading bytes
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "mimetype",
+		Method:   zip.Store,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(mimetype, "application/epub+zip")
+	if err != nil {
+		return err
+	}
+
+	files := []epubFile{
+		{"META-INF/container.xml", []byte(epubContainer)},
+		{"nav.xhtml", nav.Bytes()},
+	}
+
+	items := []epubItem{
+		{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav"},
+	}
+
+	spine := []string{}
+	for i, chapter := range chapters {
+		id := fmt.Sprintf("chapter-%d", i)
+		name := epubDocument(chapter)
+
+		files = append(files, epubFile{name, documents[name]})
+		items = append(items, epubItem{ID: id, Href: name, MediaType: "application/xhtml+xml"})
+		spine = append(spine, id)
+	}
+
+	for i, stylesheet := range engine.Stylesheets {
+		content, err := ioutil.ReadFile(stylesheet)
+		if err != nil {
+			return err
+		}
+
+		name := engine.stylesheetPaths()[i]
+
+		files = append(files, epubFile{name, content})
+		items = append(items, epubItem{ID: fmt.Sprintf("css-%d", i), Href: name, MediaType: "text/css"})
+	}
+
+	for i, image := range engine.images {
+		file := filepath.Join(engine.Directory, filepath.FromSlash(image))
+
+		// images can't be outside of the directory by their path, but they
+		// can be by a symlink
+		if safe := con.Top().Safe; safe != nil {
+			err := safe.CheckFileIn(engine.Directory, file)
+			if err != nil {
+				return err
+			}
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		mediaType := mime.TypeByExtension(path.Ext(image))
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+
+		files = append(files, epubFile{image, content})
+		items = append(items, epubItem{ID: fmt.Sprintf("image-%d", i), Href: image, MediaType: mediaType})
+	}
+
+	files = append(files, epubFile{"content.opf", epubPackage(con, modified, items, spine)})
+
+	for _, file := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(file.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// chapterOf returns the section whose document contains the given section:
+// either the root section or one of its children.
+func (engine *EPUBRenderingEngine) chapterOf(section *booklit.Section) *booklit.Section {
+	for s := section; s != nil; s = s.Parent {
+		if s == engine.root || s.Parent == engine.root {
+			return s
+		}
+	}
+
+	return section.Top()
+}
+
+// renderChapter renders the chapter's document, or reuses the one saved in
+// the cache along with the images it referenced.
+func (engine *EPUBRenderingEngine) renderChapter(structure string, chapter *booklit.Section) ([]byte, error) {
+	var digest string
+	if engine.Chapters != nil {
+		// the root's children are documents of their own
+		sections := []*booklit.Section{chapter}
+		if chapter != engine.root {
+			sections = chapterSections(chapter)
+		}
+
+		var err error
+		digest, err = engine.Chapters.digest(engine, structure, chapter, sections)
+		if err != nil {
+			return nil, err
+		}
+
+		artifact, found, err := engine.Chapters.fetch(digest)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			for _, image := range artifact.Images {
+				engine.addImage(image)
+			}
+
+			return artifact.Content, nil
+		}
+	}
+
+	// record the images referenced by this chapter alone
+	images := engine.images
+	engine.images = nil
+
+	buf := new(bytes.Buffer)
+	err := engine.HTMLRenderingEngine.RenderSection(buf, chapter)
+
+	chapterImages := engine.images
+	engine.images = images
+
+	for _, image := range chapterImages {
+		engine.addImage(image)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if engine.Chapters != nil {
+		err := engine.Chapters.store(digest, chapterArtifact{
+			Content: buf.Bytes(),
+			Images:  chapterImages,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// addImage records a relative image path to embed in the book. Absolute
+// URLs are linked to as-is.
+func (engine *EPUBRenderingEngine) addImage(image string) string {
+	if strings.Contains(image, "://") {
+		return image
+	}
+
+	image = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(image)), "/")
+
+	for _, seen := range engine.images {
+		if seen == image {
+			return image
+		}
+	}
+
+	engine.images = append(engine.images, image)
+
+	return image
+}
+
+func (engine *EPUBRenderingEngine) stylesheetPaths() []string {
+	paths := []string{}
+	for i, stylesheet := range engine.Stylesheets {
+		paths = append(paths, fmt.Sprintf("css/%d-%s", i, filepath.Base(stylesheet)))
+	}
+
+	return paths
+}
+
+func epubDocument(section *booklit.Section) string {
+	return section.PrimaryTag.Name + ".xhtml"
+}
+
+func epubLanguage(section *booklit.Section) string {
+	locale := section.InheritedLocale()
+	if locale == "" {
+		return "en"
+	}
+
+	return locale
+}
+
+type epubFile struct {
+	Name    string
+	Content []byte
+}
+
+type epubItem struct {
+	ID         string
+	Href       string
+	MediaType  string
+	Properties string
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// epubPackage generates the package document, which lists the book's
+// metadata, every file in the book, and the order in which its documents
+// are read.
+func epubPackage(section *booklit.Section, modified time.Time, items []epubItem, spine []string) []byte {
+	buf := new(bytes.Buffer)
+
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">` + "\n")
+	buf.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(buf, "    <dc:identifier id=\"book-id\">urn:booklit:%s</dc:identifier>\n", epubEscape(section.PrimaryTag.Name))
+	fmt.Fprintf(buf, "    <dc:title>%s</dc:title>\n", epubEscape(booklit.StripAux(section.Title).String()))
+	fmt.Fprintf(buf, "    <dc:language>%s</dc:language>\n", epubEscape(epubLanguage(section)))
+	fmt.Fprintf(buf, "    <meta property=\"dcterms:modified\">%s</meta>\n", modified.UTC().Format("2006-01-02T15:04:05Z"))
+	buf.WriteString("  </metadata>\n")
+
+	buf.WriteString("  <manifest>\n")
+	for _, item := range items {
+		fmt.Fprintf(buf, "    <item id=\"%s\" href=\"%s\" media-type=\"%s\"", item.ID, epubEscape(item.Href), item.MediaType)
+		if item.Properties != "" {
+			fmt.Fprintf(buf, " properties=\"%s\"", item.Properties)
+		}
+		buf.WriteString("/>\n")
+	}
+	buf.WriteString("  </manifest>\n")
+
+	if section.InheritedDirection() == booklit.DirectionRTL {
+		buf.WriteString(`  <spine page-progression-direction="rtl">` + "\n")
+	} else {
+		buf.WriteString("  <spine>\n")
+	}
+	for _, id := range spine {
+		fmt.Fprintf(buf, "    <itemref idref=\"%s\"/>\n", id)
+	}
+	buf.WriteString("  </spine>\n")
+
+	buf.WriteString("</package>\n")
+
+	return buf.Bytes()
+}
+
+func epubEscape(str string) string {
+	buf := new(bytes.Buffer)
+	_ = xml.EscapeText(buf, []byte(str))
+	return buf.String()
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// errorStatuses are the statuses for which sections may be designated as
+// error pages.
+var errorStatuses = []int{404, 500}
+
+// WriteErrorPages writes each section designated as an error page to a file
+// named after its status, e.g. 404.html, so that hosts can serve it in place
+// of their default error page.
+func (writer Writer) WriteErrorPages(section *booklit.Section) error {
+	for _, status := range errorStatuses {
+		page := section.FindErrorPage(status)
+		if page == nil {
+			continue
+		}
+
+		name := fmt.Sprintf("%d.%s", status, writer.Engine.FileExtension())
+
+		logrus.WithFields(logrus.Fields{
+			"section":  page.Path,
+			"rendered": filepath.Join(writer.Destination, name),
+		}).Info("rendering error page")
+
+		file, err := writer.output().Create(name)
+		if err != nil {
+			return err
+		}
+
+		err = RenderErrorPage(writer.Engine, file, page, writer.ErrorPageBase)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		err = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenderErrorPage renders the section as a page which may be served at any
+// path. A <base> element is added to the page's <head> so that its relative
+// links resolve against the given base path, defaulting to "/".
+func RenderErrorPage(engine RenderingEngine, out io.Writer, section *booklit.Section, base string) error {
+	if base == "" {
+		base = section.Top().BasePath
+	}
+
+	if base == "" {
+		base = "/"
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := engine.RenderSection(buf, section)
+	if err != nil {
+		return err
+	}
+
+	page := buf.String()
+
+	head := strings.Index(strings.ToLower(page), "<head")
+	if head != -1 {
+		if end := strings.Index(page[head:], ">"); end != -1 {
+			insert := head + end + 1
+			page = page[:insert] + `<base href="` + html.EscapeString(base) + `">` + page[insert:]
+		}
+	}
+
+	_, err = io.WriteString(out, page)
+	return err
+}
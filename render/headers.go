@@ -0,0 +1,128 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// PageCacheControl is the Cache-Control header configured for pages, which
+// may change with every build, so must be revalidated on every request.
+const PageCacheControl = "public, max-age=0, must-revalidate"
+
+// FingerprintedCacheControl is the Cache-Control header configured for
+// fingerprinted assets, which change names whenever their content changes,
+// so may be cached forever.
+const FingerprintedCacheControl = "public, max-age=31536000, immutable"
+
+// WriteHeaders writes a file configuring the Cache-Control header of each
+// page and fingerprinted asset in the destination, in the _headers format
+// understood by static hosts like Netlify and Cloudflare Pages. Other files
+// are left to the host's defaults.
+//
+// Assets are considered fingerprinted if their name ends in a hash of their
+// content, e.g. app.3f2a9c1d.css or app-7KQ2M4XZ.js, as generated by most
+// asset bundlers.
+func (writer Writer) WriteHeaders(section *booklit.Section, path string) error {
+	logrus.WithFields(logrus.Fields{
+		"path": path,
+	}).Infoln("writing headers")
+
+	pages := map[string]bool{}
+	for _, url := range writer.Manifest(section) {
+		page, _ := splitURL(url)
+		if strings.Contains(page, "://") {
+			// linked to another site, e.g. a book hosted elsewhere
+			continue
+		}
+
+		pages["/"+strings.TrimPrefix(page, "/")] = true
+	}
+
+	assets := []string{}
+	err := filepath.Walk(writer.Destination, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !Fingerprinted(info.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(writer.Destination, file)
+		if err != nil {
+			return err
+		}
+
+		assets = append(assets, basePath(section.Top())+filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sortedPages := []string{}
+	for page := range pages {
+		sortedPages = append(sortedPages, page)
+	}
+
+	sort.Strings(sortedPages)
+	sort.Strings(assets)
+
+	headers := new(bytes.Buffer)
+
+	for _, page := range sortedPages {
+		fmt.Fprintf(headers, "%s\n  Cache-Control: %s\n\n", page, PageCacheControl)
+	}
+
+	for _, asset := range assets {
+		fmt.Fprintf(headers, "/%s\n  Cache-Control: %s\n\n", strings.TrimPrefix(asset, "/"), FingerprintedCacheControl)
+	}
+
+	return writer.writeFile(path, headers.Bytes())
+}
+
+// Fingerprinted returns true if the file name ends in a hash of the file's
+// content, just before its extension: either at least 8 lowercase hex
+// digits or 8 uppercase letters and digits, mixing both letters and digits.
+func Fingerprinted(name string) bool {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	sep := strings.LastIndexAny(name, ".-")
+	if sep == -1 {
+		return false
+	}
+
+	hash := name[sep+1:]
+	if len(hash) < 8 {
+		return false
+	}
+
+	var digits, lower, upper int
+	for _, r := range hash {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r >= 'a' && r <= 'f':
+			lower++
+		case r >= 'A' && r <= 'Z':
+			upper++
+		default:
+			return false
+		}
+	}
+
+	if digits == 0 || lower+upper == 0 || (lower > 0 && upper > 0) {
+		return false
+	}
+
+	// uppercase hashes are fixed-length, e.g. esbuild's
+	return upper == 0 || len(hash) == 8
+}
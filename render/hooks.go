@@ -0,0 +1,172 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// Hook is a command run on each page a Writer renders, e.g. a validator such
+// as tidy. The build fails if the command exits nonzero.
+type Hook struct {
+	// Extension of the pages to run the command on, e.g. html, or * for every
+	// page.
+	Extension string
+
+	// Command to run. The path of the page replaces {file} in its arguments,
+	// or is appended to them if none do.
+	Command []string
+}
+
+// Matches returns true if the hook should be run on the file.
+func (hook Hook) Matches(file string) bool {
+	return hook.Extension == "*" || strings.TrimPrefix(filepath.Ext(file), ".") == hook.Extension
+}
+
+// runHooks runs each hook matching the page on it, returning a
+// booklit.FailedHookError located at its section if any fail.
+func (writer Writer) runHooks(path string, section *booklit.Section) error {
+	for _, hook := range writer.Hooks {
+		if !hook.Matches(path) {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"section": section.Path,
+			"file":    path,
+			"command": hook.Command[0],
+		}).Debug("running hook")
+
+		err := RunHook(section.Context(), hook.Command, "{file}", path, map[string]*booklit.Section{path: section})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunHook runs the command with the placeholder in its arguments replaced by
+// the value, or with the value appended if none have it, killing it if the
+// context is done first. If the command fails, a booklit.FailedHookError is
+// returned, located at the section whose file the command was run on or first
+// mentioned in its output, if any.
+func RunHook(ctx context.Context, command []string, placeholder string, value string, pages map[string]*booklit.Section) error {
+	if len(command) == 0 {
+		return fmt.Errorf("no command given for hook")
+	}
+
+	args := []string{}
+	replaced := false
+	for _, arg := range command {
+		if strings.Contains(arg, placeholder) {
+			replaced = true
+			arg = strings.Replace(arg, placeholder, value, -1)
+		}
+
+		args = append(args, arg)
+	}
+
+	if !replaced {
+		args = append(args, value)
+	}
+
+	output := new(bytes.Buffer)
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+
+	// killed because the build was cancelled, rather than failing
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	hookErr := booklit.FailedHookError{
+		Command: strings.Join(args, " "),
+		Output:  output.String(),
+		Err:     err,
+	}
+
+	if section, found := pages[value]; found {
+		hookErr.ErrorLocation = titleLocation(section)
+	} else if section := mentionedPage(output.String(), pages); section != nil {
+		hookErr.ErrorLocation = titleLocation(section)
+	}
+
+	return hookErr
+}
+
+// mentionedPage returns the section whose page is mentioned first in the
+// output, e.g. by a validator reporting problems with it.
+func mentionedPage(output string, pages map[string]*booklit.Section) *booklit.Section {
+	files := []string{}
+	for file := range pages {
+		files = append(files, file)
+	}
+
+	// prefer the longest path at the same position, e.g. docs/index.html
+	// over index.html
+	sort.Slice(files, func(i, j int) bool {
+		return len(files[i]) > len(files[j])
+	})
+
+	first := -1
+	var mentioned *booklit.Section
+	for _, file := range files {
+		idx := strings.Index(output, file)
+		if idx != -1 && (first == -1 || idx < first) {
+			first = idx
+			mentioned = pages[file]
+		}
+	}
+
+	return mentioned
+}
+
+// Pages returns the section whose page is written to each file, by the path
+// of the file, including the destination.
+func (writer Writer) Pages(section *booklit.Section) map[string]*booklit.Section {
+	pages := map[string]*booklit.Section{}
+
+	if _, ok := writer.Engine.(DocumentRenderingEngine); ok {
+		pages[filepath.Join(writer.Destination, writer.pagePath(section))] = section
+		return pages
+	}
+
+	var collect func(*booklit.Section)
+	collect = func(section *booklit.Section) {
+		if writesPage(section) {
+			pages[filepath.Join(writer.Destination, writer.pagePath(section))] = section
+		}
+
+		for _, child := range section.Children {
+			collect(child)
+		}
+	}
+
+	collect(section)
+
+	return pages
+}
+
+func titleLocation(section *booklit.Section) booklit.ErrorLocation {
+	return booklit.ErrorLocation{
+		FilePath:     section.PrimaryTag.Section.FilePath(),
+		FS:           section.PrimaryTag.Section.Top().FS,
+		NodeLocation: section.PrimaryTag.Location,
+		Length:       len("\\title"),
+	}
+}
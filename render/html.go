@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -21,7 +22,7 @@ var initHTMLTmpl *template.Template
 func init() {
 	initHTMLTmpl = template.New("engine").Funcs(template.FuncMap{
 		"url": func(tag booklit.Tag) string {
-			return sectionURL("html", tag.Section, tag.Anchor)
+			return SectionURL("html", tag.Section, tag.Anchor)
 		},
 
 		"stripAux": booklit.StripAux,
@@ -34,6 +35,10 @@ func init() {
 			return "", errors.New("render stubbed out")
 		},
 
+		"asset": func(path string) string {
+			return path
+		},
+
 		"walkContext": func(current *booklit.Section, section *booklit.Section) WalkContext {
 			return WalkContext{
 				Current: current,
@@ -41,6 +46,8 @@ func init() {
 			}
 		},
 
+		"sectionAttrs": sectionAttrs,
+
 		"headerDepth": func(con *booklit.Section) int {
 			depth := con.PageDepth() + 1
 			if depth > 6 {
@@ -67,15 +74,42 @@ func init() {
 }
 
 type HTMLRenderingEngine struct {
+	// If set, raw HTML from plugins is stripped of scripts, event handlers,
+	// and other unsafe markup, e.g. for building contributed content.
+	SanitizeHTML bool
+
+	name          string
+	fileExtension string
+
+	// link to tags via URL, relative to the page being rendered; engines
+	// based on this one link to tags their own way
+	pageLinks bool
+
+	baseTmpl     *template.Template
 	tmpl         *template.Template
 	tmplModTimes map[string]time.Time
 
+	// templates loaded by LoadTemplates, for forking
+	tmplSources []tmplSource
+
 	template *template.Template
 	data     interface{}
+
+	// section whose page is being rendered
+	page *booklit.Section
+
+	// contexts of the content being rendered, innermost last
+	contexts []*RenderContext
 }
 
 func NewHTMLRenderingEngine() *HTMLRenderingEngine {
 	engine := &HTMLRenderingEngine{
+		name:          "html",
+		fileExtension: "html",
+
+		pageLinks: true,
+
+		baseTmpl:     initHTMLTmpl,
 		tmplModTimes: map[string]time.Time{},
 	}
 
@@ -85,10 +119,41 @@ func NewHTMLRenderingEngine() *HTMLRenderingEngine {
 }
 
 func (engine *HTMLRenderingEngine) resetTmpl() {
-	engine.tmpl = template.Must(initHTMLTmpl.Clone())
+	engine.tmpl = template.Must(engine.baseTmpl.Clone())
 	engine.tmpl.Funcs(template.FuncMap{
 		"render": engine.subRender,
+
+		"rawHTML": engine.rawHTML,
+
+		"asset": func(path string) string {
+			if engine.page == nil {
+				return path
+			}
+
+			return AssetURL(engine.page, path)
+		},
 	})
+
+	if engine.pageLinks {
+		engine.tmpl.Funcs(template.FuncMap{
+			"url": engine.URL,
+		})
+	}
+}
+
+// rawHTML returns the content as HTML without escaping it, e.g. for
+// markup generated by a plugin. If SanitizeHTML is set, it is sanitized.
+func (engine *HTMLRenderingEngine) rawHTML(con booklit.Content) (template.HTML, error) {
+	if !engine.SanitizeHTML {
+		return template.HTML(con.String()), nil
+	}
+
+	sanitized, err := booklit.SanitizeHTML(con.String())
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(sanitized), nil
 }
 
 func (engine *HTMLRenderingEngine) LoadTemplates(templatesDir string) error {
@@ -119,6 +184,7 @@ func (engine *HTMLRenderingEngine) LoadTemplates(templatesDir string) error {
 	}
 
 	engine.resetTmpl()
+	engine.tmplSources = nil
 
 	for _, path := range templates {
 		content, err := ioutil.ReadFile(path)
@@ -126,27 +192,70 @@ func (engine *HTMLRenderingEngine) LoadTemplates(templatesDir string) error {
 			return err
 		}
 
-		trimmed := strings.TrimRight(string(content), "\n")
+		source := tmplSource{
+			Name:    filepath.Base(path),
+			Content: strings.TrimRight(string(content), "\n"),
+		}
 
-		_, err = engine.tmpl.New(filepath.Base(path)).Parse(trimmed)
+		_, err = engine.tmpl.New(source.Name).Parse(source.Content)
 		if err != nil {
 			return err
 		}
+
+		engine.tmplSources = append(engine.tmplSources, source)
 	}
 
 	return nil
 }
 
+// Fork returns a copy of the engine with the same templates loaded, which
+// can render pages at the same time as the original.
+func (engine *HTMLRenderingEngine) Fork() (RenderingEngine, error) {
+	fork := &HTMLRenderingEngine{
+		SanitizeHTML: engine.SanitizeHTML,
+
+		name:          engine.name,
+		fileExtension: engine.fileExtension,
+
+		pageLinks: engine.pageLinks,
+
+		baseTmpl:     engine.baseTmpl,
+		tmplModTimes: map[string]time.Time{},
+		tmplSources:  engine.tmplSources,
+	}
+
+	fork.resetTmpl()
+
+	for _, source := range fork.tmplSources {
+		_, err := fork.tmpl.New(source.Name).Parse(source.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fork, nil
+}
+
 func (engine *HTMLRenderingEngine) FileExtension() string {
-	return "html"
+	return engine.fileExtension
+}
+
+func (engine *HTMLRenderingEngine) EngineName() string {
+	return engine.name
+}
+
+func (engine *HTMLRenderingEngine) HasTemplate(name string) bool {
+	return engine.tmpl.Lookup(name+".tmpl") != nil
 }
 
 func (engine *HTMLRenderingEngine) URL(tag booklit.Tag) string {
-	return sectionURL(engine.FileExtension(), tag.Section, tag.Anchor)
+	return LinkURL(engine.FileExtension(), engine.page, tag)
 }
 
 func (engine *HTMLRenderingEngine) RenderSection(out io.Writer, con *booklit.Section) error {
 	engine.data = con
+	engine.page = con
+	engine.contexts = []*RenderContext{newRenderContext(con)}
 
 	try := []string{}
 
@@ -170,6 +279,23 @@ func (engine *HTMLRenderingEngine) RenderSection(out io.Writer, con *booklit.Sec
 	return engine.render(out)
 }
 
+func (engine *HTMLRenderingEngine) RenderFragment(out io.Writer, tag booklit.Tag) error {
+	content, err := FragmentContent(tag)
+	if err != nil {
+		return err
+	}
+
+	engine.page = PageOwner(tag.Section)
+	engine.contexts = []*RenderContext{newRenderContext(content)}
+
+	err = content.Visit(engine)
+	if err != nil {
+		return err
+	}
+
+	return engine.render(out)
+}
+
 func (engine *HTMLRenderingEngine) VisitString(con booklit.String) error {
 	engine.data = con
 	return engine.setTmpl("string")
@@ -245,6 +371,26 @@ func (engine *HTMLRenderingEngine) VisitDefinitions(con booklit.Definitions) err
 	return engine.setTmpl("definitions")
 }
 
+func (engine *HTMLRenderingEngine) VisitFigure(con *booklit.Figure) error {
+	engine.data = con
+	return engine.setTmpl("figure")
+}
+
+func (engine *HTMLRenderingEngine) VisitFootnote(con *booklit.Footnote) error {
+	engine.data = con
+	return engine.setTmpl("footnote")
+}
+
+func (engine *HTMLRenderingEngine) VisitListOfFigures(con booklit.ListOfFigures) error {
+	engine.data = con.Section
+	return engine.setTmpl("list-of-figures")
+}
+
+func (engine *HTMLRenderingEngine) VisitAttributions(con booklit.Attributions) error {
+	engine.data = con.Section
+	return engine.setTmpl("attributions")
+}
+
 func (engine *HTMLRenderingEngine) setTmpl(name string) error {
 	tmpl := engine.tmpl.Lookup(name + ".tmpl")
 
@@ -262,24 +408,91 @@ func (engine *HTMLRenderingEngine) render(out io.Writer) error {
 		return fmt.Errorf("unknown template for '%s' (%T)", engine.data, engine.data)
 	}
 
-	return engine.template.Execute(out, engine.data)
+	err := engine.template.Execute(out, engine.data)
+	if err != nil {
+		return traceTemplate(engine.template.Name(), engine.data, err)
+	}
+
+	return nil
 }
 
 func (engine *HTMLRenderingEngine) subRender(content booklit.Content) (template.HTML, error) {
 	buf := new(bytes.Buffer)
 
-	subEngine := NewHTMLRenderingEngine()
-	subEngine.tmpl = engine.tmpl
+	subEngine := &HTMLRenderingEngine{
+		name:          engine.name,
+		fileExtension: engine.fileExtension,
+
+		baseTmpl: engine.baseTmpl,
+		tmpl:     engine.tmpl,
+	}
+
+	context := engine.pushContext(content)
+	defer engine.popContext()
 
 	err := content.Visit(subEngine)
 	if err != nil {
 		return "", err
 	}
 
+	if subEngine.template != nil {
+		name := strings.TrimSuffix(subEngine.template.Name(), ".tmpl") + "-context.tmpl"
+		if tmpl := engine.tmpl.Lookup(name); tmpl != nil {
+			subEngine.template = tmpl
+			subEngine.data = context
+		}
+	}
+
 	err = subEngine.render(buf)
 	if err != nil {
-		return "", err
+		return "", tracePartial(engine.data, content, err)
 	}
 
 	return template.HTML(buf.String()), nil
 }
+
+// pushContext notes that the content is being rendered by the template of
+// the content being rendered, returning its context.
+func (engine *HTMLRenderingEngine) pushContext(content booklit.Content) *RenderContext {
+	var context *RenderContext
+	if len(engine.contexts) == 0 {
+		context = newRenderContext(content)
+	} else {
+		context = engine.contexts[len(engine.contexts)-1].child(content)
+	}
+
+	engine.contexts = append(engine.contexts, context)
+
+	return context
+}
+
+func (engine *HTMLRenderingEngine) popContext() {
+	engine.contexts = engine.contexts[:len(engine.contexts)-1]
+}
+
+// sectionAttrs returns the attributes for the element wrapping the section,
+// if it sets its direction, classes, or data attributes.
+func sectionAttrs(section *booklit.Section) template.HTMLAttr {
+	attrs := []string{}
+
+	if section.Direction != "" {
+		attrs = append(attrs, fmt.Sprintf(`dir="%s"`, template.HTMLEscapeString(string(section.Direction))))
+	}
+
+	if len(section.Classes) > 0 {
+		attrs = append(attrs, fmt.Sprintf(`class="%s"`, template.HTMLEscapeString(strings.Join(section.Classes, " "))))
+	}
+
+	names := []string{}
+	for name := range section.DataAttributes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		attrs = append(attrs, fmt.Sprintf(`data-%s="%s"`, name, template.HTMLEscapeString(section.DataAttributes[name])))
+	}
+
+	return template.HTMLAttr(strings.Join(attrs, " "))
+}
@@ -0,0 +1,326 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/latex"
+)
+
+// DefaultLaTeXDocumentClass is the document class of books rendered with
+// LaTeX unless configured otherwise.
+const DefaultLaTeXDocumentClass = "book"
+
+// LaTeXRenderingEngine renders a section and all of its children as a
+// single LaTeX document, e.g. for typesetting with pdflatex.
+type LaTeXRenderingEngine struct {
+	*TextRenderingEngine
+
+	// Document class of the book, e.g. article. Defaults to
+	// DefaultLaTeXDocumentClass.
+	DocumentClass string
+
+	// Options for the document class, e.g. a4paper,11pt.
+	ClassOptions string
+
+	// LaTeX to include in the preamble after the packages the templates
+	// depend on, e.g. \usepackage commands or \hypersetup.
+	Preamble string
+
+	// If set, chapters which are unchanged since they were saved in the
+	// cache are not rendered again.
+	Chapters *ChapterCache
+
+	// digest of the book's structure, computed by RenderSection for the
+	// cache
+	structure string
+}
+
+// NewLaTeXRenderingEngine constructs an engine which renders a LaTeX
+// document. It is based on the plain text engine, overriding its templates
+// to generate LaTeX commands. References are rendered as links to a \label
+// for each section and target, along with their number via \ref, and asides
+// are rendered as footnotes.
+func NewLaTeXRenderingEngine() *LaTeXRenderingEngine {
+	engine := &LaTeXRenderingEngine{}
+
+	base := template.Must(initTextTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"escape":     latexEscape,
+		"label":      latexLabel,
+		"ref":        latexRef,
+		"href":       latexHref,
+		"sectioning": engine.sectioning,
+		"chapter":    engine.renderChapter,
+		"columns":    latexColumns,
+		"trim":       strings.TrimSpace,
+
+		"documentClass": func() string {
+			if engine.DocumentClass == "" {
+				return DefaultLaTeXDocumentClass
+			}
+
+			return engine.DocumentClass
+		},
+
+		"classOptions": func() string {
+			return engine.ClassOptions
+		},
+
+		"preamble": func() string {
+			return strings.TrimSpace(engine.Preamble)
+		},
+
+		"verbatim": func(content booklit.Content) string {
+			return strings.Trim(content.String(), "\n")
+		},
+	})
+
+	for _, asset := range latex.AssetNames() {
+		info, err := latex.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(latex.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine.TextRenderingEngine = &TextRenderingEngine{
+		name:          "latex",
+		fileExtension: "tex",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return engine
+}
+
+func (engine *LaTeXRenderingEngine) RendersDocument() {}
+
+// RenderSection renders the document, collapsing the blank lines which
+// templates easily leave behind.
+func (engine *LaTeXRenderingEngine) RenderSection(out io.Writer, con *booklit.Section) error {
+	if engine.Chapters != nil {
+		engine.structure = structureDigest(engine, con.Top())
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := engine.TextRenderingEngine.RenderSection(buf, con)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(out, latexTidy(buf.String()))
+	return err
+}
+
+func (engine *LaTeXRenderingEngine) URL(tag booklit.Tag) string {
+	return "#" + latexTagLabel(tag)
+}
+
+// renderChapter renders the chapter, or reuses what it was rendered to from
+// the cache.
+func (engine *LaTeXRenderingEngine) renderChapter(chapter *booklit.Section) (string, error) {
+	if engine.Chapters == nil {
+		return engine.subRender(chapter)
+	}
+
+	digest, err := engine.Chapters.digest(engine, engine.structure, chapter, chapterSections(chapter))
+	if err != nil {
+		return "", err
+	}
+
+	artifact, found, err := engine.Chapters.fetch(digest)
+	if err != nil {
+		return "", err
+	}
+
+	if found {
+		return string(artifact.Content), nil
+	}
+
+	rendered, err := engine.subRender(chapter)
+	if err != nil {
+		return "", err
+	}
+
+	err = engine.Chapters.store(digest, chapterArtifact{
+		Content: []byte(rendered),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rendered, nil
+}
+
+// sectioning returns the command for the section's heading, starting from
+// \chapter for document classes which have chapters, and \section
+// otherwise.
+func (engine *LaTeXRenderingEngine) sectioning(section *booklit.Section) string {
+	commands := []string{"section", "subsection", "subsubsection", "paragraph", "subparagraph"}
+
+	class := engine.DocumentClass
+	if class == "" {
+		class = DefaultLaTeXDocumentClass
+	}
+
+	switch class {
+	case "book", "report", "memoir", "scrbook", "scrreprt":
+		commands = append([]string{"chapter"}, commands...)
+	}
+
+	depth := section.Depth() - 1
+	if depth < 0 {
+		depth = 0
+	} else if depth >= len(commands) {
+		depth = len(commands) - 1
+	}
+
+	return commands[depth]
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+	booklit.NonBreakingSpace, "~",
+	booklit.ThinSpace, `\,`,
+	booklit.WordBreak, `\hspace{0pt}`,
+)
+
+func latexEscape(str string) string {
+	return latexEscaper.Replace(str)
+}
+
+// latexLabel returns the name of the label for a tag, which may only
+// contain characters which are safe in \label and \ref.
+func latexLabel(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-', r == ':', r == '.':
+			return r
+		}
+
+		return '-'
+	}, name)
+}
+
+func latexTagLabel(tag booklit.Tag) string {
+	if tag.Anchor != "" {
+		return latexLabel(tag.Anchor)
+	}
+
+	return latexLabel(tag.Section.PrimaryTag.Name)
+}
+
+// latexRef links to the tag's label with the display text, followed by the
+// number of the section it refers to, if any, so that references can be
+// followed in print.
+func latexRef(tag booklit.Tag, display string) string {
+	label := latexTagLabel(tag)
+
+	link := `\hyperref[` + label + `]{` + strings.TrimSpace(display) + `}`
+
+	if tag.Anchor == "" && tag.Section.Number() != "" && tag.Section.FrontMatter == "" {
+		return link + ` (\S\ref{` + label + `})`
+	}
+
+	return link
+}
+
+// latexHref links to the URL, escaping the characters \href treats
+// specially.
+func latexHref(target string, content string) string {
+	url := strings.NewReplacer(`\`, `\\`, "#", `\#`, "%", `\%`, "{", `\{`, "}", `\}`).Replace(target)
+	return `\href{` + url + `}{` + strings.TrimSpace(content) + `}`
+}
+
+// latexColumns returns the tabular column specification for the table,
+// aligning each column as configured, and to the left otherwise.
+func latexColumns(table booklit.Table) string {
+	columns := table.Columns()
+	if columns == 0 {
+		return "l"
+	}
+
+	spec := "|"
+	for i := 0; i < columns; i++ {
+		spec += alignmentKey(table.Alignment(i)) + "|"
+	}
+
+	return spec
+}
+
+// alignmentKey returns the letter for the alignment in LaTeX column
+// specifications and tbl formats, which share them.
+func alignmentKey(alignment booklit.Alignment) string {
+	switch alignment {
+	case booklit.AlignCenter:
+		return "c"
+	case booklit.AlignRight:
+		return "r"
+	default:
+		return "l"
+	}
+}
+
+// latexTidy collapses consecutive blank lines outside of verbatim
+// environments, and ends the text with a newline.
+func latexTidy(text string) string {
+	out := []string{}
+
+	env := ""
+	blank := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if env != "" {
+			if line == `\end{`+env+`}` {
+				env = ""
+			}
+
+			out = append(out, line)
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				out = append(out, "")
+			}
+
+			blank = true
+			continue
+		}
+
+		for _, verbatim := range []string{"verbatim", "alltt"} {
+			if line == `\begin{`+verbatim+`}` {
+				env = verbatim
+			}
+		}
+
+		blank = false
+		out = append(out, strings.TrimRight(line, " \t"))
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
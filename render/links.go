@@ -0,0 +1,492 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vito/booklit"
+)
+
+// LinkChecker checks the targets of every link in a book: links to its own
+// pages and their anchors, as they'd be rendered by an engine with the given
+// file extension, and optionally external URLs.
+//
+// Relative links to anything other than a page, e.g. an asset, are checked
+// against the files in the given directories, if any.
+type LinkChecker struct {
+	// file extension of the engine's pages, e.g. html
+	FileExtension string
+
+	// directories which files other than pages are linked to relative to,
+	// e.g. the destination and asset directories
+	Directories []string
+
+	// check http and https URLs by requesting them
+	External bool
+
+	// client external URLs are requested with, e.g. with a timeout; defaults
+	// to http.DefaultClient
+	Client *http.Client
+
+	// number of external URLs to request at a time; defaults to 1
+	Jobs int
+}
+
+// checkedLink is a link found in a book, along with the section it was found
+// in.
+type checkedLink struct {
+	booklit.Link
+
+	section *booklit.Section
+}
+
+// CheckLinks returns a BuildErrors with a BrokenLinkError for each broken
+// link in the book, in the order they appear, or nil if none are broken.
+func (checker LinkChecker) CheckLinks(book *booklit.Section) error {
+	collector := &linkCollector{}
+
+	err := book.Visit(collector)
+	if err != nil {
+		return err
+	}
+
+	pages := map[string]map[string]bool{}
+	checker.collectAnchors(book, pages)
+
+	broken := make([]error, len(collector.links))
+
+	external := []int{}
+	for i, link := range collector.links {
+		target, err := url.Parse(link.Target)
+		if err != nil {
+			broken[i] = brokenLink(link, "invalid URL")
+			continue
+		}
+
+		if target.Scheme == "http" || target.Scheme == "https" {
+			external = append(external, i)
+			continue
+		}
+
+		if target.Scheme != "" || target.Host != "" {
+			// e.g. mailto:
+			continue
+		}
+
+		reason, ok := checker.checkInternal(link, target, pages)
+		if !ok {
+			broken[i] = brokenLink(link, reason)
+		}
+	}
+
+	if checker.External {
+		for i, reason := range checker.checkExternal(collector.links, external) {
+			if reason != "" {
+				broken[i] = brokenLink(collector.links[i], reason)
+			}
+		}
+	}
+
+	errs := &booklit.BuildErrors{}
+	for _, err := range broken {
+		if err != nil {
+			_ = errs.Record(err)
+		}
+	}
+
+	return errs.Err()
+}
+
+func brokenLink(link checkedLink, reason string) error {
+	return booklit.BrokenLinkError{
+		Target: link.Target,
+		Reason: reason,
+		ErrorLocation: booklit.ErrorLocation{
+			FilePath:     link.section.FilePath(),
+			FS:           link.section.Top().FS,
+			NodeLocation: link.Location,
+			Length:       len("\\link"),
+		},
+	}
+}
+
+// collectAnchors records the anchors on each page of the book by the page's
+// path.
+func (checker LinkChecker) collectAnchors(section *booklit.Section, pages map[string]map[string]bool) {
+	page := pagePath(SectionURL(checker.FileExtension, PageOwner(section), ""))
+
+	anchors, found := pages[page]
+	if !found {
+		anchors = map[string]bool{}
+		pages[page] = anchors
+	}
+
+	anchors[section.PrimaryTag.Name] = true
+
+	for _, tag := range section.AnchorTags() {
+		anchors[tag.Anchor] = true
+	}
+
+	for _, child := range section.Children {
+		checker.collectAnchors(child, pages)
+	}
+}
+
+func (checker LinkChecker) checkInternal(link checkedLink, target *url.URL, pages map[string]map[string]bool) (string, bool) {
+	from := pagePath(SectionURL(checker.FileExtension, PageOwner(link.section), ""))
+
+	page := from
+	if target.Path != "" {
+		base := &url.URL{Path: from}
+		page = pagePath(base.ResolveReference(&url.URL{Path: target.Path}).Path)
+	}
+
+	anchors, found := pages[page]
+	if !found {
+		if target.Fragment == "" && checker.fileExists(link.section.Top(), page) {
+			// some other file, e.g. an asset
+			return "", true
+		}
+
+		if target.Fragment != "" || path.Ext(target.Path) == "."+checker.FileExtension {
+			return "no such page", false
+		}
+
+		if len(checker.Directories) > 0 {
+			return "no such file", false
+		}
+
+		return "", true
+	}
+
+	if target.Fragment != "" && !anchors[target.Fragment] {
+		return fmt.Sprintf("no such anchor on %s", page), false
+	}
+
+	return "", true
+}
+
+// fileExists returns true if the file at the path, as linked to from the
+// book's pages, is in one of the directories.
+func (checker LinkChecker) fileExists(top *booklit.Section, path string) bool {
+	rel := strings.TrimPrefix(strings.TrimPrefix(path, "/"), strings.TrimPrefix(top.BasePath, "/"))
+
+	for _, dir := range checker.Directories {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pagePath normalizes a page's URL, which may be relative, into an absolute
+// path.
+func pagePath(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") && p != "/" {
+		p += "/"
+	}
+
+	return p
+}
+
+// checkExternal requests each external URL once, returning the reason each
+// link is broken, if it is, by its index.
+func (checker LinkChecker) checkExternal(links []checkedLink, indexes []int) map[int]string {
+	client := checker.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	jobs := checker.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, i := range indexes {
+		if !seen[links[i].Target] {
+			seen[links[i].Target] = true
+			urls = append(urls, links[i].Target)
+		}
+	}
+
+	sort.Strings(urls)
+
+	reasons := map[string]string{}
+	reasonsL := new(sync.Mutex)
+
+	queue := make(chan string)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for target := range queue {
+				reason := requestLink(client, target)
+
+				reasonsL.Lock()
+				reasons[target] = reason
+				reasonsL.Unlock()
+			}
+		}()
+	}
+
+	for _, target := range urls {
+		queue <- target
+	}
+
+	close(queue)
+
+	wg.Wait()
+
+	broken := map[int]string{}
+	for _, i := range indexes {
+		broken[i] = reasons[links[i].Target]
+	}
+
+	return broken
+}
+
+// requestLink requests the URL, returning why it's broken, if it is. Servers
+// which don't allow HEAD requests are sent a GET instead.
+func requestLink(client *http.Client, target string) string {
+	res, err := client.Head(target)
+	if err == nil && (res.StatusCode == http.StatusMethodNotAllowed || res.StatusCode == http.StatusNotImplemented) {
+		res.Body.Close()
+		res, err = client.Get(target)
+	}
+
+	if err != nil {
+		return err.Error()
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return res.Status
+	}
+
+	return ""
+}
+
+// linkCollector finds every link in a section and its sub-sections.
+type linkCollector struct {
+	section *booklit.Section
+
+	links []checkedLink
+}
+
+func (collector *linkCollector) VisitString(booklit.String) error {
+	return nil
+}
+
+func (collector *linkCollector) VisitSequence(con booklit.Sequence) error {
+	for _, c := range con {
+		err := c.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitParagraph(con booklit.Paragraph) error {
+	for _, c := range con {
+		err := c.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitPreformatted(con booklit.Preformatted) error {
+	for _, c := range con {
+		err := c.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitReference(*booklit.Reference) error {
+	// checked upon resolving
+	return nil
+}
+
+func (collector *linkCollector) VisitSection(con *booklit.Section) error {
+	parent := collector.section
+	defer func() { collector.section = parent }()
+
+	collector.section = con
+
+	err := con.Title.Visit(collector)
+	if err != nil {
+		return err
+	}
+
+	err = con.Body.Visit(collector)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range con.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := con.Partials[name].Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, child := range con.Children {
+		err := child.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitTableOfContents(booklit.TableOfContents) error {
+	return nil
+}
+
+func (collector *linkCollector) VisitStyled(con booklit.Styled) error {
+	err := con.Content.Visit(collector)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range con.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		partial := con.Partials[name]
+		if partial == nil {
+			continue
+		}
+
+		err := partial.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitTarget(con booklit.Target) error {
+	if con.Content == nil {
+		return nil
+	}
+
+	return con.Content.Visit(collector)
+}
+
+func (collector *linkCollector) VisitImage(booklit.Image) error {
+	return nil
+}
+
+func (collector *linkCollector) VisitList(con booklit.List) error {
+	for _, c := range con.Items {
+		err := c.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitLink(con booklit.Link) error {
+	collector.links = append(collector.links, checkedLink{
+		Link:    con,
+		section: collector.section,
+	})
+
+	return con.Content.Visit(collector)
+}
+
+func (collector *linkCollector) VisitTable(con booklit.Table) error {
+	for _, row := range con.Rows {
+		for _, c := range row {
+			err := c.Visit(collector)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitDefinitions(con booklit.Definitions) error {
+	for _, def := range con {
+		err := def.Subject.Visit(collector)
+		if err != nil {
+			return err
+		}
+
+		err = def.Definition.Visit(collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector *linkCollector) VisitFigure(con *booklit.Figure) error {
+	err := con.Content.Visit(collector)
+	if err != nil {
+		return err
+	}
+
+	return con.Caption.Visit(collector)
+}
+
+func (collector *linkCollector) VisitFootnote(con *booklit.Footnote) error {
+	return con.Content.Visit(collector)
+}
+
+func (collector *linkCollector) VisitListOfFigures(booklit.ListOfFigures) error {
+	return nil
+}
+
+func (collector *linkCollector) VisitAttributions(booklit.Attributions) error {
+	return nil
+}
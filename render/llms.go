@@ -0,0 +1,513 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// WriteLLMsBundle writes an llms.txt index along with a Markdown file for
+// each top-level chapter under llms/, suitable for ingestion by language
+// models. Each heading is annotated with its section's tag, so that answers
+// can cite a stable ID.
+func (writer Writer) WriteLLMsBundle(section *booklit.Section) error {
+	logrus.WithFields(logrus.Fields{
+		"path": "llms.txt",
+	}).Infoln("writing llms bundle")
+
+	index := new(bytes.Buffer)
+
+	fmt.Fprintf(index, "# %s\n\n", plainText(section.Title))
+
+	if summary := llmsSummary(section); summary != "" {
+		fmt.Fprintf(index, "> %s\n\n", summary)
+	}
+
+	index.WriteString("## Chapters\n\n")
+
+	chapters := []*booklit.Section{section}
+	if len(section.Children) > 0 {
+		// the book's own content is written separately as an overview, so
+		// that it isn't repeated in every chapter
+		overview := &llmsRenderer{out: new(bytes.Buffer), depth: section.Depth()}
+
+		err := overview.renderSectionHeader(section)
+		if err != nil {
+			return err
+		}
+
+		err = overview.render(section.Body)
+		if err != nil {
+			return err
+		}
+
+		err = writer.writeLLMsFile(section, overview.out.String())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(index, "- [%s](llms/%s.md): Overview\n", plainText(section.Title), section.PrimaryTag.Name)
+
+		chapters = section.Children
+	}
+
+	for _, chapter := range chapters {
+		renderer := &llmsRenderer{out: new(bytes.Buffer), depth: chapter.Depth()}
+
+		err := renderer.render(chapter)
+		if err != nil {
+			return err
+		}
+
+		err = writer.writeLLMsFile(chapter, renderer.out.String())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(index, "- [%s](llms/%s.md)", plainText(chapter.Title), chapter.PrimaryTag.Name)
+
+		if summary := llmsSummary(chapter); summary != "" {
+			fmt.Fprintf(index, ": %s", summary)
+		}
+
+		index.WriteString("\n")
+	}
+
+	return writer.writeFile("llms.txt", index.Bytes())
+}
+
+func (writer Writer) writeLLMsFile(section *booklit.Section, content string) error {
+	return writer.writeFile("llms/"+section.PrimaryTag.Name+".md", []byte(strings.TrimSpace(content)+"\n"))
+}
+
+// llmsSummary returns the first paragraph of the section's body as a single
+// line.
+func llmsSummary(section *booklit.Section) string {
+	var summary string
+	for _, para := range paragraphs(section.Body) {
+		summary = strings.Join(strings.Fields(plainText(para)), " ")
+		if summary != "" {
+			break
+		}
+	}
+
+	return summary
+}
+
+func paragraphs(content booklit.Content) []booklit.Content {
+	switch con := content.(type) {
+	case booklit.Paragraph:
+		return []booklit.Content{con}
+	case booklit.Sequence:
+		paras := []booklit.Content{}
+		for _, c := range con {
+			paras = append(paras, paragraphs(c)...)
+		}
+
+		return paras
+	default:
+		return nil
+	}
+}
+
+func plainText(content booklit.Content) string {
+	return strings.TrimSpace(booklit.StripAux(content).String())
+}
+
+// llmsRenderer renders content as Markdown, including every sub-section
+// regardless of how they are split into pages.
+type llmsRenderer struct {
+	out *bytes.Buffer
+
+	// depth of the chapter being rendered, used for heading levels
+	depth int
+}
+
+func (renderer *llmsRenderer) render(content booklit.Content) error {
+	return content.Visit(renderer)
+}
+
+// sub renders content to a string with a fresh renderer.
+func (renderer *llmsRenderer) sub(content booklit.Content) (string, error) {
+	buf := new(bytes.Buffer)
+
+	err := content.Visit(&llmsRenderer{out: buf, depth: renderer.depth})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func (renderer *llmsRenderer) block(str string) {
+	if renderer.out.Len() > 0 && !strings.HasSuffix(renderer.out.String(), "\n\n") {
+		if strings.HasSuffix(renderer.out.String(), "\n") {
+			renderer.out.WriteString("\n")
+		} else {
+			renderer.out.WriteString("\n\n")
+		}
+	}
+
+	renderer.out.WriteString(str)
+	renderer.out.WriteString("\n\n")
+}
+
+func (renderer *llmsRenderer) renderSectionHeader(con *booklit.Section) error {
+	level := con.Depth() - renderer.depth + 1
+	if level > 6 {
+		level = 6
+	}
+
+	title, err := renderer.sub(booklit.StripAux(con.Title))
+	if err != nil {
+		return err
+	}
+
+	if con.Number() != "" {
+		title = con.Number() + " " + title
+	}
+
+	renderer.block(fmt.Sprintf("%s %s {#%s}", strings.Repeat("#", level), title, con.PrimaryTag.Name))
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitString(con booklit.String) error {
+	renderer.out.WriteString(string(con))
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitSequence(con booklit.Sequence) error {
+	for _, c := range con {
+		err := c.Visit(renderer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitReference(con *booklit.Reference) error {
+	display, err := renderer.sub(booklit.StripAux(con.Display()))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(renderer.out, "%s [#%s]", display, con.Tag.Name)
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitLink(con booklit.Link) error {
+	text, err := renderer.sub(con.Content)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(renderer.out, "[%s](%s)", text, con.Target)
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitSection(con *booklit.Section) error {
+	err := renderer.renderSectionHeader(con)
+	if err != nil {
+		return err
+	}
+
+	err = con.Body.Visit(renderer)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range con.Children {
+		err := child.Visit(renderer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitParagraph(con booklit.Paragraph) error {
+	lines := []string{}
+	for _, line := range con {
+		str, err := renderer.sub(line)
+		if err != nil {
+			return err
+		}
+
+		lines = append(lines, str)
+	}
+
+	renderer.block(strings.Join(lines, " "))
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitTableOfContents(booklit.TableOfContents) error {
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitPreformatted(con booklit.Preformatted) error {
+	lines := []string{}
+	for _, line := range con {
+		buf := new(bytes.Buffer)
+
+		err := line.Visit(&llmsRenderer{out: buf, depth: renderer.depth})
+		if err != nil {
+			return err
+		}
+
+		lines = append(lines, buf.String())
+	}
+
+	renderer.block("```\n" + strings.Join(lines, "\n") + "\n```")
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitStyled(con booklit.Styled) error {
+	if con.Style == booklit.StyleVerbatim && !con.IsFlow() {
+		renderer.block("```\n" + strings.Trim(con.Content.String(), "\n") + "\n```")
+		return nil
+	}
+
+	if con.Style == booklit.StylePlayground {
+		// the code as a code block, tagged with its language
+		renderer.block("```" + con.Partial("Language").String() + "\n" + strings.Trim(con.Content.String(), "\n") + "\n```")
+		return nil
+	}
+
+	if con.Style == booklit.StyleRuby {
+		base, err := renderer.sub(con.Content)
+		if err != nil {
+			return err
+		}
+
+		reading, err := renderer.sub(con.Partial("Reading"))
+		if err != nil {
+			return err
+		}
+
+		renderer.out.WriteString(base + "(" + reading + ")")
+		return nil
+	}
+
+	var wrap string
+	switch con.Style {
+	case booklit.StyleVerbatim:
+		wrap = "`"
+	case booklit.StyleBold:
+		wrap = "**"
+	case booklit.StyleItalic:
+		wrap = "_"
+	case booklit.StyleStrike:
+		wrap = "~~"
+	}
+
+	if wrap != "" {
+		str, err := renderer.sub(con.Content)
+		if err != nil {
+			return err
+		}
+
+		renderer.out.WriteString(wrap + str + wrap)
+
+		return nil
+	}
+
+	if !con.IsFlow() {
+		str, err := renderer.sub(con.Content)
+		if err != nil {
+			return err
+		}
+
+		switch con.Style {
+		case booklit.StyleInset, booklit.StyleAside, booklit.StylePullQuote, booklit.StyleEpigraph:
+			str = "> " + strings.Replace(str, "\n", "\n> ", -1)
+		}
+
+		renderer.block(str)
+	} else {
+		err := con.Content.Visit(renderer)
+		if err != nil {
+			return err
+		}
+	}
+
+	// partials are often meaningful text, e.g. an epigraph's attribution, so
+	// include them in order of name
+	names := []string{}
+	for name := range con.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		str, err := renderer.sub(con.Partials[name])
+		if err != nil {
+			return err
+		}
+
+		if str == "" {
+			continue
+		}
+
+		if con.IsFlow() {
+			renderer.out.WriteString(" " + str)
+		} else {
+			renderer.block(str)
+		}
+	}
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitTarget(booklit.Target) error {
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitImage(con booklit.Image) error {
+	fmt.Fprintf(renderer.out, "![%s](%s)", con.Description, con.Path)
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitList(con booklit.List) error {
+	items := []string{}
+	for i, item := range con.Items {
+		str, err := renderer.sub(item)
+		if err != nil {
+			return err
+		}
+
+		marker := "- "
+		if con.Ordered {
+			marker = fmt.Sprintf("%d. ", i+1)
+		}
+
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.Replace(str, "\n", "\n"+indent, -1))
+	}
+
+	renderer.block(strings.Join(items, "\n"))
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitTable(con booklit.Table) error {
+	rows := []string{}
+	for i, row := range con.Rows {
+		cells := []string{}
+		for _, cell := range row {
+			str, err := renderer.sub(cell)
+			if err != nil {
+				return err
+			}
+
+			cells = append(cells, strings.Join(strings.Fields(str), " "))
+		}
+
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+
+		if i == 0 {
+			rows = append(rows, markdownDelimiterRow(con))
+		}
+	}
+
+	renderer.block(strings.Join(rows, "\n"))
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitDefinitions(con booklit.Definitions) error {
+	defs := []string{}
+	for _, def := range con {
+		subject, err := renderer.sub(def.Subject)
+		if err != nil {
+			return err
+		}
+
+		definition, err := renderer.sub(def.Definition)
+		if err != nil {
+			return err
+		}
+
+		defs = append(defs, "- **"+subject+"**: "+strings.Replace(definition, "\n", "\n  ", -1))
+	}
+
+	renderer.block(strings.Join(defs, "\n"))
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitFigure(con *booklit.Figure) error {
+	err := con.Content.Visit(renderer)
+	if err != nil {
+		return err
+	}
+
+	caption, err := renderer.sub(con.Caption)
+	if err != nil {
+		return err
+	}
+
+	renderer.block(fmt.Sprintf("_%s: %s_", con.Title(), caption))
+
+	return nil
+}
+
+// footnotes are rendered inline, as each chapter is written to its own file
+// apart from wherever the footnote is collected
+func (renderer *llmsRenderer) VisitFootnote(con *booklit.Footnote) error {
+	content, err := renderer.sub(con.Content)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(renderer.out, "^[%s]", strings.Join(strings.Fields(content), " "))
+
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitListOfFigures(booklit.ListOfFigures) error {
+	return nil
+}
+
+func (renderer *llmsRenderer) VisitAttributions(con booklit.Attributions) error {
+	items := []string{}
+	for _, attr := range con.Section.AllAttributions() {
+		item, err := renderer.sub(booklit.StripAux(attr.Subject))
+		if err != nil {
+			return err
+		}
+
+		if attr.Credit != nil {
+			credit, err := renderer.sub(attr.Credit)
+			if err != nil {
+				return err
+			}
+
+			item += ": " + credit
+		}
+
+		if attr.License != "" {
+			item += " (" + attr.License + ")"
+		}
+
+		items = append(items, "- "+item)
+	}
+
+	if len(items) > 0 {
+		renderer.block(strings.Join(items, "\n"))
+	}
+
+	return nil
+}
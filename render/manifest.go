@@ -0,0 +1,212 @@
+package render
+
+import (
+	"encoding/json"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// Manifest maps each tag name to the URL it was rendered at.
+type Manifest map[string]string
+
+// LoadManifest reads a manifest previously written by WriteManifest.
+func LoadManifest(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	manifest := Manifest{}
+	err = json.NewDecoder(file).Decode(&manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (writer Writer) Manifest(section *booklit.Section) Manifest {
+	manifest := Manifest{}
+	writer.loadManifest(manifest, section)
+	return manifest
+}
+
+func (writer Writer) WriteManifest(section *booklit.Section, path string) error {
+	logrus.WithFields(logrus.Fields{
+		"path": path,
+	}).Infoln("writing manifest")
+
+	payload, err := json.Marshal(writer.Manifest(section))
+	if err != nil {
+		return err
+	}
+
+	return writer.writeFile(path, append(payload, '\n'))
+}
+
+// WriteRedirects generates stub pages for any pages in the previous manifest
+// which are no longer rendered, redirecting each of their anchors to wherever
+// the tag now lives. The previous manifest may be nil.
+//
+// The pages which the aliases of sections would have named are redirected
+// too, unless the previous manifest says otherwise.
+func (writer Writer) WriteRedirects(section *booklit.Section, previous Manifest) error {
+	current := writer.Manifest(section)
+
+	aliases := writer.AliasManifest(section)
+	if len(aliases) > 0 {
+		merged := Manifest{}
+		for tag, url := range aliases {
+			merged[tag] = url
+		}
+
+		for tag, url := range previous {
+			merged[tag] = url
+		}
+
+		previous = merged
+	}
+
+	pages := map[string]bool{}
+	for _, url := range current {
+		page, _ := splitURL(url)
+		pages[page] = true
+	}
+
+	stubs := map[string]*redirectStub{}
+	for tag, oldURL := range previous {
+		newURL, found := current[tag]
+		if !found || newURL == oldURL {
+			continue
+		}
+
+		page, anchor := splitURL(oldURL)
+		if pages[page] {
+			logrus.WithFields(logrus.Fields{
+				"tag": tag,
+				"was": oldURL,
+				"now": newURL,
+			}).Warn("tag moved off of a page which still exists; not redirecting")
+			continue
+		}
+
+		stub, found := stubs[page]
+		if !found {
+			stub = &redirectStub{Anchors: map[string]string{}}
+			stubs[page] = stub
+		}
+
+		if anchor == "" {
+			stub.Default = newURL
+		} else {
+			stub.Anchors[anchor] = newURL
+		}
+	}
+
+	for page, stub := range stubs {
+		if stub.Default == "" {
+			for _, url := range stub.Anchors {
+				stub.Default, _ = splitURL(url)
+				break
+			}
+		}
+
+		name := urlPagePath(section, writer.Engine.FileExtension(), page)
+
+		logrus.WithFields(logrus.Fields{
+			"rendered": filepath.Join(writer.Destination, name),
+			"target":   stub.Default,
+		}).Debug("writing redirect")
+
+		file, err := writer.output().Create(filepath.ToSlash(name))
+		if err != nil {
+			return err
+		}
+
+		err = redirectTmpl.Execute(file, stub)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		err = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AliasManifest maps the aliases of each section with a page of its own to
+// the URL of the page the alias would have named, e.g. before the section was
+// renamed.
+//
+// Aliases of sections within another section's page are left out, as they're
+// rendered as anchors alongside the section's own.
+func (writer Writer) AliasManifest(section *booklit.Section) Manifest {
+	manifest := Manifest{}
+	writer.loadAliases(manifest, section)
+	return manifest
+}
+
+func (writer Writer) loadAliases(manifest Manifest, section *booklit.Section) {
+	if PageOwner(section) == section {
+		for _, alias := range section.Aliases {
+			manifest[alias] = pageURL(writer.Engine.FileExtension(), section.Top(), section.PermalinkAs(alias), "")
+		}
+	}
+
+	for _, child := range section.Children {
+		writer.loadAliases(manifest, child)
+	}
+}
+
+func (writer Writer) loadManifest(manifest Manifest, section *booklit.Section) {
+	for _, tag := range section.Tags {
+		manifest[tag.Name] = writer.Engine.URL(tag)
+	}
+
+	for _, child := range section.Children {
+		writer.loadManifest(manifest, child)
+	}
+}
+
+type redirectStub struct {
+	Default string
+	Anchors map[string]string
+}
+
+func splitURL(url string) (string, string) {
+	segs := strings.SplitN(url, "#", 2)
+	if len(segs) == 1 {
+		return segs[0], ""
+	}
+
+	return segs[0], segs[1]
+}
+
+var redirectTmpl = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="0; url={{.Default}}">
+<link rel="canonical" href="{{.Default}}">
+<script>
+var anchors = {{.Anchors}};
+var target = anchors[window.location.hash.slice(1)];
+window.location.replace(target || {{.Default}});
+</script>
+</head>
+<body>
+<p>This page has moved to <a href="{{.Default}}">{{.Default}}</a>.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,276 @@
+package render
+
+import (
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/manpage"
+)
+
+// ManpageRenderingEngine renders each page as a man page in troff, named
+// after the page's \man-name and \man-section, e.g. booklit.1.
+type ManpageRenderingEngine struct {
+	*TextRenderingEngine
+}
+
+// NewManpageRenderingEngine constructs an engine which renders man pages,
+// suitable for man(1). It is based on the plain text engine, overriding its
+// templates to generate man macros, with tables for tbl(1).
+func NewManpageRenderingEngine() *ManpageRenderingEngine {
+	base := template.Must(initTextTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"escape":     manpageEscape,
+		"arg":        manpageArg,
+		"heading":    manpageHeading,
+		"codeBlock":  manpageCodeBlock,
+		"item":       manpageItem,
+		"cell":       manpageCell,
+		"columns":    manpageColumns,
+		"tidy":       manpageTidy,
+		"manName":    manpageName,
+		"manSection": manpageSection,
+		"upper":      strings.ToUpper,
+
+		"plain": func(content booklit.Content) string {
+			return booklit.StripAux(content).String()
+		},
+
+		"isPage": writesPage,
+
+		"inc": func(i int) int {
+			return i + 1
+		},
+	})
+
+	for _, asset := range manpage.AssetNames() {
+		info, err := manpage.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(manpage.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine := &TextRenderingEngine{
+		name:          "manpage",
+		fileExtension: "man",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return &ManpageRenderingEngine{engine}
+}
+
+// Fork returns a copy of the engine with the same templates loaded.
+func (engine *ManpageRenderingEngine) Fork() (RenderingEngine, error) {
+	fork, err := engine.TextRenderingEngine.Fork()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ManpageRenderingEngine{fork.(*TextRenderingEngine)}, nil
+}
+
+// PagePath names the page after its man page, e.g. booklit.1.
+func (engine *ManpageRenderingEngine) PagePath(section *booklit.Section) string {
+	return manpageName(section) + "." + manpageSection(section)
+}
+
+// URL returns the file name of the tag's man page; man pages cannot link to
+// anchors within them.
+func (engine *ManpageRenderingEngine) URL(tag booklit.Tag) string {
+	return engine.PagePath(PageOwner(tag.Section))
+}
+
+// manpageName returns the name of the man page of the section's page, which
+// is its \man-name or otherwise its tag.
+func manpageName(section *booklit.Section) string {
+	owner := PageOwner(section)
+
+	if name := owner.Partial("ManName"); name != nil {
+		return name.String()
+	}
+
+	return owner.PrimaryTag.Name
+}
+
+// manpageSection returns the manual section of the section's page, which is
+// its \man-section or that of its nearest parent, defaulting to 1.
+func manpageSection(section *booklit.Section) string {
+	for sec := PageOwner(section); sec != nil; sec = sec.Parent {
+		if man := sec.Partial("ManSection"); man != nil {
+			return man.String()
+		}
+	}
+
+	return "1"
+}
+
+var manpageEscaper = strings.NewReplacer(
+	`\`, `\e`,
+	"-", `\-`,
+	"\n.", "\n\\&.",
+	"\n'", "\n\\&'",
+	booklit.NonBreakingSpace, `\~`,
+	booklit.ThinSpace, `\|`,
+	booklit.WordBreak, `\:`,
+)
+
+// manpageEscape escapes backslashes and hyphens, and any period or
+// apostrophe at the start of a line which would otherwise be read as a
+// request.
+func manpageEscape(text string) string {
+	text = manpageEscaper.Replace(text)
+
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+
+	return text
+}
+
+// manpageArg quotes the text as an argument to a macro.
+func manpageArg(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return `"` + strings.Replace(manpageEscape(text), `"`, `\(dq`, -1) + `"`
+}
+
+// manpageHeading returns the macro introducing the section in its page:
+// .SH for the page's sections, .SS for theirs, and a bold paragraph for any
+// further sections, which man pages have no macros for.
+func manpageHeading(section *booklit.Section) string {
+	title := booklit.StripAux(section.Title).String()
+
+	switch section.Depth() - PageOwner(section).Depth() {
+	case 1:
+		return ".SH " + manpageArg(strings.ToUpper(title))
+	case 2:
+		return ".SS " + manpageArg(title)
+	default:
+		return ".PP\n\\fB" + manpageEscape(title) + `\fP`
+	}
+}
+
+// manpageCodeBlock renders the content verbatim, indented and without
+// filling.
+func manpageCodeBlock(content booklit.Content) string {
+	lines := strings.Split(strings.Trim(content.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = manpageEscape(line)
+	}
+
+	return ".PP\n.RS 4\n.nf\n" + strings.Join(lines, "\n") + "\n.fi\n.RE"
+}
+
+// manpageItem adapts the paragraphs of a list item or definition to follow
+// its .IP or .TP: the first paragraph continues the item, and the rest are
+// indented with it. Nested lists are indented further.
+func manpageItem(text string) string {
+	text = strings.TrimPrefix(strings.TrimSpace(text), ".PP\n")
+
+	out := []string{}
+	nested := false
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, ".IP ") || strings.HasPrefix(line, ".TP"):
+			if !nested {
+				out = append(out, ".RS 4")
+				nested = true
+			}
+		case line == ".PP":
+			if nested {
+				out = append(out, ".RE")
+				nested = false
+			}
+
+			line = ".IP"
+		}
+
+		out = append(out, line)
+	}
+
+	if nested {
+		out = append(out, ".RE")
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// manpageCell collapses the text onto a single line, as tbl separates rows
+// by line and cells by tab.
+func manpageCell(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, ".") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+}
+
+// manpageColumns returns the tbl format of a row of the table, with one key
+// letter per column for its alignment, followed by the modifier, e.g. b for
+// bold.
+func manpageColumns(table booklit.Table, modifier string) string {
+	keys := make([]string, table.Columns())
+	for i := range keys {
+		keys[i] = alignmentKey(table.Alignment(i)) + modifier
+	}
+
+	return strings.Join(keys, " ")
+}
+
+// manpageTidy removes the blank lines and leading whitespace which templates
+// easily leave behind, as troff would render them, except in unfilled text
+// such as code blocks, and ends the text with a newline.
+func manpageTidy(text string) string {
+	out := []string{}
+
+	unfilled := false
+	for _, line := range strings.Split(text, "\n") {
+		if unfilled {
+			if line == ".fi" {
+				unfilled = false
+			}
+
+			out = append(out, line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		// text indented by the template, rather than a request
+		if line[0] != trimmed[0] && (trimmed[0] == '.' || trimmed[0] == '\'') {
+			trimmed = `\&` + trimmed
+		}
+
+		line = trimmed
+
+		if line == ".nf" {
+			unfilled = true
+		}
+
+		out = append(out, line)
+	}
+
+	if len(out) == 0 {
+		return ""
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
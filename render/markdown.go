@@ -0,0 +1,242 @@
+package render
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/markdown"
+)
+
+// NewMarkdownRenderingEngine constructs an engine which renders pages as
+// GitHub Flavored Markdown, e.g. for publishing to a GitHub wiki. It is based
+// on the text engine, rendering references as relative links to each page's
+// .md file and code blocks as fenced code blocks.
+func NewMarkdownRenderingEngine() *TextRenderingEngine {
+	templates := map[string]string{}
+	for _, asset := range markdown.AssetNames() {
+		info, err := markdown.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		templates[filepath.Base(info.Name())] = string(markdown.MustAsset(asset))
+	}
+
+	engine, err := NewTemplateRenderingEngine("markdown", "md", templates, template.FuncMap{
+		"escape":        markdownEscape,
+		"escapeLeading": markdownEscapeLeading,
+		"codeSpan":      markdownCodeSpan,
+		"codeBlock":     markdownCodeBlock,
+		"destination":   markdownDestination,
+		"quote":         markdownQuote,
+		"indent":        markdownIndent,
+		"tableCell":     markdownTableCell,
+		"delimiterRow":  markdownDelimiterRow,
+		"tocIndent":     markdownTOCIndent,
+		"tidy":          markdownTidy,
+
+		"heading": func(con *booklit.Section) string {
+			depth := con.PageDepth() + 1
+			if depth > 6 {
+				depth = 6
+			}
+
+			return strings.Repeat("#", depth)
+		},
+
+		"inc": func(i int) int {
+			return i + 1
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return engine
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+)
+
+// markdownEscape escapes characters in text which would otherwise be
+// interpreted as inline markup.
+func markdownEscape(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+var markdownBlockStart = regexp.MustCompile(`^([#>+-]|\d+[.)])`)
+
+// markdownEscapeLeading escapes the start of a paragraph which would
+// otherwise begin a heading, block quote, or list.
+func markdownEscapeLeading(text string) string {
+	text = strings.TrimLeft(text, " \t")
+
+	loc := markdownBlockStart.FindStringIndex(text)
+	if loc == nil {
+		return text
+	}
+
+	return text[:loc[1]-1] + `\` + text[loc[1]-1:]
+}
+
+// markdownFence returns a run of the given character long enough to fence
+// the code without being closed early by a run within it.
+func markdownFence(code string, char string, min int) string {
+	longest := 0
+	for _, run := range regexp.MustCompile(regexp.QuoteMeta(char)+"+").FindAllString(code, -1) {
+		if len(run) > longest {
+			longest = len(run)
+		}
+	}
+
+	if longest < min {
+		return strings.Repeat(char, min)
+	}
+
+	return strings.Repeat(char, longest+1)
+}
+
+func markdownCodeSpan(content booklit.Content) string {
+	code := content.String()
+	fence := markdownFence(code, "`", 1)
+
+	// pad the code if it would otherwise merge with the fence
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+
+	return fence + code + fence
+}
+
+func markdownCodeBlock(content booklit.Content, language ...booklit.Content) string {
+	code := strings.Trim(content.String(), "\n")
+	fence := markdownFence(code, "`", 3)
+
+	info := ""
+	if len(language) > 0 && language[0] != nil {
+		info = language[0].String()
+	}
+
+	return fence + info + "\n" + code + "\n" + fence
+}
+
+// markdownDestination returns the destination of a link, wrapped in angle
+// brackets if it contains spaces or parentheses.
+func markdownDestination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+
+	return url
+}
+
+// markdownQuote renders the text as a block quote.
+func markdownQuote(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownIndent indents every line of the text but the first by the given
+// number of spaces, so that it continues e.g. a list item.
+func markdownIndent(width int, text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		if i > 0 && line != "" {
+			lines[i] = strings.Repeat(" ", width) + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownTableCell collapses the text onto a single line, as table cells
+// cannot span lines, and escapes any pipes.
+func markdownTableCell(text string) string {
+	return strings.Replace(strings.Join(strings.Fields(text), " "), "|", `\|`, -1)
+}
+
+// markdownDelimiterRow returns the row separating the header of the table,
+// i.e. its first row, from the rest, with a delimiter for each of its cells
+// marking the alignment of its column.
+func markdownDelimiterRow(table booklit.Table) string {
+	var columns int
+	if len(table.Rows) > 0 {
+		columns = len(table.Rows[0])
+	}
+
+	row := "|"
+	for i := 0; i < columns; i++ {
+		switch table.Alignment(i) {
+		case booklit.AlignLeft:
+			row += " :--- |"
+		case booklit.AlignCenter:
+			row += " :---: |"
+		case booklit.AlignRight:
+			row += " ---: |"
+		default:
+			row += " --- |"
+		}
+	}
+
+	return row
+}
+
+// markdownTOCIndent returns the indentation for the section's item in the
+// table of contents of the given section.
+func markdownTOCIndent(toc *booklit.Section, section *booklit.Section) string {
+	return strings.Repeat("  ", section.Depth()-toc.Depth()-1)
+}
+
+// markdownTidy collapses consecutive blank lines outside of code blocks,
+// which templates easily leave behind, and ends the text with a newline.
+func markdownTidy(text string) string {
+	out := []string{}
+
+	fence := ""
+	blank := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence == "" && strings.HasPrefix(trimmed, "```") {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "`"))]
+		} else if fence != "" {
+			if trimmed == fence {
+				fence = ""
+			}
+
+			out = append(out, line)
+			continue
+		} else if trimmed == "" {
+			if blank {
+				continue
+			}
+
+			blank = true
+			out = append(out, "")
+			continue
+		}
+
+		blank = false
+		out = append(out, strings.TrimRight(line, " \t"))
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Output is where a Writer writes the files it renders. Implement it to write
+// them somewhere other than a directory, e.g. into an archive, or into memory
+// with MemoryOutput.
+type Output interface {
+	// Creates the file at the slash-separated path relative to the output,
+	// replacing it if it exists. The file is only complete once it's closed.
+	Create(path string) (io.WriteCloser, error)
+}
+
+// DirOutput writes files into a directory, creating any directories they're
+// in.
+type DirOutput string
+
+func (dir DirOutput) Create(name string) (io.WriteCloser, error) {
+	file := filepath.Join(string(dir), filepath.FromSlash(name))
+
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Create(file)
+}
+
+// MemoryOutput collects files in memory, e.g. for tests.
+type MemoryOutput struct {
+	files map[string][]byte
+	lock  sync.Mutex
+}
+
+func (output *MemoryOutput) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{
+		output: output,
+		name:   path.Clean(name),
+	}, nil
+}
+
+// Files returns the content of each file written, by path.
+func (output *MemoryOutput) Files() map[string][]byte {
+	output.lock.Lock()
+	defer output.lock.Unlock()
+
+	files := map[string][]byte{}
+	for name, content := range output.files {
+		files[name] = content
+	}
+
+	return files
+}
+
+type memoryFile struct {
+	bytes.Buffer
+
+	output *MemoryOutput
+	name   string
+}
+
+func (file *memoryFile) Close() error {
+	file.output.lock.Lock()
+	defer file.output.lock.Unlock()
+
+	if file.output.files == nil {
+		file.output.files = map[string][]byte{}
+	}
+
+	file.output.files[file.name] = file.Bytes()
+
+	return nil
+}
+
+// output returns where the writer writes files: Output, or Destination if
+// it isn't set.
+func (writer Writer) output() Output {
+	if writer.Output != nil {
+		return writer.Output
+	}
+
+	return DirOutput(writer.Destination)
+}
+
+// writeFile writes the file at the given path relative to the destination.
+func (writer Writer) writeFile(name string, content []byte) error {
+	file, err := writer.output().Create(filepath.ToSlash(name))
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(content)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,319 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/pdf"
+)
+
+// PDFConverter converts rendered HTML pages to PDF by running an external
+// command, e.g. a headless browser.
+//
+// The strings "{input}" and "{output}" are replaced in each argument with the
+// path to the HTML file and the path of the PDF file to generate.
+type PDFConverter struct {
+	Command []string
+}
+
+// Convert runs the command to convert the HTML file to PDF. The command is
+// killed if the context is done first.
+func (converter PDFConverter) Convert(ctx context.Context, htmlPath string, pdfPath string) error {
+	if len(converter.Command) == 0 {
+		return fmt.Errorf("no pdf command configured")
+	}
+
+	args := make([]string, len(converter.Command))
+	for i, arg := range converter.Command {
+		arg = strings.Replace(arg, "{input}", htmlPath, -1)
+		arg = strings.Replace(arg, "{output}", pdfPath, -1)
+		args[i] = arg
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+
+	output := new(bytes.Buffer)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("pdf command failed: %w\n%s", err, output.String())
+	}
+
+	return nil
+}
+
+// DefaultPDFCommand is the command run by PDFRenderingEngine if none is
+// configured. WeasyPrint generates bookmarks from the headers of each
+// section and preserves links within the document.
+var DefaultPDFCommand = []string{"weasyprint", "{input}", "{output}"}
+
+// PDFRenderingEngine renders a section and all of its children as a single
+// PDF document, by rendering them to HTML and converting it with an external
+// command.
+type PDFRenderingEngine struct {
+	*HTMLRenderingEngine
+
+	// Converter for generating the PDF from the rendered HTML.
+	Converter PDFConverter
+
+	// Directory to write the intermediate HTML to, which relative paths to
+	// images and other assets are resolved against. Defaults to a temporary
+	// directory.
+	Directory string
+
+	// If set, chapters which are unchanged since they were saved in the
+	// cache are not rendered to HTML again, and the PDF is not converted
+	// again if neither the HTML nor any file it refers to changed.
+	Chapters *ChapterCache
+
+	// section being rendered, whose children are each cached as a chapter
+	root *booklit.Section
+
+	// digest of the book's structure, computed by RenderSection for the
+	// cache
+	structure string
+}
+
+// NewPDFRenderingEngine constructs an engine which renders a PDF document.
+// It is based on the HTML engine, overriding its templates to render every
+// section inline regardless of split sections, and linking to tags by their
+// anchor within the document.
+func NewPDFRenderingEngine() *PDFRenderingEngine {
+	engine := &PDFRenderingEngine{
+		Converter: PDFConverter{
+			Command: DefaultPDFCommand,
+		},
+	}
+
+	base := template.Must(initHTMLTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"url": pdfFragment,
+
+		"chapter": engine.renderChapter,
+
+		// split sections are rendered inline, so header depth continues
+		// through them
+		"headerDepth": func(con *booklit.Section) int {
+			depth := con.Depth() + 1
+			if depth > 6 {
+				depth = 6
+			}
+
+			return depth
+		},
+	})
+
+	for _, asset := range pdf.AssetNames() {
+		info, err := pdf.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(pdf.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine.HTMLRenderingEngine = &HTMLRenderingEngine{
+		name:          "pdf",
+		fileExtension: "pdf",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return engine
+}
+
+func (engine *PDFRenderingEngine) RendersDocument() {}
+
+func (engine *PDFRenderingEngine) URL(tag booklit.Tag) string {
+	return pdfFragment(tag)
+}
+
+func (engine *PDFRenderingEngine) RenderSection(out io.Writer, con *booklit.Section) error {
+	engine.root = con
+
+	if engine.Chapters != nil {
+		engine.structure = structureDigest(engine, con.Top())
+	}
+
+	tmpDir, err := ioutil.TempDir("", "booklit-pdf")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	htmlDir := engine.Directory
+	if htmlDir == "" {
+		htmlDir = tmpDir
+	}
+
+	html := new(bytes.Buffer)
+	err = engine.HTMLRenderingEngine.RenderSection(html, con)
+	if err != nil {
+		return err
+	}
+
+	var digest string
+	if engine.Chapters != nil {
+		digest, err = engine.documentDigest(htmlDir, html.Bytes())
+		if err != nil {
+			return err
+		}
+
+		artifact, found, err := engine.Chapters.fetch(digest)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			_, err = out.Write(artifact.Content)
+			return err
+		}
+	}
+
+	htmlFile, err := ioutil.TempFile(htmlDir, ".booklit-*.html")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(htmlFile.Name())
+
+	_, err = htmlFile.Write(html.Bytes())
+	if err != nil {
+		_ = htmlFile.Close()
+		return err
+	}
+
+	err = htmlFile.Close()
+	if err != nil {
+		return err
+	}
+
+	pdfPath := filepath.Join(tmpDir, con.PrimaryTag.Name+".pdf")
+
+	err = engine.Converter.Convert(con.Context(), htmlFile.Name(), pdfPath)
+	if err != nil {
+		return err
+	}
+
+	if engine.Chapters != nil {
+		content, err := ioutil.ReadFile(pdfPath)
+		if err != nil {
+			return err
+		}
+
+		err = engine.Chapters.store(digest, chapterArtifact{
+			Content: content,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	pdfFile, err := os.Open(pdfPath)
+	if err != nil {
+		return err
+	}
+
+	defer pdfFile.Close()
+
+	_, err = io.Copy(out, pdfFile)
+	return err
+}
+
+// renderChapter renders the section, or reuses what it was rendered to from
+// the cache if it is a chapter, i.e. a child of the section being rendered.
+func (engine *PDFRenderingEngine) renderChapter(section *booklit.Section) (template.HTML, error) {
+	if engine.Chapters == nil || section.Parent == nil || section.Parent != engine.root {
+		return engine.subRender(section)
+	}
+
+	digest, err := engine.Chapters.digest(engine, engine.structure, section, chapterSections(section))
+	if err != nil {
+		return "", err
+	}
+
+	artifact, found, err := engine.Chapters.fetch(digest)
+	if err != nil {
+		return "", err
+	}
+
+	if found {
+		return template.HTML(artifact.Content), nil
+	}
+
+	rendered, err := engine.subRender(section)
+	if err != nil {
+		return "", err
+	}
+
+	err = engine.Chapters.store(digest, chapterArtifact{
+		Content: []byte(rendered),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rendered, nil
+}
+
+// e.g. src="images/figure.png"
+var pdfReferencePattern = regexp.MustCompile(`(?:src|href)="([^"#:]+)"`)
+
+// documentDigest computes the digest of everything the PDF is converted
+// from: the HTML, the command converting it, and each local file the HTML
+// refers to, e.g. images, relative to the directory it is converted in.
+func (engine *PDFRenderingEngine) documentDigest(htmlDir string, html []byte) (string, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "key=%s\n", engine.Chapters.Key)
+	fmt.Fprintf(h, "command=%q\n", engine.Converter.Command)
+	fmt.Fprintf(h, "html=%x\n", sha256.Sum256(html))
+
+	for _, match := range pdfReferencePattern.FindAllSubmatch(html, -1) {
+		file := filepath.Join(htmlDir, filepath.FromSlash(string(match[1])))
+
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		sum, err := engine.Chapters.files.hash(file)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "file=%s:%s\n", match[1], sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func pdfFragment(tag booklit.Tag) string {
+	if tag.Anchor != "" {
+		return "#" + tag.Anchor
+	}
+
+	return "#" + tag.Section.PrimaryTag.Name
+}
@@ -0,0 +1,224 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vito/booklit"
+)
+
+// PrecacheManifest lists every URL to be cached by a service worker when it
+// is installed, along with a revision which changes whenever its content
+// does.
+type PrecacheManifest []PrecacheEntry
+
+type PrecacheEntry struct {
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+}
+
+// Precache returns an entry for every page rendered for the section,
+// followed by every other file in the destination, e.g. stylesheets and
+// images. Hidden files, files beginning with an underscore, and .lit sources
+// are skipped, along with any of the given files, e.g. the service worker
+// itself.
+func (writer Writer) Precache(section *booklit.Section, exclude ...string) (PrecacheManifest, error) {
+	manifest := PrecacheManifest{}
+
+	skip := map[string]bool{}
+	for _, file := range exclude {
+		skip[filepath.Join(writer.Destination, file)] = true
+	}
+
+	err := writer.precachePages(&manifest, skip, section)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.Walk(writer.Destination, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// skip hidden files and host configuration, e.g. _headers, which
+		// would fail the whole install if it can't be fetched
+		if file != writer.Destination && strings.IndexAny(info.Name(), "._") == 0 {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.IsDir() || skip[file] || filepath.Ext(file) == ".lit" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(writer.Destination, file)
+		if err != nil {
+			return err
+		}
+
+		return manifest.add(basePath(section.Top())+filepath.ToSlash(rel), file)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (writer Writer) precachePages(manifest *PrecacheManifest, skip map[string]bool, section *booklit.Section) error {
+	if writesPage(section) {
+		page, _ := splitURL(writer.Engine.URL(section.PrimaryTag))
+
+		// pages linked to on another site, e.g. a book hosted elsewhere, are
+		// left to the network
+		if !strings.Contains(page, "://") {
+			file := filepath.Join(writer.Destination, writer.pagePath(section))
+
+			err := manifest.add(page, file)
+			if err != nil {
+				return err
+			}
+
+			skip[file] = true
+		}
+	}
+
+	for _, child := range section.Children {
+		err := writer.precachePages(manifest, skip, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (manifest *PrecacheManifest) add(url string, file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(content)
+
+	*manifest = append(*manifest, PrecacheEntry{
+		URL:      url,
+		Revision: hex.EncodeToString(sum[:8]),
+	})
+
+	return nil
+}
+
+// WriteServiceWorker writes a service worker script to path, along with the
+// precache manifest it installs from to precachePath, so that the site works
+// offline and loads instantly on repeat visits.
+//
+// It should be written after everything else, so that every other file in
+// the destination is precached. Its cache is named after a digest of the
+// manifest, so browsers install it anew whenever anything changes.
+//
+// Pages register the script if they render the "ServiceWorker" partial,
+// which is set on each page when the Writer's ServiceWorker is configured.
+func (writer Writer) WriteServiceWorker(section *booklit.Section, path string, precachePath string) error {
+	logrus.WithFields(logrus.Fields{
+		"path":     path,
+		"precache": precachePath,
+	}).Infoln("writing service worker")
+
+	manifest, err := writer.Precache(section, path, precachePath)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	err = writer.writeFile(precachePath, payload)
+	if err != nil {
+		return err
+	}
+
+	precacheURL, err := filepath.Rel(filepath.Dir(path), precachePath)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(payload)
+	cacheName := "booklit-" + hex.EncodeToString(sum[:8])
+
+	script := fmt.Sprintf(serviceWorkerScript, cacheName, filepath.ToSlash(precacheURL))
+
+	return writer.writeFile(path, []byte(script))
+}
+
+const serviceWorkerScript = `// generated by booklit; caches every page and asset for offline use
+
+const cacheName = %q;
+const precacheManifest = %q;
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(
+    fetch(precacheManifest, { cache: "no-cache" })
+      .then((response) => response.json())
+      .then((entries) =>
+        caches.open(cacheName).then((cache) =>
+          cache.addAll(entries.map((entry) => new Request(entry.url, { cache: "reload" })))
+        )
+      )
+      .then(() => self.skipWaiting())
+  );
+});
+
+self.addEventListener("activate", (event) => {
+  event.waitUntil(
+    caches.keys()
+      .then((names) =>
+        Promise.all(
+          names
+            .filter((name) => name.startsWith("booklit-") && name !== cacheName)
+            .map((name) => caches.delete(name))
+        )
+      )
+      .then(() => self.clients.claim())
+  );
+});
+
+self.addEventListener("fetch", (event) => {
+  const request = event.request;
+  const url = new URL(request.url);
+
+  if (request.method !== "GET" || url.origin !== self.location.origin) {
+    return;
+  }
+
+  event.respondWith(
+    caches.open(cacheName).then((cache) =>
+      cache.match(request, { ignoreSearch: true }).then((cached) => {
+        if (cached) {
+          return cached;
+        }
+
+        if (request.mode === "navigate" && url.pathname.endsWith("/")) {
+          return cache
+            .match(new URL("index.html", url))
+            .then((index) => index || fetch(request));
+        }
+
+        return fetch(request);
+      })
+    )
+  );
+});
+`
@@ -0,0 +1,194 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/render/texinfo"
+)
+
+// TexinfoRenderingEngine renders a section and all of its children as a
+// single Texinfo manual, with a node and menu for each section.
+type TexinfoRenderingEngine struct {
+	*TextRenderingEngine
+}
+
+// NewTexinfoRenderingEngine constructs an engine which renders a Texinfo
+// manual, suitable for makeinfo. It is based on the plain text engine,
+// overriding its templates to generate Texinfo commands.
+func NewTexinfoRenderingEngine() *TexinfoRenderingEngine {
+	base := template.Must(initTextTmpl.Clone())
+
+	base.Funcs(template.FuncMap{
+		"nodeName":        texinfoNodeName,
+		"anchorName":      texinfoSanitize,
+		"sectioning":      texinfoSectioning,
+		"escape":          texinfoEscape,
+		"ref":             texinfoRef,
+		"uref":            texinfoURef,
+		"image":           texinfoImage,
+		"columnFractions": texinfoColumnFractions,
+		"trim":            strings.TrimSpace,
+	})
+
+	for _, asset := range texinfo.AssetNames() {
+		info, err := texinfo.AssetInfo(asset)
+		if err != nil {
+			panic(err)
+		}
+
+		content := strings.TrimRight(string(texinfo.MustAsset(asset)), "\n")
+
+		template.Must(base.New(filepath.Base(info.Name())).Parse(content))
+	}
+
+	engine := &TextRenderingEngine{
+		name:          "texinfo",
+		fileExtension: "texi",
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return &TexinfoRenderingEngine{engine}
+}
+
+func (engine *TexinfoRenderingEngine) RendersDocument() {}
+
+func (engine *TexinfoRenderingEngine) URL(tag booklit.Tag) string {
+	return "#" + texinfoTagNode(tag)
+}
+
+// texinfoNodeName returns the name of the section's node, which is its title
+// made safe for Info. Node names must be unique, so repeated titles are
+// numbered.
+func texinfoNodeName(section *booklit.Section) string {
+	if section.Parent == nil {
+		return "Top"
+	}
+
+	name := texinfoSanitize(booklit.StripAux(section.Title).String())
+
+	var seen int
+	var found bool
+	texinfoWalk(section.Top(), func(other *booklit.Section) {
+		if found {
+			return
+		}
+
+		if other == section {
+			found = true
+			return
+		}
+
+		if other.Parent != nil && texinfoSanitize(booklit.StripAux(other.Title).String()) == name {
+			seen++
+		}
+	})
+
+	if seen > 0 {
+		name = fmt.Sprintf("%s %d", name, seen+1)
+	}
+
+	return name
+}
+
+func texinfoWalk(section *booklit.Section, fn func(*booklit.Section)) {
+	fn(section)
+
+	for _, child := range section.Children {
+		texinfoWalk(child, fn)
+	}
+}
+
+func texinfoTagNode(tag booklit.Tag) string {
+	if tag.Anchor != "" {
+		return texinfoSanitize(tag.Anchor)
+	}
+
+	return texinfoNodeName(tag.Section)
+}
+
+// texinfoSanitize strips characters which confuse Info readers when used in
+// node names.
+func texinfoSanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case ',', ':', '.', '(', ')', '\n':
+			return ' '
+		}
+
+		return r
+	}, name)
+
+	return texinfoEscape(strings.Join(strings.Fields(name), " "))
+}
+
+func texinfoSectioning(section *booklit.Section) string {
+	switch section.Depth() {
+	case 0:
+		return "top"
+	case 1:
+		return "chapter"
+	case 2:
+		return "section"
+	case 3:
+		return "subsection"
+	default:
+		return "subsubsection"
+	}
+}
+
+var texinfoEscaper = strings.NewReplacer(
+	"@", "@@",
+	"{", "@{",
+	"}", "@}",
+	booklit.NonBreakingSpace, "@tie{}",
+	booklit.ThinSpace, "@thinspace{}",
+	booklit.WordBreak, "@/",
+)
+
+func texinfoEscape(str string) string {
+	return texinfoEscaper.Replace(str)
+}
+
+// texinfoArg escapes commas so that rendered content can be passed as an
+// argument to a command.
+func texinfoArg(str string) string {
+	return strings.Replace(strings.TrimSpace(str), ",", "@comma{}", -1)
+}
+
+func texinfoRef(tag booklit.Tag, display string) string {
+	return "@ref{" + texinfoTagNode(tag) + ", " + texinfoArg(display) + "}"
+}
+
+func texinfoURef(target string, content string) string {
+	return "@uref{" + texinfoArg(texinfoEscape(target)) + ", " + texinfoArg(content) + "}"
+}
+
+func texinfoImage(image booklit.Image) string {
+	ext := filepath.Ext(image.Path)
+	base := strings.TrimSuffix(image.Path, ext)
+
+	return "@image{" + texinfoArg(texinfoEscape(base)) + ",,," + texinfoArg(texinfoEscape(image.Description)) + "," + texinfoArg(texinfoEscape(ext)) + "}"
+}
+
+func texinfoColumnFractions(table booklit.Table) string {
+	columns := table.Columns()
+	if columns == 0 {
+		return "1"
+	}
+
+	fractions := make([]string, columns)
+	for i := range fractions {
+		fractions[i] = fmt.Sprintf("%.2f", 1/float64(columns))
+	}
+
+	return strings.Join(fractions, " ")
+}
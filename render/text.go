@@ -25,7 +25,7 @@ func init() {
 		},
 
 		"htmlURL": func(tag booklit.Tag) string {
-			return sectionURL("html", tag.Section, tag.Anchor)
+			return SectionURL("html", tag.Section, tag.Anchor)
 		},
 
 		"stripAux": booklit.StripAux,
@@ -71,19 +71,32 @@ func init() {
 }
 
 type TextRenderingEngine struct {
+	name          string
 	fileExtension string
 
+	baseTmpl     *template.Template
 	tmpl         *template.Template
 	tmplModTimes map[string]time.Time
 
+	// templates loaded by LoadTemplates, for forking
+	tmplSources []tmplSource
+
 	template *template.Template
 	data     interface{}
+
+	// section whose page is being rendered
+	page *booklit.Section
+
+	// contexts of the content being rendered, innermost last
+	contexts []*RenderContext
 }
 
 func NewTextRenderingEngine(fileExtension string) *TextRenderingEngine {
 	engine := &TextRenderingEngine{
+		name:          "text",
 		fileExtension: fileExtension,
 
+		baseTmpl:     initTextTmpl,
 		tmplModTimes: map[string]time.Time{},
 	}
 
@@ -92,13 +105,43 @@ func NewTextRenderingEngine(fileExtension string) *TextRenderingEngine {
 	return engine
 }
 
+// NewTemplateRenderingEngine constructs an engine for a format of its own,
+// e.g. Confluence storage format, whose base templates are given by their
+// file name, e.g. "page.tmpl". Anything not given falls back to the plain
+// text templates. The funcs are made available to all templates.
+func NewTemplateRenderingEngine(name string, fileExtension string, templates map[string]string, funcs template.FuncMap) (*TextRenderingEngine, error) {
+	base, err := initTextTmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	base.Funcs(funcs)
+
+	for file, content := range templates {
+		_, err := base.New(file).Parse(strings.TrimRight(content, "\n"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	engine := &TextRenderingEngine{
+		name:          name,
+		fileExtension: fileExtension,
+
+		baseTmpl:     base,
+		tmplModTimes: map[string]time.Time{},
+	}
+
+	engine.resetTmpl()
+
+	return engine, nil
+}
+
 func (engine *TextRenderingEngine) resetTmpl() {
-	engine.tmpl = template.Must(initTextTmpl.Clone())
+	engine.tmpl = template.Must(engine.baseTmpl.Clone())
 	engine.tmpl.Funcs(template.FuncMap{
 		"render": engine.subRender,
-		"url": func(tag booklit.Tag) string {
-			return sectionURL(engine.FileExtension(), tag.Section, tag.Anchor)
-		},
+		"url":    engine.URL,
 	})
 }
 
@@ -130,6 +173,7 @@ func (engine *TextRenderingEngine) LoadTemplates(templatesDir string) error {
 	}
 
 	engine.resetTmpl()
+	engine.tmplSources = nil
 
 	for _, path := range templates {
 		content, err := ioutil.ReadFile(path)
@@ -137,23 +181,60 @@ func (engine *TextRenderingEngine) LoadTemplates(templatesDir string) error {
 			return err
 		}
 
-		trimmed := strings.TrimRight(string(content), "\n")
+		source := tmplSource{
+			Name:    filepath.Base(path),
+			Content: strings.TrimRight(string(content), "\n"),
+		}
 
-		_, err = engine.tmpl.New(filepath.Base(path)).Parse(trimmed)
+		_, err = engine.tmpl.New(source.Name).Parse(source.Content)
 		if err != nil {
 			return err
 		}
+
+		engine.tmplSources = append(engine.tmplSources, source)
 	}
 
 	return nil
 }
 
+// Fork returns a copy of the engine with the same templates loaded, which
+// can render pages at the same time as the original.
+func (engine *TextRenderingEngine) Fork() (RenderingEngine, error) {
+	fork := &TextRenderingEngine{
+		name:          engine.name,
+		fileExtension: engine.fileExtension,
+
+		baseTmpl:     engine.baseTmpl,
+		tmplModTimes: map[string]time.Time{},
+		tmplSources:  engine.tmplSources,
+	}
+
+	fork.resetTmpl()
+
+	for _, source := range fork.tmplSources {
+		_, err := fork.tmpl.New(source.Name).Parse(source.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fork, nil
+}
+
 func (engine *TextRenderingEngine) FileExtension() string {
 	return engine.fileExtension
 }
 
+func (engine *TextRenderingEngine) EngineName() string {
+	return engine.name
+}
+
+func (engine *TextRenderingEngine) HasTemplate(name string) bool {
+	return engine.tmpl.Lookup(name+".tmpl") != nil
+}
+
 func (engine *TextRenderingEngine) URL(tag booklit.Tag) string {
-	return sectionURL(engine.FileExtension(), tag.Section, tag.Anchor)
+	return LinkURL(engine.FileExtension(), engine.page, tag)
 }
 
 func (engine *TextRenderingEngine) RenderSection(out io.Writer, con *booklit.Section) error {
@@ -163,6 +244,8 @@ func (engine *TextRenderingEngine) RenderSection(out io.Writer, con *booklit.Sec
 	}
 
 	engine.data = con
+	engine.page = con
+	engine.contexts = []*RenderContext{newRenderContext(con)}
 
 	err := engine.setTmpl(tmpl)
 	if err != nil {
@@ -172,6 +255,22 @@ func (engine *TextRenderingEngine) RenderSection(out io.Writer, con *booklit.Sec
 	return engine.render(out)
 }
 
+func (engine *TextRenderingEngine) RenderFragment(out io.Writer, tag booklit.Tag) error {
+	content, err := FragmentContent(tag)
+	if err != nil {
+		return err
+	}
+
+	engine.contexts = []*RenderContext{newRenderContext(content)}
+
+	err = content.Visit(engine)
+	if err != nil {
+		return err
+	}
+
+	return engine.render(out)
+}
+
 func (engine *TextRenderingEngine) VisitString(con booklit.String) error {
 	engine.data = con
 	return engine.setTmpl("string")
@@ -247,6 +346,26 @@ func (engine *TextRenderingEngine) VisitDefinitions(con booklit.Definitions) err
 	return engine.setTmpl("definitions")
 }
 
+func (engine *TextRenderingEngine) VisitFigure(con *booklit.Figure) error {
+	engine.data = con
+	return engine.setTmpl("figure")
+}
+
+func (engine *TextRenderingEngine) VisitFootnote(con *booklit.Footnote) error {
+	engine.data = con
+	return engine.setTmpl("footnote")
+}
+
+func (engine *TextRenderingEngine) VisitListOfFigures(con booklit.ListOfFigures) error {
+	engine.data = con.Section
+	return engine.setTmpl("list-of-figures")
+}
+
+func (engine *TextRenderingEngine) VisitAttributions(con booklit.Attributions) error {
+	engine.data = con.Section
+	return engine.setTmpl("attributions")
+}
+
 func (engine *TextRenderingEngine) setTmpl(name string) error {
 	tmpl := engine.tmpl.Lookup(name + ".tmpl")
 
@@ -264,24 +383,64 @@ func (engine *TextRenderingEngine) render(out io.Writer) error {
 		return fmt.Errorf("unknown template for '%s' (%T)", engine.data, engine.data)
 	}
 
-	return engine.template.Execute(out, engine.data)
+	err := engine.template.Execute(out, engine.data)
+	if err != nil {
+		return traceTemplate(engine.template.Name(), engine.data, err)
+	}
+
+	return nil
 }
 
 func (engine *TextRenderingEngine) subRender(content booklit.Content) (string, error) {
 	buf := new(bytes.Buffer)
 
-	subEngine := NewTextRenderingEngine(engine.fileExtension)
-	subEngine.tmpl = engine.tmpl
+	subEngine := &TextRenderingEngine{
+		name:          engine.name,
+		fileExtension: engine.fileExtension,
+
+		baseTmpl: engine.baseTmpl,
+		tmpl:     engine.tmpl,
+	}
+
+	context := engine.pushContext(content)
+	defer engine.popContext()
 
 	err := content.Visit(subEngine)
 	if err != nil {
 		return "", err
 	}
 
+	if subEngine.template != nil {
+		name := strings.TrimSuffix(subEngine.template.Name(), ".tmpl") + "-context.tmpl"
+		if tmpl := engine.tmpl.Lookup(name); tmpl != nil {
+			subEngine.template = tmpl
+			subEngine.data = context
+		}
+	}
+
 	err = subEngine.render(buf)
 	if err != nil {
-		return "", err
+		return "", tracePartial(engine.data, content, err)
 	}
 
 	return buf.String(), nil
 }
+
+// pushContext notes that the content is being rendered by the template of
+// the content being rendered, returning its context.
+func (engine *TextRenderingEngine) pushContext(content booklit.Content) *RenderContext {
+	var context *RenderContext
+	if len(engine.contexts) == 0 {
+		context = newRenderContext(content)
+	} else {
+		context = engine.contexts[len(engine.contexts)-1].child(content)
+	}
+
+	engine.contexts = append(engine.contexts, context)
+
+	return context
+}
+
+func (engine *TextRenderingEngine) popContext() {
+	engine.contexts = engine.contexts[:len(engine.contexts)-1]
+}
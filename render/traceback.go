@@ -0,0 +1,112 @@
+package render
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/vito/booklit"
+)
+
+// e.g. template: page.tmpl:12:5: executing "page.tmpl" at <render>: ...
+var templateErrorPattern = regexp.MustCompile(`(?s)^template: ([^:]+):(\d+):(\d+): executing "[^"]*" at <(.*?)>: (.*)$`)
+
+// traceTemplate wraps an error returned by executing the template in a
+// booklit.TemplateError, with a frame for the template preceding those of
+// any template it rendered content with. If the template was rendering a
+// section, and none of those were, the error is attributed to it.
+func traceTemplate(name string, data interface{}, err error) error {
+	frame := booklit.TemplateFrame{
+		Template: name,
+	}
+
+	cause := err
+	if match := templateErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+		frame.Template = match[1]
+		frame.Line, _ = strconv.Atoi(match[2])
+		frame.Column, _ = strconv.Atoi(match[3])
+		frame.Action = match[4]
+		cause = errors.New(match[5])
+	}
+
+	var tmplErr booklit.TemplateError
+	if errors.As(err, &tmplErr) {
+		tmplErr.Frames = append([]booklit.TemplateFrame{frame}, tmplErr.Frames...)
+	} else {
+		tmplErr = booklit.TemplateError{
+			Frames: []booklit.TemplateFrame{frame},
+			Err:    cause,
+		}
+	}
+
+	if section, ok := data.(*booklit.Section); ok && tmplErr.Section == "" {
+		tmplErr.Section = section.PrimaryTag.Name
+		tmplErr.ErrorLocation = booklit.ErrorLocation{
+			FilePath:     section.PrimaryTag.Section.FilePath(),
+			FS:           section.PrimaryTag.Section.Top().FS,
+			NodeLocation: section.PrimaryTag.Location,
+			Length:       len("\\title"),
+		}
+	}
+
+	return tmplErr
+}
+
+// tracePartial records the name of the partial which was being rendered
+// when a template failed, if the content was one. Templates render content
+// through the engine rendering the page, so the partials of each section on
+// the page are searched.
+func tracePartial(data interface{}, content booklit.Content, err error) error {
+	var tmplErr booklit.TemplateError
+	if !errors.As(err, &tmplErr) || len(tmplErr.Frames) == 0 || tmplErr.Frames[0].Partial != "" {
+		return err
+	}
+
+	name, found := "", false
+	switch typed := data.(type) {
+	case *booklit.Section:
+		name, found = sectionPartialName(typed, content)
+	case booklit.Styled:
+		name, found = partialName(typed.Partials, content)
+	}
+
+	if !found {
+		return err
+	}
+
+	frames := make([]booklit.TemplateFrame, len(tmplErr.Frames))
+	copy(frames, tmplErr.Frames)
+	frames[0].Partial = name
+	tmplErr.Frames = frames
+
+	return tmplErr
+}
+
+func sectionPartialName(section *booklit.Section, content booklit.Content) (string, bool) {
+	if name, found := partialName(section.Partials, content); found {
+		return name, true
+	}
+
+	for _, child := range section.Children {
+		if writesPage(child) {
+			continue
+		}
+
+		if name, found := sectionPartialName(child, content); found {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func partialName(partials booklit.Partials, content booklit.Content) (string, bool) {
+	for name, partial := range partials {
+		if reflect.DeepEqual(partial, content) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
@@ -2,26 +2,116 @@ package render
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
-	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vito/booklit"
 )
 
+// RenderingEngine renders sections to files. Engines may live outside of
+// this package; see TextRenderingEngine for a template-driven base.
+//
+// Each Visit method is called with a piece of content to render, and
+// RenderSection renders a page for a section. Engines should typically
+// implement booklit.RenderingEngine too, so that plugins can tailor their
+// output to them.
 type RenderingEngine interface {
 	booklit.Visitor
 
+	// Extension of the files rendered by the engine, e.g. "html".
 	FileExtension() string
+
+	// Renders the section as a full page to the writer.
 	RenderSection(io.Writer, *booklit.Section) error
+
+	// URL of the tag; see SectionURL.
 	URL(booklit.Tag) string
 }
 
+// ForkableRenderingEngine is implemented by engines which can be copied, so
+// that several pages can be rendered at once, each by a copy of its own.
+type ForkableRenderingEngine interface {
+	RenderingEngine
+
+	// Returns a copy of the engine which shares no state with it.
+	Fork() (RenderingEngine, error)
+}
+
+// FragmentRenderingEngine is implemented by engines which can render a tag's
+// content on its own, without the page around it, e.g. for embedding in
+// another site.
+type FragmentRenderingEngine interface {
+	RenderingEngine
+
+	// Renders the content of the tag to the writer; see FragmentContent.
+	RenderFragment(io.Writer, booklit.Tag) error
+}
+
+// PagePathRenderingEngine is implemented by engines which name the file of
+// each page themselves, rather than after its tag, e.g. man pages named
+// after the command they document.
+type PagePathRenderingEngine interface {
+	RenderingEngine
+
+	// Path of the file to which the section's page is written, relative to
+	// the destination; see PagePath.
+	PagePath(*booklit.Section) string
+}
+
 type Writer struct {
 	Engine RenderingEngine
 
 	Destination string
+
+	// Where files are written instead of Destination, e.g. into memory. PDFs,
+	// the Cache, Hooks, headers, service workers, and UnusedAssets read files
+	// back from Destination, so they require it.
+	Output Output
+
+	// If set, a PDF is generated alongside each rendered page, and the page's
+	// section is given a "PDF" partial containing the PDF's file name.
+	PDF *PDFConverter
+
+	// Path of the service worker written by WriteServiceWorker, if any. Each
+	// rendered page's section is given a "ServiceWorker" partial containing
+	// its URL, so that the page can register it.
+	ServiceWorker string
+
+	// Path of the calendar written by WriteCalendar, if any. Each rendered
+	// page's section is given a "Calendar" partial containing its URL, so
+	// that the page can link to it.
+	Calendar string
+
+	// Time which events are upcoming as of. If the book has any upcoming
+	// events, each rendered page's section is given an "UpcomingEvents"
+	// partial listing them. Defaults to the current time.
+	Now time.Time
+
+	// Path which error pages' relative links are resolved against, e.g.
+	// "/docs/" for a site hosted under /docs. Defaults to "/".
+	ErrorPageBase string
+
+	// Number of pages to render at once, if the engine is a
+	// ForkableRenderingEngine. Defaults to 1.
+	Jobs int
+
+	// If set, pages whose inputs are unchanged since they were recorded in
+	// the cache are not rendered again.
+	Cache *BuildCache
+
+	// Commands run on each page once it's rendered, e.g. validators.
+	Hooks []Hook
+
+	// If set, it's notified as each page is rendered, including pages which
+	// are skipped because they're unchanged.
+	Progress booklit.Progress
+
+	// digest of the book's structure, computed by WriteSection for the cache
+	structure string
 }
 
 type SearchIndex map[string]SearchDocument
@@ -35,20 +125,202 @@ type SearchDocument struct {
 }
 
 func (writer Writer) WriteSection(section *booklit.Section) error {
-	if section.Parent == nil || section.Parent.SplitSections {
+	// use the same time for every page, so they list the same events
+	writer.Now = writer.now()
+
+	if _, onDisk := writer.output().(DirOutput); !onDisk && (writer.PDF != nil || writer.Cache != nil || len(writer.Hooks) > 0) {
+		return fmt.Errorf("PDFs, the cache, and hooks require a destination rather than an output")
+	}
+
+	if writer.Cache != nil {
+		writer.structure = structureDigest(writer.Engine, section.Top())
+	}
+
+	if _, ok := writer.Engine.(DocumentRenderingEngine); ok {
+		started := writer.startStage(1)
+
 		err := writer.writeSingleSection(section)
 		if err != nil {
 			return err
 		}
+
+		writer.finishStage(started)
+
+		return nil
 	}
 
-	for _, child := range section.Children {
-		err := writer.WriteSection(child)
+	err := writer.checkCollisions(section)
+	if err != nil {
+		return err
+	}
+
+	return writer.writeSections(section)
+}
+
+// startStage notifies Progress that the given number of pages are about to
+// be rendered, if it's set, returning when rendering started.
+func (writer Writer) startStage(total int) time.Time {
+	if writer.Progress != nil {
+		writer.Progress.StartStage(booklit.StageRender, total)
+	}
+
+	return time.Now()
+}
+
+// finishStage notifies Progress that the pages are rendered, if it's set.
+func (writer Writer) finishStage(started time.Time) {
+	if writer.Progress != nil {
+		writer.Progress.FinishStage(booklit.StageRender, time.Since(started))
+	}
+}
+
+func (writer Writer) writeSections(section *booklit.Section) error {
+	pages := []*booklit.Section{}
+
+	var collect func(*booklit.Section)
+	collect = func(section *booklit.Section) {
+		if writesPage(section) {
+			pages = append(pages, section)
+		}
+
+		for _, child := range section.Children {
+			collect(child)
+		}
+	}
+
+	collect(section)
+
+	started := writer.startStage(len(pages))
+
+	forkable, ok := writer.Engine.(ForkableRenderingEngine)
+	if !ok || writer.Jobs <= 1 || len(pages) <= 1 {
+		for _, page := range pages {
+			err := writer.writeSingleSection(page)
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.finishStage(started)
+
+		return nil
+	}
+
+	// set up every page before rendering any of them, since pages may read
+	// each other's partials
+	for _, page := range pages {
+		writer.setPagePartials(page)
+	}
+
+	jobs := writer.Jobs
+	if jobs > len(pages) {
+		jobs = len(pages)
+	}
+
+	queue := make(chan int, len(pages))
+	for i := range pages {
+		queue <- i
+	}
+
+	close(queue)
+
+	errs := make([]error, len(pages))
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < jobs; i++ {
+		engine, err := forkable.Fork()
+		if err != nil {
+			return err
+		}
+
+		worker := writer
+		worker.Engine = engine
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				errs[i] = worker.renderPage(pages[i])
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// return the first error in page order, regardless of which page failed
+	// first, so that failures are reported consistently
+	for _, err := range errs {
 		if err != nil {
 			return err
 		}
 	}
 
+	writer.finishStage(started)
+
+	return nil
+}
+
+func writesPage(section *booklit.Section) bool {
+	return section.Parent == nil || section.Parent.SplitSections
+}
+
+// pagePath returns the path of the file to which the section's page is
+// written, relative to the destination.
+func (writer Writer) pagePath(section *booklit.Section) string {
+	if named, ok := writer.Engine.(PagePathRenderingEngine); ok {
+		return named.PagePath(section)
+	}
+
+	return PagePath(writer.Engine.FileExtension(), section)
+}
+
+// checkCollisions returns a booklit.PageCollisionError if any sections
+// would be written to the same file, rather than letting one overwrite the
+// other.
+func (writer Writer) checkCollisions(section *booklit.Section) error {
+	pages := map[string][]*booklit.Section{}
+	names := []string{}
+
+	var collect func(*booklit.Section)
+	collect = func(section *booklit.Section) {
+		if writesPage(section) {
+			name := writer.pagePath(section)
+			if _, found := pages[name]; !found {
+				names = append(names, name)
+			}
+
+			pages[name] = append(pages[name], section)
+		}
+
+		for _, child := range section.Children {
+			collect(child)
+		}
+	}
+
+	collect(section)
+
+	for _, name := range names {
+		if len(pages[name]) == 1 {
+			continue
+		}
+
+		locs := []booklit.ErrorLocation{}
+		for _, section := range pages[name] {
+			locs = append(locs, booklit.ErrorLocation{
+				FilePath:     section.PrimaryTag.Section.FilePath(),
+				FS:           section.PrimaryTag.Section.Top().FS,
+				NodeLocation: section.PrimaryTag.Location,
+				Length:       len("\\title"),
+			})
+		}
+
+		return booklit.PageCollisionError{
+			FileName:         name,
+			DefinedLocations: locs,
+		}
+	}
+
 	return nil
 }
 
@@ -57,27 +329,15 @@ func (writer Writer) WriteSearchIndex(section *booklit.Section, path string) err
 		"path": path,
 	}).Infoln("writing search index")
 
-	indexPath := filepath.Join(writer.Destination, path)
-
 	index := SearchIndex{}
 	writer.loadTags(index, section)
 
-	indexFile, err := os.Create(indexPath)
+	payload, err := json.Marshal(index)
 	if err != nil {
 		return err
 	}
 
-	err = json.NewEncoder(indexFile).Encode(index)
-	if err != nil {
-		return err
-	}
-
-	err = indexFile.Close()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return writer.writeFile(path, append(payload, '\n'))
 }
 
 func (writer Writer) loadTags(index SearchIndex, section *booklit.Section) {
@@ -104,10 +364,86 @@ func (writer Writer) loadTags(index SearchIndex, section *booklit.Section) {
 }
 
 func (writer Writer) writeSingleSection(section *booklit.Section) error {
-	name := section.PrimaryTag.Name + "." + writer.Engine.FileExtension()
+	writer.setPagePartials(section)
+	return writer.renderPage(section)
+}
+
+// setPagePartials sets the partials describing the files written alongside
+// the section's page.
+func (writer Writer) setPagePartials(section *booklit.Section) {
+	if writer.PDF != nil {
+		section.SetPartial("PDF", booklit.String(pdfName(section)))
+	}
+
+	if writer.ServiceWorker != "" {
+		section.SetPartial("ServiceWorker", booklit.String(AssetURL(section, writer.ServiceWorker)))
+	}
+
+	if writer.Calendar != "" {
+		section.SetPartial("Calendar", booklit.String(AssetURL(section, writer.Calendar)))
+	}
+
+	if events := section.Top().UpcomingEvents(writer.now()); len(events) > 0 {
+		section.SetPartial("UpcomingEvents", eventList(events))
+	}
+}
+
+func (writer Writer) now() time.Time {
+	if writer.Now.IsZero() {
+		return time.Now()
+	}
+
+	return writer.Now
+}
+
+// pdfName returns the name of the PDF for the section's page, which is
+// written alongside the page so it can be linked to by name.
+func pdfName(section *booklit.Section) string {
+	return section.PrimaryTag.Name + ".pdf"
+}
+
+// renderPage renders the section's page, notifying Progress once it's done.
+func (writer Writer) renderPage(section *booklit.Section) error {
+	err := writer.renderPageFile(section)
+	if err != nil {
+		return err
+	}
+
+	if writer.Progress != nil {
+		writer.Progress.Advance(booklit.StageRender, section)
+	}
+
+	return nil
+}
+
+func (writer Writer) renderPageFile(section *booklit.Section) error {
+	name := writer.pagePath(section)
 	path := filepath.Join(writer.Destination, name)
 
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	// a cancelled build stops before rendering any more pages
+	err := section.Context().Err()
+	if err != nil {
+		return err
+	}
+
+	var digest string
+	if writer.Cache != nil {
+		digest, err = writer.Cache.pageDigest(writer, section)
+		if err != nil {
+			return err
+		}
+
+		if writer.Cache.fresh(path, digest) {
+			logrus.WithFields(logrus.Fields{
+				"section":  section.Path,
+				"rendered": path,
+			}).Debug("unchanged; skipping")
+
+			return nil
+		}
+	}
+
+	file, err := writer.output().Create(filepath.ToSlash(name))
 	if err != nil {
 		return err
 	}
@@ -117,12 +453,40 @@ func (writer Writer) writeSingleSection(section *booklit.Section) error {
 	logrus.WithFields(logrus.Fields{
 		"section":  section.Path,
 		"rendered": path,
-	}).Info("rendering")
+	}).Debug("rendering")
 
 	err = writer.Engine.RenderSection(file, section)
 	if err != nil {
 		return err
 	}
 
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	err = writer.runHooks(path, section)
+	if err != nil {
+		return err
+	}
+
+	if writer.PDF != nil {
+		pdfPath := filepath.Join(filepath.Dir(path), pdfName(section))
+
+		logrus.WithFields(logrus.Fields{
+			"section":  section.Path,
+			"rendered": pdfPath,
+		}).Debug("converting to pdf")
+
+		err = writer.PDF.Convert(section.Context(), path, pdfPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if writer.Cache != nil {
+		writer.Cache.record(path, digest)
+	}
+
 	return nil
 }
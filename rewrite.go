@@ -0,0 +1,84 @@
+package booklit
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// LinkRewrite is a rule for rewriting the URLs of links, images, and assets
+// as they're rendered, e.g. for pointing a staging build at staging
+// services.
+type LinkRewrite struct {
+	Pattern *regexp.Regexp
+
+	// Replacement for each match, which may refer to the pattern's groups,
+	// e.g. $1; see regexp.Regexp.Expand.
+	Replacement string
+}
+
+// ParseLinkRewrite parses a rule given as pattern=replacement, where
+// pattern is a regular expression.
+func ParseLinkRewrite(rule string) (LinkRewrite, error) {
+	segs := strings.SplitN(rule, "=", 2)
+	if len(segs) != 2 {
+		return LinkRewrite{}, fmt.Errorf("invalid link rewrite (expected pattern=replacement): %s", rule)
+	}
+
+	pattern, err := regexp.Compile(segs[0])
+	if err != nil {
+		return LinkRewrite{}, fmt.Errorf("invalid link rewrite pattern: %w", err)
+	}
+
+	return LinkRewrite{
+		Pattern:     pattern,
+		Replacement: segs[1],
+	}, nil
+}
+
+// AbsoluteLinkRewrites returns rules which make relative URLs absolute to
+// the given base URL, e.g. https://example.com/docs/, for content rendered
+// outside of its site. URLs with a scheme, protocol-relative URLs, and
+// anchors are left alone.
+func AbsoluteLinkRewrites(baseURL string) ([]LinkRewrite, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL (must be absolute): %s", baseURL)
+	}
+
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	origin := base.Scheme + "://" + base.Host
+
+	return []LinkRewrite{
+		{
+			// relative to the root, e.g. /css/booklit.css
+			Pattern:     regexp.MustCompile(`^/([^/].*)?$`),
+			Replacement: strings.Replace(origin, "$", "$$", -1) + "/$1",
+		},
+		{
+			// relative to the page, e.g. images/diagram.png, but not
+			// e.g. mailto:someone@example.com
+			Pattern:     regexp.MustCompile(`^([^/?#:]+([/?#].*)?)$`),
+			Replacement: strings.Replace(baseURL, "$", "$$", -1) + "$1",
+		},
+	}, nil
+}
+
+// Rewrite replaces each match of the pattern in the URL.
+func (rewrite LinkRewrite) Rewrite(url string) string {
+	return rewrite.Pattern.ReplaceAllString(url, rewrite.Replacement)
+}
+
+// RewriteURL applies each of the link rewrites of the section's book to the
+// URL, in order.
+func (con *Section) RewriteURL(url string) string {
+	for _, rewrite := range con.Top().LinkRewrites {
+		url = rewrite.Rewrite(url)
+	}
+
+	return url
+}
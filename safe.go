@@ -0,0 +1,105 @@
+package booklit
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// SafeMode limits what a book may do, so that untrusted input, e.g. submitted
+// to a hosted service, can be built without risk: only plugins declared safe
+// via DeclareSafePlugin may be used, and files may only be read from within
+// the directory of the book's top-level section.
+type SafeMode struct {
+	// Maximum depth of sections included via \include-section, counting from
+	// the top-level section. Unlimited if zero.
+	MaxIncludeDepth int
+
+	// Maximum size in bytes of each file read by the book, e.g. included
+	// sections. Unlimited if zero.
+	MaxFileSize int64
+}
+
+// CheckFile returns an error if a book whose top-level section was loaded
+// from root may not read the file at path.
+func (safe SafeMode) CheckFile(root string, path string) error {
+	return safe.CheckFileIn(filepath.Dir(root), path)
+}
+
+// CheckFileIn returns an error if the file at path, with any symlinks
+// followed, is outside of the directory or too large, e.g. for files a
+// plugin reads from the output directory rather than the book's.
+func (safe SafeMode) CheckFileIn(dir string, path string) error {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	file, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	file, err = filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("file is outside of the book in safe mode: %s", path)
+	}
+
+	return safe.checkSize(nil, file, path)
+}
+
+// CheckFSFile is like CheckFile, for a book read from the file system. Files
+// can't be read from outside of the file system, and symlinks aren't
+// followed, so only the paths are compared.
+func (safe SafeMode) CheckFSFile(fsys fs.FS, root string, path string) error {
+	rel, err := filepath.Rel(filepath.Dir(filepath.Clean(root)), filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("file is outside of the book in safe mode: %s", path)
+	}
+
+	return safe.checkSize(fsys, path, path)
+}
+
+func (safe SafeMode) checkSize(fsys fs.FS, file string, path string) error {
+	if safe.MaxFileSize <= 0 {
+		return nil
+	}
+
+	info, err := StatFile(fsys, file)
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > safe.MaxFileSize {
+		return fmt.Errorf("file exceeds %d bytes in safe mode: %s", safe.MaxFileSize, path)
+	}
+
+	return nil
+}
+
+// CheckFile returns an error if the section's book is built in safe mode and
+// may not read the file at path; see SafeMode. Plugins which read files
+// should check them first.
+func (con *Section) CheckFile(path string) error {
+	top := con.Top()
+	if top.Safe == nil {
+		return nil
+	}
+
+	if top.FS != nil {
+		return top.Safe.CheckFSFile(top.FS, top.Path, path)
+	}
+
+	return top.Safe.CheckFile(top.Path, path)
+}
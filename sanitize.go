@@ -0,0 +1,282 @@
+package booklit
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// elements which are kept, by namespace: "" for HTML, or svg or math
+var safeElements = map[string]map[string]bool{
+	"": setOf(
+		"a", "abbr", "article", "aside", "b", "bdi", "bdo", "blockquote", "br",
+		"caption", "cite", "code", "col", "colgroup", "dd", "del", "details",
+		"dfn", "div", "dl", "dt", "em", "figcaption", "figure", "footer", "h1",
+		"h2", "h3", "h4", "h5", "h6", "header", "hr", "i", "img", "ins", "kbd",
+		"li", "mark", "nav", "ol", "p", "picture", "pre", "q", "rp", "rt",
+		"ruby", "s", "samp", "section", "small", "span", "strong", "sub",
+		"summary", "sup", "table", "tbody", "td", "tfoot", "th", "thead",
+		"time", "tr", "u", "ul", "var", "wbr",
+	),
+
+	"svg": setOf(
+		"svg", "a", "circle", "clippath", "defs", "desc", "ellipse", "g",
+		"line", "lineargradient", "marker", "mask", "path", "pattern",
+		"polygon", "polyline", "radialgradient", "rect", "stop", "style",
+		"symbol", "text", "textpath", "title", "tspan", "use",
+	),
+
+	"math": setOf(
+		"math", "maction", "menclose", "merror", "mfenced", "mfrac", "mi",
+		"mmultiscripts", "mn", "mo", "mover", "mpadded", "mphantom", "mprescripts",
+		"mroot", "mrow", "ms", "mspace", "msqrt", "mstyle", "msub", "msubsup",
+		"msup", "mtable", "mtd", "mtext", "mtr", "munder", "munderover", "none",
+		"semantics", "annotation",
+	),
+}
+
+// elements which are dropped along with their content; any others which
+// aren't safe are dropped, keeping their content
+var droppedElements = setOf(
+	"script", "style", "iframe", "frame", "frameset", "embed", "object",
+	"applet", "noscript", "noembed", "noframes", "template", "textarea",
+	"select", "xmp", "plaintext", "title", "head", "base", "link", "meta",
+	"foreignobject", "annotation-xml", "metadata", "animate",
+	"animatemotion", "animatetransform", "set", "mglyph", "malignmark",
+)
+
+// attributes which are kept on any element
+var globalAttributes = setOf(
+	"class", "id", "title", "lang", "dir", "role", "style", "xml:lang",
+)
+
+// attributes which are kept on the elements they belong to, by namespace
+var safeAttributes = map[string]map[string]bool{
+	"": setOf(
+		"href", "name", "rel", "src", "alt", "width", "height", "cite",
+		"datetime", "colspan", "rowspan", "headers", "scope", "span", "align",
+		"valign", "start", "reversed", "type", "value", "open", "loading",
+	),
+
+	"svg": setOf(
+		"xmlns", "xmlns:xlink", "version", "viewbox", "preserveaspectratio",
+		"width", "height", "x", "y", "x1", "y1", "x2", "y2", "cx", "cy", "r",
+		"rx", "ry", "fx", "fy", "d", "points", "pathlength", "transform",
+		"fill", "fill-opacity", "fill-rule", "stroke", "stroke-width",
+		"stroke-linecap", "stroke-linejoin", "stroke-miterlimit",
+		"stroke-dasharray", "stroke-dashoffset", "stroke-opacity", "opacity",
+		"clip-path", "clip-rule", "clippathunits", "mask", "maskunits",
+		"maskcontentunits", "color", "display", "visibility", "overflow",
+		"font-family", "font-size", "font-weight", "font-style",
+		"font-variant", "text-anchor", "text-decoration", "dominant-baseline",
+		"alignment-baseline", "baseline-shift", "letter-spacing",
+		"word-spacing", "dx", "dy", "rotate", "textlength", "lengthadjust",
+		"startoffset", "offset", "stop-color", "stop-opacity", "gradientunits",
+		"gradienttransform", "spreadmethod", "markerwidth", "markerheight",
+		"markerunits", "refx", "refy", "orient", "marker-start", "marker-mid",
+		"marker-end", "patternunits", "patterncontentunits",
+		"patterntransform", "vector-effect", "shape-rendering",
+		"text-rendering", "href", "xlink:href",
+	),
+
+	"math": setOf(
+		"xmlns", "display", "displaystyle", "scriptlevel", "mathvariant",
+		"mathsize", "mathcolor", "mathbackground", "dir", "stretchy", "fence",
+		"separator", "separators", "lspace", "rspace", "accent", "accentunder",
+		"linethickness", "columnalign", "rowalign", "columnspacing",
+		"rowspacing", "columnlines", "rowlines", "frame", "framespacing",
+		"columnspan", "rowspan", "width", "height", "depth", "voffset",
+		"notation", "open", "close", "form", "largeop", "movablelimits",
+		"symmetric", "minsize", "maxsize", "encoding", "actiontype",
+		"selection",
+	),
+}
+
+// attributes whose values are URLs, checked by safeURL
+var urlAttributes = setOf("href", "xlink:href", "src", "cite")
+
+// schemes which URLs may have; URLs without a scheme are relative
+var safeSchemes = setOf("http", "https", "mailto")
+
+// SanitizeHTML strips anything not known to be safe from the HTML, SVG, or
+// MathML so that it can be inlined into a page: scripts, styles, frames,
+// embedded objects, forms, event handlers, and URLs with schemes other than
+// http, https, and mailto. Formatting elements, inline styles, and the
+// shapes of SVG drawings are kept. Comments and doctypes are dropped too.
+//
+// The markup is parsed the way a browser would parse it, and the result is
+// rendered from what was kept, so that markup which would be parsed
+// differently in a page can't slip through.
+func SanitizeHTML(source string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(source), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	out := new(bytes.Buffer)
+	for _, node := range nodes {
+		for _, kept := range sanitizeNode(node) {
+			err := html.Render(out, kept)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+// sanitizeNode returns the node with anything unsafe stripped from it and
+// its children, or its children alone if the node itself isn't safe.
+func sanitizeNode(node *html.Node) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		// text in a style element is CSS, which html.Render doesn't escape
+		if node.Parent != nil && node.Parent.Data == "style" && !safeCSS(node.Data) {
+			return nil
+		}
+
+		return []*html.Node{{Type: html.TextNode, Data: node.Data}}
+
+	case html.ElementNode:
+		// handled below
+
+	default:
+		// comments, doctypes, etc.
+		return nil
+	}
+
+	name := strings.ToLower(node.Data)
+	safe := safeElements[node.Namespace][name]
+
+	if !safe && droppedElements[name] {
+		return nil
+	}
+
+	children := []*html.Node{}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if name == "style" && child.Type != html.TextNode {
+			continue
+		}
+
+		children = append(children, sanitizeNode(child)...)
+	}
+
+	if !safe {
+		return children
+	}
+
+	kept := &html.Node{
+		Type:      html.ElementNode,
+		Data:      node.Data,
+		DataAtom:  node.DataAtom,
+		Namespace: node.Namespace,
+		Attr:      sanitizeAttributes(node),
+	}
+
+	for _, child := range children {
+		kept.AppendChild(child)
+	}
+
+	return []*html.Node{kept}
+}
+
+func sanitizeAttributes(node *html.Node) []html.Attribute {
+	safe := []html.Attribute{}
+	for _, attr := range node.Attr {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" {
+			key = attr.Namespace + ":" + key
+		}
+
+		if !globalAttributes[key] && !safeAttributes[node.Namespace][key] {
+			continue
+		}
+
+		if urlAttributes[key] && !safeURL(node, attr.Val) {
+			continue
+		}
+
+		if (key == "style" || strings.Contains(strings.ToLower(attr.Val), "url(")) && !safeCSS(attr.Val) {
+			continue
+		}
+
+		safe = append(safe, attr)
+	}
+
+	return safe
+}
+
+// safeURL returns true if the URL is relative or has a safe scheme, once
+// stripped of the whitespace and control characters which browsers ignore.
+// Within SVG, only references to elements in the same document are allowed,
+// besides links.
+func safeURL(node *html.Node, value string) bool {
+	url := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if node.Namespace == "svg" && node.Data != "a" {
+		return strings.HasPrefix(url, "#")
+	}
+
+	end := strings.IndexAny(url, "/?#")
+	if end == -1 {
+		end = len(url)
+	}
+
+	colon := strings.Index(url[:end], ":")
+	if colon == -1 {
+		return true
+	}
+
+	return safeSchemes[strings.ToLower(url[:colon])]
+}
+
+// safeCSS returns true if the CSS only refers to elements in the same
+// document, and can't be mistaken for markup or escape the checks with
+// escape sequences.
+func safeCSS(css string) bool {
+	lower := strings.ToLower(css)
+
+	if strings.ContainsAny(lower, `<>\`) {
+		return false
+	}
+
+	for _, unsafe := range []string{"@import", "expression", "javascript:", "behavior", "binding"} {
+		if strings.Contains(lower, unsafe) {
+			return false
+		}
+	}
+
+	for rest := lower; ; {
+		idx := strings.Index(rest, "url(")
+		if idx == -1 {
+			return true
+		}
+
+		rest = strings.TrimLeft(rest[idx+len("url("):], " \t\n\r\f'\"")
+		if !strings.HasPrefix(rest, "#") {
+			return false
+		}
+	}
+}
+
+func setOf(names ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+
+	return set
+}
@@ -0,0 +1,206 @@
+package booklit
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// SARIFSchema is the JSON schema of the SARIF 2.1.0 logs written by
+// WriteSARIF.
+const SARIFSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// WriteSARIF writes a SARIF 2.1.0 log with a result for each error, e.g. for
+// annotating .lit files in code scanning or review tools. Multiple errors
+// collected by BuildErrors each get their own result. If err is nil, the log
+// has no results.
+func WriteSARIF(out io.Writer, err error) error {
+	diagnostics := &Diagnostics{}
+	diagnostics.RecordError(err)
+	return diagnostics.WriteSARIF(out)
+}
+
+// WriteSARIF writes a SARIF 2.1.0 log with a result for each error and
+// warning, at the level of each.
+func (diagnostics *Diagnostics) WriteSARIF(out io.Writer) error {
+	diagnostics.lock.Lock()
+	defer diagnostics.lock.Unlock()
+
+	results := []sarifResult{}
+	rules := []sarifRule{}
+	seenRules := map[string]bool{}
+
+	add := func(e error, level string) {
+		kind := errorType(e)
+		ruleID := strings.Replace(kind, " ", "-", -1)
+
+		if !seenRules[ruleID] {
+			rules = append(rules, sarifRule{
+				ID:               ruleID,
+				ShortDescription: sarifMessage{Text: kind},
+			})
+
+			seenRules[ruleID] = true
+		}
+
+		result := sarifResult{
+			RuleID:  ruleID,
+			Level:   level,
+			Message: sarifMessage{Text: e.Error()},
+		}
+
+		var related []ErrorLocation
+		switch typed := e.(type) {
+		case AmbiguousReferenceError:
+			related = typed.DefinedLocations
+		case PageCollisionError:
+			related = typed.DefinedLocations
+		}
+
+		if loc, ok := errorLocation(e); ok {
+			result.Locations = append(result.Locations, sarifLocationOf(loc))
+		} else if len(related) > 0 {
+			// no location of its own, e.g. a page collision
+			result.Locations = append(result.Locations, sarifLocationOf(related[0]))
+			related = related[1:]
+		}
+
+		for _, loc := range related {
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocationOf(loc))
+		}
+
+		results = append(results, result)
+	}
+
+	for _, e := range diagnostics.Errors {
+		add(e, "error")
+	}
+
+	for _, warning := range diagnostics.Warnings {
+		add(warning, "warning")
+	}
+
+	log := sarifLog{
+		Schema:  SARIFSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "booklit",
+						Version:        Version,
+						InformationURI: "https://booklit.page",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// innermostError returns the error returned by a function if it has a
+// location of its own, e.g. a parse error in an included section, so that
+// it can be annotated more precisely.
+func innermostError(err error) error {
+	failed, ok := err.(FailedFunctionError)
+	if !ok {
+		return err
+	}
+
+	if _, ok := errorLocation(failed.Err); !ok {
+		return err
+	}
+
+	return innermostError(failed.Err)
+}
+
+func sarifLocationOf(loc ErrorLocation) sarifLocation {
+	uri := &url.URL{Path: filepath.ToSlash(loc.FilePath)}
+	if filepath.IsAbs(loc.FilePath) {
+		uri.Scheme = "file"
+	}
+
+	physical := sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: uri.String()},
+	}
+
+	if loc.NodeLocation.Line != 0 {
+		region := &sarifRegion{
+			StartLine:   loc.NodeLocation.Line,
+			StartColumn: loc.NodeLocation.Col,
+		}
+
+		if loc.Length > 0 {
+			region.EndColumn = loc.NodeLocation.Col + loc.Length
+		}
+
+		physical.Region = region
+	}
+
+	return sarifLocation{PhysicalLocation: physical}
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
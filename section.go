@@ -1,9 +1,12 @@
 package booklit
 
 import (
+	"context"
 	"fmt"
-	"regexp"
+	"io/fs"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/agext/levenshtein"
 	"github.com/vito/booklit/ast"
@@ -18,32 +21,266 @@ type Section struct {
 	PrimaryTag Tag
 	Tags       []Tag
 
+	// former tags of the section, e.g. from before it was renamed, which are
+	// kept among its tags so that references to them still work; see
+	// AddAlias
+	Aliases []string
+
 	Parent   *Section
 	Children []*Section
 
+	Figures []*Figure
+
+	// footnotes in the section's body, in the order they appear
+	Footnotes []*Footnote
+
+	// collect the footnotes in the section and its children at the end of
+	// the section, rather than at the end of its page
+	CollectEndnotes bool
+
+	// events announced by the section, e.g. releases or meetups
+	Events []*Event
+
+	// SPDX license identifier for the section's content
+	License      string
+	Attributions []Attribution
+
+	// files other than the section's own which its content was generated
+	// from, e.g. by plugins; see AddDependency
+	Dependencies []string
+
 	Style    string
 	Partials Partials
 
 	SplitSections        bool
 	PreventSplitSections bool
 
+	// split child sections onto their own pages if any of them exceeds this
+	// many words, in addition to \split-sections
+	SplitSectionsOverWords int
+
 	ResetDepth bool
 
+	// render children in a shuffled order, e.g. for flashcards; a non-zero
+	// seed makes the order reproducible
+	ShuffleChildren bool
+	ShuffleSeed     int64
+
 	OmitChildrenFromTableOfContents bool
 
+	// leave the section, and so its children, out of tables of contents
+	HideFromTableOfContents bool
+
+	// if non-zero, only list this many levels of the section's descendants
+	// in tables of contents; see TableOfContentsChildren
+	TableOfContentsDepth int
+
+	// kind of front matter the section represents, e.g. "preface"; front
+	// matter sections are not numbered
+	FrontMatter FrontMatter
+
+	Locale string
+
+	// direction of the section's text; inherited from the parent if empty
+	Direction Direction
+
+	// extra classes and data attributes for the element wrapping the
+	// section, e.g. for styling it from a theme
+	Classes        []string
+	DataAttributes map[string]string
+
+	// generates default tags from titles; inherited from the parent if nil
+	Slugifier *Slugifier
+
+	// HTTP status code, e.g. 404, for which the section is served in place
+	// of the host's default error page
+	ErrorPage int
+
+	// engine the section will be rendered with, if known in advance
+	Engine RenderingEngine
+
+	// prepended to the URLs of pages in the section's book when they're
+	// linked to from another book in a workspace; see render.LinkURL
+	URLPrefix string
+
+	// how pages in the section's book are named and linked to; only
+	// consulted on the top-level section
+	URLStyle URLStyle
+
+	// paths of the pages beneath the section; inherited from the parent if
+	// empty
+	Permalinks Permalinks
+
+	// paths of the pages beneath the sections with the given tags, e.g. from
+	// config, unless they set Permalinks themselves. Only consulted on the
+	// top-level section.
+	PermalinksByTag map[string]Permalinks
+
+	// whether any page's permalink nests it in a directory, so that pages
+	// and assets must be linked to absolutely; set upon resolving. Only
+	// consulted on the top-level section.
+	NestedPermalinks bool
+
+	// date the section was published, e.g. for a blog post, which dated
+	// permalinks are generated from
+	Published time.Time
+
+	// time the section's book is built as of, e.g. for \today; the current
+	// time if zero. Only consulted on the top-level section.
+	BuildTime time.Time
+
+	// tags of the sections which should be read before this one, e.g. for a
+	// tutorial building on others
+	Requires []string
+
+	// tags of the sections ordered by their prerequisites into the section's
+	// LearningPath partial upon resolving; if empty but not nil, the
+	// section's children are ordered instead
+	LearningPath []string
+
+	// path the book is hosted under, e.g. /docs/; if set, links to pages and
+	// assets are absolute. Only consulted on the top-level section.
+	BasePath string
+
+	// rules for rewriting the URLs of links, images, and assets in the
+	// section's book. Only consulted on the top-level section.
+	LinkRewrites []LinkRewrite
+
+	// collects warnings reported for the section's book. Only consulted on
+	// the top-level section.
+	Warnings *Warnings
+
+	// limits what the section's book may do, e.g. for building untrusted
+	// input; see SafeMode. Only consulted on the top-level section.
+	Safe *SafeMode
+
+	// flags enabled for the section's book, e.g. enterprise, which
+	// conditional content is included by. Only consulted on the top-level
+	// section.
+	Flags []string
+
+	// URL of the execution backend which playgrounds run their code with,
+	// unless given their own. Only consulted on the top-level section.
+	PlaygroundURL string
+
+	// commands which diagrams are rendered to SVG with at build time, by the
+	// language of the diagram, e.g. mermaid or dot; diagrams in languages
+	// without one are left to the renderer. Only consulted on the top-level
+	// section.
+	DiagramCommands map[string][]string
+
+	// commands which math is rendered to HTML with at build time, e.g. KaTeX,
+	// rather than being converted to MathML; display math is rendered with
+	// the first if the second isn't set. Only consulted on the top-level
+	// section.
+	MathCommand        []string
+	DisplayMathCommand []string
+
+	// catalogs of translated strings for the section's book, along with the
+	// locale they fall back to, e.g. the language a book was first written
+	// in. Only consulted on the top-level section.
+	Translations  Translations
+	DefaultLocale string
+
+	// looks up the issues referred to in the section's book, e.g. on GitHub.
+	// Only consulted on the top-level section.
+	IssueTracker IssueTracker
+
+	// generates variants of the images referred to in the section's book,
+	// e.g. resized for smaller screens. Only consulted on the top-level
+	// section.
+	ImageProcessor ImageProcessor
+
+	// file system which the section's book and the files it refers to are
+	// read from, with paths relative to its root; if nil, they're read from
+	// the operating system's, relative to the working directory. Only
+	// consulted on the top-level section.
+	FS fs.FS
+
+	// context the section's book is being loaded in; see Context
+	ctx context.Context
+
+	EmojiShortcodes bool
+	EmojiImages     string
+
+	// preserve the line breaks within the paragraphs of the section and its
+	// children, e.g. for poetry, rather than joining their lines
+	HardWraps bool
+
+	// keep units attached to the numbers before them within the prose of the
+	// section and its children, e.g. '10 km', with non-breaking spaces
+	AttachUnits bool
+
 	Processor       SectionProcessor
 	PluginFactories []PluginFactory
 	Plugins         []Plugin
 
 	Location       ast.Location
 	InvokeLocation ast.Location
+
+	// syntax tree the section was evaluated from, e.g. for checking whether
+	// its source has changed; nil if it was constructed, e.g. imported
+	Node ast.Node
+
+	// guards Partials, Dependencies, and Plugins, which may be modified while
+	// other pages are rendered concurrently
+	lock sync.RWMutex
 }
 
 type Partials map[string]Content
 
+// URLStyle determines the file each page is written to and the URL used to
+// link to it.
+type URLStyle string
+
+const (
+	// pages are written to tag.html and linked to as such; the default
+	URLStyleFiles URLStyle = "files"
+
+	// pages are written to tag/index.html and linked to as /tag/, with the
+	// index page linked to as /
+	URLStyleDirectories URLStyle = "directories"
+
+	// pages are written to tag.html but linked to as tag, for servers which
+	// resolve the extension themselves
+	URLStyleExtensionless URLStyle = "extensionless"
+)
+
+var URLStyles = []URLStyle{
+	URLStyleFiles,
+	URLStyleDirectories,
+	URLStyleExtensionless,
+}
+
+type FrontMatter string
+
+const (
+	FrontMatterTitlePage       FrontMatter = "title-page"
+	FrontMatterCopyright       FrontMatter = "copyright"
+	FrontMatterDedication      FrontMatter = "dedication"
+	FrontMatterForeword        FrontMatter = "foreword"
+	FrontMatterPreface         FrontMatter = "preface"
+	FrontMatterAcknowledgments FrontMatter = "acknowledgments"
+)
+
+var FrontMatterKinds = []FrontMatter{
+	FrontMatterTitlePage,
+	FrontMatterCopyright,
+	FrontMatterDedication,
+	FrontMatterForeword,
+	FrontMatterPreface,
+	FrontMatterAcknowledgments,
+}
+
 type SectionProcessor interface {
 	EvaluateFile(*Section, string, []PluginFactory) (*Section, error)
 	EvaluateNode(*Section, ast.Node, []PluginFactory) (*Section, error)
+
+	// EvaluateContent evaluates the node as content of the section, rather
+	// than as a section of its own, e.g. for functions which only evaluate
+	// their arguments conditionally. It returns nil if the node evaluates to
+	// no content, e.g. only a section.
+	EvaluateContent(*Section, ast.Node) (Content, error)
 }
 
 type Tag struct {
@@ -91,6 +328,10 @@ func (con *Section) SetTitle(title Content, loc ast.Location, tags ...string) {
 		con.SetTag(name, title, loc)
 	}
 
+	for _, alias := range con.Aliases {
+		con.SetTag(alias, title, loc)
+	}
+
 	con.Title = title
 	con.PrimaryTag = con.Tags[0]
 }
@@ -124,8 +365,16 @@ func (con *Section) SetTagAnchored(name string, title Content, loc ast.Location,
 	})
 }
 
+// AddAlias adds a former tag of the section, e.g. from before it was renamed.
+// References to the alias refer to the section, and its page, if it has one
+// of its own, is redirected from the page the alias would have named.
+func (con *Section) AddAlias(name string, loc ast.Location) {
+	con.Aliases = append(con.Aliases, name)
+	con.SetTag(name, con.Title, loc)
+}
+
 func (con *Section) Number() string {
-	if con.Parent == nil {
+	if con.Parent == nil || con.FrontMatter != "" {
 		return ""
 	}
 
@@ -136,7 +385,9 @@ func (con *Section) Number() string {
 			break
 		}
 
-		selfIndex++
+		if child.FrontMatter == "" {
+			selfIndex++
+		}
 	}
 
 	if parentNumber == "" {
@@ -275,6 +526,9 @@ func (con *Section) SimilarTags(tagName string) []Tag {
 }
 
 func (con *Section) SetPartial(name string, value Content) {
+	con.lock.Lock()
+	defer con.lock.Unlock()
+
 	if con.Partials == nil {
 		con.Partials = Partials{}
 	}
@@ -283,12 +537,136 @@ func (con *Section) SetPartial(name string, value Content) {
 }
 
 func (con *Section) Partial(name string) Content {
+	con.lock.RLock()
+	defer con.lock.RUnlock()
+
 	return con.Partials[name]
 }
 
+// AddDependency records that the section's content was generated from the
+// file at the given path, e.g. one read by a plugin, so that incremental
+// builds re-render the section whenever the file changes.
+func (con *Section) AddDependency(path string) {
+	con.lock.Lock()
+	defer con.lock.Unlock()
+
+	for _, dep := range con.Dependencies {
+		if dep == path {
+			return
+		}
+	}
+
+	con.Dependencies = append(con.Dependencies, path)
+}
+
 func (con *Section) UsePlugin(pf PluginFactory) {
+	// construct the plugin without holding the lock, since factories are
+	// given the section and typically configure it
+	plugin := pf(con)
+
+	con.lock.Lock()
+	defer con.lock.Unlock()
+
 	con.PluginFactories = append(con.PluginFactories, pf)
-	con.Plugins = append(con.Plugins, pf(con))
+	con.Plugins = append(con.Plugins, plugin)
+}
+
+// AllFigures returns the figures in the section and its children,
+// recursively, in the order they appear.
+func (con *Section) AllFigures() []*Figure {
+	figures := []*Figure{}
+	figures = append(figures, con.Figures...)
+
+	for _, child := range con.Children {
+		figures = append(figures, child.AllFigures()...)
+	}
+
+	return figures
+}
+
+// AllFootnotes returns the footnotes in the section and its children,
+// recursively, in the order they appear.
+func (con *Section) AllFootnotes() []*Footnote {
+	notes := []*Footnote{}
+	notes = append(notes, con.Footnotes...)
+
+	for _, child := range con.Children {
+		notes = append(notes, child.AllFootnotes()...)
+	}
+
+	return notes
+}
+
+// CollectsEndnotes reports whether the footnotes in the section are listed at
+// its end, either because it is configured to collect them or because it is
+// rendered on its own page.
+func (con *Section) CollectsEndnotes() bool {
+	return con.CollectEndnotes || con.Parent == nil || con.Parent.SplitSections
+}
+
+// Endnotes returns the footnotes listed at the end of the section: those in
+// the section and its children, recursively, except for children which
+// collect their own. If the section does not collect endnotes, it returns
+// none.
+func (con *Section) Endnotes() []*Footnote {
+	if !con.CollectsEndnotes() {
+		return nil
+	}
+
+	return con.endnotes()
+}
+
+func (con *Section) endnotes() []*Footnote {
+	notes := []*Footnote{}
+	notes = append(notes, con.Footnotes...)
+
+	for _, child := range con.Children {
+		if child.CollectsEndnotes() {
+			continue
+		}
+
+		notes = append(notes, child.endnotes()...)
+	}
+
+	return notes
+}
+
+// FindErrorPage returns the section designated as the error page for the
+// given HTTP status code, searching the section and its children.
+func (con *Section) FindErrorPage(status int) *Section {
+	if con.ErrorPage == status {
+		return con
+	}
+
+	for _, child := range con.Children {
+		if page := child.FindErrorPage(status); page != nil {
+			return page
+		}
+	}
+
+	return nil
+}
+
+// AllAttributions returns the license of the section and the attributions
+// within it, followed by those of its children, recursively.
+func (con *Section) AllAttributions() []Attribution {
+	attrs := []Attribution{}
+
+	if con.License != "" {
+		attrs = append(attrs, Attribution{
+			Subject: con.Title,
+			License: con.License,
+			Section: con,
+		})
+	}
+
+	attrs = append(attrs, con.Attributions...)
+
+	for _, child := range con.Children {
+		attrs = append(attrs, child.AllAttributions()...)
+	}
+
+	return attrs
 }
 
 func (con *Section) Depth() int {
@@ -307,6 +685,34 @@ func (con *Section) PageDepth() int {
 	return con.Parent.PageDepth() + 1
 }
 
+// TableOfContentsChildren returns the children to list beneath the section in
+// tables of contents: none if it omits them or they are deeper than the
+// TableOfContentsDepth of the section or any of its ancestors allows, and
+// otherwise those which aren't hidden.
+func (con *Section) TableOfContentsChildren() []*Section {
+	if con.OmitChildrenFromTableOfContents {
+		return nil
+	}
+
+	levels := 1
+	for ancestor := con; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor.TableOfContentsDepth != 0 && levels > ancestor.TableOfContentsDepth {
+			return nil
+		}
+
+		levels++
+	}
+
+	children := []*Section{}
+	for _, child := range con.Children {
+		if !child.HideFromTableOfContents {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
 func (con *Section) SplitSectionsPrevented() bool {
 	if con.PreventSplitSections {
 		return true
@@ -319,6 +725,126 @@ func (con *Section) SplitSectionsPrevented() bool {
 	return false
 }
 
+// WordCount estimates the number of words in the section's title, body, and
+// children.
+func (con *Section) WordCount() int {
+	count := 0
+
+	if con.Title != nil {
+		count += len(strings.Fields(con.Title.String()))
+	}
+
+	if con.Body != nil {
+		count += len(strings.Fields(con.Body.String()))
+	}
+
+	for _, child := range con.Children {
+		count += child.WordCount()
+	}
+
+	return count
+}
+
+// RenderingEngine returns the engine the section will be rendered with, or
+// nil if it is not known.
+func (con *Section) RenderingEngine() RenderingEngine {
+	if con.Engine != nil {
+		return con.Engine
+	}
+
+	if con.Parent != nil {
+		return con.Parent.RenderingEngine()
+	}
+
+	return nil
+}
+
+// InheritedSlugifier returns the slugifier of the nearest section which
+// configures one, or the default slugifier if none do.
+func (con *Section) InheritedSlugifier() Slugifier {
+	if con.Slugifier != nil {
+		return *con.Slugifier
+	}
+
+	if con.Parent != nil {
+		return con.Parent.InheritedSlugifier()
+	}
+
+	return Slugifier{}
+}
+
+func (con *Section) InheritedLocale() string {
+	if con.Locale != "" {
+		return con.Locale
+	}
+
+	if con.Parent != nil {
+		return con.Parent.InheritedLocale()
+	}
+
+	return ""
+}
+
+// FlagEnabled reports whether the given flag is enabled for the section's
+// book.
+func (con *Section) FlagEnabled(flag string) bool {
+	for _, enabled := range con.Top().Flags {
+		if enabled == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (con *Section) EmojiShortcodesEnabled() bool {
+	if con.EmojiShortcodes {
+		return true
+	}
+
+	if con.Parent != nil && con.Parent.EmojiShortcodesEnabled() {
+		return true
+	}
+
+	return false
+}
+
+func (con *Section) HardWrapsEnabled() bool {
+	if con.HardWraps {
+		return true
+	}
+
+	if con.Parent != nil && con.Parent.HardWrapsEnabled() {
+		return true
+	}
+
+	return false
+}
+
+func (con *Section) AttachUnitsEnabled() bool {
+	if con.AttachUnits {
+		return true
+	}
+
+	if con.Parent != nil && con.Parent.AttachUnitsEnabled() {
+		return true
+	}
+
+	return false
+}
+
+func (con *Section) InheritedEmojiImages() string {
+	if con.EmojiImages != "" {
+		return con.EmojiImages
+	}
+
+	if con.Parent != nil {
+		return con.Parent.InheritedEmojiImages()
+	}
+
+	return ""
+}
+
 func (con *Section) filterTags(up bool, exclude *Section, match func(string) bool) []Tag {
 	tags := []Tag{}
 
@@ -345,22 +871,6 @@ func (con *Section) filterTags(up bool, exclude *Section, match func(string) boo
 	return tags
 }
 
-var whitespaceRegexp = regexp.MustCompile(`\s+`)
-var specialCharsRegexp = regexp.MustCompile(`[^[:alnum:]_\-]`)
-
 func (con *Section) defaultTag(title Content) string {
-	return strings.ToLower(
-		specialCharsRegexp.ReplaceAllString(
-			whitespaceRegexp.ReplaceAllString(
-				strings.Replace(
-					StripAux(title).String(),
-					" & ",
-					" and ",
-					-1,
-				),
-				"-",
-			),
-			"",
-		),
-	)
+	return con.InheritedSlugifier().Slug(StripAux(title).String())
 }
@@ -0,0 +1,92 @@
+package booklit
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slugifier generates the default tag for a section from its title. The tag
+// is also used for the section's file name and its anchor on the page.
+//
+// The zero value lowercases titles and drops any characters other than ASCII
+// letters, digits, '-', and '_', replacing whitespace with '-' and ' & ' with
+// ' and '.
+type Slugifier struct {
+	// Transliterate letters to ASCII where possible, e.g. 'é' to 'e' or 'ß'
+	// to 'ss', rather than dropping them.
+	Transliterate bool
+
+	// Case to convert slugs to. Defaults to SlugCaseLower.
+	Case SlugCase
+}
+
+type SlugCase string
+
+const (
+	SlugCaseLower    SlugCase = "lower"
+	SlugCasePreserve SlugCase = "preserve"
+)
+
+var whitespaceRegexp = regexp.MustCompile(`\s+`)
+var specialCharsRegexp = regexp.MustCompile(`[^[:alnum:]_\-]`)
+
+// Slug converts the given title to a tag.
+func (slugifier Slugifier) Slug(title string) string {
+	slug := strings.Replace(title, " & ", " and ", -1)
+
+	if slugifier.Transliterate {
+		slug = transliterate(slug)
+	}
+
+	slug = specialCharsRegexp.ReplaceAllString(
+		whitespaceRegexp.ReplaceAllString(slug, "-"),
+		"",
+	)
+
+	if slugifier.Case == SlugCasePreserve {
+		return slug
+	}
+
+	return strings.ToLower(slug)
+}
+
+// letters which don't decompose into an ASCII letter and combining marks
+var transliterations = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'Æ': "AE",
+	'œ': "oe",
+	'Œ': "OE",
+	'ø': "o",
+	'Ø': "O",
+	'đ': "d",
+	'Đ': "D",
+	'ð': "d",
+	'Ð': "D",
+	'ł': "l",
+	'Ł': "L",
+	'þ': "th",
+	'Þ': "TH",
+	'ı': "i",
+}
+
+func transliterate(str string) string {
+	out := new(strings.Builder)
+	for _, r := range norm.NFD.String(str) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if ascii, found := transliterations[r]; found {
+			out.WriteString(ascii)
+			continue
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
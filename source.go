@@ -0,0 +1,45 @@
+package booklit
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// OpenFile opens the file at the path in the file system, or relative to the
+// working directory if the file system is nil. Paths are converted to the
+// slash-separated form that fs.FS expects.
+func OpenFile(fsys fs.FS, path string) (fs.File, error) {
+	if fsys == nil {
+		return os.Open(path)
+	}
+
+	return fsys.Open(fsPath(path))
+}
+
+// StatFile returns the info of the file at the path in the file system, or
+// relative to the working directory if the file system is nil.
+func StatFile(fsys fs.FS, path string) (fs.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(path)
+	}
+
+	return fs.Stat(fsys, fsPath(path))
+}
+
+// ReadFile reads the file at the path from the book's file system; see
+// Section.FS. Plugins which read files should check them with CheckFile
+// first.
+func (con *Section) ReadFile(path string) ([]byte, error) {
+	fsys := con.Top().FS
+	if fsys == nil {
+		return ioutil.ReadFile(path)
+	}
+
+	return fs.ReadFile(fsys, fsPath(path))
+}
+
+func fsPath(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
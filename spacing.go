@@ -0,0 +1,59 @@
+package booklit
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Spaces which control where lines may break, for use in prose where the
+// renderer's automatic handling isn't enough. Renderers for print targets
+// convert them to their own equivalents, e.g. ~ in LaTeX.
+const (
+	// a space which lines never break at, e.g. between a number and its unit
+	NonBreakingSpace = "\u00a0"
+
+	// a narrow space, e.g. between initials or around a dash
+	ThinSpace = "\u2009"
+
+	// a point within a word which lines may break at, e.g. in a long path or
+	// URL, which is otherwise invisible
+	WordBreak = "\u200b"
+)
+
+// Units which AttachUnits keeps attached to the numbers before them. Units
+// which are also common words, e.g. 'in', are left out.
+var Units = []string{
+	"nm", "µm", "μm", "mm", "cm", "m", "km", "ft", "mi",
+	"mg", "g", "kg", "lb", "lbs", "oz",
+	"ns", "µs", "μs", "ms", "s", "sec", "min", "h", "hr", "hrs",
+	"B", "KB", "kB", "MB", "GB", "TB", "PB", "KiB", "MiB", "GiB", "TiB",
+	"kbps", "Kbps", "Mbps", "Gbps",
+	"Hz", "kHz", "MHz", "GHz",
+	"mV", "V", "kV", "mA", "A", "W", "kW", "MW", "kWh", "mAh", "Ω",
+	"°C", "°F", "°", "K",
+	"mL", "L", "px", "pt", "em", "rem", "dpi", "%", "‰",
+}
+
+var unitsRegexp = regexp.MustCompile(unitsPattern())
+
+func unitsPattern() string {
+	quoted := []string{}
+	for _, unit := range Units {
+		quoted = append(quoted, regexp.QuoteMeta(unit))
+	}
+
+	// prefer the longest unit, e.g. 'MB' over 'M'
+	sort.SliceStable(quoted, func(i, j int) bool {
+		return len(quoted[i]) > len(quoted[j])
+	})
+
+	return `(\d) +(` + strings.Join(quoted, "|") + `)([^\p{L}\p{N}]|$)`
+}
+
+// AttachUnits replaces the spaces between numbers and the Units following
+// them with non-breaking spaces, so that lines never break between them, e.g.
+// in '10 km'.
+func AttachUnits(str string) string {
+	return unitsRegexp.ReplaceAllString(str, "${1}"+NonBreakingSpace+"${2}${3}")
+}
@@ -1,9 +1,18 @@
 package stages
 
-import "github.com/vito/booklit"
+import (
+	"math/rand"
+	"time"
+
+	"github.com/vito/booklit"
+)
 
 type Collect struct {
 	Section *booklit.Section
+
+	// number of figures collected so far, shared with the collectors of
+	// child sections so that figures are numbered in the order they appear
+	figures *int
 }
 
 func (collect *Collect) VisitString(booklit.String) error {
@@ -48,6 +57,16 @@ func (collect *Collect) VisitReference(con *booklit.Reference) error {
 }
 
 func (collect *Collect) VisitSection(con *booklit.Section) error {
+	if con.SplitSectionsOverWords > 0 && !con.SplitSections && !con.SplitSectionsPrevented() {
+		for _, child := range con.Children {
+			if child.WordCount() > con.SplitSectionsOverWords {
+				con.SplitSections = true
+				con.ResetDepth = true
+				break
+			}
+		}
+	}
+
 	err := con.Title.Visit(collect)
 	if err != nil {
 		return err
@@ -65,11 +84,39 @@ func (collect *Collect) VisitSection(con *booklit.Section) error {
 		}
 	}
 
+	for _, attr := range con.Attributions {
+		for _, c := range []booklit.Content{attr.Subject, attr.Credit} {
+			if c == nil {
+				continue
+			}
+
+			err = c.Visit(collect)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if con.ShuffleChildren {
+		seed := con.ShuffleSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+
+		rand.New(rand.NewSource(seed)).Shuffle(len(con.Children), func(i, j int) {
+			con.Children[i], con.Children[j] = con.Children[j], con.Children[i]
+		})
+
+		// sections may be collected more than once; only shuffle the first time
+		con.ShuffleChildren = false
+	}
+
 	// TODO: this probably does redundant resolving, since i think the section
 	// was loaded via a processor in the first place
 	for _, child := range con.Children {
 		subCollector := &Collect{
 			Section: child,
+			figures: collect.figureCount(),
 		}
 
 		err := child.Visit(subCollector)
@@ -142,6 +189,51 @@ func (collect *Collect) VisitTable(con booklit.Table) error {
 	return nil
 }
 
+func (collect *Collect) VisitFigure(con *booklit.Figure) error {
+	count := collect.figureCount()
+	*count++
+
+	con.Section = collect.Section
+	con.SetNumber(*count)
+	collect.Section.Figures = append(collect.Section.Figures, con)
+
+	if con.TagName != "" {
+		collect.Section.SetTagAnchored(con.TagName, con.Title(), con.Location, con.Caption, con.TagName)
+	}
+
+	err := con.Content.Visit(collect)
+	if err != nil {
+		return err
+	}
+
+	return con.Caption.Visit(collect)
+}
+
+// figureCount returns the number of figures collected so far, shared by the
+// collectors of the section's children.
+func (collect *Collect) figureCount() *int {
+	if collect.figures == nil {
+		collect.figures = new(int)
+	}
+
+	return collect.figures
+}
+
+func (collect *Collect) VisitFootnote(con *booklit.Footnote) error {
+	con.Section = collect.Section
+	collect.Section.Footnotes = append(collect.Section.Footnotes, con)
+
+	return con.Content.Visit(collect)
+}
+
+func (collect *Collect) VisitListOfFigures(booklit.ListOfFigures) error {
+	return nil
+}
+
+func (collect *Collect) VisitAttributions(booklit.Attributions) error {
+	return nil
+}
+
 func (collect *Collect) VisitDefinitions(con booklit.Definitions) error {
 	for _, def := range con {
 		err := def.Subject.Visit(collect)
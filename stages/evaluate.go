@@ -1,20 +1,49 @@
 package stages
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/vito/booklit"
 	"github.com/vito/booklit/ast"
+	"github.com/vito/booklit/emoji"
 )
 
 type Evaluate struct {
 	Section *booklit.Section
 
+	// If set, the time spent in each function invocation is recorded.
+	Profile *Profile
+
+	// If set, undefined functions and unknown plugins are replaced with a
+	// placeholder and a warning rather than failing evaluation.
+	IgnoreMissingPlugins bool
+
+	// If set, each function invocation is logged along with its arguments
+	// and the type of content it returned.
+	Debug bool
+
+	// If set, errors from function invocations are recorded and evaluation
+	// carries on past them until the limit is reached.
+	Errors *booklit.BuildErrors
+
 	Result booklit.Content
+
+	// set while evaluating preformatted text, e.g. code blocks, which is
+	// left as-is rather than treated as prose
+	preformatted bool
 }
 
 func (eval *Evaluate) VisitString(str ast.String) error {
+	if eval.Section.EmojiShortcodesEnabled() && !eval.preformatted {
+		eval.Result = booklit.Append(eval.Result, emoji.Expand(eval.Section, string(str)))
+		return nil
+	}
+
 	eval.Result = booklit.Append(eval.Result, booklit.String(str))
 	return nil
 }
@@ -43,6 +72,10 @@ func (eval *Evaluate) VisitParagraph(node ast.Paragraph) error {
 		}
 
 		if eval.Result != nil {
+			if eval.Section.AttachUnitsEnabled() {
+				eval.Result = attachUnits(eval.Result)
+			}
+
 			para = append(para, eval.Result)
 		}
 	}
@@ -61,14 +94,41 @@ func (eval *Evaluate) VisitParagraph(node ast.Paragraph) error {
 		return nil
 	}
 
+	if len(para) > 1 && eval.Section.HardWrapsEnabled() {
+		eval.Result = booklit.Append(previous, para.Lines())
+		return nil
+	}
+
 	eval.Result = booklit.Append(previous, para)
 
 	return nil
 }
 
+// attachUnits attaches units to numbers in the line's own text, leaving the
+// content returned by functions, e.g. code, as-is.
+func attachUnits(line booklit.Content) booklit.Content {
+	switch con := line.(type) {
+	case booklit.String:
+		return booklit.String(booklit.AttachUnits(string(con)))
+	case booklit.Sequence:
+		attached := booklit.Sequence{}
+		for _, c := range con {
+			attached = append(attached, attachUnits(c))
+		}
+
+		return attached
+	default:
+		return line
+	}
+}
+
 func (eval *Evaluate) VisitPreformatted(node ast.Preformatted) error {
 	previous := eval.Result
 
+	wasPreformatted := eval.preformatted
+	eval.preformatted = true
+	defer func() { eval.preformatted = wasPreformatted }()
+
 	pre := booklit.Preformatted{}
 	for _, line := range node {
 		eval.Result = nil
@@ -89,12 +149,37 @@ func (eval *Evaluate) VisitPreformatted(node ast.Preformatted) error {
 }
 
 func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
+	err := eval.invoke(invoke)
+
+	// a cancelled build stops rather than carrying on past it
+	if err != nil && eval.Errors != nil && eval.Section.Context().Err() == nil {
+		return eval.Errors.Record(err)
+	}
+
+	return err
+}
+
+func (eval *Evaluate) invoke(invoke ast.Invoke) error {
+	ctx := eval.Section.Context()
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
 	eval.Section.InvokeLocation = invoke.Location
 
 	methodName := invoke.Method()
 
 	var method reflect.Value
 	for _, p := range eval.Section.Plugins {
+		if dynamic, ok := p.(booklit.DynamicPlugin); ok {
+			method = dynamicFunction(dynamic, invoke.Function)
+			if method.IsValid() {
+				break
+			}
+		}
+
 		value := reflect.ValueOf(p)
 		method = value.MethodByName(methodName)
 		if method.IsValid() {
@@ -102,11 +187,16 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 		}
 	}
 
+	if !method.IsValid() && eval.IgnoreMissingPlugins {
+		return eval.placeholder(invoke)
+	}
+
 	if !method.IsValid() {
 		return booklit.UndefinedFunctionError{
 			Function: invoke.Function,
 			ErrorLocation: booklit.ErrorLocation{
 				FilePath:     eval.Section.FilePath(),
+				FS:           eval.Section.Top().FS,
 				NodeLocation: invoke.Location,
 				Length:       len("\\" + invoke.Function),
 			},
@@ -117,7 +207,17 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 
 	rawArgs := invoke.Arguments
 
-	argc := methodType.NumIn()
+	argv := []reflect.Value{}
+
+	// methods may take the book's context first, e.g. to respect the build's
+	// deadline
+	if takesContext(methodType) {
+		argv = append(argv, reflect.ValueOf(ctx))
+	}
+
+	offset := len(argv)
+
+	argc := methodType.NumIn() - offset
 	if methodType.IsVariadic() {
 		argc--
 
@@ -130,20 +230,19 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 		}
 	}
 
-	argv := make([]reflect.Value, argc)
 	for i := 0; i < argc; i++ {
-		t := methodType.In(i)
+		t := methodType.In(offset + i)
 		arg, err := eval.convert(t, rawArgs[i])
 		if err != nil {
 			return err
 		}
 
-		argv[i] = arg
+		argv = append(argv, arg)
 	}
 
 	if methodType.IsVariadic() {
 		variadic := rawArgs[argc:]
-		variadicType := methodType.In(argc)
+		variadicType := methodType.In(offset + argc)
 
 		subType := variadicType.Elem()
 		for _, varg := range variadic {
@@ -156,7 +255,30 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 		}
 	}
 
-	result := method.Call(argv)
+	start := time.Now()
+
+	result, err := call(ctx, method, argv)
+	if err != nil {
+		return booklit.FailedFunctionError{
+			Function: invoke.Function,
+			Err:      err,
+
+			ErrorLocation: booklit.ErrorLocation{
+				FilePath:     eval.Section.FilePath(),
+				FS:           eval.Section.Top().FS,
+				NodeLocation: invoke.Location,
+				Length:       len("\\" + invoke.Function),
+			},
+		}
+	}
+
+	if eval.Profile != nil {
+		eval.Profile.Record(invoke.Function, profileSection(eval.Section), time.Since(start))
+	}
+
+	if eval.Debug {
+		eval.debugInvoke(invoke, argv, result)
+	}
 
 	switch methodType.NumOut() {
 	case 0:
@@ -167,6 +289,10 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 
 		switch reflect.New(valType).Interface().(type) {
 		case *error:
+			if val != nil && eval.ignoreUnknownPlugin(invoke, val.(error)) {
+				return nil
+			}
+
 			if val != nil {
 				return booklit.FailedFunctionError{
 					Function: invoke.Function,
@@ -174,13 +300,16 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 
 					ErrorLocation: booklit.ErrorLocation{
 						FilePath:     eval.Section.FilePath(),
+						FS:           eval.Section.Top().FS,
 						NodeLocation: invoke.Location,
 						Length:       len("\\" + invoke.Function),
 					},
 				}
 			}
 		case *booklit.Content:
-			eval.Result = booklit.Append(eval.Result, val.(booklit.Content))
+			if val != nil {
+				eval.Result = booklit.Append(eval.Result, val.(booklit.Content))
+			}
 		default:
 			return fmt.Errorf("unknown return type: %s", valType)
 		}
@@ -196,6 +325,7 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 
 					ErrorLocation: booklit.ErrorLocation{
 						FilePath:     eval.Section.FilePath(),
+						FS:           eval.Section.Top().FS,
 						NodeLocation: invoke.Location,
 					},
 				}
@@ -208,7 +338,9 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 		firstType := methodType.Out(0)
 		switch reflect.New(firstType).Interface().(type) {
 		case *booklit.Content:
-			eval.Result = booklit.Append(eval.Result, first.(booklit.Content))
+			if first != nil {
+				eval.Result = booklit.Append(eval.Result, first.(booklit.Content))
+			}
 		default:
 			return fmt.Errorf("unknown first return type: %s", firstType)
 		}
@@ -219,6 +351,60 @@ func (eval *Evaluate) VisitInvoke(invoke ast.Invoke) error {
 	return nil
 }
 
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// call calls the method, giving up on it once the context's deadline passes
+// so that a hung plugin can't hang the build with it. The method is left to
+// return in the background, as booklit.Plugin allows.
+//
+// Methods which take the context are trusted to return once it's done, and
+// are waited on, as are all methods if the context has no deadline, e.g. if
+// it's only cancelled upon interrupt.
+func call(ctx context.Context, method reflect.Value, argv []reflect.Value) ([]reflect.Value, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	_, hasDeadline := ctx.Deadline()
+	if !hasDeadline || takesContext(method.Type()) {
+		return method.Call(argv), nil
+	}
+
+	results := make(chan []reflect.Value, 1)
+	go func() {
+		results <- method.Call(argv)
+	}()
+
+	select {
+	case result := <-results:
+		return result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// takesContext returns true if the method takes the book's context as its
+// first argument.
+func takesContext(methodType reflect.Type) bool {
+	return methodType.NumIn() > 0 && methodType.In(0) == contextType
+}
+
+// dynamicFunction returns the function provided by the plugin with the given
+// name, invoked like any other method, or an invalid value if the plugin
+// doesn't provide it.
+func dynamicFunction(plugin booklit.DynamicPlugin, function string) reflect.Value {
+	for _, name := range plugin.Functions() {
+		if name == function {
+			return reflect.ValueOf(func(args ...booklit.Content) (booklit.Content, error) {
+				return plugin.Invoke(function, args...)
+			})
+		}
+	}
+
+	return reflect.Value{}
+}
+
 func (eval Evaluate) convert(to reflect.Type, node ast.Node) (reflect.Value, error) {
 	switch reflect.New(to).Interface().(type) {
 	case *string:
@@ -250,6 +436,13 @@ func (eval Evaluate) convert(to reflect.Type, node ast.Node) (reflect.Value, err
 func (eval Evaluate) evalArg(node ast.Node) (booklit.Content, error) {
 	subEval := &Evaluate{
 		Section: eval.Section,
+		Profile: eval.Profile,
+
+		IgnoreMissingPlugins: eval.IgnoreMissingPlugins,
+		Debug:                eval.Debug,
+		Errors:               eval.Errors,
+
+		preformatted: eval.preformatted,
 	}
 
 	err := node.Visit(subEval)
@@ -259,3 +452,112 @@ func (eval Evaluate) evalArg(node ast.Node) (booklit.Content, error) {
 
 	return subEval.Result, nil
 }
+
+// debugArgLength is the maximum length of each argument logged by
+// debugInvoke.
+const debugArgLength = 60
+
+func (eval *Evaluate) debugInvoke(invoke ast.Invoke, argv []reflect.Value, result []reflect.Value) {
+	args := []string{}
+	for _, arg := range argv {
+		var str string
+		switch val := arg.Interface().(type) {
+		case context.Context:
+			continue
+		case booklit.Content:
+			str = val.String()
+		case ast.Node:
+			// unevaluated, e.g. for \section
+			str = fmt.Sprintf("(%T)", val)
+		default:
+			str = fmt.Sprintf("%v", val)
+		}
+
+		if len(str) > debugArgLength {
+			str = str[:debugArgLength] + "..."
+		}
+
+		args = append(args, fmt.Sprintf("%q", str))
+	}
+
+	returned := "nothing"
+	for _, val := range result {
+		iface := val.Interface()
+		if iface == nil {
+			continue
+		}
+
+		if _, ok := iface.(error); ok {
+			returned = "error"
+		} else {
+			returned = fmt.Sprintf("%T", iface)
+		}
+
+		break
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"section":  eval.Section.FilePath(),
+		"location": fmt.Sprintf("%d:%d", invoke.Location.Line, invoke.Location.Col),
+		"args":     strings.Join(args, " "),
+		"result":   returned,
+	}).Infof("invoked \\%s", invoke.Function)
+}
+
+// placeholder renders an undefined function as its name followed by its
+// evaluated arguments, so that any prose within them is still visible.
+func (eval *Evaluate) placeholder(invoke ast.Invoke) error {
+	logrus.WithFields(logrus.Fields{
+		"section":  eval.Section.FilePath(),
+		"location": fmt.Sprintf("%d:%d", invoke.Location.Line, invoke.Location.Col),
+	}).Warnf("undefined function \\%s; rendering placeholder", invoke.Function)
+
+	placeholder := booklit.Sequence{
+		booklit.Styled{
+			Style:   booklit.StyleVerbatim,
+			Content: booklit.String("\\" + invoke.Function),
+		},
+	}
+
+	for _, arg := range invoke.Arguments {
+		content, err := eval.evalArg(arg)
+		if err != nil {
+			return err
+		}
+
+		if content != nil {
+			placeholder = append(placeholder, booklit.String(" "), content)
+		}
+	}
+
+	eval.Result = booklit.Append(eval.Result, placeholder)
+
+	return nil
+}
+
+// ignoreUnknownPlugin determines whether an error returned by a function
+// may be ignored because it comes from using an unknown plugin.
+func (eval *Evaluate) ignoreUnknownPlugin(invoke ast.Invoke, err error) bool {
+	if !eval.IgnoreMissingPlugins {
+		return false
+	}
+
+	if _, ok := err.(booklit.UnknownPluginError); !ok {
+		return false
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"section":  eval.Section.FilePath(),
+		"location": fmt.Sprintf("%d:%d", invoke.Location.Line, invoke.Location.Col),
+	}).Warnf("%s; ignoring", err)
+
+	return true
+}
+
+func profileSection(section *booklit.Section) string {
+	if section.PrimaryTag.Name != "" {
+		return section.PrimaryTag.Name
+	}
+
+	return section.FilePath()
+}
@@ -0,0 +1,307 @@
+package stages
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+)
+
+// Lint checks the prose of a section and its sub-sections against a
+// terminology, skipping code. Each term which breaks it is reported once per
+// section, located at its first use in the section's source.
+type Lint struct {
+	Terminology *booklit.Terminology
+
+	Warnings []booklit.TerminologyWarning
+
+	// section being checked
+	current *lintedSection
+
+	// contents of each file, read as needed
+	files map[string]string
+}
+
+type lintedSection struct {
+	section *booklit.Section
+
+	// terms reported for the section so far
+	reported map[string]bool
+
+	// uses of terms in the section's source, starting from base, found upon
+	// the first use of one in its prose
+	source string
+	base   int
+	uses   []booklit.TermViolation
+	read   bool
+}
+
+func (lint *Lint) VisitString(con booklit.String) error {
+	for _, violation := range lint.Terminology.Check(string(con)) {
+		if lint.current.reported[violation.Term] {
+			continue
+		}
+
+		lint.current.reported[violation.Term] = true
+
+		lint.Warnings = append(lint.Warnings, booklit.TerminologyWarning{
+			Term:      violation.Term,
+			Preferred: violation.Preferred,
+			Reason:    violation.Reason,
+
+			ErrorLocation: lint.locate(violation.Term),
+		})
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitSequence(con booklit.Sequence) error {
+	for _, c := range con {
+		err := c.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitParagraph(con booklit.Paragraph) error {
+	for _, c := range con {
+		err := c.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitPreformatted(con booklit.Preformatted) error {
+	for _, c := range con {
+		err := c.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitReference(*booklit.Reference) error {
+	// displayed as the title of the target, which is checked where it's
+	// defined
+	return nil
+}
+
+func (lint *Lint) VisitSection(con *booklit.Section) error {
+	parent := lint.current
+	defer func() { lint.current = parent }()
+
+	lint.current = &lintedSection{
+		section:  con,
+		reported: map[string]bool{},
+	}
+
+	err := con.Title.Visit(lint)
+	if err != nil {
+		return err
+	}
+
+	err = con.Body.Visit(lint)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range con.Children {
+		err := child.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitTableOfContents(booklit.TableOfContents) error {
+	return nil
+}
+
+func (lint *Lint) VisitStyled(con booklit.Styled) error {
+	switch con.Style {
+	case booklit.StyleVerbatim, booklit.StyleCodeBlock, booklit.StyleInlineCode,
+		booklit.StylePlayground, booklit.StyleSVG, booklit.StyleQRCode:
+		// code, markup, and URLs aren't prose
+		return nil
+	}
+
+	err := con.Content.Visit(lint)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range con.Partials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		partial := con.Partials[name]
+		if partial == nil {
+			continue
+		}
+
+		err := partial.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitTarget(booklit.Target) error {
+	return nil
+}
+
+func (lint *Lint) VisitImage(booklit.Image) error {
+	return nil
+}
+
+func (lint *Lint) VisitList(con booklit.List) error {
+	for _, c := range con.Items {
+		err := c.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitLink(con booklit.Link) error {
+	return con.Content.Visit(lint)
+}
+
+func (lint *Lint) VisitTable(con booklit.Table) error {
+	for _, row := range con.Rows {
+		for _, c := range row {
+			err := c.Visit(lint)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitDefinitions(con booklit.Definitions) error {
+	for _, def := range con {
+		err := def.Subject.Visit(lint)
+		if err != nil {
+			return err
+		}
+
+		err = def.Definition.Visit(lint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lint *Lint) VisitFigure(con *booklit.Figure) error {
+	err := con.Content.Visit(lint)
+	if err != nil {
+		return err
+	}
+
+	return con.Caption.Visit(lint)
+}
+
+func (lint *Lint) VisitFootnote(con *booklit.Footnote) error {
+	return con.Content.Visit(lint)
+}
+
+func (lint *Lint) VisitListOfFigures(booklit.ListOfFigures) error {
+	return nil
+}
+
+func (lint *Lint) VisitAttributions(booklit.Attributions) error {
+	return nil
+}
+
+// locate returns the location of the first use of the term in the current
+// section's source, or just its file if the term isn't found there, e.g.
+// because it was generated by a plugin.
+func (lint *Lint) locate(term string) booklit.ErrorLocation {
+	current := lint.current
+
+	loc := booklit.ErrorLocation{
+		FilePath: current.section.FilePath(),
+		FS:       current.section.Top().FS,
+	}
+
+	if !current.read {
+		current.read = true
+		lint.readSource(current, loc.FilePath)
+	}
+
+	for _, use := range current.uses {
+		if use.Term != term {
+			continue
+		}
+
+		offset := current.base + use.Offset
+		preceding := current.source[:offset]
+
+		lineStart := strings.LastIndex(preceding, "\n") + 1
+
+		loc.NodeLocation = ast.Location{
+			Line:   strings.Count(preceding, "\n") + 1,
+			Col:    utf8.RuneCountInString(preceding[lineStart:]) + 1,
+			Offset: offset,
+		}
+
+		loc.Length = utf8.RuneCountInString(term)
+
+		break
+	}
+
+	return loc
+}
+
+// readSource finds the uses of terms in the section's source: the whole
+// file for a section loaded from one, or from its \section onward for a
+// section within its parent's file.
+func (lint *Lint) readSource(current *lintedSection, path string) {
+	if lint.files == nil {
+		lint.files = map[string]string{}
+	}
+
+	source, found := lint.files[path]
+	if !found {
+		content, err := current.section.ReadFile(path)
+		if err != nil {
+			return
+		}
+
+		source = string(content)
+		lint.files[path] = source
+	}
+
+	section := current.section
+	if section.Path == "" && section.Parent != nil && section.Location.Offset < len(source) {
+		current.base = section.Location.Offset
+	}
+
+	current.source = source
+	current.uses = lint.Terminology.Check(source[current.base:])
+}
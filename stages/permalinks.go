@@ -0,0 +1,91 @@
+package stages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// checkPermalinks returns a booklit.PageCollisionError if any pages in the
+// book would have the same permalink, and notes whether any are nested in a
+// directory, so that they're linked to absolutely.
+//
+// Pages which collide only by their tags, with flat permalinks, are left for
+// the writer to report along with the files they'd be written to.
+func checkPermalinks(top *booklit.Section) error {
+	pages := map[string][]*booklit.Section{}
+	links := []string{}
+
+	nested := false
+
+	var collect func(*booklit.Section) error
+	collect = func(section *booklit.Section) error {
+		if section.Parent == nil || section.Parent.SplitSections {
+			if section.Parent != nil && section.InheritedPermalinks().Dated() && section.Published.IsZero() {
+				return fmt.Errorf("section has no \\published date for its permalink: %s", section.PrimaryTag.Name)
+			}
+
+			link := section.Permalink()
+			if _, found := pages[link]; !found {
+				links = append(links, link)
+			}
+
+			pages[link] = append(pages[link], section)
+
+			if strings.Contains(link, "/") {
+				nested = true
+			}
+		}
+
+		for _, child := range section.Children {
+			err := collect(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := collect(top)
+	if err != nil {
+		return err
+	}
+
+	top.NestedPermalinks = nested
+
+	for _, link := range links {
+		if len(pages[link]) == 1 {
+			continue
+		}
+
+		flat := true
+		for _, section := range pages[link] {
+			if section.Parent != nil && section.InheritedPermalinks().Pattern() != booklit.PermalinksFlat.Pattern() {
+				flat = false
+			}
+		}
+
+		if flat {
+			continue
+		}
+
+		locs := []booklit.ErrorLocation{}
+		for _, section := range pages[link] {
+			locs = append(locs, booklit.ErrorLocation{
+				FilePath:     section.PrimaryTag.Section.FilePath(),
+				FS:           section.PrimaryTag.Section.Top().FS,
+				NodeLocation: section.PrimaryTag.Location,
+				Length:       len("\\title"),
+			})
+		}
+
+		return booklit.PageCollisionError{
+			FileName:         link,
+			DefinedLocations: locs,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package stages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vito/booklit"
+)
+
+// orderLearningPaths sets the LearningPath partial of each section in the
+// book with a learning path, listing its sections in the order given except
+// that each comes after the sections it requires, whether directly or
+// through others. Prerequisites which form a cycle are an error.
+//
+// Tags which aren't found are listed as-is, and reported upon resolving the
+// partial.
+func orderLearningPaths(top *booklit.Section) error {
+	err := checkPrerequisites(top, top, map[*booklit.Section]visitState{}, nil)
+	if err != nil {
+		return err
+	}
+
+	setLearningPaths(top, top)
+
+	return nil
+}
+
+func setLearningPaths(top *booklit.Section, section *booklit.Section) {
+	if section.LearningPath != nil {
+		tags := section.LearningPath
+		if len(tags) == 0 {
+			for _, child := range section.Children {
+				tags = append(tags, child.PrimaryTag.Name)
+			}
+		}
+
+		members := map[*booklit.Section]string{}
+		for _, tag := range tags {
+			member, found := sectionByTag(top, tag)
+			if found {
+				members[member] = tag
+			}
+		}
+
+		path := booklit.List{
+			Ordered: true,
+		}
+
+		listed := map[*booklit.Section]bool{}
+
+		var list func(*booklit.Section)
+		list = func(s *booklit.Section) {
+			if listed[s] {
+				return
+			}
+
+			listed[s] = true
+
+			for _, tag := range s.Requires {
+				prerequisite, found := sectionByTag(top, tag)
+				if found {
+					list(prerequisite)
+				}
+			}
+
+			if tag, found := members[s]; found {
+				path.Items = append(path.Items, &booklit.Reference{
+					TagName: tag,
+				})
+			}
+		}
+
+		for _, tag := range tags {
+			member, found := sectionByTag(top, tag)
+			if found {
+				list(member)
+			} else {
+				path.Items = append(path.Items, &booklit.Reference{
+					TagName: tag,
+				})
+			}
+		}
+
+		section.SetPartial("LearningPath", path)
+	}
+
+	for _, child := range section.Children {
+		setLearningPaths(top, child)
+	}
+}
+
+type visitState int
+
+const (
+	unvisited visitState = iota
+	visiting
+	visited
+)
+
+// checkPrerequisites returns an error if the prerequisites of the section,
+// its sub-sections, or any of the sections they require form a cycle.
+func checkPrerequisites(top *booklit.Section, section *booklit.Section, states map[*booklit.Section]visitState, path []*booklit.Section) error {
+	switch states[section] {
+	case visited:
+		return nil
+	case visiting:
+		cycle := []string{}
+		for i, s := range path {
+			if s == section {
+				for _, c := range path[i:] {
+					cycle = append(cycle, c.PrimaryTag.Name)
+				}
+
+				break
+			}
+		}
+
+		cycle = append(cycle, section.PrimaryTag.Name)
+
+		return fmt.Errorf("prerequisites form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	states[section] = visiting
+
+	for _, tag := range section.Requires {
+		prerequisite, found := sectionByTag(top, tag)
+		if !found {
+			// reported upon resolving the Prerequisites partial
+			continue
+		}
+
+		err := checkPrerequisites(top, prerequisite, states, append(path, section))
+		if err != nil {
+			return err
+		}
+	}
+
+	states[section] = visited
+
+	for _, child := range section.Children {
+		err := checkPrerequisites(top, child, states, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sectionByTag returns the section defining the tag, unless none or more
+// than one do.
+func sectionByTag(section *booklit.Section, tag string) (*booklit.Section, bool) {
+	tags := section.FindTag(tag)
+	if len(tags) == 0 {
+		return nil, false
+	}
+
+	for _, t := range tags[1:] {
+		if t.Section != tags[0].Section {
+			return nil, false
+		}
+	}
+
+	return tags[0].Section, true
+}
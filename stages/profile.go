@@ -0,0 +1,105 @@
+package stages
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Profile collects timing for each function invoked during evaluation,
+// grouped by function and section.
+//
+// Durations are inclusive, so a function which evaluates sub-sections (e.g.
+// \section) counts the time spent evaluating their content, too.
+type Profile struct {
+	entries  map[profileKey]*ProfileEntry
+	entriesL sync.Mutex
+}
+
+type ProfileEntry struct {
+	Function string
+	Section  string
+	Count    int
+	Duration time.Duration
+}
+
+type profileKey struct {
+	function string
+	section  string
+}
+
+func (profile *Profile) Record(function string, section string, duration time.Duration) {
+	profile.entriesL.Lock()
+	defer profile.entriesL.Unlock()
+
+	if profile.entries == nil {
+		profile.entries = map[profileKey]*ProfileEntry{}
+	}
+
+	key := profileKey{function, section}
+
+	entry, found := profile.entries[key]
+	if !found {
+		entry = &ProfileEntry{
+			Function: function,
+			Section:  section,
+		}
+
+		profile.entries[key] = entry
+	}
+
+	entry.Count++
+	entry.Duration += duration
+}
+
+// Entries returns the recorded entries, slowest first.
+func (profile *Profile) Entries() []ProfileEntry {
+	profile.entriesL.Lock()
+	defer profile.entriesL.Unlock()
+
+	entries := []ProfileEntry{}
+	for _, entry := range profile.entries {
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Duration == entries[j].Duration {
+			return entries[i].Function < entries[j].Function
+		}
+
+		return entries[i].Duration > entries[j].Duration
+	})
+
+	return entries
+}
+
+// WriteReport writes a table of the recorded entries, with each entry's
+// share of the given total build time.
+func (profile *Profile) WriteReport(dest io.Writer, total time.Duration) error {
+	w := tabwriter.NewWriter(dest, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(w, "FUNCTION\tSECTION\tCOUNT\tTOTAL\tAVERAGE\tBUILD")
+
+	for _, entry := range profile.Entries() {
+		var share float64
+		if total > 0 {
+			share = float64(entry.Duration) / float64(total) * 100
+		}
+
+		fmt.Fprintf(
+			w,
+			"\\%s\t%s\t%d\t%s\t%s\t%.1f%%\n",
+			entry.Function,
+			entry.Section,
+			entry.Count,
+			entry.Duration,
+			entry.Duration/time.Duration(entry.Count),
+			share,
+		)
+	}
+
+	return w.Flush()
+}
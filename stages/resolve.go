@@ -3,13 +3,23 @@ package stages
 import (
 	"fmt"
 
-	"github.com/sirupsen/logrus"
 	"github.com/vito/booklit"
 )
 
 type Resolve struct {
 	AllowBrokenReferences bool
 
+	// Root sections of other books whose tags may be referenced when no tag
+	// is found in the section's own book.
+	Books []*booklit.Section
+
+	// If set, broken references are recorded and resolution carries on past
+	// them until the limit is reached.
+	Errors *booklit.BuildErrors
+
+	// If set, it's notified as each section is resolved.
+	Progress booklit.Progress
+
 	Section *booklit.Section
 }
 
@@ -52,6 +62,11 @@ func (resolve *Resolve) VisitPreformatted(con booklit.Preformatted) error {
 
 func (resolve *Resolve) VisitReference(con *booklit.Reference) error {
 	tags := resolve.Section.FindTag(con.TagName)
+	if len(tags) == 0 {
+		for _, book := range resolve.Books {
+			tags = append(tags, book.FindTag(con.TagName)...)
+		}
+	}
 
 	var err error
 	switch len(tags) {
@@ -61,6 +76,7 @@ func (resolve *Resolve) VisitReference(con *booklit.Reference) error {
 			SimilarTags: resolve.Section.SimilarTags(con.TagName),
 			ErrorLocation: booklit.ErrorLocation{
 				FilePath:     resolve.Section.FilePath(),
+				FS:           resolve.Section.Top().FS,
 				NodeLocation: con.Location,
 				Length:       len("\\reference"),
 			},
@@ -72,6 +88,7 @@ func (resolve *Resolve) VisitReference(con *booklit.Reference) error {
 		for _, t := range tags {
 			locs = append(locs, booklit.ErrorLocation{
 				FilePath:     t.Section.FilePath(),
+				FS:           t.Section.Top().FS,
 				NodeLocation: t.Location,
 			})
 		}
@@ -81,6 +98,7 @@ func (resolve *Resolve) VisitReference(con *booklit.Reference) error {
 			DefinedLocations: locs,
 			ErrorLocation: booklit.ErrorLocation{
 				FilePath:     resolve.Section.FilePath(),
+				FS:           resolve.Section.Top().FS,
 				NodeLocation: con.Location,
 				Length:       len("\\reference"),
 			},
@@ -92,26 +110,51 @@ func (resolve *Resolve) VisitReference(con *booklit.Reference) error {
 	}
 
 	if resolve.AllowBrokenReferences {
-		logrus.WithFields(logrus.Fields{
-			"section": resolve.Section.Path,
-		}).Warnf("broken reference: %s", err)
+		// reported as a warning instead, unless the build is strict
+		err = resolve.Section.Warn(booklit.BrokenReferenceWarning{Err: err})
+	}
 
-		con.Tag = &booklit.Tag{
-			Name:     con.TagName,
-			Anchor:   "broken",
-			Title:    booklit.String(fmt.Sprintf("{broken reference: %s}", con.TagName)),
-			Section:  resolve.Section,
-			Location: con.Location,
+	switch {
+	case err == nil:
+	case resolve.Errors != nil:
+		err = resolve.Errors.Record(err)
+		if err != nil {
+			return err
 		}
+	default:
+		return err
+	}
 
-		return nil
+	// stand in for the missing tag so that the reference can still be
+	// rendered
+
+	con.Tag = &booklit.Tag{
+		Name:     con.TagName,
+		Anchor:   "broken",
+		Title:    booklit.String(fmt.Sprintf("{broken reference: %s}", con.TagName)),
+		Section:  resolve.Section,
+		Location: con.Location,
 	}
 
-	return err
+	return nil
 }
 
 func (resolve *Resolve) VisitSection(con *booklit.Section) error {
-	err := con.Title.Visit(resolve)
+	err := con.Context().Err()
+	if err != nil {
+		return err
+	}
+
+	if con.Parent == nil {
+		// ordered up front so that the references are resolved along with
+		// the rest of the partials
+		err := orderLearningPaths(con)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = con.Title.Visit(resolve)
 	if err != nil {
 		return err
 	}
@@ -128,11 +171,31 @@ func (resolve *Resolve) VisitSection(con *booklit.Section) error {
 		}
 	}
 
+	for _, attr := range con.Attributions {
+		for _, c := range []booklit.Content{attr.Subject, attr.Credit} {
+			if c == nil {
+				continue
+			}
+
+			err = c.Visit(resolve)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if resolve.Progress != nil {
+		resolve.Progress.Advance(booklit.StageResolve, con)
+	}
+
 	// TODO: this probably does redundant resolving, since i think the section
 	// was loaded via a processor in the first place
 	for _, child := range con.Children {
 		subResolver := &Resolve{
 			AllowBrokenReferences: resolve.AllowBrokenReferences,
+			Books:                 resolve.Books,
+			Errors:                resolve.Errors,
+			Progress:              resolve.Progress,
 			Section:               child,
 		}
 
@@ -142,6 +205,10 @@ func (resolve *Resolve) VisitSection(con *booklit.Section) error {
 		}
 	}
 
+	if con.Parent == nil {
+		return checkPermalinks(con)
+	}
+
 	return nil
 }
 
@@ -217,6 +284,27 @@ func (resolve *Resolve) VisitTable(con booklit.Table) error {
 	return nil
 }
 
+func (resolve *Resolve) VisitFigure(con *booklit.Figure) error {
+	err := con.Content.Visit(resolve)
+	if err != nil {
+		return err
+	}
+
+	return con.Caption.Visit(resolve)
+}
+
+func (resolve *Resolve) VisitFootnote(con *booklit.Footnote) error {
+	return con.Content.Visit(resolve)
+}
+
+func (resolve *Resolve) VisitListOfFigures(booklit.ListOfFigures) error {
+	return nil
+}
+
+func (resolve *Resolve) VisitAttributions(booklit.Attributions) error {
+	return nil
+}
+
 func (resolve *Resolve) VisitDefinitions(con booklit.Definitions) error {
 	for _, def := range con {
 		err := def.Subject.Visit(resolve)
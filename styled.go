@@ -21,6 +21,27 @@ const (
 	StyleSubscript   Style = "subscript"
 	StyleInset       Style = "inset"
 	StyleAside       Style = "aside"
+	StyleEpigraph    Style = "epigraph"
+	StylePullQuote   Style = "pull-quote"
+	StyleBadge       Style = "badge"
+	StyleIssue       Style = "issue"
+	StyleQRCode      Style = "qrcode"
+	StyleSwatch      Style = "swatch"
+	StylePalette     Style = "palette"
+	StyleDate        Style = "date"
+	StyleEmoji       Style = "emoji"
+	StyleSVG         Style = "svg"
+	StyleIsolate     Style = "isolate"
+	StyleRuby        Style = "ruby"
+	StyleSearchBox   Style = "search-box"
+	StyleEvent       Style = "event"
+	StylePlayground  Style = "playground"
+	StyleDiagram     Style = "diagram"
+	StyleMath        Style = "math"
+	StyleVerse       Style = "verse"
+	StyleLines       Style = "lines"
+	StyleCodeBlock   Style = "code-block"
+	StyleInlineCode  Style = "inline-code"
 )
 
 func (con Styled) String() string {
@@ -2,8 +2,26 @@ package booklit
 
 type Table struct {
 	Rows [][]Content
+
+	// Number of rows at the start of the table which are headers, e.g.
+	// naming each column.
+	HeaderRows int
+
+	// Alignment of the cells in each column, in order. Columns beyond them
+	// are aligned however the renderer aligns them by default.
+	Alignments []Alignment
 }
 
+// Alignment is the horizontal alignment of the cells in a table's column.
+type Alignment string
+
+const (
+	AlignDefault Alignment = ""
+	AlignLeft    Alignment = "left"
+	AlignCenter  Alignment = "center"
+	AlignRight   Alignment = "right"
+)
+
 func (con Table) IsFlow() bool {
 	return false
 }
@@ -27,3 +45,43 @@ func (con Table) String() string {
 func (con Table) Visit(visitor Visitor) error {
 	return visitor.VisitTable(con)
 }
+
+// Header returns the table's header rows.
+func (con Table) Header() [][]Content {
+	if con.HeaderRows > len(con.Rows) {
+		return con.Rows
+	}
+
+	return con.Rows[:con.HeaderRows]
+}
+
+// Body returns the table's rows following its header rows.
+func (con Table) Body() [][]Content {
+	if con.HeaderRows > len(con.Rows) {
+		return nil
+	}
+
+	return con.Rows[con.HeaderRows:]
+}
+
+// Columns returns the number of columns in the table's widest row.
+func (con Table) Columns() int {
+	var columns int
+	for _, row := range con.Rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	return columns
+}
+
+// Alignment returns the alignment of the cells in the given column,
+// counting from 0.
+func (con Table) Alignment(column int) Alignment {
+	if column < 0 || column >= len(con.Alignments) {
+		return AlignDefault
+	}
+
+	return con.Alignments[column]
+}
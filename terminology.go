@@ -0,0 +1,169 @@
+package booklit
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Terminology is a project's rules for the terms used in prose, e.g. to
+// keep a book written by many authors consistent. Prose which breaks them is
+// reported as a TerminologyWarning.
+//
+// Terms are matched as whole words, ignoring case, and not as part of a URL
+// or path, e.g. github.com.
+type Terminology struct {
+	// terms which must be cased as given, e.g. GitHub rather than Github
+	Terms []string `yaml:"terms"`
+
+	// preferred spellings, by the spelling to avoid, e.g. e-mail: email
+	Preferred map[string]string `yaml:"preferred"`
+
+	// terms which may not be used at all, along with the reason or an
+	// alternative, if any, e.g. simply: condescending
+	Banned map[string]string `yaml:"banned"`
+
+	rules    []termRule
+	compiled sync.Once
+}
+
+// TermViolation is a use of a term which breaks a Terminology's rules.
+type TermViolation struct {
+	// the term as written, e.g. Github
+	Term string
+
+	// byte offset of the term in the text that was checked
+	Offset int
+
+	// spelling to use instead; empty if the term is banned
+	Preferred string
+
+	// reason for banning the term, if any
+	Reason string
+}
+
+type termRule struct {
+	regexp *regexp.Regexp
+
+	// if set, only uses of the term cased differently break the rule
+	cased string
+
+	preferred string
+	banned    bool
+	reason    string
+}
+
+// Check returns the uses of terms in the text which break the rules, in
+// order.
+func (terminology *Terminology) Check(text string) []TermViolation {
+	terminology.compiled.Do(terminology.compile)
+
+	violations := []TermViolation{}
+	for _, rule := range terminology.rules {
+		for _, loc := range rule.regexp.FindAllStringIndex(text, -1) {
+			if !wordBoundary(text, loc[0], loc[1]) {
+				continue
+			}
+
+			term := text[loc[0]:loc[1]]
+			if rule.cased != "" && term == rule.cased {
+				continue
+			}
+
+			violation := TermViolation{
+				Term:   term,
+				Offset: loc[0],
+				Reason: rule.reason,
+			}
+
+			if !rule.banned {
+				violation.Preferred = rule.preferred
+			}
+
+			violations = append(violations, violation)
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Offset < violations[j].Offset
+	})
+
+	return violations
+}
+
+func (terminology *Terminology) compile() {
+	for _, term := range terminology.Terms {
+		terminology.rules = append(terminology.rules, termRule{
+			regexp:    termRegexp(term),
+			cased:     term,
+			preferred: term,
+		})
+	}
+
+	for _, avoid := range sortedKeys(terminology.Preferred) {
+		terminology.rules = append(terminology.rules, termRule{
+			regexp:    termRegexp(avoid),
+			preferred: terminology.Preferred[avoid],
+		})
+	}
+
+	for _, term := range sortedKeys(terminology.Banned) {
+		terminology.rules = append(terminology.rules, termRule{
+			regexp: termRegexp(term),
+			banned: true,
+			reason: terminology.Banned[term],
+		})
+	}
+}
+
+func termRegexp(term string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)` + regexp.QuoteMeta(term))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// wordBoundary reports whether the text between start and end is a whole
+// word, rather than part of a longer word, URL, or path.
+func wordBoundary(text string, start int, end int) bool {
+	before, size := utf8.DecodeLastRuneInString(text[:start])
+	if start > 0 && isWordRune(before) {
+		return false
+	}
+
+	if start > 0 && strings.ContainsRune("./@", before) {
+		prev, _ := utf8.DecodeLastRuneInString(text[:start-size])
+		if isWordRune(prev) {
+			return false
+		}
+	}
+
+	after, size := utf8.DecodeRuneInString(text[end:])
+	if end < len(text) && isWordRune(after) {
+		return false
+	}
+
+	if end < len(text) && strings.ContainsRune("./@", after) {
+		next, _ := utf8.DecodeRuneInString(text[end+size:])
+		if isWordRune(next) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
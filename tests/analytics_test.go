@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit/booklitcmd"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+var _ = Describe("Analytics", func() {
+	var dir string
+	var server *booklitcmd.Server
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-analytics")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello, world!}{index}
+
+Hi!
+`), 0644)).To(Succeed())
+
+		server = &booklitcmd.Server{
+			In:         filepath.Join(dir, "index.lit"),
+			Processor:  &load.Processor{},
+			Engine:     render.NewHTMLRenderingEngine(),
+			FileServer: http.FileServer(http.Dir(dir)),
+			Analytics: &booklitcmd.Analytics{
+				Token: "some-token",
+				Log:   filepath.Join(dir, "analytics.log"),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	type entry struct {
+		Query    string `json:"query"`
+		Path     string `json:"path"`
+		Count    int    `json:"count"`
+		Referrer string `json:"referrer"`
+	}
+
+	type report struct {
+		Searches []entry `json:"searches"`
+		NotFound []entry `json:"not_found"`
+	}
+
+	search := func(contentType string, body string) int {
+		req := httptest.NewRequest("POST", "/_booklit/analytics", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	visit := func(path string, referrer string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Referer", referrer)
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	fetchReport := func(token string) (int, report) {
+		req := httptest.NewRequest("GET", "/_booklit/analytics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		var fetched report
+		if rec.Code == http.StatusOK {
+			Expect(json.NewDecoder(rec.Body).Decode(&fetched)).To(Succeed())
+		}
+
+		return rec.Code, fetched
+	}
+
+	It("reports searches which found nothing and paths which weren't found, most frequent first", func() {
+		Expect(search("application/json", `{"query":"widgets"}`)).To(Equal(http.StatusNoContent))
+		Expect(search("application/json", `{"query":"  gadgets  "}`)).To(Equal(http.StatusNoContent))
+		Expect(search("application/json", `{"query":"Gadgets"}`)).To(Equal(http.StatusNoContent))
+
+		Expect(visit("/missing.png", "http://example.com/index.html")).To(Equal(http.StatusNotFound))
+		Expect(visit("/index.html", "")).To(Equal(http.StatusOK))
+
+		status, fetched := fetchReport("some-token")
+		Expect(status).To(Equal(http.StatusOK))
+
+		Expect(fetched.Searches).To(Equal([]entry{
+			{Query: "gadgets", Count: 2},
+			{Query: "widgets", Count: 1},
+		}))
+
+		Expect(fetched.NotFound).To(Equal([]entry{
+			{Path: "/missing.png", Count: 1, Referrer: "http://example.com/index.html"},
+		}))
+	})
+
+	It("only shows the report to those with the token", func() {
+		status, _ := fetchReport("")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		status, _ = fetchReport("bogus")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects searches which aren't JSON, or are empty or too long", func() {
+		Expect(search("text/plain", `{"query":"widgets"}`)).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(search("application/json", `{"query":"   "}`)).To(Equal(http.StatusBadRequest))
+		Expect(search("application/json", `{"query":"`+strings.Repeat("a", 201)+`"}`)).To(Equal(http.StatusBadRequest))
+		Expect(search("application/json", `bogus`)).To(Equal(http.StatusBadRequest))
+
+		_, fetched := fetchReport("some-token")
+		Expect(fetched.Searches).To(BeEmpty())
+	})
+
+	It("forgets the queries seen least recently once it's full", func() {
+		server.Analytics.MaxEntries = 2
+
+		Expect(search("application/json", `{"query":"one"}`)).To(Equal(http.StatusNoContent))
+		Expect(search("application/json", `{"query":"two"}`)).To(Equal(http.StatusNoContent))
+		Expect(search("application/json", `{"query":"one"}`)).To(Equal(http.StatusNoContent))
+		Expect(search("application/json", `{"query":"three"}`)).To(Equal(http.StatusNoContent))
+
+		_, fetched := fetchReport("some-token")
+		Expect(fetched.Searches).To(Equal([]entry{
+			{Query: "one", Count: 2},
+			{Query: "three", Count: 1},
+		}))
+	})
+
+	It("loads what was recorded from the log upon restarting, including the rotated log", func() {
+		server.Analytics.MaxLogSize = 200
+
+		for _, query := range []string{"one", "two", "three", "one"} {
+			Expect(search("application/json", `{"query":"`+query+`"}`)).To(Equal(http.StatusNoContent))
+		}
+
+		Expect(filepath.Join(dir, "analytics.log.1")).To(BeAnExistingFile())
+
+		rotated, err := ioutil.ReadFile(filepath.Join(dir, "analytics.log.1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(rotated)).To(BeNumerically("<=", 200))
+
+		server.Analytics = &booklitcmd.Analytics{
+			Token: "some-token",
+			Log:   filepath.Join(dir, "analytics.log"),
+		}
+
+		_, fetched := fetchReport("some-token")
+		Expect(fetched.Searches).To(ConsistOf(
+			entry{Query: "one", Count: 2},
+			entry{Query: "two", Count: 1},
+			entry{Query: "three", Count: 1},
+		))
+	})
+})
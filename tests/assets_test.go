@@ -0,0 +1,43 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Unused assets", (Example).Run,
+	Entry("referred to by pages and other assets", Example{
+		Input: `\title{Hello, world!}
+
+\image{assets/diagram.png}{A diagram}
+
+Download \link{the slides}{assets/slides%20v2.pdf}.
+`,
+
+		Inputs: Files{
+			"assets/diagram.png":     "png",
+			"assets/slides v2.pdf":   "pdf",
+			"assets/old-diagram.png": "png",
+			"assets/fonts/font.woff": "woff",
+			"assets/fonts/font.css":  "@font-face { src: url(font.woff); }",
+			"assets/.gitkeep":        "",
+		},
+
+		UnusedAssets: []string{
+			"assets/fonts/font.css",
+			"assets/old-diagram.png",
+		},
+	}),
+
+	Entry("read by plugins", Example{
+		Input: `\title{Hello, world!}
+
+\svg{assets/logo.svg}
+`,
+
+		Inputs: Files{
+			"assets/logo.svg": `<svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+		},
+
+		UnusedAssets: []string{},
+	}),
+)
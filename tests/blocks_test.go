@@ -3,6 +3,7 @@ package tests
 import (
 	. "github.com/onsi/ginkgo/extensions/table"
 	_ "github.com/vito/booklit/tests/fixtures/arbitrary-style-plugin"
+	_ "github.com/vito/booklit/tests/fixtures/engine-aware-plugin"
 )
 
 var _ = DescribeTable("Blocks", (Example).Run,
@@ -55,6 +56,51 @@ var _ = DescribeTable("Blocks", (Example).Run,
 		},
 	}),
 
+	Entry("tables with header rows and aligned columns", Example{
+		Input: `\title{Hello, world!}
+
+\table{
+	\align-columns{left}{right}
+	\header-row{Fruit}{Count}
+	\row{apples}{3}
+	\row{\cell{pears} \cell{12}}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<table>
+	<thead>
+		<tr>
+			<th style="text-align: left">Fruit</th>
+			<th style="text-align: right">Count</th>
+		</tr>
+	</thead>
+	<tr>
+		<td style="text-align: left">apples</td>
+		<td style="text-align: right">3</td>
+	</tr>
+	<tr>
+		<td style="text-align: left">pears</td>
+		<td style="text-align: right">12</td>
+	</tr>
+</table>
+</section>`,
+		},
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+| Fruit | Count |
+| :--- | ---: |
+| apples | 3 |
+| pears | 12 |
+`,
+		},
+	}),
+
 	Entry("definitions", Example{
 		Input: `\title{Hello, world!}
 
@@ -80,6 +126,68 @@ var _ = DescribeTable("Blocks", (Example).Run,
 		},
 	}),
 
+	Entry("glossary", Example{
+		Input: `\title{Hello, world!}
+
+\glossary{
+	\definition{éclair}{3}
+}{
+	\definition{Banana}{2}
+}{
+	\definition{apple}{1}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<dl>
+	<dt>apple</dt>
+		<dd>1</dd>
+
+	<dt>Banana</dt>
+		<dd>2</dd>
+
+	<dt>éclair</dt>
+		<dd>3</dd>
+</dl>
+</section>`,
+		},
+	}),
+
+	Entry("glossary in a locale", Example{
+		Input: `\title{Hello, world!}
+
+\glossary{
+	\definition{Öl}{beer}
+}{
+	\definition{Zebra}{zebra}
+}{
+	\definition{Oxe}{ox}
+}
+`,
+
+		Locale: "sv",
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<dl>
+	<dt>Oxe</dt>
+		<dd>ox</dd>
+
+	<dt>Zebra</dt>
+		<dd>zebra</dd>
+
+	<dt>Öl</dt>
+		<dd>beer</dd>
+</dl>
+</section>`,
+		},
+	}),
+
 	Entry("inset", Example{
 		Input: `\title{Hello, world!}
 
@@ -157,4 +265,464 @@ var _ = DescribeTable("Blocks", (Example).Run,
 </section>`,
 		},
 	}),
+
+	Entry("engine-aware plugins", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{engine-aware}
+
+\diagram{arbitrary}
+
+\diagram{unknown}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<p><blink>svg diagram</blink></p>
+
+<p>[ascii diagram]</p>
+</section>`,
+		},
+	}),
+
+	Entry("color palettes", Example{
+		Input: `\title{Hello, world!}
+
+Our brand color is \color{#1A2B3C}.
+
+\palette{\color{#fff}}{#000000}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<p>Our brand color is <span class="swatch"><span class="swatch-chip" style="display: inline-block; width: 1em; height: 1em; vertical-align: middle; background-color: #1a2b3c"></span> <code>#1a2b3c</code> <code>rgb(26, 43, 60)</code></span>.</p>
+
+<div class="palette"><span class="swatch"><span class="swatch-chip" style="display: inline-block; width: 1em; height: 1em; vertical-align: middle; background-color: #ffffff"></span> <code>#ffffff</code> <code>rgb(255, 255, 255)</code></span><span class="swatch"><span class="swatch-chip" style="display: inline-block; width: 1em; height: 1em; vertical-align: middle; background-color: #000000"></span> <code>#000000</code> <code>rgb(0, 0, 0)</code></span></div>
+</section>`,
+		},
+	}),
+
+	Entry("QR codes", Example{
+		Input: `\title{Hello, world!}
+
+\qrcode{hi}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p><svg xmlns="http://www.w3.org/2000/svg" class="qrcode" viewBox="0 0 29 29" shape-rendering="crispEdges"><rect width="29" height="29" fill="#fff"/><path fill="#000" d="M4 4h1v1h-1zM5 4h1v1h-1zM6 4h1v1h-1zM7 4h1v1h-1zM8 4h1v1h-1zM9 4h1v1h-1zM10 4h1v1h-1zM15 4h1v1h-1zM16 4h1v1h-1zM18 4h1v1h-1zM19 4h1v1h-1zM20 4h1v1h-1zM21 4h1v1h-1zM22 4h1v1h-1zM23 4h1v1h-1zM24 4h1v1h-1zM4 5h1v1h-1zM10 5h1v1h-1zM12 5h1v1h-1zM13 5h1v1h-1zM14 5h1v1h-1zM15 5h1v1h-1zM18 5h1v1h-1zM24 5h1v1h-1zM4 6h1v1h-1zM6 6h1v1h-1zM7 6h1v1h-1zM8 6h1v1h-1zM10 6h1v1h-1zM14 6h1v1h-1zM15 6h1v1h-1zM16 6h1v1h-1zM18 6h1v1h-1zM20 6h1v1h-1zM21 6h1v1h-1zM22 6h1v1h-1zM24 6h1v1h-1zM4 7h1v1h-1zM6 7h1v1h-1zM7 7h1v1h-1zM8 7h1v1h-1zM10 7h1v1h-1zM13 7h1v1h-1zM15 7h1v1h-1zM18 7h1v1h-1zM20 7h1v1h-1zM21 7h1v1h-1zM22 7h1v1h-1zM24 7h1v1h-1zM4 8h1v1h-1zM6 8h1v1h-1zM7 8h1v1h-1zM8 8h1v1h-1zM10 8h1v1h-1zM12 8h1v1h-1zM13 8h1v1h-1zM14 8h1v1h-1zM15 8h1v1h-1zM16 8h1v1h-1zM18 8h1v1h-1zM20 8h1v1h-1zM21 8h1v1h-1zM22 8h1v1h-1zM24 8h1v1h-1zM4 9h1v1h-1zM10 9h1v1h-1zM13 9h1v1h-1zM15 9h1v1h-1zM18 9h1v1h-1zM24 9h1v1h-1zM4 10h1v1h-1zM5 10h1v1h-1zM6 10h1v1h-1zM7 10h1v1h-1zM8 10h1v1h-1zM9 10h1v1h-1zM10 10h1v1h-1zM12 10h1v1h-1zM14 10h1v1h-1zM16 10h1v1h-1zM18 10h1v1h-1zM19 10h1v1h-1zM20 10h1v1h-1zM21 10h1v1h-1zM22 10h1v1h-1zM23 10h1v1h-1zM24 10h1v1h-1zM14 11h1v1h-1zM4 12h1v1h-1zM6 12h1v1h-1zM8 12h1v1h-1zM10 12h1v1h-1zM13 12h1v1h-1zM14 12h1v1h-1zM16 12h1v1h-1zM20 12h1v1h-1zM23 12h1v1h-1zM4 13h1v1h-1zM6 13h1v1h-1zM7 13h1v1h-1zM14 13h1v1h-1zM15 13h1v1h-1zM17 13h1v1h-1zM19 13h1v1h-1zM21 13h1v1h-1zM22 13h1v1h-1zM23 13h1v1h-1zM24 13h1v1h-1zM8 14h1v1h-1zM10 14h1v1h-1zM11 14h1v1h-1zM12 14h1v1h-1zM14 14h1v1h-1zM15 14h1v1h-1zM17 14h1v1h-1zM18 14h1v1h-1zM19 14h1v1h-1zM21 14h1v1h-1zM22 14h1v1h-1zM23 14h1v1h-1zM24 14h1v1h-1zM6 15h1v1h-1zM8 15h1v1h-1zM9 15h1v1h-1zM11 15h1v1h-1zM13 15h1v1h-1zM15 15h1v1h-1zM16 15h1v1h-1zM17 15h1v1h-1zM19 15h1v1h-1zM20 15h1v1h-1zM21 15h1v1h-1zM23 15h1v1h-1zM4 16h1v1h-1zM5 16h1v1h-1zM7 16h1v1h-1zM10 16h1v1h-1zM12 16h1v1h-1zM13 16h1v1h-1zM14 16h1v1h-1zM15 16h1v1h-1zM17 16h1v1h-1zM18 16h1v1h-1zM19 16h1v1h-1zM22 16h1v1h-1zM12 17h1v1h-1zM18 17h1v1h-1zM22 17h1v1h-1zM23 17h1v1h-1zM24 17h1v1h-1zM4 18h1v1h-1zM5 18h1v1h-1zM6 18h1v1h-1zM7 18h1v1h-1zM8 18h1v1h-1zM9 18h1v1h-1zM10 18h1v1h-1zM13 18h1v1h-1zM16 18h1v1h-1zM20 18h1v1h-1zM23 18h1v1h-1zM24 18h1v1h-1zM4 19h1v1h-1zM10 19h1v1h-1zM13 19h1v1h-1zM14 19h1v1h-1zM18 19h1v1h-1zM22 19h1v1h-1zM23 19h1v1h-1zM24 19h1v1h-1zM4 20h1v1h-1zM6 20h1v1h-1zM7 20h1v1h-1zM8 20h1v1h-1zM10 20h1v1h-1zM12 20h1v1h-1zM13 20h1v1h-1zM14 20h1v1h-1zM16 20h1v1h-1zM18 20h1v1h-1zM20 20h1v1h-1zM22 20h1v1h-1zM24 20h1v1h-1zM4 21h1v1h-1zM6 21h1v1h-1zM7 21h1v1h-1zM8 21h1v1h-1zM10 21h1v1h-1zM13 21h1v1h-1zM14 21h1v1h-1zM15 21h1v1h-1zM17 21h1v1h-1zM19 21h1v1h-1zM21 21h1v1h-1zM23 21h1v1h-1zM4 22h1v1h-1zM6 22h1v1h-1zM7 22h1v1h-1zM8 22h1v1h-1zM10 22h1v1h-1zM12 22h1v1h-1zM13 22h1v1h-1zM14 22h1v1h-1zM15 22h1v1h-1zM17 22h1v1h-1zM18 22h1v1h-1zM19 22h1v1h-1zM21 22h1v1h-1zM22 22h1v1h-1zM24 22h1v1h-1zM4 23h1v1h-1zM10 23h1v1h-1zM13 23h1v1h-1zM14 23h1v1h-1zM15 23h1v1h-1zM16 23h1v1h-1zM17 23h1v1h-1zM19 23h1v1h-1zM20 23h1v1h-1zM21 23h1v1h-1zM23 23h1v1h-1zM4 24h1v1h-1zM5 24h1v1h-1zM6 24h1v1h-1zM7 24h1v1h-1zM8 24h1v1h-1zM9 24h1v1h-1zM10 24h1v1h-1zM12 24h1v1h-1zM13 24h1v1h-1zM15 24h1v1h-1zM17 24h1v1h-1zM18 24h1v1h-1zM19 24h1v1h-1zM21 24h1v1h-1zM22 24h1v1h-1zM23 24h1v1h-1zM24 24h1v1h-1z"/></svg></p>
+</section>`,
+		},
+	}),
+
+	Entry("figures", Example{
+		Input: `\title{Hello, world!}
+
+\list-of-figures
+
+See \reference{cat-pic}.
+
+\figure{\image{dog.png}}{A dog.}
+
+\section{
+	\title{Cats}
+
+	\figure{\image{cat.png}}{A cat.}{cat-pic}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<nav class="list-of-figures">
+	<ul>
+		<li><a href="hello-world.html#figure-1">Figure 1</a>: A dog.</li>
+		<li><a href="hello-world.html#cat-pic">Figure 2</a>: A cat.</li>
+	</ul>
+</nav>
+
+<p>See <a href="hello-world.html#cat-pic">Figure 2</a>.</p>
+
+<figure id="figure-1">
+	<img src="dog.png" alt="" />
+	<figcaption><span class="figure-number">Figure 1:</span> A dog.</figcaption>
+</figure>
+
+<h2>1 Cats</h2>
+
+<figure id="cat-pic">
+	<img src="cat.png" alt="" />
+	<figcaption><span class="figure-number">Figure 2:</span> A cat.</figcaption>
+</figure>
+</section>`,
+		},
+	}),
+
+	Entry("figures after a child section", Example{
+		Input: `\title{Hello, world!}
+
+\list-of-figures
+
+\figure{\image{dog.png}}{A dog.}
+
+\section{
+	\title{Cats}
+
+	\figure{\image{cat.png}}{A cat.}
+
+	\section{
+		\title{Kittens}
+
+		\figure{\image{kitten.png}}{A kitten.}{kitten-pic}
+	}
+}
+
+\section{
+	\title{Birds}
+
+	\figure{\image{bird.png}}{A bird.}
+}
+
+\figure{\image{fish.png}}{A fish, shown before the sections.}
+
+See \reference{kitten-pic}.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<nav class="list-of-figures">
+	<ul>
+		<li><a href="hello-world.html#figure-1">Figure 1</a>: A dog.</li>
+		<li><a href="hello-world.html#figure-2">Figure 2</a>: A fish, shown before the sections.</li>
+		<li><a href="hello-world.html#figure-3">Figure 3</a>: A cat.</li>
+		<li><a href="hello-world.html#kitten-pic">Figure 4</a>: A kitten.</li>
+		<li><a href="hello-world.html#figure-5">Figure 5</a>: A bird.</li>
+	</ul>
+</nav>
+
+<figure id="figure-1">
+	<img src="dog.png" alt="" />
+	<figcaption><span class="figure-number">Figure 1:</span> A dog.</figcaption>
+</figure>
+
+<figure id="figure-2">
+	<img src="fish.png" alt="" />
+	<figcaption><span class="figure-number">Figure 2:</span> A fish, shown before the sections.</figcaption>
+</figure>
+
+<p>See <a href="hello-world.html#kitten-pic">Figure 4</a>.</p>
+
+<h2>1 Cats</h2>
+
+<figure id="figure-3">
+	<img src="cat.png" alt="" />
+	<figcaption><span class="figure-number">Figure 3:</span> A cat.</figcaption>
+</figure>
+
+<h3>1.1 Kittens</h3>
+
+<figure id="kitten-pic">
+	<img src="kitten.png" alt="" />
+	<figcaption><span class="figure-number">Figure 4:</span> A kitten.</figcaption>
+</figure>
+
+<h2>2 Birds</h2>
+
+<figure id="figure-5">
+	<img src="bird.png" alt="" />
+	<figcaption><span class="figure-number">Figure 5:</span> A bird.</figcaption>
+</figure>
+</section>`,
+		},
+	}),
+
+	Entry("footnotes", Example{
+		Input: `\title{Hello, world!}
+
+Booklit\footnote{A pun on \italic{lit}.} is a tool.\footnote{Or a toy.}
+
+\section{
+	\title{Usage}
+
+	Run it.\footnote{See \reference{hello-world}.}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<p>Booklit<sup class="footnote-ref"><a id="hello-world-footnote-ref-1" href="hello-world.html#hello-world-footnote-1" role="doc-noteref">1</a></sup> is a tool.<sup class="footnote-ref"><a id="hello-world-footnote-ref-2" href="hello-world.html#hello-world-footnote-2" role="doc-noteref">2</a></sup></p>
+
+<h2>1 Usage</h2>
+
+<p>Run it.<sup class="footnote-ref"><a id="hello-world-footnote-ref-3" href="hello-world.html#hello-world-footnote-3" role="doc-noteref">3</a></sup></p>
+
+<section class="endnotes" role="doc-endnotes">
+	<ol>
+		<li id="hello-world-footnote-1">A pun on <em>lit</em>. <a class="footnote-backref" href="hello-world.html#hello-world-footnote-ref-1" role="doc-backlink">↩</a></li>
+		<li id="hello-world-footnote-2">Or a toy. <a class="footnote-backref" href="hello-world.html#hello-world-footnote-ref-2" role="doc-backlink">↩</a></li>
+		<li id="hello-world-footnote-3">See <a href="hello-world.html">Hello, world!</a>. <a class="footnote-backref" href="hello-world.html#hello-world-footnote-ref-3" role="doc-backlink">↩</a></li>
+	</ol>
+</section>
+</section>`,
+		},
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+Booklit[^hello-world-footnote-1] is a tool.[^hello-world-footnote-2]
+
+## <a id="usage"></a>1 Usage
+
+Run it.[^hello-world-footnote-3]
+
+[^hello-world-footnote-1]: A pun on *lit*.
+[^hello-world-footnote-2]: Or a toy.
+[^hello-world-footnote-3]: See [Hello, world!](hello-world.md).
+`,
+		},
+	}),
+
+	Entry("footnotes collected per section", Example{
+		Input: `\title{Hello, world!}
+
+Intro.\footnote{About the intro.}
+
+\section{
+	\title{Usage}
+
+	\collect-endnotes
+
+	Run it.\footnote{About running.}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<p>Intro.<sup class="footnote-ref"><a id="hello-world-footnote-ref-1" href="hello-world.html#hello-world-footnote-1" role="doc-noteref">1</a></sup></p>
+
+<h2>1 Usage</h2>
+
+<p>Run it.<sup class="footnote-ref"><a id="usage-footnote-ref-1" href="hello-world.html#usage-footnote-1" role="doc-noteref">1</a></sup></p>
+
+<section class="endnotes" role="doc-endnotes">
+	<ol>
+		<li id="usage-footnote-1">About running. <a class="footnote-backref" href="hello-world.html#usage-footnote-ref-1" role="doc-backlink">↩</a></li>
+	</ol>
+</section>
+
+<section class="endnotes" role="doc-endnotes">
+	<ol>
+		<li id="hello-world-footnote-1">About the intro. <a class="footnote-backref" href="hello-world.html#hello-world-footnote-ref-1" role="doc-backlink">↩</a></li>
+	</ol>
+</section>
+</section>`,
+		},
+	}),
+
+	Entry("epigraphs and pull-quotes", Example{
+		Input: `\title{Hello, world!}
+
+\epigraph{
+	It's lit!
+}{Jacques Berman Webster II}
+
+\pullquote{Sup!}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<blockquote class="epigraph">
+	<p>It's lit!</p>
+	<footer>— <cite>Jacques Berman Webster II</cite></footer>
+</blockquote>
+
+<aside class="pull-quote" role="note"><blockquote>Sup!</blockquote></aside>
+</section>`,
+		},
+	}),
+
+	Entry("attributions", Example{
+		Input: `\title{Hello, world!}
+
+\license{CC-BY-4.0}
+
+\credit{cat.png}{Photo by \link{Jane}{https://example.com}}{CC0-1.0}
+
+\attributions
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+<table class="attributions">
+	<tr>
+		<td><a href="hello-world.html">Hello, world!</a></td>
+		<td></td>
+		<td><a href="https://spdx.org/licenses/CC-BY-4.0.html">CC-BY-4.0</a></td>
+	</tr>
+	<tr>
+		<td><a href="hello-world.html">cat.png</a></td>
+		<td>Photo by <a href="https://example.com">Jane</a></td>
+		<td><a href="https://spdx.org/licenses/CC0-1.0.html">CC0-1.0</a></td>
+	</tr>
+</table>
+</section>`,
+		},
+	}),
+
+	Entry("search boxes", Example{
+		Input: `\title{Hello, world!}
+
+\search-box
+
+\search-box{Search the docs}
+`,
+
+		Dump: `Section "hello-world"
+  title:
+    String "Hello, world!"
+  body:
+    Sequence
+      Styled "search-box" (block)
+        String "Search"
+        partial Index:
+          String "search_index.json"
+      Styled "search-box" (block)
+        String "Search the docs"
+        partial Index:
+          String "search_index.json"
+`,
+	}),
+	Entry("playgrounds", Example{
+		PlaygroundURL: "https://play.example.com/run",
+
+		Input: `\title{Hello, world!}
+
+\playground{go}{{{
+package main
+
+func main() {}
+}}}
+
+\playground{python}{{{
+print("hi")
+}}}{https://py.example.com/run}
+`,
+
+		Dump: `Section "hello-world"
+  title:
+    String "Hello, world!"
+  body:
+    Sequence
+      Styled "playground" (block)
+        Styled "verbatim" (block)
+          Preformatted
+            Sequence
+              String ""
+              String "package main"
+            String ""
+            Sequence
+              String ""
+              String "func main() {"
+              String "}"
+        partial Language:
+          String "go"
+        partial URL:
+          String "https://play.example.com/run"
+      Styled "playground" (block)
+        Styled "verbatim" (block)
+          Preformatted
+            Sequence
+              String ""
+              String "print(\"hi\")"
+        partial Language:
+          String "python"
+        partial URL:
+          String "https://py.example.com/run"
+`,
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+` + "```go" + `
+package main
+
+func main() {}
+` + "```" + `
+
+` + "```python" + `
+print("hi")
+` + "```" + `
+`,
+		},
+	}),
+
+	Entry("diagrams rendered by the renderer", Example{
+		Input: `\title{Hello, world!}
+
+\mermaid{{{
+graph TD
+  A --> B
+}}}
+
+\dot{{{
+digraph { a -> b }
+}}}
+`,
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+` + "```mermaid" + `
+graph TD
+  A --> B
+` + "```" + `
+
+` + "```dot" + `
+digraph { a -> b }
+` + "```" + `
+`,
+		},
+	}),
+
+	Entry("diagrams rendered at build time", Example{
+		DiagramCommands: map[string][]string{
+			"mermaid": {"sh", "-c", `printf '<?xml version="1.0"?>\n<svg><text>%s</text><script>steal()</script></svg>' "$(cat)"`},
+			"dot":     {"cp", "{input}", "{output}"},
+		},
+
+		Input: `\title{Hello, world!}
+
+\mermaid{graph TD}
+
+\dot{<svg><circle r="1"/></svg>}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<figure class="diagram"><svg><text>graph TD</text></svg></figure>
+
+	<figure class="diagram"><svg><circle r="1"/></svg></figure>
+</section>
+`,
+		},
+	}),
 )
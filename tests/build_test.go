@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/build"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+var _ = Describe("Build", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-build")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello, world!}
+
+Hi!
+
+\section{
+	\title{Empty}
+}
+`), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("writes the book's pages", func() {
+		out := filepath.Join(dir, "out")
+
+		result, err := build.Build(context.Background(), build.Config{
+			In:              filepath.Join(dir, "index.lit"),
+			Out:             out,
+			Processor:       &load.Processor{AllowBrokenReferences: true},
+			SaveSearchIndex: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Book.PrimaryTag.Name).To(Equal("hello-world"))
+		Expect(result.Manifest).To(Equal(render.Manifest{
+			"hello-world": "hello-world.html",
+			"empty":       "hello-world.html#empty",
+		}))
+
+		warnings := []string{}
+		for _, warning := range result.Warnings {
+			warnings = append(warnings, warning.Error())
+		}
+
+		Expect(warnings).To(Equal([]string{"section 'empty' is empty"}))
+
+		Expect(filepath.Join(out, "hello-world.html")).To(BeARegularFile())
+		Expect(filepath.Join(out, "search_index.json")).To(BeARegularFile())
+	})
+
+	It("renders the book to the output without a destination", func() {
+		output := new(bytes.Buffer)
+
+		_, err := build.Build(context.Background(), build.Config{
+			In:     filepath.Join(dir, "index.lit"),
+			Engine: render.NewTextRenderingEngine("txt"),
+			Output: output,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(output.String()).To(ContainSubstring("Hi!"))
+	})
+
+	It("returns errors that can be inspected", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello, world!}
+
+See \reference{missing}.
+`), 0644)).To(Succeed())
+
+		_, err := build.Build(context.Background(), build.Config{
+			In: filepath.Join(dir, "index.lit"),
+		})
+
+		var unknown booklit.UnknownTagError
+		Expect(errors.As(err, &unknown)).To(BeTrue())
+		Expect(unknown.TagName).To(Equal("missing"))
+	})
+
+	It("loads the book from a file system and writes it to an output", func() {
+		output := &render.MemoryOutput{}
+
+		_, err := build.Build(context.Background(), build.Config{
+			In: "docs/index.lit",
+			FS: fstest.MapFS{
+				"docs/index.lit": {Data: []byte(`\title{Hello, world!}
+
+\include-section{child.lit}
+`)},
+				"docs/child.lit": {Data: []byte(`\title{Child}
+
+Hi from the child!
+`)},
+			},
+			Destination: output,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		files := output.Files()
+		Expect(files).To(HaveKey("hello-world.html"))
+		Expect(string(files["hello-world.html"])).To(ContainSubstring("Hi from the child!"))
+	})
+
+	It("annotates errors with the file system's source", func() {
+		_, err := build.Build(context.Background(), build.Config{
+			In: "index.lit",
+			FS: fstest.MapFS{
+				"index.lit": {Data: []byte(`\title{Hello, world!}
+
+See \reference{missing}.
+`)},
+			},
+			Destination: &render.MemoryOutput{},
+		})
+
+		var unknown booklit.UnknownTagError
+		Expect(errors.As(err, &unknown)).To(BeTrue())
+
+		annotated := new(bytes.Buffer)
+		unknown.PrettyPrint(annotated)
+		Expect(annotated.String()).To(ContainSubstring(`   3| See \reference{missing}.`))
+	})
+
+	It("stops once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := build.Build(ctx, build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: filepath.Join(dir, "out"),
+		})
+		Expect(err).To(Equal(context.Canceled))
+
+		Expect(filepath.Join(dir, "out")).ToNot(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"github.com/vito/booklit/booklitcmd"
+)
+
+var _ = Describe("Build info", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-build-info")
+		Expect(err).ToNot(HaveOccurred())
+
+		files := map[string]string{
+			"index.lit": `\title{Hello}
+
+\include-section{child.lit}
+`,
+			"child.lit": `\title{Child}
+
+Hi!
+`,
+			"booklit.yml":      "rebuild-token: base-secret\n",
+			"booklit.prod.yml": "analytics-token: prod-secret\n",
+		}
+
+		for name, content := range files {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	checksum := func(content []byte) string {
+		sum := sha256.Sum256(content)
+		return hex.EncodeToString(sum[:])
+	}
+
+	It("records every source, archiving all but the config files", func() {
+		build := exec.Command(booklitPath,
+			"-i", "index.lit",
+			"-o", "out",
+			"--env", "prod",
+			"--save-build-info",
+			"--save-source-archive",
+		)
+		build.Dir = dir
+		build.Env = append(os.Environ(), "SOURCE_DATE_EPOCH=1700000000")
+
+		session, err := gexec.Start(build, GinkgoWriter, GinkgoWriter)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(session, "10s").Should(gexec.Exit(0))
+
+		payload, err := ioutil.ReadFile(filepath.Join(dir, "out", "build-info.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var info booklitcmd.BuildInfo
+		Expect(json.Unmarshal(payload, &info)).To(Succeed())
+
+		Expect(info.BuiltAt.Unix()).To(Equal(int64(1700000000)))
+		Expect(info.Git).To(BeNil())
+
+		sums := map[string]string{}
+		for _, source := range info.Sources {
+			sums[source.Path] = source.SHA256
+		}
+
+		Expect(sums).To(HaveLen(4))
+		for _, name := range []string{"index.lit", "child.lit", "booklit.yml", "booklit.prod.yml"} {
+			content, err := ioutil.ReadFile(filepath.Join(dir, name))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sums).To(HaveKeyWithValue(name, checksum(content)))
+		}
+
+		archive, err := ioutil.ReadFile(filepath.Join(dir, "out", "sources.tar.gz"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(info.Archive).ToNot(BeNil())
+		Expect(info.Archive.Path).To(Equal("sources.tar.gz"))
+		Expect(info.Archive.SHA256).To(Equal(checksum(archive)))
+
+		archived := map[string]string{}
+
+		gz, err := gzip.NewReader(bytes.NewReader(archive))
+		Expect(err).ToNot(HaveOccurred())
+
+		tr := tar.NewReader(gz)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+
+			content, err := ioutil.ReadAll(tr)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sums).To(HaveKeyWithValue(header.Name, checksum(content)))
+			archived[header.Name] = string(content)
+		}
+
+		Expect(archived).To(HaveLen(2))
+		Expect(archived).To(HaveKey("index.lit"))
+		Expect(archived).To(HaveKey("child.lit"))
+
+		for _, content := range archived {
+			Expect(content).ToNot(ContainSubstring("secret"))
+		}
+	})
+})
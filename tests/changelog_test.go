@@ -0,0 +1,198 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit/changelog"
+	_ "github.com/vito/booklit/changelog/plugin"
+)
+
+var _ = Describe("Changelog", func() {
+	DescribeTable("parsing commit messages",
+		func(message string, expected changelog.Commit) {
+			commit, ok := changelog.Parse("abc123", message)
+			Expect(ok).To(BeTrue())
+			Expect(commit).To(Equal(expected))
+		},
+		Entry("with a type", "feat: add tables", changelog.Commit{
+			Hash:    "abc123",
+			Type:    "feat",
+			Subject: "add tables",
+		}),
+		Entry("with a scope", "fix(render): escape titles", changelog.Commit{
+			Hash:    "abc123",
+			Type:    "fix",
+			Scope:   "render",
+			Subject: "escape titles",
+		}),
+		Entry("lowercasing the type", "Docs(API): explain plugins", changelog.Commit{
+			Hash:    "abc123",
+			Type:    "docs",
+			Scope:   "API",
+			Subject: "explain plugins",
+		}),
+		Entry("marked breaking with a !", "feat(load)!: drop v1 plugins", changelog.Commit{
+			Hash:     "abc123",
+			Type:     "feat",
+			Scope:    "load",
+			Subject:  "drop v1 plugins",
+			Breaking: true,
+		}),
+		Entry("marked breaking with a footer", "feat: rename flags\n\nBREAKING CHANGE: --errors is now --error-format", changelog.Commit{
+			Hash:     "abc123",
+			Type:     "feat",
+			Subject:  "rename flags",
+			Breaking: true,
+		}),
+		Entry("marked breaking with a hyphenated footer", "fix: stricter slugs\n\nBREAKING-CHANGE: tags may change", changelog.Commit{
+			Hash:     "abc123",
+			Type:     "fix",
+			Subject:  "stricter slugs",
+			Breaking: true,
+		}),
+		Entry("with surrounding whitespace", "\n  perf: cache templates\n", changelog.Commit{
+			Hash:    "abc123",
+			Type:    "perf",
+			Subject: "cache templates",
+		}),
+	)
+
+	DescribeTable("rejecting malformed commit messages",
+		func(message string) {
+			_, ok := changelog.Parse("abc123", message)
+			Expect(ok).To(BeFalse())
+		},
+		Entry("without a type", "add tables"),
+		Entry("without a subject", "feat: "),
+		Entry("without a space after the colon", "feat:add tables"),
+		Entry("with an unclosed scope", "fix(render: escape titles"),
+		Entry("with an empty type", ": add tables"),
+		Entry("with the type on a later line", "add tables\n\nfeat: add tables"),
+		Entry("that are empty", ""),
+	)
+
+	commits := []changelog.Commit{
+		{Hash: "1", Type: "feat", Scope: "render", Subject: "add tables"},
+		{Hash: "2", Type: "fix", Scope: "load", Subject: "close files"},
+		{Hash: "3", Type: "docs", Subject: "explain plugins"},
+		{Hash: "4", Type: "fix", Scope: "render", Subject: "escape titles"},
+	}
+
+	hashes := func(commits []changelog.Commit) []string {
+		hashes := []string{}
+		for _, commit := range commits {
+			hashes = append(hashes, commit.Hash)
+		}
+
+		return hashes
+	}
+
+	DescribeTable("filtering commits by scope",
+		func(scopes []string, expected []string) {
+			Expect(hashes(changelog.Filter(commits, scopes...))).To(Equal(expected))
+		},
+		Entry("selecting everything by default", nil, []string{"1", "2", "3", "4"}),
+		Entry("including scopes", []string{"render"}, []string{"1", "4"}),
+		Entry("including many scopes", []string{"render", "load"}, []string{"1", "2", "4"}),
+		Entry("including commits without a scope", []string{""}, []string{"3"}),
+		Entry("excluding scopes", []string{"!render"}, []string{"2", "3"}),
+		Entry("excluding scopes from the included ones", []string{"render", "load", "!load"}, []string{"1", "4"}),
+		Entry("including scopes that no commits have", []string{"search"}, []string{}),
+	)
+
+	Describe("grouping commits by type", func() {
+		It("follows the order of the types, omitting any others", func() {
+			groups := changelog.GroupByType([]changelog.Commit{
+				{Hash: "1", Type: "docs"},
+				{Hash: "2", Type: "fix"},
+				{Hash: "3", Type: "chore"},
+				{Hash: "4", Type: "feat"},
+				{Hash: "5", Type: "fix"},
+			})
+
+			Expect(groups).To(HaveLen(3))
+
+			Expect(groups[0].Type).To(Equal("feat"))
+			Expect(groups[0].Title).To(Equal("Features"))
+			Expect(hashes(groups[0].Commits)).To(Equal([]string{"4"}))
+
+			Expect(groups[1].Type).To(Equal("fix"))
+			Expect(groups[1].Title).To(Equal("Bug Fixes"))
+			Expect(hashes(groups[1].Commits)).To(Equal([]string{"2", "5"}))
+
+			Expect(groups[2].Type).To(Equal("docs"))
+			Expect(groups[2].Title).To(Equal("Documentation"))
+			Expect(hashes(groups[2].Commits)).To(Equal([]string{"1"}))
+		})
+
+		It("collects breaking changes into a leading group, in addition to their type", func() {
+			groups := changelog.GroupByType([]changelog.Commit{
+				{Hash: "1", Type: "fix"},
+				{Hash: "2", Type: "feat", Breaking: true},
+				{Hash: "3", Type: "chore", Breaking: true},
+			})
+
+			Expect(groups).To(HaveLen(3))
+
+			Expect(groups[0].Type).To(Equal("breaking"))
+			Expect(groups[0].Title).To(Equal("Breaking Changes"))
+			Expect(hashes(groups[0].Commits)).To(Equal([]string{"2", "3"}))
+
+			Expect(groups[1].Type).To(Equal("feat"))
+			Expect(hashes(groups[1].Commits)).To(Equal([]string{"2"}))
+
+			Expect(groups[2].Type).To(Equal("fix"))
+			Expect(hashes(groups[2].Commits)).To(Equal([]string{"1"}))
+		})
+
+		It("returns no groups for no commits", func() {
+			Expect(changelog.GroupByType(nil)).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("generating release notes", (Example).Run,
+		Entry("a section for each type of commit", Example{
+			Input: `\title{Hello, world!}
+
+\use-plugin{changelog}
+
+\changelog{}{HEAD}
+`,
+
+			Commits: []string{
+				"feat(render): add tables",
+				"chore: bump dependencies",
+				"fix: close files",
+				"feat(load)!: drop v1 plugins",
+				"not conventional at all",
+			},
+
+			Outputs: Files{
+				"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<h2>1 Breaking Changes</h2>
+
+	<ul>
+		<li><strong>load:</strong> drop v1 plugins (<code>a7696d7</code>)</li>
+	</ul>
+
+	<h2>2 Features</h2>
+
+	<ul>
+		<li><strong>load:</strong> drop v1 plugins (<code>a7696d7</code>)</li>
+		<li><strong>render:</strong> add tables (<code>a528136</code>)</li>
+	</ul>
+
+	<h2>3 Bug Fixes</h2>
+
+	<ul>
+		<li>close files (<code>0bc73ed</code>)</li>
+	</ul>
+</section>`,
+			},
+		}),
+	)
+})
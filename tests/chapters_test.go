@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+var _ = Describe("Chapter cache", func() {
+	var dir string
+
+	writeFile := func(name string, content string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	// render renders the book with LaTeX, with a cache constructed anew as
+	// it is for each build
+	render := func() string {
+		processor := &load.Processor{}
+
+		book, err := processor.LoadFile(filepath.Join(dir, "index.lit"), []booklit.PluginFactory{baselit.NewPlugin})
+		Expect(err).ToNot(HaveOccurred())
+
+		engine := render.NewLaTeXRenderingEngine()
+		engine.Chapters = render.NewChapterCache(filepath.Join(dir, "cache"), "some-key")
+
+		writer := render.Writer{
+			Engine:      engine,
+			Destination: filepath.Join(dir, "out"),
+		}
+
+		Expect(writer.WriteSection(book)).To(Succeed())
+		Expect(engine.Chapters.Prune()).To(Succeed())
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, "out", "book.tex"))
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(content)
+	}
+
+	// markCached marks what each chapter in the cache was rendered to, so
+	// that chapters which are reused rather than rendered again stand out
+	markCached := func() {
+		entries, err := ioutil.ReadDir(filepath.Join(dir, "cache"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, "cache", entry.Name())
+
+			payload, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			var artifact struct {
+				Content []byte `json:"content"`
+			}
+
+			Expect(json.Unmarshal(payload, &artifact)).To(Succeed())
+
+			artifact.Content = append([]byte("% cached\n"), artifact.Content...)
+
+			payload, err = json.Marshal(artifact)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(path, payload, 0644)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-chapters")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.Mkdir(filepath.Join(dir, "out"), 0755)).To(Succeed())
+
+		writeFile("index.lit", `\title{Book}{book}
+
+\include-section{first.lit}
+\include-section{second.lit}
+`)
+
+		writeFile("first.lit", `\title{First}{first}
+
+Continued in \reference{second}.
+`)
+
+		writeFile("second.lit", `\title{Second}{second}
+
+The end.
+`)
+
+		Expect(render()).ToNot(ContainSubstring("% cached"))
+
+		markCached()
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("reuses unchanged chapters", func() {
+		Expect(strings.Count(render(), "% cached")).To(Equal(2))
+	})
+
+	It("renders changed chapters again", func() {
+		writeFile("second.lit", `\title{Second}{second}
+
+The very end.
+`)
+
+		rendered := render()
+		Expect(strings.Count(rendered, "% cached")).To(Equal(1))
+		Expect(rendered).To(ContainSubstring("% cached\n\\chapter{First}"))
+		Expect(rendered).To(ContainSubstring("The very end."))
+
+		entries, err := ioutil.ReadDir(filepath.Join(dir, "cache"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+	})
+
+	It("renders chapters again when a title they refer to changes", func() {
+		writeFile("second.lit", `\title{Finale}{second}
+
+The end.
+`)
+
+		rendered := render()
+		Expect(rendered).ToNot(ContainSubstring("% cached"))
+		Expect(rendered).To(ContainSubstring(`Continued in \hyperref[second]{Finale}`))
+	})
+})
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	flags "github.com/jessevdk/go-flags"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit/booklitcmd"
+)
+
+var _ = Describe("Command flags", func() {
+	DescribeTable("splitting commands into arguments",
+		func(value string, args []string) {
+			var command booklitcmd.CommandLine
+			Expect(command.UnmarshalFlag(value)).To(Succeed())
+			Expect([]string(command)).To(Equal(args))
+		},
+		Entry("by whitespace", "mmdc  -i {input}\t-o {output}", []string{"mmdc", "-i", "{input}", "-o", "{output}"}),
+		Entry("keeping single-quoted arguments together", `cwebp -metadata 'icc exif' {input}`, []string{"cwebp", "-metadata", "icc exif", "{input}"}),
+		Entry("keeping double-quoted arguments together", `"/Applications/Google Chrome" --print-to-pdf={output}`, []string{"/Applications/Google Chrome", "--print-to-pdf={output}"}),
+		Entry("with escapes in double quotes", `echo "say \"hi\" \n"`, []string{"echo", `say "hi" \n`}),
+		Entry("with escaped spaces", `/path/with\ spaces/katex --display-mode`, []string{"/path/with spaces/katex", "--display-mode"}),
+		Entry("joining quoted and unquoted parts", `--title="Hello World"''`, []string{"--title=Hello World"}),
+		Entry("with empty quoted arguments", `cmd '' ""`, []string{"cmd", "", ""}),
+	)
+
+	DescribeTable("rejecting malformed commands",
+		func(value string, message string) {
+			var command booklitcmd.CommandLine
+			Expect(command.UnmarshalFlag(value)).To(MatchError(message))
+		},
+		Entry("with an unterminated single quote", `katex 'oops`, `unterminated quote in command: katex 'oops`),
+		Entry("with an unterminated double quote", `katex "oops`, `unterminated quote in command: katex "oops`),
+		Entry("with a trailing backslash", `katex \`, `trailing backslash in command: katex \`),
+	)
+
+	It("parses command flags, including repeated ones", func() {
+		cmd := &booklitcmd.Command{}
+
+		parser := flags.NewParser(cmd, flags.None)
+		parser.NamespaceDelimiter = "-"
+		parser.SubcommandsOptional = true
+
+		_, err := parser.ParseArgs([]string{
+			"--html-pdf-command", `"/opt/Google Chrome/chrome" --headless --print-to-pdf={output} {input}`,
+			"--post-build", `htmltest '{out}'`,
+			"--post-build", `vale --config "my vale.ini" {out}`,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect([]string(cmd.HTMLEngine.PDFCommand)).To(Equal([]string{"/opt/Google Chrome/chrome", "--headless", "--print-to-pdf={output}", "{input}"}))
+		Expect(cmd.PostBuild).To(Equal([]booklitcmd.CommandLine{
+			{"htmltest", "{out}"},
+			{"vale", "--config", "my vale.ini", "{out}"},
+		}))
+	})
+
+	It("splits external plugin commands", func() {
+		var plugin booklitcmd.ExternalPlugin
+		Expect(plugin.UnmarshalFlag(`shout=python3 "./my plugins/shout.py"`)).To(Succeed())
+		Expect(plugin.Name).To(Equal("shout"))
+		Expect(plugin.Command).To(Equal([]string{"python3", "./my plugins/shout.py"}))
+
+		Expect(plugin.UnmarshalFlag(`shout=python3 "./shout.py`)).ToNot(Succeed())
+	})
+
+	It("splits post-render hook commands", func() {
+		var hook booklitcmd.PostRenderHook
+		Expect(hook.UnmarshalFlag(`.html=tidy -q -e '{file}'`)).To(Succeed())
+		Expect(hook.Extension).To(Equal("html"))
+		Expect(hook.Command).To(Equal([]string{"tidy", "-q", "-e", "{file}"}))
+
+		Expect(hook.UnmarshalFlag(`html=tidy 'oops`)).ToNot(Succeed())
+	})
+})
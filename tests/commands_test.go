@@ -0,0 +1,647 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	"github.com/vito/booklit/booklitcmd"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// writeBook writes the files of a book to a new directory, returning it
+func writeBook(files Files) string {
+	dir, err := ioutil.TempDir("", "booklit-commands")
+	Expect(err).ToNot(HaveOccurred())
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	return dir
+}
+
+// runBooklit runs booklit in the given directory, waiting for it to exit
+func runBooklit(dir string, args ...string) *gexec.Session {
+	cmd := exec.Command(booklitPath, args...)
+	cmd.Dir = dir
+
+	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).ToNot(HaveOccurred())
+	Eventually(session, "10s").Should(gexec.Exit())
+
+	return session
+}
+
+// serveBooklit starts serving the book in the given directory on a free port,
+// returning the session along with the server's URL once it's listening
+func serveBooklit(dir string, args ...string) (*gexec.Session, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	Expect(listener.Close()).To(Succeed())
+
+	cmd := exec.Command(booklitPath, append([]string{"-s", strconv.Itoa(port)}, args...)...)
+	cmd.Dir = dir
+
+	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).ToNot(HaveOccurred())
+	Eventually(session.Err, "10s").Should(gbytes.Say("msg=listening"))
+
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	Eventually(func() error {
+		response, err := http.Get(url)
+		if err != nil {
+			return err
+		}
+
+		return response.Body.Close()
+	}, "10s").Should(Succeed())
+
+	return session, url
+}
+
+var _ = Describe("Commands", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = writeBook(Files{
+			"index.lit": `\title{Hello}
+
+Hello, world!
+`,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("printing errors", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\unknown-function{}`), 0644)).To(Succeed())
+		})
+
+		DescribeTable("in a format",
+			func(args []string, stdout string, stderr string) {
+				session := runBooklit(dir, append([]string{"-i", "index.lit", "-o", "out"}, args...)...)
+				Expect(session.ExitCode()).To(Equal(1))
+				Expect(string(session.Out.Contents())).To(ContainSubstring(stdout))
+				Expect(string(session.Err.Contents())).To(ContainSubstring(stderr))
+			},
+			Entry("as text by default", nil, "", "undefined function \\unknown-function"),
+			Entry("as JSON", []string{"--error-format", "json"}, "", `"errors": [`),
+			Entry("as SARIF", []string{"--error-format", "sarif"}, `"version": "2.1.0"`, ""),
+			Entry("with the deprecated --errors", []string{"--errors", "json"}, "", `"errors": [`),
+			Entry("with the last of --errors and --error-format", []string{"--errors", "sarif", "--error-format", "json"}, "", `"errors": [`),
+		)
+
+		It("rejects unknown formats", func() {
+			session := runBooklit(dir, "-i", "index.lit", "-o", "out", "--error-format", "yaml")
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("Allowed values are: text, json or sarif"))
+		})
+	})
+
+	Describe("selecting a renderer", func() {
+		It("renders with the selected one", func() {
+			session := runBooklit(dir, "-i", "index.lit", "-o", "out", "--markdown-render")
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(filepath.Join(dir, "out", "hello.md")).To(BeAnExistingFile())
+			Expect(filepath.Join(dir, "out", "hello.html")).ToNot(BeAnExistingFile())
+		})
+
+		DescribeTable("by name",
+			func(args []string, page string) {
+				session := runBooklit(dir, append([]string{"-i", "index.lit", "-o", "out"}, args...)...)
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(filepath.Join(dir, "out", page)).To(BeAnExistingFile())
+			},
+			Entry("html", []string{"--renderer", "html"}, "hello.html"),
+			Entry("markdown", []string{"--renderer", "markdown"}, "hello.md"),
+			Entry("text, with a file extension", []string{"--renderer", "text", "--text-file-extension", "txt"}, "hello.txt"),
+			Entry("along with its --X-render flag", []string{"--renderer", "markdown", "--markdown-render"}, "hello.md"),
+		)
+
+		DescribeTable("rejecting invalid selections",
+			func(args []string, message string) {
+				session := runBooklit(dir, append([]string{"-i", "index.lit", "-o", "out"}, args...)...)
+				Expect(session.ExitCode()).To(Equal(1))
+				Expect(string(session.Err.Contents())).To(ContainSubstring(message))
+				Expect(filepath.Join(dir, "out")).ToNot(BeAnExistingFile())
+			},
+			Entry("with two --X-render flags", []string{"--pdf-render", "--epub-render"}, "only one renderer may be selected, but --epub-render and --pdf-render select different ones"),
+			Entry("with a text file extension", []string{"--markdown-render", "--text-file-extension", "txt"}, "only one renderer may be selected, but --markdown-render and --text-file-extension select different ones"),
+			Entry("with a Confluence URL", []string{"--confluence-url", "https://example.atlassian.net/wiki", "--docx-render"}, "only one renderer may be selected, but --confluence-url and --docx-render select different ones"),
+			Entry("with --renderer and a different --X-render flag", []string{"--renderer", "html", "--markdown-render"}, "only one renderer may be selected, but --renderer html and --markdown-render select different ones"),
+			Entry("with --renderer text and no file extension", []string{"--renderer", "text"}, "--renderer text requires --text-file-extension"),
+			Entry("with --renderer pdf in safe mode", []string{"--renderer", "pdf", "--safe"}, "--renderer pdf is not supported with --safe"),
+			Entry("with an unknown --renderer", []string{"--renderer", "rtf"}, "Invalid value `rtf' for option `--renderer'"),
+		)
+	})
+	DescribeTable("logging how long each stage took",
+		func(args []string, logged bool) {
+			session := runBooklit(dir, append([]string{"-i", "index.lit", "-o", "out"}, args...)...)
+			Expect(session.ExitCode()).To(Equal(0))
+
+			if logged {
+				Expect(session.Err).To(gbytes.Say(`msg="finished stage".*stage=parse`))
+			} else {
+				Expect(string(session.Err.Contents())).ToNot(ContainSubstring("finished stage"))
+			}
+		},
+		Entry("not by default", nil, false),
+		Entry("with --verbose", []string{"--verbose"}, true),
+		Entry("with --debug", []string{"--debug"}, true),
+	)
+	It("profiles the functions invoked by each section", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello}
+
+\bold{one} \italic{two} \bold{three}
+
+\section{
+  \title{Child}
+
+  \bold{four}
+}
+`), 0644)).To(Succeed())
+
+		session := runBooklit(dir, "-i", "index.lit", "-o", "out", "--profile")
+		Expect(session.ExitCode()).To(Equal(0))
+
+		rows := map[string]string{}
+		for _, line := range strings.Split(string(session.Err.Contents()), "\n") {
+			if line == "" || strings.HasPrefix(line, "time=") {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			Expect(fields).To(HaveLen(6), line)
+			rows[fields[0]+" "+fields[1]] = fields[2]
+
+			if fields[0] != "FUNCTION" {
+				Expect(fields[3]).To(MatchRegexp(`^[0-9.]+(ns|µs|ms|s)$`))
+				Expect(fields[4]).To(MatchRegexp(`^[0-9.]+(ns|µs|ms|s)$`))
+				Expect(fields[5]).To(MatchRegexp(`^[0-9.]+%$`))
+			}
+		}
+
+		Expect(rows).To(Equal(map[string]string{
+			"FUNCTION SECTION": "COUNT",
+			`\title hello`:     "1",
+			`\bold hello`:      "2",
+			`\italic hello`:    "1",
+			`\section hello`:   "1",
+			`\title child`:     "1",
+			`\bold child`:      "1",
+		}))
+	})
+	Describe("writing metrics", func() {
+		// samples parses the metrics, checking that each is described
+		samples := func(metrics string) map[string]string {
+			samples := map[string]string{}
+			described := map[string]bool{}
+
+			for _, line := range strings.Split(strings.TrimSpace(metrics), "\n") {
+				fields := strings.Fields(line)
+
+				if strings.HasPrefix(line, "# TYPE ") {
+					Expect(fields).To(HaveLen(4), line)
+					Expect(fields[3]).To(Or(Equal("counter"), Equal("gauge")))
+					described[fields[2]] = true
+					continue
+				}
+
+				if strings.HasPrefix(line, "# HELP ") {
+					continue
+				}
+
+				Expect(fields).To(HaveLen(2), line)
+				Expect(described).To(HaveKey(strings.SplitN(fields[0], "{", 2)[0]))
+
+				samples[fields[0]] = fields[1]
+			}
+
+			return samples
+		}
+
+		It("writes the outcome of the build to --metrics-file", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello}
+
+\section{\title{Child} Hi.}
+`), 0644)).To(Succeed())
+
+			session := runBooklit(dir, "-i", "index.lit", "-o", "out", "--metrics-file", "metrics.prom")
+			Expect(session.ExitCode()).To(Equal(0))
+
+			metrics, err := ioutil.ReadFile(filepath.Join(dir, "metrics.prom"))
+			Expect(err).ToNot(HaveOccurred())
+
+			written := samples(string(metrics))
+			Expect(written).To(HaveLen(8))
+			Expect(written).To(HaveKeyWithValue("booklit_builds_total", "1"))
+			Expect(written).To(HaveKeyWithValue("booklit_build_errors_total", "0"))
+			Expect(written).To(HaveKeyWithValue("booklit_sections", "2"))
+			Expect(written).To(HaveKeyWithValue("booklit_parse_cache_hits_total", "0"))
+			Expect(written).To(HaveKeyWithValue("booklit_parse_cache_misses_total", "1"))
+			Expect(written).To(HaveKeyWithValue("booklit_request_duration_seconds_total", "0"))
+			Expect(written["booklit_last_build_duration_seconds"]).To(Equal(written["booklit_build_duration_seconds_total"]))
+			Expect(strconv.ParseFloat(written["booklit_last_build_duration_seconds"], 64)).To(BeNumerically(">", 0))
+		})
+
+		It("counts failed builds", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\unknown-function{}`), 0644)).To(Succeed())
+
+			session := runBooklit(dir, "-i", "index.lit", "-o", "out", "--metrics-file", "metrics.prom")
+			Expect(session.ExitCode()).To(Equal(1))
+
+			metrics, err := ioutil.ReadFile(filepath.Join(dir, "metrics.prom"))
+			Expect(err).ToNot(HaveOccurred())
+
+			written := samples(string(metrics))
+			Expect(written).To(HaveKeyWithValue("booklit_builds_total", "1"))
+			Expect(written).To(HaveKeyWithValue("booklit_build_errors_total", "1"))
+			Expect(written).To(HaveKeyWithValue("booklit_sections", "0"))
+		})
+
+		It("counts requests by status code when serving", func() {
+			metrics := &booklitcmd.Metrics{}
+			metrics.ObserveRequest(http.StatusOK, time.Second)
+			metrics.ObserveRequest(http.StatusNotFound, time.Second)
+			metrics.ObserveRequest(http.StatusOK, time.Second)
+
+			recorder := httptest.NewRecorder()
+			metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("text/plain; version=0.0.4"))
+
+			written := samples(recorder.Body.String())
+			Expect(written).To(HaveKeyWithValue(`booklit_requests_total{code="200"}`, "2"))
+			Expect(written).To(HaveKeyWithValue(`booklit_requests_total{code="404"}`, "1"))
+			Expect(written).To(HaveKeyWithValue("booklit_request_duration_seconds_total", "3"))
+		})
+	})
+	Describe("serving", func() {
+		get := func(url string) int {
+			request, err := http.NewRequest("GET", url, nil)
+			Expect(err).ToNot(HaveOccurred())
+			request.Header.Set("User-Agent", "booklit-tests")
+
+			response, err := http.DefaultClient.Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+
+			return response.StatusCode
+		}
+
+		It("logs each request with --access-log", func() {
+			session, url := serveBooklit(dir, "-i", "index.lit", "--access-log")
+			defer session.Interrupt()
+
+			Expect(get(url + "/hello.html")).To(Equal(http.StatusOK))
+			Eventually(session.Err).Should(gbytes.Say(`msg=request bytes=[1-9][0-9]* duration=[0-9.e-]+ method=GET path=/hello.html remote="127.0.0.1:[0-9]+" status=200 user_agent=booklit-tests`))
+
+			Expect(get(url + "/missing.html")).To(Equal(http.StatusNotFound))
+			Eventually(session.Err).Should(gbytes.Say(`msg=request bytes=[0-9]+ duration=[0-9.e-]+ method=GET path=/missing.html remote="127.0.0.1:[0-9]+" status=404 user_agent=booklit-tests`))
+
+			session.Interrupt()
+			Eventually(session, "10s").Should(gexec.Exit(0))
+		})
+
+		It("doesn't log requests by default", func() {
+			session, url := serveBooklit(dir, "-i", "index.lit")
+			defer session.Interrupt()
+
+			Expect(get(url + "/hello.html")).To(Equal(http.StatusOK))
+
+			session.Interrupt()
+			Eventually(session, "10s").Should(gexec.Exit(0))
+			Expect(string(session.Err.Contents())).ToNot(ContainSubstring("msg=request"))
+		})
+	})
+	Describe("printing trees", func() {
+		// the same tree is expected in either format, as JSON is valid YAML
+		matchTree := func(format string, tree string) OmegaMatcher {
+			if format == "yaml" {
+				return And(MatchYAML(tree), Not(HavePrefix("{")))
+			}
+
+			return MatchJSON(tree)
+		}
+
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello}
+
+Some \bold{bold} text.
+
+\section{
+  \title{Child}
+
+  Hi.
+}
+`), 0644)).To(Succeed())
+		})
+
+		const childTree = `{
+  "type": "section",
+  "number": "1",
+  "tags": ["child"],
+  "title": {"type": "string", "value": "Child"},
+  "body": {
+    "type": "paragraph",
+    "lines": [{"type": "string", "value": "Hi."}]
+  },
+  "children": []
+}`
+
+		DescribeTable("printing the syntax tree of a file with ast",
+			func(format string) {
+				Expect(ioutil.WriteFile(filepath.Join(dir, "para.lit"), []byte(`Some \bold{bold} text.`), 0644)).To(Succeed())
+
+				args := []string{"ast", "para.lit"}
+				if format != "" {
+					args = append(args, "--format", format)
+				}
+
+				session := runBooklit(dir, args...)
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(matchTree(format, `{
+  "type": "sequence",
+  "nodes": [
+    {
+      "type": "paragraph",
+      "lines": [
+        {
+          "type": "sequence",
+          "nodes": [
+            {"type": "string", "value": "Some "},
+            {
+              "type": "invoke",
+              "function": "bold",
+              "location": {"line": 1, "column": 6},
+              "arguments": [
+                {"type": "sequence", "nodes": [{"type": "string", "value": "bold"}]}
+              ]
+            },
+            {"type": "string", "value": " text."}
+          ]
+        }
+      ]
+    }
+  ]
+}`))
+			},
+			Entry("as JSON by default", ""),
+			Entry("as YAML", "yaml"),
+		)
+
+		DescribeTable("printing the resolved content tree of a section with resolve",
+			func(format string) {
+				args := []string{"-i", "index.lit", "resolve"}
+				if format != "" {
+					args = append(args, "--format", format)
+				}
+
+				session := runBooklit(dir, args...)
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(matchTree(format, `{
+  "type": "section",
+  "number": "",
+  "tags": ["hello"],
+  "title": {"type": "string", "value": "Hello"},
+  "body": {
+    "type": "paragraph",
+    "lines": [
+      {
+        "type": "sequence",
+        "contents": [
+          {"type": "string", "value": "Some "},
+          {
+            "type": "styled",
+            "style": "bold",
+            "block": false,
+            "content": {"type": "string", "value": "bold"}
+          },
+          {"type": "string", "value": " text."}
+        ]
+      }
+    ]
+  },
+  "children": [`+childTree+`]
+}`))
+			},
+			Entry("as JSON by default", ""),
+			Entry("as YAML", "yaml"),
+		)
+
+		It("prints the tree of the section with the given tag", func() {
+			session := runBooklit(dir, "-i", "index.lit", "resolve", "--section", "child")
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchJSON(childTree))
+		})
+
+		DescribeTable("failing",
+			func(args []string, message string) {
+				session := runBooklit(dir, args...)
+				Expect(session.ExitCode()).To(Equal(1))
+				Expect(string(session.Err.Contents())).To(ContainSubstring(message))
+				Expect(session.Out.Contents()).To(BeEmpty())
+			},
+			Entry("to parse a missing file", []string{"ast", "missing.lit"}, "open missing.lit: no such file or directory"),
+			Entry("to resolve without --in", []string{"resolve"}, "--in must be specified"),
+			Entry("to resolve an unknown tag", []string{"-i", "index.lit", "resolve", "--section", "missing"}, "unknown tag: missing"),
+		)
+	})
+	Describe("printing syntax definitions", func() {
+		// knownFunctions returns the functions matched between the prefix and
+		// suffix of a pattern
+		knownFunctions := func(pattern, prefix, separator, suffix string) []string {
+			Expect(pattern).To(HavePrefix(prefix))
+			Expect(pattern).To(HaveSuffix(suffix))
+			return strings.Split(strings.TrimSuffix(strings.TrimPrefix(pattern, prefix), suffix), separator)
+		}
+
+		DescribeTable("for an editor, recognizing the functions of every plugin",
+			func(target string, functions func([]byte) []string) {
+				session := runBooklit(dir, "--external-plugin", "ext="+externalPluginPath, "syntax", "--target", target)
+				Expect(session.ExitCode()).To(Equal(0))
+
+				known := functions(session.Out.Contents())
+				Expect(sort.StringsAreSorted(known)).To(BeTrue())
+				Expect(known).To(ContainElement("title"))
+				Expect(known).To(ContainElement("include-section"))
+				Expect(known).To(ContainElement("shout"))
+
+				seen := map[string]bool{}
+				for _, fn := range known {
+					Expect(fn).To(MatchRegexp(`^[a-z-]+$`))
+					Expect(seen).ToNot(HaveKey(fn))
+					seen[fn] = true
+				}
+			},
+			Entry("as a VS Code grammar", "vscode", func(output []byte) []string {
+				var grammar struct {
+					ScopeName  string   `json:"scopeName"`
+					FileTypes  []string `json:"fileTypes"`
+					Repository struct {
+						Function struct {
+							Patterns []struct {
+								Match string `json:"match"`
+								Name  string `json:"name"`
+							} `json:"patterns"`
+						} `json:"function"`
+					} `json:"repository"`
+				}
+
+				Expect(json.Unmarshal(output, &grammar)).To(Succeed())
+				Expect(grammar.ScopeName).To(Equal("text.booklit"))
+				Expect(grammar.FileTypes).To(Equal([]string{"lit"}))
+
+				known := grammar.Repository.Function.Patterns[0]
+				Expect(known.Name).To(Equal("support.function.booklit"))
+
+				return knownFunctions(known.Match, `\\(?:`, "|", `)(?![a-z-])`)
+			}),
+			Entry("as a Vim syntax file", "vim", func(output []byte) []string {
+				lines := strings.Split(string(output), "\n")
+				Expect(lines).To(ContainElement(`let b:current_syntax = "booklit"`))
+
+				for _, line := range lines {
+					if strings.HasPrefix(line, "syn match booklitKnownFunction ") {
+						return knownFunctions(strings.TrimPrefix(line, "syn match booklitKnownFunction "), `"\\\%(`, `\|`, `\)[a-z-]\@!"`)
+					}
+				}
+
+				Fail("no booklitKnownFunction in:\n" + string(output))
+				return nil
+			}),
+			Entry("as a Sublime Text syntax", "sublime", func(output []byte) []string {
+				// yaml.v2 rejects the directive for YAML 1.2, which it otherwise parses
+				Expect(string(output)).To(HavePrefix("%YAML 1.2\n---\n"))
+				output = bytes.TrimPrefix(output, []byte("%YAML 1.2\n"))
+
+				var syntax struct {
+					Scope          string   `yaml:"scope"`
+					FileExtensions []string `yaml:"file_extensions"`
+					Contexts       struct {
+						Main []struct {
+							Match string `yaml:"match"`
+							Scope string `yaml:"scope"`
+						} `yaml:"main"`
+					} `yaml:"contexts"`
+				}
+
+				Expect(yaml.Unmarshal(output, &syntax)).To(Succeed())
+				Expect(syntax.Scope).To(Equal("text.booklit"))
+				Expect(syntax.FileExtensions).To(Equal([]string{"lit"}))
+
+				for _, context := range syntax.Contexts.Main {
+					if context.Scope == "support.function.booklit" {
+						return knownFunctions(context.Match, `\\(?:`, "|", `)(?![a-z-])`)
+					}
+				}
+
+				Fail("no support.function.booklit in:\n" + string(output))
+				return nil
+			}),
+		)
+
+		It("requires a target", func() {
+			session := runBooklit(dir, "syntax")
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("the required flag `-t, --target' was not specified"))
+		})
+	})
+	Describe("completing the command line", func() {
+		complete := func(args ...string) []string {
+			cmd := exec.Command(booklitPath, args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GO_FLAGS_COMPLETION=1")
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(session, "10s").Should(gexec.Exit(0))
+
+			return strings.Fields(string(session.Out.Contents()))
+		}
+
+		It("completes flags, leaving out deprecated ones", func() {
+			Expect(complete("--error")).To(ContainElement("--error-format"))
+			Expect(complete("--error")).ToNot(ContainElement("--errors"))
+			Expect(complete("--max-e")).To(Equal([]string{"--max-errors"}))
+			Expect(complete("-i", "index.lit", "--html-")).To(ContainElement("--html-templates"))
+		})
+
+		It("completes subcommands and their arguments", func() {
+			Expect(complete("comp")).To(Equal([]string{"completion"}))
+			Expect(complete("completion", "")).To(Equal([]string{"bash", "fish", "zsh"}))
+			Expect(complete("completion", "z")).To(Equal([]string{"zsh"}))
+		})
+
+		It("completes environments from the config overlays", func() {
+			for _, name := range []string{"booklit.yml", "booklit.production.yml", "booklit.staging.yml", "other.yml", "other.preview.yml"} {
+				Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644)).To(Succeed())
+			}
+
+			Expect(complete("--env", "")).To(Equal([]string{"production", "staging"}))
+			Expect(complete("--env=p")).To(Equal([]string{"--env=production"}))
+			Expect(complete("--config", "other.yml", "--env", "")).To(Equal([]string{"preview"}))
+		})
+
+		It("completes tags from the manifest in --out, or the config's out", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello}
+
+\section{\title{Child}}
+
+\section{\title{Chores}}
+`), 0644)).To(Succeed())
+
+			session := runBooklit(dir, "-i", "index.lit", "-o", "out", "--save-manifest")
+			Expect(session.ExitCode()).To(Equal(0))
+
+			Expect(complete("-o", "out", "--section-tag", "")).To(Equal([]string{"child", "chores", "hello"}))
+			Expect(complete("--out", "out", "--section-tag", "ch")).To(Equal([]string{"child", "chores"}))
+			Expect(complete("--section-tag", "")).To(BeEmpty())
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "booklit.yml"), []byte("out: out\n"), 0644)).To(Succeed())
+			Expect(complete("--section-tag", "h")).To(Equal([]string{"hello"}))
+		})
+
+		DescribeTable("printing a completion script",
+			func(shell string, line string) {
+				session := runBooklit(dir, "completion", shell)
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(string(session.Out.Contents())).To(HavePrefix("# " + shell + " completion for booklit\n"))
+				Expect(strings.Split(string(session.Out.Contents()), "\n")).To(ContainElement(line))
+			},
+			Entry("for bash", "bash", "complete -o default -F _booklit booklit"),
+			Entry("for zsh", "zsh", "compdef _booklit booklit"),
+			Entry("for fish", "fish", "complete -c booklit -a '(__booklit_complete)'"),
+		)
+
+		It("rejects unknown shells", func() {
+			session := runBooklit(dir, "completion", "tcsh")
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("unknown shell: tcsh"))
+		})
+	})
+})
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+// configExample builds a book with config files, checking which flags they
+// were converted to by the book's output
+type configExample struct {
+	// config files and any other files they refer to, by path
+	Files Files
+
+	Args []string
+
+	// content expected in each output file, and outputs which should not
+	// exist
+	Outputs Files
+	Missing []string
+
+	// expected error, if the build should fail
+	Err string
+}
+
+const configBook = `\title{Hello}{hello}
+
+\if-flag{beta}{Beta content.}
+
+\if-flag{enterprise}{Enterprise content.}
+`
+
+func (example configExample) Run() {
+	dir, err := ioutil.TempDir("", "booklit-config")
+	Expect(err).ToNot(HaveOccurred())
+
+	defer os.RemoveAll(dir)
+
+	files := Files{"index.lit": configBook}
+	for name, content := range example.Files {
+		files[name] = content
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	cmd := exec.Command(booklitPath, append([]string{"-i", "index.lit"}, example.Args...)...)
+	cmd.Dir = dir
+
+	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	Expect(err).ToNot(HaveOccurred())
+	Eventually(session, "10s").Should(gexec.Exit())
+
+	if example.Err != "" {
+		Expect(session.ExitCode()).ToNot(Equal(0))
+		Expect(string(session.Err.Contents())).To(ContainSubstring(example.Err))
+		return
+	}
+
+	Expect(session.ExitCode()).To(Equal(0))
+
+	for name, content := range example.Outputs {
+		output, err := ioutil.ReadFile(filepath.Join(dir, name))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring(content))
+	}
+
+	for _, name := range example.Missing {
+		Expect(filepath.Join(dir, name)).ToNot(BeAnExistingFile())
+	}
+}
+
+var _ = DescribeTable("Config", (configExample).Run,
+	Entry("converting keys to flags", configExample{
+		Files: Files{
+			"booklit.yml": "out: out\n",
+		},
+
+		Outputs: Files{
+			"out/hello.html": "<title>Hello</title>",
+		},
+	}),
+
+	Entry("joining nested keys", configExample{
+		Files: Files{
+			"booklit.yml":    "out: out\nhtml:\n  templates: tmpl\n",
+			"tmpl/page.tmpl": "custom page: {{.Title.String}}",
+		},
+
+		Outputs: Files{
+			"out/hello.html": "custom page: Hello",
+		},
+	}),
+
+	Entry("converting true bools to flags", configExample{
+		Files: Files{
+			"booklit.yml": "out: out\nstrict: true\n",
+			"index.lit":   "\\title{Hello}{hello}\n\n\\section{\\title{Empty}}\n",
+		},
+
+		Err: "section 'empty' is empty",
+	}),
+
+	Entry("leaving out false bools", configExample{
+		Files: Files{
+			"booklit.yml": "out: out\nstrict: false\n",
+			"index.lit":   "\\title{Hello}{hello}\n\n\\section{\\title{Empty}}\n",
+		},
+
+		Outputs: Files{
+			"out/hello.html": "Empty",
+		},
+	}),
+
+	Entry("repeating flags for lists", configExample{
+		Files: Files{
+			"booklit.yml": "out: out\nflag: [beta, enterprise]\n",
+		},
+
+		Outputs: Files{
+			"out/hello.html": "<p>Beta content.</p><p>Enterprise content.</p>",
+		},
+	}),
+
+	Entry("applying the environment's overlay over the config", configExample{
+		Files: Files{
+			"booklit.yml":      "out: base\nflag: [beta]\n",
+			"booklit.prod.yml": "out: prod\n",
+		},
+
+		Args: []string{"--env", "prod"},
+
+		Outputs: Files{
+			"prod/hello.html": "Beta content.",
+		},
+
+		Missing: []string{"base"},
+	}),
+
+	Entry("preferring flags given on the command line", configExample{
+		Files: Files{
+			"booklit.yml":      "out: base\n",
+			"booklit.prod.yml": "out: prod\n",
+		},
+
+		Args: []string{"--env", "prod", "-o", "cli"},
+
+		Outputs: Files{
+			"cli/hello.html": "<title>Hello</title>",
+		},
+
+		Missing: []string{"base", "prod"},
+	}),
+
+	Entry("loading the config given by --config", configExample{
+		Files: Files{
+			"booklit.yml": "out: base\n",
+			"other.yml":   "out: other\n",
+		},
+
+		Args: []string{"--config", "other.yml"},
+
+		Outputs: Files{
+			"other/hello.html": "<title>Hello</title>",
+		},
+
+		Missing: []string{"base"},
+	}),
+
+	Entry("rejecting invalid config", configExample{
+		Files: Files{
+			"booklit.yml": "out: [\n",
+		},
+
+		Err: "invalid config booklit.yml",
+	}),
+
+	Entry("rejecting nested keys which aren't strings", configExample{
+		Files: Files{
+			"booklit.yml": "html:\n  1: x\n",
+		},
+
+		Err: "invalid config key under html: 1",
+	}),
+
+	Entry("requiring the environment's overlay", configExample{
+		Files: Files{
+			"booklit.yml": "out: out\n",
+		},
+
+		Args: []string{"--env", "prod"},
+
+		Err: "booklit.prod.yml",
+	}),
+)
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/confluence"
+	"github.com/vito/booklit/render"
+)
+
+// confluencePage is a page stored by fakeConfluence, as sent by the
+// publisher.
+type confluencePage struct {
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Space struct {
+		Key string `json:"key"`
+	} `json:"space"`
+	Ancestors []struct {
+		ID string `json:"id"`
+	} `json:"ancestors,omitempty"`
+	Body struct {
+		Storage struct {
+			Value          string `json:"value"`
+			Representation string `json:"representation"`
+		} `json:"storage"`
+	} `json:"body"`
+	Version *confluenceVersion `json:"version,omitempty"`
+}
+
+type confluenceVersion struct {
+	Number int `json:"number"`
+}
+
+// fakeConfluence implements enough of Confluence's REST API to publish to,
+// storing pages by title.
+type fakeConfluence struct {
+	pages    map[string]*confluencePage
+	requests []string
+
+	lock sync.Mutex
+}
+
+func (fake *fakeConfluence) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	fake.requests = append(fake.requests, r.Method+" "+r.URL.Path)
+
+	user, token, _ := r.BasicAuth()
+	if user != "someone" || token != "some-token" {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/wiki/rest/api/content":
+		results := []*confluencePage{}
+		if page, found := fake.pages[r.URL.Query().Get("title")]; found && r.URL.Query().Get("spaceKey") == page.Space.Key {
+			results = append(results, page)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+
+	case r.Method == "POST" && r.URL.Path == "/wiki/rest/api/content":
+		page := &confluencePage{}
+		err := json.NewDecoder(r.Body).Decode(page)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page.ID = fmt.Sprintf("%d", len(fake.pages)+1)
+		page.Version = &confluenceVersion{Number: 1}
+		fake.pages[page.Title] = page
+
+		json.NewEncoder(w).Encode(page)
+
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/wiki/rest/api/content/"):
+		page := &confluencePage{}
+		err := json.NewDecoder(r.Body).Decode(page)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if page.ID != strings.TrimPrefix(r.URL.Path, "/wiki/rest/api/content/") {
+			http.Error(w, "mismatched id", http.StatusBadRequest)
+			return
+		}
+		fake.pages[page.Title] = page
+
+		json.NewEncoder(w).Encode(page)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var _ = Describe("Publishing to Confluence", func() {
+	var fake *fakeConfluence
+	var server *httptest.Server
+	var publisher confluence.Publisher
+
+	BeforeEach(func() {
+		fake = &fakeConfluence{
+			pages: map[string]*confluencePage{},
+		}
+
+		server = httptest.NewServer(fake)
+
+		publisher = confluence.Publisher{
+			URL:      server.URL + "/wiki/",
+			Space:    "DOCS",
+			ParentID: "99",
+			User:     "someone",
+			Token:    "some-token",
+			Engine:   render.NewConfluenceRenderingEngine(),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	section := func() *booklit.Section {
+		return loadSection(`\title{Hello, world!}{hello}
+\split-sections
+
+Hello!
+
+\section{
+	\title{Split}
+
+	Split off.
+
+	\section{
+		\title{Inline}
+
+		Rendered inline.
+	}
+}
+`)
+	}
+
+	It("creates a page for each page-level section beneath its parent", func() {
+		Expect(publisher.Publish(section())).To(Succeed())
+
+		Expect(fake.pages).To(HaveLen(2))
+
+		root := fake.pages["Hello, world!"]
+		Expect(root.Type).To(Equal("page"))
+		Expect(root.Space.Key).To(Equal("DOCS"))
+		Expect(root.Ancestors).To(HaveLen(1))
+		Expect(root.Ancestors[0].ID).To(Equal("99"))
+		Expect(root.Body.Storage.Representation).To(Equal("storage"))
+		Expect(root.Body.Storage.Value).To(ContainSubstring("<p>Hello!</p>"))
+
+		split := fake.pages["Split"]
+		Expect(split.Ancestors).To(HaveLen(1))
+		Expect(split.Ancestors[0].ID).To(Equal(root.ID))
+		Expect(split.Body.Storage.Value).To(ContainSubstring("<p>Split off.</p>"))
+		Expect(split.Body.Storage.Value).To(ContainSubstring("<p>Rendered inline.</p>"))
+
+		Expect(fake.requests).To(Equal([]string{
+			"GET /wiki/rest/api/content",
+			"POST /wiki/rest/api/content",
+			"GET /wiki/rest/api/content",
+			"POST /wiki/rest/api/content",
+		}))
+	})
+
+	It("updates existing pages with the next version", func() {
+		Expect(publisher.Publish(section())).To(Succeed())
+
+		fake.pages["Split"].Version.Number = 3
+
+		Expect(publisher.Publish(section())).To(Succeed())
+
+		Expect(fake.pages).To(HaveLen(2))
+		Expect(fake.pages["Hello, world!"].Version.Number).To(Equal(2))
+		Expect(fake.pages["Split"].Version.Number).To(Equal(4))
+
+		Expect(fake.requests[4:]).To(Equal([]string{
+			"GET /wiki/rest/api/content",
+			"PUT /wiki/rest/api/content/1",
+			"GET /wiki/rest/api/content",
+			"PUT /wiki/rest/api/content/2",
+		}))
+	})
+
+	It("returns the error responded with", func() {
+		publisher.Token = "bogus"
+
+		err := publisher.Publish(section())
+		Expect(err).To(MatchError(ContainSubstring("publish 'Hello, world!': GET /rest/api/content?")))
+		Expect(err).To(MatchError(ContainSubstring("401 Unauthorized: bad credentials")))
+	})
+})
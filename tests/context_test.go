@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/build"
+	"github.com/vito/booklit/load"
+)
+
+// contextPlugin provides functions which take a while, either respecting the
+// context or ignoring it
+type contextPlugin struct {
+	release chan struct{}
+}
+
+func (plugin contextPlugin) Hang() booklit.Content {
+	<-plugin.release
+	return booklit.String("done")
+}
+
+func (plugin contextPlugin) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (plugin contextPlugin) HasDeadline(ctx context.Context, label booklit.Content) booklit.Content {
+	_, hasDeadline := ctx.Deadline()
+	return booklit.String(fmt.Sprintf("%s: %v", label, hasDeadline))
+}
+
+// cancelPlugin cancels the build's context, finishing only after a while
+type cancelPlugin struct {
+	cancel   context.CancelFunc
+	finished *bool
+}
+
+func (plugin cancelPlugin) Cancel() {
+	plugin.cancel()
+	time.Sleep(10 * time.Millisecond)
+	*plugin.finished = true
+}
+
+var _ = Describe("Contexts", func() {
+	var dir string
+	var plugin contextPlugin
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-context")
+		Expect(err).ToNot(HaveOccurred())
+
+		plugin = contextPlugin{
+			release: make(chan struct{}),
+		}
+	})
+
+	AfterEach(func() {
+		close(plugin.release)
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	loadBook := func(ctx context.Context, source string) (build.Result, error) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(source), 0644)).To(Succeed())
+
+		return build.Build(ctx, build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: filepath.Join(dir, "out"),
+			Plugins: []booklit.PluginFactory{
+				func(*booklit.Section) booklit.Plugin { return plugin },
+			},
+		})
+	}
+
+	It("gives the context to functions which take it", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		result, err := loadBook(ctx, `\title{Hello}
+
+\has-deadline{deadline}
+`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Book.Body.String()).To(ContainSubstring("deadline: true"))
+	})
+
+	It("lists functions which take the context", func() {
+		Expect(booklit.PluginFunctions(plugin)).To(Equal([]string{"hang", "has-deadline", "wait"}))
+	})
+
+	It("gives the book its context", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		result, err := loadBook(ctx, `\title{Hello}
+
+\section{
+	\title{Child}
+
+	Hi!
+}
+`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Book.Children[0].Context()).To(Equal(ctx))
+	})
+
+	It("stops functions which respect the deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := loadBook(ctx, `\title{Hello}
+
+\wait
+`)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		var funcErr booklit.FailedFunctionError
+		Expect(errors.As(err, &funcErr)).To(BeTrue())
+		Expect(funcErr.Function).To(Equal("wait"))
+	})
+
+	It("gives up on functions which hang", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := loadBook(ctx, `\title{Hello}
+
+\section{
+	\title{Child}
+
+	\hang
+}
+`)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		var funcErr booklit.FailedFunctionError
+		Expect(errors.As(err, &funcErr)).To(BeTrue())
+		Expect(funcErr.Function).To(Equal("section"))
+
+		Expect(filepath.Join(dir, "out")).ToNot(BeAnExistingFile())
+	})
+
+	It("waits on functions when there's no deadline", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello}
+
+\cancel
+`), 0644)).To(Succeed())
+
+		var finished bool
+		_, err := build.Build(ctx, build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: filepath.Join(dir, "out"),
+			Plugins: []booklit.PluginFactory{
+				func(*booklit.Section) booklit.Plugin {
+					return cancelPlugin{cancel: cancel, finished: &finished}
+				},
+			},
+		})
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(finished).To(BeTrue())
+	})
+
+	It("stops rather than collecting the errors", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello}
+
+\wait
+
+\undefined-function
+`), 0644)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		processor := &load.Processor{MaxErrors: 10}
+		_, err := processor.LoadFileContext(ctx, filepath.Join(dir, "index.lit"), []booklit.PluginFactory{
+			func(*booklit.Section) booklit.Plugin { return plugin },
+		})
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		var undefined booklit.UndefinedFunctionError
+		Expect(errors.As(err, &undefined)).To(BeFalse())
+	})
+})
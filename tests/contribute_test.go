@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit/booklitcmd"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+// fakeGitHub serves repositories via git's smart HTTP protocol, along with
+// enough of GitHub's API to open pull requests against them.
+type fakeGitHub struct {
+	// directory containing the bare repositories, e.g. vito/book.git
+	root string
+
+	pulls []map[string]string
+
+	// Authorization headers sent to the API and with each push
+	apiAuth  []string
+	pushAuth []string
+
+	lock sync.Mutex
+}
+
+func (fake *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/v3/"):
+		fake.apiAuth = append(fake.apiAuth, r.Header.Get("Authorization"))
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v3/repos/vito/book":
+			json.NewEncoder(w).Encode(map[string]string{"default_branch": "main"})
+
+		case r.Method == "POST" && r.URL.Path == "/api/v3/repos/vito/book/pulls":
+			pull := map[string]string{}
+			err := json.NewDecoder(r.Body).Decode(&pull)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			fake.pulls = append(fake.pulls, pull)
+
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"number":   len(fake.pulls),
+				"html_url": "https://github.example.com/vito/book/pull/1",
+			})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+		}
+
+	default:
+		if strings.HasSuffix(r.URL.Path, "/git-receive-pack") {
+			fake.pushAuth = append(fake.pushAuth, r.Header.Get("Authorization"))
+		}
+
+		gitPath, err := exec.LookPath("git")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		backend := &cgi.Handler{
+			Path: gitPath,
+			Args: []string{"http-backend"},
+			Env: []string{
+				"GIT_PROJECT_ROOT=" + fake.root,
+				"GIT_HTTP_EXPORT_ALL=1",
+			},
+		}
+
+		backend.ServeHTTP(w, r)
+	}
+}
+
+var _ = Describe("Proposing edits", func() {
+	var dir string
+	var work string
+	var remote string
+	var github *fakeGitHub
+	var githubServer *httptest.Server
+	var builds int
+	var server *booklitcmd.Server
+
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+
+		output, err := cmd.CombinedOutput()
+		Expect(err).ToNot(HaveOccurred(), string(output))
+
+		return strings.TrimSpace(string(output))
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-contribute")
+		Expect(err).ToNot(HaveOccurred())
+
+		remote = filepath.Join(dir, "remote", "vito", "book.git")
+		Expect(os.MkdirAll(remote, 0755)).To(Succeed())
+		git(remote, "init", "-q", "--bare")
+		git(remote, "config", "http.receivepack", "true")
+
+		work = filepath.Join(dir, "work")
+		Expect(os.MkdirAll(filepath.Join(work, "book"), 0755)).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(work, "book", "index.lit"), []byte(`\title{Hello, world!}{index}
+
+Hi!
+
+\include-section{other.lit}
+`), 0644)).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(work, "book", "other.lit"), []byte(`\title{Other}
+
+Hello from the other side.
+
+\section{
+	\title{Nested}
+
+	Deep within.
+}
+`), 0644)).To(Succeed())
+
+		git(work, "init", "-q")
+		git(work, "config", "user.name", "Someone")
+		git(work, "config", "user.email", "someone@example.com")
+		git(work, "add", ".")
+		git(work, "commit", "-q", "-m", "initial")
+
+		github = &fakeGitHub{root: filepath.Join(dir, "remote")}
+		githubServer = httptest.NewServer(github)
+
+		builds = 0
+
+		server = &booklitcmd.Server{
+			In:         filepath.Join(work, "book", "index.lit"),
+			Processor:  &load.Processor{},
+			Engine:     render.NewHTMLRenderingEngine(),
+			FileServer: http.FileServer(http.Dir(work)),
+			Editor: &booklitcmd.Editor{
+				Build: func() error {
+					builds++
+					return nil
+				},
+
+				Contributions: &booklitcmd.Contributions{
+					Repo:  "vito/book",
+					Token: "some-token",
+					URL:   githubServer.URL,
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		githubServer.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	post := func(path string, body interface{}) (int, map[string]interface{}) {
+		payload := new(strings.Builder)
+		Expect(json.NewEncoder(payload).Encode(body)).To(Succeed())
+
+		req := httptest.NewRequest("POST", path, strings.NewReader(payload.String()))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		response := map[string]interface{}{}
+		Expect(json.NewDecoder(rec.Body).Decode(&response)).To(Succeed())
+
+		return rec.Code, response
+	}
+
+	edit := func(page string, source string) {
+		req := httptest.NewRequest("GET", "/_booklit/edit?page="+page, nil)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var loaded struct {
+			Revision string `json:"revision"`
+		}
+
+		Expect(json.NewDecoder(rec.Body).Decode(&loaded)).To(Succeed())
+
+		status, _ := post("/_booklit/edit?page="+page, map[string]string{
+			"source":   source,
+			"revision": loaded.Revision,
+		})
+		Expect(status).To(Equal(http.StatusOK))
+	}
+
+	It("pushes the edits to a new branch and opens a pull request listing the sections changed", func() {
+		edited := "\\title{Other}\n\nHello from the other side.\n\n\\section{\n\t\\title{Nested}\n\n\tDeeper within.\n}\n"
+		edit("/other.html", edited)
+
+		builds = 0
+
+		status, response := post("/_booklit/contribute", map[string]string{
+			"title":       "Go deeper",
+			"description": "It wasn't deep enough.",
+		})
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(response).To(Equal(map[string]interface{}{
+			"number":   1.0,
+			"html_url": "https://github.example.com/vito/book/pull/1",
+		}))
+
+		Expect(github.pulls).To(HaveLen(1))
+
+		pull := github.pulls[0]
+		Expect(pull["title"]).To(Equal("Go deeper"))
+		Expect(pull["base"]).To(Equal("main"))
+		Expect(pull["head"]).To(HavePrefix("booklit/edit-"))
+		Expect(pull["body"]).To(Equal("It wasn't deep enough.\n\nChanged sections:\n\n- Nested (`book/other.lit`)\n\nProposed with the editing panel of `booklit --serve`."))
+
+		Expect(github.apiAuth).To(ConsistOf("Bearer some-token", "Bearer some-token"))
+		Expect(github.pushAuth).ToNot(BeEmpty())
+		for _, auth := range github.pushAuth {
+			Expect(auth).To(Equal("Basic eC1hY2Nlc3MtdG9rZW46c29tZS10b2tlbg=="))
+		}
+
+		Expect(git(remote, "show", pull["head"]+":book/other.lit")).To(Equal(strings.TrimSpace(edited)))
+		Expect(git(remote, "log", "--format=%s", "-1", pull["head"])).To(Equal("Go deeper"))
+
+		// the checkout is left as it was
+		original, err := ioutil.ReadFile(filepath.Join(work, "book", "other.lit"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(original)).To(ContainSubstring("Deep within."))
+		Expect(git(work, "status", "--porcelain")).To(BeEmpty())
+		Expect(git(work, "rev-list", "--count", "HEAD")).To(Equal("1"))
+
+		Expect(builds).To(Equal(1))
+
+		status, response = post("/_booklit/contribute", map[string]string{
+			"title": "Again",
+		})
+		Expect(status).To(Equal(http.StatusBadRequest))
+		Expect(response["error"]).To(Equal("no changes to propose"))
+	})
+
+	It("titles the pull request after the files changed if not given a title", func() {
+		edit("/other.html", "\\title{Other}\n\nHello again.\n")
+
+		status, _ := post("/_booklit/contribute", map[string]string{})
+		Expect(status).To(Equal(http.StatusOK))
+
+		Expect(github.pulls).To(HaveLen(1))
+		Expect(github.pulls[0]["title"]).To(Equal("Update book/other.lit"))
+		Expect(github.pulls[0]["body"]).To(Equal("Changed sections:\n\n- Other (`book/other.lit`)\n\nProposed with the editing panel of `booklit --serve`."))
+	})
+
+	It("returns the error GitHub responds with", func() {
+		server.Editor.Contributions.Repo = "vito/bogus"
+
+		edit("/other.html", "\\title{Other}\n\nHello again.\n")
+
+		status, response := post("/_booklit/contribute", map[string]string{})
+		Expect(status).To(Equal(http.StatusBadGateway))
+		Expect(response["error"]).To(Equal("unexpected response: 404 Not Found: Not Found"))
+	})
+})
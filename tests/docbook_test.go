@@ -0,0 +1,65 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("DocBook", (Example).Run,
+	Entry("a book with a chapter for each top-level section", Example{
+		Input: `\title{Hello, world!}
+
+Some \bold{bold} text; see \reference{child}.
+
+\section{
+	\title{Child}
+
+	\code{{{
+	a < b
+	}}}
+
+	\aside{A note.}
+
+	\section{
+		\title{Grandchild}
+
+		Back to \reference{hello-world}{the top}.
+	}
+}
+`,
+
+		DocBook: Files{
+			"hello-world.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<book xmlns="http://docbook.org/ns/docbook" xmlns:xlink="http://www.w3.org/1999/xlink" version="5.0" xml:id="hello-world">
+  <info>
+    <title>Hello, world!</title>
+  </info>
+
+  
+  <preface>
+    <title>Hello, world!</title>
+
+    <para>Some <emphasis role="bold">bold</emphasis> text; see <link linkend="child">Child</link>.</para>
+  </preface>
+  
+
+  
+    <chapter xml:id="child">
+  <title>Child</title>
+
+  <programlisting>a &lt; b</programlisting><para><note><para>A note.</para></note></para>
+
+  
+    <section xml:id="grandchild">
+  <title>Grandchild</title>
+
+  <para>Back to <link linkend="hello-world">the top</link>.</para>
+
+  
+</section>
+  
+</chapter>
+  
+</book>`,
+		},
+	}),
+)
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+var _ = DescribeTable("DOCX", (Example).Run,
+	Entry("a section and its children", Example{
+		Input: `\title{Hello, world!}
+
+Some \bold{bold} text; see \reference{child}.
+
+\section{
+	\title{Child}
+
+	\code{{{
+	a < b
+	}}}
+}
+`,
+
+		Docx: Files{
+			"hello-world.docx": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<w:body><w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:bookmarkStart w:id="0" w:name="b_hello_world"/><w:bookmarkEnd w:id="0"/><w:r><w:rPr></w:rPr><w:t xml:space="preserve">Hello, world!</w:t></w:r></w:p><w:p><w:pPr></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">Some </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">bold</w:t></w:r><w:r><w:rPr></w:rPr><w:t xml:space="preserve"> text; see </w:t></w:r><w:hyperlink w:anchor="b_child"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve">Child</w:t></w:r></w:hyperlink><w:r><w:rPr></w:rPr><w:t xml:space="preserve">.</w:t></w:r></w:p><w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:bookmarkStart w:id="1" w:name="b_child"/><w:bookmarkEnd w:id="1"/><w:r><w:rPr></w:rPr><w:t xml:space="preserve">1 </w:t></w:r><w:r><w:rPr></w:rPr><w:t xml:space="preserve">Child</w:t></w:r></w:p><w:p><w:pPr><w:pStyle w:val="Code"/></w:pPr><w:r><w:rPr></w:rPr><w:t xml:space="preserve">a &lt; b</w:t></w:r></w:p><w:sectPr/></w:body>
+</w:document>`,
+		},
+	}),
+)
+
+// failingContent fails to be rendered by any engine.
+type failingContent struct{}
+
+func (failingContent) String() string { return "failing" }
+
+func (failingContent) IsFlow() bool { return true }
+
+func (failingContent) Visit(booklit.Visitor) error {
+	return errors.New("oh no")
+}
+
+// loadSection loads the section from the input, using baselit.
+func loadSection(input string) *booklit.Section {
+	processor := &load.Processor{
+		FS: fstest.MapFS{
+			"index.lit": {Data: []byte(input)},
+		},
+	}
+
+	section, err := processor.LoadFile("index.lit", []booklit.PluginFactory{baselit.NewPlugin})
+	Expect(err).ToNot(HaveOccurred())
+
+	return section
+}
+
+var _ = Describe("DOCX rendering", func() {
+	It("returns errors from rendering a section's title", func() {
+		section := loadSection("\\title{Hello}\n\n\\section{\\title{Child}}\n")
+		section.Children[0].Title = failingContent{}
+
+		err := render.NewDocxRenderingEngine().RenderSection(new(bytes.Buffer), section)
+		Expect(err).To(MatchError("oh no"))
+	})
+})
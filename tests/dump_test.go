@@ -0,0 +1,44 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Booklit", (Example).Run,
+	Entry("dumping the content tree", Example{
+		Input: `\title{Hello, world!}
+
+Some \italic{styled} prose, with a \link{link}{https://example.com}.
+
+\section{
+  \title{Child}
+
+  \list{one}{two}
+}
+`,
+
+		Dump: `Section "hello-world"
+  title:
+    String "Hello, world!"
+  body:
+    Paragraph
+      Sequence
+        String "Some "
+        Styled "italic"
+          String "styled"
+        String " prose, with a "
+        Link "https://example.com"
+          String "link"
+        String "."
+  Section "child"
+    title:
+      String "Child"
+    body:
+      List (unordered)
+        item:
+          String "one"
+        item:
+          String "two"
+`,
+	}),
+)
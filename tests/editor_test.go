@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit/booklitcmd"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+var _ = Describe("The editing panel", func() {
+	var dir string
+	var builds int
+	var server *booklitcmd.Server
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-editor")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.Mkdir(filepath.Join(dir, "book"), 0755)).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "book", "index.lit"), []byte(`\title{Hello, world!}{index}
+
+Hi!
+
+\include-section{other.lit}
+\include-section{../outside.lit}
+`), 0644)).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "book", "other.lit"), []byte(`\title{Other}
+
+Hello from the other side.
+`), 0644)).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "outside.lit"), []byte(`\title{Outside}
+
+Not part of the book's directory.
+`), 0644)).To(Succeed())
+
+		builds = 0
+
+		server = &booklitcmd.Server{
+			In:         filepath.Join(dir, "book", "index.lit"),
+			Processor:  &load.Processor{},
+			Engine:     render.NewHTMLRenderingEngine(),
+			FileServer: http.FileServer(http.Dir(dir)),
+			Editor: &booklitcmd.Editor{
+				Build: func() error {
+					builds++
+					return nil
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	type source struct {
+		Path     string `json:"path"`
+		Source   string `json:"source"`
+		Revision string `json:"revision"`
+		Error    string `json:"error"`
+	}
+
+	request := func(method string, page string, contentType string, body interface{}) (int, source) {
+		payload := new(strings.Builder)
+		if body != nil {
+			Expect(json.NewEncoder(payload).Encode(body)).To(Succeed())
+		}
+
+		req := httptest.NewRequest(method, "/_booklit/edit?page="+page, strings.NewReader(payload.String()))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		var response source
+		Expect(json.NewDecoder(rec.Body).Decode(&response)).To(Succeed())
+
+		return rec.Code, response
+	}
+
+	readFile := func(path string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, path))
+		Expect(err).ToNot(HaveOccurred())
+		return string(contents)
+	}
+
+	It("loads the source of the file the page is in", func() {
+		status, loaded := request("GET", "/other.html", "", nil)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(loaded.Path).To(Equal("other.lit"))
+		Expect(loaded.Source).To(Equal(readFile("book/other.lit")))
+		Expect(loaded.Revision).ToNot(BeEmpty())
+	})
+
+	It("saves the source and rebuilds", func() {
+		_, loaded := request("GET", "/other.html", "", nil)
+
+		status, saved := request("POST", "/other.html", "application/json", map[string]string{
+			"source":   "\\title{Other}\n\nEdited.\n",
+			"revision": loaded.Revision,
+		})
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(saved.Path).To(Equal("other.lit"))
+		Expect(saved.Revision).ToNot(Equal(loaded.Revision))
+
+		Expect(readFile("book/other.lit")).To(Equal("\\title{Other}\n\nEdited.\n"))
+		Expect(builds).To(Equal(1))
+
+		_, reloaded := request("GET", "/other.html", "", nil)
+		Expect(reloaded.Revision).To(Equal(saved.Revision))
+	})
+
+	It("refuses to save over changes made since the source was loaded", func() {
+		_, loaded := request("GET", "/other.html", "", nil)
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "book", "other.lit"), []byte("\\title{Other}\n\nChanged elsewhere.\n"), 0644)).To(Succeed())
+
+		status, response := request("POST", "/other.html", "application/json", map[string]string{
+			"source":   "\\title{Other}\n\nEdited.\n",
+			"revision": loaded.Revision,
+		})
+		Expect(status).To(Equal(http.StatusConflict))
+		Expect(response.Error).To(Equal("other.lit has changed since it was loaded"))
+
+		Expect(readFile("book/other.lit")).To(Equal("\\title{Other}\n\nChanged elsewhere.\n"))
+		Expect(builds).To(Equal(0))
+	})
+
+	It("only saves JSON requests, which other sites can't send", func() {
+		_, loaded := request("GET", "/other.html", "", nil)
+
+		status, response := request("POST", "/other.html", "text/plain", map[string]string{
+			"source":   "\\title{Other}\n\nEdited.\n",
+			"revision": loaded.Revision,
+		})
+		Expect(status).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(response.Error).To(Equal("expected JSON"))
+
+		Expect(readFile("book/other.lit")).To(Equal("\\title{Other}\n\nHello from the other side.\n"))
+		Expect(builds).To(Equal(0))
+	})
+
+	It("refuses to load or save files outside of the input's directory", func() {
+		status, response := request("GET", "/outside.html", "", nil)
+		Expect(status).To(Equal(http.StatusForbidden))
+		Expect(response.Error).To(HavePrefix("source may not be edited: "))
+		Expect(response.Source).To(BeEmpty())
+
+		status, response = request("POST", "/outside.html", "application/json", map[string]string{
+			"source":   "\\title{Outside}\n\nEdited.\n",
+			"revision": "",
+		})
+		Expect(status).To(Equal(http.StatusForbidden))
+		Expect(response.Error).To(HavePrefix("source may not be edited: "))
+
+		Expect(readFile("outside.lit")).To(Equal("\\title{Outside}\n\nNot part of the book's directory.\n"))
+		Expect(builds).To(Equal(0))
+	})
+
+	It("responds with 404 for unknown pages", func() {
+		status, response := request("GET", "/bogus.html", "", nil)
+		Expect(status).To(Equal(http.StatusNotFound))
+		Expect(response.Error).To(Equal("unknown page: /bogus.html"))
+	})
+})
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+var _ = DescribeTable("EPUB", (Example).Run,
+	Entry("a book with a document for each top-level section", Example{
+		Input: `\title{Hello, world!}
+
+Some \bold{bold} text; see \reference{child}.
+
+\section{
+	\title{Child}
+
+	\code{{{
+	a < b
+	}}}
+
+	\section{
+		\title{Grandchild}
+
+		Back to \reference{hello-world}{the top}.
+	}
+}
+`,
+
+		Now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+
+		EPUB: Files{
+			"mimetype": `application/epub+zip`,
+
+			"content.opf": `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">urn:booklit:hello-world</dc:identifier>
+    <dc:title>Hello, world!</dc:title>
+    <dc:language>en</dc:language>
+    <meta property="dcterms:modified">2020-01-02T03:04:05Z</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="chapter-0" href="hello-world.xhtml" media-type="application/xhtml+xml"/>
+    <item id="chapter-1" href="child.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="chapter-0"/>
+    <itemref idref="chapter-1"/>
+  </spine>
+</package>
+`,
+
+			"nav.xhtml": `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+  <head>
+    <meta charset="utf-8" />
+    <title>Hello, world!</title>
+  </head>
+  <body>
+    <nav epub:type="toc" id="toc">
+      <h1>Hello, world!</h1>
+
+      <ol>
+        <li><a href="hello-world.xhtml">Hello, world!</a></li>
+        
+          <li>
+  <a href="child.xhtml">1 Child</a>
+
+  
+  <ol>
+    
+      <li>
+  <a href="child.xhtml#grandchild">1.1 Grandchild</a>
+
+  
+</li>
+    
+  </ol>
+  
+</li>
+        
+      </ol>
+    </nav>
+  </body>
+</html>`,
+
+			"hello-world.xhtml": `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+  <head>
+    <meta charset="utf-8" />
+    <title>Hello, world!</title>
+    
+  </head>
+  <body>
+    
+<h1 class="section-header" id="hello-world">Hello, world!</h1>
+
+<p>Some <strong>bold</strong> text; see <a href="child.xhtml">Child</a>.</p>
+
+
+
+  
+
+
+  </body>
+</html>`,
+
+			"child.xhtml": `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+  <head>
+    <meta charset="utf-8" />
+    <title>Child</title>
+    
+  </head>
+  <body>
+    
+<h2 class="section-header" id="child"><span class="section-number">1 </span>Child</h2>
+
+<pre>a &lt; b</pre>
+
+
+  
+    
+<h3 class="section-header" id="grandchild"><span class="section-number">1.1 </span>Grandchild</h3>
+
+<p>Back to <a href="hello-world.xhtml">the top</a>.</p>
+
+
+  
+
+
+
+  
+
+
+  
+
+
+  </body>
+</html>`,
+		},
+	}),
+)
+
+var _ = Describe("EPUB images", func() {
+	var dir string
+	var processor *load.Processor
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-epub")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "outside"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "outside", "secret.png"), []byte("secret"), 0644)).To(Succeed())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "book"), 0755)).To(Succeed())
+		Expect(os.Symlink(filepath.Join(dir, "outside"), filepath.Join(dir, "book", "images"))).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "book", "index.lit"), []byte(`\title{Hello}
+
+\image{images/secret.png}
+`), 0644)).To(Succeed())
+
+		processor = &load.Processor{}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	write := func() error {
+		section, err := processor.LoadFile(filepath.Join(dir, "book", "index.lit"), []booklit.PluginFactory{baselit.NewPlugin})
+		Expect(err).ToNot(HaveOccurred())
+
+		engine := render.NewEPUBRenderingEngine()
+		engine.Directory = filepath.Join(dir, "book")
+
+		writer := render.Writer{
+			Engine:      engine,
+			Destination: filepath.Join(dir, "out"),
+		}
+
+		Expect(os.MkdirAll(writer.Destination, 0755)).To(Succeed())
+
+		return writer.WriteSection(section)
+	}
+
+	It("embeds images through symlinks", func() {
+		Expect(write()).To(Succeed())
+
+		book := readZip(filepath.Join(dir, "out", "hello.epub"))
+		Expect(book).To(HaveKeyWithValue("images/secret.png", "secret"))
+	})
+
+	It("refuses to follow symlinks out of the directory in safe mode", func() {
+		processor.Safe = &booklit.SafeMode{}
+
+		Expect(write()).To(MatchError(ContainSubstring("file is outside of the book in safe mode")))
+	})
+})
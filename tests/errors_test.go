@@ -1,8 +1,13 @@
 package tests
 
 import (
+	"strings"
+
 	. "github.com/onsi/ginkgo/extensions/table"
 	"github.com/onsi/gomega"
+	"github.com/vito/booklit"
+	_ "github.com/vito/booklit/tests/fixtures/dependent-plugin"
+	_ "github.com/vito/booklit/tests/fixtures/deprecated-plugin"
 	_ "github.com/vito/booklit/tests/fixtures/erroring-plugin"
 )
 
@@ -16,6 +21,247 @@ var _ = DescribeTable("Booklit", (Example).Run,
 		Err: gomega.ContainSubstring("undefined function \\banana"),
 	}),
 
+	Entry("ignoring missing plugins and functions", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{missing-dependency}
+
+Some \banana{attack} prose.
+`,
+
+		IgnoreMissingPlugins: true,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Some <code>\banana</code> attack prose.</p>
+</section>`,
+		},
+	}),
+
+	Entry("invalid error page status", Example{
+		Input: `\title{Hello, world!}
+
+\error-page{200}
+`,
+
+		Err: gomega.ContainSubstring("invalid status code: 200"),
+	}),
+
+	Entry("invalid direction", Example{
+		Input: `\title{Hello, world!}
+
+\direction{sideways}
+`,
+
+		Err: gomega.ContainSubstring("invalid direction: sideways"),
+	}),
+
+	Entry("invalid table of contents depth", Example{
+		Input: `\title{Hello, world!}
+
+\toc-depth{0}
+`,
+
+		Err: gomega.ContainSubstring("invalid depth: 0"),
+	}),
+
+	Entry("invalid column alignment", Example{
+		Input: `\title{Hello, world!}
+
+\table{
+	\align-columns{left}{sideways}
+	\row{a}{b}
+}
+`,
+
+		Err: gomega.ContainSubstring("invalid alignment: sideways"),
+	}),
+
+	Entry("header rows following body rows", Example{
+		Input: `\title{Hello, world!}
+
+\table{
+	\row{a}{b}
+	\header-row{A}{B}
+}
+`,
+
+		Err: gomega.ContainSubstring("header row follows body rows"),
+	}),
+
+	Entry("invalid data attribute", Example{
+		Input: `\title{Hello, world!}
+
+\style-section{wide}{Layout grid}
+`,
+
+		Err: gomega.ContainSubstring("invalid data attribute (expected name=value): Layout grid"),
+	}),
+
+	Entry("warnings", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{deprecated}
+
+\old-greeting
+
+\section{
+	\title{Section A}
+}
+`,
+
+		Warnings: []string{
+			"\\old-greeting is deprecated; use \\greeting instead",
+			"section 'section-a' is empty",
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Hello!</p>
+
+	<h2>1 Section A</h2>
+</section>
+`,
+		},
+	}),
+
+	Entry("terminology", Example{
+		Input: `\title{Working with Github}
+
+Push your code to Github, or e-mail it to us. Simply run \code{github push}.
+
+See github.com for more.
+
+\section{
+	\title{Setup}
+
+	Install the GitHub app, or the Github CLI.
+}
+`,
+
+		Terminology: &booklit.Terminology{
+			Terms: []string{"GitHub"},
+			Preferred: map[string]string{
+				"e-mail": "email",
+			},
+			Banned: map[string]string{
+				"simply": "condescending",
+			},
+		},
+
+		Warnings: []string{
+			"use 'GitHub' instead of 'Github'",
+			"use 'email' instead of 'e-mail'",
+			"avoid 'Simply': condescending",
+			"use 'GitHub' instead of 'Github'",
+		},
+	}),
+
+	Entry("terminology with locations", Example{
+		Input: `\title{Working with Github}
+
+Push your code to Github, or e-mail it to us. Simply run \code{github push}.
+
+See github.com for more.
+
+\section{
+	\title{Setup}
+
+	Install the GitHub app, or the Github CLI.
+}
+`,
+
+		Terminology: &booklit.Terminology{
+			Terms: []string{"GitHub"},
+			Preferred: map[string]string{
+				"e-mail": "email",
+			},
+			Banned: map[string]string{
+				"simply": "condescending",
+			},
+		},
+
+		Strict:    true,
+		MaxErrors: 10,
+
+		JSON: `{
+  "errors": [
+    {
+      "type": "terminology",
+      "message": "use 'GitHub' instead of 'Github'",
+      "file": "terminology with locations.lit",
+      "line": 1,
+      "column": 21,
+      "length": 6
+    },
+    {
+      "type": "terminology",
+      "message": "use 'email' instead of 'e-mail'",
+      "file": "terminology with locations.lit",
+      "line": 3,
+      "column": 30,
+      "length": 6
+    },
+    {
+      "type": "terminology",
+      "message": "avoid 'Simply': condescending",
+      "file": "terminology with locations.lit",
+      "line": 3,
+      "column": 47,
+      "length": 6
+    },
+    {
+      "type": "terminology",
+      "message": "use 'GitHub' instead of 'Github'",
+      "file": "terminology with locations.lit",
+      "line": 10,
+      "column": 33,
+      "length": 6
+    }
+  ],
+  "stopped": false
+}`,
+
+		Err: gomega.ContainSubstring("4 errors:"),
+	}),
+
+	Entry("strict warnings", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{deprecated}
+
+\old-greeting
+`,
+
+		Strict: true,
+
+		Err: gomega.ContainSubstring("function \\old-greeting returned an error: \\old-greeting is deprecated; use \\greeting instead"),
+	}),
+
+	Entry("plugin requiring a newer Booklit", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{dependent}
+`,
+
+		Version: "0.0.0",
+
+		Err: gomega.ContainSubstring("plugin 'dependent' requires booklit 0.0.1 or newer (running 0.0.0)"),
+	}),
+
+	Entry("plugin with a missing dependency", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{missing-dependency}
+`,
+
+		Err: gomega.ContainSubstring("plugin 'missing-dependency' requires unknown plugin 'nonexistent'"),
+	}),
+
 	Entry("erroring single-return function", Example{
 		Input: `\title{Hello, world!}
 
@@ -40,6 +286,290 @@ var _ = DescribeTable("Booklit", (Example).Run,
 		Err: gomega.ContainSubstring("function \\multi-fail returned an error: oh no"),
 	}),
 
+	Entry("collecting multiple errors", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{errer}
+
+\banana{attack}
+
+\section{
+	\title{Broken}
+
+	\single-fail{some arg}
+
+	See \reference{nonexistent}.
+}
+`,
+
+		MaxErrors: 10,
+
+		Err: gomega.Equal(strings.Join([]string{
+			"3 errors:",
+			"- undefined function \\banana",
+			"- function \\single-fail returned an error: oh no",
+			"- unknown tag 'nonexistent'",
+		}, "\n")),
+	}),
+
+	Entry("stopping after too many errors", Example{
+		Input: `\title{Hello, world!}
+
+\banana{attack}
+
+\section{
+	\title{Broken}
+
+	\apple{attack}
+
+	\cherry{attack}
+}
+`,
+
+		MaxErrors: 2,
+
+		Err: gomega.Equal(strings.Join([]string{
+			"2 errors:",
+			"- undefined function \\banana",
+			"- undefined function \\apple",
+		}, "\n")),
+	}),
+
+	Entry("grouping errors by file", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{broken.lit}
+
+See \reference{nonexistent}.
+
+\include-section{unparseable.lit}
+
+\banana{attack}
+`,
+
+		Inputs: Files{
+			"broken.lit": `\title{Broken}
+
+\apple{attack}
+`,
+			"unparseable.lit": `\title{Unparseable}
+
+\cherry{
+`,
+		},
+
+		MaxErrors: 10,
+
+		ErrorFiles: []string{
+			"broken.lit",
+			"unparseable.lit",
+			"grouping errors by file.lit",
+		},
+
+		Err: gomega.ContainSubstring("4 errors:"),
+	}),
+
+	Entry("JSON errors", Example{
+		Input: `\title{Hello, world!}
+
+See \reference{hello-wrld}.
+`,
+
+		JSON: `{
+  "errors": [
+    {
+      "type": "unknown-tag",
+      "message": "unknown tag 'hello-wrld'",
+      "file": "JSON errors.lit",
+      "line": 3,
+      "column": 5,
+      "length": 10,
+      "similar_tags": ["hello-world"]
+    }
+  ],
+  "stopped": false
+}`,
+
+		Err: gomega.ContainSubstring("unknown tag 'hello-wrld'"),
+	}),
+
+	Entry("SARIF log of errors", Example{
+		Input: `\title{Hello, world!}
+
+\banana{attack}
+
+See \reference{nonexistent}.
+`,
+
+		MaxErrors: 10,
+
+		SARIF: `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "booklit",
+          "version": "0.0.0-dev",
+          "informationUri": "https://booklit.page",
+          "rules": [
+            {"id": "undefined-function", "shortDescription": {"text": "undefined function"}},
+            {"id": "unknown-tag", "shortDescription": {"text": "unknown tag"}}
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "undefined-function",
+          "level": "error",
+          "message": {"text": "undefined function \\banana"},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "SARIF%20log%20of%20errors.lit"},
+                "region": {"startLine": 3, "startColumn": 1, "endColumn": 8}
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "unknown-tag",
+          "level": "error",
+          "message": {"text": "unknown tag 'nonexistent'"},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "SARIF%20log%20of%20errors.lit"},
+                "region": {"startLine": 5, "startColumn": 5, "endColumn": 15}
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`,
+
+		Err: gomega.ContainSubstring("2 errors:"),
+	}),
+
+	Entry("SARIF log of errors and warnings", Example{
+		Input: `\title{Hello, world!}
+
+See \reference{nonexistent}.
+
+\section{
+	\title{Empty}
+}
+`,
+
+		SARIF: `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "booklit",
+          "version": "0.0.0-dev",
+          "informationUri": "https://booklit.page",
+          "rules": [
+            {"id": "unknown-tag", "shortDescription": {"text": "unknown tag"}},
+            {"id": "empty-section", "shortDescription": {"text": "empty section"}}
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "unknown-tag",
+          "level": "error",
+          "message": {"text": "unknown tag 'nonexistent'"},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "SARIF%20log%20of%20errors%20and%20warnings.lit"},
+                "region": {"startLine": 3, "startColumn": 5, "endColumn": 15}
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "empty-section",
+          "level": "warning",
+          "message": {"text": "section 'empty' is empty"},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "SARIF%20log%20of%20errors%20and%20warnings.lit"},
+                "region": {"startLine": 6, "startColumn": 2, "endColumn": 8}
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`,
+
+		Err: gomega.ContainSubstring("unknown tag 'nonexistent'"),
+	}),
+
+	Entry("broken links", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+See \link{the guide}{guide.html}, \link{its setup}{guide.html#setup},
+and \link{the missing page}{missing.html}.
+
+\section{
+	\title{Guide}
+
+	\target{install}{Installing}
+
+	Go \link{back}{hello-world.html#somewhere}, \link{up}{#install}, or
+	\link{home}{hello-world.html}.
+
+	Mail \link{us}{mailto:hello@example.com} or grab \link{the logo}{logo.png}.
+}
+`,
+
+		CheckLinks: true,
+
+		JSON: `{
+  "errors": [
+    {
+      "type": "broken-link",
+      "message": "broken link 'guide.html#setup': no such anchor on /guide.html",
+      "file": "broken links.lit",
+      "line": 5,
+      "column": 35,
+      "length": 5
+    },
+    {
+      "type": "broken-link",
+      "message": "broken link 'missing.html': no such page",
+      "file": "broken links.lit",
+      "line": 6,
+      "column": 5,
+      "length": 5
+    },
+    {
+      "type": "broken-link",
+      "message": "broken link 'hello-world.html#somewhere': no such anchor on /hello-world.html",
+      "file": "broken links.lit",
+      "line": 13,
+      "column": 5,
+      "length": 5
+    }
+  ],
+  "stopped": false
+}`,
+
+		Err: gomega.ContainSubstring("3 errors"),
+	}),
+
 	Entry("ambiguous references", Example{
 		Input: `\title{Hello, world!}
 
@@ -61,6 +591,187 @@ See also \reference{dupe-tag}{this tag}.
 		Err: gomega.MatchRegexp(`ambiguous target for tag 'dupe-tag'`),
 	}),
 
+	Entry("colliding pages", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{Café}
+}
+
+\section{
+	\title{Caf}
+}
+`,
+
+		Err: gomega.ContainSubstring("multiple sections would be rendered to 'caf.html'"),
+	}),
+
+	Entry("colliding permalinks", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{Blog}
+
+	\permalinks{{{ {parent}/{year} }}}
+
+	\split-sections
+
+	\section{
+		\title{Launch}
+
+		\published{2024-05-01}
+	}
+
+	\section{
+		\title{Recap}
+
+		\published{2024-12-31}
+	}
+}
+`,
+
+		Err: gomega.ContainSubstring("multiple sections would be rendered to 'blog/2024'"),
+	}),
+
+	Entry("undated permalinks", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{Blog}
+
+	\permalinks{date}
+
+	\split-sections
+
+	\section{
+		\title{Launch}
+	}
+}
+`,
+
+		Err: gomega.ContainSubstring("section has no \\published date for its permalink: launch"),
+	}),
+
+	Entry("invalid permalinks", Example{
+		Input: `\title{Hello, world!}
+
+\permalinks{by-date}
+`,
+
+		Err: gomega.ContainSubstring("invalid permalinks (expected flat, nested, date, or a pattern of placeholders such as {tag}): by-date"),
+	}),
+
+	Entry("failing diagram commands", Example{
+		DiagramCommands: map[string][]string{
+			"dot": {"sh", "-c", "echo syntax error >&2; exit 1"},
+		},
+
+		Input: `\title{Hello, world!}
+
+\dot{digraph \{}
+`,
+
+		Err: gomega.ContainSubstring("dot diagram command failed: exit status 1\nsyntax error"),
+	}),
+
+	Entry("invalid math", Example{
+		Input: `\title{Hello, world!}
+
+\math{{{\frac{1}{2} + \nope}}}
+`,
+
+		Err: gomega.ContainSubstring(`invalid math: unknown command: \nope`),
+	}),
+
+	Entry("unbalanced math", Example{
+		Input: `\title{Hello, world!}
+
+\display-math{{{\sqrt{x}}}
+`,
+
+		Err: gomega.ContainSubstring("invalid math: missing }"),
+	}),
+
+	Entry("playgrounds without a backend", Example{
+		Input: `\title{Hello, world!}
+
+\playground{go}{{{
+package main
+}}}
+`,
+
+		Err: gomega.ContainSubstring("no execution backend for playground (expected --html-playground-url or a URL)"),
+	}),
+
+	Entry("unknown translations", Example{
+		Input: `\title{Hello, world!}
+
+\translate{greeting}
+`,
+
+		Locale: "ja",
+
+		Translations: booklit.Translations{
+			"ja": {
+				"farewell": "さようなら",
+			},
+		},
+
+		Err: gomega.ContainSubstring("unknown translation: greeting"),
+	}),
+
+	Entry("cyclic prerequisites", Example{
+		Input: `\title{Hello, world!}
+
+\section{
+	\title{Building}
+
+	\requires{testing}
+}
+
+\section{
+	\title{Testing}
+
+	\requires{deploying}
+}
+
+\section{
+	\title{Deploying}
+
+	\requires{building}
+}
+`,
+
+		Err: gomega.ContainSubstring("prerequisites form a cycle: building -> testing -> deploying -> building"),
+	}),
+
+	Entry("invalid issues", Example{
+		Input: `\title{Hello, world!}
+
+See \issue{booklit#12}.
+`,
+
+		Err: gomega.ContainSubstring("invalid issue (expected org/repo#123 or gitlab:group/project#123): booklit#12"),
+	}),
+
+	Entry("unsupported interchange versions", Example{
+		Import: `{"version": 2, "book": {"type": "section", "title": "Hello, world!"}}`,
+
+		Err: "unsupported interchange version: 2",
+	}),
+
+	Entry("invalid interchange content", Example{
+		Import: `{"version": 1, "book": {"type": "section", "title": "Hello, world!", "body": {"type": "marquee"}}}`,
+
+		Err: `invalid content type: "marquee"`,
+	}),
+
 	Entry("missing references", Example{
 		Input: `\title{Hello, world!}
 
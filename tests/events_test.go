@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Events", (Example).Run,
+	Entry("inline", Example{
+		Input: `\title{Hello, world!}
+
+\event{2030-06-01}{Release Party}{
+	Celebrate the \link{release}{https://example.com}.
+}
+`,
+
+		Now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+  <h1>Hello, world!</h1>
+
+<div class="event">
+  <a id="2030-06-01-release-party"></a>
+  <p class="event-header"><strong class="event-title">Release Party</strong> <span class="event-date"><time datetime="2030-06-01T00:00:00Z">June 1, 2030</time></span></p>
+  <p>Celebrate the <a href="https://example.com">release</a>.</p>
+</div>
+
+  <aside><ul>
+
+  <li><time datetime="2030-06-01T00:00:00Z">June 1, 2030</time>: <a href="hello-world.html#2030-06-01-release-party">Release Party</a></li>
+
+</ul></aside>
+</section>
+`,
+		},
+	}),
+
+	Entry("upcoming events across sections", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{News}
+
+	\event{2029-12-25}{Launch}{We launched.}
+
+	\event{2030-01-01}{Meetup}{All day today.}
+
+	\event{2030-02-01T18:30:00Z}{Office Hours}{Ask us anything.}{office-hours}
+}
+
+\section{
+	\title{Roadmap}
+
+	\event{2030-01-15}{Beta}{Coming soon.}
+}
+`,
+
+		Now: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+  <h1>Hello, world!</h1>
+
+  <aside><ul>
+
+  <li><time datetime="2030-01-01T00:00:00Z">January 1, 2030</time>: <a href="news.html#2030-01-01-meetup">Meetup</a></li>
+
+  <li><time datetime="2030-01-15T00:00:00Z">January 15, 2030</time>: <a href="roadmap.html#2030-01-15-beta">Beta</a></li>
+
+  <li><time datetime="2030-02-01T18:30:00Z">February 1, 2030 at 6:30 PM UTC</time>: <a href="news.html#office-hours">Office Hours</a></li>
+
+</ul></aside>
+</section>
+`,
+
+			"news.html": `<section>
+  <h1>1 News</h1>
+
+<div class="event">
+  <a id="2029-12-25-launch"></a>
+  <p class="event-header"><strong class="event-title">Launch</strong> <span class="event-date"><time datetime="2029-12-25T00:00:00Z">December 25, 2029</time></span></p>
+  We launched.
+</div><div class="event">
+  <a id="2030-01-01-meetup"></a>
+  <p class="event-header"><strong class="event-title">Meetup</strong> <span class="event-date"><time datetime="2030-01-01T00:00:00Z">January 1, 2030</time></span></p>
+  All day today.
+</div><div class="event">
+  <a id="office-hours"></a>
+  <p class="event-header"><strong class="event-title">Office Hours</strong> <span class="event-date"><time datetime="2030-02-01T18:30:00Z">February 1, 2030 at 6:30 PM UTC</time></span></p>
+  Ask us anything.
+</div>
+
+  <aside><ul>
+
+  <li><time datetime="2030-01-01T00:00:00Z">January 1, 2030</time>: <a href="news.html#2030-01-01-meetup">Meetup</a></li>
+
+  <li><time datetime="2030-01-15T00:00:00Z">January 15, 2030</time>: <a href="roadmap.html#2030-01-15-beta">Beta</a></li>
+
+  <li><time datetime="2030-02-01T18:30:00Z">February 1, 2030 at 6:30 PM UTC</time>: <a href="news.html#office-hours">Office Hours</a></li>
+
+</ul></aside>
+</section>
+`,
+		},
+	}),
+
+	Entry("calendar", Example{
+		Input: `\title{Hello, world!}
+
+\event{2030-02-01T18:30:00Z}{Office Hours, Again}{Ask us anything; really.}{office-hours}
+
+\event{2029-12-25}{Launch}{We launched \italic{everything} we had planned for the year, which took quite a while but was well worth it.}
+`,
+
+		Now: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+
+		BaseURL: "https://example.com/docs/",
+
+		Calendar: "BEGIN:VCALENDAR\r\n" +
+			"VERSION:2.0\r\n" +
+			"PRODID:-//Booklit//Booklit 0.0.0-dev//EN\r\n" +
+			"CALSCALE:GREGORIAN\r\n" +
+			"X-WR-CALNAME:Hello\\, world!\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"UID:2029-12-25-launch@hello-world\r\n" +
+			"DTSTAMP:20300101T120000Z\r\n" +
+			"DTSTART;VALUE=DATE:20291225\r\n" +
+			"DTEND;VALUE=DATE:20291226\r\n" +
+			"SUMMARY:Launch\r\n" +
+			"DESCRIPTION:We launched everything we had planned for the year\\, which took\r\n" +
+			"  quite a while but was well worth it.\r\n" +
+			"URL:https://example.com/docs/hello-world.html#2029-12-25-launch\r\n" +
+			"END:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"UID:office-hours@hello-world\r\n" +
+			"DTSTAMP:20300101T120000Z\r\n" +
+			"DTSTART:20300201T183000Z\r\n" +
+			"SUMMARY:Office Hours\\, Again\r\n" +
+			"DESCRIPTION:Ask us anything\\; really.\r\n" +
+			"URL:https://example.com/docs/hello-world.html#office-hours\r\n" +
+			"END:VEVENT\r\n" +
+			"END:VCALENDAR\r\n",
+	}),
+)
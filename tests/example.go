@@ -1,31 +1,260 @@
 package tests
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/vito/booklit"
 	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/interchange"
 	"github.com/vito/booklit/load"
 	"github.com/vito/booklit/render"
 )
 
 type Example struct {
-	Input       string
-	Inputs      Files
+	Input  string
+	Inputs Files
+
+	// books to load together in a workspace, by name, instead of Input
+	Books Files
+
 	Outputs     Files
 	SearchIndex string
-	Err         interface{}
+	Manifest    string
+
+	// expected _headers file, configuring caching for the rendered pages and
+	// any fingerprinted Inputs
+	Headers string
+
+	// expected URLs precached by the service worker, in order
+	Precache []string
+
+	// time which the book is built as of, e.g. for \today and which events
+	// are upcoming, and the expected calendar of every event
+	Now      time.Time
+	Calendar string
+
+	// embedder for computing search embeddings, and the expected result
+	Embedder         render.Embedder
+	SearchEmbeddings string
+
+	// expected llms.txt and per-chapter files, relative to the destination
+	LLMs Files
+
+	// expected pages rendered by the Markdown engine
+	Markdown Files
+
+	// expected pages rendered by the man page engine
+	Manpages Files
+
+	// expected manuals rendered by the Texinfo engine
+	Texinfo Files
+
+	// expected documents rendered by the LaTeX engine
+	LaTeX Files
+
+	// expected books rendered by the DocBook engine
+	DocBook Files
+
+	// expected HTML which each document rendered by the PDF engine is
+	// converted from, by the document's file name
+	PDF Files
+
+	// expected files in the book rendered by the EPUB engine, by their path
+	// within it; the book is last modified as of Now
+	EPUB Files
+
+	// expected word/document.xml of each document rendered by the DOCX
+	// engine, by the document's file name
+	Docx Files
+
+	// expected files in the assets/ directory of the Inputs which are never
+	// referred to, relative to the example's directory
+	UnusedAssets []string
+
+	// messages of commits to make, oldest first, in a git repository
+	// containing the example, e.g. for \changelog; commits are dated and
+	// authored the same every time, so their hashes are stable
+	Commits []string
+
+	// previous manifest to redirect from, and the expected target for each
+	// stubbed page
+	PreviousManifest render.Manifest
+	Redirects        Files
+
+	// inputs to change before building again with the cache from the first
+	// build, and the pages expected to be rendered again as a result
+	Changes    Files
+	Rerendered []string
+
+	// render placeholders for unknown plugins and functions
+	IgnoreMissingPlugins bool
+
+	// expected content tree of the root section, as printed by booklit.Dump
+	Dump string
+
+	// expected book exported in the JSON interchange format, with file paths
+	// relative to the example's directory, which is imported and rendered
+	// again to the same Outputs
+	Export string
+
+	// book in the JSON interchange format to load in place of Input
+	Import string
+
+	// slugifier for generating default tags
+	Slugifier *booklit.Slugifier
+
+	// locale of the root section, e.g. for formatting and sorting
+	Locale string
+
+	// how pages are named and linked to, and the path they're hosted under
+	URLStyle booklit.URLStyle
+	BasePath string
+
+	// terminology which prose is checked against
+	Terminology *booklit.Terminology
+
+	// catalogs of translated strings, and the locale they fall back to
+	Translations  booklit.Translations
+	DefaultLocale string
+
+	// looks up the issues referred to by \issue
+	IssueTracker booklit.IssueTracker
+
+	// generates variants of the images referred to by \image
+	ImageProcessor booklit.ImageProcessor
+
+	// flags enabled for conditional content
+	Flags []string
+
+	// execution backend which playgrounds run their code with
+	PlaygroundURL string
+
+	// preserve line breaks within paragraphs
+	HardWraps bool
+
+	// keep units attached to numbers
+	AttachUnits bool
+
+	// commands which diagrams are rendered with, by their language
+	DiagramCommands map[string][]string
+
+	// commands which math is rendered with
+	MathCommand        []string
+	DisplayMathCommand []string
+
+	// permalinks of the pages beneath the sections with the given tags
+	Permalinks map[string]booklit.Permalinks
+
+	// rules for rewriting the URLs of links, images, and assets
+	LinkRewrites []booklit.LinkRewrite
+
+	// expected content of each tag rendered as a fragment, with links and
+	// assets made absolute to the base URL
+	Fragments Files
+	BaseURL   string
+
+	// strip unsafe markup from raw HTML
+	SanitizeHTML bool
+
+	// number of errors to collect before stopping
+	MaxErrors int
+
+	// number of files to parse and pages to render at once
+	Jobs int
+
+	// limits for building untrusted input
+	Safe *booklit.SafeMode
+
+	// version of Booklit to build as, e.g. for the minimum versions plugins
+	// require; defaults to booklit.Version
+	Version string
+
+	// treat warnings as errors, and the expected messages of the warnings
+	// otherwise reported
+	Strict   bool
+	Warnings []string
+
+	// expected SARIF log of the errors and warnings from loading, with file
+	// paths relative to the example's directory
+	SARIF string
+
+	// expected files which the errors from loading are grouped under, in
+	// order, relative to the example's directory
+	ErrorFiles []string
+
+	// expected JSON of the errors from loading, with file paths relative to
+	// the example's directory
+	JSON string
+
+	// check the links of the loaded section, treating any broken links as
+	// errors from loading
+	CheckLinks bool
+
+	Err interface{}
 }
 
 type Files map[string]string
 
 func (example Example) Run() {
-	processor := &load.Processor{}
+	if example.Version != "" {
+		defer func(version string) { booklit.Version = version }(booklit.Version)
+		booklit.Version = example.Version
+	}
+
+	engine := render.NewHTMLRenderingEngine()
+	engine.SanitizeHTML = example.SanitizeHTML
+
+	err := engine.LoadTemplates("fixtures")
+	Expect(err).ToNot(HaveOccurred())
+
+	processor := &load.Processor{
+		Engine: engine,
+
+		IgnoreMissingPlugins: example.IgnoreMissingPlugins,
+		Slugifier:            example.Slugifier,
+		Locale:               example.Locale,
+		URLStyle:             example.URLStyle,
+		Permalinks:           example.Permalinks,
+		BasePath:             example.BasePath,
+		LinkRewrites:         example.LinkRewrites,
+		MaxErrors:            example.MaxErrors,
+		Strict:               example.Strict,
+		Jobs:                 example.Jobs,
+		Safe:                 example.Safe,
+		Flags:                example.Flags,
+		Terminology:          example.Terminology,
+		PlaygroundURL:        example.PlaygroundURL,
+		DiagramCommands:      example.DiagramCommands,
+		MathCommand:          example.MathCommand,
+		DisplayMathCommand:   example.DisplayMathCommand,
+		HardWraps:            example.HardWraps,
+		AttachUnits:          example.AttachUnits,
+		Translations:         example.Translations,
+		DefaultLocale:        example.DefaultLocale,
+		IssueTracker:         example.IssueTracker,
+		ImageProcessor:       example.ImageProcessor,
+		BuildTime:            example.Now,
+	}
+
+	if example.BaseURL != "" {
+		rewrites, err := booklit.AbsoluteLinkRewrites(example.BaseURL)
+		Expect(err).ToNot(HaveOccurred())
+
+		processor.BasePath = example.BaseURL
+		processor.LinkRewrites = append(processor.LinkRewrites, rewrites...)
+	}
 
 	pluginFactories := []booklit.PluginFactory{
 		baselit.NewPlugin,
@@ -41,6 +270,11 @@ func (example Example) Run() {
 	err = ioutil.WriteFile(sectionPath, []byte(example.Input), 0644)
 	Expect(err).ToNot(HaveOccurred())
 
+	if len(example.Books) > 0 {
+		example.runBooks(dir)
+		return
+	}
+
 	for file, contents := range example.Inputs {
 		err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755)
 		Expect(err).ToNot(HaveOccurred())
@@ -49,25 +283,101 @@ func (example Example) Run() {
 		Expect(err).ToNot(HaveOccurred())
 	}
 
-	section, err := processor.LoadFile(sectionPath, pluginFactories)
-	if example.Err != nil {
+	if len(example.Commits) > 0 {
+		commit(dir, example.Commits)
+	}
+
+	var section *booklit.Section
+	if example.Import != "" {
+		section, err = importSection(processor, example.Import)
+	} else {
+		section, err = processor.LoadFile(sectionPath, pluginFactories)
+	}
+
+	if err == nil && example.CheckLinks {
+		checker := render.LinkChecker{FileExtension: engine.FileExtension()}
+		err = checker.CheckLinks(section)
+	}
+
+	if example.SARIF != "" {
+		diagnostics := &booklit.Diagnostics{}
+		diagnostics.RecordError(err)
+		diagnostics.RecordWarnings(processor.Warnings())
+
+		log := new(bytes.Buffer)
+		Expect(diagnostics.WriteSARIF(log)).To(Succeed())
+
+		relative := strings.Replace(log.String(), "file://"+filepath.ToSlash(dir)+"/", "", -1)
+		Expect(relative).To(MatchJSON(example.SARIF))
+	}
+
+	if example.JSON != "" {
+		log := new(bytes.Buffer)
+		Expect(booklit.WriteJSON(log, err)).To(Succeed())
+
+		relative := strings.Replace(log.String(), filepath.ToSlash(dir)+"/", "", -1)
+		Expect(relative).To(MatchJSON(example.JSON))
+	}
+
+	if example.ErrorFiles != nil {
+		var errs *booklit.BuildErrors
+		Expect(errors.As(err, &errs)).To(BeTrue())
+
+		files := []string{}
+		for _, group := range errs.GroupByFile() {
+			files = append(files, strings.TrimPrefix(group.FilePath, dir+string(filepath.Separator)))
+		}
+
+		Expect(files).To(Equal(example.ErrorFiles))
+	}
+
+	if example.Err != nil && err != nil {
 		Expect(err).To(MatchError(example.Err))
 		return
 	}
 
 	Expect(err).ToNot(HaveOccurred())
 
-	engine := render.NewHTMLRenderingEngine()
+	if example.Warnings != nil {
+		warnings := []string{}
+		for _, warning := range processor.Warnings() {
+			warnings = append(warnings, warning.Error())
+		}
 
-	err = engine.LoadTemplates("fixtures")
-	Expect(err).ToNot(HaveOccurred())
+		Expect(warnings).To(Equal(example.Warnings))
+	}
 
 	writer := render.Writer{
 		Engine:      engine,
 		Destination: dir,
+
+		Jobs: example.Jobs,
+
+		Now: example.Now,
 	}
 
+	if example.Changes != nil {
+		writer.Cache = render.NewBuildCache("")
+	}
+
+	if example.Precache != nil {
+		writer.ServiceWorker = "sw.js"
+	}
+
+	if example.Calendar != "" {
+		writer.Calendar = "events.ics"
+	}
+
+	// some errors, e.g. colliding pages, are only detected upon writing
 	err = writer.WriteSection(section)
+	if example.Err != nil {
+		Expect(err).To(MatchError(example.Err))
+		return
+	}
+
+	Expect(err).ToNot(HaveOccurred())
+
+	err = writer.WriteErrorPages(section)
 	Expect(err).ToNot(HaveOccurred())
 
 	for file, contents := range example.Outputs {
@@ -76,6 +386,23 @@ func (example Example) Run() {
 		Expect(string(fileContents)).To(MatchXML(contents))
 	}
 
+	if example.Export != "" {
+		example.roundTrip(dir, section, processor, engine)
+	}
+
+	for tagName, contents := range example.Fragments {
+		tags := section.FindTag(tagName)
+		Expect(tags).ToNot(BeEmpty())
+
+		buf := new(bytes.Buffer)
+		Expect(engine.RenderFragment(buf, tags[0])).To(Succeed())
+		Expect(buf.String()).To(MatchXML(contents))
+	}
+
+	if example.Changes != nil {
+		example.rebuild(dir, sectionPath, processor, writer)
+	}
+
 	if example.SearchIndex != "" {
 		err := writer.WriteSearchIndex(section, "search_index.json")
 		Expect(err).ToNot(HaveOccurred())
@@ -85,9 +412,454 @@ func (example Example) Run() {
 		Expect(string(fileContents)).To(MatchJSON(example.SearchIndex))
 	}
 
+	if example.Embedder != nil {
+		err := writer.WriteSearchEmbeddings(section, "search_embeddings.json", example.Embedder)
+		Expect(err).ToNot(HaveOccurred())
+
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, "search_embeddings.json"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).To(MatchJSON(example.SearchEmbeddings))
+	}
+
+	if example.Manifest != "" {
+		err := writer.WriteManifest(section, "manifest.json")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).To(MatchJSON(example.Manifest))
+	}
+
+	if example.LLMs != nil {
+		err := writer.WriteLLMsBundle(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.LLMs {
+			fileContents, err := ioutil.ReadFile(filepath.Join(dir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.UnusedAssets != nil {
+		unused, err := writer.UnusedAssets(section, filepath.Join(dir, "assets"))
+		Expect(err).ToNot(HaveOccurred())
+
+		relative := []string{}
+		for _, file := range unused {
+			relative = append(relative, strings.TrimPrefix(file, dir+string(filepath.Separator)))
+		}
+
+		Expect(relative).To(Equal(example.UnusedAssets))
+	}
+
+	if example.Markdown != nil {
+		markdownDir := filepath.Join(dir, "markdown")
+
+		err := os.MkdirAll(markdownDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		markdownWriter := render.Writer{
+			Engine:      render.NewMarkdownRenderingEngine(),
+			Destination: markdownDir,
+		}
+
+		err = markdownWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.Markdown {
+			fileContents, err := ioutil.ReadFile(filepath.Join(markdownDir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.Manpages != nil {
+		manpageDir := filepath.Join(dir, "manpage")
+
+		err := os.MkdirAll(manpageDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		manpageWriter := render.Writer{
+			Engine:      render.NewManpageRenderingEngine(),
+			Destination: manpageDir,
+		}
+
+		err = manpageWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.Manpages {
+			fileContents, err := ioutil.ReadFile(filepath.Join(manpageDir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.Texinfo != nil {
+		texinfoDir := filepath.Join(dir, "texinfo")
+
+		err := os.MkdirAll(texinfoDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		texinfoWriter := render.Writer{
+			Engine:      render.NewTexinfoRenderingEngine(),
+			Destination: texinfoDir,
+		}
+
+		err = texinfoWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.Texinfo {
+			fileContents, err := ioutil.ReadFile(filepath.Join(texinfoDir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.DocBook != nil {
+		docbookDir := filepath.Join(dir, "docbook")
+
+		err := os.MkdirAll(docbookDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		docbookWriter := render.Writer{
+			Engine:      render.NewDocBookRenderingEngine(),
+			Destination: docbookDir,
+		}
+
+		err = docbookWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.DocBook {
+			fileContents, err := ioutil.ReadFile(filepath.Join(docbookDir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.PDF != nil {
+		pdfDir := filepath.Join(dir, "pdf")
+
+		err := os.MkdirAll(pdfDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		// "convert" the HTML by copying it, so that it can be checked
+		pdfEngine := render.NewPDFRenderingEngine()
+		pdfEngine.Converter.Command = []string{"cp", "{input}", "{output}"}
+
+		pdfWriter := render.Writer{
+			Engine:      pdfEngine,
+			Destination: pdfDir,
+		}
+
+		err = pdfWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.PDF {
+			fileContents, err := ioutil.ReadFile(filepath.Join(pdfDir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.LaTeX != nil {
+		latexDir := filepath.Join(dir, "latex")
+
+		err := os.MkdirAll(latexDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		latexWriter := render.Writer{
+			Engine:      render.NewLaTeXRenderingEngine(),
+			Destination: latexDir,
+		}
+
+		err = latexWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.LaTeX {
+			fileContents, err := ioutil.ReadFile(filepath.Join(latexDir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(Equal(contents))
+		}
+	}
+
+	if example.EPUB != nil {
+		epubDir := filepath.Join(dir, "epub")
+
+		err := os.MkdirAll(epubDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		epubEngine := render.NewEPUBRenderingEngine()
+		epubEngine.Modified = example.Now
+
+		epubWriter := render.Writer{
+			Engine:      epubEngine,
+			Destination: epubDir,
+		}
+
+		err = epubWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		book := readZip(filepath.Join(epubDir, section.PrimaryTag.Name+".epub"))
+		for file, contents := range example.EPUB {
+			Expect(book).To(HaveKeyWithValue(file, contents))
+		}
+	}
+
+	if example.Docx != nil {
+		docxDir := filepath.Join(dir, "docx")
+
+		err := os.MkdirAll(docxDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		docxWriter := render.Writer{
+			Engine:      render.NewDocxRenderingEngine(),
+			Destination: docxDir,
+		}
+
+		err = docxWriter.WriteSection(section)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, contents := range example.Docx {
+			Expect(readZip(filepath.Join(docxDir, file))).To(HaveKeyWithValue("word/document.xml", contents))
+		}
+	}
+
+	if example.Dump != "" {
+		buf := new(bytes.Buffer)
+
+		err := booklit.Dump(buf, section)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal(example.Dump))
+	}
+
+	if example.Headers != "" {
+		err := writer.WriteHeaders(section, "_headers")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, "_headers"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).To(Equal(example.Headers))
+	}
+
+	if example.Precache != nil {
+		err := writer.WriteServiceWorker(section, "sw.js", "precache-manifest.json")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, "precache-manifest.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var manifest render.PrecacheManifest
+		Expect(json.Unmarshal(fileContents, &manifest)).To(Succeed())
+
+		urls := []string{}
+		for _, entry := range manifest {
+			Expect(entry.Revision).ToNot(BeEmpty())
+			urls = append(urls, entry.URL)
+		}
+
+		Expect(urls).To(Equal(example.Precache))
+
+		script, err := ioutil.ReadFile(filepath.Join(dir, "sw.js"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(script)).To(ContainSubstring(`"precache-manifest.json"`))
+	}
+
+	if example.Calendar != "" {
+		err := writer.WriteCalendar(section, "events.ics")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, "events.ics"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).To(Equal(example.Calendar))
+	}
+
+	if example.PreviousManifest != nil || example.Redirects != nil {
+		err := writer.WriteRedirects(section, example.PreviousManifest)
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, target := range example.Redirects {
+			fileContents, err := ioutil.ReadFile(filepath.Join(dir, file))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fileContents)).To(ContainSubstring(`url=` + target + `"`))
+		}
+	}
+
 	Expect(stringifyEverything(section)).ToNot(BeEmpty())
 }
 
+func (example Example) runBooks(dir string) {
+	names := []string{}
+	paths := []string{}
+	for name, contents := range example.Books {
+		path := filepath.Join(dir, name+".lit")
+
+		err := ioutil.WriteFile(path, []byte(contents), 0644)
+		Expect(err).ToNot(HaveOccurred())
+
+		names = append(names, name)
+		paths = append(paths, path)
+	}
+
+	processor := &load.Processor{}
+
+	books, err := processor.LoadBooks(paths, []booklit.PluginFactory{baselit.NewPlugin})
+	if example.Err != nil {
+		Expect(err).To(MatchError(example.Err))
+		return
+	}
+
+	Expect(err).ToNot(HaveOccurred())
+
+	engine := render.NewHTMLRenderingEngine()
+
+	err = engine.LoadTemplates("fixtures")
+	Expect(err).ToNot(HaveOccurred())
+
+	for i, book := range books {
+		book.URLPrefix = "../" + names[i] + "/"
+	}
+
+	for i, book := range books {
+		dest := filepath.Join(dir, names[i])
+
+		err := os.MkdirAll(dest, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		writer := render.Writer{
+			Engine:      engine,
+			Destination: dest,
+		}
+
+		err = writer.WriteSection(book)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	for file, contents := range example.Outputs {
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, file))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).To(MatchXML(contents))
+	}
+}
+
+// rebuild applies the example's changes and writes the section again with
+// the cache from the first build, checking which pages were rendered again.
+// roundTrip exports the section, checking it against the expected
+// document, and then imports it and renders it again.
+func (example Example) roundTrip(dir string, section *booklit.Section, processor *load.Processor, engine render.RenderingEngine) {
+	doc, err := interchange.Export(section)
+	Expect(err).ToNot(HaveOccurred())
+
+	payload, err := json.Marshal(doc)
+	Expect(err).ToNot(HaveOccurred())
+
+	relative := strings.Replace(string(payload), filepath.ToSlash(dir)+"/", "", -1)
+	Expect(relative).To(MatchJSON(example.Export))
+
+	imported, err := importSection(processor, string(payload))
+	Expect(err).ToNot(HaveOccurred())
+
+	importDir := filepath.Join(dir, "imported")
+
+	err = os.MkdirAll(importDir, 0755)
+	Expect(err).ToNot(HaveOccurred())
+
+	writer := render.Writer{
+		Engine:      engine,
+		Destination: importDir,
+	}
+
+	err = writer.WriteSection(imported)
+	Expect(err).ToNot(HaveOccurred())
+
+	for file, contents := range example.Outputs {
+		fileContents, err := ioutil.ReadFile(filepath.Join(importDir, file))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fileContents)).To(MatchXML(contents))
+	}
+}
+
+// importSection loads a book from a document in the JSON interchange format.
+// readZip returns the contents of each file in the zip archive.
+func readZip(path string) Files {
+	archive, err := zip.OpenReader(path)
+	Expect(err).ToNot(HaveOccurred())
+
+	defer archive.Close()
+
+	files := Files{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := ioutil.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		reader.Close()
+
+		files[file.Name] = string(contents)
+	}
+
+	return files
+}
+
+func importSection(processor *load.Processor, payload string) (*booklit.Section, error) {
+	var doc interchange.Document
+	err := json.Unmarshal([]byte(payload), &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := interchange.Import(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return processor.LoadSection(book)
+}
+
+func (example Example) rebuild(dir string, sectionPath string, processor *load.Processor, writer render.Writer) {
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	Expect(err).ToNot(HaveOccurred())
+
+	// mark every page as stale so that re-rendered ones can be identified
+	for _, page := range pages {
+		err := ioutil.WriteFile(page, []byte("stale"), 0644)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	// ensure the changes are considered newer than the last parse
+	later := time.Now().Add(time.Minute)
+
+	for file, contents := range example.Changes {
+		path := filepath.Join(dir, file)
+
+		err := ioutil.WriteFile(path, []byte(contents), 0644)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = os.Chtimes(path, later, later)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	section, err := processor.LoadFile(sectionPath, []booklit.PluginFactory{baselit.NewPlugin})
+	Expect(err).ToNot(HaveOccurred())
+
+	err = writer.WriteSection(section)
+	Expect(err).ToNot(HaveOccurred())
+
+	rerendered := []string{}
+	for _, page := range pages {
+		contents, err := ioutil.ReadFile(page)
+		Expect(err).ToNot(HaveOccurred())
+
+		if string(contents) != "stale" {
+			rerendered = append(rerendered, filepath.Base(page))
+		}
+	}
+
+	Expect(rerendered).To(Equal(example.Rerendered))
+}
+
 // NB: this is really just to cut down on "missing" non-critical test
 // coverage. this should recursively stringify all the content.
 func stringifyEverything(section *booklit.Section) string {
@@ -99,3 +871,27 @@ func stringifyEverything(section *booklit.Section) string {
 
 	return str
 }
+
+func commit(dir string, messages []string) {
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Booklit",
+			"GIT_AUTHOR_EMAIL=booklit@example.com",
+			"GIT_AUTHOR_DATE=2020-01-02T03:04:05Z",
+			"GIT_COMMITTER_NAME=Booklit",
+			"GIT_COMMITTER_EMAIL=booklit@example.com",
+			"GIT_COMMITTER_DATE=2020-01-02T03:04:05Z",
+		)
+
+		out, err := cmd.CombinedOutput()
+		Expect(err).ToNot(HaveOccurred(), string(out))
+	}
+
+	git("init", "-q")
+
+	for _, message := range messages {
+		git("commit", "-q", "--allow-empty", "-m", message)
+	}
+}
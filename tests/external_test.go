@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/build"
+	"github.com/vito/booklit/external"
+)
+
+var _ = Describe("External Plugins", func() {
+	var process *external.Process
+
+	BeforeEach(func() {
+		var err error
+		process, err = external.Start("external", []string{externalPluginPath})
+		Expect(err).ToNot(HaveOccurred())
+
+		booklit.RegisterPlugin("external", process.NewPlugin)
+	})
+
+	AfterEach(func() {
+		Expect(process.Close()).To(Succeed())
+	})
+
+	DescribeTable("invoking functions", (Example).Run,
+		Entry("with arguments", Example{
+			Input: `\title{Hello}
+
+\use-plugin{external}
+
+\shout{hello}{world}
+`,
+
+			Outputs: Files{
+				"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>HELLO WORLD!</p>
+</section>
+`,
+			},
+		}),
+
+		Entry("returning styled content", Example{
+			Input: `\title{Hello}
+
+\use-plugin{external}
+
+Say \emphasize{hello}.
+`,
+
+			Outputs: Files{
+				"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>Say <strong>hello</strong>.</p>
+</section>
+`,
+			},
+		}),
+
+		Entry("given the section", Example{
+			Input: `\title{Hello}
+
+\use-plugin{external}
+
+This is \section-title.
+`,
+
+			Outputs: Files{
+				"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>This is Hello.</p>
+</section>
+`,
+			},
+		}),
+
+		Entry("setting partials", Example{
+			Input: `\title{Set Partial Read Template}
+
+\use-plugin{external}
+
+I want to be some body.
+
+\set-the-partial
+
+Some more body.
+`,
+
+			Outputs: Files{
+				"set-partial-read-template.html": `<div>
+	Here's a partial:
+
+	<p>I'm a partial!</p>
+</div>
+
+<div>
+	Here's the partial again:
+
+	<p>I'm a partial!</p>
+</div>
+
+<p>I want to be some body.</p>
+
+<p>Some more body.</p>
+`,
+			},
+		}),
+
+		Entry("returning an error", Example{
+			Input: `\title{Hello}
+
+\use-plugin{external}
+
+\fail
+`,
+
+			Err: ContainSubstring("function \\fail returned an error: oh no"),
+		}),
+	)
+
+	Context("when the build's context is done", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "booklit-external")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		loadBook := func(ctx context.Context, source string) error {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(source), 0644)).To(Succeed())
+
+			_, err := build.Build(ctx, build.Config{
+				In:  filepath.Join(dir, "index.lit"),
+				Out: filepath.Join(dir, "out"),
+			})
+
+			return err
+		}
+
+		It("kills the process and starts it again for the next build", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := loadBook(ctx, `\title{Hello}
+
+\use-plugin{external}
+
+\hang
+`)
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+			err = loadBook(context.Background(), `\title{Hello}
+
+\use-plugin{external}
+
+\shout{hello}
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(dir, "out", "hello.html"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("HELLO!"))
+		})
+
+		It("can be closed while a function hangs", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			built := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+
+				built <- loadBook(ctx, `\title{Hello}
+
+\use-plugin{external}
+
+\hang
+`)
+			}()
+
+			Consistently(built, 100*time.Millisecond).ShouldNot(Receive())
+
+			closed := make(chan error, 1)
+			go func() {
+				closed <- process.Close()
+			}()
+
+			Eventually(built).Should(Receive(HaveOccurred()))
+			Eventually(closed).Should(Receive())
+		})
+	})
+})
@@ -22,3 +22,25 @@ func (plugin Plugin) ArbitraryStyle(content booklit.Content) booklit.Content {
 		Content: content,
 	}
 }
+
+func (plugin Plugin) BrokenStyle(content booklit.Content) booklit.Content {
+	return booklit.Styled{
+		Style:   "broken",
+		Content: content,
+	}
+}
+
+func (plugin Plugin) Lede(content booklit.Content) booklit.Content {
+	return booklit.Styled{
+		Style:   "lede",
+		Content: content,
+	}
+}
+
+func (plugin Plugin) Snippet(content booklit.Content) booklit.Content {
+	return booklit.Styled{
+		Style:   "snippet",
+		Block:   true,
+		Content: content,
+	}
+}
@@ -0,0 +1,29 @@
+package plugin
+
+import (
+	"github.com/vito/booklit"
+	_ "github.com/vito/booklit/tests/fixtures/stringer-plugin"
+)
+
+func init() {
+	booklit.RegisterPlugin("dependent", NewPlugin)
+	booklit.RequirePlugin("dependent", booklit.PluginRequirements{
+		Plugins: []string{"stringer"},
+		Version: "0.0.1",
+	})
+
+	booklit.RegisterPlugin("missing-dependency", NewPlugin)
+	booklit.RequirePlugin("missing-dependency", booklit.PluginRequirements{
+		Plugins: []string{"nonexistent"},
+	})
+}
+
+func NewPlugin(section *booklit.Section) booklit.Plugin {
+	return Plugin{}
+}
+
+type Plugin struct{}
+
+func (plugin Plugin) Shout(arg string) booklit.Content {
+	return booklit.String(arg + "!")
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"github.com/vito/booklit"
+)
+
+func init() {
+	booklit.RegisterPlugin("deprecated", NewPlugin)
+}
+
+func NewPlugin(section *booklit.Section) booklit.Plugin {
+	return Plugin{
+		section: section,
+	}
+}
+
+type Plugin struct {
+	section *booklit.Section
+}
+
+func (plugin Plugin) OldGreeting() (booklit.Content, error) {
+	err := plugin.section.Warn(booklit.DeprecatedFunctionWarning{
+		Function:    "old-greeting",
+		Replacement: "greeting",
+		ErrorLocation: booklit.ErrorLocation{
+			FilePath:     plugin.section.FilePath(),
+			NodeLocation: plugin.section.InvokeLocation,
+			Length:       len("\\old-greeting"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.Greeting(), nil
+}
+
+func (plugin Plugin) Greeting() booklit.Content {
+	return booklit.String("Hello!")
+}
@@ -0,0 +1,29 @@
+package plugin
+
+import "github.com/vito/booklit"
+
+func init() {
+	booklit.RegisterPlugin("engine-aware", NewPlugin)
+}
+
+func NewPlugin(section *booklit.Section) booklit.Plugin {
+	return Plugin{
+		section: section,
+	}
+}
+
+type Plugin struct {
+	section *booklit.Section
+}
+
+func (plugin Plugin) Diagram(style string) booklit.Content {
+	engine := plugin.section.RenderingEngine()
+	if engine == nil || engine.EngineName() != "html" || !engine.HasTemplate(style) {
+		return booklit.String("[ascii diagram]")
+	}
+
+	return booklit.Styled{
+		Style:   booklit.Style(style),
+		Content: booklit.String("svg diagram"),
+	}
+}
@@ -0,0 +1,96 @@
+// Command external-plugin is a plugin speaking the external plugin protocol,
+// for testing.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+type request struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type invoke struct {
+	Function  string   `json:"function"`
+	Arguments []string `json:"arguments"`
+	Section   struct {
+		Title string `json:"title"`
+	} `json:"section"`
+}
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+
+	for scanner.Scan() {
+		var req request
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err != nil {
+			panic(err)
+		}
+
+		res := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+		}
+
+		switch req.Method {
+		case "initialize":
+			res["result"] = map[string]interface{}{
+				"functions": []string{"shout", "emphasize", "set-the-partial", "section-title", "fail", "hang"},
+			}
+		case "invoke":
+			var params invoke
+			err := json.Unmarshal(req.Params, &params)
+			if err != nil {
+				panic(err)
+			}
+
+			switch params.Function {
+			case "shout":
+				res["result"] = map[string]interface{}{
+					"content": strings.ToUpper(strings.Join(params.Arguments, " ")) + "!",
+				}
+			case "emphasize":
+				res["result"] = map[string]interface{}{
+					"content": map[string]interface{}{
+						"type":    "styled",
+						"style":   "bold",
+						"content": params.Arguments[0],
+					},
+				}
+			case "set-the-partial":
+				res["result"] = map[string]interface{}{
+					"partials": map[string]interface{}{
+						"FooBar": map[string]interface{}{
+							"type":  "paragraph",
+							"lines": []interface{}{"I'm a partial!"},
+						},
+					},
+				}
+			case "section-title":
+				res["result"] = map[string]interface{}{
+					"content": params.Section.Title,
+				}
+			case "fail":
+				res["error"] = map[string]interface{}{
+					"code":    1,
+					"message": "oh no",
+				}
+			case "hang":
+				time.Sleep(time.Hour)
+			}
+		}
+
+		err = enc.Encode(res)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
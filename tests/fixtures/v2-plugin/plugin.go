@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/ast"
+	"github.com/vito/booklit/plugin/v2"
+)
+
+func init() {
+	plugin.Register(plugin.Manifest{
+		Name: "v2",
+		Safe: true,
+	}, NewPlugin)
+
+	plugin.Register(plugin.Manifest{
+		Name:     "v2-wrapped",
+		Requires: []string{"v2"},
+	}, plugin.V1(NewV1Plugin))
+
+	plugin.Register(plugin.Manifest{
+		Name:    "v2-newer",
+		Booklit: "0.10.0",
+	}, NewPlugin)
+
+	plugin.Register(plugin.Manifest{
+		Name: "v2-future",
+		API:  "2.99",
+	}, NewPlugin)
+
+	plugin.Register(plugin.Manifest{
+		Name: "v3",
+		API:  "3.0",
+	}, NewPlugin)
+}
+
+func NewPlugin(section *booklit.Section) plugin.Plugin {
+	return Plugin{}
+}
+
+type Plugin struct{}
+
+func (Plugin) Functions() map[string]plugin.Function {
+	return map[string]plugin.Function{
+		"shout": func(ctx context.Context, call plugin.Call) (booklit.Content, error) {
+			words := []string{}
+			for _, arg := range call.Args {
+				words = append(words, strings.ToUpper(arg.String()))
+			}
+
+			return booklit.String(strings.Join(words, " ") + "!"), nil
+		},
+
+		"fail": func(ctx context.Context, call plugin.Call) (booklit.Content, error) {
+			return nil, errors.New("oh no")
+		},
+	}
+}
+
+func NewV1Plugin(section *booklit.Section) booklit.Plugin {
+	return V1Plugin{}
+}
+
+type V1Plugin struct{}
+
+func (V1Plugin) Whisper(ctx context.Context, words ...string) booklit.Content {
+	return booklit.String(strings.ToLower(strings.Join(words, " ")) + "...")
+}
+
+func (V1Plugin) Quote(content booklit.Content) (booklit.Content, error) {
+	return booklit.Sequence{booklit.String("“"), content, booklit.String("”")}, nil
+}
+
+func (V1Plugin) Count(node ast.Node) booklit.Content {
+	return booklit.String("unevaluated")
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Fragments", (Example).Run,
+	Entry("sections with absolute links", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{Installing}
+
+	Download \link{the binary}{releases/latest.html}, then see
+	\reference{using}.
+
+	\image{diagram.png}{How it works}
+
+	Email \link{us}{mailto:hello@example.com} or jump to
+	\link{the end}{#the-end}.
+}
+
+\section{
+	\title{Using}
+
+	Run it.
+}
+`,
+
+		BaseURL: "https://example.com/docs/",
+
+		Fragments: Files{
+			"installing": `<h1>1 Installing</h1>
+
+<p>Download <a href="https://example.com/docs/releases/latest.html">the binary</a>, then see <a href="https://example.com/docs/using.html">Using</a>.</p>
+
+<p><img src="https://example.com/docs/diagram.png" alt="How it works" /></p>
+
+<p>Email <a href="mailto:hello@example.com">us</a> or jump to <a href="#the-end">the end</a>.</p>
+`,
+		},
+	}),
+
+	Entry("targets", Example{
+		Input: `\title{Hello, world!}
+
+Install it like so: \target{install-steps}{Installation Steps}{run \code{go install}}
+`,
+
+		BaseURL: "https://example.com/docs/",
+
+		Fragments: Files{
+			"install-steps": `run <code>go install</code>`,
+		},
+	}),
+)
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Freezing sections", func() {
+	var dir string
+
+	const book = `\title{Docs}
+
+\section{
+	\title{Version 1}{v1}
+
+	The old way.
+
+	\include-section{v1-details.lit}
+}
+
+\section{
+	\title{Version 2}{v2}
+
+	The new way.
+}
+`
+
+	writeFile := func(name string, content string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	build := func(args ...string) *gexec.Session {
+		cmd := exec.Command(booklitPath, append([]string{
+			"-i", "index.lit",
+			"-o", "out",
+			"--freeze-manifest", "booklit.freeze.json",
+		}, args...)...)
+		cmd.Dir = dir
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(session, "10s").Should(gexec.Exit())
+
+		return session
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-freeze")
+		Expect(err).ToNot(HaveOccurred())
+
+		writeFile("index.lit", book)
+		writeFile("v1-details.lit", "\\title{Details}\n\nSome details.\n")
+
+		Expect(build("--freeze", "v1").ExitCode()).To(Equal(0))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("records the files of the frozen section", func() {
+		manifest, err := ioutil.ReadFile(filepath.Join(dir, "booklit.freeze.json"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(manifest)).To(ContainSubstring(`"index.lit"`))
+		Expect(string(manifest)).To(ContainSubstring(`"v1-details.lit"`))
+	})
+
+	It("builds while the frozen section matches", func() {
+		Expect(build().ExitCode()).To(Equal(0))
+	})
+
+	It("fails once the frozen section changes", func() {
+		writeFile("index.lit", strings.Replace(book, "The old way.", "The old, edited way.", 1))
+
+		session := build()
+		Expect(session.ExitCode()).ToNot(Equal(0))
+		Expect(session.Err).To(gbytes.Say(`frozen section 'v1' has changed: index.lit`))
+	})
+
+	It("fails once a file included by the frozen section changes", func() {
+		writeFile("v1-details.lit", "\\title{Details}\n\nSome edited details.\n")
+
+		session := build()
+		Expect(session.ExitCode()).ToNot(Equal(0))
+		Expect(session.Err).To(gbytes.Say(`frozen section 'v1' has changed: v1-details.lit`))
+	})
+
+	It("builds after editing the sections around it in the same file", func() {
+		edited := strings.Replace(book, "The new way.", "The new, edited way.", 1)
+		edited = strings.Replace(edited, `\title{Docs}`, "\\title{Docs}\n\nAn introduction,\nover a few lines.", 1)
+		writeFile("index.lit", edited)
+
+		Expect(build().ExitCode()).To(Equal(0))
+	})
+})
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/build"
+	"github.com/vito/booklit/render"
+)
+
+var _ = Describe("Hooks", func() {
+	var dir string
+	var out string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-hooks")
+		Expect(err).ToNot(HaveOccurred())
+
+		out = filepath.Join(dir, "out")
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.lit"), []byte(`\title{Hello, world!}
+
+Hi!
+
+\section{
+	\title{Child}
+
+	\split-sections
+
+	\section{
+		\title{Grandchild}
+
+		Hello!
+	}
+}
+`), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("runs the command on each matching page", func() {
+		log := filepath.Join(dir, "log")
+
+		_, err := build.Build(context.Background(), build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: out,
+			Hooks: []render.Hook{
+				{Extension: "html", Command: []string{"sh", "-c", "echo $0 >> " + log, "{file}"}},
+				{Extension: "txt", Command: []string{"false"}},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		logged, err := ioutil.ReadFile(log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Fields(string(logged))).To(ConsistOf(
+			filepath.Join(out, "hello-world.html"),
+			filepath.Join(out, "grandchild.html"),
+		))
+	})
+
+	It("appends the page to the command if it has no placeholder", func() {
+		_, err := build.Build(context.Background(), build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: out,
+			Hooks: []render.Hook{
+				{Extension: "*", Command: []string{"test", "-f"}},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("fails at the section whose page the command failed on", func() {
+		_, err := build.Build(context.Background(), build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: out,
+			Hooks: []render.Hook{
+				{Extension: "html", Command: []string{"sh", "-c", "echo invalid markup; exit 1"}},
+			},
+		})
+
+		var hookErr booklit.FailedHookError
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(hookErr.Output).To(Equal("invalid markup\n"))
+		Expect(hookErr.FilePath).To(Equal(filepath.Join(dir, "index.lit")))
+		Expect(hookErr.NodeLocation.Line).To(Equal(1))
+	})
+
+	It("locates failures of commands on the whole output at the page they mention", func() {
+		result, err := build.Build(context.Background(), build.Config{
+			In:  filepath.Join(dir, "index.lit"),
+			Out: out,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		writer := render.Writer{
+			Engine:      render.NewHTMLRenderingEngine(),
+			Destination: out,
+		}
+
+		page := filepath.Join(out, "grandchild.html")
+		Expect(page).To(BeARegularFile())
+
+		err = render.RunHook(
+			context.Background(),
+			[]string{"sh", "-c", "echo broken link in " + page + "; exit 1"},
+			"{out}",
+			out,
+			writer.Pages(result.Book),
+		)
+
+		var hookErr booklit.FailedHookError
+		Expect(errors.As(err, &hookErr)).To(BeTrue())
+		Expect(hookErr.FilePath).To(Equal(filepath.Join(dir, "index.lit")))
+		Expect(hookErr.NodeLocation.Line).To(Equal(11))
+	})
+
+	It("requires a destination", func() {
+		_, err := build.Build(context.Background(), build.Config{
+			In:          filepath.Join(dir, "index.lit"),
+			Destination: &render.MemoryOutput{},
+			Hooks: []render.Hook{
+				{Extension: "html", Command: []string{"true"}},
+			},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
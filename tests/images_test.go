@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"context"
+	"image"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/images"
+)
+
+var _ = Describe("Images", func() {
+	resized := &images.Processor{
+		Widths: []int{80, 40, 240},
+	}
+
+	converted := &images.Processor{
+		Widths:      []int{40},
+		Sizes:       "(max-width: 40em) 100vw, 50vw",
+		WebPCommand: []string{"cat"},
+	}
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "booklit-images")
+		Expect(err).ToNot(HaveOccurred())
+
+		photo, err := ioutil.ReadFile(filepath.Join("fixtures", "images", "photo.png"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.Mkdir(filepath.Join(dir, "images"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "images", "photo.png"), photo, 0644)).To(Succeed())
+
+		resized.Dir = dir
+		converted.Dir = dir
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	DescribeTable("processing images", (Example).Run,
+		Entry("resized to each width smaller than them", Example{
+			Input: `\title{Hello, world!}
+
+Here's a \image{images/photo.png}{A photo}.
+`,
+
+			ImageProcessor: resized,
+
+			Outputs: Files{
+				"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Here's a <img src="images/photo.png" alt="A photo" srcset="images/photo-40w.89d0103f21ff.png 40w, images/photo-80w.89d0103f21ff.png 80w, images/photo.png 120w" width="120" height="60" />.</p>
+</section>`,
+			},
+		}),
+
+		Entry("converted to WebP", Example{
+			Input: `\title{Hello, world!}
+
+Here's a \image{images/photo.png}{A photo}.
+`,
+
+			ImageProcessor: converted,
+
+			Outputs: Files{
+				"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Here's a <picture><source type="image/webp" srcset="images/photo-40w.89d0103f21ff.webp 40w, images/photo-120w.89d0103f21ff.webp 120w" sizes="(max-width: 40em) 100vw, 50vw" /><img src="images/photo.png" alt="A photo" srcset="images/photo-40w.89d0103f21ff.png 40w, images/photo.png 120w" sizes="(max-width: 40em) 100vw, 50vw" width="120" height="60" /></picture>.</p>
+</section>`,
+			},
+		}),
+
+		Entry("with a base URL", Example{
+			Input: `\title{Hello, world!}
+
+Here's a \image{images/photo.png}{A photo}.
+`,
+
+			ImageProcessor: resized,
+
+			BaseURL: "https://example.com/docs/",
+
+			Outputs: Files{
+				"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Here's a <img src="https://example.com/docs/images/photo.png" alt="A photo" srcset="https://example.com/docs/images/photo-40w.89d0103f21ff.png 40w, https://example.com/docs/images/photo-80w.89d0103f21ff.png 80w, https://example.com/docs/images/photo.png 120w" width="120" height="60" />.</p>
+</section>`,
+			},
+		}),
+
+		Entry("which can't be processed", Example{
+			Input: `\title{Hello, world!}
+
+Here's \image{https://example.com/photo.png}, \image{images/missing.png}, and \image{images/photo.gif}.
+`,
+
+			ImageProcessor: resized,
+
+			Outputs: Files{
+				"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Here's <img src="https://example.com/photo.png" alt="" />, <img src="images/missing.png" alt="" />, and <img src="images/photo.gif" alt="" />.</p>
+</section>`,
+			},
+		}),
+	)
+
+	It("writes the resized images next to them", func() {
+		_, err := resized.ProcessImage(&booklit.Section{}, booklit.Image{Path: "images/photo.png"})
+		Expect(err).ToNot(HaveOccurred())
+
+		for file, size := range map[string]image.Point{
+			"photo-40w.89d0103f21ff.png": {40, 20},
+			"photo-80w.89d0103f21ff.png": {80, 40},
+		} {
+			f, err := os.Open(filepath.Join(dir, "images", file))
+			Expect(err).ToNot(HaveOccurred())
+
+			config, _, err := image.DecodeConfig(f)
+			Expect(f.Close()).To(Succeed())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(image.Pt(config.Width, config.Height)).To(Equal(size))
+		}
+
+		entries, err := ioutil.ReadDir(filepath.Join(dir, "images"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+	})
+
+	It("reuses the resized images until they change", func() {
+		_, err := resized.ProcessImage(&booklit.Section{}, booklit.Image{Path: "images/photo.png"})
+		Expect(err).ToNot(HaveOccurred())
+
+		variant := filepath.Join(dir, "images", "photo-40w.89d0103f21ff.png")
+		Expect(ioutil.WriteFile(variant, []byte("cached"), 0644)).To(Succeed())
+
+		_, err = resized.ProcessImage(&booklit.Section{}, booklit.Image{Path: "images/photo.png"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.ReadFile(variant)).To(Equal([]byte("cached")))
+	})
+
+	It("stops converting once the section's context is done", func() {
+		slow := &images.Processor{
+			Dir:         dir,
+			Widths:      []int{40},
+			WebPCommand: []string{"sleep", "10"},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		section := &booklit.Section{}
+		section.SetContext(ctx)
+
+		started := time.Now()
+
+		_, err := slow.ProcessImage(section, booklit.Image{Path: "images/photo.png"})
+		Expect(err).To(MatchError(ContainSubstring("webp command failed")))
+		Expect(time.Since(started)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	Context("with images outside of the directory", func() {
+		var out *images.Processor
+
+		BeforeEach(func() {
+			Expect(os.Mkdir(filepath.Join(dir, "out"), 0755)).To(Succeed())
+
+			out = &images.Processor{
+				Dir:    filepath.Join(dir, "out"),
+				Widths: []int{40},
+			}
+		})
+
+		It("leaves them as-is", func() {
+			section := &booklit.Section{
+				Safe: &booklit.SafeMode{},
+			}
+
+			img, err := out.ProcessImage(section, booklit.Image{Path: "../images/photo.png"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).To(Equal(booklit.Image{Path: "../images/photo.png"}))
+
+			entries, err := ioutil.ReadDir(filepath.Join(dir, "images"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("refuses to follow symlinks out of it in safe mode", func() {
+			Expect(os.Symlink(filepath.Join(dir, "images"), filepath.Join(dir, "out", "images"))).To(Succeed())
+
+			section := &booklit.Section{
+				Safe: &booklit.SafeMode{},
+			}
+
+			_, err := out.ProcessImage(section, booklit.Image{Path: "images/photo.png"})
+			Expect(err).To(MatchError(ContainSubstring("file is outside of the book in safe mode")))
+
+			entries, err := ioutil.ReadDir(filepath.Join(dir, "images"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("refuses to read images which are too large in safe mode", func() {
+			photo, err := ioutil.ReadFile(filepath.Join(dir, "images", "photo.png"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "out", "photo.png"), photo, 0644)).To(Succeed())
+
+			section := &booklit.Section{
+				Safe: &booklit.SafeMode{MaxFileSize: 16},
+			}
+
+			_, err = out.ProcessImage(section, booklit.Image{Path: "photo.png"})
+			Expect(err).To(MatchError(ContainSubstring("file exceeds 16 bytes in safe mode")))
+		})
+	})
+})
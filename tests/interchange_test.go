@@ -0,0 +1,177 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Booklit", (Example).Run,
+	Entry("exporting JSON", Example{
+		Input: `\title{Hello, world!}
+
+Say \italic{hello} to \reference{child}.\footnote{Or goodbye.}
+
+\section{
+  \title{Child}
+
+  \target{greeting}{A Greeting}Hi.
+}
+`,
+
+		Export: `{
+	"version": 1,
+	"book": {
+		"type": "section",
+		"path": "exporting JSON.lit",
+		"title": {"type": "string", "value": "Hello, world!"},
+		"tags": [
+			{"name": "hello-world", "title": {"type": "string", "value": "Hello, world!"}}
+		],
+		"body": {
+			"type": "paragraph",
+			"lines": [
+				{
+					"type": "sequence",
+					"contents": [
+						{"type": "string", "value": "Say "},
+						{"type": "styled", "style": "italic", "content": {"type": "string", "value": "hello"}},
+						{"type": "string", "value": " to "},
+						{"type": "reference", "tag": "child"},
+						{"type": "string", "value": "."},
+						{"type": "footnote", "content": {"type": "string", "value": "Or goodbye."}}
+					]
+				}
+			]
+		},
+		"children": [
+			{
+				"type": "section",
+				"title": {"type": "string", "value": "Child"},
+				"tags": [
+					{"name": "child", "title": {"type": "string", "value": "Child"}},
+					{"name": "greeting", "title": {"type": "string", "value": "A Greeting"}, "anchor": "greeting"}
+				],
+				"body": {
+					"type": "paragraph",
+					"lines": [
+						{
+							"type": "sequence",
+							"contents": [
+								{"type": "target", "tag": "greeting", "title": {"type": "string", "value": "A Greeting"}},
+								{"type": "string", "value": "Hi."}
+							]
+						}
+					]
+				}
+			}
+		]
+	}
+}`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Say <em>hello</em> to <a href="hello-world.html#child">Child</a>.<sup class="footnote-ref"><a id="hello-world-footnote-ref-1" href="hello-world.html#hello-world-footnote-1" role="doc-noteref">1</a></sup></p>
+
+	<h2>1 Child</h2>
+
+	<p><a id="greeting"></a>Hi.</p>
+
+	<section class="endnotes" role="doc-endnotes">
+		<ol>
+			<li id="hello-world-footnote-1">Or goodbye. <a class="footnote-backref" href="hello-world.html#hello-world-footnote-ref-1" role="doc-backlink">↩</a></li>
+		</ol>
+	</section>
+</section>`,
+		},
+	}),
+
+	Entry("importing JSON", Example{
+		Import: `{
+	"version": 1,
+	"book": {
+		"type": "section",
+		"title": "Hello, world!",
+		"body": [
+			{"type": "paragraph", "lines": [["Read ", {"type": "reference", "tag": "the-details"}, "."]]},
+			{"type": "table-of-contents"}
+		],
+		"children": [
+			{
+				"type": "section",
+				"title": ["The ", {"type": "styled", "style": "italic", "content": "Details"}],
+				"tags": ["the-details", "details"],
+				"body": {"type": "list", "ordered": true, "items": ["one", "two"]}
+			}
+		]
+	}
+}`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Read <a href="hello-world.html#the-details">The <em>Details</em></a>.</p>
+
+	<ul>
+		<li>
+			<a href="hello-world.html#the-details">The <em>Details</em></a>
+		</li>
+	</ul>
+
+	<h2>1 The <em>Details</em></h2>
+
+	<ol>
+		<li>one</li>
+		<li>two</li>
+	</ol>
+</section>`,
+		},
+	}),
+
+	Entry("importing figures", Example{
+		Import: `{
+	"version": 1,
+	"book": {
+		"type": "section",
+		"title": "Hello, world!",
+		"body": [
+			{"type": "figure", "content": "A dog.", "caption": "Dog"},
+			{"type": "list-of-figures"}
+		],
+		"children": [
+			{
+				"type": "section",
+				"title": "Cats",
+				"body": {"type": "figure", "content": "A cat.", "caption": "Cat", "tag": "cat-pic"}
+			}
+		]
+	}
+}`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<figure id="figure-1">
+		A dog.
+		<figcaption><span class="figure-number">Figure 1:</span> Dog</figcaption>
+	</figure>
+
+	<nav class="list-of-figures">
+		<ul>
+			<li><a href="hello-world.html#figure-1">Figure 1</a>: Dog</li>
+			<li><a href="hello-world.html#cat-pic">Figure 2</a>: Cat</li>
+		</ul>
+	</nav>
+
+	<h2>1 Cats</h2>
+
+	<figure id="cat-pic">
+		A cat.
+		<figcaption><span class="figure-number">Figure 2:</span> Cat</figcaption>
+	</figure>
+</section>`,
+		},
+	}),
+)
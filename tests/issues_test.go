@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/issues"
+)
+
+var _ = Describe("Issue tracker", func() {
+	var server *httptest.Server
+	var release chan struct{}
+
+	BeforeEach(func() {
+		release = make(chan struct{})
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/v3/repos/vito/booklit/issues/2" {
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+
+				return
+			}
+
+			Expect(r.URL.Path).To(Equal("/api/v3/repos/vito/booklit/issues/1"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer some-token"))
+
+			w.Write([]byte(`{"title":"Some issue","state":"closed","html_url":"https://example.com/1"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		close(release)
+		server.Close()
+	})
+
+	It("fetches issues from the host", func() {
+		tracker := &issues.Tracker{
+			GitHub: issues.Host{URL: server.URL, Token: "some-token"},
+		}
+
+		issue, err := tracker.Issue(context.Background(), "vito/booklit#1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issue).To(Equal(booklit.Issue{
+			Ref:   "vito/booklit#1",
+			URL:   "https://example.com/1",
+			Title: "Some issue",
+			State: "closed",
+		}))
+	})
+
+	It("stops fetching once the context is done", func() {
+		tracker := &issues.Tracker{
+			GitHub: issues.Host{URL: server.URL, Token: "some-token"},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := tracker.Issue(ctx, "vito/booklit#2")
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("links to issues which the client gives up on", func() {
+		tracker := &issues.Tracker{
+			GitHub: issues.Host{URL: server.URL, Token: "some-token"},
+			Client: &http.Client{Timeout: 10 * time.Millisecond},
+		}
+
+		issue, err := tracker.Issue(context.Background(), "vito/booklit#2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issue).To(Equal(booklit.Issue{
+			Ref: "vito/booklit#2",
+			URL: server.URL + "/vito/booklit/issues/2",
+		}))
+	})
+})
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("LaTeX", (Example).Run,
+	Entry("a book with a chapter for each top-level section", Example{
+		Input: `\title{Hello, world!}
+
+Some \bold{bold} text; see \reference{child}.
+
+\section{
+	\title{Child}
+
+	\code{{{
+	a < b && {c}
+	}}}
+
+	\section{
+		\title{Grandchild}
+
+		Back to \reference{hello-world}{the top}.
+	}
+}
+`,
+
+		LaTeX: Files{
+			"hello-world.tex": `\documentclass{book}
+
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{alltt}
+\usepackage{graphicx}
+\usepackage[normalem]{ulem}
+\usepackage{hyperref}
+
+\title{Hello, world!}
+\date{}
+
+\begin{document}
+
+\maketitle
+\label{hello-world}
+
+Some \textbf{bold} text; see \hyperref[child]{Child} (\S\ref{child}).
+
+\chapter{Child}
+\label{child}
+
+\begin{verbatim}
+a < b && {c}
+\end{verbatim}
+
+\section{Grandchild}
+\label{grandchild}
+
+Back to \hyperref[hello-world]{the top}.
+
+\end{document}
+`,
+		},
+	}),
+
+	Entry("escaping special characters", Example{
+		Input: `\title{Costs & Benefits}{costs}
+
+Paths like C:\\dir, \{braces\}, $5 & 10% off #1 under_score, ~home and 2^3.
+`,
+
+		LaTeX: Files{
+			"costs.tex": `\documentclass{book}
+
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{alltt}
+\usepackage{graphicx}
+\usepackage[normalem]{ulem}
+\usepackage{hyperref}
+
+\title{Costs \& Benefits}
+\date{}
+
+\begin{document}
+
+\maketitle
+\label{costs}
+
+Paths like C:\textbackslash{}dir, \{braces\}, \$5 \& 10\% off \#1 under\_score, \textasciitilde{}home and 2\textasciicircum{}3.
+
+\end{document}
+`,
+		},
+	}),
+
+	Entry("images", Example{
+		Input: `\title{Pictures}
+
+\image{images/photo.png}{A photo}
+`,
+
+		LaTeX: Files{
+			"pictures.tex": `\documentclass{book}
+
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{alltt}
+\usepackage{graphicx}
+\usepackage[normalem]{ulem}
+\usepackage{hyperref}
+
+\title{Pictures}
+\date{}
+
+\begin{document}
+
+\maketitle
+\label{pictures}
+
+\includegraphics[width=\linewidth]{images/photo.png}
+
+\end{document}
+`,
+		},
+	}),
+)
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("llms.txt", (Example).Run,
+	Entry("chapters", Example{
+		Input: `\title{Hello, world!}
+
+How are you?
+
+\section{
+	\title{How I'm doing}
+
+	Good, \italic{thanks}! See \reference{their-reply}.
+
+	\section{
+		\title{In Detail}
+
+		\code{{{
+		fine
+		}}}
+	}
+}
+
+\section{
+	\title{Their Reply}
+
+	\list{good}{thanks}
+}
+`,
+
+		LLMs: Files{
+			"llms.txt": `# Hello, world!
+
+> How are you?
+
+## Chapters
+
+- [Hello, world!](llms/hello-world.md): Overview
+- [How I'm doing](llms/how-im-doing.md): Good, thanks! See Their Reply.
+- [Their Reply](llms/their-reply.md)
+`,
+
+			"llms/hello-world.md": `# Hello, world! {#hello-world}
+
+How are you?
+`,
+
+			"llms/how-im-doing.md": `# 1 How I'm doing {#how-im-doing}
+
+Good, _thanks_! See Their Reply [#their-reply].
+
+## 1.1 In Detail {#in-detail}
+
+` + "```" + `
+fine
+` + "```" + `
+`,
+
+			"llms/their-reply.md": `# 2 Their Reply {#their-reply}
+
+- good
+- thanks
+`,
+		},
+	}),
+
+	Entry("a single section", Example{
+		Input: `\title{Hello, world!}
+
+How are you?
+`,
+
+		LLMs: Files{
+			"llms.txt": `# Hello, world!
+
+> How are you?
+
+## Chapters
+
+- [Hello, world!](llms/hello-world.md): How are you?
+`,
+
+			"llms/hello-world.md": `# Hello, world! {#hello-world}
+
+How are you?
+`,
+		},
+	}),
+)
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Manifest", (Example).Run,
+	Entry("sections", Example{
+		Input: `\title{Hello, world!}
+
+\section{
+	\title{How I'm doing}
+
+	Good, thanks! \target{thanks}{Thanks}
+}
+`,
+
+		Manifest: `{
+			"hello-world": "hello-world.html",
+			"how-im-doing": "hello-world.html#how-im-doing",
+			"thanks": "hello-world.html#thanks"
+		}`,
+	}),
+
+	Entry("redirecting sections that moved to the same page", Example{
+		Input: `\title{Hello, world!}
+
+\section{
+	\title{How I'm doing}
+
+	Good, thanks!
+}
+`,
+
+		PreviousManifest: map[string]string{
+			"hello-world":  "hello-world.html",
+			"how-im-doing": "how-im-doing.html",
+		},
+
+		Redirects: Files{
+			"how-im-doing.html": "hello-world.html#how-im-doing",
+		},
+	}),
+
+	Entry("redirecting sections that moved to other pages", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{How I'm doing}
+
+	Good, thanks! \target{thanks}{Thanks}
+}
+`,
+
+		PreviousManifest: map[string]string{
+			"hello-world":  "index.html",
+			"how-im-doing": "index.html#how-im-doing",
+			"thanks":       "index.html#thanks",
+		},
+
+		Redirects: Files{
+			"index.html": "hello-world.html",
+		},
+	}),
+
+	Entry("redirecting aliases of sections", Example{
+		Input: `\title{Hello, world!}
+
+See \reference{how-im-feeling}.
+
+\split-sections
+
+\section{
+	\title{How I'm doing}
+	\alias-tag{how-im-feeling}
+
+	Good, thanks!
+}
+
+\section{
+	\title{Other}
+	\alias-tag{elsewhere}
+
+	\section{
+		\title{Nested}
+		\alias-tag{within}
+	}
+}
+`,
+
+		Manifest: `{
+			"hello-world": "hello-world.html",
+			"how-im-doing": "how-im-doing.html",
+			"how-im-feeling": "how-im-doing.html",
+			"other": "other.html",
+			"elsewhere": "other.html",
+			"nested": "other.html#nested",
+			"within": "other.html#nested"
+		}`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>See <a href="how-im-doing.html">How I'm doing</a>.</p>
+</section>
+`,
+		},
+
+		Redirects: Files{
+			"how-im-feeling.html": "how-im-doing.html",
+			"elsewhere.html":      "other.html",
+		},
+	}),
+
+	Entry("redirecting aliases of sections as in the previous manifest", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{How I'm doing}
+	\alias-tag{how-im-feeling}
+
+	Good, thanks!
+}
+`,
+
+		PreviousManifest: map[string]string{
+			"hello-world":    "hello-world.html",
+			"how-im-feeling": "feeling.html",
+		},
+
+		Redirects: Files{
+			"feeling.html": "how-im-doing.html",
+		},
+	}),
+
+	Entry("caching headers", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{How I'm doing}
+
+	Good, thanks! \target{thanks}{Thanks}
+}
+`,
+
+		Inputs: Files{
+			"css/booklit.css":          "",
+			"css/app.3f2a9c1d.css":     "",
+			"js/chunk-7KQ2M4XZ.js":     "",
+			"js/release-20240101.js":   "",
+			"images/dog-facing-up.png": "",
+		},
+
+		Headers: `/hello-world.html
+  Cache-Control: public, max-age=0, must-revalidate
+
+/how-im-doing.html
+  Cache-Control: public, max-age=0, must-revalidate
+
+/css/app.3f2a9c1d.css
+  Cache-Control: public, max-age=31536000, immutable
+
+/js/chunk-7KQ2M4XZ.js
+  Cache-Control: public, max-age=31536000, immutable
+
+`,
+	}),
+
+	Entry("service worker", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{How I'm doing}
+
+	Good, thanks!
+}
+`,
+
+		Inputs: Files{
+			"css/booklit.css":          "",
+			"images/dog-facing-up.png": "",
+			"_headers":                 "",
+		},
+
+		Precache: []string{
+			"hello-world.html",
+			"how-im-doing.html",
+			"css/booklit.css",
+			"images/dog-facing-up.png",
+		},
+	}),
+)
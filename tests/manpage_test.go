@@ -0,0 +1,146 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	"github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("Man Pages", (Example).Run,
+	Entry("a command's page", Example{
+		Input: `\title{Booklit}{booklit}
+\man-name{booklit}
+
+Builds books from \code{.lit} files. See \reference{options}.
+
+\section{
+	\title{Options}
+
+	\definitions{
+		\definition{\code{-i}}{The input file.}
+	}{
+		\definition{\code{-o}}{The output directory.}
+	}
+
+	\code{{{
+	booklit -i index.lit -o out
+	}}}
+
+	\list{one}{two \list{nested}}
+}
+`,
+
+		Manpages: Files{
+			"booklit.1": `'\" t
+.TH "BOOKLIT" "1" "" "" "Booklit"
+.SH NAME
+booklit \- Booklit
+.SH DESCRIPTION
+.PP
+Builds books from \fB\&.lit\fP files. See Options\&.
+.SH "OPTIONS"
+.TP
+\fB\-i\fP
+The input file.
+.TP
+\fB\-o\fP
+The output directory.
+.PP
+.RS 4
+.nf
+booklit \-i index.lit \-o out
+.fi
+.RE
+.IP \(bu 4
+one
+.IP \(bu 4
+two
+.RS 4
+.IP \(bu 4
+nested
+.RE
+`,
+		},
+	}),
+
+	Entry("a page for each split section, named after their man names and sections", Example{
+		Input: `\title{Manual}
+\man-section{5}
+
+\split-sections
+
+\table-of-contents
+
+\section{
+	\title{Config Files}{config}
+	\man-name{booklit.yml}
+
+	Written in YAML.
+}
+
+\section{
+	\title{Commands}
+	\man-section{1}
+
+	See \reference{config}.
+}
+`,
+
+		Manpages: Files{
+			"manual.5": `'\" t
+.TH "MANUAL" "5" "" "" "Manual"
+.SH NAME
+manual \- Manual
+.SH DESCRIPTION
+.IP \(bu 4
+\fBbooklit.yml\fP(5) \- Config Files
+.IP \(bu 4
+\fBcommands\fP(1) \- Commands
+`,
+			"booklit.yml.5": `'\" t
+.TH "BOOKLIT.YML" "5" "" "" "Manual"
+.SH NAME
+booklit.yml \- Config Files
+.SH DESCRIPTION
+.PP
+Written in YAML.
+`,
+			"commands.1": `'\" t
+.TH "COMMANDS" "1" "" "" "Manual"
+.SH NAME
+commands \- Commands
+.SH DESCRIPTION
+.PP
+See \fBbooklit.yml\fP(5)\&.
+`,
+		},
+	}),
+
+	Entry("spacing", Example{
+		Input: `\title{Booklit}{booklit}
+
+See Chapter{\nbsp}3, by J.{\thinspace}R.{\thinspace}R. Tolkien, in
+/usr/share/{\wbr}books.
+`,
+
+		Manpages: Files{
+			"booklit.1": `'\" t
+.TH "BOOKLIT" "1" "" "" "Booklit"
+.SH NAME
+booklit \- Booklit
+.SH DESCRIPTION
+.PP
+See Chapter\~3, by J.\|R.\|R. Tolkien, in
+/usr/share/\:books.
+`,
+		},
+	}),
+
+	Entry("an invalid man section", Example{
+		Input: `\title{Hello}
+
+\man-section{one}
+`,
+
+		Err: gomega.ContainSubstring("invalid man section: one"),
+	}),
+)
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Markdown", (Example).Run,
+	Entry("prose and sub-sections", Example{
+		Input: `\title{Hello, world!}
+
+\table-of-contents
+
+Some *literal* [markup], \italic{emphasis}, \bold{strength}, and
+\code{inline ` + "`" + `code` + "`" + `}. See \reference{using} or \link{the site}{https://example.com}.
+
+1. Not a list.
+
+\section{
+	\title{Using}
+
+	\code{{{
+	booklit -i index.lit -o out
+	}}}
+
+	\list{one}{two \bold{items}}
+
+	\section{
+		\title{Details}
+
+		Go \reference{hello-world}{back}.
+	}
+}
+`,
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+- [1 Using](hello-world.md#using)
+  - [1.1 Details](hello-world.md#details)
+
+Some \*literal\* \[markup\], *emphasis*, **strength**, and ` + "``" + ` inline ` + "`" + `code` + "`" + ` ` + "``" + `. See [Using](hello-world.md#using) or [the site](https://example.com).
+
+1\. Not a list.
+
+## <a id="using"></a>1 Using
+
+` + "```" + `
+booklit -i index.lit -o out
+` + "```" + `
+
+- one
+- two **items**
+
+### <a id="details"></a>1.1 Details
+
+Go [back](hello-world.md).
+`,
+		},
+	}),
+
+	Entry("split sections", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{Installing}
+
+	Download it, then see \reference{using}.
+}
+
+\section{
+	\title{Using}
+
+	Run it.
+}
+`,
+
+		Markdown: Files{
+			"installing.md": `# <a id="installing"></a>1 Installing
+
+Download it, then see [Using](using.md).
+`,
+
+			"using.md": `# <a id="using"></a>2 Using
+
+Run it.
+`,
+		},
+	}),
+
+	Entry("aliases of sections", Example{
+		Input: `\title{Hello, world!}
+
+See \reference{setup}.
+
+\section{
+	\title{Installing}
+	\alias-tag{setup}{getting-started}
+
+	Download it.
+}
+`,
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+See [Installing](hello-world.md#installing).
+
+## <a id="installing"></a><a id="setup"></a><a id="getting-started"></a>1 Installing
+
+Download it.
+`,
+		},
+	}),
+
+	Entry("blocks", Example{
+		Input: `\title{Hello, world!}
+
+\inset{
+	Quoted,
+
+	twice.
+}
+
+\table{
+	\table-row{Flag}{Meaning}
+}{
+	\table-row{\code{-i}}{input | file}
+}
+
+\definitions{
+	\definition{Booklit}{A tool for
+	writing books.}
+}
+
+\ordered-list{first}{second}
+`,
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+> Quoted,
+>
+> twice.
+
+| Flag | Meaning |
+| --- | --- |
+| ` + "`" + `-i` + "`" + ` | input \| file |
+
+- **Booklit**: A tool for writing books.
+
+1. first
+2. second
+`,
+		},
+	}),
+)
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Math", (Example).Run,
+	Entry("inline and display math", Example{
+		Input: `\title{Hello, world!}
+
+Einstein showed that \math{E = mc^2}.
+
+\display-math{{{
+\sum_{i=1}^{n} i = \frac{n(n+1)}{2}
+}}}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Einstein showed that <math xmlns="http://www.w3.org/1998/Math/MathML"><semantics><mrow><mi>E</mi><mo>=</mo><mi>m</mi><msup><mi>c</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">E = mc^2</annotation></semantics></math>.</p>
+
+	<div class="math"><math xmlns="http://www.w3.org/1998/Math/MathML" display="block"><semantics><mrow><munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><mi>i</mi><mo>=</mo><mfrac><mrow><mi>n</mi><mo>(</mo><mi>n</mi><mo>+</mo><mn>1</mn><mo>)</mo></mrow><mn>2</mn></mfrac></mrow><annotation encoding="application/x-tex">\sum_{i=1}^{n} i = \frac{n(n+1)}{2}</annotation></semantics></math></div>
+</section>
+`,
+		},
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+Einstein showed that $E = mc^2$.
+
+$$
+\sum_{i=1}^{n} i = \frac{n(n+1)}{2}
+$$
+`,
+		},
+	}),
+
+	Entry("symbols, fonts, and environments", Example{
+		Input: `\title{Hello, world!}
+
+\display-math{{{
+f(x) = \begin{cases}
+  \alpha \cdot \mathbb{R} & \text{if } x \geq 0 \\
+  \left| \sqrt[3]{x} \right| & \text{otherwise}
+\end{cases}
+}}}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<div class="math"><math xmlns="http://www.w3.org/1998/Math/MathML" display="block"><semantics><mrow><mi>f</mi><mo>(</mo><mi>x</mi><mo>)</mo><mo>=</mo><mrow><mo fence="true" form="prefix">{</mo><mtable columnalign="left left"><mtr><mtd><mrow><mi>α</mi><mo>⋅</mo><mi>ℝ</mi></mrow></mtd><mtd><mrow><mtext>if </mtext><mi>x</mi><mo>≥</mo><mn>0</mn></mrow></mtd></mtr><mtr><mtd><mrow><mo fence="true" form="prefix">|</mo><mroot><mi>x</mi><mn>3</mn></mroot><mo fence="true" form="postfix">|</mo></mrow></mtd><mtd><mtext>otherwise</mtext></mtd></mtr></mtable></mrow></mrow><annotation encoding="application/x-tex">f(x) = \begin{cases}
+  \alpha \cdot \mathbb{R} &amp; \text{if } x \geq 0 \\
+  \left| \sqrt[3]{x} \right| &amp; \text{otherwise}
+\end{cases}</annotation></semantics></math></div>
+</section>
+`,
+		},
+	}),
+
+	Entry("math rendered with a command", Example{
+		MathCommand:        []string{"sh", "-c", `printf '<span class="katex">%s</span><script>steal()</script>' "$(cat)"`},
+		DisplayMathCommand: []string{"sh", "-c", `printf '<span class="katex-display">%s</span>' "$(cat)"`},
+
+		Input: `\title{Hello, world!}
+
+Einstein showed that \math{E = mc^2}.
+
+\display-math{x + y}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Einstein showed that <span class="katex">E = mc^2</span>.</p>
+
+	<div class="math"><span class="katex-display">x + y</span></div>
+</section>
+`,
+		},
+	}),
+)
@@ -82,6 +82,71 @@ Some more body. \reference{some-target}
 		},
 	}),
 
+	Entry("learning paths", Example{
+		Input: `\title{Tutorials}
+
+\styled{learning-path}
+
+\learning-path
+
+\section{
+	\title{Deploying}
+
+	\requires{building}{configuring}
+}
+
+\section{
+	\title{Building}
+
+	\requires{installing}
+}
+
+\section{
+	\title{Configuring}
+}
+
+\section{
+	\title{Installing}
+}
+`,
+
+		Outputs: Files{
+			"tutorials.html": `<section>
+	Tutorials
+
+	<nav>
+		<ol>
+			<li><a href="tutorials.html#installing">Installing</a></li>
+			<li><a href="tutorials.html#building">Building</a></li>
+			<li><a href="tutorials.html#configuring">Configuring</a></li>
+			<li><a href="tutorials.html#deploying">Deploying</a></li>
+		</ol>
+	</nav>
+
+	<h2>Deploying</h2>
+
+	<aside>
+		<ul>
+			<li><a href="tutorials.html#building">Building</a></li>
+			<li><a href="tutorials.html#configuring">Configuring</a></li>
+		</ul>
+	</aside>
+
+	<h2>Building</h2>
+
+	<aside>
+		<ul>
+			<li><a href="tutorials.html#installing">Installing</a></li>
+		</ul>
+	</aside>
+
+	<h2>Configuring</h2>
+
+	<h2>Installing</h2>
+</section>`,
+		},
+	}),
+
 	Entry("set in plugin and rendered in template", Example{
 		Input: `\title{Set Partial Read Template}
 
@@ -110,6 +175,68 @@ Some more body.
 <p>I want to be some body.</p>
 
 <p>Some more body.</p>
+`,
+		},
+	}),
+
+	Entry("set in plugins while rendering pages concurrently", Example{
+		Input: `\title{Set Partial Read Template}
+
+\use-plugin{set-partials}
+
+\split-sections
+
+I want to be some body.
+
+\set-the-partial
+
+\section{
+	\title{One}
+
+	\set-the-partial
+}
+
+\section{
+	\title{Two}
+
+	\set-the-partial
+}
+
+\section{
+	\title{Three}
+
+	\set-the-partial
+}
+`,
+
+		Jobs: 4,
+
+		Outputs: Files{
+			"set-partial-read-template.html": `<div>
+	Here's a partial:
+
+	<p>I'm a partial!</p>
+</div>
+
+<div>
+	Here's the partial again:
+
+	<p>I'm a partial!</p>
+</div>
+
+<p>I want to be some body.</p>
+`,
+			"one.html": `<section>
+	<h1>1 One</h1>
+</section>
+`,
+			"two.html": `<section>
+	<h1>2 Two</h1>
+</section>
+`,
+			"three.html": `<section>
+	<h1>3 Three</h1>
+</section>
 `,
 		},
 	}),
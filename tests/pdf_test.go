@@ -0,0 +1,97 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("PDF", (Example).Run,
+	Entry("a document with every section inline", Example{
+		Input: `\title{Hello, world!}
+
+Some \bold{bold} text; see \reference{child}.
+
+\section{
+	\title{Child}
+
+	\code{{{
+	a < b
+	}}}
+
+	\section{
+		\title{Grandchild}
+
+		Back to \reference{hello-world}{the top}.
+	}
+}
+`,
+
+		PDF: Files{
+			"hello-world.pdf": `<!DOCTYPE html>
+<html>
+  <head>
+    <meta http-equiv="content-type" content="text/html; charset=utf-8" />
+    <title>Hello, world!</title>
+    <style>
+      @page {
+        size: A4;
+        margin: 2cm;
+
+        @bottom-center {
+          content: counter(page);
+        }
+      }
+
+      h2.section-header {
+        page-break-before: always;
+      }
+
+      .section-header {
+        page-break-after: avoid;
+      }
+
+      pre {
+        white-space: pre-wrap;
+      }
+
+      code, pre {
+        direction: ltr;
+        unicode-bidi: isolate;
+      }
+
+      nav a::after {
+        content: leader(".") target-counter(attr(href), page);
+      }
+    </style>
+  </head>
+  <body>
+    
+<h1 class="section-header"><a id="hello-world"></a>Hello, world!</h1>
+
+<p>Some <strong>bold</strong> text; see <a href="#child">Child</a>.</p>
+
+
+  
+<h2 class="section-header"><a id="child"></a><span class="section-number">1 </span>Child</h2>
+
+<pre>a &lt; b</pre>
+
+
+  
+<h3 class="section-header"><a id="grandchild"></a><span class="section-number">1.1 </span>Grandchild</h3>
+
+<p>Back to <a href="#hello-world">the top</a>.</p>
+
+
+
+
+
+
+
+
+
+
+  </body>
+</html>`,
+		},
+	}),
+)
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/plugin/v2"
+	v2plugin "github.com/vito/booklit/tests/fixtures/v2-plugin"
+)
+
+var _ = DescribeTable("Plugin API v2", (Example).Run,
+	Entry("invoking functions", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2}
+
+\shout{hello}{world}
+`,
+
+		Outputs: Files{
+			"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>HELLO WORLD!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("functions returning errors", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2}
+
+\fail
+`,
+
+		Err: ContainSubstring("function \\fail returned an error: oh no"),
+	}),
+
+	Entry("wrapped v1 plugins", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2-wrapped}
+
+\whisper{HELLO}{WORLD} \quote{\shout{hi}} \count{anything}
+`,
+
+		Outputs: Files{
+			"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>hello world... “HI!” unevaluated</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("safe plugins in safe mode", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2}
+
+\shout{hi}
+`,
+
+		Safe: &booklit.SafeMode{},
+
+		Outputs: Files{
+			"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>HI!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("plugins needing a newer Booklit", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2-newer}
+`,
+
+		Version: "0.9.12",
+
+		Err: ContainSubstring("plugin 'v2-newer' requires booklit 0.10.0 or newer (running 0.9.12)"),
+	}),
+
+	Entry("plugins needing a Booklit no newer than the one running", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2-newer}
+
+\shout{hi}
+`,
+
+		Version: "0.10.1",
+
+		Outputs: Files{
+			"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>HI!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("plugins needing a newer Booklit than a prerelease", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2-newer}
+`,
+
+		Version: "0.9.99-dev",
+
+		Err: ContainSubstring("plugin 'v2-newer' requires booklit 0.10.0 or newer (running 0.9.99-dev)"),
+	}),
+
+	Entry("plugins needing any Booklit, in builds without a version", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2-newer}
+
+\shout{hi}
+`,
+
+		Outputs: Files{
+			"hello.html": `<section>
+	<h1>Hello</h1>
+
+	<p>HI!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("plugins needing a newer minor version", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v2-future}
+`,
+
+		Err: ContainSubstring("plugin 'v2-future' implements plugin API 2.99, which booklit 0.0.0-dev does not support (supports 1 through 2.0)"),
+	}),
+
+	Entry("plugins implementing an unknown major version", Example{
+		Input: `\title{Hello}
+
+\use-plugin{v3}
+`,
+
+		Err: ContainSubstring("plugin 'v3' implements plugin API 3.0"),
+	}),
+)
+
+var _ = Describe("Plugin API v2", func() {
+	It("lists the functions of wrapped v1 plugins which take evaluated arguments", func() {
+		wrapped := plugin.V1(v2plugin.NewV1Plugin)(&booklit.Section{})
+
+		functions := wrapped.Functions()
+		Expect(functions).To(HaveKey("whisper"))
+		Expect(functions).To(HaveKey("quote"))
+		Expect(functions).ToNot(HaveKey("count"))
+
+		content, err := functions["whisper"](context.Background(), plugin.Call{
+			Function: "whisper",
+			Args:     []booklit.Content{booklit.String("HELLO"), booklit.String("THERE")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(content).To(Equal(booklit.String("hello there...")))
+
+		_, err = functions["quote"](context.Background(), plugin.Call{
+			Function: "quote",
+		})
+		Expect(err).To(MatchError("argument count mismatch for quote: given 0, need 1"))
+	})
+
+	It("adapts v2 plugins for use without registering them", func() {
+		adapted := plugin.Adapt(v2plugin.NewPlugin)(&booklit.Section{})
+		Expect(booklit.PluginFunctions(adapted)).To(Equal([]string{"fail", "shout"}))
+	})
+})
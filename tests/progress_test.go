@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/build"
+	"github.com/vito/booklit/load"
+	"github.com/vito/booklit/render"
+)
+
+// recordingProgress records each event it's notified of.
+type recordingProgress struct {
+	events   []string
+	advanced map[booklit.Stage][]string
+	lock     sync.Mutex
+}
+
+func (progress *recordingProgress) StartStage(stage booklit.Stage, total int) {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	progress.events = append(progress.events, fmt.Sprintf("start %s %d", stage, total))
+}
+
+func (progress *recordingProgress) Advance(stage booklit.Stage, section *booklit.Section) {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	if progress.advanced == nil {
+		progress.advanced = map[booklit.Stage][]string{}
+	}
+
+	progress.advanced[stage] = append(progress.advanced[stage], section.PrimaryTag.Name)
+}
+
+func (progress *recordingProgress) FinishStage(stage booklit.Stage, elapsed time.Duration) {
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	progress.events = append(progress.events, fmt.Sprintf("finish %s", stage))
+}
+
+var _ = Describe("Progress", func() {
+	var files fstest.MapFS
+
+	BeforeEach(func() {
+		files = fstest.MapFS{
+			"index.lit": {Data: []byte(`\title{Index}
+
+\split-sections
+
+\include-section{child.lit}
+
+\section{
+	\title{Inline}
+
+	Hi!
+}
+`)},
+			"child.lit": {Data: []byte(`\title{Child}
+
+\section{
+	\title{Grandchild}
+
+	Hi!
+}
+`)},
+		}
+	})
+
+	It("is notified of each stage of the build", func() {
+		progress := &recordingProgress{}
+
+		_, err := build.Build(context.Background(), build.Config{
+			In:          "index.lit",
+			FS:          files,
+			Destination: &render.MemoryOutput{},
+			Progress:    progress,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(progress.events).To(Equal([]string{
+			"start parse 0",
+			"finish parse",
+			"start resolve 4",
+			"finish resolve",
+			"start render 3",
+			"finish render",
+		}))
+
+		Expect(progress.advanced).To(Equal(map[booklit.Stage][]string{
+			booklit.StageParse:   {"child", "index"},
+			booklit.StageResolve: {"index", "child", "grandchild", "inline"},
+			booklit.StageRender:  {"index", "child", "inline"},
+		}))
+	})
+
+	It("is notified of each page rendered at once", func() {
+		progress := &recordingProgress{}
+
+		_, err := build.Build(context.Background(), build.Config{
+			In:          "index.lit",
+			FS:          files,
+			Destination: &render.MemoryOutput{},
+			Jobs:        3,
+			Progress:    progress,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(progress.advanced[booklit.StageRender]).To(ConsistOf("index", "child", "inline"))
+		Expect(progress.events).To(ContainElement("finish render"))
+	})
+
+	It("is not notified that a failed stage finished", func() {
+		files["child.lit"] = &fstest.MapFile{Data: []byte(`\title{Child}
+
+See \reference{missing}.
+`)}
+
+		progress := &recordingProgress{}
+
+		_, err := build.Build(context.Background(), build.Config{
+			In:          "index.lit",
+			FS:          files,
+			Destination: &render.MemoryOutput{},
+			Progress:    progress,
+		})
+		Expect(err).To(HaveOccurred())
+
+		Expect(progress.events).To(Equal([]string{
+			"start parse 0",
+			"finish parse",
+			"start resolve 3",
+		}))
+	})
+
+	It("is notified of the books loaded together", func() {
+		progress := &recordingProgress{}
+
+		processor := &load.Processor{
+			FS:       files,
+			Progress: progress,
+		}
+
+		_, err := processor.LoadBooks([]string{"index.lit", "child.lit"}, []booklit.PluginFactory{baselit.NewPlugin})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(progress.events).To(Equal([]string{
+			"start parse 0",
+			"finish parse",
+			"start resolve 4",
+			"finish resolve",
+			"start resolve 2",
+			"finish resolve",
+		}))
+	})
+})
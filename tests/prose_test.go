@@ -1,7 +1,18 @@
 package tests
 
 import (
+	"context"
+	"regexp"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/load"
 	_ "github.com/vito/booklit/tests/fixtures/stringer-plugin"
 )
 
@@ -65,6 +76,30 @@ Here's an \image{foo.png}{with alt text} and another \image{without.gif}.
 		},
 	}),
 
+	Entry("rewritten links and images", Example{
+		Input: `\title{Hello, world!}
+
+How are \link{you}{https://api.example.com/v1/you}?
+
+Here's an \image{/images/foo.png}{with alt text}.
+`,
+
+		LinkRewrites: []booklit.LinkRewrite{
+			{Pattern: regexp.MustCompile(`^https://api\.example\.com/`), Replacement: "https://staging-api.example.com/"},
+			{Pattern: regexp.MustCompile(`^/images/(.*)$`), Replacement: "https://cdn.example.com/$1"},
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>How are <a href="https://staging-api.example.com/v1/you">you</a>?</p>
+
+	<p>Here's an <img src="https://cdn.example.com/foo.png" alt="with alt text" />.</p>
+</section>`,
+		},
+	}),
+
 	Entry("italics", Example{
 		Input: `\title{Hello, world!}
 
@@ -140,6 +175,40 @@ How are \strike{you}?
 		},
 	}),
 
+	Entry("isolate", Example{
+		Input: `\title{Hello, world!}
+
+The word for peace is \isolate{שלום}.
+
+Call \isolate{\code{len(x)}}{ltr} to count.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>The word for peace is <bdi>שלום</bdi>.</p>
+
+	<p>Call <bdi dir="ltr"><code>len(x)</code></bdi> to count.</p>
+</section>`,
+		},
+	}),
+
+	Entry("ruby", Example{
+		Input: `\title{Hello, world!}
+
+Read \ruby{漢字}{かんじ} aloud.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Read <ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby> aloud.</p>
+</section>`,
+		},
+	}),
+
 	Entry("superscript", Example{
 		Input: `\title{Hello, world!}
 
@@ -170,6 +239,207 @@ How are \subscript{you}?
 		},
 	}),
 
+	Entry("badges", Example{
+		Input: `\title{Hello, world!}
+
+Build is \badge{build}{passing}{green}.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Build is <svg xmlns="http://www.w3.org/2000/svg" class="badge" width="104" height="20" role="img" aria-label="build: passing"><rect width="45" height="20" fill="#555"/><rect x="45" width="59" height="20" fill="#97ca00"/><g fill="#fff" font-family="Verdana,Geneva,sans-serif" font-size="11" text-anchor="middle"><text x="22" y="14">build</text><text x="74" y="14">passing</text></g></svg>.</p>
+</section>`,
+		},
+	}),
+
+	Entry("issues", Example{
+		Input: `\title{Hello, world!}
+
+Fixed by \issue{vito/booklit#12}, but see \issue{vito/booklit#34}.
+`,
+
+		IssueTracker: fakeIssueTracker{
+			"vito/booklit#12": {
+				Ref:   "vito/booklit#12",
+				URL:   "https://github.com/vito/booklit/pull/12",
+				Title: "Fix parsing",
+				State: "merged",
+			},
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Fixed by <a class="issue issue-merged" href="https://github.com/vito/booklit/pull/12"><span class="issue-state" style="background-color: #8250df">merged</span> <span class="issue-ref">vito/booklit#12</span> <span class="issue-title">Fix parsing</span></a>, but see <a class="issue" href="https://github.com/vito/booklit/issues/34"><span class="issue-ref">vito/booklit#34</span></a>.</p>
+</section>`,
+		},
+	}),
+
+	Entry("issues without a token", Example{
+		Input: `\title{Hello, world!}
+
+See \issue{vito/booklit#34} and \issue{gitlab:group/sub/project!5}.
+`,
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+See [vito/booklit#34](https://github.com/vito/booklit/issues/34) and [group/sub/project!5](https://gitlab.com/group/sub/project/-/merge_requests/5).
+`,
+		},
+	}),
+
+	Entry("number formatting", Example{
+		Input: `\title{Hello, world!}
+
+We have \num{1234567} users, \bytes{1536000} of data, and \bytes{512} to spare, uploaded in \duration{90s}.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>We have 1,234,567 users, 1.5 MB of data, and 512 B to spare, uploaded in 1 minute 30 seconds.</p>
+</section>`,
+		},
+	}),
+
+	Entry("dates", Example{
+		Input: `\title{Hello, world!}
+
+Released on \date{2024-03-01} (\date{2024-03-01}{Jan 2, 2006}).
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Released on <time datetime="2024-03-01T00:00:00Z">March 1, 2024</time> (<time datetime="2024-03-01T00:00:00Z">Mar 1, 2024</time>).</p>
+</section>`,
+		},
+	}),
+
+	Entry("dates in the section's locale", Example{
+		Input: `\title{Hallo, Welt!}{hello-world}
+
+Veröffentlicht am \date{2024-03-01} (\date{2024-03-01}{Mon, 2. Jan 2006}), gebaut am \today{Monday, 2. January 2006}.
+`,
+
+		Locale: "de-DE",
+		Now:    time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC),
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hallo, Welt!</h1>
+
+	<p>Veröffentlicht am <time datetime="2024-03-01T00:00:00Z">1. März 2024</time> (<time datetime="2024-03-01T00:00:00Z">Fr., 1. März 2024</time>), gebaut am <time datetime="2024-05-06T12:00:00Z">Montag, 6. Mai 2024</time>.</p>
+</section>`,
+		},
+	}),
+
+	Entry("translations", Example{
+		Input: `\title{Hello, world!}
+
+\translate{greeting} \translate{farewell}
+`,
+
+		Locale:        "pt-BR",
+		DefaultLocale: "en",
+
+		Translations: booklit.Translations{
+			"en": {
+				"greeting": "Hello!",
+				"farewell": "Goodbye!",
+			},
+			"pt": {
+				"greeting": "Olá!",
+			},
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Olá! Goodbye!</p>
+</section>`,
+		},
+	}),
+
+	Entry("emoji", Example{
+		Input: `\title{Hello, world!}
+
+Ship it \emoji{rocket}! Not a :rocket: yet.
+
+\section{
+	\title{Shortcodes}
+
+	\emoji-shortcodes
+
+	Ship it :rocket: at 10:30:00 :not-an-emoji:.
+
+	\code{{
+	a[:x:] \bold{b[:x:]}
+	}}
+
+	\code{{{
+	c[:x:]
+	}}}
+}
+
+\section{
+	\title{Images}
+
+	\emoji-images{/emoji/%s.png}
+
+	\emoji{tada}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Ship it <span class="emoji" role="img" aria-label="rocket">🚀</span>! Not a :rocket: yet.</p>
+
+	<h2>1 Shortcodes</h2>
+
+	<p>Ship it <span class="emoji" role="img" aria-label="rocket">🚀</span> at 10:30:00 :not-an-emoji:.</p>
+
+	<pre>a[:x:] <strong>b[:x:]</strong></pre>
+
+	<pre>c[:x:]</pre>
+
+	<h2>2 Images</h2>
+
+	<p><img class="emoji" src="/emoji/tada.png" alt="🎉" title=":tada:" /></p>
+</section>`,
+		},
+	}),
+
+	Entry("inline svg", Example{
+		Input: `\title{Hello, world!}
+
+Here's a logo: \svg{logo.svg}
+`,
+
+		Inputs: Files{
+			"logo.svg": `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10" onload="alert(1)"><script>alert(2)</script><a href="javascript:alert(3)"><circle cx="5" cy="5" r="4" onclick="alert(4)"/></a></svg>
+`,
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Here's a logo: <svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><a><circle cx="5" cy="5" r="4"/></a></svg></p>
+</section>`,
+		},
+	}),
+
 	Entry("multiple paragraphs", Example{
 		Input: `\title{Hello, world!}
 
@@ -266,6 +536,167 @@ varius molestie.
 		},
 	}),
 
+	Entry("hard-wrapped paragraphs", Example{
+		HardWraps: true,
+
+		Input: `\title{Hello, world!}
+
+Roses are red,
+violets are blue.
+
+Sugar is sweet.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Roses are red,<br />violets are blue.</p>
+
+	<p>Sugar is sweet.</p>
+</section>
+`,
+		},
+
+		Markdown: Files{
+			"hello-world.md": `# <a id="hello-world"></a>Hello, world!
+
+Roses are red,\
+violets are blue.
+
+Sugar is sweet.
+`,
+		},
+	}),
+
+	Entry("hard-wrapped sections", Example{
+		Input: `\title{Hello, world!}
+
+Lorem ipsum dolor sit amet,
+consectetur adipiscing elit.
+
+\section{
+	\title{Address}
+
+	\hard-wraps
+
+	221B Baker Street
+	London
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>
+
+	<h2>1 Address</h2>
+
+	<p>221B Baker Street<br />London</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("verse", Example{
+		Input: `\title{Hello, world!}
+
+\verse{
+	Roses are red,
+	violets are blue.
+
+	Sugar is sweet.
+}
+
+Lorem ipsum dolor sit amet,
+consectetur adipiscing elit.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<div class="verse">
+		<p>Roses are red,<br />violets are blue.</p>
+
+		<p>Sugar is sweet.</p>
+	</div>
+
+	<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("spacing", Example{
+		Input: `\title{Hello, world!}
+
+See Chapter{\nbsp}3, by J.{\thinspace}R.{\thinspace}R. Tolkien, in
+/usr/share/{\wbr}books.
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>See Chapter` + booklit.NonBreakingSpace + `3, by J.` + booklit.ThinSpace + `R.` + booklit.ThinSpace + `R. Tolkien, in /usr/share/` + booklit.WordBreak + `books.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("attached units", Example{
+		Input: `\title{Hello, world!}
+
+It's 10 km away, weighs 2.5 kg, and has a 3.2 GHz CPU
+and 16 GB of memory, \code{ulimit -v 16 GB}, for 5 min.
+
+It's 10 kilometers, or 5 minutes, and 2 in 3 say 4 m.
+`,
+
+		AttachUnits: true,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>It&#39;s 10` + booklit.NonBreakingSpace + `km away, weighs 2.5` + booklit.NonBreakingSpace + `kg, and has a 3.2` + booklit.NonBreakingSpace + `GHz CPU and 16` + booklit.NonBreakingSpace + `GB of memory, <code>ulimit -v 16 GB</code>, for 5` + booklit.NonBreakingSpace + `min.</p>
+
+	<p>It&#39;s 10 kilometers, or 5 minutes, and 2 in 3 say 4` + booklit.NonBreakingSpace + `m.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("attached units within a section", Example{
+		Input: `\title{Hello, world!}
+
+It's 10 km away.
+
+\section{
+	\title{Specs}
+
+	\attach-units
+
+	It's 10 km away.
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>It&#39;s 10 km away.</p>
+
+	<h2>1 Specs</h2>
+
+	<p>It&#39;s 10` + booklit.NonBreakingSpace + `km away.</p>
+</section>
+`,
+		},
+	}),
+
 	Entry("inline code and code blocks", Example{
 		Input: `\title{Hello, world!}
 
@@ -490,6 +921,24 @@ This is an \italic{  } even more spaced italic.
 		},
 	}),
 
+	Entry("plugin dependencies", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{dependent}
+
+\shout{\string{hi}}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>hi!</p>
+</section>
+`,
+		},
+	}),
+
 	Entry("preformatted string arguments", Example{
 		Input: `\title{Hello, world!}
 
@@ -545,3 +994,54 @@ One more line, with meaning.
 		},
 	}),
 )
+
+var _ = Describe("The build time", func() {
+	It("is when each load starts, even with the same processor", func() {
+		processor := &load.Processor{
+			FS: fstest.MapFS{
+				"index.lit": {Data: []byte(`\title{Hello}
+
+\today{2006-01-02T15:04:05.999999999Z07:00}
+`)},
+			},
+		}
+
+		build := func() (*booklit.Section, time.Time, time.Time) {
+			before := time.Now()
+
+			section, err := processor.LoadFile("index.lit", []booklit.PluginFactory{baselit.NewPlugin})
+			Expect(err).ToNot(HaveOccurred())
+
+			return section, before, time.Now()
+		}
+
+		first, before, after := build()
+		Expect(first.BuildTime).To(BeTemporally(">=", before))
+		Expect(first.BuildTime).To(BeTemporally("<=", after))
+		Expect(first.Body.String()).To(ContainSubstring(first.BuildTime.Format(time.RFC3339Nano)))
+
+		time.Sleep(10 * time.Millisecond)
+
+		second, before, after := build()
+		Expect(second.BuildTime).To(BeTemporally(">=", before))
+		Expect(second.BuildTime).To(BeTemporally("<=", after))
+		Expect(second.BuildTime).To(BeTemporally(">", first.BuildTime))
+		Expect(second.Body.String()).To(ContainSubstring(second.BuildTime.Format(time.RFC3339Nano)))
+	})
+})
+
+// fakeIssueTracker returns the given issues, and links to any others on
+// GitHub.
+type fakeIssueTracker map[string]booklit.Issue
+
+func (tracker fakeIssueTracker) Issue(ctx context.Context, ref string) (booklit.Issue, error) {
+	issue, found := tracker[ref]
+	if !found {
+		issue = booklit.Issue{
+			Ref: ref,
+			URL: "https://github.com/" + strings.Replace(ref, "#", "/issues/", 1),
+		}
+	}
+
+	return issue, nil
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vito/booklit/booklitcmd"
+)
+
+var _ = Describe("Rebuild webhook", func() {
+	const payload = `{"ref":"refs/heads/master"}`
+
+	var server *booklitcmd.Server
+	var rebuilds int
+	var buildErr error
+
+	BeforeEach(func() {
+		rebuilds = 0
+		buildErr = nil
+
+		server = &booklitcmd.Server{
+			Rebuild: &booklitcmd.RebuildWebhook{
+				Token: "some-token",
+				Build: func() error {
+					rebuilds++
+					return buildErr
+				},
+			},
+		}
+	})
+
+	sign := func(secret string, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	rebuild := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/rebuild", strings.NewReader(payload))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+
+		return res
+	}
+
+	DescribeTable("authorizing rebuilds",
+		func(headers func() map[string]string) {
+			res := rebuild("POST", headers())
+			Expect(res.Code).To(Equal(http.StatusOK))
+			Expect(res.Body.String()).To(Equal("rebuilt\n"))
+			Expect(rebuilds).To(Equal(1))
+		},
+		Entry("with the token as a bearer token", func() map[string]string {
+			return map[string]string{"Authorization": "Bearer some-token"}
+		}),
+		Entry("with a signature of the body by the token", func() map[string]string {
+			return map[string]string{"X-Hub-Signature-256": sign("some-token", payload)}
+		}),
+	)
+
+	DescribeTable("rejecting unauthorized rebuilds",
+		func(headers func() map[string]string) {
+			res := rebuild("POST", headers())
+			Expect(res.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rebuilds).To(Equal(0))
+		},
+		Entry("without credentials", func() map[string]string {
+			return map[string]string{}
+		}),
+		Entry("with the wrong bearer token", func() map[string]string {
+			return map[string]string{"Authorization": "Bearer wrong-token"}
+		}),
+		Entry("with a signature by the wrong secret", func() map[string]string {
+			return map[string]string{"X-Hub-Signature-256": sign("wrong-token", payload)}
+		}),
+		Entry("with a signature of a different body", func() map[string]string {
+			return map[string]string{"X-Hub-Signature-256": sign("some-token", "{}")}
+		}),
+		Entry("with a malformed signature", func() map[string]string {
+			return map[string]string{"X-Hub-Signature-256": "sha256=not-hex"}
+		}),
+		Entry("with another kind of authorization", func() map[string]string {
+			return map[string]string{"Authorization": "Basic c29tZS10b2tlbg=="}
+		}),
+	)
+
+	It("only allows POST", func() {
+		res := rebuild("GET", map[string]string{"Authorization": "Bearer some-token"})
+		Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rebuilds).To(Equal(0))
+	})
+
+	It("responds with the error if rebuilding fails", func() {
+		buildErr = errors.New("oh no")
+
+		res := rebuild("POST", map[string]string{"Authorization": "Bearer some-token"})
+		Expect(res.Code).To(Equal(http.StatusInternalServerError))
+		Expect(res.Body.String()).To(Equal("failed to rebuild: oh no\n"))
+	})
+})
@@ -2,6 +2,7 @@ package tests
 
 import (
 	. "github.com/onsi/ginkgo/extensions/table"
+	"github.com/vito/booklit"
 )
 
 var _ = DescribeTable("Booklit", (Example).Run,
@@ -51,6 +52,186 @@ See also \reference{section-c}{the last section}.
 		},
 	}),
 
+	Entry("references with directory URLs", Example{
+		Input: `\title{Hello, world!}{index}
+
+See also \reference{section-b}.
+
+\split-sections
+
+\section{
+	\title{Section A}
+
+	See also \reference{index}.
+
+	\section{
+		\title{Section A.1}
+
+		See also \reference{section-a}.
+	}
+}
+
+\section{
+	\title{Section B}
+
+	See also \reference{section-a1}.
+}
+`,
+
+		URLStyle: booklit.URLStyleDirectories,
+
+		Outputs: Files{
+			"index.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>See also <a href="/section-b/">Section B</a>.</p>
+</section>
+`,
+			"section-a/index.html": `<section>
+	<h1>1 Section A</h1>
+
+	<p>See also <a href="/">Hello, world!</a>.</p>
+
+	<h2>1.1 Section A.1</h2>
+
+	<p>See also <a href="/section-a/">Section A</a>.</p>
+</section>
+`,
+			"section-b/index.html": `<section>
+	<h1>2 Section B</h1>
+
+	<p>See also <a href="/section-a/#section-a1">Section A.1</a>.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("references with nested and dated permalinks", Example{
+		Input: `\title{Hello, world!}
+
+See also \reference{install} and \reference{launch}.
+
+\split-sections
+
+\section{
+	\title{Docs}
+
+	\permalinks{nested}
+
+	\split-sections
+
+	\section{
+		\title{Install}
+
+		See also \reference{launch}.
+	}
+}
+
+\section{
+	\title{Blog}
+
+	\split-sections
+
+	\section{
+		\title{Launch}
+
+		\published{2024-05-01}
+
+		See also \reference{docs}.
+	}
+}
+`,
+
+		Permalinks: map[string]booklit.Permalinks{
+			"blog": booklit.PermalinksDate,
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>See also <a href="/docs/install.html">Install</a> and <a href="/blog/2024/05/01/launch.html">Launch</a>.</p>
+</section>
+`,
+			"docs/install.html": `<section>
+	<h1>1.1 Install</h1>
+
+	<p>See also <a href="/blog/2024/05/01/launch.html">Launch</a>.</p>
+</section>
+`,
+			"blog/2024/05/01/launch.html": `<section>
+	<h1>2.1 Launch</h1>
+
+	<p>See also <a href="/docs.html">Docs</a>.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("references with extensionless URLs", Example{
+		Input: `\title{Hello, world!}
+
+See also \reference{section-a}.
+
+\split-sections
+
+\section{
+	\title{Section A}
+
+	See also \reference{hello-world}.
+}
+`,
+
+		URLStyle: booklit.URLStyleExtensionless,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>See also <a href="section-a">Section A</a>.</p>
+</section>
+`,
+			"section-a.html": `<section>
+	<h1>1 Section A</h1>
+
+	<p>See also <a href="hello-world">Hello, world!</a>.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("references under a base path", Example{
+		Input: `\title{Hello, world!}
+
+See also \reference{section-a}.
+
+\split-sections
+
+\section{
+	\title{Section A}
+
+	See also \reference{hello-world}.
+}
+`,
+
+		BasePath: "/docs/",
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>See also <a href="/docs/section-a.html">Section A</a>.</p>
+</section>
+`,
+			"section-a.html": `<section>
+	<h1>1 Section A</h1>
+
+	<p>See also <a href="/docs/hello-world.html">Hello, world!</a>.</p>
+</section>
+`,
+		},
+	}),
+
 	Entry("references to other sections by title", Example{
 		Input: `\title{Hello, world!}
 
@@ -97,6 +278,88 @@ See also \reference{Section C}{the last section}.
 		},
 	}),
 
+	Entry("references to other books", Example{
+		Books: Files{
+			"user": `\title{User Guide}
+
+See \reference{setup}.
+`,
+			"admin": `\title{Admin Guide}
+
+\split-sections
+
+\section{
+	\title{Setup}
+
+	Back to \reference{user-guide}.
+}
+`,
+		},
+
+		Outputs: Files{
+			"user/user-guide.html": `<section>
+	<h1>User Guide</h1>
+
+	<p>See <a href="../admin/setup.html">Setup</a>.</p>
+</section>
+`,
+			"admin/setup.html": `<section>
+	<h1>1 Setup</h1>
+
+	<p>Back to <a href="../user/user-guide.html">User Guide</a>.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("references within a book of a workspace", Example{
+		Books: Files{
+			"user": `\title{User Guide}
+
+\split-sections
+
+See \reference{install} and \reference{setup}.
+
+\section{
+	\title{Install}
+
+	Back to \reference{user-guide}.
+}
+`,
+			"admin": `\title{Admin Guide}
+
+\split-sections
+
+\section{
+	\title{Setup}
+
+	See \reference{admin-guide}.
+}
+`,
+		},
+
+		Outputs: Files{
+			"user/user-guide.html": `<section>
+	<h1>User Guide</h1>
+
+	<p>See <a href="install.html">Install</a> and <a href="../admin/setup.html">Setup</a>.</p>
+</section>
+`,
+			"user/install.html": `<section>
+	<h1>1 Install</h1>
+
+	<p>Back to <a href="user-guide.html">User Guide</a>.</p>
+</section>
+`,
+			"admin/setup.html": `<section>
+	<h1>1 Setup</h1>
+
+	<p>See <a href="admin-guide.html">Admin Guide</a>.</p>
+</section>
+`,
+		},
+	}),
+
 	Entry("references to other sections on split pages", Example{
 		Input: `\title{Hello, world!}
 
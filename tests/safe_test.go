@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"strings"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/vito/booklit"
+	"github.com/vito/booklit/baselit"
+	"github.com/vito/booklit/load"
+	_ "github.com/vito/booklit/tests/fixtures/arbitrary-style-plugin"
+)
+
+var _ = DescribeTable("Safe Mode", (Example).Run,
+	Entry("including sections within the limits", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{how-im-doing.lit}
+`,
+
+		Inputs: Files{
+			"how-im-doing.lit": `\title{How I'm doing}
+
+Good, thanks! And you?
+`,
+		},
+
+		Safe: &booklit.SafeMode{
+			MaxIncludeDepth: 1,
+			MaxFileSize:     1024,
+		},
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<h2>1 How I'm doing</h2>
+
+	<p>Good, thanks! And you?</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("rejecting plugins which are not declared safe", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{arbitrary-style}
+`,
+
+		Safe: &booklit.SafeMode{},
+
+		Err: ContainSubstring("plugin 'arbitrary-style' is not allowed in safe mode"),
+	}),
+
+	Entry("rejecting sections included too deeply", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{how-im-doing.lit}
+`,
+
+		Inputs: Files{
+			"how-im-doing.lit": `\title{How I'm doing}
+
+\include-section{their-reply.lit}
+`,
+
+			"their-reply.lit": `\title{Their Reply}
+
+Good, thanks!
+`,
+		},
+
+		Safe: &booklit.SafeMode{
+			MaxIncludeDepth: 1,
+		},
+
+		Err: ContainSubstring("include depth exceeds 1 in safe mode"),
+	}),
+
+	Entry("rejecting files which are too large", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{how-im-doing.lit}
+`,
+
+		Inputs: Files{
+			"how-im-doing.lit": `\title{How I'm doing}
+
+Good, thanks! And you? I have a lot to say about it, so this file is rather long.
+`,
+		},
+
+		Safe: &booklit.SafeMode{
+			MaxFileSize: 64,
+		},
+
+		Err: ContainSubstring("file exceeds 64 bytes in safe mode"),
+	}),
+
+	Entry("rejecting files outside of the book", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{../../../../../../../../../../etc/passwd}
+`,
+
+		Safe: &booklit.SafeMode{},
+
+		Err: ContainSubstring("file is outside of the book in safe mode"),
+	}),
+
+	Entry("rejecting files outside of the book when parsing ahead", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{../../../../../../../../../../etc/passwd}
+`,
+
+		Jobs: 4,
+
+		Safe: &booklit.SafeMode{},
+
+		Err: ContainSubstring("file is outside of the book in safe mode"),
+	}),
+
+	Entry("rejecting files which are too large when parsing ahead", Example{
+		Input: `\title{Hello, world!}
+
+\include-section{how-im-doing.lit}
+`,
+
+		Inputs: Files{
+			"how-im-doing.lit": `\title{How I'm doing}
+
+Good, thanks! And you? I have a lot to say about it, so this file is rather long.
+`,
+		},
+
+		Jobs: 4,
+
+		Safe: &booklit.SafeMode{
+			MaxFileSize: 64,
+		},
+
+		Err: ContainSubstring("file exceeds 64 bytes in safe mode"),
+	}),
+)
+
+var _ = Describe("Safe Mode", func() {
+	loadWithJobs := func(files fstest.MapFS, safe *booklit.SafeMode) *load.Processor {
+		processor := &load.Processor{
+			FS:   files,
+			Jobs: 4,
+			Safe: safe,
+		}
+
+		_, err := processor.LoadFile("index.lit", []booklit.PluginFactory{baselit.NewPlugin})
+		Expect(err).To(HaveOccurred())
+
+		return processor
+	}
+
+	// files are parsed ahead of time in the background, so give them a
+	// chance to be
+	misses := func(processor *load.Processor) func() int {
+		return func() int {
+			_, misses := processor.CacheStats()
+			return misses
+		}
+	}
+
+	It("does not parse files which are too large ahead of time", func() {
+		processor := loadWithJobs(fstest.MapFS{
+			"index.lit": {Data: []byte(`\title{Hello, world!}
+
+\include-section{child.lit}
+`)},
+			"child.lit": {Data: []byte(`\title{Child}
+
+` + strings.Repeat("Far too long. ", 100) + `
+`)},
+		}, &booklit.SafeMode{MaxFileSize: 512})
+
+		Consistently(misses(processor), 200*time.Millisecond).Should(Equal(1))
+	})
+
+	It("does not parse files included too deeply ahead of time", func() {
+		processor := loadWithJobs(fstest.MapFS{
+			"index.lit": {Data: []byte(`\title{Hello, world!}
+
+\include-section{child.lit}
+`)},
+			"child.lit": {Data: []byte(`\title{Child}
+
+\include-section{grandchild.lit}
+`)},
+			"grandchild.lit": {Data: []byte(`\title{Grandchild}
+
+\include-section{index.lit}
+`)},
+		}, &booklit.SafeMode{MaxIncludeDepth: 1})
+
+		// index.lit and child.lit are parsed, but not grandchild.lit, nor
+		// index.lit again by way of it
+		Consistently(misses(processor), 200*time.Millisecond).Should(Equal(2))
+	})
+})
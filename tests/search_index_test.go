@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/extensions/table"
 )
 
@@ -158,4 +160,36 @@ and a \reference{sub-section}.
 			}
 		}`,
 	}),
+
+	Entry("embeddings", Example{
+		Input: `\title{Hello, world!}
+
+How are you?
+
+\section{
+	\title{Their Reply}
+
+	Good, thanks!
+}
+`,
+
+		Embedder: lengthEmbedder{},
+
+		SearchEmbeddings: `{
+			"hello-world": [29, 1],
+			"their-reply": [28, 1]
+		}`,
+	}),
 )
+
+// lengthEmbedder embeds each text as its length.
+type lengthEmbedder struct{}
+
+func (lengthEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := [][]float64{}
+	for _, text := range texts {
+		vectors = append(vectors, []float64{float64(len(text)), 1})
+	}
+
+	return vectors, nil
+}
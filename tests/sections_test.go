@@ -2,6 +2,8 @@ package tests
 
 import (
 	. "github.com/onsi/ginkgo/extensions/table"
+	"github.com/onsi/gomega"
+	"github.com/vito/booklit"
 )
 
 var _ = DescribeTable("Booklit", (Example).Run,
@@ -223,6 +225,295 @@ How are you?
 		},
 	}),
 
+	Entry("parallel loading and rendering", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\include-section{doing.lit}
+\include-section{going.lit}
+`,
+
+		Inputs: Files{
+			"doing.lit": `\title{How I'm Doing}
+
+Good, thanks!
+`,
+			"going.lit": `\title{Where I'm Going}
+
+\split-sections
+
+\include-section{sub/next.lit}
+`,
+			"sub/next.lit": `\title{Where Next}
+
+Nowhere.
+`,
+		},
+
+		Jobs: 4,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+</section>
+`,
+			"how-im-doing.html": `<section>
+	<h1>1 How I'm Doing</h1>
+
+	<p>Good, thanks!</p>
+</section>
+`,
+			"where-im-going.html": `<section>
+	<h1>2 Where I'm Going</h1>
+</section>
+`,
+			"where-next.html": `<section>
+	<h1>2.1 Where Next</h1>
+
+	<p>Nowhere.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("incremental rebuilds", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\include-section{doing.lit}
+\include-section{going.lit}
+`,
+
+		Inputs: Files{
+			"doing.lit": `\title{How I'm Doing}
+
+Good, thanks!
+`,
+			"going.lit": `\title{Where I'm Going}
+
+Nowhere.
+`,
+		},
+
+		Changes: Files{
+			"going.lit": `\title{Where I'm Going}
+
+Somewhere.
+`,
+		},
+
+		Rerendered: []string{"where-im-going.html"},
+	}),
+
+	Entry("incremental rebuilds after a title changes", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\include-section{doing.lit}
+\include-section{going.lit}
+`,
+
+		Inputs: Files{
+			"doing.lit": `\title{How I'm Doing}
+
+Good, thanks!
+`,
+			"going.lit": `\title{Where I'm Going}{where-im-going}
+
+Nowhere.
+`,
+		},
+
+		Changes: Files{
+			"going.lit": `\title{Where I'm Headed}{where-im-going}
+
+Nowhere.
+`,
+		},
+
+		Rerendered: []string{"hello-world.html", "how-im-doing.html", "where-im-going.html"},
+	}),
+
+	Entry("transliterated tags", Example{
+		Input: `\title{Hello, world!}
+
+\split-sections
+
+\section{
+	\title{Café Straße & Ærø}
+
+	Good, thanks!
+}
+`,
+
+		Slugifier: &booklit.Slugifier{
+			Transliterate: true,
+			Case:          booklit.SlugCasePreserve,
+		},
+
+		Outputs: Files{
+			"Hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+</section>
+`,
+			"Cafe-Strasse-and-AEro.html": `<section>
+	<h1>1 Café Straße &amp; Ærø</h1>
+
+	<p>Good, thanks!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("error pages", Example{
+		Input: `\title{Hello, world!}
+
+How are you?
+
+\split-sections
+
+\section{
+	\title{Not Found}
+
+	\error-page{404}
+
+	Nothing to see here.
+}
+`,
+
+		Outputs: Files{
+			"not-found.html": `<section>
+	<h1>1 Not Found</h1>
+
+	<p>Nothing to see here.</p>
+</section>
+`,
+			"404.html": `<section>
+	<h1>1 Not Found</h1>
+
+	<p>Nothing to see here.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("splitting sub-sections by size", Example{
+		Input: `\title{Hello, world!}
+
+How are you?
+
+\split-sections-over{3}
+
+\section{
+	\title{Short}
+
+	Fine.
+}
+
+\section{
+	\title{Long}
+
+	Good, thanks for asking!
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>How are you?</p>
+</section>
+`,
+			"short.html": `<section>
+	<h1>1 Short</h1>
+
+	<p>Fine.</p>
+</section>
+`,
+			"long.html": `<section>
+	<h1>2 Long</h1>
+
+	<p>Good, thanks for asking!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("not splitting small sub-sections by size", Example{
+		Input: `\title{Hello, world!}
+
+How are you?
+
+\split-sections-over{100}
+
+\section{
+	\title{How I'm Doing}
+
+	Good, thanks!
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>How are you?</p>
+
+	<h2>1 How I'm Doing</h2>
+
+	<p>Good, thanks!</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("shuffled sub-sections", Example{
+		Input: `\title{Hello, world!}
+
+\shuffle-sections{42}
+
+\section{
+	\title{A}
+
+	Question A?
+}
+
+\section{
+	\title{B}
+
+	Question B?
+}
+
+\section{
+	\title{C}
+
+	Question C?
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<h2>1 C</h2>
+
+	<p>Question C?</p>
+
+	<h2>2 A</h2>
+
+	<p>Question A?</p>
+
+	<h2>3 B</h2>
+
+	<p>Question B?</p>
+</section>
+`,
+		},
+	}),
+
 	Entry("forcing sections onto one page", Example{
 		Input: `\title{Hello, world!}
 
@@ -444,6 +735,158 @@ This is some more content.
 		},
 	}),
 
+	Entry("tables of contents with limited depth", Example{
+		Input: `\title{Hello, world!}
+
+\toc-depth{2}
+
+\table-of-contents
+
+\section{
+	\title{Section A}
+
+	\section{
+		\title{Nested Section}
+
+		\section{
+			\title{Deeply Nested Section}
+		}
+	}
+
+	\section{
+		\title{Hidden Section}
+
+		\hide-from-toc
+
+		\section{
+			\title{Child of Hidden Section}
+		}
+	}
+}
+
+\section{
+	\title{Section B}
+
+	\toc-depth{1}
+
+	\table-of-contents
+
+	\section{
+		\title{Listed Section}
+
+		\section{
+			\title{Unlisted Section}
+		}
+	}
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<ul>
+		<li>
+			<a href="hello-world.html#section-a">Section A</a>
+			<ul>
+				<li><a href="hello-world.html#nested-section">Nested Section</a></li>
+			</ul>
+		</li>
+		<li>
+			<a href="hello-world.html#section-b">Section B</a>
+			<ul>
+				<li><a href="hello-world.html#listed-section">Listed Section</a></li>
+			</ul>
+		</li>
+	</ul>
+
+	<h2>1 Section A</h2>
+
+	<h3>1.1 Nested Section</h3>
+
+	<h4>1.1.1 Deeply Nested Section</h4>
+
+	<h3>1.2 Hidden Section</h3>
+
+	<h4>1.2.1 Child of Hidden Section</h4>
+
+	<h2>2 Section B</h2>
+
+	<ul>
+		<li><a href="hello-world.html#listed-section">Listed Section</a></li>
+	</ul>
+
+	<h3>2.1 Listed Section</h3>
+
+	<h4>2.1.1 Unlisted Section</h4>
+</section>
+`,
+		},
+	}),
+
+	Entry("conditional sections", Example{
+		Flags: []string{"enterprise"},
+
+		Input: `\title{Hello, world!}
+
+Welcome to the \if-flag{enterprise}{Enterprise}{Community} Edition.
+
+\table-of-contents
+
+\section{
+	\title{Getting Started}
+
+	First, breathe.
+}
+
+\if-flag{enterprise}{
+	\section{
+		\title{Single Sign-On}
+
+		Log in with your company's account.
+	}
+}
+
+\if-flag{draft}{
+	\section{
+		\title{Upcoming Features}
+
+		Coming soon.
+	}
+}{
+	Stay tuned.
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p>Welcome to the Enterprise Edition.</p>
+
+	<ul>
+		<li>
+			<a href="hello-world.html#getting-started">Getting Started</a>
+		</li>
+		<li>
+			<a href="hello-world.html#single-sign-on">Single Sign-On</a>
+		</li>
+	</ul>
+
+	<p>Stay tuned.</p>
+
+	<h2>1 Getting Started</h2>
+
+	<p>First, breathe.</p>
+
+	<h2>2 Single Sign-On</h2>
+
+	<p>Log in with your company's account.</p>
+</section>
+`,
+		},
+	}),
+
 	Entry("styled sections", Example{
 		Input: `\title{Hello, world!}
 
@@ -501,4 +944,47 @@ How are you?
 `,
 		},
 	}),
+
+	Entry("front matter", Example{
+		Input: `\title{Hello, world!}
+
+\section{
+	\title{Preface}
+
+	\front-matter{preface}
+
+	Why I wrote this.
+}
+
+\section{
+	\title{Getting Started}
+
+	First, breathe.
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<h2>Preface</h2>
+
+	<p>Why I wrote this.</p>
+
+	<h2>1 Getting Started</h2>
+
+	<p>First, breathe.</p>
+</section>
+`,
+		},
+	}),
+
+	Entry("unknown front matter", Example{
+		Input: `\title{Hello, world!}
+
+\front-matter{blurb}
+`,
+
+		Err: gomega.ContainSubstring("unknown front matter kind 'blurb'"),
+	}),
 )
@@ -2,6 +2,7 @@ package tests
 
 import (
 	. "github.com/onsi/ginkgo/extensions/table"
+	_ "github.com/vito/booklit/tests/fixtures/arbitrary-style-plugin"
 	_ "github.com/vito/booklit/tests/fixtures/partials-style-plugin"
 )
 
@@ -37,6 +38,146 @@ Sup?
 
 	<p>Sup?</p>
 </section>
+`,
+		},
+	}),
+	Entry("context templates", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{arbitrary-style}
+
+\lede{Once} upon a time, \lede{there} was a section.
+
+\snippet{one}
+
+\snippet{two}
+
+Some prose.
+
+\snippet{three}
+
+\section{
+	\title{Sub-section}
+
+	\lede{Again}.
+}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<h1>Hello, world!</h1>
+
+	<p><span class="drop-cap">Once</span> upon a time, <span class="lede">there</span> was a section.</p>
+
+	<pre>one
+two</pre>
+
+	<p>Some prose.</p>
+
+	<pre>three</pre>
+
+	<h2>1 Sub-section</h2>
+
+	<p><span class="drop-cap">Again</span>.</p>
+</section>
+`,
+		},
+	}),
+	Entry("section classes and data attributes", Example{
+		Input: `\title{Hello, world!}
+
+\styled{wrapped}
+\style-section{wide dark}{layout=grid}{theme=night}
+
+Sup?
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<div class="wide dark" data-layout="grid" data-theme="night">
+		<h1>Hello, world!</h1>
+
+		<p>Sup?</p>
+	</div>
+</section>
+`,
+		},
+	}),
+	Entry("raw HTML", Example{
+		Input: `\title{Hello, world!}
+
+\styled{raw-html}
+\set-partial{HTML}{<b onclick="steal()">Hi</b><script>steal()</script>}
+`,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<div class="raw"><b onclick="steal()">Hi</b><script>steal()</script></div>
+</section>
+`,
+		},
+	}),
+	Entry("sanitized raw HTML", Example{
+		Input: `\title{Hello, world!}
+
+\styled{raw-html}
+\set-partial{HTML}{<b onclick="steal()">Hi</b><script>steal()</script><a href="javascript:steal()">there</a>}
+`,
+
+		SanitizeHTML: true,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<div class="raw"><b>Hi</b><a>there</a></div>
+</section>
+`,
+		},
+	}),
+	Entry("sanitized raw HTML with obfuscated URL schemes", Example{
+		Input: `\title{Hello, world!}
+
+\styled{raw-html}
+\set-partial{HTML}{<a href="java&#x09;script:alert(1)">x</a><a href=" JaVaScRiPt:alert(1)">y</a><img src="data:text/html,x"><a href="/ok">z</a>}
+`,
+
+		SanitizeHTML: true,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<div class="raw"><a>x</a><a>y</a><img/><a href="/ok">z</a></div>
+</section>
+`,
+		},
+	}),
+	Entry("sanitized raw HTML with markup in SVG styles", Example{
+		Input: `\title{Hello, world!}
+
+\styled{raw-html}
+\set-partial{HTML}{<svg><style><img src=x onerror=alert(1)></style></svg>}
+`,
+
+		SanitizeHTML: true,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<div class="raw"><svg><style></style></svg></div>
+</section>
+`,
+		},
+	}),
+	Entry("sanitized raw HTML with SVG animations", Example{
+		Input: `\title{Hello, world!}
+
+\styled{raw-html}
+\set-partial{HTML}{<svg><animate values="x;javascript:alert(1)" attributeName="href"/><path d="M0 0" fill="url(#g)" onload="alert(1)"/></svg>}
+`,
+
+		SanitizeHTML: true,
+
+		Outputs: Files{
+			"hello-world.html": `<section>
+	<div class="raw"><svg><path d="M0 0" fill="url(#g)"></path></svg></div>
+</section>
 `,
 		},
 	}),
@@ -3,6 +3,7 @@ package tests
 import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
 	"github.com/sirupsen/logrus"
 
 	"testing"
@@ -13,6 +14,23 @@ func TestBooklit(t *testing.T) {
 	RunSpecs(t, "Booklit Suite")
 }
 
+// executable speaking the external plugin protocol
+var externalPluginPath string
+
+// booklit executable, for testing its commands
+var booklitPath string
+
 var _ = BeforeSuite(func() {
 	logrus.SetLevel(logrus.FatalLevel)
+
+	var err error
+	externalPluginPath, err = gexec.Build("github.com/vito/booklit/tests/fixtures/external-plugin")
+	Expect(err).ToNot(HaveOccurred())
+
+	booklitPath, err = gexec.Build("github.com/vito/booklit/cmd/booklit")
+	Expect(err).ToNot(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	gexec.CleanupBuildArtifacts()
 })
@@ -0,0 +1,42 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	_ "github.com/vito/booklit/tests/fixtures/arbitrary-style-plugin"
+)
+
+var _ = DescribeTable("Template Errors", (Example).Run,
+	Entry("tracing the templates rendering a failing template", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{arbitrary-style}
+
+\section{
+	\title{Broken}
+
+	\broken-style{Oh no.}
+}
+`,
+
+		Err: "rendering section 'broken' failed in broken.tmpl:3:4 at {{.Missing}}: can't evaluate field Missing in type booklit.Styled",
+	}),
+
+	Entry("naming the partial being rendered", Example{
+		Input: `\title{Hello, world!}
+
+\use-plugin{arbitrary-style}
+
+\section{
+	\title{Broken}
+
+	\styled{sidebar}
+
+	\set-partial{Sidebar}{\broken-style{Oh no.}}
+
+	Hi.
+}
+`,
+
+		Err: `rendering section 'broken' failed in broken.tmpl:3:4 at {{.Missing}} (rendering partial "Sidebar"): can't evaluate field Missing in type booklit.Styled`,
+	}),
+)
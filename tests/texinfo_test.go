@@ -0,0 +1,94 @@
+package tests
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+)
+
+var _ = DescribeTable("Texinfo", (Example).Run,
+	Entry("a manual with a node for each section", Example{
+		Input: `\title{Hello, world!}
+
+Some \bold{bold} text with @{braces}; see \reference{child}.
+
+\section{
+	\title{Child}
+
+	\code{{{
+	a < b
+	}}}
+
+	\list{one}{two}
+}
+
+\section{
+	\title{Other}
+
+	Back to \reference{hello-world}{the top}.
+}
+`,
+
+		Texinfo: Files{
+			"hello-world.texi": `\input texinfo
+@setfilename hello-world.info
+@settitle Hello, world!
+@documentencoding UTF-8
+
+@titlepage
+@title Hello, world!
+@end titlepage
+
+@ifnottex
+@node Top
+@top Hello, world!
+@end ifnottex
+
+Some @strong{bold} text with @@braces; see @ref{Child, Child}.
+
+
+
+
+@menu
+* Child::
+* Other::
+@end menu
+
+
+
+@node Child
+@chapter Child
+
+
+@example
+a < b
+@end example
+
+
+@itemize @bullet
+@item
+one
+@item
+two
+@end itemize
+
+
+
+
+
+
+
+@node Other
+@chapter Other
+
+Back to @ref{Top, the top}.
+
+
+
+
+
+
+
+
+@bye`,
+		},
+	}),
+)
@@ -0,0 +1,47 @@
+package booklit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Translations are catalogs of translated strings by locale and then by key,
+// e.g. for labels which are the same on every page, such as "Next".
+type Translations map[string]map[string]string
+
+// Lookup returns the string for the key from the catalog of the first
+// locale which has one, trying each locale's base language after it, e.g. pt
+// after pt-BR.
+func (translations Translations) Lookup(key string, locales ...string) (string, bool) {
+	for _, locale := range locales {
+		if locale == "" {
+			continue
+		}
+
+		candidates := []string{locale}
+		if base := strings.SplitN(locale, "-", 2)[0]; base != locale {
+			candidates = append(candidates, base)
+		}
+
+		for _, candidate := range candidates {
+			if str, found := translations[candidate][key]; found {
+				return str, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Translate returns the string for the key translated into the section's
+// locale, or into the book's default locale if it has no translation.
+func (con *Section) Translate(key string) (string, error) {
+	top := con.Top()
+
+	str, found := top.Translations.Lookup(key, con.InheritedLocale(), top.DefaultLocale)
+	if !found {
+		return "", fmt.Errorf("unknown translation: %s", key)
+	}
+
+	return str, nil
+}
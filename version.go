@@ -1,4 +1,8 @@
 package booklit
 
 // overridden via linker flags
-var Version = "0.0.0-dev"
+var Version = devVersion
+
+// version of builds which weren't given one, e.g. via go install; they
+// satisfy any minimum version, as there's no telling how new they are
+const devVersion = "0.0.0-dev"
@@ -0,0 +1,230 @@
+package booklit
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sync"
+)
+
+// Warning is a non-fatal issue with a book, e.g. use of a deprecated
+// function. Warnings are printed like errors once the book is loaded, but
+// don't stop the build unless it is strict.
+type Warning interface {
+	error
+	PrettyError
+}
+
+// Warnings collects the warnings reported while loading a book.
+type Warnings struct {
+	// If set, warnings are returned as errors so that the build is stopped.
+	Strict bool
+
+	Warnings []Warning
+
+	warningsL sync.Mutex
+}
+
+// Record adds the warning to the collection. If the build is strict, the
+// warning is returned instead, so that it can be returned as an error.
+func (warnings *Warnings) Record(warning Warning) error {
+	if warnings.Strict {
+		return warning
+	}
+
+	warnings.warningsL.Lock()
+	warnings.Warnings = append(warnings.Warnings, warning)
+	warnings.warningsL.Unlock()
+
+	return nil
+}
+
+func (warnings *Warnings) PrettyPrint(out io.Writer) {
+	for _, warning := range warnings.Warnings {
+		fmt.Fprint(out, "warning: ")
+		warning.PrettyPrint(out)
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintf(out, "Found %d warnings.\n", len(warnings.Warnings))
+}
+
+// Warn reports a warning for the section's book, e.g. from a plugin's
+// function. If the build is strict, the warning is returned, and should be
+// returned by the function so that the build is stopped.
+//
+// Warnings are only collected for books loaded by a processor; otherwise
+// they are ignored.
+func (con *Section) Warn(warning Warning) error {
+	warnings := con.Top().Warnings
+	if warnings == nil {
+		return nil
+	}
+
+	return warnings.Record(warning)
+}
+
+// DeprecatedFunctionWarning is reported by plugins when a function which
+// is due to be removed is used.
+type DeprecatedFunctionWarning struct {
+	Function string
+
+	// function to use instead, if any
+	Replacement string
+
+	ErrorLocation
+}
+
+func (warning DeprecatedFunctionWarning) Error() string {
+	if warning.Replacement != "" {
+		return fmt.Sprintf("\\%s is deprecated; use \\%s instead", warning.Function, warning.Replacement)
+	}
+
+	return fmt.Sprintf("\\%s is deprecated", warning.Function)
+}
+
+func (warning DeprecatedFunctionWarning) PrettyPrint(out io.Writer) {
+	fmt.Fprintf(out, warning.Annotate("%s\n\n", warning))
+	warning.AnnotateLocation(out)
+}
+
+func (warning DeprecatedFunctionWarning) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("warning.tmpl").Execute(out, warning)
+}
+
+func (warning DeprecatedFunctionWarning) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(warning))
+}
+
+// EmptySectionWarning is reported for a section with neither a body nor
+// any child sections, e.g. one which has yet to be written.
+type EmptySectionWarning struct {
+	TagName string
+
+	ErrorLocation
+}
+
+func (warning EmptySectionWarning) Error() string {
+	return fmt.Sprintf("section '%s' is empty", warning.TagName)
+}
+
+func (warning EmptySectionWarning) PrettyPrint(out io.Writer) {
+	fmt.Fprintf(out, warning.Annotate("%s\n\n", warning))
+	warning.AnnotateLocation(out)
+}
+
+func (warning EmptySectionWarning) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("warning.tmpl").Execute(out, warning)
+}
+
+func (warning EmptySectionWarning) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(warning))
+}
+
+// BrokenReferenceWarning is reported in place of the error for a reference
+// which could not be resolved, if broken references are allowed.
+type BrokenReferenceWarning struct {
+	// UnknownTagError or AmbiguousReferenceError
+	Err error
+}
+
+func (warning BrokenReferenceWarning) Error() string {
+	return fmt.Sprintf("broken reference: %s", warning.Err)
+}
+
+func (warning BrokenReferenceWarning) PrettyPrint(out io.Writer) {
+	if prettyErr, ok := warning.Err.(PrettyError); ok {
+		prettyErr.PrettyPrint(out)
+	} else {
+		fmt.Fprintln(out, warning)
+	}
+}
+
+func (warning BrokenReferenceWarning) PrettyHTML(out io.Writer) error {
+	if prettyErr, ok := warning.Err.(PrettyError); ok {
+		return prettyErr.PrettyHTML(out)
+	}
+
+	_, err := fmt.Fprintf(out, `<pre class="raw-error">%s</pre>`, template.HTMLEscapeString(warning.Error()))
+	return err
+}
+
+func (warning BrokenReferenceWarning) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(warning))
+}
+
+func (warning BrokenReferenceWarning) Unwrap() error {
+	return warning.Err
+}
+
+func (warning BrokenReferenceWarning) location() ErrorLocation {
+	loc, _ := errorLocation(warning.Err)
+	return loc
+}
+
+// UnusedAssetWarning is reported for a file in an asset directory, e.g. an
+// image, which is never referred to by any page or other asset.
+type UnusedAssetWarning struct {
+	Path string
+}
+
+func (warning UnusedAssetWarning) Error() string {
+	return fmt.Sprintf("asset '%s' is never used", warning.Path)
+}
+
+func (warning UnusedAssetWarning) PrettyPrint(out io.Writer) {
+	fmt.Fprintln(out, warning)
+}
+
+func (warning UnusedAssetWarning) PrettyHTML(out io.Writer) error {
+	_, err := fmt.Fprintf(out, `<pre class="raw-error">%s</pre>`, template.HTMLEscapeString(warning.Error()))
+	return err
+}
+
+func (warning UnusedAssetWarning) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(warning))
+}
+
+func (warning UnusedAssetWarning) location() ErrorLocation {
+	return ErrorLocation{FilePath: warning.Path}
+}
+
+// TerminologyWarning is reported for a use of a term in prose which breaks
+// the book's Terminology, e.g. Github rather than GitHub.
+type TerminologyWarning struct {
+	// the term as written, e.g. Github
+	Term string
+
+	// spelling to use instead; empty if the term is banned
+	Preferred string
+
+	// reason for banning the term, if any
+	Reason string
+
+	ErrorLocation
+}
+
+func (warning TerminologyWarning) Error() string {
+	if warning.Preferred != "" {
+		return fmt.Sprintf("use '%s' instead of '%s'", warning.Preferred, warning.Term)
+	}
+
+	if warning.Reason != "" {
+		return fmt.Sprintf("avoid '%s': %s", warning.Term, warning.Reason)
+	}
+
+	return fmt.Sprintf("avoid '%s'", warning.Term)
+}
+
+func (warning TerminologyWarning) PrettyPrint(out io.Writer) {
+	fmt.Fprintf(out, warning.Annotate("%s\n\n", warning))
+	warning.AnnotateLocation(out)
+}
+
+func (warning TerminologyWarning) PrettyHTML(out io.Writer) error {
+	return errorTmpl.Lookup("warning.tmpl").Execute(out, warning)
+}
+
+func (warning TerminologyWarning) PrettyJSON(out io.Writer) error {
+	return writeJSON(out, jsonErrorOf(warning))
+}